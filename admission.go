@@ -0,0 +1,269 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/bits"
+	"os"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+const (
+	hotKeysMagicBytes = "FTSH"
+	hotKeysVersion    = 1
+)
+
+// WithTinyLFU enables TinyLFU admission for the bitmap cache. Access counts are
+// tracked in a compact frequency sketch, and a bitmap loaded from disk only
+// displaces the least recently used entry if it has been requested more often.
+// This keeps scan-like queries over one-off n-grams from flushing hot bitmaps.
+func WithTinyLFU() CachedIndexOption {
+	return cachedIndexOption(func(idx *CachedIndex) {
+		idx.useTinyLFU = true
+	})
+}
+
+// WithHotKeys preloads the bitmaps listed in a file written by SaveHotKeys when
+// the index is opened, most frequent first, and seeds the TinyLFU sketch with
+// their saved frequencies. For an Index it orders the background load of
+// LoadFromFileAsync the same way, and has no effect otherwise. A missing file
+// is ignored so the same option can be used on first start.
+func WithHotKeys(path string) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			idx.hotKeysPath = path
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			idx.hotKeysPath = path
+		}),
+	}
+}
+
+// frequencySketch is a count-min sketch of 8-bit counters with periodic aging,
+// as used by TinyLFU. Counts are halved every sampleSize increments so that
+// popularity reflects recent traffic.
+type frequencySketch struct {
+	counters   []uint8 // sketchDepth rows of width counters
+	mask       uint64  // width - 1
+	additions  int
+	sampleSize int
+}
+
+const sketchDepth = 4
+
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// newFrequencySketch sizes the sketch for a cache of about capacity entries.
+func newFrequencySketch(capacity int) *frequencySketch {
+	width := 1 << bits.Len(uint(max(capacity, 1024)-1))
+	return &frequencySketch{
+		counters:   make([]uint8, sketchDepth*width),
+		mask:       uint64(width - 1),
+		sampleSize: 10 * width,
+	}
+}
+
+func (s *frequencySketch) slot(key uint64, row int) int {
+	h := (key ^ sketchSeeds[row]) * 0x9e3779b97f4a7c15
+	h ^= h >> 32
+	return row*int(s.mask+1) + int(h&s.mask)
+}
+
+// increment records one access to key.
+func (s *frequencySketch) increment(key uint64) {
+	s.add(key, 1)
+}
+
+// add records n accesses to key, saturating at 255.
+func (s *frequencySketch) add(key uint64, n int) {
+	for row := range sketchDepth {
+		i := s.slot(key, row)
+		s.counters[i] = uint8(min(int(s.counters[i])+n, 255))
+	}
+
+	s.additions += n
+	if s.additions >= s.sampleSize {
+		for i := range s.counters {
+			s.counters[i] >>= 1
+		}
+		s.additions /= 2
+	}
+}
+
+// estimate returns the approximate access count of key.
+func (s *frequencySketch) estimate(key uint64) int {
+	n := 255
+	for row := range sketchDepth {
+		n = min(n, int(s.counters[s.slot(key, row)]))
+	}
+	return n
+}
+
+// admitLocked reports whether a newly loaded bitmap should enter the cache.
+func (idx *CachedIndex) admitLocked(key uint64, bm *roaring.Bitmap) bool {
+	if idx.sketch == nil {
+		return true
+	}
+	victim, full := idx.lru.victim(bm.GetSizeInBytes())
+	return !full || idx.sketch.estimate(key) > idx.sketch.estimate(victim)
+}
+
+// HotKey is a cached n-gram key with its estimated access frequency.
+type HotKey struct {
+	Key       uint64
+	Frequency int
+}
+
+// HotKeys returns the keys currently in cache, most frequently used first.
+// Without TinyLFU frequencies are zero and keys are ordered by recency.
+func (idx *CachedIndex) HotKeys() []HotKey {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	hot := make([]HotKey, 0, idx.lru.len())
+	for e := idx.lru.head; e != nil; e = e.next {
+		hk := HotKey{Key: e.key}
+		if idx.sketch != nil {
+			hk.Frequency = idx.sketch.estimate(e.key)
+		}
+		hot = append(hot, hk)
+	}
+	slices.SortStableFunc(hot, func(a, b HotKey) int {
+		return cmp.Compare(b.Frequency, a.Frequency)
+	})
+	return hot
+}
+
+// SaveHotKeys saves the cached keys and their frequencies to a file
+// atomically, for WithHotKeys to re-warm the cache after a restart.
+//
+// Format: magic(4) + version(2) + reserved(2) + count(4), then
+// key(8) + frequency(4) per entry, most frequent first.
+func (idx *CachedIndex) SaveHotKeys(path string) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	if err := writeHotKeys(f, idx.HotKeys()); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+func writeHotKeys(w io.Writer, hot []HotKey) error {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 12)
+	copy(header[0:4], hotKeysMagicBytes)
+	binary.LittleEndian.PutUint16(header[4:6], hotKeysVersion)
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(hot)))
+	if _, err := bw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	buf := make([]byte, 12)
+	for _, hk := range hot {
+		binary.LittleEndian.PutUint64(buf[0:8], hk.Key)
+		binary.LittleEndian.PutUint32(buf[8:12], uint32(hk.Frequency))
+		if _, err := bw.Write(buf); err != nil {
+			return fmt.Errorf("write hot key: %w", err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func readHotKeys(r io.Reader) ([]HotKey, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if string(header[0:4]) != hotKeysMagicBytes {
+		return nil, ErrInvalidMagic
+	}
+	if binary.LittleEndian.Uint16(header[4:6]) != hotKeysVersion {
+		return nil, ErrInvalidVersion
+	}
+	count := binary.LittleEndian.Uint32(header[8:12])
+	if count > maxNgramCount {
+		return nil, ErrInvalidCount
+	}
+
+	hot := make([]HotKey, count)
+	buf := make([]byte, 12)
+	for i := range hot {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, fmt.Errorf("read hot key: %w", err)
+		}
+		hot[i] = HotKey{
+			Key:       binary.LittleEndian.Uint64(buf[0:8]),
+			Frequency: int(binary.LittleEndian.Uint32(buf[8:12])),
+		}
+	}
+	return hot, nil
+}
+
+// loadHotKeys seeds the sketch and preloads the saved hot keys that fit in
+// the cache, so the most frequent are the most recently used.
+func (idx *CachedIndex) loadHotKeys() error {
+	f, err := os.Open(idx.hotKeysPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open hot keys: %w", err)
+	}
+	defer f.Close()
+
+	hot, err := readHotKeys(f)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]uint64, 0, len(hot))
+	for _, hk := range hot {
+		if _, ok := idx.ngramIndex[hk.Key]; !ok {
+			continue
+		}
+		if idx.sketch != nil {
+			idx.sketch.add(hk.Key, hk.Frequency)
+		}
+		keys = append(keys, hk.Key)
+	}
+
+	keys = idx.fitCache(keys)
+	slices.Reverse(keys)
+	return idx.preloadConcurrent(keys)
+}
@@ -0,0 +1,118 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestFrequencySketch(t *testing.T) {
+	s := newFrequencySketch(16)
+	for range 5 {
+		s.increment(42)
+	}
+	s.increment(7)
+
+	if got := s.estimate(42); got < 5 {
+		t.Errorf("estimate(42) = %d, want >= 5", got)
+	}
+	if s.estimate(42) <= s.estimate(7) {
+		t.Errorf("frequent key should estimate higher: %d vs %d", s.estimate(42), s.estimate(7))
+	}
+
+	// Aging halves counts once the sample size is reached
+	s.add(99, s.sampleSize)
+	if got := s.estimate(42); got > 3 {
+		t.Errorf("estimate(42) after aging = %d, want <= 3", got)
+	}
+}
+
+func newAdmissionTestFile(t *testing.T) string {
+	t.Helper()
+
+	idx := NewIndex(3)
+	for i := uint32(0); i < 50; i++ {
+		idx.Add(i, fmt.Sprintf("word%02d", i))
+	}
+	path := filepath.Join(t.TempDir(), "admission.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	return path
+}
+
+func TestTinyLFUResistsScans(t *testing.T) {
+	path := newAdmissionTestFile(t)
+	hot := NgramKey("wor")
+
+	for _, tinyLFU := range []bool{false, true} {
+		opts := []CachedIndexOption{WithCacheSize(4)}
+		if tinyLFU {
+			opts = append(opts, WithTinyLFU())
+		}
+		cached, err := OpenCachedIndex(path, opts...)
+		if err != nil {
+			t.Fatalf("OpenCachedIndex failed: %v", err)
+		}
+		defer cached.Close()
+
+		for range 10 {
+			cached.SearchAny("wor")
+		}
+		// A scan over one-off n-grams
+		for i := 0; i < 50; i++ {
+			cached.SearchAny(fmt.Sprintf("d%02d", i))
+		}
+
+		_, cachedHot := cached.lru.entries[hot]
+		if cachedHot != tinyLFU {
+			t.Errorf("tinyLFU=%v: hot n-gram cached = %v", tinyLFU, cachedHot)
+		}
+	}
+}
+
+func TestSaveHotKeys(t *testing.T) {
+	path := newAdmissionTestFile(t)
+	hotPath := filepath.Join(t.TempDir(), "hot.keys")
+
+	// First start: no hot keys file yet
+	cached, err := OpenCachedIndex(path, WithTinyLFU(), WithHotKeys(hotPath))
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	defer cached.Close()
+	for range 3 {
+		cached.SearchAny("wor")
+	}
+	cached.SearchAny("d01")
+
+	hot := cached.HotKeys()
+	if len(hot) != 2 || hot[0].Key != NgramKey("wor") || hot[0].Frequency < 3 {
+		t.Fatalf("HotKeys = %+v, want wor first", hot)
+	}
+	if err := cached.SaveHotKeys(hotPath); err != nil {
+		t.Fatalf("SaveHotKeys failed: %v", err)
+	}
+
+	restarted, err := OpenCachedIndex(path, WithTinyLFU(), WithHotKeys(hotPath))
+	if err != nil {
+		t.Fatalf("OpenCachedIndex with hot keys failed: %v", err)
+	}
+	defer restarted.Close()
+	if restarted.CacheSize() != 2 {
+		t.Errorf("CacheSize after restart = %d, want 2", restarted.CacheSize())
+	}
+	if got := restarted.sketch.estimate(NgramKey("wor")); got < 3 {
+		t.Errorf("restored frequency = %d, want >= 3", got)
+	}
+
+	// Only the most frequent keys are loaded into a smaller cache
+	small, err := OpenCachedIndex(path, WithCacheSize(1), WithHotKeys(hotPath))
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	defer small.Close()
+	if _, ok := small.lru.entries[NgramKey("wor")]; !ok || small.CacheSize() != 1 {
+		t.Errorf("expected only wor cached, CacheSize = %d", small.CacheSize())
+	}
+}
@@ -0,0 +1,83 @@
+package roaringsearch
+
+import "strings"
+
+// analyzeGramSizes are the gram sizes compared by AnalyzeCorpus.
+var analyzeGramSizes = []int{2, 3, 4}
+
+const (
+	analyzeQueryRunes = 6   // length of probe queries cut from the sample
+	analyzeMaxQueries = 200 // probe queries per gram size
+)
+
+// GramSizeAnalysis projects how an index of one gram size behaves on a corpus.
+type GramSizeAnalysis struct {
+	GramSize          int
+	UniqueNgrams      int     // distinct n-grams in the sample
+	AvgPostings       float64 // mean number of sample documents per n-gram
+	FalsePositiveRate float64 // share of n-gram matches that do not contain the query
+	MemoryBytes       uint64  // bitmap memory for the sample
+}
+
+// AnalyzeCorpus indexes a sample of documents at gram sizes 2, 3, and 4 and
+// reports, per size, the unique n-gram count, average postings length, and an
+// estimated false-positive rate, to help choose a gram size without guessing.
+//
+// The false-positive rate is measured with 6-rune probe queries cut from the
+// sample: a match is false when a document contains all of the query's
+// n-grams but not the query itself. Smaller gram sizes give fewer, longer
+// postings lists and more false positives; larger sizes the reverse.
+// Options such as WithNormalizer apply to each trial index.
+func AnalyzeCorpus(sample []string, opts ...Option) []GramSizeAnalysis {
+	results := make([]GramSizeAnalysis, 0, len(analyzeGramSizes))
+	for _, gramSize := range analyzeGramSizes {
+		idx := NewIndex(gramSize, opts...)
+		batch := idx.BatchSize(len(sample))
+		for i, text := range sample {
+			batch.Add(uint32(i), text)
+		}
+		batch.Flush()
+
+		stats := idx.Stats(0)
+		results = append(results, GramSizeAnalysis{
+			GramSize:          gramSize,
+			UniqueNgrams:      stats.Ngrams,
+			AvgPostings:       stats.AvgCardinality,
+			FalsePositiveRate: idx.falsePositiveRate(sample),
+			MemoryBytes:       stats.MemoryBytes,
+		})
+	}
+	return results
+}
+
+// falsePositiveRate searches probe queries taken from evenly spaced sample
+// documents and verifies each match against the normalized text.
+func (idx *Index) falsePositiveRate(sample []string) float64 {
+	normalized := make([]string, len(sample))
+	for i, text := range sample {
+		normalized[i] = idx.normalizer(text)
+	}
+
+	step := max(1, len(sample)/analyzeMaxQueries)
+	var matches, falseMatches int
+	for i := 0; i < len(normalized); i += step {
+		runes := []rune(normalized[i])
+		if len(runes) < analyzeQueryRunes {
+			continue
+		}
+		mid := (len(runes) - analyzeQueryRunes) / 2
+		query := string(runes[mid : mid+analyzeQueryRunes])
+
+		for _, docID := range idx.Search(query) {
+			matches++
+			if !strings.Contains(normalized[docID], query) {
+				falseMatches++
+			}
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+	return float64(falseMatches) / float64(matches)
+}
@@ -0,0 +1,50 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnalyzeCorpus(t *testing.T) {
+	words := []string{"alpha", "beta", "gamma", "delta", "epsilon", "zeta", "theta", "iota"}
+	sample := make([]string, 300)
+	for i := range sample {
+		sample[i] = fmt.Sprintf("%s %s %s", words[i%8], words[(i*3)%8], words[(i*5+1)%8])
+	}
+
+	results := AnalyzeCorpus(sample)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, r := range results {
+		if r.GramSize != i+2 {
+			t.Errorf("result %d has gram size %d", i, r.GramSize)
+		}
+		if r.UniqueNgrams == 0 || r.AvgPostings == 0 || r.MemoryBytes == 0 {
+			t.Errorf("gram size %d: empty analysis %+v", r.GramSize, r)
+		}
+		if r.FalsePositiveRate < 0 || r.FalsePositiveRate > 1 {
+			t.Errorf("gram size %d: false positive rate %v out of range", r.GramSize, r.FalsePositiveRate)
+		}
+	}
+
+	// Longer grams mean more distinct n-grams with shorter postings and
+	// no more false positives
+	if !(results[0].UniqueNgrams < results[2].UniqueNgrams) {
+		t.Errorf("unique n-grams should grow with gram size: %+v", results)
+	}
+	if !(results[0].AvgPostings > results[2].AvgPostings) {
+		t.Errorf("average postings should shrink with gram size: %+v", results)
+	}
+	if results[0].FalsePositiveRate < results[2].FalsePositiveRate {
+		t.Errorf("false positives should not grow with gram size: %+v", results)
+	}
+}
+
+func TestAnalyzeCorpusEmpty(t *testing.T) {
+	for _, r := range AnalyzeCorpus(nil) {
+		if r.UniqueNgrams != 0 || r.FalsePositiveRate != 0 {
+			t.Errorf("empty sample analysis = %+v", r)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// Defaults for zero Config fields.
+const (
+	DefaultDocs       = 100_000
+	DefaultMinWords   = 5
+	DefaultMaxWords   = 20
+	DefaultGramSize   = 3
+	DefaultIterations = 1000
+	DefaultBatchSize  = 100_000
+)
+
+// DefaultQueries is the query mix used when Config.Queries is empty: frequent
+// technical terms, a first name and a rare word.
+var DefaultQueries = []string{"server", "client", "database", "john", "xylophone"}
+
+// Config describes a load test. Zero fields take the defaults above.
+type Config struct {
+	Docs     int   // number of documents to index
+	MinWords int   // shortest generated document, in words
+	MaxWords int   // longest generated document, in words
+	Seed     int64 // seed for the document generator
+
+	// Document returns the text of docID. Defaults to GenerateDocument; set
+	// it to index a sample of a real corpus or a generator with its shape.
+	Document func(rng *rand.Rand, docID uint32) string
+
+	GramSize     int
+	IndexOptions []rs.Option
+	BatchSize    int // documents per IndexBatch flush while building
+
+	Queries     []string
+	Iterations  int // total searches, cycling through Queries
+	Concurrency int // concurrent searchers, default 1
+	Limit       int // use SearchWithLimit when > 0 (in-memory index only)
+
+	// Cached runs the searches against a CachedIndex opened from a file
+	// written to Dir (a temporary directory when empty) instead of the
+	// in-memory index. CacheOptions set its budget, e.g. rs.WithCacheSize or
+	// rs.WithMemoryBudget.
+	Cached       bool
+	CacheOptions []rs.CachedIndexOption
+	Dir          string
+}
+
+// Latency summarizes a set of search durations.
+type Latency struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	P999 time.Duration
+	Max  time.Duration
+}
+
+// QueryResult is the latency and match count of one query of the mix.
+type QueryResult struct {
+	Query   string
+	Matches int
+	Latency Latency
+}
+
+// Result is the outcome of a load test.
+type Result struct {
+	Docs      int
+	Ngrams    int
+	BuildTime time.Duration
+
+	HeapBytes   uint64 // live heap after building, following a GC
+	IndexBytes  uint64 // MemoryUsage of the searched index after the run
+	FileBytes   int64  // size of the index file when Cached
+	CachedAfter int    // bitmaps cached after the run when Cached
+
+	Searches   int
+	Elapsed    time.Duration
+	Throughput float64 // searches per second over Elapsed
+	Latency    Latency
+	Queries    []QueryResult
+}
+
+// String formats the result for logs.
+func (r *Result) String() string {
+	return fmt.Sprintf("%d docs, %d n-grams, built in %v, heap %.1f MB, index %.1f MB; "+
+		"%d searches at %.0f/s: p50=%v p90=%v p99=%v max=%v",
+		r.Docs, r.Ngrams, r.BuildTime.Round(time.Millisecond),
+		float64(r.HeapBytes)/(1<<20), float64(r.IndexBytes)/(1<<20),
+		r.Searches, r.Throughput, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+}
+
+// withDefaults fills zero fields of cfg.
+func (cfg Config) withDefaults() Config {
+	if cfg.Docs <= 0 {
+		cfg.Docs = DefaultDocs
+	}
+	if cfg.MinWords <= 0 {
+		cfg.MinWords = DefaultMinWords
+	}
+	if cfg.MaxWords < cfg.MinWords {
+		cfg.MaxWords = max(DefaultMaxWords, cfg.MinWords)
+	}
+	if cfg.Document == nil {
+		minWords, maxWords := cfg.MinWords, cfg.MaxWords
+		cfg.Document = func(rng *rand.Rand, _ uint32) string {
+			return GenerateDocument(rng, minWords, maxWords)
+		}
+	}
+	if cfg.GramSize <= 0 {
+		cfg.GramSize = DefaultGramSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if len(cfg.Queries) == 0 {
+		cfg.Queries = DefaultQueries
+	}
+	if cfg.Iterations <= 0 {
+		cfg.Iterations = DefaultIterations
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return cfg
+}
+
+// RunLoadTest builds an index as described by cfg, then runs cfg.Iterations
+// searches over cfg.Queries from cfg.Concurrency goroutines, timing each one.
+// Cancelling ctx stops the build or the searches early; the result then
+// covers the searches that completed, and the error is ctx.Err().
+func RunLoadTest(ctx context.Context, cfg Config) (*Result, error) {
+	cfg = cfg.withDefaults()
+	res := &Result{Docs: cfg.Docs}
+
+	start := time.Now()
+	idx, err := build(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	res.BuildTime = time.Since(start)
+	res.Ngrams = idx.NgramCount()
+
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	res.HeapBytes = m.HeapAlloc
+
+	search := func(q string) int {
+		if cfg.Limit > 0 {
+			return len(idx.SearchWithLimit(q, cfg.Limit))
+		}
+		return len(idx.Search(q))
+	}
+	memoryUsage := idx.MemoryUsage
+	var cached *rs.CachedIndex
+	if cfg.Cached {
+		var cleanup func()
+		cached, res.FileBytes, cleanup, err = openCached(idx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		idx = nil
+		search = func(q string) int { return len(cached.Search(q)) }
+		memoryUsage = cached.MemoryUsage
+	}
+
+	samples, elapsed, err := run(ctx, cfg, search, res)
+	res.Elapsed = elapsed
+	res.IndexBytes = memoryUsage()
+	if cached != nil {
+		res.CachedAfter = cached.CacheSize()
+	}
+
+	var all []time.Duration
+	for i, q := range cfg.Queries {
+		res.Queries[i].Query = q
+		res.Queries[i].Latency = summarize(samples[i])
+		all = append(all, samples[i]...)
+	}
+	res.Searches = len(all)
+	res.Latency = summarize(all)
+	if elapsed > 0 {
+		res.Throughput = float64(res.Searches) / elapsed.Seconds()
+	}
+	return res, err
+}
+
+// build indexes cfg.Docs documents, flushing every cfg.BatchSize.
+func build(ctx context.Context, cfg Config) (*rs.Index, error) {
+	idx := rs.NewIndex(cfg.GramSize, cfg.IndexOptions...)
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	batch := idx.BatchSize(min(cfg.BatchSize, cfg.Docs))
+	for i := 0; i < cfg.Docs; i++ {
+		batch.Add(uint32(i), cfg.Document(rng, uint32(i)))
+		if batch.Len() >= cfg.BatchSize {
+			if err := batch.FlushContext(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := batch.FlushContext(ctx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// openCached saves idx to cfg.Dir and opens it as a CachedIndex. cleanup
+// closes it and removes the file.
+func openCached(idx *rs.Index, cfg Config) (cached *rs.CachedIndex, size int64, cleanup func(), err error) {
+	dir := cfg.Dir
+	removeDir := false
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "roaringsearch-bench-*"); err != nil {
+			return nil, 0, nil, fmt.Errorf("create bench dir: %w", err)
+		}
+		removeDir = true
+	}
+	path := filepath.Join(dir, "bench.idx")
+	cleanup = func() {
+		if cached != nil {
+			cached.Close()
+		}
+		os.Remove(path)
+		if removeDir {
+			os.RemoveAll(dir)
+		}
+	}
+
+	if err = idx.SaveToFile(path); err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(path); err == nil {
+			size = info.Size()
+			cached, err = rs.OpenCachedIndex(path, cfg.CacheOptions...)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return cached, size, cleanup, nil
+}
+
+// run performs the searches, returning the durations of each query of the
+// mix and the wall time of the whole run.
+func run(ctx context.Context, cfg Config, search func(string) int, res *Result) ([][]time.Duration, time.Duration, error) {
+	res.Queries = make([]QueryResult, len(cfg.Queries))
+	for i, q := range cfg.Queries {
+		res.Queries[i].Matches = search(q) // also warms caches
+	}
+
+	type sample struct {
+		query int
+		d     time.Duration
+	}
+	perWorker := make([][]sample, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := range perWorker {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < cfg.Iterations; i += cfg.Concurrency {
+				if ctx.Err() != nil {
+					return
+				}
+				q := i % len(cfg.Queries)
+				t := time.Now()
+				search(cfg.Queries[q])
+				perWorker[w] = append(perWorker[w], sample{q, time.Since(t)})
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	samples := make([][]time.Duration, len(cfg.Queries))
+	for _, worker := range perWorker {
+		for _, s := range worker {
+			samples[s.query] = append(samples[s.query], s.d)
+		}
+	}
+	return samples, elapsed, ctx.Err()
+}
+
+// summarize computes the latency summary of durations, sorting them in place.
+func summarize(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	slices.Sort(durations)
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	at := func(p float64) time.Duration {
+		return durations[min(int(p*float64(len(durations))), len(durations)-1)]
+	}
+	return Latency{
+		Min:  durations[0],
+		Mean: total / time.Duration(len(durations)),
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P99:  at(0.99),
+		P999: at(0.999),
+		Max:  durations[len(durations)-1],
+	}
+}
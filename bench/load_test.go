@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+func TestRunLoadTest(t *testing.T) {
+	res, err := RunLoadTest(context.Background(), Config{
+		Docs:        2000,
+		Seed:        42,
+		Iterations:  200,
+		Concurrency: 4,
+		BatchSize:   500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Docs != 2000 || res.Ngrams == 0 || res.Searches != 200 || res.HeapBytes == 0 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Queries) != len(DefaultQueries) || res.Queries[0].Matches == 0 {
+		t.Errorf("queries = %+v", res.Queries)
+	}
+	l := res.Latency
+	if l.Min > l.P50 || l.P50 > l.P90 || l.P90 > l.P99 || l.P99 > l.Max || l.Max == 0 {
+		t.Errorf("latency percentiles out of order: %+v", l)
+	}
+	if !strings.Contains(res.String(), "2000 docs") {
+		t.Errorf("String() = %s", res.String())
+	}
+}
+
+func TestRunLoadTestCached(t *testing.T) {
+	res, err := RunLoadTest(context.Background(), Config{
+		Docs:       1000,
+		Iterations: 50,
+		Queries:    []string{"server", "zzzz"},
+		Document: func(_ *rand.Rand, docID uint32) string {
+			if docID%10 == 0 {
+				return "server room"
+			}
+			return "other text"
+		},
+		Cached:       true,
+		CacheOptions: []rs.CachedIndexOption{rs.WithCacheSize(2)},
+		Dir:          t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Queries[0].Matches != 100 || res.Queries[1].Matches != 0 {
+		t.Errorf("matches = %+v", res.Queries)
+	}
+	if res.FileBytes == 0 || res.CachedAfter == 0 || res.CachedAfter > 2 {
+		t.Errorf("file %d bytes, %d cached bitmaps", res.FileBytes, res.CachedAfter)
+	}
+}
+
+func TestRunLoadTestCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := RunLoadTest(ctx, Config{
+		Docs:       100,
+		Iterations: 1 << 30,
+		Document: func(_ *rand.Rand, _ uint32) string {
+			return "hello world"
+		},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+	if res == nil || res.Searches == 0 || res.Searches >= 1<<30 {
+		t.Errorf("canceled run = %+v", res)
+	}
+}
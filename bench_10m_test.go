@@ -239,6 +239,7 @@ func BenchmarkCachedSearch10M_ColdCache(b *testing.B) {
 			b.Fatalf("Failed to open cached index: %v", err)
 		}
 		cached.Search(queries[i%len(queries)])
+		cached.Close()
 	}
 }
 
@@ -252,6 +253,7 @@ func BenchmarkCachedSearch10M_WarmCache(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to open cached index: %v", err)
 	}
+	defer cached.Close()
 
 	queries := []string{"server", "client", "database"}
 
@@ -0,0 +1,256 @@
+package roaringsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseBleveQuery converts a Bleve query in its JSON form into a Query, so
+// code already building Bleve requests can use an Index for candidate
+// generation:
+//
+//	q, err := roaringsearch.ParseBleveQuery(req.Query) // req.Query is json.RawMessage
+//	candidates := idx.SearchQueryBitmap(q)
+//
+// Supported queries:
+//
+//	{"match": "..."}                 words ORed ("operator": "and" ANDs them)
+//	{"match_phrase": "..."}          phrase
+//	{"term": "..."}                  single term
+//	{"query": "..."}                 query string: +must -must_not should "phrase"
+//	{"conjuncts": [...]}             AND
+//	{"disjuncts": [...], "min": N}   OR; N must be 0, 1 or the number of disjuncts
+//	{"must": ..., "should": ..., "must_not": ...}
+//	{"match_all": {}}, {"match_none": {}}
+//
+// The index has a single text field, so "field" prefixes and parameters are
+// ignored, as are "boost" and "fuzziness". Matching is by n-gram containment,
+// which makes every result a superset of what Bleve's analyzed match would
+// return. Other query types wrap ErrUnsupportedQuery; malformed JSON wraps
+// ErrQuerySyntax.
+func ParseBleveQuery(data []byte) (*Query, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQuerySyntax, err)
+	}
+
+	var str string
+	switch {
+	case raw["match_all"] != nil:
+		return &Query{Op: QueryAll}, nil
+	case raw["match_none"] != nil:
+		return &Query{Op: QueryOr}, nil
+	case raw["match"] != nil:
+		if err := json.Unmarshal(raw["match"], &str); err != nil {
+			return nil, bleveFieldError("match", err)
+		}
+		op := QueryOr
+		if bleveOperator(raw) == "and" {
+			op = QueryAnd
+		}
+		return bleveWords(op, str), nil
+	case raw["match_phrase"] != nil:
+		if err := json.Unmarshal(raw["match_phrase"], &str); err != nil {
+			return nil, bleveFieldError("match_phrase", err)
+		}
+		return &Query{Op: QueryTerm, Text: str, Phrase: true}, nil
+	case raw["term"] != nil:
+		if err := json.Unmarshal(raw["term"], &str); err != nil {
+			return nil, bleveFieldError("term", err)
+		}
+		return &Query{Op: QueryTerm, Text: str}, nil
+	case raw["query"] != nil:
+		if err := json.Unmarshal(raw["query"], &str); err != nil {
+			return nil, bleveFieldError("query", err)
+		}
+		return parseBleveQueryString(str)
+	case raw["conjuncts"] != nil:
+		clauses, err := parseBleveClauses(raw["conjuncts"])
+		if err != nil {
+			return nil, err
+		}
+		if len(clauses) == 0 {
+			return &Query{Op: QueryAll}, nil
+		}
+		return combineQuery(QueryAnd, clauses), nil
+	case raw["disjuncts"] != nil:
+		return parseBleveDisjunction(raw)
+	case raw["must"] != nil || raw["should"] != nil || raw["must_not"] != nil:
+		return parseBleveBoolean(raw)
+	}
+
+	for name := range raw {
+		if name != "field" && name != "boost" {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedQuery, name)
+		}
+	}
+	return nil, fmt.Errorf("%w: empty query", ErrQuerySyntax)
+}
+
+func bleveFieldError(name string, err error) error {
+	return fmt.Errorf("%w: %s: %v", ErrQuerySyntax, name, err)
+}
+
+// bleveOperator returns the lower-cased "operator" of a match query. Bleve
+// accepts both the names and the numeric values 0 (or) and 1 (and).
+func bleveOperator(raw map[string]json.RawMessage) string {
+	switch strings.ToLower(strings.Trim(string(raw["operator"]), `"`)) {
+	case "and", "1":
+		return "and"
+	}
+	return "or"
+}
+
+// bleveWords joins the whitespace-separated words of text under op.
+func bleveWords(op QueryOp, text string) *Query {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return &Query{Op: QueryOr}
+	}
+	clauses := make([]*Query, len(words))
+	for i, w := range words {
+		clauses[i] = &Query{Op: QueryTerm, Text: w}
+	}
+	return combineQuery(op, clauses)
+}
+
+func parseBleveClauses(data json.RawMessage) ([]*Query, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("%w: expected an array of queries: %v", ErrQuerySyntax, err)
+	}
+	clauses := make([]*Query, len(items))
+	for i, item := range items {
+		q, err := ParseBleveQuery(item)
+		if err != nil {
+			return nil, err
+		}
+		clauses[i] = q
+	}
+	return clauses, nil
+}
+
+func parseBleveDisjunction(raw map[string]json.RawMessage) (*Query, error) {
+	clauses, err := parseBleveClauses(raw["disjuncts"])
+	if err != nil {
+		return nil, err
+	}
+	var minMatch int
+	if raw["min"] != nil {
+		if err := json.Unmarshal(raw["min"], &minMatch); err != nil {
+			return nil, bleveFieldError("min", err)
+		}
+	}
+	switch {
+	case minMatch > len(clauses):
+		return &Query{Op: QueryOr}, nil
+	case minMatch > 1 && minMatch == len(clauses):
+		return combineQuery(QueryAnd, clauses), nil
+	case minMatch > 1:
+		return nil, fmt.Errorf("%w: disjunction min %d of %d", ErrUnsupportedQuery, minMatch, len(clauses))
+	case len(clauses) == 0:
+		return &Query{Op: QueryOr}, nil
+	}
+	return combineQuery(QueryOr, clauses), nil
+}
+
+// parseBleveBoolean converts a boolean query. As in Bleve, should clauses only
+// restrict the matches when there are no must clauses.
+func parseBleveBoolean(raw map[string]json.RawMessage) (*Query, error) {
+	var clauses []*Query
+	if raw["must"] != nil {
+		q, err := ParseBleveQuery(raw["must"])
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, q)
+	}
+	if raw["should"] != nil && raw["must"] == nil {
+		q, err := ParseBleveQuery(raw["should"])
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, q)
+	}
+	if raw["must_not"] != nil {
+		q, err := ParseBleveQuery(raw["must_not"])
+		if err != nil {
+			return nil, err
+		}
+		if q.Op == QueryOr {
+			// Exclude each disjunct rather than the complement of their union
+			for _, c := range q.Clauses {
+				clauses = append(clauses, &Query{Op: QueryNot, Clauses: []*Query{c}})
+			}
+		} else {
+			clauses = append(clauses, &Query{Op: QueryNot, Clauses: []*Query{q}})
+		}
+	}
+	if len(clauses) == 0 {
+		return &Query{Op: QueryAll}, nil
+	}
+	return combineQuery(QueryAnd, clauses), nil
+}
+
+// parseBleveQueryString converts Bleve's query string syntax: clauses
+// prefixed with '+' are required, with '-' excluded, and the rest optional,
+// with at least one optional clause required when nothing else is. Quoted
+// text is a phrase and "field:" prefixes are dropped.
+func parseBleveQueryString(s string) (*Query, error) {
+	var must, should, mustNot []*Query
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			i++
+			continue
+		}
+
+		start := i
+		target := &should
+		switch c {
+		case '+':
+			target = &must
+			i++
+		case '-':
+			target = &mustNot
+			i++
+		}
+		// Drop a field prefix, leaving the (possibly quoted) text
+		if colon := strings.IndexByte(s[i:], ':'); colon > 0 && !strings.ContainsAny(s[i:i+colon], " \t\n\r\"") {
+			i += colon + 1
+		}
+
+		var term *Query
+		if i < len(s) && s[i] == '"' {
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quote at %d", ErrQuerySyntax, i)
+			}
+			term = &Query{Op: QueryTerm, Text: s[i+1 : i+1+end], Phrase: true}
+			i += end + 2
+		} else {
+			wordStart := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r", rune(s[i])) {
+				i++
+			}
+			if wordStart == i {
+				return nil, fmt.Errorf("%w: empty clause at %d", ErrQuerySyntax, start)
+			}
+			term = &Query{Op: QueryTerm, Text: s[wordStart:i]}
+		}
+		*target = append(*target, term)
+	}
+
+	clauses := must
+	if len(must) == 0 && len(should) > 0 {
+		clauses = append(clauses, combineQuery(QueryOr, should))
+	}
+	for _, q := range mustNot {
+		clauses = append(clauses, &Query{Op: QueryNot, Clauses: []*Query{q}})
+	}
+	if len(clauses) == 0 {
+		return &Query{Op: QueryOr}, nil
+	}
+	return combineQuery(QueryAnd, clauses), nil
+}
@@ -0,0 +1,69 @@
+package roaringsearch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseBleveQuery(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, "the lazy brown dog")
+	idx.Add(3, testHelloWorld)
+	idx.Add(4, "a quick hello")
+
+	tests := []struct {
+		query string
+		want  []uint32
+	}{
+		{`{"match": "fox hello"}`, []uint32{1, 3, 4}},
+		{`{"match": "quick hello", "operator": "and"}`, []uint32{4}},
+		{`{"match": "quick hello", "field": "title", "operator": 1}`, []uint32{4}},
+		{`{"match_phrase": "brown fox"}`, []uint32{1}},
+		{`{"term": "lazy"}`, []uint32{1, 2}},
+		{`{"query": "+brown -fox"}`, []uint32{2}},
+		{`{"query": "fox world"}`, []uint32{1, 3}},
+		{`{"query": "+quick fox"}`, []uint32{1, 4}},
+		{`{"query": "title:\"brown dog\""}`, []uint32{2}},
+		{`{"conjuncts": [{"term": "quick"}, {"term": "brown"}]}`, []uint32{1}},
+		{`{"disjuncts": [{"term": "lazy"}, {"term": "world"}], "min": 1}`, []uint32{1, 2, 3}},
+		{`{"disjuncts": [{"term": "quick"}, {"term": "hello"}], "min": 2}`, []uint32{4}},
+		{`{"must": {"conjuncts": [{"term": "brown"}]}, "must_not": {"disjuncts": [{"term": "fox"}]}}`, []uint32{2}},
+		{`{"should": {"disjuncts": [{"term": "fox"}, {"term": "world"}]}, "must_not": {"disjuncts": [{"term": "lazy"}]}}`, []uint32{3}},
+		{`{"must_not": {"disjuncts": [{"term": "hello"}, {"term": "fox"}]}}`, []uint32{2}},
+		{`{"match_all": {}}`, []uint32{1, 2, 3, 4}},
+		{`{"match_none": {}}`, nil},
+	}
+	for _, tt := range tests {
+		q, err := ParseBleveQuery([]byte(tt.query))
+		if err != nil {
+			t.Errorf("ParseBleveQuery(%s) error: %v", tt.query, err)
+			continue
+		}
+		if got := idx.SearchQuery(q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseBleveQuery(%s) matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseBleveQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  error
+	}{
+		{`not json`, ErrQuerySyntax},
+		{`{}`, ErrQuerySyntax},
+		{`{"match": 3}`, ErrQuerySyntax},
+		{`{"conjuncts": {"term": "x"}}`, ErrQuerySyntax},
+		{`{"query": "\"open"}`, ErrQuerySyntax},
+		{`{"prefix": "qui"}`, ErrUnsupportedQuery},
+		{`{"conjuncts": [{"term": "x"}, {"wildcard": "q*"}]}`, ErrUnsupportedQuery},
+		{`{"disjuncts": [{"term": "a"}, {"term": "b"}, {"term": "c"}], "min": 2}`, ErrUnsupportedQuery},
+	}
+	for _, tt := range tests {
+		if _, err := ParseBleveQuery([]byte(tt.query)); !errors.Is(err, tt.want) {
+			t.Errorf("ParseBleveQuery(%s) error = %v, want %v", tt.query, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package roaringsearch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BlockReader is the random-access storage a CachedIndex reads its file
+// from. *os.File implements it; wrap other backends (mmap, object storage
+// range requests, decryption) to serve an index from them. ReadAt must be safe
+// for concurrent use.
+type BlockReader interface {
+	io.ReaderAt
+}
+
+// readFullAt reads exactly len(p) bytes at off, accepting the io.EOF that
+// ReadAt may return alongside a full read at the end of the data.
+func readFullAt(r BlockReader, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// DefaultCacheBlockSize is the block size of a BlockCache.
+const DefaultCacheBlockSize = 1 << 20
+
+// BlockCache is a second-tier cache in front of a slow BlockReader: blocks
+// read from the origin are kept as files in a local directory (e.g. on SSD),
+// so a remote-origin index is only fetched once across restarts. The
+// in-memory bitmap cache of CachedIndex stays the first tier.
+//
+// The directory must be dedicated to one origin; delete it when the origin
+// changes. Concurrent misses of the same block may both fetch it.
+//
+// Example:
+//
+//	origin := newS3Reader(bucket, "index.bin") // any io.ReaderAt
+//	idx, err := OpenCachedIndexReader(NewBlockCache(origin, "/mnt/ssd/index.cache", 0))
+type BlockCache struct {
+	origin    BlockReader
+	dir       string
+	blockSize int64
+}
+
+// NewBlockCache caches blocks of origin in dir, creating it on first use.
+// blockSize <= 0 uses DefaultCacheBlockSize.
+func NewBlockCache(origin BlockReader, dir string, blockSize int) *BlockCache {
+	if blockSize <= 0 {
+		blockSize = DefaultCacheBlockSize
+	}
+	return &BlockCache{origin: origin, dir: dir, blockSize: int64(blockSize)}
+}
+
+// ReadAt implements BlockReader, reading whole blocks from the local
+// directory or, on a miss, from the origin.
+func (c *BlockCache) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("block cache: negative offset %d", off)
+	}
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		block, err := c.block(pos / c.blockSize)
+		if err != nil {
+			return n, err
+		}
+		start := pos % c.blockSize
+		if start >= int64(len(block)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], block[start:])
+		if int64(len(block)) < c.blockSize && n < len(p) {
+			return n, io.EOF // short block: end of the origin
+		}
+	}
+	return n, nil
+}
+
+// block returns block i, fetching and storing it on a miss.
+func (c *BlockCache) block(i int64) ([]byte, error) {
+	path := filepath.Join(c.dir, fmt.Sprintf("%016x.blk", i))
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("block cache: %w", err)
+	}
+
+	data = make([]byte, c.blockSize)
+	n, err := c.origin.ReadAt(data, i*c.blockSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	data = data[:n]
+
+	// Storing is best effort: a full or read-only disk only costs refetches
+	if err := os.MkdirAll(c.dir, 0o755); err == nil {
+		tmp, err := os.CreateTemp(c.dir, "*.tmp")
+		if err == nil {
+			_, werr := tmp.Write(data)
+			cerr := tmp.Close()
+			if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+				os.Remove(tmp.Name())
+			}
+		}
+	}
+	return data, nil
+}
+
+// Close closes the origin if it implements io.Closer. Cached blocks are kept.
+func (c *BlockCache) Close() error {
+	if closer, ok := c.origin.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// countingReader is an in-memory origin counting the bytes read from it.
+type countingReader struct {
+	r      *bytes.Reader
+	read   atomic.Int64
+	closed bool
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestOpenCachedIndexReader(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	origin := &countingReader{r: bytes.NewReader(buf.Bytes())}
+	cached, err := OpenCachedIndexReader(origin)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	if got := cached.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search(hello) = %v, want [1 2]", got)
+	}
+	if err := cached.Close(); err != nil || !origin.closed {
+		t.Errorf("Close = %v, origin closed = %v", err, origin.closed)
+	}
+
+	if _, err := OpenCachedIndexReader(bytes.NewReader(buf.Bytes()[:20])); err == nil {
+		t.Error("truncated index opened without error")
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 500; i++ {
+		idx.Add(i, testQuickBrownFox)
+	}
+	idx.Add(1000, testHelloWorld)
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	dir := filepath.Join(t.TempDir(), "blocks")
+	origin := &countingReader{r: bytes.NewReader(data)}
+	cache := NewBlockCache(origin, dir, 64)
+
+	// Reads spanning blocks and the short final block match the origin
+	for _, span := range [][2]int{{0, 10}, {60, 10}, {0, len(data)}, {len(data) - 5, 5}} {
+		got := make([]byte, span[1])
+		if err := readFullAt(cache, got, int64(span[0])); err != nil {
+			t.Fatalf("read %v: %v", span, err)
+		}
+		if !bytes.Equal(got, data[span[0]:span[0]+span[1]]) {
+			t.Errorf("read %v returned different bytes", span)
+		}
+	}
+	if err := readFullAt(cache, make([]byte, 10), int64(len(data)-5)); err == nil {
+		t.Error("read past the end succeeded")
+	}
+	fetched := origin.read.Load()
+	if fetched > int64(len(data)+64) {
+		t.Errorf("origin read %d bytes for a %d byte file", fetched, len(data))
+	}
+
+	// A new cache over the same directory never touches the origin
+	origin2 := &countingReader{r: bytes.NewReader(data)}
+	cached, err := OpenCachedIndexReader(NewBlockCache(origin2, dir, 64))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("hello"); !reflect.DeepEqual(got, []uint32{1000}) {
+		t.Errorf("Search(hello) = %v, want [1000]", got)
+	}
+	if n := origin2.read.Load(); n != 0 {
+		t.Errorf("warm block cache read %d bytes from the origin", n)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != (len(data)+63)/64 {
+		t.Errorf("%d block files for %d bytes", len(entries), len(data))
+	}
+}
@@ -0,0 +1,450 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"io"
+	"math/bits"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/freeeve/msgpck"
+)
+
+// BitSlicedIndex stores 64-bit unsigned values as one roaring bitmap per bit.
+// Range comparisons, sums, and top-K queries run as bitmap operations over
+// at most 64 slices instead of scanning values document by document.
+//
+// Example:
+//
+//	prices := NewBitSlicedIndex()
+//	prices.Set(1, 1999)
+//	prices.Set(2, 4999)
+//
+//	cheap := prices.LessThan(2500, nil)              // bitmap of docs < 2500
+//	mid := prices.Between(1000, 5000, filtered)      // restricted to a bitmap
+//	total, count := prices.Sum(filtered)
+type BitSlicedIndex struct {
+	mu     sync.RWMutex
+	exists *roaring.Bitmap   // docs that have a value
+	slices []*roaring.Bitmap // slices[i] holds docs whose value has bit i set
+	dirty  atomic.Bool
+}
+
+// NewBitSlicedIndex creates a new empty bit-sliced index.
+func NewBitSlicedIndex() *BitSlicedIndex {
+	return &BitSlicedIndex{
+		exists: roaring.New(),
+	}
+}
+
+// Set sets the value for a document, replacing any previous value.
+func (b *BitSlicedIndex) Set(docID uint32, value uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setLocked(docID, value)
+}
+
+func (b *BitSlicedIndex) setLocked(docID uint32, value uint64) {
+	for len(b.slices) < bits.Len64(value) {
+		b.slices = append(b.slices, roaring.New())
+	}
+
+	for i, bm := range b.slices {
+		if value&(1<<uint(i)) != 0 {
+			bm.Add(docID)
+		} else {
+			bm.Remove(docID)
+		}
+	}
+	b.exists.Add(docID)
+	b.dirty.Store(true)
+}
+
+// Get returns the value for a document and whether it has one.
+func (b *BitSlicedIndex) Get(docID uint32) (uint64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.getLocked(docID)
+}
+
+func (b *BitSlicedIndex) getLocked(docID uint32) (uint64, bool) {
+	if !b.exists.Contains(docID) {
+		return 0, false
+	}
+	var value uint64
+	for i, bm := range b.slices {
+		if bm.Contains(docID) {
+			value |= 1 << uint(i)
+		}
+	}
+	return value, true
+}
+
+// Remove removes a document's value.
+func (b *BitSlicedIndex) Remove(docID uint32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.exists.Remove(docID)
+	for _, bm := range b.slices {
+		bm.Remove(docID)
+	}
+	b.dirty.Store(true)
+}
+
+// RemoveMany removes the values of a batch of documents with one AndNot per slice.
+func (b *BitSlicedIndex) RemoveMany(docIDs []uint32) {
+	if len(docIDs) == 0 {
+		return
+	}
+	b.removeBitmap(roaring.BitmapOf(docIDs...))
+}
+
+func (b *BitSlicedIndex) removeBitmap(victims *roaring.Bitmap) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.exists.AndNot(victims)
+	for _, bm := range b.slices {
+		bm.AndNot(victims)
+	}
+	b.dirty.Store(true)
+}
+
+// Exists returns a copy of the bitmap of documents that have a value.
+func (b *BitSlicedIndex) Exists() *roaring.Bitmap {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.exists.Clone()
+}
+
+// Count returns the number of documents that have a value.
+func (b *BitSlicedIndex) Count() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.exists.GetCardinality()
+}
+
+// BitDepth returns the number of bit slices currently stored.
+func (b *BitSlicedIndex) BitDepth() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.slices)
+}
+
+// baseLocked returns the documents with a value, restricted to filter when non-nil.
+func (b *BitSlicedIndex) baseLocked(filter *roaring.Bitmap) *roaring.Bitmap {
+	if filter == nil {
+		return b.exists.Clone()
+	}
+	return roaring.And(b.exists, filter)
+}
+
+// compareLocked splits the base set into documents less than, equal to,
+// and greater than value, walking the slices from the most significant bit.
+func (b *BitSlicedIndex) compareLocked(value uint64, filter *roaring.Bitmap) (lt, eq, gt *roaring.Bitmap) {
+	eq = b.baseLocked(filter)
+	lt = roaring.New()
+	gt = roaring.New()
+
+	// value has bits above the highest slice: every stored value is smaller
+	if bits.Len64(value) > len(b.slices) {
+		return eq, roaring.New(), gt
+	}
+
+	for i := len(b.slices) - 1; i >= 0 && !eq.IsEmpty(); i-- {
+		bm := b.slices[i]
+		if value&(1<<uint(i)) != 0 {
+			lt.Or(roaring.AndNot(eq, bm))
+			eq.And(bm)
+		} else {
+			gt.Or(roaring.And(eq, bm))
+			eq.AndNot(bm)
+		}
+	}
+	return lt, eq, gt
+}
+
+// Equal returns documents whose value equals value.
+// If filter is non-nil, only documents in filter are considered.
+func (b *BitSlicedIndex) Equal(value uint64, filter *roaring.Bitmap) *roaring.Bitmap {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, eq, _ := b.compareLocked(value, filter)
+	return eq
+}
+
+// GreaterThan returns documents whose value is strictly greater than value.
+func (b *BitSlicedIndex) GreaterThan(value uint64, filter *roaring.Bitmap) *roaring.Bitmap {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, _, gt := b.compareLocked(value, filter)
+	return gt
+}
+
+// GreaterThanOrEqual returns documents whose value is >= value.
+func (b *BitSlicedIndex) GreaterThanOrEqual(value uint64, filter *roaring.Bitmap) *roaring.Bitmap {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, eq, gt := b.compareLocked(value, filter)
+	gt.Or(eq)
+	return gt
+}
+
+// LessThan returns documents whose value is strictly less than value.
+func (b *BitSlicedIndex) LessThan(value uint64, filter *roaring.Bitmap) *roaring.Bitmap {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	lt, _, _ := b.compareLocked(value, filter)
+	return lt
+}
+
+// LessThanOrEqual returns documents whose value is <= value.
+func (b *BitSlicedIndex) LessThanOrEqual(value uint64, filter *roaring.Bitmap) *roaring.Bitmap {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	lt, eq, _ := b.compareLocked(value, filter)
+	lt.Or(eq)
+	return lt
+}
+
+// Between returns documents whose value is in the inclusive range [lo, hi].
+func (b *BitSlicedIndex) Between(lo, hi uint64, filter *roaring.Bitmap) *roaring.Bitmap {
+	if lo > hi {
+		return roaring.New()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, eqLo, gtLo := b.compareLocked(lo, filter)
+	gtLo.Or(eqLo)
+
+	ltHi, eqHi, _ := b.compareLocked(hi, gtLo)
+	ltHi.Or(eqHi)
+	return ltHi
+}
+
+// Sum returns the sum of values and the number of documents summed.
+// If filter is non-nil, only documents in filter are included.
+// The sum wraps on uint64 overflow.
+func (b *BitSlicedIndex) Sum(filter *roaring.Bitmap) (sum, count uint64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	base := b.exists
+	if filter != nil {
+		base = roaring.And(b.exists, filter)
+	}
+
+	for i, bm := range b.slices {
+		sum += bm.AndCardinality(base) << uint(i)
+	}
+	return sum, base.GetCardinality()
+}
+
+// TopK returns the k documents with the largest values, sorted descending.
+// Ties at the boundary are broken by lowest docID.
+// If filter is non-nil, only documents in filter are considered.
+func (b *BitSlicedIndex) TopK(k int, filter *roaring.Bitmap) []SortedResult[uint64] {
+	if k <= 0 {
+		return nil
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// greater holds docs definitely in the top-K; candidates are tied so far.
+	greater := roaring.New()
+	candidates := b.baseLocked(filter)
+	if candidates.IsEmpty() {
+		return nil
+	}
+
+	for i := len(b.slices) - 1; i >= 0; i-- {
+		withBit := roaring.And(candidates, b.slices[i])
+		n := greater.GetCardinality() + withBit.GetCardinality()
+		switch {
+		case n > uint64(k):
+			candidates = withBit
+		case n < uint64(k):
+			greater.Or(withBit)
+			candidates.AndNot(b.slices[i])
+		default:
+			greater.Or(withBit)
+			candidates = roaring.New()
+		}
+		if candidates.IsEmpty() {
+			break
+		}
+	}
+
+	// Fill remaining slots from the tied candidates in docID order
+	need := k - int(greater.GetCardinality())
+	it := candidates.Iterator()
+	for need > 0 && it.HasNext() {
+		greater.Add(it.Next())
+		need--
+	}
+
+	results := make([]SortedResult[uint64], 0, greater.GetCardinality())
+	greater.Iterate(func(docID uint32) bool {
+		value, _ := b.getLocked(docID)
+		results = append(results, SortedResult[uint64]{DocID: docID, Value: value})
+		return true
+	})
+
+	slices.SortStableFunc(results, func(x, y SortedResult[uint64]) int {
+		switch {
+		case x.Value > y.Value:
+			return -1
+		case x.Value < y.Value:
+			return 1
+		}
+		return 0
+	})
+	return results
+}
+
+// MemoryUsage returns the total memory used by all bitmaps in bytes.
+func (b *BitSlicedIndex) MemoryUsage() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	total := b.exists.GetSizeInBytes()
+	for _, bm := range b.slices {
+		total += bm.GetSizeInBytes()
+	}
+	return total
+}
+
+// bitSlicedIndexData is the serializable representation.
+// Slices holds the serialized slice bitmaps back to back, in bit order.
+type bitSlicedIndexData struct {
+	Exists []byte `msgpack:"exists"`
+	Depth  int    `msgpack:"depth"`
+	Slices []byte `msgpack:"slices"`
+}
+
+// SaveToFile saves the bit-sliced index to a file atomically.
+// Writes to a temp file first, then renames to prevent corruption on crash.
+func (b *BitSlicedIndex) SaveToFile(path string) error {
+	if !b.dirty.Load() {
+		if _, err := os.Stat(path); err == nil {
+			return nil // File exists and no changes - safe to skip
+		}
+		// File doesn't exist, must create it even if not dirty
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := b.Encode(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	b.dirty.Store(false)
+	return nil
+}
+
+// Encode writes the bit-sliced index to a writer.
+// Takes a snapshot of the data first to avoid holding the lock during I/O.
+func (b *BitSlicedIndex) Encode(w io.Writer) error {
+	b.mu.RLock()
+	existsBytes, err := b.exists.ToBytes()
+	if err != nil {
+		b.mu.RUnlock()
+		return err
+	}
+	var slicesBuf bytes.Buffer
+	for _, bm := range b.slices {
+		if _, err := bm.WriteTo(&slicesBuf); err != nil {
+			b.mu.RUnlock()
+			return err
+		}
+	}
+	data := bitSlicedIndexData{
+		Exists: existsBytes,
+		Depth:  len(b.slices),
+		Slices: slicesBuf.Bytes(),
+	}
+	b.mu.RUnlock()
+
+	enc := msgpck.GetStructEncoder[bitSlicedIndexData]()
+	encoded, err := enc.Encode(&data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(encoded)
+	return err
+}
+
+// LoadBitSlicedIndex loads a bit-sliced index from a file.
+func LoadBitSlicedIndex(path string) (*BitSlicedIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadBitSlicedIndex(file)
+}
+
+// ReadBitSlicedIndex reads a bit-sliced index from a reader.
+func ReadBitSlicedIndex(r io.Reader) (*BitSlicedIndex, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var data bitSlicedIndexData
+	dec := msgpck.GetStructDecoder[bitSlicedIndexData](false)
+	if err := dec.Decode(raw, &data); err != nil {
+		return nil, err
+	}
+	if data.Depth < 0 || data.Depth > 64 {
+		return nil, ErrInvalidCount
+	}
+
+	b := NewBitSlicedIndex()
+	if len(data.Exists) > 0 {
+		if err := b.exists.UnmarshalBinary(data.Exists); err != nil {
+			return nil, err
+		}
+	}
+
+	b.slices = make([]*roaring.Bitmap, data.Depth)
+	sliceReader := bytes.NewReader(data.Slices)
+	for i := range b.slices {
+		bm := roaring.New()
+		if _, err := bm.ReadFrom(sliceReader); err != nil {
+			return nil, err
+		}
+		b.slices[i] = bm
+	}
+
+	return b, nil
+}
@@ -0,0 +1,144 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func newTestBSI() *BitSlicedIndex {
+	b := NewBitSlicedIndex()
+	b.Set(1, 10)
+	b.Set(2, 20)
+	b.Set(3, 30)
+	b.Set(4, 20)
+	b.Set(5, 0)
+	return b
+}
+
+func TestBitSlicedIndexGetSet(t *testing.T) {
+	b := newTestBSI()
+
+	if v, ok := b.Get(3); !ok || v != 30 {
+		t.Errorf("Get(3) = %d, %v, want 30, true", v, ok)
+	}
+	if v, ok := b.Get(5); !ok || v != 0 {
+		t.Errorf("Get(5) = %d, %v, want 0, true", v, ok)
+	}
+	if _, ok := b.Get(99); ok {
+		t.Error("Get(99) should report missing")
+	}
+
+	// Overwrite with a smaller value clears old bits
+	b.Set(3, 1)
+	if v, _ := b.Get(3); v != 1 {
+		t.Errorf("Get(3) after overwrite = %d, want 1", v)
+	}
+
+	b.Remove(3)
+	if _, ok := b.Get(3); ok {
+		t.Error("Get(3) after Remove should report missing")
+	}
+	if b.Count() != 4 {
+		t.Errorf("Count = %d, want 4", b.Count())
+	}
+}
+
+func TestBitSlicedIndexCompare(t *testing.T) {
+	b := newTestBSI()
+
+	tests := []struct {
+		name string
+		got  *roaring.Bitmap
+		want []uint32
+	}{
+		{"Equal", b.Equal(20, nil), []uint32{2, 4}},
+		{"GreaterThan", b.GreaterThan(10, nil), []uint32{2, 3, 4}},
+		{"GreaterThanOrEqual", b.GreaterThanOrEqual(20, nil), []uint32{2, 3, 4}},
+		{"LessThan", b.LessThan(20, nil), []uint32{1, 5}},
+		{"LessThanOrEqual", b.LessThanOrEqual(10, nil), []uint32{1, 5}},
+		{"Between", b.Between(10, 20, nil), []uint32{1, 2, 4}},
+		{"BetweenFiltered", b.Between(10, 30, roaring.BitmapOf(1, 3)), []uint32{1, 3}},
+		{"LessThanHuge", b.LessThan(1<<40, nil), []uint32{1, 2, 3, 4, 5}},
+		{"GreaterThanHuge", b.GreaterThan(1<<40, nil), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.got.ToArray()
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBitSlicedIndexSumAndTopK(t *testing.T) {
+	b := newTestBSI()
+
+	sum, count := b.Sum(nil)
+	if sum != 80 || count != 5 {
+		t.Errorf("Sum(nil) = %d, %d, want 80, 5", sum, count)
+	}
+
+	sum, count = b.Sum(roaring.BitmapOf(2, 3, 99))
+	if sum != 50 || count != 2 {
+		t.Errorf("Sum(filter) = %d, %d, want 50, 2", sum, count)
+	}
+
+	top := b.TopK(3, nil)
+	want := []SortedResult[uint64]{{DocID: 3, Value: 30}, {DocID: 2, Value: 20}, {DocID: 4, Value: 20}}
+	if !reflect.DeepEqual(top, want) {
+		t.Errorf("TopK(3) = %v, want %v", top, want)
+	}
+
+	// Tie at the boundary keeps the lowest docID
+	top = b.TopK(2, nil)
+	want = []SortedResult[uint64]{{DocID: 3, Value: 30}, {DocID: 2, Value: 20}}
+	if !reflect.DeepEqual(top, want) {
+		t.Errorf("TopK(2) = %v, want %v", top, want)
+	}
+
+	if top := b.TopK(10, roaring.BitmapOf(1, 5)); len(top) != 2 || top[0].DocID != 1 {
+		t.Errorf("TopK(filter) = %v, want [1 5]", top)
+	}
+}
+
+func TestBitSlicedIndexPersistence(t *testing.T) {
+	b := newTestBSI()
+	path := filepath.Join(t.TempDir(), "prices.bsi")
+
+	if err := b.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, err := LoadBitSlicedIndex(path)
+	if err != nil {
+		t.Fatalf("LoadBitSlicedIndex failed: %v", err)
+	}
+
+	for docID := uint32(1); docID <= 5; docID++ {
+		want, _ := b.Get(docID)
+		if got, ok := loaded.Get(docID); !ok || got != want {
+			t.Errorf("loaded Get(%d) = %d, %v, want %d", docID, got, ok, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewBitSlicedIndex().Encode(&buf); err != nil {
+		t.Fatalf("Encode empty failed: %v", err)
+	}
+	empty, err := ReadBitSlicedIndex(&buf)
+	if err != nil {
+		t.Fatalf("ReadBitSlicedIndex empty failed: %v", err)
+	}
+	if empty.Count() != 0 {
+		t.Errorf("empty Count = %d, want 0", empty.Count())
+	}
+}
@@ -3,12 +3,14 @@ package roaringsearch
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"os"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/RoaringBitmap/roaring/v2"
 )
@@ -19,26 +21,60 @@ type CachedIndex struct {
 	mu         sync.RWMutex
 	gramSize   int
 	normalizer Normalizer
-	filePath   string
+	chain      NormalizerChain // set by WithCachedNormalizerChain or read from the file
+	reader     *readerRef
 
 	// LRU cache
-	cache         map[uint64]*lruEntry
-	lruHead       *lruEntry // most recently used
-	lruTail       *lruEntry // least recently used
-	maxCache      int       // max number of bitmaps (0 = unlimited when using memory budget)
-	maxMemory     int64     // max memory in bytes (0 = use maxCache instead)
-	currentMemory uint64    // current memory usage in bytes
+	lru bitmapLRU[uint64]
 
 	// Index of n-gram positions in file for lazy loading
 	ngramIndex map[uint64]ngramLocation
-}
 
-type lruEntry struct {
-	key    uint64
-	bitmap *roaring.Bitmap
-	size   uint64 // memory size of bitmap
-	prev   *lruEntry
-	next   *lruEntry
+	// Exact key dictionary of files written with WithExactKeys
+	exact *ngramDict
+
+	// Bigrams for CJK runs, for files written with WithCJKBigrams
+	cjkBigrams bool
+
+	// Described by Metadata; set by loadIndex and Refresh
+	metadata IndexMetadata
+
+	// Generation of each n-gram key changed by Refresh; absent keys are 0
+	gens map[uint64]uint64
+
+	// Whole query results, nil unless WithResultCache, and the generation
+	// of the index, bumped by every Refresh
+	results    *resultCache
+	generation uint64
+
+	// Optional TinyLFU admission and saved hot keys
+	useTinyLFU  bool
+	sketch      *frequencySketch
+	hotKeysPath string
+
+	// Pinned bitmaps stay resident and are not counted against the LRU limits
+	pinned       map[uint64]*roaring.Bitmap
+	pinnedMemory uint64
+
+	// Shared memory budget across structures; nil unless WithResourceManager
+	manager *ResourceManager
+
+	// Most docIDs Search and SearchAny return; 0 is unlimited
+	maxResults int
+
+	// What Search does about an n-gram absent from the index
+	missingNgrams MissingNgramPolicy
+
+	// Largest filter checked on disk by SearchAndFilter; 0 disables pushdown
+	pushdownLimit int
+
+	// Container layout of large bitmaps, from files written with
+	// WithContainerDirectory
+	containers containerDirectory
+
+	// Optional logger and the duration from which it logs a search
+	logger    *slog.Logger
+	slowQuery time.Duration
 }
 
 type ngramLocation struct {
@@ -46,67 +82,148 @@ type ngramLocation struct {
 	size   uint32 // size of bitmap data
 }
 
-// CachedIndexOption configures a CachedIndex.
-type CachedIndexOption func(*CachedIndex)
+// CachedIndexOption configures a CachedIndex. SharedOptions are
+// CachedIndexOptions too.
+type CachedIndexOption interface {
+	applyCached(*CachedIndex)
+}
+
+// cachedIndexOption adapts a func to a CachedIndexOption.
+type cachedIndexOption func(*CachedIndex)
+
+func (f cachedIndexOption) applyCached(idx *CachedIndex) { f(idx) }
 
 // WithCacheSize sets the maximum number of bitmaps to keep in memory.
 // Default is 1000.
 func WithCacheSize(n int) CachedIndexOption {
-	return func(idx *CachedIndex) {
-		if n > 0 {
-			idx.maxCache = n
-		}
-	}
+	return cachedIndexOption(func(idx *CachedIndex) {
+		idx.lru.setMaxEntries(n)
+	})
 }
 
 // WithMemoryBudget sets the maximum memory (in bytes) for cached bitmaps.
 // When set, maxCache count is ignored and eviction is based purely on memory.
 // Example: WithMemoryBudget(100 * 1024 * 1024) for 100MB limit.
 func WithMemoryBudget(bytes int64) CachedIndexOption {
-	return func(idx *CachedIndex) {
-		if bytes > 0 {
-			idx.maxMemory = bytes
-			idx.maxCache = 0 // disable count-based limit
-		}
-	}
+	return cachedIndexOption(func(idx *CachedIndex) {
+		idx.lru.setMemoryBudget(bytes)
+	})
 }
 
 // WithCachedNormalizer sets the normalizer for the cached index.
+// If the file records the normalizer it was built with and n differs,
+// OpenCachedIndex returns ErrNormalizerMismatch.
+//
+// Deprecated: WithNormalizer applies to a CachedIndex too.
 func WithCachedNormalizer(n Normalizer) CachedIndexOption {
-	return func(idx *CachedIndex) {
-		idx.normalizer = n
+	return WithNormalizer(n)
+}
+
+// WithCachedNormalizerChain sets the normalizer from a declarative chain, as
+// WithNormalizerChain does for an Index.
+//
+// Deprecated: WithNormalizerChain applies to a CachedIndex too.
+func WithCachedNormalizerChain(chain NormalizerChain) CachedIndexOption {
+	return WithNormalizerChain(chain)
+}
+
+// resolveNormalizer reconciles the configured normalizer with the one
+// recorded in the file. A file's chain is adopted when no normalizer other
+// than the default was configured; otherwise the two must match.
+func (idx *CachedIndex) resolveNormalizer(recorded string) error {
+	if recorded == "" {
+		return nil
+	}
+	if normalizerID(idx.normalizer, idx.chain) == NormalizerLowercaseAlphanumeric {
+		chain, err := ParseNormalizerChain(recorded)
+		if err != nil {
+			return err
+		}
+		WithNormalizerChain(chain).applyCached(idx)
+		return nil
 	}
+	return checkNormalizer(recorded, idx.normalizer, idx.chain)
+}
+
+// NormalizerChain returns the normalizer chain in use, or nil for the default
+// or a custom Normalizer func.
+func (idx *CachedIndex) NormalizerChain() NormalizerChain {
+	return idx.chain
 }
 
 // OpenCachedIndex opens an index file for cached access.
 // Only metadata is loaded initially; bitmaps are loaded on demand.
+// The file stays open until Close is called, so callers must Close the
+// index when done with it; it is no longer reopened for every load.
 func OpenCachedIndex(path string, opts ...CachedIndexOption) (*CachedIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	idx, err := OpenCachedIndexReader(f, opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// OpenCachedIndexReader is like OpenCachedIndex but reads the index through r,
+// so it can be served from mmap, remote or encrypted storage, or through a
+// BlockCache. If r implements io.Closer, Close closes it.
+func OpenCachedIndexReader(r BlockReader, opts ...CachedIndexOption) (*CachedIndex, error) {
 	idx := &CachedIndex{
-		filePath:   path,
+		reader:     &readerRef{BlockReader: r},
 		normalizer: NormalizeLowercaseAlphanumeric,
-		cache:      make(map[uint64]*lruEntry),
+		lru:        newBitmapLRU[uint64](1000),
 		ngramIndex: make(map[uint64]ngramLocation),
-		maxCache:   1000,
 	}
 
 	for _, opt := range opts {
-		opt(idx)
+		opt.applyCached(idx)
+	}
+	if idx.useTinyLFU {
+		idx.sketch = newFrequencySketch(idx.lru.maxEntries)
 	}
 
 	if err := idx.loadIndex(); err != nil {
 		return nil, err
 	}
+	if idx.manager != nil {
+		idx.lru.share(idx.manager, &idx.mu)
+	}
+	if idx.logger != nil {
+		idx.logEvictions()
+		idx.logger.Info("roaringsearch: opened cached index",
+			"ngrams", len(idx.ngramIndex), "gramSize", idx.gramSize)
+	}
+
+	if idx.hotKeysPath != "" {
+		if err := idx.loadHotKeys(); err != nil {
+			idx.lru.leave()
+			return nil, err
+		}
+	}
 
 	return idx, nil
 }
 
+// Close closes the underlying reader if it implements io.Closer, and
+// releases the index's share of a ResourceManager.
+func (idx *CachedIndex) Close() error {
+	idx.lru.leave()
+	idx.mu.RLock()
+	r := idx.reader
+	idx.mu.RUnlock()
+	if c, ok := r.BlockReader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // loadIndex reads the file and builds an index of n-gram locations without loading bitmaps.
 func (idx *CachedIndex) loadIndex() error {
-	f, err := os.Open(idx.filePath)
-	if err != nil {
-		return fmt.Errorf("open file: %w", err)
-	}
-	defer f.Close()
+	f := io.NewSectionReader(idx.reader, 0, math.MaxInt64)
 
 	// Read header
 	header := make([]byte, 8)
@@ -118,13 +235,28 @@ func (idx *CachedIndex) loadIndex() error {
 		return ErrInvalidMagic
 	}
 
+	// The forward index section of later versions is not needed for search
 	fileVersion := binary.LittleEndian.Uint16(header[4:6])
-	if fileVersion != version {
+	if fileVersion < version || fileVersion > versionExtended {
 		return ErrInvalidVersion
 	}
 
 	idx.gramSize = int(binary.LittleEndian.Uint16(header[6:8]))
 
+	ext, extRead, err := readExtensions(f, fileVersion)
+	if err != nil {
+		return err
+	}
+	idx.exact = ext.exact
+	idx.cjkBigrams = ext.flags&flagCJKBigrams != 0
+	idx.metadata = ext.metadata
+	idx.metadata.FormatVersion = fileVersion
+	idx.metadata.GramSize = idx.gramSize
+	idx.metadata.Normalizer = ext.normalizer
+	if err := idx.resolveNormalizer(ext.normalizer); err != nil {
+		return err
+	}
+
 	// Read n-gram count
 	countBuf := make([]byte, 4)
 	if _, err := io.ReadFull(f, countBuf); err != nil {
@@ -132,42 +264,66 @@ func (idx *CachedIndex) loadIndex() error {
 	}
 	ngramCount := binary.LittleEndian.Uint32(countBuf)
 
-	// Build index of n-gram locations
-	// Format: key(8) + size(4) + bitmap_data(size)
-	currentOffset := int64(12) // header(8) + count(4)
+	idx.ngramIndex, err = readNgramLocations(f, ngramCount, 12+extRead) // header(8) + extensions + count(4)
+	if err != nil {
+		return err
+	}
+	idx.metadata.NgramCount = len(idx.ngramIndex)
 
+	if ext.flags&flagContainers != 0 {
+		dir, _, err := readContainerDirectory(f)
+		if err != nil {
+			return err
+		}
+		for key, d := range dir {
+			loc, ok := idx.ngramIndex[key]
+			if !ok {
+				return fmt.Errorf("container directory: unknown ngram key %d", key)
+			}
+			d.r, d.loc = idx.reader, loc
+		}
+		idx.containers = dir
+	}
+
+	return nil
+}
+
+// readNgramLocations reads the key and size of count n-gram entries starting
+// at offset, seeking past each bitmap.
+// Format: key(8) + size(4) + bitmap_data(size)
+func readNgramLocations(f io.ReadSeeker, count uint32, offset int64) (map[uint64]ngramLocation, error) {
+	locations := make(map[uint64]ngramLocation)
 	keyBuf := make([]byte, 8)
 	sizeBuf := make([]byte, 4)
 
-	for i := uint32(0); i < ngramCount; i++ {
+	for i := uint32(0); i < count; i++ {
 		// Read n-gram key
 		if _, err := io.ReadFull(f, keyBuf); err != nil {
-			return fmt.Errorf("read ngram key: %w", err)
+			return nil, fmt.Errorf("read ngram key: %w", err)
 		}
 		key := binary.LittleEndian.Uint64(keyBuf)
-		currentOffset += 8
+		offset += 8
 
 		// Read bitmap size
 		if _, err := io.ReadFull(f, sizeBuf); err != nil {
-			return fmt.Errorf("read bitmap size: %w", err)
+			return nil, fmt.Errorf("read bitmap size: %w", err)
 		}
 		bmSize := binary.LittleEndian.Uint32(sizeBuf)
-		currentOffset += 4
+		offset += 4
 
 		// Record location (offset where bitmap data starts)
-		idx.ngramIndex[key] = ngramLocation{
-			offset: currentOffset,
+		locations[key] = ngramLocation{
+			offset: offset,
 			size:   bmSize,
 		}
 
 		// Skip bitmap data
 		if _, err := f.Seek(int64(bmSize), io.SeekCurrent); err != nil {
-			return fmt.Errorf("skip bitmap: %w", err)
+			return nil, fmt.Errorf("skip bitmap: %w", err)
 		}
-		currentOffset += int64(bmSize)
+		offset += int64(bmSize)
 	}
-
-	return nil
+	return locations, nil
 }
 
 // GramSize returns the n-gram size.
@@ -177,6 +333,8 @@ func (idx *CachedIndex) GramSize() int {
 
 // NgramCount returns the number of unique n-grams in the index.
 func (idx *CachedIndex) NgramCount() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
 	return len(idx.ngramIndex)
 }
 
@@ -184,51 +342,63 @@ func (idx *CachedIndex) NgramCount() int {
 func (idx *CachedIndex) CacheSize() int {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
-	return len(idx.cache)
+	return idx.lru.len()
 }
 
-// getBitmap retrieves a bitmap, loading from disk if necessary.
+// getBitmap retrieves a bitmap, loading from disk if necessary. A bitmap
+// that fails to load is reported as missing; see loadKey.
 func (idx *CachedIndex) getBitmap(key uint64) (*roaring.Bitmap, bool) {
+	bm, ok, _ := idx.loadKey(key)
+	return bm, ok
+}
+
+// loadKey is like getBitmap but also returns the error of a failed load.
+func (idx *CachedIndex) loadKey(key uint64) (*roaring.Bitmap, bool, error) {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
+	if idx.sketch != nil {
+		idx.sketch.increment(key)
+	}
+
+	if bm, ok := idx.pinned[key]; ok {
+		return bm, true, nil
+	}
+
 	// Check cache first
-	if entry, ok := idx.cache[key]; ok {
-		idx.moveToFront(entry)
-		return entry.bitmap, true
+	if bm, ok := idx.lru.get(key); ok {
+		return bm, true, nil
 	}
 
 	// Check if n-gram exists
 	loc, ok := idx.ngramIndex[key]
 	if !ok {
-		return nil, false
+		return nil, false, nil
 	}
 
 	// Load from disk
 	bm, err := idx.loadBitmap(loc)
 	if err != nil {
-		return nil, false
+		idx.logLoadError(key, loc, err)
+		return nil, false, fmt.Errorf("load ngram key %d: %w", key, err)
 	}
 
-	// Add to cache
-	idx.addToCache(key, bm)
+	// Add to cache unless TinyLFU prefers the entry it would evict
+	if idx.admitLocked(key, bm) {
+		idx.lru.add(key, bm)
+	}
 
-	return bm, true
+	return bm, true, nil
 }
 
 func (idx *CachedIndex) loadBitmap(loc ngramLocation) (*roaring.Bitmap, error) {
-	f, err := os.Open(idx.filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	if _, err := f.Seek(loc.offset, io.SeekStart); err != nil {
-		return nil, err
-	}
+	return loadBitmapAt(idx.reader, loc)
+}
 
+// loadBitmapAt reads the whole bitmap at loc.
+func loadBitmapAt(r BlockReader, loc ngramLocation) (*roaring.Bitmap, error) {
 	data := make([]byte, loc.size)
-	if _, err := io.ReadFull(f, data); err != nil {
+	if err := readFullAt(r, data, loc.offset); err != nil {
 		return nil, err
 	}
 
@@ -240,104 +410,19 @@ func (idx *CachedIndex) loadBitmap(loc ngramLocation) (*roaring.Bitmap, error) {
 	return bm, nil
 }
 
-func (idx *CachedIndex) addToCache(key uint64, bm *roaring.Bitmap) {
-	bmSize := bm.GetSizeInBytes()
-
-	// Evict based on memory budget or count limit
-	if idx.maxMemory > 0 {
-		// Skip caching if single bitmap exceeds entire budget
-		if bmSize > uint64(idx.maxMemory) {
-			return
-		}
-		for idx.currentMemory+bmSize > uint64(idx.maxMemory) && idx.lruTail != nil {
-			idx.evictLRU()
-		}
-	} else {
-		for len(idx.cache) >= idx.maxCache && idx.lruTail != nil {
-			idx.evictLRU()
-		}
-	}
-
-	entry := &lruEntry{
-		key:    key,
-		bitmap: bm,
-		size:   bmSize,
-	}
-
-	idx.cache[key] = entry
-	idx.currentMemory += bmSize
-	idx.addToFront(entry)
-}
-
-func (idx *CachedIndex) addToFront(entry *lruEntry) {
-	entry.prev = nil
-	entry.next = idx.lruHead
-
-	if idx.lruHead != nil {
-		idx.lruHead.prev = entry
-	}
-	idx.lruHead = entry
-
-	if idx.lruTail == nil {
-		idx.lruTail = entry
-	}
-}
-
-func (idx *CachedIndex) moveToFront(entry *lruEntry) {
-	if entry == idx.lruHead {
-		return
-	}
-
-	// Remove from current position
-	if entry.prev != nil {
-		entry.prev.next = entry.next
-	}
-	if entry.next != nil {
-		entry.next.prev = entry.prev
-	}
-	if entry == idx.lruTail {
-		idx.lruTail = entry.prev
-	}
-
-	// Add to front
-	idx.addToFront(entry)
-}
-
-func (idx *CachedIndex) evictLRU() {
-	if idx.lruTail == nil {
-		return
-	}
-
-	entry := idx.lruTail
-	delete(idx.cache, entry.key)
-	idx.currentMemory -= entry.size
-
-	if entry.prev != nil {
-		entry.prev.next = nil
-	}
-	idx.lruTail = entry.prev
-
-	if idx.lruHead == entry {
-		idx.lruHead = nil
-	}
-}
-
 // ClearCache removes all bitmaps from memory.
 func (idx *CachedIndex) ClearCache() {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	idx.cache = make(map[uint64]*lruEntry)
-	idx.lruHead = nil
-	idx.lruTail = nil
-	idx.currentMemory = 0
+	idx.lru.clear()
 }
 
 // MemoryUsage returns the current memory usage of cached bitmaps in bytes.
 func (idx *CachedIndex) MemoryUsage() uint64 {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
-	return idx.currentMemory
+	return idx.lru.memory
 }
 
 // generateKeys generates unique n-gram keys from a query.
@@ -345,6 +430,9 @@ func (idx *CachedIndex) generateKeys(query string) []uint64 {
 	normalized := idx.normalizer(query)
 	runes := []rune(normalized)
 
+	if idx.cjkBigrams {
+		return appendCJKKeys(nil, runes, idx.gramSize, idx.exact.queryKey)
+	}
 	if len(runes) < idx.gramSize {
 		return nil
 	}
@@ -353,7 +441,7 @@ func (idx *CachedIndex) generateKeys(query string) []uint64 {
 	seen := make(map[uint64]struct{})
 
 	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
+		key := idx.exact.queryKey(runes[i : i+idx.gramSize])
 		if _, ok := seen[key]; ok {
 			continue
 		}
@@ -365,28 +453,54 @@ func (idx *CachedIndex) generateKeys(query string) []uint64 {
 }
 
 // Search performs an AND search - documents containing ALL n-grams.
-func (idx *CachedIndex) Search(query string) []uint32 {
+func (idx *CachedIndex) Search(query string) (matches []uint32) {
+	if idx.logger != nil {
+		defer func(start time.Time) { idx.logQuery("Search", query, len(matches), start) }(time.Now())
+	}
+	result := idx.searchBitmap(query)
+	if result == nil || result.IsEmpty() {
+		return nil
+	}
+
+	return limitedArray(result, idx.maxResults)
+}
+
+// searchBitmap returns the AND matches of query, or nil if an n-gram is
+// missing and the MissingNgramPolicy is MissingNgramFail. A single n-gram's
+// cached bitmap is returned as is; do not modify the result.
+func (idx *CachedIndex) searchBitmap(query string) *roaring.Bitmap {
 	keys := idx.generateKeys(query)
 	if len(keys) == 0 {
 		return nil
 	}
+	if idx.results != nil {
+		return idx.cachedSearchBitmap(keys)
+	}
+	return idx.searchKeys(keys)
+}
 
+// searchKeys is searchBitmap for the query's unique n-gram keys.
+func (idx *CachedIndex) searchKeys(keys []uint64) *roaring.Bitmap {
 	bitmaps := make([]*roaring.Bitmap, 0, len(keys))
+	missing := false
 
 	for _, key := range keys {
 		bm, ok := idx.getBitmap(key)
 		if !ok {
-			return nil
+			if idx.missingNgrams == MissingNgramFail {
+				return nil
+			}
+			missing = true
+			continue
 		}
 		bitmaps = append(bitmaps, bm)
 	}
 
-	if len(bitmaps) == 0 {
-		return nil
+	if missing {
+		return partialBitmap(idx.missingNgrams, bitmaps, idx.gramSize)
 	}
-
 	if len(bitmaps) == 1 {
-		return bitmaps[0].ToArray()
+		return bitmaps[0]
 	}
 
 	// Sort by cardinality for better performance
@@ -394,16 +508,14 @@ func (idx *CachedIndex) Search(query string) []uint32 {
 		return bitmaps[i].GetCardinality() < bitmaps[j].GetCardinality()
 	})
 
-	result := roaring.FastAnd(bitmaps...)
-	if result == nil || result.IsEmpty() {
-		return nil
-	}
-
-	return result.ToArray()
+	return roaring.FastAnd(bitmaps...)
 }
 
 // SearchAny performs an OR search - documents containing ANY n-gram.
-func (idx *CachedIndex) SearchAny(query string) []uint32 {
+func (idx *CachedIndex) SearchAny(query string) (matches []uint32) {
+	if idx.logger != nil {
+		defer func(start time.Time) { idx.logQuery("SearchAny", query, len(matches), start) }(time.Now())
+	}
 	keys := idx.generateKeys(query)
 	if len(keys) == 0 {
 		return nil
@@ -421,11 +533,14 @@ func (idx *CachedIndex) SearchAny(query string) []uint32 {
 		return nil
 	}
 
-	return result.ToArray()
+	return limitedArray(result, idx.maxResults)
 }
 
 // SearchThreshold returns documents matching at least minMatches n-grams.
-func (idx *CachedIndex) SearchThreshold(query string, minMatches int) SearchResult {
+func (idx *CachedIndex) SearchThreshold(query string, minMatches int) (result SearchResult) {
+	if idx.logger != nil {
+		defer func(start time.Time) { idx.logQuery("SearchThreshold", query, len(result.DocIDs), start) }(time.Now())
+	}
 	keys := idx.generateKeys(query)
 	if len(keys) == 0 || minMatches <= 0 {
 		return SearchResult{}
@@ -435,40 +550,17 @@ func (idx *CachedIndex) SearchThreshold(query string, minMatches int) SearchResu
 		minMatches = len(keys)
 	}
 
-	counts := make(map[uint32]int)
-
+	bitmaps := make([]*roaring.Bitmap, 0, len(keys))
 	for _, key := range keys {
 		if bm, ok := idx.getBitmap(key); ok {
-			it := bm.Iterator()
-			for it.HasNext() {
-				docID := it.Next()
-				counts[docID]++
-			}
+			bitmaps = append(bitmaps, bm)
 		}
 	}
-
-	var docIDs []uint32
-	scores := make(map[uint32]int)
-
-	for docID, count := range counts {
-		if count >= minMatches {
-			docIDs = append(docIDs, docID)
-			scores[docID] = count
-		}
+	if len(bitmaps) == 0 {
+		return SearchResult{}
 	}
 
-	// Sort by score desc, then docID asc
-	sort.Slice(docIDs, func(i, j int) bool {
-		if scores[docIDs[i]] != scores[docIDs[j]] {
-			return scores[docIDs[i]] > scores[docIDs[j]]
-		}
-		return docIDs[i] < docIDs[j]
-	})
-
-	return SearchResult{
-		DocIDs: docIDs,
-		Scores: scores,
-	}
+	return thresholdResult(matchLevels(bitmaps), minMatches, 0)
 }
 
 // HasNgram checks if an n-gram exists in the index without loading it.
@@ -477,25 +569,12 @@ func (idx *CachedIndex) HasNgram(ngram string) bool {
 	if len(runes) != idx.gramSize {
 		return false
 	}
-	key := runeNgramKey(runes)
-	_, ok := idx.ngramIndex[key]
+	_, ok := idx.location(idx.exact.queryKey(runes))
 	return ok
 }
 
-// PreloadKeys loads specific n-gram keys into cache.
+// PreloadKeys loads specific n-gram keys into cache, reading from disk in parallel.
+// Use NgramKey to compute the key for an n-gram string.
 func (idx *CachedIndex) PreloadKeys(keys []uint64) error {
-	var errs []error
-
-	for _, key := range keys {
-		if _, ok := idx.getBitmap(key); !ok {
-			if _, exists := idx.ngramIndex[key]; exists {
-				errs = append(errs, fmt.Errorf("failed to load key: %d", key))
-			}
-		}
-	}
-
-	if len(errs) > 0 {
-		return errors.Join(errs...)
-	}
-	return nil
+	return idx.preloadConcurrent(keys)
 }
@@ -27,6 +27,7 @@ func TestCachedIndexBasic(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Verify metadata
 	if cached.GramSize() != 3 {
@@ -74,6 +75,7 @@ func TestCachedIndexLRUEviction(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Search for different terms to fill cache
 	cached.Search("abc")
@@ -105,6 +107,7 @@ func TestCachedIndexSearchAny(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	results := cached.SearchAny("app")
 	if len(results) != 1 || results[0] != 1 {
@@ -128,6 +131,7 @@ func TestCachedIndexSearchThreshold(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	result := cached.SearchThreshold("hello", 2)
 	if len(result.DocIDs) != 2 {
@@ -152,6 +156,7 @@ func TestCachedIndexMoveToFront(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Search for different terms to populate cache
 	cached.Search("hello")
@@ -183,6 +188,7 @@ func TestCachedIndexClearCache(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Populate cache
 	cached.Search("hello")
@@ -218,6 +224,7 @@ func TestCachedIndexPreload(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Preload by searching (populates cache)
 	cached.Search("hello")
@@ -244,6 +251,7 @@ func TestWithCachedNormalizer(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Search should work with normalizer
 	results := cached.Search("HELLO")
@@ -268,6 +276,7 @@ func TestPreloadKeys(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Get some valid keys from the index
 	var keys []uint64
@@ -306,6 +315,7 @@ func TestHasNgram(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to open cached index: %v", err)
 	}
+	defer cached.Close()
 
 	if !cached.HasNgram("hel") {
 		t.Error("expected HasNgram(hel) to return true")
@@ -332,6 +342,7 @@ func TestCachedIndexSearchEdgeCases(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Short query
 	results := cached.Search("he")
@@ -394,6 +405,7 @@ func TestCachedIndexEviction(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Search multiple times to fill and evict cache
 	cached.Search("alpha")
@@ -420,6 +432,7 @@ func TestCachedIndexSingleEntryEviction(t *testing.T) {
 
 	// Cache size 1 - every new search evicts
 	cached, _ := OpenCachedIndex(path, WithCacheSize(1))
+	defer cached.Close()
 
 	cached.Search("hello")
 	if cached.CacheSize() != 1 {
@@ -454,6 +467,7 @@ func TestWithMemoryBudget(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Initial memory should be 0
 	if cached.MemoryUsage() != 0 {
@@ -516,6 +530,7 @@ func TestMemoryBudgetRespected(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	// Track max memory seen
 	var maxMemory uint64
@@ -583,6 +598,7 @@ func TestMemoryBudgetWithLargeBitmaps(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	t.Logf("Index has %d ngrams", cached.NgramCount())
 
@@ -633,6 +649,7 @@ func TestMemoryBudgetLargeBitmaps(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	t.Logf("Index has %d ngrams", cached.NgramCount())
 
@@ -686,6 +703,7 @@ func TestMemoryBudgetManyUniqueQueries(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	totalNgrams := cached.NgramCount()
 	t.Logf("Index has %d ngrams, budget %d bytes", totalNgrams, memoryBudget)
@@ -749,6 +767,7 @@ func TestMemoryBudgetOversizedBitmap(t *testing.T) {
 	if err != nil {
 		t.Fatalf(errOpenCachedIndex, err)
 	}
+	defer cached.Close()
 
 	t.Logf("Index has %d ngrams, budget %d bytes", cached.NgramCount(), memoryBudget)
 
@@ -787,6 +806,7 @@ func TestCachedIndexSearchAnyPartialMatch(t *testing.T) {
 	idx.SaveToFile(path)
 
 	cached, _ := OpenCachedIndex(path)
+	defer cached.Close()
 
 	// SearchAny with partial match - "hello xyz" has some ngrams that exist
 	results := cached.SearchAny("hello xyz")
@@ -807,6 +827,7 @@ func BenchmarkCachedSearch(b *testing.B) {
 	idx.SaveToFile(path)
 
 	cached, _ := OpenCachedIndex(path, WithCacheSize(100))
+	defer cached.Close()
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,375 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+const (
+	columnMagicBytes = "FTSC"
+	columnVersion    = 1
+	columnHeaderSize = 16
+)
+
+// PagedValue is the set of fixed-size value types that can be stored in a
+// paged column file and read back with CachedSortColumn.
+type PagedValue interface {
+	~int8 | ~int16 | ~int32 | ~int64 |
+		~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// SavePagedSortColumn saves a sort column to a file atomically in a paged
+// binary format that CachedSortColumn can read one page at a time.
+// Pages whose values are all zero are omitted.
+//
+// Format: magic(4) + version(2) + kind(1) + value size(1) + max docID(4) +
+// page count(4), then page IDs (4 each), then each page's values in order.
+func SavePagedSortColumn[T PagedValue](col *SortColumn[T], path string) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := writePagedSortColumn(col, file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// writePagedSortColumn streams the column's pages to w under the read lock.
+func writePagedSortColumn[T PagedValue](col *SortColumn[T], w io.Writer) error {
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+
+	pageIDs, page := col.pageListLocked()
+	var zero T
+
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, columnHeaderSize)
+	copy(header[0:4], columnMagicBytes)
+	binary.LittleEndian.PutUint16(header[4:6], columnVersion)
+	header[6] = byte(reflect.TypeFor[T]().Kind())
+	header[7] = byte(unsafe.Sizeof(zero))
+	binary.LittleEndian.PutUint32(header[8:12], col.maxDocID)
+	binary.LittleEndian.PutUint32(header[12:16], uint32(len(pageIDs)))
+	if _, err := bw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	idBuf := make([]byte, 4)
+	for _, id := range pageIDs {
+		binary.LittleEndian.PutUint32(idBuf, id)
+		if _, err := bw.Write(idBuf); err != nil {
+			return fmt.Errorf("write page id: %w", err)
+		}
+	}
+
+	buf := make([]byte, sortColumnPageSize*int(unsafe.Sizeof(zero)))
+	for _, id := range pageIDs {
+		if _, err := binary.Encode(buf, binary.LittleEndian, page(id)); err != nil {
+			return fmt.Errorf("encode page: %w", err)
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return fmt.Errorf("write page: %w", err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// pageListLocked returns the sorted IDs of non-zero pages and a function
+// returning a full page of values for each ID.
+func (col *SortColumn[T]) pageListLocked() ([]uint32, func(uint32) []T) {
+	if col.pages != nil {
+		ids := make([]uint32, 0, len(col.pages))
+		for id := range col.pages {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		return ids, func(id uint32) []T { return col.pages[id] }
+	}
+
+	var zero T
+	var ids []uint32
+	for start := 0; start < len(col.values); start += sortColumnPageSize {
+		end := min(start+sortColumnPageSize, len(col.values))
+		if slices.ContainsFunc(col.values[start:end], func(v T) bool { return v != zero }) {
+			ids = append(ids, uint32(start>>sortColumnPageBits))
+		}
+	}
+
+	scratch := make([]T, sortColumnPageSize)
+	return ids, func(id uint32) []T {
+		start := int(id) << sortColumnPageBits
+		end := min(start+sortColumnPageSize, len(col.values))
+		n := copy(scratch, col.values[start:end])
+		clear(scratch[n:])
+		return scratch
+	}
+}
+
+// CachedSortColumn is a read-only, disk-backed sort column that loads value
+// pages on demand and keeps recently used pages in an LRU cache. Sorting a
+// small filtered subset of a large column only reads the pages it touches.
+//
+// Example:
+//
+//	SavePagedSortColumn(ratings, "ratings.col")
+//	cached, _ := OpenCachedSortColumn[uint16]("ratings.col")
+//	defer cached.Close()
+//	top := cached.SortBitmapDesc(filtered, 10)
+type CachedSortColumn[T PagedValue] struct {
+	mu       sync.Mutex
+	file     *os.File
+	maxDocID uint32
+	offsets  map[uint32]int64 // page ID -> file offset
+
+	// LRU cache of decoded pages
+	lru lruCache[uint32, []T]
+	buf []byte // scratch buffer for reading one page
+}
+
+// cachedColumnConfig holds options for OpenCachedSortColumn.
+type cachedColumnConfig struct {
+	maxPages  int
+	maxMemory int64
+	manager   *ResourceManager
+}
+
+// CachedColumnOption configures a CachedSortColumn.
+type CachedColumnOption func(*cachedColumnConfig)
+
+// WithPageCacheSize sets the maximum number of value pages to keep in memory.
+// Default is 256.
+func WithPageCacheSize(n int) CachedColumnOption {
+	return func(cfg *cachedColumnConfig) {
+		cfg.maxPages = n
+	}
+}
+
+// WithPageMemoryBudget sets the maximum memory (in bytes) for cached pages.
+// When set, the page count limit is ignored.
+func WithPageMemoryBudget(bytes int64) CachedColumnOption {
+	return func(cfg *cachedColumnConfig) {
+		cfg.maxMemory = bytes
+	}
+}
+
+// OpenCachedSortColumn opens a file written by SavePagedSortColumn.
+// Only the page directory is read; values are loaded on demand.
+// The file stays open until Close is called.
+func OpenCachedSortColumn[T PagedValue](path string, opts ...CachedColumnOption) (*CachedSortColumn[T], error) {
+	var cfg cachedColumnConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+
+	var zero T
+	valueSize := int(unsafe.Sizeof(zero))
+	pageBytes := int64(sortColumnPageSize * valueSize)
+
+	c := &CachedSortColumn[T]{
+		file:    f,
+		offsets: make(map[uint32]int64),
+		lru: newLRUCache[uint32](256, func(page []T) uint64 {
+			return uint64(len(page) * valueSize)
+		}),
+		buf: make([]byte, pageBytes),
+	}
+	c.lru.setMaxEntries(cfg.maxPages)
+	c.lru.setMemoryBudget(cfg.maxMemory)
+
+	if err := c.loadDirectory(pageBytes); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if cfg.manager != nil {
+		c.lru.share(cfg.manager, &c.mu)
+	}
+
+	return c, nil
+}
+
+// loadDirectory reads the header and page IDs, validating them against T.
+func (c *CachedSortColumn[T]) loadDirectory(pageBytes int64) error {
+	r := bufio.NewReader(c.file)
+
+	header := make([]byte, columnHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	if string(header[0:4]) != columnMagicBytes {
+		return ErrInvalidMagic
+	}
+	if binary.LittleEndian.Uint16(header[4:6]) != columnVersion {
+		return ErrInvalidVersion
+	}
+
+	var zero T
+	if reflect.Kind(header[6]) != reflect.TypeFor[T]().Kind() || int(header[7]) != int(unsafe.Sizeof(zero)) {
+		return ErrTypeMismatch
+	}
+
+	c.maxDocID = binary.LittleEndian.Uint32(header[8:12])
+	pageCount := binary.LittleEndian.Uint32(header[12:16])
+	if pageCount > (1<<32)>>sortColumnPageBits {
+		return ErrInvalidCount
+	}
+
+	dataStart := int64(columnHeaderSize) + int64(pageCount)*4
+	idBuf := make([]byte, 4)
+	for i := uint32(0); i < pageCount; i++ {
+		if _, err := io.ReadFull(r, idBuf); err != nil {
+			return fmt.Errorf("read page id: %w", err)
+		}
+		c.offsets[binary.LittleEndian.Uint32(idBuf)] = dataStart + int64(i)*pageBytes
+	}
+
+	return nil
+}
+
+// pageLocked returns the page for pageID, loading it from disk if necessary.
+// Returns nil for pages that were omitted because all their values are zero.
+func (c *CachedSortColumn[T]) pageLocked(pageID uint32) []T {
+	if page, ok := c.lru.get(pageID); ok {
+		return page
+	}
+
+	offset, ok := c.offsets[pageID]
+	if !ok {
+		return nil
+	}
+
+	if _, err := c.file.ReadAt(c.buf, offset); err != nil {
+		return nil
+	}
+	page := make([]T, sortColumnPageSize)
+	if _, err := binary.Decode(c.buf, binary.LittleEndian, page); err != nil {
+		return nil
+	}
+
+	c.lru.add(pageID, page)
+	return page
+}
+
+// valueLocked returns the value for docID, or the zero value if unset.
+func (c *CachedSortColumn[T]) valueLocked(docID uint32) T {
+	var zero T
+	if docID > c.maxDocID {
+		return zero
+	}
+	page := c.pageLocked(docID >> sortColumnPageBits)
+	if page == nil {
+		return zero
+	}
+	return page[docID&sortColumnPageMask]
+}
+
+// Get returns the value for a document, loading its page if necessary.
+// Documents without a value return the zero value.
+func (c *CachedSortColumn[T]) Get(docID uint32) T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.valueLocked(docID)
+}
+
+// Sort sorts document IDs by their value.
+// Uses heap-based partial sort when limit is small relative to input.
+func (c *CachedSortColumn[T]) Sort(docIDs []uint32, asc bool, limit int) []SortedResult[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return sortByValue(docIDs, asc, limit, c.valueLocked)
+}
+
+// SortDesc is a convenience method for descending sort.
+func (c *CachedSortColumn[T]) SortDesc(docIDs []uint32, limit int) []SortedResult[T] {
+	return c.Sort(docIDs, false, limit)
+}
+
+// SortBitmap sorts documents from a bitmap by their value.
+func (c *CachedSortColumn[T]) SortBitmap(bm *roaring.Bitmap, asc bool, limit int) []SortedResult[T] {
+	if bm == nil || bm.IsEmpty() {
+		return nil
+	}
+	return c.Sort(bm.ToArray(), asc, limit)
+}
+
+// SortBitmapDesc is a convenience method for descending bitmap sort.
+func (c *CachedSortColumn[T]) SortBitmapDesc(bm *roaring.Bitmap, limit int) []SortedResult[T] {
+	return c.SortBitmap(bm, false, limit)
+}
+
+// MaxDocID returns the highest docID stored in the column.
+func (c *CachedSortColumn[T]) MaxDocID() uint32 {
+	return c.maxDocID
+}
+
+// PageCount returns the number of non-zero pages in the file.
+func (c *CachedSortColumn[T]) PageCount() int {
+	return len(c.offsets)
+}
+
+// CacheSize returns the current number of pages in cache.
+func (c *CachedSortColumn[T]) CacheSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.len()
+}
+
+// MemoryUsage returns the current memory usage of cached pages in bytes.
+func (c *CachedSortColumn[T]) MemoryUsage() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.memory
+}
+
+// ClearCache removes all pages from memory.
+func (c *CachedSortColumn[T]) ClearCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.clear()
+}
+
+// Close closes the underlying file and releases the column's share of a
+// ResourceManager.
+func (c *CachedSortColumn[T]) Close() error {
+	c.lru.leave()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
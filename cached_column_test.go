@@ -0,0 +1,91 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestCachedSortColumn(t *testing.T) {
+	col := NewSortColumn[uint16]()
+	for i := uint32(0); i < 20000; i++ {
+		col.Set(i, uint16(i%1000))
+	}
+
+	path := filepath.Join(t.TempDir(), "ratings.col")
+	if err := SavePagedSortColumn(col, path); err != nil {
+		t.Fatalf("SavePagedSortColumn failed: %v", err)
+	}
+
+	cached, err := OpenCachedSortColumn[uint16](path, WithPageCacheSize(2))
+	if err != nil {
+		t.Fatalf("OpenCachedSortColumn failed: %v", err)
+	}
+	defer cached.Close()
+
+	if cached.CacheSize() != 0 {
+		t.Errorf("CacheSize after open = %d, want 0", cached.CacheSize())
+	}
+	if got := cached.Get(12345); got != 345 {
+		t.Errorf("Get(12345) = %d, want 345", got)
+	}
+	if got := cached.Get(50000); got != 0 {
+		t.Errorf("Get beyond maxDocID = %d, want 0", got)
+	}
+
+	// Sorting a small subset only loads the pages it touches
+	cached.ClearCache()
+	filtered := roaring.BitmapOf(10, 999, 500)
+	results := cached.SortBitmapDesc(filtered, 2)
+	if len(results) != 2 || results[0].DocID != 999 || results[1].DocID != 500 {
+		t.Errorf("SortBitmapDesc = %v", results)
+	}
+	if cached.CacheSize() != 1 {
+		t.Errorf("CacheSize after subset sort = %d, want 1", cached.CacheSize())
+	}
+
+	// Matches the in-memory column across many pages
+	docIDs := []uint32{19999, 4096, 8191, 0, 15000}
+	want := col.Sort(docIDs, true, 0)
+	got := cached.Sort(docIDs, true, 0)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Sort[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if cached.CacheSize() > 2 {
+		t.Errorf("CacheSize = %d, exceeds limit 2", cached.CacheSize())
+	}
+}
+
+func TestCachedSortColumnSparse(t *testing.T) {
+	col := NewSortColumn[float64](WithSparseValues())
+	col.Set(7, 1.5)
+	col.Set(2_000_000_000, 9.25)
+
+	path := filepath.Join(t.TempDir(), "sparse.col")
+	if err := SavePagedSortColumn(col, path); err != nil {
+		t.Fatalf("SavePagedSortColumn failed: %v", err)
+	}
+
+	cached, err := OpenCachedSortColumn[float64](path)
+	if err != nil {
+		t.Fatalf("OpenCachedSortColumn failed: %v", err)
+	}
+	defer cached.Close()
+
+	if cached.PageCount() != 2 {
+		t.Errorf("PageCount = %d, want 2", cached.PageCount())
+	}
+	if got := cached.Get(2_000_000_000); got != 9.25 {
+		t.Errorf("Get = %v, want 9.25", got)
+	}
+	if got := cached.Get(1_000_000); got != 0 {
+		t.Errorf("Get of missing page = %v, want 0", got)
+	}
+
+	if _, err := OpenCachedSortColumn[uint64](path); err != ErrTypeMismatch {
+		t.Errorf("open with wrong type error = %v, want ErrTypeMismatch", err)
+	}
+}
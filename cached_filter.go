@@ -0,0 +1,184 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// CachedBitmapFilter is a read-only bitmap filter that keeps only recently used
+// category bitmaps in memory, loading others from disk on demand.
+// Use it for filters with many fields where only a few are queried at a time.
+type CachedBitmapFilter struct {
+	mu       sync.Mutex
+	filePath string
+
+	// LRU cache
+	lru bitmapLRU[filterEntryRef]
+
+	// Index of field/category positions in file for lazy loading
+	locations map[string]map[string]ngramLocation
+
+	// Shared memory budget; nil unless WithFilterResourceManager
+	manager *ResourceManager
+}
+
+// CachedFilterOption configures a CachedBitmapFilter.
+type CachedFilterOption func(*CachedBitmapFilter)
+
+// WithFilterCacheSize sets the maximum number of category bitmaps to keep in memory.
+// Default is 1000.
+func WithFilterCacheSize(n int) CachedFilterOption {
+	return func(c *CachedBitmapFilter) {
+		c.lru.setMaxEntries(n)
+	}
+}
+
+// WithFilterMemoryBudget sets the maximum memory (in bytes) for cached bitmaps.
+// When set, the count limit is ignored and eviction is based purely on memory.
+func WithFilterMemoryBudget(bytes int64) CachedFilterOption {
+	return func(c *CachedBitmapFilter) {
+		c.lru.setMemoryBudget(bytes)
+	}
+}
+
+// OpenCachedBitmapFilter opens a filter file written by SaveToFile or SaveField.
+// Only field and category names are loaded initially; bitmaps are loaded on demand.
+func OpenCachedBitmapFilter(path string, opts ...CachedFilterOption) (*CachedBitmapFilter, error) {
+	c := &CachedBitmapFilter{
+		filePath:  path,
+		lru:       newBitmapLRU[filterEntryRef](1000),
+		locations: make(map[string]map[string]ngramLocation),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := scanFilterEntries(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		fieldMap, ok := c.locations[e.field]
+		if !ok {
+			fieldMap = make(map[string]ngramLocation)
+			c.locations[e.field] = fieldMap
+		}
+		fieldMap[e.category] = e.loc
+	}
+	if c.manager != nil {
+		c.lru.share(c.manager, &c.mu)
+	}
+
+	return c, nil
+}
+
+// getBitmap retrieves a category bitmap, loading from disk if necessary.
+func (c *CachedBitmapFilter) getBitmap(field, category string) (*roaring.Bitmap, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ref := filterEntryRef{field: field, category: category}
+	if bm, ok := c.lru.get(ref); ok {
+		return bm, true
+	}
+
+	loc, ok := c.locations[field][category]
+	if !ok {
+		return nil, false
+	}
+
+	f, err := os.Open(c.filePath)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	bm, err := readFilterBitmap(f, loc)
+	if err != nil {
+		return nil, false
+	}
+
+	c.lru.add(ref, bm)
+	return bm, true
+}
+
+// Get returns a bitmap of documents in the given category for a field.
+// Returns nil if field or category doesn't exist.
+func (c *CachedBitmapFilter) Get(field, category string) *roaring.Bitmap {
+	bm, _ := c.getBitmap(field, category)
+	return bm
+}
+
+// GetAny returns a bitmap of documents in ANY of the given categories (OR).
+func (c *CachedBitmapFilter) GetAny(field string, categories []string) *roaring.Bitmap {
+	result := roaring.New()
+	for _, cat := range categories {
+		if bm, ok := c.getBitmap(field, cat); ok {
+			result.Or(bm)
+		}
+	}
+	return result
+}
+
+// Fields returns the names of all fields in the file.
+func (c *CachedBitmapFilter) Fields() []string {
+	fields := make([]string, 0, len(c.locations))
+	for field := range c.locations {
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Categories returns all category values for a given field without loading bitmaps.
+func (c *CachedBitmapFilter) Categories(field string) []string {
+	fieldMap, ok := c.locations[field]
+	if !ok {
+		return nil
+	}
+
+	cats := make([]string, 0, len(fieldMap))
+	for cat := range fieldMap {
+		cats = append(cats, cat)
+	}
+	return cats
+}
+
+// CacheSize returns the current number of bitmaps in cache.
+func (c *CachedBitmapFilter) CacheSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.len()
+}
+
+// MemoryUsage returns the current memory usage of cached bitmaps in bytes.
+func (c *CachedBitmapFilter) MemoryUsage() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.memory
+}
+
+// ClearCache removes all bitmaps from memory.
+func (c *CachedBitmapFilter) ClearCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.clear()
+}
+
+// Close releases the cached bitmaps and the filter's share of a
+// ResourceManager. The file is only open while bitmaps load, so Close is
+// needed only with WithFilterResourceManager.
+func (c *CachedBitmapFilter) Close() error {
+	c.lru.leave()
+	return nil
+}
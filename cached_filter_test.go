@@ -0,0 +1,90 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestFilterFile(t *testing.T) string {
+	t.Helper()
+
+	filter := NewBitmapFilter()
+	for i := uint32(0); i < 100; i++ {
+		filter.Set(i, "parity", []string{"even", "odd"}[i%2])
+		filter.Set(i, "bucket", string(rune('a'+i%10)))
+	}
+
+	path := filepath.Join(t.TempDir(), "filter.idx")
+	if err := filter.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	return path
+}
+
+func TestCachedBitmapFilterBasic(t *testing.T) {
+	cached, err := OpenCachedBitmapFilter(newTestFilterFile(t))
+	if err != nil {
+		t.Fatalf("OpenCachedBitmapFilter failed: %v", err)
+	}
+
+	if cached.CacheSize() != 0 {
+		t.Errorf("CacheSize after open = %d, want 0", cached.CacheSize())
+	}
+	if got := len(cached.Categories("bucket")); got != 10 {
+		t.Errorf("bucket categories = %d, want 10", got)
+	}
+	if got := len(cached.Fields()); got != 2 {
+		t.Errorf("Fields = %d, want 2", got)
+	}
+
+	even := cached.Get("parity", "even")
+	if even == nil || even.GetCardinality() != 50 || !even.Contains(42) {
+		t.Fatalf("even = %v, want 50 even docs", even)
+	}
+	if cached.CacheSize() != 1 {
+		t.Errorf("CacheSize after Get = %d, want 1", cached.CacheSize())
+	}
+
+	if got := cached.GetAny("bucket", []string{"a", "b", "missing"}).GetCardinality(); got != 20 {
+		t.Errorf("GetAny count = %d, want 20", got)
+	}
+	if cached.Get("parity", "missing") != nil || cached.Get("missing", "even") != nil {
+		t.Error("missing field or category should return nil")
+	}
+
+	cached.ClearCache()
+	if cached.CacheSize() != 0 || cached.MemoryUsage() != 0 {
+		t.Error("ClearCache should empty the cache")
+	}
+}
+
+func TestCachedBitmapFilterEviction(t *testing.T) {
+	cached, err := OpenCachedBitmapFilter(newTestFilterFile(t), WithFilterCacheSize(3))
+	if err != nil {
+		t.Fatalf("OpenCachedBitmapFilter failed: %v", err)
+	}
+
+	for _, cat := range cached.Categories("bucket") {
+		if cached.Get("bucket", cat) == nil {
+			t.Fatalf("bucket %q should load", cat)
+		}
+	}
+	if cached.CacheSize() != 3 {
+		t.Errorf("CacheSize = %d, want 3", cached.CacheSize())
+	}
+}
+
+func TestCachedBitmapFilterMemoryBudget(t *testing.T) {
+	const budget = 200
+	cached, err := OpenCachedBitmapFilter(newTestFilterFile(t), WithFilterMemoryBudget(budget))
+	if err != nil {
+		t.Fatalf("OpenCachedBitmapFilter failed: %v", err)
+	}
+
+	for _, cat := range cached.Categories("bucket") {
+		cached.Get("bucket", cat)
+		if cached.MemoryUsage() > budget {
+			t.Fatalf("MemoryUsage %d exceeds budget %d", cached.MemoryUsage(), budget)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package roaringsearch
+
+// cjkGramSize is the n-gram size of CJK runs under WithCJKBigrams.
+const cjkGramSize = 2
+
+// WithCJKBigrams indexes runs of CJK text (Han, Hiragana, Katakana and
+// Hangul) as bigrams and all other text at the index's gram size, so a
+// mixed Japanese and English corpus gets bigram recall for "東京" and
+// trigram precision for "tower" from one index. N-grams never span a CJK
+// and a non-CJK rune, so a run shorter than its gram size produces none.
+//
+// Bigram keys pack two runes into the upper bits, so they never equal a
+// packed ASCII n-gram; hashed n-grams keep their usual small collision risk.
+// The setting is recorded in the index file; LoadFromFile and
+// OpenCachedIndex follow it. Unlike HybridIndex, which keeps one index per
+// gram size, each document is indexed once.
+func WithCJKBigrams() Option {
+	return indexOption(func(idx *Index) {
+		idx.cjkBigrams = true
+	})
+}
+
+// appendCJKKeys appends the unique n-gram keys of runes to keys, splitting
+// them into CJK and non-CJK runs and using bigrams for CJK runs and
+// gramSize n-grams for the rest.
+func appendCJKKeys(keys []uint64, runes []rune, gramSize int, key func([]rune) uint64) []uint64 {
+	for len(runes) > 0 {
+		cjk := isCJK(runes[0])
+		n := 1
+		for n < len(runes) && isCJK(runes[n]) == cjk {
+			n++
+		}
+		size := gramSize
+		if cjk {
+			size = cjkGramSize
+		}
+		for i := 0; i <= n-size; i++ {
+			keys = appendKeyDedup(keys, key(runes[i:i+size]))
+		}
+		runes = runes[n:]
+	}
+	return keys
+}
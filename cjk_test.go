@@ -0,0 +1,72 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func cjkIndex() *Index {
+	idx := NewIndex(3, WithCJKBigrams())
+	idx.Add(1, "東京タワー Tokyo Tower")
+	idx.Add(2, "京都 Kyoto tower")
+	idx.Add(3, "東京 station")
+	idx.Add(4, testHelloWorld)
+	return idx
+}
+
+func TestWithCJKBigrams(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []uint32
+	}{
+		{"東京", []uint32{1, 3}},
+		{"京都", []uint32{2}},
+		{"タワー", []uint32{1}},
+		{"tower", []uint32{1, 2}},
+		{"東京tower", []uint32{1}}, // runs are matched separately
+		{"東", nil},               // shorter than a bigram
+		{"hello", []uint32{4}},
+	}
+
+	idx := cjkIndex()
+	for _, tt := range tests {
+		if got := idx.Search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got := idx.Freeze().Search("東京"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("frozen Search = %v, want [1 3]", got)
+	}
+
+	// Without the option "東京" is shorter than a trigram
+	if got := NewIndex(3).Search("東京"); got != nil {
+		t.Errorf("Search without WithCJKBigrams = %v, want nil", got)
+	}
+}
+
+func TestCJKBigramsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cjk.sear")
+	if err := cjkIndex().SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if got := loaded.Search("東京"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("loaded Search = %v, want [1 3]", got)
+	}
+
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	for query, want := range map[string][]uint32{"東京": {1, 3}, "tower": {1, 2}} {
+		if got := cached.Search(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("cached Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// CodeIndex is an indexing profile for source code search, in the manner of
+// zoekt: trigrams over the text as is (see NormalizeIdentity), so case,
+// punctuation and whitespace are significant, plus a second trigram index
+// over individual lines that tracks where each match is. Search finds files;
+// SearchLines finds the lines to show.
+//
+// Each line is indexed as its own document under a line ID, so the line
+// index costs about as much memory as the file index. Line IDs are never
+// reused; a CodeIndex holds at most 2^32 lines over its lifetime.
+//
+// Example:
+//
+//	idx := rs.NewCodeIndex()
+//	idx.Add(1, "package main\n\nfunc Foo(x int) {}\n")
+//	idx.SearchLines("Foo(") // [{DocID: 1, Line: 3}]
+type CodeIndex struct {
+	files *Index // one document per file
+	lines *Index // one document per line, by line ID
+
+	mu      sync.RWMutex
+	lineDoc []uint32            // line ID -> docID
+	spans   map[uint32]lineSpan // docID -> its line IDs
+}
+
+// lineSpan is the contiguous range of line IDs of a document.
+type lineSpan struct {
+	first uint32
+	count uint32
+}
+
+// LineMatch is a line whose text contains every trigram of a query.
+type LineMatch struct {
+	DocID uint32
+	Line  int // 1-based line number
+}
+
+// NewCodeIndex creates a trigram CodeIndex with WithCaseSensitive. Options
+// apply to both the file and the line index, after the preset.
+func NewCodeIndex(opts ...Option) *CodeIndex {
+	opts = append([]Option{WithCaseSensitive()}, opts...)
+	return &CodeIndex{
+		files: NewIndex(3, opts...),
+		lines: NewIndex(3, opts...),
+		spans: make(map[uint32]lineSpan),
+	}
+}
+
+// Files returns the file-level index, for filters, statistics and saving.
+// Modify documents through the CodeIndex so the line index stays in sync.
+func (c *CodeIndex) Files() *Index {
+	return c.files
+}
+
+// Add indexes a file, replacing its previous text if docID was added before.
+// Lines are split on "\n", with a trailing "\r" removed.
+func (c *CodeIndex) Add(docID uint32, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.removeLinesLocked(docID) {
+		c.files.Update(docID, text)
+	} else {
+		c.files.Add(docID, text)
+	}
+
+	span := lineSpan{first: uint32(len(c.lineDoc))}
+	for line := range strings.SplitSeq(text, "\n") {
+		c.lines.Add(span.first+span.count, strings.TrimSuffix(line, "\r"))
+		c.lineDoc = append(c.lineDoc, docID)
+		span.count++
+	}
+	c.spans[docID] = span
+}
+
+// Remove removes a file and its lines.
+func (c *CodeIndex) Remove(docID uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.removeLinesLocked(docID) {
+		c.files.Remove(docID)
+	}
+}
+
+// removeLinesLocked removes a document's lines from the line index and
+// reports whether it had any.
+func (c *CodeIndex) removeLinesLocked(docID uint32) bool {
+	span, ok := c.spans[docID]
+	if !ok {
+		return false
+	}
+	lines := roaring.New()
+	lines.AddRange(uint64(span.first), uint64(span.first)+uint64(span.count))
+	c.lines.AndNot(lines)
+	delete(c.spans, docID)
+	return true
+}
+
+// Search returns the files containing all trigrams of the query, which may
+// span lines.
+func (c *CodeIndex) Search(query string) []uint32 {
+	return c.files.Search(query)
+}
+
+// SearchLines returns the lines containing all trigrams of the query,
+// ordered by docID and line. Like Search, matches are candidates: verify
+// them against the text when the query is longer than a trigram. A query
+// containing a newline matches no single line and returns nil; use Search.
+func (c *CodeIndex) SearchLines(query string) []LineMatch {
+	if strings.ContainsRune(query, '\n') {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := c.lines.Search(query)
+	if len(ids) == 0 {
+		return nil
+	}
+	matches := make([]LineMatch, len(ids))
+	for i, id := range ids {
+		docID := c.lineDoc[id]
+		matches[i] = LineMatch{DocID: docID, Line: int(id-c.spans[docID].first) + 1}
+	}
+	slices.SortFunc(matches, func(a, b LineMatch) int {
+		return cmp.Or(cmp.Compare(a.DocID, b.DocID), cmp.Compare(a.Line, b.Line))
+	})
+	return matches
+}
@@ -0,0 +1,49 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeIndex(t *testing.T) {
+	idx := NewCodeIndex()
+	idx.Add(2, "package main\r\n\r\nfunc Foo(x int) {\r\n\treturn foo(x)\r\n}\r\n")
+	idx.Add(1, "func foo(x int) int {\n\treturn Foo(x) + Foo(1)\n}\n")
+
+	if got := idx.Search("Foo("); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search(Foo() = %v, want [1 2]", got)
+	}
+	if got := idx.Search("int {\n"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("multi-line Search = %v, want [1]", got)
+	}
+
+	want := []LineMatch{{1, 2}, {2, 3}}
+	if got := idx.SearchLines("Foo("); !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchLines(Foo() = %v, want %v", got, want)
+	}
+	want = []LineMatch{{1, 1}, {2, 4}}
+	if got := idx.SearchLines("foo("); !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchLines(foo() = %v, want %v", got, want)
+	}
+	if got := idx.SearchLines("int {\n"); got != nil {
+		t.Errorf("SearchLines with a newline = %v, want nil", got)
+	}
+
+	// Re-adding a file replaces its lines
+	idx.Add(1, "// Foo(\n")
+	want = []LineMatch{{1, 1}, {2, 3}}
+	if got := idx.SearchLines("Foo("); !reflect.DeepEqual(got, want) {
+		t.Errorf("after re-add SearchLines = %v, want %v", got, want)
+	}
+	if got := idx.SearchLines("return"); !reflect.DeepEqual(got, []LineMatch{{2, 4}}) {
+		t.Errorf("old lines still found: %v", got)
+	}
+
+	idx.Remove(2)
+	if got := idx.SearchLines("Foo("); !reflect.DeepEqual(got, []LineMatch{{1, 1}}) {
+		t.Errorf("after Remove SearchLines = %v", got)
+	}
+	if got := idx.Files().DocCount(); got != 1 {
+		t.Errorf("Files().DocCount() = %d, want 1", got)
+	}
+}
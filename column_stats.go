@@ -0,0 +1,216 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// ColumnStats summarizes the values of a column, as computed by
+// SortColumnStats and FloatColumn.Stats. Min, Max and Mean are exact;
+// percentiles are approximated with a t-digest.
+type ColumnStats[T PagedValue] struct {
+	Count uint64 // number of values summarized
+	Min   T
+	Max   T
+	Mean  float64
+
+	digest *tdigest
+}
+
+// Percentile returns the approximate value below which the fraction p of the
+// values lie, with p in [0, 1]: Percentile(0.5) is the median. It returns NaN
+// if there are no values.
+func (s ColumnStats[T]) Percentile(p float64) float64 {
+	if s.digest == nil {
+		return math.NaN()
+	}
+	return s.digest.quantile(p)
+}
+
+// statsAccumulator collects ColumnStats in one pass over the values.
+type statsAccumulator[T PagedValue] struct {
+	stats ColumnStats[T]
+	sum   float64
+}
+
+func (a *statsAccumulator[T]) add(v T) {
+	s := &a.stats
+	if s.Count == 0 {
+		s.Min, s.Max = v, v
+		s.digest = newTDigest(defaultDigestCompression)
+	}
+	s.Count++
+	s.Min, s.Max = min(s.Min, v), max(s.Max, v)
+	a.sum += float64(v)
+	s.digest.add(float64(v))
+}
+
+func (a *statsAccumulator[T]) result() ColumnStats[T] {
+	if a.stats.Count > 0 {
+		a.stats.Mean = a.sum / float64(a.stats.Count)
+	}
+	return a.stats
+}
+
+// SortColumnStats returns the min, max, mean and approximate percentiles of
+// the values of col, restricted to the documents of bm, or to every document
+// of the column if bm is nil. Use it to bound range sliders or to
+// sanity-check ingested data.
+//
+// A SortColumn cannot tell an unset value from zero, so every document up to
+// the largest one set counts, with unset documents as zero; documents of bm
+// past that are skipped. Use FloatColumn.Stats when missing values must not
+// skew the result.
+//
+// Example:
+//
+//	stats := SortColumnStats(prices, results)
+//	slider := [2]uint32{stats.Min, stats.Max}
+//	median := stats.Percentile(0.5)
+func SortColumnStats[T PagedValue](col *SortColumn[T], bm *roaring.Bitmap) ColumnStats[T] {
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+
+	var acc statsAccumulator[T]
+	col.eachStoredLocked(bm, acc.add)
+	return acc.result()
+}
+
+// eachStoredLocked calls fn with the value of every document of bm the
+// column has storage for, or of every document up to the largest one set if
+// bm is nil, in one pass.
+func (col *SortColumn[T]) eachStoredLocked(bm *roaring.Bitmap, fn func(T)) {
+	if bm != nil {
+		it := bm.Iterator()
+		for it.HasNext() {
+			if v, ok := col.storedLocked(it.Next()); ok {
+				fn(v)
+			}
+		}
+		return
+	}
+
+	if col.pages == nil {
+		if len(col.values) > 0 {
+			for _, v := range col.values[:col.maxDocID+1] {
+				fn(v)
+			}
+		}
+		return
+	}
+	pageIDs := make([]uint32, 0, len(col.pages))
+	for pageID := range col.pages {
+		pageIDs = append(pageIDs, pageID)
+	}
+	slices.Sort(pageIDs)
+	for _, pageID := range pageIDs {
+		page := col.pages[pageID]
+		if last := col.maxDocID - pageID<<sortColumnPageBits; last < sortColumnPageMask {
+			page = page[:last+1]
+		}
+		for _, v := range page {
+			fn(v)
+		}
+	}
+}
+
+// storedLocked returns the value of docID and whether the column has storage
+// for it: docID is at most the largest document set, and in paged mode its
+// page exists.
+func (col *SortColumn[T]) storedLocked(docID uint32) (T, bool) {
+	var zero T
+	if docID > col.maxDocID {
+		return zero, false
+	}
+	if col.pages != nil {
+		page, ok := col.pages[docID>>sortColumnPageBits]
+		if !ok {
+			return zero, false
+		}
+		return page[docID&sortColumnPageMask], true
+	}
+	if docID >= uint32(len(col.values)) {
+		return zero, false
+	}
+	return col.values[docID], true
+}
+
+// Stats returns the min, max, mean and approximate percentiles of the values
+// of the documents of bm, or of every document with a value if bm is nil.
+// Documents without a value are skipped. See SortColumnStats.
+func (c *FloatColumn) Stats(bm *roaring.Bitmap) ColumnStats[float64] {
+	c.col.mu.RLock()
+	defer c.col.mu.RUnlock()
+
+	docs := c.col.present
+	if bm != nil {
+		docs = roaring.And(bm, c.col.present)
+	}
+	return SortColumnStats(c.col.values, docs)
+}
+
+// Histogram counts the values of the documents of bm per bucket in one pass,
+// for faceted numeric navigation such as price or date ranges. buckets are
+// strictly ascending boundaries, and the result has one more count than
+// there are boundaries: counts[0] is for values below buckets[0], counts[i]
+// for values from buckets[i-1] up to but excluding buckets[i], and the last
+// for values of at least the last boundary. A nil bm counts every document
+// of the column, as in SortColumnStats. Histogram returns nil if buckets is
+// empty or not strictly ascending.
+//
+// Example:
+//
+//	counts := prices.Histogram(results, []uint32{10, 50, 100})
+//	// counts[0]: under 10, counts[1]: 10-49, counts[2]: 50-99, counts[3]: 100+
+func (col *SortColumn[T]) Histogram(bm *roaring.Bitmap, buckets []T) []uint64 {
+	if !strictlyAscending(buckets) {
+		return nil
+	}
+
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+
+	counts := make([]uint64, len(buckets)+1)
+	col.eachStoredLocked(bm, func(v T) {
+		counts[bucketIndex(buckets, v)]++
+	})
+	return counts
+}
+
+// bucketIndex returns the number of boundaries of buckets at most v.
+func bucketIndex[T cmp.Ordered](buckets []T, v T) int {
+	i, found := slices.BinarySearch(buckets, v)
+	if found {
+		i++
+	}
+	return i
+}
+
+// strictlyAscending reports whether s is non-empty and strictly ascending.
+func strictlyAscending[T cmp.Ordered](s []T) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !(s[i-1] < s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Histogram is SortColumn.Histogram for the documents of bm that have a
+// value, or every document with a value if bm is nil.
+func (c *FloatColumn) Histogram(bm *roaring.Bitmap, buckets []float64) []uint64 {
+	c.col.mu.RLock()
+	defer c.col.mu.RUnlock()
+
+	docs := c.col.present
+	if bm != nil {
+		docs = roaring.And(bm, c.col.present)
+	}
+	return c.col.values.Histogram(docs, buckets)
+}
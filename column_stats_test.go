@@ -0,0 +1,106 @@
+package roaringsearch
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestSortColumnStats(t *testing.T) {
+	col := NewSortColumn[uint32]()
+	for docID := uint32(0); docID < 10_000; docID++ {
+		col.Set(docID, docID+1)
+	}
+
+	stats := SortColumnStats(col, nil)
+	if stats.Count != 10_000 || stats.Min != 1 || stats.Max != 10_000 || stats.Mean != 5000.5 {
+		t.Errorf("stats = %d values, min %d, max %d, mean %v", stats.Count, stats.Min, stats.Max, stats.Mean)
+	}
+	for _, tc := range []struct{ p, want float64 }{{0, 1}, {0.01, 100}, {0.5, 5000}, {0.99, 9900}, {1, 10_000}} {
+		if got := stats.Percentile(tc.p); math.Abs(got-tc.want) > 50 {
+			t.Errorf("Percentile(%v) = %v, want about %v", tc.p, got, tc.want)
+		}
+	}
+
+	// Documents past the largest one set are skipped
+	bm := roaring.BitmapOf(4, 9, 20_000)
+	stats = SortColumnStats(col, bm)
+	if stats.Count != 2 || stats.Min != 5 || stats.Max != 10 || stats.Mean != 7.5 {
+		t.Errorf("filtered stats = %d values, min %d, max %d, mean %v", stats.Count, stats.Min, stats.Max, stats.Mean)
+	}
+
+	empty := SortColumnStats(NewSortColumn[int64](), nil)
+	if empty.Count != 0 || !math.IsNaN(empty.Percentile(0.5)) {
+		t.Errorf("empty stats = %d values, median %v", empty.Count, empty.Percentile(0.5))
+	}
+}
+
+func TestSortColumnStatsSparse(t *testing.T) {
+	col := NewSortColumn[int64](WithSparseValues())
+	col.Set(1_000_000, -3)
+	col.Set(1_000_001, 7)
+
+	stats := SortColumnStats(col, nil)
+	// Unset documents of the allocated page before the largest docID count as zero
+	if want := uint64(1_000_001&sortColumnPageMask) + 1; stats.Count != want {
+		t.Errorf("Count = %d, want %d", stats.Count, want)
+	}
+	if stats.Min != -3 || stats.Max != 7 {
+		t.Errorf("min %d, max %d, want -3 and 7", stats.Min, stats.Max)
+	}
+	if got := SortColumnStats(col, roaring.BitmapOf(5, 1_000_000)); got.Count != 1 || got.Mean != -3 {
+		t.Errorf("filtered stats = %d values, mean %v", got.Count, got.Mean)
+	}
+}
+
+func TestFloatColumnStats(t *testing.T) {
+	col := NewFloatColumn()
+	col.Set(1, 2.5)
+	col.Set(2, 0)
+	col.Set(3, 7.5)
+	col.Set(10, -1)
+
+	stats := col.Stats(nil)
+	if stats.Count != 4 || stats.Min != -1 || stats.Max != 7.5 || stats.Mean != 2.25 {
+		t.Errorf("stats = %d values, min %v, max %v, mean %v", stats.Count, stats.Min, stats.Max, stats.Mean)
+	}
+	stats = col.Stats(roaring.BitmapOf(1, 3, 5))
+	if stats.Count != 2 || stats.Mean != 5 || stats.Percentile(0.5) != 5 {
+		t.Errorf("filtered stats = %d values, mean %v, median %v", stats.Count, stats.Mean, stats.Percentile(0.5))
+	}
+}
+
+func TestSortColumnHistogram(t *testing.T) {
+	prices := NewSortColumn[uint32]()
+	for docID, price := range []uint32{5, 10, 25, 49, 50, 99, 100, 500} {
+		prices.Set(uint32(docID), price)
+	}
+	buckets := []uint32{10, 50, 100}
+
+	if got := prices.Histogram(nil, buckets); !reflect.DeepEqual(got, []uint64{1, 3, 2, 2}) {
+		t.Errorf("Histogram(nil) = %v, want [1 3 2 2]", got)
+	}
+	// Documents past the largest one set are skipped
+	if got := prices.Histogram(roaring.BitmapOf(1, 2, 7, 100), buckets); !reflect.DeepEqual(got, []uint64{0, 2, 0, 1}) {
+		t.Errorf("filtered Histogram = %v, want [0 2 0 1]", got)
+	}
+	for _, bad := range [][]uint32{nil, {50, 10}, {10, 10}} {
+		if got := prices.Histogram(nil, bad); got != nil {
+			t.Errorf("Histogram(%v) = %v, want nil", bad, got)
+		}
+	}
+}
+
+func TestFloatColumnHistogram(t *testing.T) {
+	col := NewFloatColumn()
+	col.Set(1, 0.5)
+	col.Set(2, 1.5)
+	col.Set(3, 2.5)
+
+	// Documents without a value are not counted as zero
+	if got := col.Histogram(roaring.BitmapOf(1, 2, 3, 4), []float64{1, 2}); !reflect.DeepEqual(got, []uint64{1, 1, 1}) {
+		t.Errorf("Histogram = %v, want [1 1 1]", got)
+	}
+}
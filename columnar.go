@@ -0,0 +1,124 @@
+package roaringsearch
+
+// Columnar loaders take whole columns as produced by Arrow arrays and Parquet
+// column readers, taking each lock once instead of once per Set. Documents
+// are numbered by position: element i belongs to docID first+i. Nullability
+// is given by valid, called with the element index, which matches the
+// signature of Arrow's Array.IsValid; nil means every element is valid.
+//
+// Example with Apache Arrow (arrow-go):
+//
+//	rating := rec.Column(2).(*array.Float64)
+//	ratings.SetRange(first, rating.Float64Values(), rating.IsValid)
+//
+//	genre := rec.Column(3).(*array.Dictionary)
+//	dict := genre.Dictionary().(*array.String)
+//	names := make([]string, dict.Len())
+//	for i := range names {
+//		names[i] = dict.Value(i)
+//	}
+//	roaringsearch.SetDictionaryColumn(filter, "genre", first, names,
+//		genre.Indices().(*array.Int32).Int32Values(), genre.IsValid)
+
+// DictionaryIndex is the integer type of dictionary-encoded column indices.
+type DictionaryIndex interface {
+	~int8 | ~int16 | ~int32 | ~int64 |
+		~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// SetRange sets the values of docIDs first, first+1, ... from values,
+// skipping elements for which valid returns false. Dense columns copy the
+// values in one pass. Elements past docID 2^32-1 are dropped.
+func (col *SortColumn[T]) SetRange(first uint32, values []T, valid func(i int) bool) {
+	if n := uint64(1<<32 - uint64(first)); uint64(len(values)) > n {
+		values = values[:n]
+	}
+	if len(values) == 0 {
+		return
+	}
+	last := first + uint32(len(values)-1)
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	if col.pages == nil && col.shouldPageLocked(last) {
+		col.convertToPagesLocked()
+	}
+	if col.pages != nil {
+		for i, v := range values {
+			if valid == nil || valid(i) {
+				col.setPagedLocked(first+uint32(i), v)
+			}
+		}
+		return
+	}
+
+	if uint64(last) >= uint64(len(col.values)) {
+		newValues := make([]T, max(uint64(last)+1, uint64(len(col.values))*5/4, 1024))
+		copy(newValues, col.values)
+		col.values = newValues
+	}
+	dst := col.values[first : uint64(last)+1]
+	if valid == nil {
+		copy(dst, values)
+	} else {
+		for i, v := range values {
+			if valid(i) {
+				dst[i] = v
+			}
+		}
+	}
+	col.maxDocID = max(col.maxDocID, last)
+	col.dirty.Store(true)
+}
+
+// SetStrings assigns docIDs first, first+1, ... to the categories in values
+// within field, skipping elements for which valid returns false or that are
+// empty.
+func (c *BitmapFilter) SetStrings(field string, first uint32, values []string, valid func(i int) bool) {
+	positions := make(map[string][]uint32)
+	for i, v := range values {
+		if v == "" || (valid != nil && !valid(i)) || uint64(first)+uint64(i) >= 1<<32 {
+			continue
+		}
+		positions[v] = append(positions[v], first+uint32(i))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for cat, ids := range positions {
+		c.addManyLocked(field, cat, ids)
+	}
+}
+
+// SetDictionaryColumn assigns docIDs first, first+1, ... to the categories of
+// a dictionary-encoded column within field: element i belongs to category
+// dict[indices[i]]. Elements for which valid returns false, or whose index is
+// outside dict, are skipped. Each category's bitmap is built in one AddMany.
+func SetDictionaryColumn[I DictionaryIndex](c *BitmapFilter, field string, first uint32, dict []string, indices []I, valid func(i int) bool) {
+	positions := make([][]uint32, len(dict))
+	for i, ix := range indices {
+		if (valid != nil && !valid(i)) || uint64(first)+uint64(i) >= 1<<32 {
+			continue
+		}
+		if ix < 0 || uint64(ix) >= uint64(len(dict)) {
+			continue
+		}
+		positions[ix] = append(positions[ix], first+uint32(i))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ix, ids := range positions {
+		if len(ids) > 0 {
+			c.addManyLocked(field, dict[ix], ids)
+		}
+	}
+}
+
+// addManyLocked adds sorted docIDs to a category of field.
+func (c *BitmapFilter) addManyLocked(field, category string, ids []uint32) {
+	c.categoryLocked(field, category).AddMany(ids)
+	c.all.AddMany(ids)
+	c.dirty.Store(true)
+}
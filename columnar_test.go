@@ -0,0 +1,70 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortColumnSetRange(t *testing.T) {
+	ratings := NewSortColumn[float64]()
+	ratings.Set(1, 9)
+	ratings.SetRange(2, []float64{1.5, 2.5, 3.5}, nil)
+	ratings.SetRange(10, []float64{7, 8, 9}, func(i int) bool { return i != 1 })
+
+	for docID, want := range map[uint32]float64{1: 9, 2: 1.5, 4: 3.5, 10: 7, 11: 0, 12: 9} {
+		if got := ratings.Get(docID); got != want {
+			t.Errorf("Get(%d) = %v, want %v", docID, got, want)
+		}
+	}
+	if got := ratings.SortDesc([]uint32{2, 10, 12}, 0); len(got) != 3 || got[0].DocID != 12 || got[2].DocID != 2 {
+		t.Errorf("SortDesc = %v", got)
+	}
+
+	// A far-off range converts to pages like Set does
+	ratings.SetRange(1<<31, []float64{5, 6}, nil)
+	if ratings.Get(1<<31+1) != 6 || ratings.Get(3) != 2.5 {
+		t.Errorf("paged range = %v, dense value = %v", ratings.Get(1<<31+1), ratings.Get(3))
+	}
+
+	// Values past the last docID are dropped
+	ratings.SetRange(1<<32-1, []float64{1, 2}, nil)
+	if ratings.Get(1<<32-1) != 1 {
+		t.Errorf("Get(max) = %v, want 1", ratings.Get(1<<32-1))
+	}
+}
+
+func TestBitmapFilterSetStrings(t *testing.T) {
+	filter := NewBitmapFilter()
+	filter.SetStrings("genre", 10, []string{"fable", "", "poetry", "fable", "drama"}, func(i int) bool { return i != 4 })
+
+	if got := filter.Get("genre", "fable").ToArray(); !reflect.DeepEqual(got, []uint32{10, 13}) {
+		t.Errorf("fable = %v, want [10 13]", got)
+	}
+	if filter.Get("genre", "drama") != nil && !filter.Get("genre", "drama").IsEmpty() {
+		t.Error("null element added")
+	}
+	if filter.DocCount() != 3 {
+		t.Errorf("DocCount = %d, want 3", filter.DocCount())
+	}
+}
+
+func TestSetDictionaryColumn(t *testing.T) {
+	filter := NewBitmapFilter()
+	dict := []string{"en", "fr", "de"}
+	SetDictionaryColumn(filter, "lang", 100, dict, []int32{0, 1, 0, 2, -1, 7, 1}, func(i int) bool { return i != 6 })
+
+	want := map[string][]uint32{"en": {100, 102}, "fr": {101}, "de": {103}}
+	for cat, ids := range want {
+		if got := filter.Get("lang", cat).ToArray(); !reflect.DeepEqual(got, ids) {
+			t.Errorf("%s = %v, want %v", cat, got, ids)
+		}
+	}
+	if filter.DocCount() != 4 {
+		t.Errorf("DocCount = %d, want 4", filter.DocCount())
+	}
+
+	SetDictionaryColumn(filter, "lang", 200, dict, []uint8{2}, nil)
+	if !filter.Get("lang", "de").Contains(200) {
+		t.Error("uint8 indices not loaded")
+	}
+}
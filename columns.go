@@ -0,0 +1,476 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/freeeve/msgpck"
+)
+
+// nullableColumn is a SortColumn paired with a bitmap of documents that have a value.
+// It lets typed columns tell "no value" apart from the zero value.
+type nullableColumn[T cmp.Ordered] struct {
+	mu      sync.RWMutex
+	values  *SortColumn[T]
+	present *roaring.Bitmap
+	dirty   atomic.Bool
+}
+
+func newNullableColumn[T cmp.Ordered]() *nullableColumn[T] {
+	return &nullableColumn[T]{
+		values:  NewSortColumn[T](),
+		present: roaring.New(),
+	}
+}
+
+func (c *nullableColumn[T]) set(docID uint32, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values.Set(docID, value)
+	c.present.Add(docID)
+	c.dirty.Store(true)
+}
+
+func (c *nullableColumn[T]) remove(docID uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.present.CheckedRemove(docID) {
+		return
+	}
+	var zero T
+	c.values.Set(docID, zero)
+	c.dirty.Store(true)
+}
+
+func (c *nullableColumn[T]) get(docID uint32) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.present.Contains(docID) {
+		var zero T
+		return zero, false
+	}
+	return c.values.Get(docID), true
+}
+
+func (c *nullableColumn[T]) has(docID uint32) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.present.Contains(docID)
+}
+
+func (c *nullableColumn[T]) presentBitmap() *roaring.Bitmap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.present.Clone()
+}
+
+func (c *nullableColumn[T]) memoryUsage() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values.MemoryUsage() + c.present.GetSizeInBytes()
+}
+
+// sort orders documents with a value, then appends documents without one
+// (in input order) until limit is reached. Missing values sort last in both directions.
+// The second return value is the number of leading results that have a value.
+func (c *nullableColumn[T]) sort(docIDs []uint32, asc bool, limit int, missing T) ([]SortedResult[T], int) {
+	if len(docIDs) == 0 {
+		return nil, 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	have := make([]uint32, 0, len(docIDs))
+	var absent []uint32
+	for _, docID := range docIDs {
+		if c.present.Contains(docID) {
+			have = append(have, docID)
+		} else {
+			absent = append(absent, docID)
+		}
+	}
+
+	results := c.values.Sort(have, asc, limit)
+	return appendMissing(results, absent, limit, missing), len(results)
+}
+
+func (c *nullableColumn[T]) sortBitmap(bm *roaring.Bitmap, asc bool, limit int, missing T) ([]SortedResult[T], int) {
+	if bm == nil || bm.IsEmpty() {
+		return nil, 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	have := roaring.And(bm, c.present)
+	results := c.values.SortBitmap(have, asc, limit)
+	n := len(results)
+	if limit > 0 && n >= limit {
+		return results, n
+	}
+	return appendMissing(results, roaring.AndNot(bm, c.present).ToArray(), limit, missing), n
+}
+
+// appendMissing appends absent documents with the missing value, respecting limit.
+func appendMissing[T cmp.Ordered](results []SortedResult[T], absent []uint32, limit int, missing T) []SortedResult[T] {
+	for _, docID := range absent {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		results = append(results, SortedResult[T]{DocID: docID, Value: missing})
+	}
+	return results
+}
+
+// nullableColumnData is the serializable representation.
+// Value fields mirror sortColumnData.
+type nullableColumnData[T cmp.Ordered] struct {
+	Values     []T      `msgpack:"values"`
+	MaxDocID   uint32   `msgpack:"max_doc_id"`
+	PageIDs    []uint32 `msgpack:"page_ids"`
+	PageValues []T      `msgpack:"page_values"`
+	Present    []byte   `msgpack:"present"`
+}
+
+func (c *nullableColumn[T]) saveToFile(path string) error {
+	if !c.dirty.Load() {
+		if _, err := os.Stat(path); err == nil {
+			return nil // File exists and no changes - safe to skip
+		}
+		// File doesn't exist, must create it even if not dirty
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := c.encode(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	c.dirty.Store(false)
+	return nil
+}
+
+func (c *nullableColumn[T]) encode(w io.Writer) error {
+	// Snapshot data while holding locks briefly
+	c.mu.RLock()
+	presentBytes, err := c.present.ToBytes()
+	if err != nil {
+		c.mu.RUnlock()
+		return err
+	}
+	c.values.mu.RLock()
+	values := c.values.snapshotLocked()
+	c.values.mu.RUnlock()
+	c.mu.RUnlock()
+
+	data := nullableColumnData[T]{
+		Values:     values.Values,
+		MaxDocID:   values.MaxDocID,
+		PageIDs:    values.PageIDs,
+		PageValues: values.PageValues,
+		Present:    presentBytes,
+	}
+
+	enc := msgpck.GetStructEncoder[nullableColumnData[T]]()
+	encoded, err := enc.Encode(&data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(encoded)
+	return err
+}
+
+func readNullableColumn[T cmp.Ordered](r io.Reader) (*nullableColumn[T], error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var data nullableColumnData[T]
+	dec := msgpck.GetStructDecoder[nullableColumnData[T]](false)
+	if err := dec.Decode(raw, &data); err != nil {
+		return nil, err
+	}
+
+	c := newNullableColumn[T]()
+	values := sortColumnData[T]{
+		Values:     data.Values,
+		MaxDocID:   data.MaxDocID,
+		PageIDs:    data.PageIDs,
+		PageValues: data.PageValues,
+	}
+	if err := c.values.restoreLocked(&values); err != nil {
+		return nil, err
+	}
+	if len(data.Present) > 0 {
+		if err := c.present.UnmarshalBinary(data.Present); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// FloatColumn is a float64 sort column that distinguishes missing values from zero.
+// Setting NaN is treated as removing the value. Documents without a value sort
+// last in both directions and are reported with a NaN Value.
+//
+// Example:
+//
+//	scores := NewFloatColumn()
+//	scores.Set(1, 0.0)   // present, value zero
+//	scores.Set(2, 4.5)
+//	scores.Get(3)        // 0, false - no value
+//
+//	results := scores.SortBitmap(filtered, true, 10) // doc 1, doc 2, then missing docs
+type FloatColumn struct {
+	col *nullableColumn[float64]
+}
+
+// NewFloatColumn creates a new float column.
+func NewFloatColumn() *FloatColumn {
+	return &FloatColumn{col: newNullableColumn[float64]()}
+}
+
+// Set sets the value for a document. NaN removes the document's value.
+func (c *FloatColumn) Set(docID uint32, value float64) {
+	if math.IsNaN(value) {
+		c.col.remove(docID)
+		return
+	}
+	c.col.set(docID, value)
+}
+
+// Remove clears the value for a document.
+func (c *FloatColumn) Remove(docID uint32) {
+	c.col.remove(docID)
+}
+
+// Get returns the value for a document and whether it has one.
+func (c *FloatColumn) Get(docID uint32) (float64, bool) {
+	return c.col.get(docID)
+}
+
+// Has reports whether the document has a value.
+func (c *FloatColumn) Has(docID uint32) bool {
+	return c.col.has(docID)
+}
+
+// Present returns a copy of the bitmap of documents that have a value.
+func (c *FloatColumn) Present() *roaring.Bitmap {
+	return c.col.presentBitmap()
+}
+
+// MemoryUsage returns the memory used by values and the presence bitmap in bytes.
+func (c *FloatColumn) MemoryUsage() uint64 {
+	return c.col.memoryUsage()
+}
+
+// Sort sorts document IDs by value; documents without a value come last.
+func (c *FloatColumn) Sort(docIDs []uint32, asc bool, limit int) []SortedResult[float64] {
+	results, _ := c.col.sort(docIDs, asc, limit, math.NaN())
+	return results
+}
+
+// SortDesc is a convenience method for descending sort.
+func (c *FloatColumn) SortDesc(docIDs []uint32, limit int) []SortedResult[float64] {
+	return c.Sort(docIDs, false, limit)
+}
+
+// SortBitmap sorts documents from a bitmap by value; documents without a value come last.
+func (c *FloatColumn) SortBitmap(bm *roaring.Bitmap, asc bool, limit int) []SortedResult[float64] {
+	results, _ := c.col.sortBitmap(bm, asc, limit, math.NaN())
+	return results
+}
+
+// SortBitmapDesc is a convenience method for descending bitmap sort.
+func (c *FloatColumn) SortBitmapDesc(bm *roaring.Bitmap, limit int) []SortedResult[float64] {
+	return c.SortBitmap(bm, false, limit)
+}
+
+// SaveToFile saves the column to a file atomically.
+func (c *FloatColumn) SaveToFile(path string) error {
+	return c.col.saveToFile(path)
+}
+
+// Encode writes the column to a writer.
+func (c *FloatColumn) Encode(w io.Writer) error {
+	return c.col.encode(w)
+}
+
+// LoadFloatColumn loads a float column from a file.
+func LoadFloatColumn(path string) (*FloatColumn, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadFloatColumn(file)
+}
+
+// ReadFloatColumn reads a float column from a reader.
+func ReadFloatColumn(r io.Reader) (*FloatColumn, error) {
+	col, err := readNullableColumn[float64](r)
+	if err != nil {
+		return nil, err
+	}
+	return &FloatColumn{col: col}, nil
+}
+
+// TimeResult holds a document ID and its timestamp.
+// Time is the zero time.Time for documents without a value.
+type TimeResult struct {
+	DocID uint32
+	Time  time.Time
+}
+
+// TimeColumn is a timestamp sort column stored as int64 Unix nanoseconds.
+// Unlike SortColumn, it distinguishes missing values from the Unix epoch.
+// Documents without a value sort last in both directions.
+//
+// Example:
+//
+//	published := NewTimeColumn()
+//	published.Set(1, time.Now())
+//
+//	newest := published.SortBitmapDesc(filtered, 20)
+type TimeColumn struct {
+	col *nullableColumn[int64]
+}
+
+// NewTimeColumn creates a new time column.
+func NewTimeColumn() *TimeColumn {
+	return &TimeColumn{col: newNullableColumn[int64]()}
+}
+
+// Set sets the timestamp for a document.
+func (c *TimeColumn) Set(docID uint32, t time.Time) {
+	c.col.set(docID, t.UnixNano())
+}
+
+// SetUnix sets the timestamp for a document from Unix seconds.
+func (c *TimeColumn) SetUnix(docID uint32, sec int64) {
+	c.Set(docID, time.Unix(sec, 0))
+}
+
+// Remove clears the timestamp for a document.
+func (c *TimeColumn) Remove(docID uint32) {
+	c.col.remove(docID)
+}
+
+// Get returns the timestamp for a document and whether it has one.
+func (c *TimeColumn) Get(docID uint32) (time.Time, bool) {
+	nanos, ok := c.col.get(docID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// Has reports whether the document has a timestamp.
+func (c *TimeColumn) Has(docID uint32) bool {
+	return c.col.has(docID)
+}
+
+// Present returns a copy of the bitmap of documents that have a timestamp.
+func (c *TimeColumn) Present() *roaring.Bitmap {
+	return c.col.presentBitmap()
+}
+
+// MemoryUsage returns the memory used by values and the presence bitmap in bytes.
+func (c *TimeColumn) MemoryUsage() uint64 {
+	return c.col.memoryUsage()
+}
+
+// Sort sorts document IDs by timestamp; documents without one come last.
+func (c *TimeColumn) Sort(docIDs []uint32, asc bool, limit int) []TimeResult {
+	return toTimeResults(c.col.sort(docIDs, asc, limit, 0))
+}
+
+// SortDesc is a convenience method for descending (newest first) sort.
+func (c *TimeColumn) SortDesc(docIDs []uint32, limit int) []TimeResult {
+	return c.Sort(docIDs, false, limit)
+}
+
+// SortBitmap sorts documents from a bitmap by timestamp; documents without one come last.
+func (c *TimeColumn) SortBitmap(bm *roaring.Bitmap, asc bool, limit int) []TimeResult {
+	return toTimeResults(c.col.sortBitmap(bm, asc, limit, 0))
+}
+
+// SortBitmapDesc is a convenience method for descending bitmap sort.
+func (c *TimeColumn) SortBitmapDesc(bm *roaring.Bitmap, limit int) []TimeResult {
+	return c.SortBitmap(bm, false, limit)
+}
+
+// toTimeResults converts nanosecond results; entries past present have the zero time.
+func toTimeResults(results []SortedResult[int64], present int) []TimeResult {
+	if results == nil {
+		return nil
+	}
+
+	out := make([]TimeResult, len(results))
+	for i, r := range results {
+		out[i].DocID = r.DocID
+		if i < present {
+			out[i].Time = time.Unix(0, r.Value)
+		}
+	}
+	return out
+}
+
+// SaveToFile saves the column to a file atomically.
+func (c *TimeColumn) SaveToFile(path string) error {
+	return c.col.saveToFile(path)
+}
+
+// Encode writes the column to a writer.
+func (c *TimeColumn) Encode(w io.Writer) error {
+	return c.col.encode(w)
+}
+
+// LoadTimeColumn loads a time column from a file.
+func LoadTimeColumn(path string) (*TimeColumn, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadTimeColumn(file)
+}
+
+// ReadTimeColumn reads a time column from a reader.
+func ReadTimeColumn(r io.Reader) (*TimeColumn, error) {
+	col, err := readNullableColumn[int64](r)
+	if err != nil {
+		return nil, err
+	}
+	return &TimeColumn{col: col}, nil
+}
@@ -0,0 +1,126 @@
+package roaringsearch
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestFloatColumnMissingValues(t *testing.T) {
+	col := NewFloatColumn()
+	col.Set(1, 0)
+	col.Set(2, 4.5)
+	col.Set(3, -1.5)
+	col.Set(4, math.NaN()) // treated as missing
+
+	if v, ok := col.Get(1); !ok || v != 0 {
+		t.Errorf("Get(1) = %v, %v, want 0, true", v, ok)
+	}
+	if _, ok := col.Get(4); ok {
+		t.Error("NaN value should be reported as missing")
+	}
+	if _, ok := col.Get(5); ok {
+		t.Error("unset doc should be reported as missing")
+	}
+
+	// Missing docs sort last ascending, not first as zero values would
+	results := col.SortBitmap(roaring.BitmapOf(1, 2, 3, 4, 5), true, 0)
+	wantIDs := []uint32{3, 1, 2, 4, 5}
+	if len(results) != len(wantIDs) {
+		t.Fatalf("got %d results, want %d", len(results), len(wantIDs))
+	}
+	for i, r := range results {
+		if r.DocID != wantIDs[i] {
+			t.Errorf("results[%d].DocID = %d, want %d", i, r.DocID, wantIDs[i])
+		}
+	}
+	if !math.IsNaN(results[3].Value) {
+		t.Errorf("missing doc value = %v, want NaN", results[3].Value)
+	}
+
+	// Missing docs also sort last descending
+	results = col.SortDesc([]uint32{5, 1, 2}, 0)
+	if results[0].DocID != 2 || results[1].DocID != 1 || results[2].DocID != 5 {
+		t.Errorf("desc results = %v, want [2 1 5]", results)
+	}
+
+	// Limit only takes missing docs when present ones run out
+	results = col.SortBitmap(roaring.BitmapOf(1, 2, 3, 4), true, 2)
+	if len(results) != 2 || results[0].DocID != 3 || results[1].DocID != 1 {
+		t.Errorf("limited results = %v, want [3 1]", results)
+	}
+
+	col.Remove(2)
+	if col.Has(2) {
+		t.Error("Has(2) after Remove should be false")
+	}
+}
+
+func TestTimeColumn(t *testing.T) {
+	col := NewTimeColumn()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	col.Set(1, base)
+	col.Set(2, base.Add(time.Hour))
+	col.SetUnix(3, 0) // epoch is a real value, not missing
+
+	if got, ok := col.Get(3); !ok || got.Unix() != 0 {
+		t.Errorf("Get(3) = %v, %v, want epoch, true", got, ok)
+	}
+
+	results := col.SortBitmapDesc(roaring.BitmapOf(1, 2, 3, 4), 0)
+	wantIDs := []uint32{2, 1, 3, 4}
+	for i, r := range results {
+		if r.DocID != wantIDs[i] {
+			t.Errorf("results[%d].DocID = %d, want %d", i, r.DocID, wantIDs[i])
+		}
+	}
+	if !results[0].Time.Equal(base.Add(time.Hour)) {
+		t.Errorf("results[0].Time = %v, want %v", results[0].Time, base.Add(time.Hour))
+	}
+	if !results[3].Time.IsZero() {
+		t.Errorf("missing doc Time = %v, want zero", results[3].Time)
+	}
+}
+
+func TestNullableColumnPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	floats := NewFloatColumn()
+	floats.Set(1, 0)
+	floats.Set(3, 2.5)
+	floatPath := filepath.Join(tmpDir, "scores.col")
+	if err := floats.SaveToFile(floatPath); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	loadedFloats, err := LoadFloatColumn(floatPath)
+	if err != nil {
+		t.Fatalf("LoadFloatColumn failed: %v", err)
+	}
+	if v, ok := loadedFloats.Get(1); !ok || v != 0 {
+		t.Errorf("loaded Get(1) = %v, %v, want 0, true", v, ok)
+	}
+	if _, ok := loadedFloats.Get(2); ok {
+		t.Error("loaded Get(2) should be missing")
+	}
+
+	times := NewTimeColumn()
+	now := time.Now()
+	times.Set(2, now)
+	timePath := filepath.Join(tmpDir, "times.col")
+	if err := times.SaveToFile(timePath); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	loadedTimes, err := LoadTimeColumn(timePath)
+	if err != nil {
+		t.Fatalf("LoadTimeColumn failed: %v", err)
+	}
+	if got, ok := loadedTimes.Get(2); !ok || !got.Equal(now) {
+		t.Errorf("loaded Get(2) = %v, %v, want %v", got, ok, now)
+	}
+	if loadedTimes.Has(1) {
+		t.Error("loaded Has(1) should be false")
+	}
+}
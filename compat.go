@@ -0,0 +1,31 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// BitmapFrom converts a bitmap of another roaring version, such as a
+// github.com/RoaringBitmap/roaring (v1) *Bitmap, to the roaring/v2 bitmaps
+// this package takes and returns. Any src that writes the portable roaring
+// format works, so no v1 import is needed here. The reverse is the same
+// round trip: pass the v2 bitmap to the v1 bitmap's ReadFrom.
+//
+// Example:
+//
+//	filter, err := rs.BitmapFrom(v1Bitmap) // v1 *roaring.Bitmap
+//	ids := idx.SearchAndFilter("hello", filter)
+func BitmapFrom(src io.WriterTo) (*roaring.Bitmap, error) {
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("serialize bitmap: %w", err)
+	}
+	bm := roaring.New()
+	if _, err := bm.ReadFrom(&buf); err != nil {
+		return nil, fmt.Errorf("read bitmap: %w", err)
+	}
+	return bm, nil
+}
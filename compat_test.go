@@ -0,0 +1,29 @@
+package roaringsearch
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+type failingWriterTo struct{}
+
+func (failingWriterTo) WriteTo(io.Writer) (int64, error) { return 0, errors.New("boom") }
+
+func TestBitmapFrom(t *testing.T) {
+	src := roaring.BitmapOf(1, 5, 70_000, 1<<20)
+	src.AddRange(200_000, 210_000)
+	bm, err := BitmapFrom(src)
+	if err != nil {
+		t.Fatalf("BitmapFrom failed: %v", err)
+	}
+	if !bm.Equals(src) {
+		t.Errorf("BitmapFrom = %v, want %v", bm, src)
+	}
+
+	if _, err := BitmapFrom(failingWriterTo{}); err == nil {
+		t.Error("expected error from a failing source")
+	}
+}
@@ -0,0 +1,162 @@
+package roaringsearch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// maxPresizedNgrams caps the n-gram map Config.ExpectedDocs allocates.
+const maxPresizedNgrams = 1 << 20
+
+// Config describes an Index as a plain struct, for settings loaded from a
+// configuration file or shared between services. Build validates it and
+// creates the index; Options and CachedOptions convert it to the Option and
+// CachedIndexOption funcs it stands for. The zero Config is the NewIndex(3)
+// default.
+//
+// Example:
+//
+//	cfg := rs.ProductCatalogConfig()
+//	cfg.MaxResults = 500
+//	idx, err := cfg.Build()
+type Config struct {
+	GramSize      int                // 1-8; 0 selects 3
+	Normalizer    NormalizerChain    // nil for NormalizeLowercaseAlphanumeric; see WithNormalizerChain
+	ForwardIndex  bool               // see WithForwardIndex
+	DocLengths    bool               // see WithDocLengths
+	ExactKeys     bool               // see WithExactKeys
+	CJKBigrams    bool               // see WithCJKBigrams
+	ContainerDir  bool               // see WithContainerDirectory
+	Compress      bool               // run-length optimize before saving; see WithOptimizeOnSave
+	Deterministic bool               // see WithDeterministicBuild
+	MaxResults    int                // 0 for no cap; see WithMaxResults
+	MissingNgrams MissingNgramPolicy // see WithMissingNgramPolicy
+	ExpectedDocs  int                // sizes the n-gram map up front; 0 to grow it as needed
+}
+
+// LogSearchConfig is a preset for log lines: lowercase trigrams that keep
+// punctuation, so paths, IPs and key=value pairs stay searchable, with a
+// container directory for serving large files through OpenCachedIndex and
+// a result cap for queries as broad as "error".
+func LogSearchConfig() Config {
+	return Config{
+		GramSize:     3,
+		Normalizer:   NormalizerChain{NormalizerLowercase},
+		ContainerDir: true,
+		Compress:     true,
+		MaxResults:   10000,
+	}
+}
+
+// ProductCatalogConfig is a preset for short product titles: accent- and
+// width-insensitive trigrams, a typo-tolerant missing n-gram policy, and
+// document lengths and a forward index for ranking and highlighting.
+func ProductCatalogConfig() Config {
+	return Config{
+		GramSize:      3,
+		Normalizer:    NormalizerChain{NormalizerStripAccents},
+		ForwardIndex:  true,
+		DocLengths:    true,
+		MissingNgrams: MissingNgramThreshold,
+		MaxResults:    1000,
+	}
+}
+
+// CodeSearchConfig is a preset for source files, the file index of
+// NewCodeIndex: case-sensitive trigrams over the text as is.
+func CodeSearchConfig() Config {
+	return Config{
+		GramSize:   3,
+		Normalizer: NormalizerChain{NormalizerIdentity},
+		Compress:   true,
+	}
+}
+
+// Validate reports every invalid setting, each wrapping ErrInvalidConfig
+// or, for normalizer names, ErrUnknownNormalizer.
+func (c Config) Validate() error {
+	var errs []error
+	if c.GramSize < 0 || c.GramSize > maxGramSize {
+		errs = append(errs, fmt.Errorf("%w: gram size %d outside 1-%d", ErrInvalidConfig, c.GramSize, maxGramSize))
+	}
+	if err := c.Normalizer.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.MaxResults < 0 {
+		errs = append(errs, fmt.Errorf("%w: negative max results %d", ErrInvalidConfig, c.MaxResults))
+	}
+	if c.MissingNgrams < MissingNgramFail || c.MissingNgrams > MissingNgramThreshold {
+		errs = append(errs, fmt.Errorf("%w: unknown missing n-gram policy %d", ErrInvalidConfig, c.MissingNgrams))
+	}
+	if c.ExpectedDocs < 0 {
+		errs = append(errs, fmt.Errorf("%w: negative expected docs %d", ErrInvalidConfig, c.ExpectedDocs))
+	}
+	return errors.Join(errs...)
+}
+
+// Options returns the Option funcs equivalent to c, without validating it.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Normalizer != nil {
+		opts = append(opts, WithNormalizerChain(c.Normalizer))
+	}
+	if c.ForwardIndex {
+		opts = append(opts, WithForwardIndex())
+	}
+	if c.DocLengths {
+		opts = append(opts, WithDocLengths())
+	}
+	if c.ExactKeys {
+		opts = append(opts, WithExactKeys())
+	}
+	if c.CJKBigrams {
+		opts = append(opts, WithCJKBigrams())
+	}
+	if c.ContainerDir {
+		opts = append(opts, WithContainerDirectory())
+	}
+	if c.Compress {
+		opts = append(opts, WithOptimizeOnSave())
+	}
+	if c.Deterministic {
+		opts = append(opts, WithDeterministicBuild())
+	}
+	if c.MaxResults > 0 {
+		opts = append(opts, WithMaxResults(c.MaxResults))
+	}
+	if c.MissingNgrams != MissingNgramFail {
+		opts = append(opts, WithMissingNgramPolicy(c.MissingNgrams))
+	}
+	if c.ExpectedDocs > 0 {
+		n := min(c.ExpectedDocs, maxPresizedNgrams)
+		opts = append(opts, indexOption(func(idx *Index) {
+			idx.bitmaps = make(map[uint64]*roaring.Bitmap, n)
+		}))
+	}
+	return opts
+}
+
+// CachedOptions returns the CachedIndexOption funcs for opening a file
+// written by an index built from c. The normalizer is recorded in the file,
+// so only the query settings are needed.
+func (c Config) CachedOptions() []CachedIndexOption {
+	var opts []CachedIndexOption
+	if c.MaxResults > 0 {
+		opts = append(opts, WithMaxResults(c.MaxResults))
+	}
+	if c.MissingNgrams != MissingNgramFail {
+		opts = append(opts, WithMissingNgramPolicy(c.MissingNgrams))
+	}
+	return opts
+}
+
+// Build validates c and creates an empty Index from it, plus any extra
+// options, which apply after the configuration.
+func (c Config) Build(opts ...Option) (*Index, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return NewIndex(c.GramSize, append(c.Options(), opts...)...), nil
+}
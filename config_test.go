@@ -0,0 +1,82 @@
+package roaringsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, cfg := range []Config{{}, LogSearchConfig(), ProductCatalogConfig(), CodeSearchConfig()} {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", cfg, err)
+		}
+	}
+
+	bad := Config{
+		GramSize:      9,
+		Normalizer:    NormalizerChain{"no-such-normalizer"},
+		MaxResults:    -1,
+		MissingNgrams: MissingNgramPolicy(42),
+		ExpectedDocs:  -5,
+	}
+	err := bad.Validate()
+	if !errors.Is(err, ErrInvalidConfig) || !errors.Is(err, ErrUnknownNormalizer) {
+		t.Fatalf("Validate = %v, want ErrInvalidConfig and ErrUnknownNormalizer", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 5 {
+		t.Errorf("Validate reported %d errors, want 5: %v", n, err)
+	}
+	if idx, err := bad.Build(); idx != nil || err == nil {
+		t.Errorf("Build = %v, %v, want an error", idx, err)
+	}
+}
+
+func TestConfigBuild(t *testing.T) {
+	cfg := ProductCatalogConfig()
+	cfg.ExpectedDocs = 100
+	idx, err := cfg.Build(WithMaxResults(1))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	idx.Add(1, "Café crème")
+	idx.Add(2, "cafe latte")
+
+	if got := idx.Search("cafe"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search = %v, want [1] with the extra option's cap", got)
+	}
+	if got := idx.DocLength(1); got == 0 {
+		t.Error("DocLength = 0, want lengths recorded")
+	}
+	if got := idx.NormalizerChain(); !reflect.DeepEqual(got, cfg.Normalizer) {
+		t.Errorf("NormalizerChain = %v, want %v", got, cfg.Normalizer)
+	}
+
+	path := filepath.Join(t.TempDir(), "catalog.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path, cfg.CachedOptions()...)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("latte"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("cached Search = %v, want [2]", got)
+	}
+}
+
+func TestWithOptimizeOnSave(t *testing.T) {
+	idx := NewIndex(3, WithOptimizeOnSave())
+	for i := range uint32(5000) {
+		idx.Add(i, testHelloWorld)
+	}
+	before := idx.MemoryUsage()
+	if err := idx.SaveToFile(filepath.Join(t.TempDir(), "opt.sear")); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	if after := idx.MemoryUsage(); after >= before {
+		t.Errorf("MemoryUsage = %d after save, want below %d", after, before)
+	}
+}
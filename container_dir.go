@@ -0,0 +1,285 @@
+package roaringsearch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// WithContainerDirectory makes WriteTo and SaveToFile record where each
+// roaring container of the larger n-gram bitmaps starts. A CachedIndex opened
+// from such a file keeps the directory in memory, so SearchRange and
+// filter pushdown read only the containers they need without first reading
+// each bitmap's header. The directory costs 8 bytes per container of bitmaps
+// too large to be read whole; files with one need this version or later.
+func WithContainerDirectory() Option {
+	return indexOption(func(idx *Index) {
+		idx.containerDir = true
+	})
+}
+
+// containerDirectory maps n-gram keys to the container layout of their
+// bitmaps in an index file.
+type containerDirectory map[uint64]*diskBitmap
+
+// add records the layout of a serialized bitmap if it is large enough to be
+// read container by container.
+func (dir containerDirectory) add(key uint64, data []byte) error {
+	if len(data) < pushdownMinSize {
+		return nil
+	}
+	headerLen, err := bitmapHeaderLen(data)
+	if err != nil {
+		return err
+	}
+	if headerLen > len(data) {
+		return ErrInvalidSize
+	}
+	d := parseBitmapHeader(slices.Clone(data[:headerLen]))
+	if d.offsets == nil {
+		if err := d.computeOffsets(data, headerLen); err != nil {
+			return err
+		}
+	}
+	dir[key] = d
+	return nil
+}
+
+// computeOffsets derives container offsets by walking the serialized
+// containers, for headers that do not store them.
+func (d *diskBitmap) computeOffsets(data []byte, headerLen int) error {
+	d.offsets = make([]uint32, len(d.keys))
+	off := headerLen
+	for i := range d.keys {
+		d.offsets[i] = uint32(off)
+		switch card := int(d.cards[i]) + 1; {
+		case d.isRun(i):
+			if off+2 > len(data) {
+				return ErrInvalidSize
+			}
+			off += 2 + 4*int(binary.LittleEndian.Uint16(data[off:]))
+		case card > arrayMaxSize:
+			off += bitmapContainer
+		default:
+			off += 2 * card
+		}
+	}
+	if off > len(data) {
+		return ErrInvalidSize
+	}
+	return nil
+}
+
+// writeTo writes the directory section: its byte length, the entry count,
+// then per bitmap its key, container count, run container bitset, and each
+// container's high bits, cardinality - 1 and offset.
+func (dir containerDirectory) writeTo(w io.Writer, keys []uint64) (int64, error) {
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(keys)))
+	for _, key := range keys {
+		d := dir[key]
+		n := len(d.keys)
+		body = binary.LittleEndian.AppendUint64(body, key)
+		body = binary.LittleEndian.AppendUint32(body, uint32(n))
+		runs := make([]byte, (n+7)/8)
+		copy(runs, d.runs)
+		body = append(body, runs...)
+		for i := range n {
+			body = binary.LittleEndian.AppendUint16(body, d.keys[i])
+			body = binary.LittleEndian.AppendUint16(body, d.cards[i])
+			body = binary.LittleEndian.AppendUint32(body, d.offsets[i])
+		}
+	}
+
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(len(body)))
+	n, err := w.Write(append(buf, body...))
+	if err != nil {
+		return int64(n), fmt.Errorf("write container directory: %w", err)
+	}
+	return int64(n), nil
+}
+
+// readContainerDirectory reads a section written by writeTo.
+func readContainerDirectory(r io.Reader) (containerDirectory, int64, error) {
+	body, read, err := readContainerSection(r)
+	if err != nil {
+		return nil, read, err
+	}
+	if len(body) < 4 {
+		return nil, read, ErrInvalidSize
+	}
+	count := binary.LittleEndian.Uint32(body)
+	if count > maxNgramCount {
+		return nil, read, ErrInvalidCount
+	}
+
+	dir := make(containerDirectory, count)
+	pos := 4
+	for range count {
+		if pos+12 > len(body) {
+			return nil, read, ErrInvalidSize
+		}
+		key := binary.LittleEndian.Uint64(body[pos:])
+		n := int(binary.LittleEndian.Uint32(body[pos+8:]))
+		pos += 12
+		if n > 1<<16 || pos+(n+7)/8+8*n > len(body) {
+			return nil, read, ErrInvalidSize
+		}
+		d := &diskBitmap{
+			runs:    body[pos : pos+(n+7)/8],
+			keys:    make([]uint16, n),
+			cards:   make([]uint16, n),
+			offsets: make([]uint32, n),
+		}
+		pos += len(d.runs)
+		for i := range n {
+			d.keys[i] = binary.LittleEndian.Uint16(body[pos:])
+			d.cards[i] = binary.LittleEndian.Uint16(body[pos+2:])
+			d.offsets[i] = binary.LittleEndian.Uint32(body[pos+4:])
+			pos += 8
+		}
+		dir[key] = d
+	}
+	return dir, read, nil
+}
+
+// skipContainerDirectory reads past a section written by writeTo.
+func skipContainerDirectory(r io.Reader) (int64, error) {
+	_, read, err := readContainerSection(r)
+	return read, err
+}
+
+// readContainerSection reads the length-prefixed body of a directory section.
+func readContainerSection(r io.Reader) ([]byte, int64, error) {
+	lenBuf := make([]byte, 8)
+	n, err := io.ReadFull(r, lenBuf)
+	read := int64(n)
+	if err != nil {
+		return nil, read, fmt.Errorf("read container directory length: %w", err)
+	}
+	size := binary.LittleEndian.Uint64(lenBuf)
+	if size > maxBitmapSize {
+		return nil, read, ErrInvalidSize
+	}
+	body := make([]byte, size)
+	n, err = io.ReadFull(r, body)
+	read += int64(n)
+	if err != nil {
+		return nil, read, fmt.Errorf("read container directory: %w", err)
+	}
+	return body, read, nil
+}
+
+// diskBitmap returns the container layout of the bitmap of key at loc in the
+// file of v, from the directory when the file has one, else by reading the
+// bitmap's header.
+func (idx *CachedIndex) diskBitmap(v fileView, key uint64, loc ngramLocation) (*diskBitmap, error) {
+	if d, ok := v.containers[key]; ok {
+		return d, nil
+	}
+	d, err := readDiskBitmap(v.reader, loc)
+	if err != nil {
+		idx.logLoadError(key, loc, err)
+	}
+	return d, err
+}
+
+// SearchRange returns the documents in [lo, hi] containing all n-grams of
+// the query. Large bitmaps that are not in memory are not loaded: only their
+// containers overlapping the range are read, and nothing is added to the
+// cache. Use it to page through a docID range, or for time or tenant
+// partitions laid out as docID ranges, under a tight WithMemoryBudget.
+func (idx *CachedIndex) SearchRange(query string, lo, hi uint32) (matches []uint32) {
+	if idx.logger != nil {
+		defer func(start time.Time) { idx.logQuery("SearchRange", query, len(matches), start) }(time.Now())
+	}
+	keys := idx.generateKeys(query)
+	if len(keys) == 0 || lo > hi {
+		return nil
+	}
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	window := roaring.New()
+	window.AddRange(uint64(lo), uint64(hi)+1)
+	scratch.bitmaps = append(scratch.bitmaps, window)
+	v := idx.view()
+	defer v.release()
+	for _, key := range keys {
+		loc, ok := v.location(key)
+		if !ok {
+			return nil
+		}
+		bm, ok := idx.residentBitmap(key)
+		if !ok && loc.size < pushdownMinSize {
+			bm, ok = idx.getBitmap(key)
+		}
+		if !ok {
+			d, err := idx.diskBitmap(v, key, loc)
+			if err != nil {
+				return nil
+			}
+			if bm, err = d.rangeBitmap(lo, hi); err != nil {
+				return nil
+			}
+		}
+		scratch.bitmaps = append(scratch.bitmaps, bm)
+	}
+	sortByCardinality(scratch.bitmaps)
+
+	matches = scratch.appendCollected(nil)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
+}
+
+// rangeBitmap returns the part of the bitmap that overlaps [lo, hi], reading
+// only the containers that do. Documents outside the range may be included.
+func (d *diskBitmap) rangeBitmap(lo, hi uint32) (*roaring.Bitmap, error) {
+	if d.offsets == nil {
+		return loadBitmapAt(d.r, d.loc)
+	}
+	bm := roaring.New()
+	first, _ := slices.BinarySearch(d.keys, uint16(lo>>16))
+	for i := first; i < len(d.keys) && d.keys[i] <= uint16(hi>>16); i++ {
+		c, err := d.container(i)
+		if err != nil {
+			return nil, err
+		}
+		c.addTo(bm, uint32(d.keys[i])<<16)
+	}
+	return bm, nil
+}
+
+// addTo adds the container's documents, with the given high bits, to bm.
+func (c diskContainer) addTo(bm *roaring.Bitmap, high uint32) {
+	switch c.kind {
+	case containerBitmap:
+		ids := make([]uint32, 0, arrayMaxSize)
+		for w := range len(c.data) / 8 {
+			word := binary.LittleEndian.Uint64(c.data[8*w:])
+			for ; word != 0; word &= word - 1 {
+				ids = append(ids, high|uint32(w*64+bits.TrailingZeros64(word)))
+			}
+		}
+		bm.AddMany(ids)
+	case containerRun:
+		runs := c.data[2:]
+		for j := 0; j+4 <= len(runs); j += 4 {
+			start := uint64(high) + uint64(binary.LittleEndian.Uint16(runs[j:]))
+			bm.AddRange(start, start+uint64(binary.LittleEndian.Uint16(runs[j+2:]))+1)
+		}
+	default:
+		ids := make([]uint32, len(c.data)/2)
+		for j := range ids {
+			ids[j] = high | uint32(binary.LittleEndian.Uint16(c.data[2*j:]))
+		}
+		bm.AddMany(ids)
+	}
+}
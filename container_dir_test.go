@@ -0,0 +1,150 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// rangeIndex returns an index of 300K documents whose common n-grams have
+// array, bitmap and run containers.
+func rangeIndex(opts ...Option) *Index {
+	idx := NewIndex(3, opts...)
+	batch := idx.BatchSize(50000)
+	for i := uint32(0); i < 300000; i++ {
+		text := "filler"
+		switch {
+		case i < 70000:
+			text = "common words"
+		case i%2 == 0:
+			text = "common words"
+		}
+		if i%3 == 0 {
+			text += fmt.Sprintf(" tag%d", i%10)
+		}
+		batch.Add(i, text)
+	}
+	batch.Flush()
+	idx.Optimize()
+	return idx
+}
+
+func TestContainerDirectoryRoundTrip(t *testing.T) {
+	idx := rangeIndex(WithContainerDirectory(), WithForwardIndex())
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	// ReadFrom skips the directory and still finds the forward index after it
+	loaded := NewIndex(3)
+	read, err := loaded.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if read != int64(buf.Len()) {
+		t.Errorf("ReadFrom read %d of %d bytes", read, buf.Len())
+	}
+	if !loaded.HasForwardIndex() || !loaded.containerDir {
+		t.Error("loaded index lost its forward index or container directory")
+	}
+	if diff := CompareIndexes(idx, loaded); !diff.Equal() {
+		t.Errorf("round trip differs: %+v", diff)
+	}
+
+	path := filepath.Join(t.TempDir(), "dir.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if len(cached.containers) == 0 {
+		t.Fatal("no container directory loaded")
+	}
+	for key, d := range cached.containers {
+		fromHeader, err := readDiskBitmap(cached.reader, cached.ngramIndex[key])
+		if err != nil {
+			t.Fatalf("readDiskBitmap: %v", err)
+		}
+		if fromHeader.offsets != nil && !reflect.DeepEqual(d.offsets, fromHeader.offsets) {
+			t.Errorf("key %d: directory offsets %v, header offsets %v", key, d.offsets, fromHeader.offsets)
+		}
+	}
+}
+
+func TestSearchRange(t *testing.T) {
+	dir := t.TempDir()
+	plainPath, dirPath := filepath.Join(dir, "plain.sear"), filepath.Join(dir, "dir.sear")
+	idx := rangeIndex()
+	if err := idx.SaveToFile(plainPath); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	WithContainerDirectory().applyIndex(idx)
+	if err := idx.SaveToFile(dirPath); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	for _, path := range []string{plainPath, dirPath} {
+		cached, err := OpenCachedIndex(path)
+		if err != nil {
+			t.Fatalf(errOpenCachedIndex, err)
+		}
+		for _, r := range [][2]uint32{{0, 10}, {65530, 65545}, {60000, 140000}, {299990, 4000000000}, {5, 4}} {
+			for _, q := range []string{"common", "tag3 common", "filler", "absent"} {
+				var want []uint32
+				for _, id := range idx.Search(q) {
+					if id >= r[0] && id <= r[1] {
+						want = append(want, id)
+					}
+				}
+				if got := cached.SearchRange(q, r[0], r[1]); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: SearchRange(%q, %d, %d) = %d docs, want %d",
+						filepath.Base(path), q, r[0], r[1], len(got), len(want))
+				}
+			}
+		}
+		if cached.MemoryUsage() > 64<<10 {
+			t.Errorf("%s: SearchRange cached %d bytes", filepath.Base(path), cached.MemoryUsage())
+		}
+		cached.Close()
+	}
+}
+
+func TestContainerDirectoryComputesOffsets(t *testing.T) {
+	// Run bitmaps of fewer than four containers store no offsets
+	bm := roaring.New()
+	for i := uint32(0); i < 1<<16; i += 3 {
+		bm.Add(i)
+	}
+	bm.AddRange(1<<16+10, 1<<16+5000)
+	bm.AddMany([]uint32{2 << 16, 2<<16 + 7})
+	bm.RunOptimize()
+	data, err := bm.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := readDiskBitmap(bytes.NewReader(data), ngramLocation{size: uint32(len(data))}); d.offsets != nil {
+		t.Fatal("test bitmap unexpectedly stores offsets")
+	}
+
+	dir := make(containerDirectory)
+	if err := dir.add(1, data); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	d := dir[1]
+	d.r, d.loc = bytes.NewReader(data), ngramLocation{size: uint32(len(data))}
+	got, err := d.rangeBitmap(0, 3<<16)
+	if err != nil {
+		t.Fatalf("rangeBitmap: %v", err)
+	}
+	if !got.Equals(bm) {
+		t.Errorf("rangeBitmap returned %d docs, want %d", got.GetCardinality(), bm.GetCardinality())
+	}
+}
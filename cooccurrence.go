@@ -0,0 +1,53 @@
+package roaringsearch
+
+import (
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// CoOccurrence counts the documents shared by each pair of categories of two
+// fields: counts[a][b] is the number of documents in category a of fieldA and
+// category b of fieldB. A non-nil result restricts the counts to its
+// documents, as in Facets. Pairs with no shared documents are omitted, and an
+// unknown field yields an empty matrix.
+//
+// Rows are computed in parallel under a single read lock.
+//
+// Example:
+//
+//	// media_type x language counts for the current search
+//	matrix := filter.CoOccurrence("media_type", "language", idx.SearchBitmap("star"))
+//	books := matrix["book"]["english"]
+func (c *BitmapFilter) CoOccurrence(fieldA, fieldB string, result *roaring.Bitmap) map[string]map[string]uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	mapA, mapB := c.fields[fieldA], c.fields[fieldB]
+	counts := make(map[string]map[string]uint64, len(mapA))
+	if len(mapA) == 0 || len(mapB) == 0 {
+		return counts
+	}
+
+	cats := make([]string, 0, len(mapA))
+	for cat := range mapA {
+		cats = append(cats, cat)
+	}
+	rows := make([]map[string]uint64, len(cats))
+
+	runBatch(len(cats), 0, func(i int) {
+		row := mapA[cats[i]]
+		if result != nil {
+			row = roaring.And(row, result)
+		}
+		if row.IsEmpty() {
+			return
+		}
+		rows[i] = facetCounts(mapB, row)
+	})
+
+	for i, cat := range cats {
+		if len(rows[i]) > 0 {
+			counts[cat] = rows[i]
+		}
+	}
+	return counts
+}
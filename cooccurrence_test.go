@@ -0,0 +1,45 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestCoOccurrence(t *testing.T) {
+	filter := NewBitmapFilter()
+	for docID, pair := range map[uint32][2]string{
+		1: {"book", "english"},
+		2: {"book", "english"},
+		3: {"book", "french"},
+		4: {"movie", "english"},
+		5: {"movie", "german"},
+		6: {"music", "french"},
+	} {
+		filter.Set(docID, "media_type", pair[0])
+		filter.Set(docID, "language", pair[1])
+	}
+
+	want := map[string]map[string]uint64{
+		"book":  {"english": 2, "french": 1},
+		"movie": {"english": 1, "german": 1},
+		"music": {"french": 1},
+	}
+	if got := filter.CoOccurrence("media_type", "language", nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("CoOccurrence = %v, want %v", got, want)
+	}
+
+	result := roaring.BitmapOf(1, 3, 5)
+	want = map[string]map[string]uint64{
+		"book":  {"english": 1, "french": 1},
+		"movie": {"german": 1},
+	}
+	if got := filter.CoOccurrence("media_type", "language", result); !reflect.DeepEqual(got, want) {
+		t.Errorf("CoOccurrence(result) = %v, want %v", got, want)
+	}
+
+	if got := filter.CoOccurrence("media_type", "missing", nil); len(got) != 0 {
+		t.Errorf("CoOccurrence with unknown field = %v, want empty", got)
+	}
+}
@@ -0,0 +1,78 @@
+package roaringsearch
+
+import "math"
+
+// cursorDone is the position of an exhausted Cursor, past every uint32 docID.
+const cursorDone = math.MaxUint32 + 1
+
+// Cursor iterates the AND matches of a query in ascending docID order, one
+// caller-sized chunk at a time. It holds only the query's n-gram keys and
+// the next docID to visit, never the result set, so millions of matches can
+// be exported with a fixed buffer. Each Next takes the index's read lock
+// briefly and resumes where the previous call stopped, so writes can proceed
+// between chunks; documents added or removed behind the cursor's position
+// are not revisited, those ahead of it are seen as they are at that time.
+//
+// A Cursor is not safe for concurrent use.
+type Cursor struct {
+	idx  *Index
+	keys []uint64
+	next uint64 // next docID to consider; cursorDone once exhausted
+}
+
+// SearchCursor returns a Cursor over the documents matching all n-grams of
+// the query, as Search would return them.
+func (idx *Index) SearchCursor(query string) *Cursor {
+	c := &Cursor{idx: idx, keys: idx.appendQueryKeys(nil, query)}
+	if len(c.keys) == 0 {
+		c.next = cursorDone
+	}
+	return c
+}
+
+// Next fills batch with the following matches and returns how many it
+// wrote. It returns 0 once the matches are exhausted.
+func (c *Cursor) Next(batch []uint32) int {
+	if c.next == cursorDone || len(batch) == 0 {
+		return 0
+	}
+
+	idx := c.idx
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectLocked(idx, c.keys) {
+		c.next = cursorDone
+		return 0
+	}
+
+	smallest, rest := scratch.bitmaps[0], scratch.bitmaps[1:]
+	it := smallest.Iterator()
+	it.AdvanceIfNeeded(uint32(c.next))
+	n := 0
+	for n < len(batch) && it.HasNext() {
+		docID := it.Next()
+		if existsInAllBitmaps(docID, rest) {
+			batch[n] = docID
+			n++
+		}
+	}
+
+	if n < len(batch) {
+		c.next = cursorDone
+	} else {
+		c.next = uint64(batch[n-1]) + 1
+	}
+	return n
+}
+
+// Seek moves the cursor so the next match returned is the first one at or
+// after docID. Seeking with the last docID of a chunk plus one resumes a
+// scan, e.g. across stateless HTTP requests.
+func (c *Cursor) Seek(docID uint32) {
+	if len(c.keys) > 0 {
+		c.next = uint64(docID)
+	}
+}
@@ -0,0 +1,68 @@
+package roaringsearch
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSearchCursor(t *testing.T) {
+	idx := NewIndex(3)
+	rng := rand.New(rand.NewSource(7))
+	for i := uint32(0); i < 5000; i++ {
+		idx.Add(i*3, generateDocument(rng, 5, 20))
+	}
+
+	for _, q := range []string{"server", "the", "john server", "zzzz", "ab"} {
+		want := idx.Search(q)
+		var got []uint32
+		c := idx.SearchCursor(q)
+		batch := make([]uint32, 37)
+		for n := c.Next(batch); n > 0; n = c.Next(batch) {
+			got = append(got, batch[:n]...)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("cursor over %q returned %d docs, want %d", q, len(got), len(want))
+		}
+		if c.Next(batch) != 0 {
+			t.Errorf("exhausted cursor over %q returned more docs", q)
+		}
+	}
+}
+
+func TestSearchCursorSeekAndWrites(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(1); i <= 10; i++ {
+		idx.Add(i*10, testHelloWorld)
+	}
+
+	c := idx.SearchCursor("hello")
+	batch := make([]uint32, 3)
+	if n := c.Next(batch); n != 3 || !reflect.DeepEqual(batch, []uint32{10, 20, 30}) {
+		t.Fatalf("first chunk = %v", batch[:n])
+	}
+
+	// Writes between chunks: behind the cursor are not revisited, ahead are seen
+	idx.Add(15, testHelloWorld)
+	idx.Add(35, testHelloWorld)
+	idx.Remove(40)
+	if n := c.Next(batch); n != 3 || !reflect.DeepEqual(batch, []uint32{35, 50, 60}) {
+		t.Errorf("second chunk = %v, want [35 50 60]", batch[:n])
+	}
+
+	c.Seek(95)
+	if n := c.Next(batch); n != 1 || batch[0] != 100 {
+		t.Errorf("after Seek(95) = %v, want [100]", batch[:n])
+	}
+	c.Seek(0)
+	if n := c.Next(batch); n != 3 || batch[0] != 10 || batch[1] != 15 {
+		t.Errorf("after Seek(0) = %v", batch[:n])
+	}
+
+	idx.Add(math.MaxUint32, testHelloWorld)
+	c.Seek(math.MaxUint32)
+	if n := c.Next(batch); n != 1 || batch[0] != math.MaxUint32 || c.Next(batch) != 0 {
+		t.Error("cursor at the last docID did not stop")
+	}
+}
@@ -0,0 +1,226 @@
+package roaringsearch
+
+import (
+	"math"
+	"slices"
+	"sync"
+)
+
+// dedupHashes is the MinHash signature length of a Dedup.
+const dedupHashes = 128
+
+// Dedup finds near-duplicate documents: those whose n-gram sets have a
+// Jaccard similarity of at least a threshold. Each document is reduced to a
+// MinHash signature over the n-gram keys an Index would generate for it, and
+// locality-sensitive hashing on bands of the signature pairs up candidates,
+// so clustering a corpus does not compare every document with every other.
+// Similarity is estimated from the signatures, to within a few percent.
+//
+// A Dedup keeps 512 bytes per document and is safe for concurrent use.
+//
+// Example:
+//
+//	d := rs.NewDedup(3, 0.8)
+//	for id, text := range corpus {
+//	    d.Add(id, text)
+//	}
+//	for _, cluster := range d.Clusters() {
+//	    keep(cluster[0]) // drop the rest
+//	}
+type Dedup struct {
+	keys      *Index // generates n-gram keys; holds no documents
+	threshold float64
+	bands     int
+	rows      int
+
+	mu         sync.Mutex
+	signatures map[uint32][]uint32
+	buckets    map[uint64][]uint32 // band hash -> documents
+}
+
+// NewDedup returns a Dedup for documents with the given gram size and
+// Jaccard threshold, clamped to [0.05, 1]. Options such as WithNormalizer
+// configure n-gram generation as for NewIndex.
+func NewDedup(gramSize int, threshold float64, opts ...Option) *Dedup {
+	threshold = max(0.05, min(threshold, 1))
+	bands, rows := dedupBands(threshold)
+	return &Dedup{
+		keys:       NewIndex(gramSize, opts...),
+		threshold:  threshold,
+		bands:      bands,
+		rows:       rows,
+		signatures: make(map[uint32][]uint32),
+		buckets:    make(map[uint64][]uint32),
+	}
+}
+
+// dedupBands splits the signature into bands of rows so that documents
+// share a band with high probability from a similarity somewhat below the
+// threshold, (1/bands)^(1/rows), trading extra candidates for recall.
+func dedupBands(threshold float64) (bands, rows int) {
+	bands, rows = dedupHashes, 1
+	for r := 2; r <= dedupHashes/2; r++ {
+		b := dedupHashes / r
+		if math.Pow(1/float64(b), 1/float64(r)) > threshold*0.85 {
+			break
+		}
+		bands, rows = b, r
+	}
+	return bands, rows
+}
+
+// Len returns the number of documents added.
+func (d *Dedup) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.signatures)
+}
+
+// Add records a document. Documents shorter than the gram size have no
+// n-grams and are never duplicates. Adding a docID again replaces it.
+func (d *Dedup) Add(docID uint32, text string) {
+	scratch := indexScratchPool.Get().(*indexScratch)
+	keys := d.keys.appendDocumentKeys(scratch, text)
+	sig := minHash(keys)
+	putIndexScratch(scratch)
+	if sig == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if old, ok := d.signatures[docID]; ok {
+		d.removeLocked(docID, old)
+	}
+	d.signatures[docID] = sig
+	for band := range d.bands {
+		h := d.bandHash(band, sig)
+		d.buckets[h] = append(d.buckets[h], docID)
+	}
+}
+
+// removeLocked drops docID from the buckets of its old signature.
+func (d *Dedup) removeLocked(docID uint32, sig []uint32) {
+	for band := range d.bands {
+		h := d.bandHash(band, sig)
+		ids := slices.DeleteFunc(d.buckets[h], func(id uint32) bool { return id == docID })
+		if len(ids) == 0 {
+			delete(d.buckets, h)
+		} else {
+			d.buckets[h] = ids
+		}
+	}
+	delete(d.signatures, docID)
+}
+
+// Similarity returns the estimated Jaccard similarity of two added
+// documents, or 0 if either is unknown.
+func (d *Dedup) Similarity(a, b uint32) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sa, oka := d.signatures[a]
+	sb, okb := d.signatures[b]
+	if !oka || !okb {
+		return 0
+	}
+	return signatureSimilarity(sa, sb)
+}
+
+// Clusters returns the groups of near-duplicate documents, linking any two
+// whose estimated similarity reaches the threshold. Each cluster is sorted
+// by docID and has at least two documents; clusters are ordered by their
+// first docID.
+func (d *Dedup) Clusters() [][]uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	parent := make(map[uint32]uint32)
+	var find func(uint32) uint32
+	find = func(x uint32) uint32 {
+		p, ok := parent[x]
+		if !ok || p == x {
+			return x
+		}
+		root := find(p)
+		parent[x] = root
+		return root
+	}
+
+	for _, ids := range d.buckets {
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				ra, rb := find(a), find(b)
+				if ra == rb || signatureSimilarity(d.signatures[a], d.signatures[b]) < d.threshold {
+					continue
+				}
+				parent[max(ra, rb)] = min(ra, rb)
+			}
+		}
+	}
+
+	groups := make(map[uint32][]uint32)
+	for id := range parent {
+		root := find(id)
+		groups[root] = append(groups[root], id)
+	}
+	clusters := make([][]uint32, 0, len(groups))
+	for root, ids := range groups {
+		if !slices.Contains(ids, root) {
+			ids = append(ids, root)
+		}
+		slices.Sort(ids)
+		clusters = append(clusters, ids)
+	}
+	slices.SortFunc(clusters, func(a, b []uint32) int { return int(a[0]) - int(b[0]) })
+	return clusters
+}
+
+// bandHash hashes one band of a signature together with its index.
+func (d *Dedup) bandHash(band int, sig []uint32) uint64 {
+	h := splitmix64(uint64(band))
+	for _, v := range sig[band*d.rows : (band+1)*d.rows] {
+		h = splitmix64(h ^ uint64(v))
+	}
+	return h
+}
+
+// minHash returns the MinHash signature of a key set, or nil if it is
+// empty. Hash i of a key is splitmix64 of the key mixed with seed i.
+func minHash(keys []uint64) []uint32 {
+	if len(keys) == 0 {
+		return nil
+	}
+	sig := make([]uint32, dedupHashes)
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+	for _, key := range keys {
+		h := splitmix64(key)
+		for i := range sig {
+			// Derive hash i from one mix of the key, as in double hashing
+			v := uint32((h + uint64(i)*(h>>32|1)) >> 16)
+			sig[i] = min(sig[i], v)
+		}
+	}
+	return sig
+}
+
+// signatureSimilarity estimates Jaccard similarity as the fraction of
+// equal signature slots.
+func signatureSimilarity(a, b []uint32) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// splitmix64 is the SplitMix64 finalizer, a fast 64-bit mixer.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
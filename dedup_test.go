@@ -0,0 +1,76 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDedupClusters(t *testing.T) {
+	d := NewDedup(3, 0.7)
+	d.Add(1, testQuickBrownFox)
+	d.Add(2, "the quick brown fox jumps over the lazy dog!")
+	d.Add(3, testHelloWorld)
+	d.Add(4, "The quick brown fox jumps over the lazy dogs")
+	d.Add(5, "completely unrelated text about databases")
+	d.Add(6, "hi") // too short for a trigram
+
+	if got := d.Len(); got != 5 {
+		t.Errorf("Len = %d, want 5", got)
+	}
+	want := [][]uint32{{1, 2, 4}}
+	if got := d.Clusters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Clusters = %v, want %v", got, want)
+	}
+	if sim := d.Similarity(1, 2); sim != 1 {
+		t.Errorf("Similarity(1, 2) = %v, want 1 after normalization", sim)
+	}
+	if sim := d.Similarity(1, 5); sim > 0.2 {
+		t.Errorf("Similarity(1, 5) = %v, want near 0", sim)
+	}
+	if sim := d.Similarity(1, 6); sim != 0 {
+		t.Errorf("Similarity with unknown doc = %v, want 0", sim)
+	}
+
+	// Replacing a document moves it out of its cluster
+	d.Add(4, "completely unrelated text about databases.")
+	want = [][]uint32{{1, 2}, {4, 5}}
+	if got := d.Clusters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Clusters after replace = %v, want %v", got, want)
+	}
+}
+
+func TestDedupCorpus(t *testing.T) {
+	d := NewDedup(3, 0.8)
+	for i := range uint32(200) {
+		d.Add(i, fmt.Sprintf("document number %d talks about topic %d in detail", i, i*7919))
+	}
+	base := "an entirely distinct paragraph describing near duplicate detection"
+	d.Add(1000, base)
+	d.Add(1001, base+" ok")
+
+	clusters := d.Clusters()
+	found := false
+	for _, c := range clusters {
+		if reflect.DeepEqual(c, []uint32{1000, 1001}) {
+			found = true
+		}
+		for _, id := range c {
+			if id >= 1000 && len(c) != 2 {
+				t.Errorf("cluster %v mixes the distinct paragraph with others", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Clusters = %v, want one containing [1000 1001]", clusters)
+	}
+}
+
+func TestDedupBands(t *testing.T) {
+	for _, threshold := range []float64{0.05, 0.5, 0.8, 0.95, 1} {
+		bands, rows := dedupBands(threshold)
+		if bands*rows > dedupHashes || bands < 1 || rows < 1 {
+			t.Errorf("dedupBands(%v) = %d, %d", threshold, bands, rows)
+		}
+	}
+}
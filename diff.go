@@ -0,0 +1,100 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"slices"
+	"unsafe"
+)
+
+// IndexDiff reports how two indexes differ, for validating rebuilds or merges.
+type IndexDiff struct {
+	GramSizeA, GramSizeB int
+	DocsA, DocsB         uint64      // number of documents in each index
+	OnlyInA              []uint64    // n-gram keys present only in A, sorted
+	OnlyInB              []uint64    // n-gram keys present only in B, sorted
+	Changed              []NgramDiff // n-grams in both whose documents differ, sorted by key
+}
+
+// NgramDiff describes an n-gram whose document set differs between two indexes.
+type NgramDiff struct {
+	Key    uint64
+	CountA uint64 // documents containing the n-gram in A
+	CountB uint64 // documents containing the n-gram in B
+}
+
+// Equal reports whether the two indexes had identical contents.
+func (d IndexDiff) Equal() bool {
+	return d.GramSizeA == d.GramSizeB && d.DocsA == d.DocsB &&
+		len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.Changed) == 0
+}
+
+// Keys returns the n-gram keys whose bitmaps differ: those only in A, only
+// in B, or changed, sorted. Pass them to CachedIndex.Refresh when a file
+// written from A is replaced by one written from B.
+func (d IndexDiff) Keys() []uint64 {
+	keys := make([]uint64, 0, len(d.OnlyInA)+len(d.OnlyInB)+len(d.Changed))
+	keys = append(keys, d.OnlyInA...)
+	keys = append(keys, d.OnlyInB...)
+	for _, c := range d.Changed {
+		keys = append(keys, c.Key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// CompareIndexes reports the n-grams and document counts that differ between a and b.
+func CompareIndexes(a, b *Index) IndexDiff {
+	unlock := rlockPair(a, b)
+	defer unlock()
+
+	diff := IndexDiff{
+		GramSizeA: a.gramSize,
+		GramSizeB: b.gramSize,
+		DocsA:     a.docs.GetCardinality(),
+		DocsB:     b.docs.GetCardinality(),
+	}
+
+	for key, bmA := range a.bitmaps {
+		bmB, ok := b.bitmaps[key]
+		if !ok {
+			diff.OnlyInA = append(diff.OnlyInA, key)
+			continue
+		}
+		if !bmA.Equals(bmB) {
+			diff.Changed = append(diff.Changed, NgramDiff{
+				Key:    key,
+				CountA: bmA.GetCardinality(),
+				CountB: bmB.GetCardinality(),
+			})
+		}
+	}
+	for key := range b.bitmaps {
+		if _, ok := a.bitmaps[key]; !ok {
+			diff.OnlyInB = append(diff.OnlyInB, key)
+		}
+	}
+
+	slices.Sort(diff.OnlyInA)
+	slices.Sort(diff.OnlyInB)
+	slices.SortFunc(diff.Changed, func(x, y NgramDiff) int {
+		return cmp.Compare(x.Key, y.Key)
+	})
+	return diff
+}
+
+// rlockPair read-locks two indexes in a fixed order and returns the unlock func.
+func rlockPair(a, b *Index) func() {
+	if a == b {
+		a.mu.RLock()
+		return a.mu.RUnlock
+	}
+	if uintptr(unsafe.Pointer(a)) > uintptr(unsafe.Pointer(b)) {
+		a, b = b, a
+	}
+	a.mu.RLock()
+	b.mu.RLock()
+	return func() {
+		b.mu.RUnlock()
+		a.mu.RUnlock()
+	}
+}
@@ -0,0 +1,92 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestIndexAndNot(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testGoodbyeWorld)
+	idx.Add(4, "hello")
+
+	idx.SubtractDocs([]uint32{1, 4})
+
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search(hello) = %v, want [2]", got)
+	}
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{3}) {
+		t.Errorf("Search(world) = %v, want [3]", got)
+	}
+	if idx.DocCount() != 2 {
+		t.Errorf("DocCount = %d, want 2", idx.DocCount())
+	}
+
+	// Matches removing one at a time
+	expected := NewIndex(3)
+	expected.Add(2, testHelloThere)
+	expected.Add(3, testGoodbyeWorld)
+	if diff := CompareIndexes(idx, expected); !diff.Equal() {
+		t.Errorf("SubtractDocs result differs from rebuilt index: %+v", diff)
+	}
+
+	idx.AndNot(nil)
+	idx.SubtractDocs(nil)
+}
+
+func TestIndexRemoveMany(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithForwardIndex()}} {
+		idx := NewIndex(3, opts...)
+		expected := NewIndex(3)
+		for i := uint32(0); i < 20; i++ {
+			text := fmt.Sprintf("doc %d text", i)
+			idx.Add(i, text)
+			if i%3 != 0 {
+				expected.Add(i, text)
+			}
+		}
+
+		idx.RemoveMany([]uint32{0, 3, 6, 9, 12, 15, 18})
+
+		if diff := CompareIndexes(idx, expected); !diff.Equal() {
+			t.Errorf("forward=%v: RemoveMany result differs: %+v", idx.HasForwardIndex(), diff)
+		}
+	}
+}
+
+func TestCompareIndexes(t *testing.T) {
+	a := NewIndex(3)
+	a.Add(1, "abcd")
+	a.Add(2, "abc")
+
+	b := NewIndex(3)
+	b.Add(1, "abcx")
+
+	diff := CompareIndexes(a, b)
+	if diff.Equal() {
+		t.Fatal("indexes should differ")
+	}
+	if diff.DocsA != 2 || diff.DocsB != 1 {
+		t.Errorf("doc counts = %d/%d, want 2/1", diff.DocsA, diff.DocsB)
+	}
+
+	bcd := runeNgramKey([]rune("bcd"))
+	bcx := runeNgramKey([]rune("bcx"))
+	abc := runeNgramKey([]rune("abc"))
+	if !reflect.DeepEqual(diff.OnlyInA, []uint64{bcd}) {
+		t.Errorf("OnlyInA = %v, want [%d]", diff.OnlyInA, bcd)
+	}
+	if !reflect.DeepEqual(diff.OnlyInB, []uint64{bcx}) {
+		t.Errorf("OnlyInB = %v, want [%d]", diff.OnlyInB, bcx)
+	}
+	if !reflect.DeepEqual(diff.Changed, []NgramDiff{{Key: abc, CountA: 2, CountB: 1}}) {
+		t.Errorf("Changed = %v", diff.Changed)
+	}
+
+	if !CompareIndexes(a, a).Equal() {
+		t.Error("an index should equal itself")
+	}
+}
@@ -0,0 +1,213 @@
+package roaringsearch
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// BM25-style length normalization constants of SearchThresholdNormalized.
+const (
+	normK1 = 1.2
+	normB  = 0.75
+)
+
+// WithDocLengths records each document's number of unique n-grams in a
+// uint16 column (capped at 65535) as it is added, so
+// SearchThresholdNormalized can score matches relative to document length.
+// It costs 2 bytes per docID. Lengths are saved with the index.
+func WithDocLengths() Option {
+	return indexOption(func(idx *Index) {
+		if idx.lengths == nil {
+			idx.lengths = NewSortColumn[uint16]()
+		}
+	})
+}
+
+// docLength caps an n-gram count to the uint16 column.
+func docLength(n int) uint16 {
+	return uint16(min(n, math.MaxUint16))
+}
+
+// setLengthLocked records a document's length, keeping the running total
+// for the average.
+func (idx *Index) setLengthLocked(docID uint32, n uint16) {
+	if idx.lengths == nil || n == 0 {
+		return
+	}
+	if old := idx.lengths.Get(docID); old != 0 {
+		idx.lengthSum -= uint64(old)
+		idx.lengthCount--
+	}
+	idx.lengths.Set(docID, n)
+	idx.lengthSum += uint64(n)
+	idx.lengthCount++
+}
+
+// removeLengthsLocked forgets the lengths of docs.
+func (idx *Index) removeLengthsLocked(docs *roaring.Bitmap) {
+	if idx.lengths == nil {
+		return
+	}
+	ids := make([]uint32, 0, docs.GetCardinality())
+	docs.Iterate(func(docID uint32) bool {
+		if n := idx.lengths.Get(docID); n != 0 {
+			idx.lengthSum -= uint64(n)
+			idx.lengthCount--
+			ids = append(ids, docID)
+		}
+		return true
+	})
+	idx.lengths.RemoveMany(ids)
+}
+
+// DocLength returns the number of unique n-grams recorded for a document,
+// or 0 if the index was not created with WithDocLengths or has no length
+// for it.
+func (idx *Index) DocLength(docID uint32) int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.lengths == nil {
+		return 0
+	}
+	return int(idx.lengths.Get(docID))
+}
+
+// AvgDocLength returns the mean recorded document length, or 0 if there is
+// none.
+func (idx *Index) AvgDocLength() float64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.avgLengthLocked()
+}
+
+func (idx *Index) avgLengthLocked() float64 {
+	if idx.lengthCount == 0 {
+		return 0
+	}
+	return float64(idx.lengthSum) / float64(idx.lengthCount)
+}
+
+// NormalizedResult is a document scored by SearchThresholdNormalized.
+type NormalizedResult struct {
+	DocID   uint32
+	Matched int     // query n-grams the document contains, as in SearchThreshold
+	Score   float64 // Matched normalized by document length
+}
+
+// SearchThresholdNormalized is like SearchThresholdTopK but ranks documents
+// by their matched n-grams normalized by document length, in the manner of
+// BM25 with binary term frequencies:
+//
+//	score = matched * (k1+1) / (1 + k1*(1 - b + b*length/avgLength))
+//
+// with k1 = 1.2 and b = 0.75, so a short document matching the query
+// outranks a long one matching the same n-grams by chance. Without
+// WithDocLengths, or for documents with no recorded length, every document
+// counts as average length and the order is that of SearchThresholdTopK.
+// Results are best first, ties by ascending docID; k <= 0 returns all.
+func (idx *Index) SearchThresholdNormalized(query string, threshold, k int) (results []NormalizedResult) {
+	if idx.stats != nil {
+		defer func(start time.Time) {
+			idx.recordQuery("SearchThresholdNormalized", query, len(results), start)
+		}(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 || threshold <= 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	levels, threshold := idx.thresholdLevelsLocked(keys, threshold)
+	if levels == nil {
+		return nil
+	}
+	candidates := levels[threshold-1].GetCardinality()
+	if k <= 0 || uint64(k) > candidates {
+		k = int(candidates)
+	}
+
+	avg := idx.avgLengthLocked()
+	norm := func(docID uint32) float64 {
+		if idx.lengths == nil || avg == 0 {
+			return 1
+		}
+		n := idx.lengths.Get(docID)
+		if n == 0 {
+			return 1
+		}
+		return 1 - normB + normB*float64(n)/avg
+	}
+
+	h := &resultHeap[float64]{items: make([]SortedResult[float64], 0, k)}
+	walkThreshold(levels, threshold, func(docID uint32, matched int) bool {
+		score := float64(matched) * (normK1 + 1) / (1 + normK1*norm(docID))
+		heapInsert(h, docID, score, false, k)
+		return true
+	})
+	if h.Len() < k && h.Len() > 0 {
+		heap.Init(h)
+	}
+
+	top := heapToSortedResults(h)
+	results = make([]NormalizedResult, len(top))
+	for i, r := range top {
+		results[i] = NormalizedResult{
+			DocID:   r.DocID,
+			Matched: matchedLevel(levels, r.DocID),
+			Score:   r.Value,
+		}
+	}
+	return results
+}
+
+// writeDocLengths writes the recorded lengths as a count followed by
+// (docID, length) pairs in docID order.
+func (idx *Index) writeDocLengths(w io.Writer) (int64, error) {
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(idx.lengthCount))
+	idx.lengths.mu.RLock()
+	_ = idx.lengths.eachValueLocked(func(docID uint32, n uint16) error {
+		buf = binary.LittleEndian.AppendUint32(buf, docID)
+		buf = binary.LittleEndian.AppendUint16(buf, n)
+		return nil
+	})
+	idx.lengths.mu.RUnlock()
+
+	n, err := w.Write(buf)
+	if err != nil {
+		return int64(n), fmt.Errorf("write doc lengths: %w", err)
+	}
+	return int64(n), nil
+}
+
+// readDocLengths reads the section written by writeDocLengths into a new
+// column.
+func (idx *Index) readDocLengths(r io.Reader) (int64, error) {
+	var buf [6]byte
+	n, err := io.ReadFull(r, buf[:4])
+	read := int64(n)
+	if err != nil {
+		return read, fmt.Errorf("read doc length count: %w", err)
+	}
+	count := binary.LittleEndian.Uint32(buf[:4])
+
+	idx.lengths = NewSortColumn[uint16]()
+	idx.lengthSum, idx.lengthCount = 0, 0
+	for range count {
+		n, err := io.ReadFull(r, buf[:])
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("read doc length: %w", err)
+		}
+		idx.setLengthLocked(binary.LittleEndian.Uint32(buf[:4]), binary.LittleEndian.Uint16(buf[4:]))
+	}
+	return read, nil
+}
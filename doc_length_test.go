@@ -0,0 +1,86 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+const testLongFox = "the quick brown fox jumps over the lazy dog and keeps running far away from every hunter"
+
+func TestSearchThresholdNormalized(t *testing.T) {
+	idx := NewIndex(3, WithDocLengths())
+	idx.Add(1, testLongFox)
+	idx.Add(2, "quick brown fox")
+	idx.Add(3, testHelloWorld)
+
+	if got := idx.DocLength(2); got != 11 {
+		t.Errorf("DocLength(2) = %d, want 11", got)
+	}
+	if got := idx.SearchThresholdTopK("quick brown fox", 1, 0).DocIDs; got[0] != 1 {
+		t.Fatalf("unnormalized order = %v, want the long document first on the docID tie", got)
+	}
+	results := idx.SearchThresholdNormalized("quick brown fox", 1, 0)
+	if len(results) != 2 || results[0].DocID != 2 || results[1].DocID != 1 {
+		t.Fatalf("normalized results = %+v, want the short document first", results)
+	}
+	if results[0].Matched != 11 || results[0].Score <= results[1].Score {
+		t.Errorf("normalized results = %+v", results)
+	}
+	if got := idx.SearchThresholdNormalized("quick brown fox", 1, 1); len(got) != 1 || got[0].DocID != 2 {
+		t.Errorf("top 1 = %+v, want doc 2", got)
+	}
+
+	// Lengths follow removals and survive a save
+	idx.Remove(1)
+	if idx.DocLength(1) != 0 || idx.AvgDocLength() != float64(11+8)/2 {
+		t.Errorf("after Remove: length %d, average %v", idx.DocLength(1), idx.AvgDocLength())
+	}
+	path := filepath.Join(t.TempDir(), "lengths.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if loaded.DocLength(2) != 11 || loaded.AvgDocLength() != idx.AvgDocLength() {
+		t.Errorf("loaded length %d, average %v", loaded.DocLength(2), loaded.AvgDocLength())
+	}
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("quick"); len(got) != 1 {
+		t.Errorf("cached Search = %v", got)
+	}
+
+	snap := idx.Snapshot()
+	idx.Clear()
+	if snap.DocLength(2) != 11 || idx.DocLength(2) != 0 {
+		t.Error("snapshot shares its length column with the index")
+	}
+}
+
+func TestDocLengthsBatch(t *testing.T) {
+	idx := NewIndex(3, WithDocLengths())
+	batch := idx.BatchSize(500)
+	for i := range uint32(500) {
+		batch.Add(i, fmt.Sprintf("document %d %s", i, testLongFox[:i%80]))
+	}
+	batch.Flush()
+
+	plain := NewIndex(3, WithDocLengths())
+	for i := range uint32(500) {
+		plain.Add(i, fmt.Sprintf("document %d %s", i, testLongFox[:i%80]))
+	}
+	for i := range uint32(500) {
+		if got, want := idx.DocLength(i), plain.DocLength(i); got != want {
+			t.Fatalf("batch DocLength(%d) = %d, want %d", i, got, want)
+		}
+	}
+	if idx.AvgDocLength() != plain.AvgDocLength() {
+		t.Errorf("batch average %v, want %v", idx.AvgDocLength(), plain.AvgDocLength())
+	}
+}
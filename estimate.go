@@ -0,0 +1,82 @@
+package roaringsearch
+
+import (
+	"math"
+	"time"
+)
+
+// CountEstimate is an approximate result count with guaranteed bounds: the
+// true count always lies in [Lower, Upper].
+type CountEstimate struct {
+	Estimate uint64 // best guess, within [Lower, Upper]
+	Lower    uint64
+	Upper    uint64
+}
+
+// Exact reports whether the bounds pin down the count, so Estimate is exact.
+func (e CountEstimate) Exact() bool {
+	return e.Lower == e.Upper
+}
+
+// EstimateSearchCount estimates the number of documents Search would return,
+// for "about 1,200 results" displays and query planning, without
+// materializing the intersection.
+//
+// Each n-gram bitmap is compared with the rarest one using roaring's
+// container-level AndCardinality, which counts overlaps without allocating.
+// The smallest pairwise overlap bounds the count from above; the
+// inclusion-exclusion (Bonferroni) bound of the overlaps bounds it from below.
+// The estimate assumes the n-grams occur independently within the rarest
+// n-gram's documents and is clamped to the bounds. Queries of one or two
+// n-grams are counted exactly, and since the n-grams of a phrase tend to occur
+// together the bounds are often tight for longer queries too.
+func (idx *Index) EstimateSearchCount(query string) (estimate CountEstimate) {
+	if idx.stats != nil {
+		defer func(start time.Time) {
+			idx.recordQuery("EstimateSearchCount", query, int(estimate.Estimate), start)
+		}(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
+		return CountEstimate{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectLocked(idx, keys) {
+		return CountEstimate{}
+	}
+
+	rarest, rest := scratch.bitmaps[0], scratch.bitmaps[1:]
+	base := rarest.GetCardinality()
+	upper := base
+	missing := uint64(0) // sum over n-grams of rarest docs lacking them
+	fraction := 1.0
+	for _, bm := range rest {
+		overlap := rarest.AndCardinality(bm)
+		if overlap == 0 {
+			return CountEstimate{}
+		}
+		upper = min(upper, overlap)
+		missing += base - overlap
+		fraction *= float64(overlap) / float64(base)
+	}
+	if len(rest) <= 1 {
+		return CountEstimate{Estimate: upper, Lower: upper, Upper: upper}
+	}
+
+	var lower uint64
+	if missing < base {
+		lower = base - missing
+	}
+	guess := uint64(math.Round(float64(base) * fraction))
+	return CountEstimate{
+		Estimate: max(lower, min(guess, upper)),
+		Lower:    lower,
+		Upper:    upper,
+	}
+}
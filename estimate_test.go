@@ -0,0 +1,85 @@
+package roaringsearch
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestEstimateSearchCount(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 3000; i++ {
+		switch i % 3 {
+		case 0:
+			idx.Add(i, testQuickBrownFox)
+		case 1:
+			idx.Add(i, "brown bears fox around")
+		default:
+			idx.Add(i, testHelloWorld)
+		}
+	}
+
+	// One and two n-grams are exact
+	for _, query := range []string{"fox", "brow", "zebra", "ab"} {
+		got := idx.EstimateSearchCount(query)
+		if want := idx.SearchCount(query); !got.Exact() || got.Estimate != want {
+			t.Errorf("EstimateSearchCount(%q) = %+v, want exact %d", query, got, want)
+		}
+	}
+
+	// Perfectly correlated n-grams pin the bounds
+	got := idx.EstimateSearchCount("quick brown fox")
+	if !got.Exact() || got.Estimate != 1000 {
+		t.Errorf("EstimateSearchCount(quick brown fox) = %+v, want exact 1000", got)
+	}
+}
+
+func TestEstimateSearchCountBounds(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	words := strings.Fields("alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo lima")
+
+	idx := NewIndex(3)
+	for i := uint32(0); i < 5000; i++ {
+		var doc []string
+		for range 4 {
+			doc = append(doc, words[rng.IntN(len(words))])
+		}
+		idx.Add(i*7, strings.Join(doc, " "))
+	}
+
+	for i := 0; i < 200; i++ {
+		query := words[rng.IntN(len(words))] + " " + words[rng.IntN(len(words))]
+		got := idx.EstimateSearchCount(query)
+		want := idx.SearchCount(query)
+		if want < got.Lower || want > got.Upper {
+			t.Fatalf("EstimateSearchCount(%q) = %+v, true count %d outside bounds", query, got, want)
+		}
+		if got.Estimate < got.Lower || got.Estimate > got.Upper {
+			t.Fatalf("EstimateSearchCount(%q) = %+v, estimate outside bounds", query, got)
+		}
+	}
+}
+
+func BenchmarkEstimateSearchCount(b *testing.B) {
+	idx := NewIndex(3)
+	for i := 0; i < 100000; i++ {
+		if i%2 == 0 {
+			idx.Add(uint32(i), testQuickBrownFox)
+		} else {
+			idx.Add(uint32(i), "the brown dog and the quick cat")
+		}
+	}
+
+	b.Run("Estimate", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.EstimateSearchCount("quick brown fox")
+		}
+	})
+	b.Run("SearchCount", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.SearchCount("quick brown fox")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package roaringsearch
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// DocRemover is implemented by stores that support bulk document removal:
+// Index, BitmapFilter, SortColumn, and BitSlicedIndex.
+type DocRemover interface {
+	RemoveMany(docIDs []uint32)
+}
+
+// ExpiryColumn records an expiration time per document and removes expired
+// documents from other stores, for retention windows in log-style workloads.
+// Expiration times are kept at millisecond precision in a BitSlicedIndex, so
+// finding every expired document is a single range query.
+//
+// Example:
+//
+//	expiry := NewExpiryColumn()
+//	expiry.SetTTL(docID, 24*time.Hour)
+//
+//	stop := expiry.StartSweeper(time.Minute, idx, filter, ratings)
+//	defer stop()
+type ExpiryColumn struct {
+	bsi *BitSlicedIndex
+}
+
+// NewExpiryColumn creates an empty expiry column.
+func NewExpiryColumn() *ExpiryColumn {
+	return &ExpiryColumn{bsi: NewBitSlicedIndex()}
+}
+
+// expiryMillis converts t to the stored representation, clamping times
+// before the Unix epoch to zero.
+func expiryMillis(t time.Time) uint64 {
+	ms := t.UnixMilli()
+	if ms < 0 {
+		return 0
+	}
+	return uint64(ms)
+}
+
+// SetExpiry sets the time at which a document expires.
+func (e *ExpiryColumn) SetExpiry(docID uint32, at time.Time) {
+	e.bsi.Set(docID, expiryMillis(at))
+}
+
+// SetTTL sets a document to expire ttl from now.
+func (e *ExpiryColumn) SetTTL(docID uint32, ttl time.Duration) {
+	e.SetExpiry(docID, time.Now().Add(ttl))
+}
+
+// ExpiresAt returns a document's expiration time and whether it has one.
+func (e *ExpiryColumn) ExpiresAt(docID uint32) (time.Time, bool) {
+	ms, ok := e.bsi.Get(docID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(ms)), true
+}
+
+// Remove clears a document's expiration so it is kept indefinitely.
+func (e *ExpiryColumn) Remove(docID uint32) {
+	e.bsi.Remove(docID)
+}
+
+// Count returns the number of documents with an expiration time.
+func (e *ExpiryColumn) Count() uint64 {
+	return e.bsi.Count()
+}
+
+// Expired returns the documents whose expiration time is at or before now.
+func (e *ExpiryColumn) Expired(now time.Time) *roaring.Bitmap {
+	return e.bsi.LessThanOrEqual(expiryMillis(now), nil)
+}
+
+// Sweep removes every document expired at now from the targets and from the
+// column itself, returning the removed docIDs.
+func (e *ExpiryColumn) Sweep(now time.Time, targets ...DocRemover) []uint32 {
+	expired := e.Expired(now)
+	if expired.IsEmpty() {
+		return nil
+	}
+
+	ids := expired.ToArray()
+	for _, target := range targets {
+		target.RemoveMany(ids)
+	}
+	e.bsi.removeBitmap(expired)
+	return ids
+}
+
+// StartSweeper runs Sweep against the targets every interval in a background
+// goroutine. The returned function stops the sweeper and waits for any sweep
+// in progress to finish.
+func (e *ExpiryColumn) StartSweeper(interval time.Duration, targets ...DocRemover) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				e.Sweep(now, targets...)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// MemoryUsage returns the memory used by the column's bitmaps in bytes.
+func (e *ExpiryColumn) MemoryUsage() uint64 {
+	return e.bsi.MemoryUsage()
+}
+
+// SaveToFile saves the expiry column to a file atomically.
+func (e *ExpiryColumn) SaveToFile(path string) error {
+	return e.bsi.SaveToFile(path)
+}
+
+// Encode writes the expiry column to a writer.
+func (e *ExpiryColumn) Encode(w io.Writer) error {
+	return e.bsi.Encode(w)
+}
+
+// LoadExpiryColumn loads an expiry column from a file.
+func LoadExpiryColumn(path string) (*ExpiryColumn, error) {
+	bsi, err := LoadBitSlicedIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpiryColumn{bsi: bsi}, nil
+}
+
+// ReadExpiryColumn reads an expiry column from a reader.
+func ReadExpiryColumn(r io.Reader) (*ExpiryColumn, error) {
+	bsi, err := ReadBitSlicedIndex(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpiryColumn{bsi: bsi}, nil
+}
@@ -0,0 +1,99 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiryColumnSweep(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	idx := NewIndex(3)
+	filter := NewBitmapFilter()
+	ratings := NewSortColumn[uint16]()
+	expiry := NewExpiryColumn()
+
+	for i := uint32(1); i <= 4; i++ {
+		idx.Add(i, "log line")
+		filter.Set(i, "level", "info")
+		ratings.Set(i, uint16(i*10))
+	}
+	expiry.SetExpiry(1, now.Add(-time.Hour))
+	expiry.SetExpiry(2, now)
+	expiry.SetExpiry(3, now.Add(time.Hour))
+	// doc 4 never expires
+
+	if got := expiry.Expired(now).ToArray(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Expired = %v, want [1 2]", got)
+	}
+
+	removed := expiry.Sweep(now, idx, filter, ratings)
+	if !reflect.DeepEqual(removed, []uint32{1, 2}) {
+		t.Errorf("Sweep removed %v, want [1 2]", removed)
+	}
+	if got := idx.Search("log"); !reflect.DeepEqual(got, []uint32{3, 4}) {
+		t.Errorf("index after sweep = %v, want [3 4]", got)
+	}
+	if got := filter.Get("level", "info").ToArray(); !reflect.DeepEqual(got, []uint32{3, 4}) {
+		t.Errorf("filter after sweep = %v, want [3 4]", got)
+	}
+	if ratings.Get(1) != 0 || ratings.Get(3) != 30 {
+		t.Errorf("ratings after sweep = %d, %d", ratings.Get(1), ratings.Get(3))
+	}
+	if expiry.Count() != 1 {
+		t.Errorf("expiry Count = %d, want 1", expiry.Count())
+	}
+	if removed := expiry.Sweep(now, idx); removed != nil {
+		t.Errorf("second sweep removed %v, want nil", removed)
+	}
+
+	at, ok := expiry.ExpiresAt(3)
+	if !ok || !at.Equal(now.Add(time.Hour)) {
+		t.Errorf("ExpiresAt(3) = %v, %v", at, ok)
+	}
+	if _, ok := expiry.ExpiresAt(4); ok {
+		t.Error("doc 4 should have no expiry")
+	}
+}
+
+func TestExpiryColumnSweeper(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, "short lived")
+	idx.Add(2, "long lived")
+
+	expiry := NewExpiryColumn()
+	expiry.SetTTL(1, -time.Second)
+	expiry.SetTTL(2, time.Hour)
+
+	stop := expiry.StartSweeper(time.Millisecond, idx)
+	deadline := time.Now().Add(2 * time.Second)
+	for idx.DocCount() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	stop() // idempotent
+
+	if got := idx.Search("lived"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search after sweeper = %v, want [2]", got)
+	}
+}
+
+func TestExpiryColumnPersistence(t *testing.T) {
+	expiry := NewExpiryColumn()
+	at := time.UnixMilli(1_700_000_000_123)
+	expiry.SetExpiry(7, at)
+
+	path := filepath.Join(t.TempDir(), "expiry.bsi")
+	if err := expiry.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	loaded, err := LoadExpiryColumn(path)
+	if err != nil {
+		t.Fatalf("LoadExpiryColumn failed: %v", err)
+	}
+	if got, ok := loaded.ExpiresAt(7); !ok || !got.Equal(at) {
+		t.Errorf("loaded ExpiresAt = %v, %v, want %v", got, ok, at)
+	}
+}
@@ -0,0 +1,430 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// Export formats are meant for inspection, diffing in CI and loading by
+// other tools, not for storage: they are much larger and slower than
+// WriteTo and SaveToFile. Output is deterministic, with one n-gram, category
+// or value per line.
+
+// exportedIndex is the JSON form of an Index.
+type exportedIndex struct {
+	GramSize   int             `json:"gramSize"`
+	Normalizer string          `json:"normalizer,omitempty"`
+	ExactKeys  bool            `json:"exactKeys,omitempty"`
+	Docs       []uint32        `json:"docs"`
+	Ngrams     []exportedNgram `json:"ngrams"`
+}
+
+// exportedNgram is one n-gram's postings. Ngram holds the text when the key
+// decodes; otherwise Key holds the raw key as a decimal string, which tools
+// using float64 numbers cannot corrupt.
+type exportedNgram struct {
+	Ngram string   `json:"ngram,omitempty"`
+	Key   uint64   `json:"key,omitempty,string"`
+	Docs  []uint32 `json:"docs"`
+}
+
+// ExportJSON writes the index as JSON: the gram size, normalizer, every docID
+// and each n-gram's docIDs, with n-grams in key order.
+//
+//	{"gramSize":3,"docs":[1,2],"ngrams":[
+//	{"ngram":"hel","docs":[1,2]},
+//	{"key":"9326475612098133111","docs":[2]}]}
+//
+// Hashed keys of Unicode n-grams are written as "key" unless the index uses
+// WithExactKeys, which records their text. The forward index is not exported;
+// ImportJSON rebuilds it when requested.
+func (idx *Index) ExportJSON(w io.Writer) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	header, err := json.Marshal(struct {
+		GramSize   int    `json:"gramSize"`
+		Normalizer string `json:"normalizer,omitempty"`
+		ExactKeys  bool   `json:"exactKeys,omitempty"`
+	}{idx.gramSize, idx.recordedNormalizerLocked(), idx.exact != nil})
+	if err != nil {
+		return err
+	}
+	bw.Write(header[:len(header)-1])
+	bw.WriteString(`,"docs":`)
+	writeIDArray(bw, idx.docs)
+	bw.WriteString(`,"ngrams":[`)
+
+	keys := make([]uint64, 0, len(idx.bitmaps))
+	for key := range idx.bitmaps {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var num [20]byte
+	for i, key := range keys {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('\n')
+		if ngram, ok := idx.ngramText(key); ok {
+			text, _ := json.Marshal(ngram)
+			bw.WriteString(`{"ngram":`)
+			bw.Write(text)
+		} else {
+			bw.WriteString(`{"key":"`)
+			bw.Write(strconv.AppendUint(num[:0], key, 10))
+			bw.WriteByte('"')
+		}
+		bw.WriteString(`,"docs":`)
+		writeIDArray(bw, idx.bitmaps[key])
+		bw.WriteByte('}')
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
+
+// ImportJSON reads an index written by ExportJSON. The gram size, normalizer
+// and exact key mode follow the data; opts can add others such as
+// WithForwardIndex. N-gram text is rekeyed, so exports are portable across
+// versions with different key schemes.
+func ImportJSON(r io.Reader, opts ...Option) (*Index, error) {
+	var data exportedIndex
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	if data.GramSize < 1 || data.GramSize > 8 {
+		return nil, ErrInvalidGramSize
+	}
+
+	idx := NewIndex(data.GramSize, opts...)
+	if data.Normalizer != "" {
+		chain, err := ParseNormalizerChain(data.Normalizer)
+		if err != nil {
+			return nil, err
+		}
+		WithNormalizerChain(chain).applyIndex(idx)
+	}
+	if data.ExactKeys {
+		WithExactKeys().applyIndex(idx)
+	}
+
+	idx.docs.AddMany(data.Docs)
+	for _, ng := range data.Ngrams {
+		key := ng.Key
+		if ng.Ngram != "" {
+			if utf8.RuneCountInString(ng.Ngram) != data.GramSize {
+				return nil, fmt.Errorf("%w: n-gram %q", ErrInvalidGramSize, ng.Ngram)
+			}
+			key = idx.indexKey([]rune(ng.Ngram))
+		}
+		bm := roaring.BitmapOf(ng.Docs...)
+		if existing, ok := idx.bitmaps[key]; ok {
+			existing.Or(bm) // hash collision in a non-exact export
+		} else {
+			idx.bitmaps[key] = bm
+		}
+		idx.docs.Or(bm)
+	}
+	if idx.forward != nil {
+		for key, bm := range idx.bitmaps {
+			idx.forward.recordBitmap(key, bm)
+		}
+	}
+	return idx, nil
+}
+
+// writeIDArray writes bm as a JSON array of docIDs.
+func writeIDArray(bw *bufio.Writer, bm *roaring.Bitmap) {
+	var num [10]byte
+	bw.WriteByte('[')
+	it := bm.Iterator()
+	for first := true; it.HasNext(); first = false {
+		if !first {
+			bw.WriteByte(',')
+		}
+		bw.Write(strconv.AppendUint(num[:0], uint64(it.Next()), 10))
+	}
+	bw.WriteByte(']')
+}
+
+// sortedFieldsLocked returns the filter's fields and each field's categories
+// in sorted order.
+func (c *BitmapFilter) sortedFieldsLocked() ([]string, map[string][]string) {
+	fields := make([]string, 0, len(c.fields))
+	cats := make(map[string][]string, len(c.fields))
+	for field, fieldMap := range c.fields {
+		fields = append(fields, field)
+		list := make([]string, 0, len(fieldMap))
+		for cat := range fieldMap {
+			list = append(list, cat)
+		}
+		slices.Sort(list)
+		cats[field] = list
+	}
+	slices.Sort(fields)
+	return fields, cats
+}
+
+// ExportJSON writes the filter as a JSON object mapping each field to its
+// categories' docIDs, one category per line:
+//
+//	{"genre":{
+//	"fable":[1,2],
+//	"howto":[4,5]}}
+func (c *BitmapFilter) ExportJSON(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	fields, cats := c.sortedFieldsLocked()
+	bw.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			bw.WriteString(",\n")
+		}
+		name, _ := json.Marshal(field)
+		bw.Write(name)
+		bw.WriteString(":{")
+		for j, cat := range cats[field] {
+			if j > 0 {
+				bw.WriteByte(',')
+			}
+			name, _ := json.Marshal(cat)
+			bw.WriteByte('\n')
+			bw.Write(name)
+			bw.WriteByte(':')
+			writeIDArray(bw, c.fields[field][cat])
+		}
+		bw.WriteByte('}')
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// ImportBitmapFilterJSON reads a filter written by BitmapFilter.ExportJSON.
+func ImportBitmapFilterJSON(r io.Reader) (*BitmapFilter, error) {
+	var data map[string]map[string][]uint32
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	c := NewBitmapFilter()
+	for _, field := range slices.Sorted(maps.Keys(data)) {
+		cats := data[field]
+		for _, cat := range slices.Sorted(maps.Keys(cats)) {
+			bm := roaring.BitmapOf(cats[cat]...)
+			c.putCategoryLocked(field, cat, bm)
+			c.all.Or(bm)
+		}
+	}
+	return c, nil
+}
+
+// ExportCSV writes the filter as "doc_id,field,category" records, with a
+// header, sorted by field, category and docID.
+func (c *BitmapFilter) ExportCSV(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"doc_id", "field", "category"})
+	fields, cats := c.sortedFieldsLocked()
+	record := make([]string, 3)
+	for _, field := range fields {
+		for _, cat := range cats[field] {
+			it := c.fields[field][cat].Iterator()
+			for it.HasNext() {
+				record[0] = strconv.FormatUint(uint64(it.Next()), 10)
+				record[1], record[2] = field, cat
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportBitmapFilterCSV reads "doc_id,field,category" records, such as those
+// written by BitmapFilter.ExportCSV. A header record is skipped.
+func ImportBitmapFilterCSV(r io.Reader) (*BitmapFilter, error) {
+	c := NewBitmapFilter()
+	err := readCSVRecords(r, 3, func(record []string) error {
+		docID, err := strconv.ParseUint(record[0], 10, 32)
+		if err != nil {
+			return err
+		}
+		c.setLocked(uint32(docID), record[1], record[2])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readCSVRecords calls fn for every record of r after an optional header
+// whose first column is "doc_id". Each record must have n columns.
+func readCSVRecords(r io.Reader, n int, fn func(record []string) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = n
+	cr.ReuseRecord = true
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read csv: %w", err)
+		}
+		if line == 1 && record[0] == "doc_id" {
+			continue
+		}
+		if err := fn(record); err != nil {
+			return fmt.Errorf("record %d: %w", line, err)
+		}
+	}
+}
+
+// eachValueLocked calls fn for every document whose value is not the zero
+// value, in docID order.
+func (col *SortColumn[T]) eachValueLocked(fn func(docID uint32, v T) error) error {
+	var zero T
+	if col.pages == nil {
+		for id, v := range col.values {
+			if v != zero {
+				if err := fn(uint32(id), v); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	pageIDs := make([]uint32, 0, len(col.pages))
+	for pageID := range col.pages {
+		pageIDs = append(pageIDs, pageID)
+	}
+	slices.Sort(pageIDs)
+	for _, pageID := range pageIDs {
+		for i, v := range col.pages[pageID] {
+			if v != zero {
+				if err := fn(pageID<<sortColumnPageBits|uint32(i), v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ExportJSON writes the column's values as a JSON array of {"id","value"}
+// objects in docID order, one per line. Values equal to the zero value are
+// omitted, since Get does not distinguish them from unset ones.
+func (col *SortColumn[T]) ExportJSON(w io.Writer) error {
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteByte('[')
+	first := true
+	var num [10]byte
+	err := col.eachValueLocked(func(docID uint32, v T) error {
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		bw.WriteString("\n{\"id\":")
+		bw.Write(strconv.AppendUint(num[:0], uint64(docID), 10))
+		bw.WriteString(`,"value":`)
+		bw.Write(value)
+		bw.WriteByte('}')
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// ImportSortColumnJSON reads a column written by SortColumn.ExportJSON.
+func ImportSortColumnJSON[T cmp.Ordered](r io.Reader, opts ...SortColumnOption) (*SortColumn[T], error) {
+	var data []struct {
+		ID    uint32 `json:"id"`
+		Value T      `json:"value"`
+	}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	col := NewSortColumn[T](opts...)
+	batch := col.BatchSize(len(data))
+	for _, d := range data {
+		batch.Add(d.ID, d.Value)
+	}
+	batch.Flush()
+	return col, nil
+}
+
+// ExportCSV writes the column as "doc_id,value" records with a header, in
+// docID order, omitting zero values as ExportJSON does.
+func (col *SortColumn[T]) ExportCSV(w io.Writer) error {
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"doc_id", "value"})
+	record := make([]string, 2)
+	err := col.eachValueLocked(func(docID uint32, v T) error {
+		record[0] = strconv.FormatUint(uint64(docID), 10)
+		record[1] = fmt.Sprint(v)
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportSortColumnCSV reads "doc_id,value" records, such as those written by
+// SortColumn.ExportCSV. A header record is skipped.
+func ImportSortColumnCSV[T cmp.Ordered](r io.Reader, opts ...SortColumnOption) (*SortColumn[T], error) {
+	col := NewSortColumn[T](opts...)
+	batch := col.Batch()
+	err := readCSVRecords(r, 2, func(record []string) error {
+		docID, err := strconv.ParseUint(record[0], 10, 32)
+		if err != nil {
+			return err
+		}
+		var v T
+		if s, ok := any(&v).(*string); ok {
+			*s = record[1]
+		} else if _, err := fmt.Sscan(record[1], &v); err != nil {
+			return fmt.Errorf("value %q: %w", record[1], err)
+		}
+		batch.Add(uint32(docID), v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	batch.Flush()
+	return col, nil
+}
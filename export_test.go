@@ -0,0 +1,169 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndexExportJSON(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, "héllo wörld")
+	idx.Add(3, "ab") // too short for an n-gram, still a document
+
+	var buf bytes.Buffer
+	if err := idx.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`{"gramSize":3,"docs":[1,2,3],"ngrams":[`, "\n" + `{"ngram":"hel","docs":[1]}`, `{"key":"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("export missing %s:\n%s", want, out)
+		}
+	}
+
+	var again bytes.Buffer
+	idx.ExportJSON(&again)
+	if again.String() != out {
+		t.Error("export is not deterministic")
+	}
+
+	imported, err := ImportJSON(strings.NewReader(out), WithForwardIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.NgramCount() != idx.NgramCount() || imported.AllDocs().GetCardinality() != 3 {
+		t.Errorf("imported %d n-grams, %d docs", imported.NgramCount(), imported.AllDocs().GetCardinality())
+	}
+	for _, q := range []string{"hello", "héllo wörld", "world"} {
+		if got, want := imported.Search(q), idx.Search(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("imported Search(%q) = %v, want %v", q, got, want)
+		}
+	}
+	imported.Remove(1)
+	if got := imported.Search("hello"); got != nil {
+		t.Errorf("forward index not rebuilt: Search after Remove = %v", got)
+	}
+}
+
+func TestIndexExportJSONExactKeys(t *testing.T) {
+	idx := NewIndex(3, WithExactKeys(), WithNormalizerChain(NormalizerChain{NormalizerLowercase}))
+	idx.Add(1, "Über straße")
+
+	var buf bytes.Buffer
+	if err := idx.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `"key"`) || !strings.Contains(buf.String(), `{"ngram":"übe"`) {
+		t.Errorf("exact keys not exported as text:\n%s", buf.String())
+	}
+	imported, err := ImportJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported.HasExactKeys() || !reflect.DeepEqual(imported.NormalizerChain(), idx.NormalizerChain()) {
+		t.Error("exact keys or normalizer not restored")
+	}
+	if got := imported.Search("ÜBER"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(ÜBER) = %v, want [1]", got)
+	}
+
+	for _, bad := range []string{`{"gramSize":0}`, `{"gramSize":3,"ngrams":[{"ngram":"toolong","docs":[1]}]}`, `[`} {
+		if _, err := ImportJSON(strings.NewReader(bad)); err == nil {
+			t.Errorf("ImportJSON(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestBitmapFilterExport(t *testing.T) {
+	filter := NewBitmapFilter()
+	filter.Set(1, "genre", "fable")
+	filter.Set(2, "genre", "fable")
+	filter.Set(4, "genre", "how, to")
+	filter.Set(1, "lang", "en")
+
+	var js bytes.Buffer
+	if err := filter.ExportJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"genre\":{\n\"fable\":[1,2],\n\"how, to\":[4]},\n\"lang\":{\n\"en\":[1]}}\n"
+	if js.String() != want {
+		t.Errorf("ExportJSON =\n%s\nwant\n%s", js.String(), want)
+	}
+	var csvBuf bytes.Buffer
+	if err := filter.ExportCSV(&csvBuf); err != nil {
+		t.Fatal(err)
+	}
+	want = "doc_id,field,category\n1,genre,fable\n2,genre,fable\n4,genre,\"how, to\"\n1,lang,en\n"
+	if csvBuf.String() != want {
+		t.Errorf("ExportCSV =\n%s\nwant\n%s", csvBuf.String(), want)
+	}
+
+	fromJSON, err := ImportBitmapFilterJSON(&js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCSV, err := ImportBitmapFilterCSV(&csvBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []*BitmapFilter{fromJSON, fromCSV} {
+		if !reflect.DeepEqual(got.AllCounts(), filter.AllCounts()) || got.DocCount() != 3 {
+			t.Errorf("imported counts = %v, want %v", got.AllCounts(), filter.AllCounts())
+		}
+	}
+
+	if _, err := ImportBitmapFilterCSV(strings.NewReader("x,genre,fable\n")); err == nil {
+		t.Error("bad docID imported")
+	}
+}
+
+func TestSortColumnExport(t *testing.T) {
+	ratings := NewSortColumn[float64]()
+	ratings.Set(1, 4.5)
+	ratings.Set(3, 0.1)
+	ratings.Set(2, 0) // indistinguishable from unset, omitted
+
+	var js, csvBuf bytes.Buffer
+	if err := ratings.ExportJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\n{\"id\":1,\"value\":4.5},\n{\"id\":3,\"value\":0.1}]\n"; js.String() != want {
+		t.Errorf("ExportJSON = %q, want %q", js.String(), want)
+	}
+	if err := ratings.ExportCSV(&csvBuf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "doc_id,value\n1,4.5\n3,0.1\n"; csvBuf.String() != want {
+		t.Errorf("ExportCSV = %q, want %q", csvBuf.String(), want)
+	}
+
+	fromJSON, err := ImportSortColumnJSON[float64](&js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCSV, err := ImportSortColumnCSV[float64](&csvBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range []*SortColumn[float64]{fromJSON, fromCSV} {
+		if col.Get(1) != 4.5 || col.Get(3) != 0.1 || col.Get(2) != 0 {
+			t.Errorf("imported values = %v %v %v", col.Get(1), col.Get(2), col.Get(3))
+		}
+	}
+
+	names := NewSortColumn[string](WithSparseValues())
+	names.Set(5000, "bob smith")
+	names.Set(7, "alice")
+	var out bytes.Buffer
+	names.ExportCSV(&out)
+	imported, err := ImportSortColumnCSV[string](&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Get(5000) != "bob smith" || imported.Get(7) != "alice" {
+		t.Errorf("string column round trip = %q %q", imported.Get(5000), imported.Get(7))
+	}
+}
@@ -0,0 +1,73 @@
+package roaringsearch
+
+import "github.com/RoaringBitmap/roaring/v2"
+
+// maxMaskFields is the number of fields a FieldMatch mask can hold.
+const maxMaskFields = 64
+
+// FieldMatch is a document found by SearchFields with a bitmask of the
+// fields it matched in: bit i is set when fields[i] matched.
+type FieldMatch struct {
+	DocID  uint32
+	Fields uint64
+}
+
+// Has reports whether the document matched in fields[field].
+func (m FieldMatch) Has(field int) bool {
+	return field >= 0 && field < maxMaskFields && m.Fields&(1<<field) != 0
+}
+
+// SearchFields runs SearchBitmap for the query against one index per field
+// and returns the documents matching in any of them, in docID order, each
+// with the mask of fields it matched in, so a UI can badge whether a hit was
+// in the title, body or tags without searching again. Masks are computed in
+// one pass over the union of the per-field bitmaps. Only the first 64
+// fields are searched.
+//
+// Example:
+//
+//	for _, m := range rs.SearchFields("fox", title, body, tags) {
+//	    if m.Has(0) {
+//	        // matched in the title
+//	    }
+//	}
+func SearchFields(query string, fields ...*Index) []FieldMatch {
+	if len(fields) > maxMaskFields {
+		fields = fields[:maxMaskFields]
+	}
+	bitmaps := make([]*roaring.Bitmap, len(fields))
+	for i, field := range fields {
+		bitmaps[i] = field.SearchBitmap(query)
+	}
+	return fieldMasks(bitmaps)
+}
+
+// fieldMasks returns the union of the bitmaps with, for each document, the
+// mask of the bitmaps containing it.
+func fieldMasks(bitmaps []*roaring.Bitmap) []FieldMatch {
+	union := roaring.FastOr(bitmaps...)
+	if union.IsEmpty() {
+		return nil
+	}
+
+	matches := make([]FieldMatch, 0, union.GetCardinality())
+	its := make([]roaring.IntPeekable, len(bitmaps))
+	for i, bm := range bitmaps {
+		its[i] = bm.Iterator()
+	}
+	it := union.Iterator()
+	for it.HasNext() {
+		docID := it.Next()
+		var mask uint64
+		for i, fieldIt := range its {
+			// Every field iterator trails the union, so advancing to docID
+			// visits each field bitmap once over the whole loop
+			fieldIt.AdvanceIfNeeded(docID)
+			if fieldIt.HasNext() && fieldIt.PeekNext() == docID {
+				mask |= 1 << i
+			}
+		}
+		matches = append(matches, FieldMatch{DocID: docID, Fields: mask})
+	}
+	return matches
+}
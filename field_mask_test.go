@@ -0,0 +1,31 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchFields(t *testing.T) {
+	title, body, tags := NewIndex(3), NewIndex(3), NewIndex(3)
+	title.Add(1, "The quick fox")
+	body.Add(1, testQuickBrownFox)
+	body.Add(2, "a fox in the henhouse")
+	tags.Add(3, "fox animals")
+	title.Add(4, testHelloWorld)
+
+	want := []FieldMatch{{1, 0b011}, {2, 0b010}, {3, 0b100}}
+	got := SearchFields("fox", title, body, tags)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SearchFields = %v, want %v", got, want)
+	}
+	if !got[0].Has(0) || !got[0].Has(1) || got[0].Has(2) || got[0].Has(-1) || got[0].Has(64) {
+		t.Errorf("Has on mask %b is wrong", got[0].Fields)
+	}
+
+	if got := SearchFields("zebra", title, body, tags); got != nil {
+		t.Errorf("no match = %v, want nil", got)
+	}
+	if got := SearchFields("fox"); got != nil {
+		t.Errorf("no fields = %v, want nil", got)
+	}
+}
@@ -3,6 +3,7 @@ package roaringsearch
 import (
 	"cmp"
 	"container/heap"
+	"context"
 	"io"
 	"os"
 	"slices"
@@ -31,6 +32,8 @@ import (
 type BitmapFilter struct {
 	mu     sync.RWMutex
 	fields map[string]map[string]*roaring.Bitmap
+	all    *roaring.Bitmap // every docID assigned to any category
+	cats   categoryTable   // interned IDs of the categories; see CategoryID
 	dirty  atomic.Bool
 }
 
@@ -38,6 +41,7 @@ type BitmapFilter struct {
 func NewBitmapFilter() *BitmapFilter {
 	return &BitmapFilter{
 		fields: make(map[string]map[string]*roaring.Bitmap),
+		all:    roaring.New(),
 	}
 }
 
@@ -49,49 +53,144 @@ func (c *BitmapFilter) Set(docID uint32, field, category string) {
 }
 
 func (c *BitmapFilter) setLocked(docID uint32, field, category string) {
-	fieldMap, ok := c.fields[field]
-	if !ok {
-		fieldMap = make(map[string]*roaring.Bitmap)
-		c.fields[field] = fieldMap
-	}
-
-	bm, ok := fieldMap[category]
-	if !ok {
-		bm = roaring.New()
-		fieldMap[category] = bm
-	}
-	bm.Add(docID)
+	c.categoryLocked(field, category).Add(docID)
+	c.all.Add(docID)
 	c.dirty.Store(true)
 }
 
 // FilterBatch accumulates entries for efficient batch insertion.
+// It is safe for concurrent use. By default entries go to one buffer; with
+// WithFilterBatchShards, concurrent producers spread over several so they do
+// not contend on one lock.
 type FilterBatch struct {
-	filter     *BitmapFilter
-	field      string
+	filter *BitmapFilter
+	field  string
+	shards []filterBatchShard
+	next   atomic.Uint32 // round-robin shard selector
+	rows   atomic.Int64  // buffered entries across all shards
+
+	maxRows  int
+	flushing sync.Mutex // held while a flush is applied
+}
+
+// filterBatchShard is one producer buffer of a FilterBatch.
+type filterBatchShard struct {
+	mu         sync.Mutex
 	docIDs     []uint32
 	categories []string
+	_          [8]byte // pad to a cache line so shards do not false-share
+}
+
+// FilterBatchOption configures a FilterBatch.
+type FilterBatchOption func(*FilterBatch)
+
+// WithFilterAutoFlush flushes the batch whenever it holds n entries, so a
+// load of any size needs bounded memory. The Add that reaches the threshold
+// applies the flush.
+func WithFilterAutoFlush(n int) FilterBatchOption {
+	return func(b *FilterBatch) {
+		b.maxRows = n
+	}
+}
+
+// WithFilterBatchShards spreads entries over n internal buffers, for
+// batches fed by several goroutines. Default is 1.
+func WithFilterBatchShards(n int) FilterBatchOption {
+	return func(b *FilterBatch) {
+		if n > 0 {
+			b.shards = make([]filterBatchShard, n)
+		}
+	}
 }
 
 // Batch creates a new batch builder for the given field.
 // Use BatchSize for better performance when you know the approximate count.
-func (c *BitmapFilter) Batch(field string) *FilterBatch {
-	return c.BatchSize(field, 1024)
+func (c *BitmapFilter) Batch(field string, opts ...FilterBatchOption) *FilterBatch {
+	return c.BatchSize(field, 1024, opts...)
 }
 
 // BatchSize creates a batch builder with pre-allocated capacity.
-func (c *BitmapFilter) BatchSize(field string, size int) *FilterBatch {
-	return &FilterBatch{
-		filter:     c,
-		field:      field,
-		docIDs:     make([]uint32, 0, size),
-		categories: make([]string, 0, size),
+func (c *BitmapFilter) BatchSize(field string, size int, opts ...FilterBatchOption) *FilterBatch {
+	b := &FilterBatch{
+		filter: c,
+		field:  field,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.shards == nil {
+		b.shards = make([]filterBatchShard, 1)
+	}
+	if b.maxRows > 0 {
+		size = min(size, b.maxRows)
 	}
+
+	perShard := (size + len(b.shards) - 1) / len(b.shards)
+	for i := range b.shards {
+		b.shards[i].docIDs = make([]uint32, 0, perShard)
+		b.shards[i].categories = make([]string, 0, perShard)
+	}
+	return b
 }
 
-// Add adds a document with a category to the batch.
+// Add adds a document with a category to the batch. With WithFilterAutoFlush
+// set, reaching the threshold flushes the batch.
 func (b *FilterBatch) Add(docID uint32, category string) {
-	b.docIDs = append(b.docIDs, docID)
-	b.categories = append(b.categories, category)
+	shard := &b.shards[0]
+	if len(b.shards) > 1 {
+		shard = &b.shards[int(b.next.Add(1))%len(b.shards)]
+	}
+	shard.mu.Lock()
+	shard.docIDs = append(shard.docIDs, docID)
+	shard.categories = append(shard.categories, category)
+	shard.mu.Unlock()
+
+	if rows := b.rows.Add(1); b.maxRows > 0 && rows >= int64(b.maxRows) {
+		b.Flush()
+	}
+}
+
+// Len returns the number of buffered entries.
+func (b *FilterBatch) Len() int {
+	return int(b.rows.Load())
+}
+
+// drain takes every buffered entry out of the shards.
+func (b *FilterBatch) drain() ([]uint32, []string) {
+	if len(b.shards) == 1 {
+		shard := &b.shards[0]
+		shard.mu.Lock()
+		docIDs, categories := shard.docIDs, shard.categories
+		shard.docIDs = make([]uint32, 0, cap(docIDs))
+		shard.categories = make([]string, 0, cap(categories))
+		shard.mu.Unlock()
+		b.rows.Add(-int64(len(docIDs)))
+		return docIDs, categories
+	}
+
+	docIDs := make([]uint32, 0, b.Len())
+	categories := make([]string, 0, b.Len())
+	for i := range b.shards {
+		shard := &b.shards[i]
+		shard.mu.Lock()
+		docIDs = append(docIDs, shard.docIDs...)
+		categories = append(categories, shard.categories...)
+		shard.docIDs = shard.docIDs[:0]
+		shard.categories = shard.categories[:0]
+		shard.mu.Unlock()
+	}
+	b.rows.Add(-int64(len(docIDs)))
+	return docIDs, categories
+}
+
+// restore puts entries back into the batch after a cancelled flush.
+func (b *FilterBatch) restore(docIDs []uint32, categories []string) {
+	shard := &b.shards[0]
+	shard.mu.Lock()
+	shard.docIDs = append(shard.docIDs, docIDs...)
+	shard.categories = append(shard.categories, categories...)
+	shard.mu.Unlock()
+	b.rows.Add(int64(len(docIDs)))
 }
 
 // findCategoryIndex returns the index of category in the list, or -1 if not found.
@@ -105,12 +204,12 @@ func findCategoryIndex(categoryList []string, cat string) int {
 }
 
 // buildCategoryGroups builds category list, indices, and grouped doc IDs.
-func (b *FilterBatch) buildCategoryGroups() ([]string, [][]uint32) {
-	n := len(b.docIDs)
+func buildCategoryGroups(docIDs []uint32, categories []string) ([]string, [][]uint32) {
+	n := len(docIDs)
 	categoryList := make([]string, 0, 16)
 	indices := make([]int, n)
 
-	for i, cat := range b.categories {
+	for i, cat := range categories {
 		idx := findCategoryIndex(categoryList, cat)
 		if idx == -1 {
 			idx = len(categoryList)
@@ -131,7 +230,7 @@ func (b *FilterBatch) buildCategoryGroups() ([]string, [][]uint32) {
 	}
 
 	for i, idx := range indices {
-		groups[idx] = append(groups[idx], b.docIDs[i])
+		groups[idx] = append(groups[idx], docIDs[i])
 	}
 
 	return categoryList, groups
@@ -152,31 +251,38 @@ func addToBitmapsParallel(bitmaps []*roaring.Bitmap, groups [][]uint32) {
 
 // Flush commits all accumulated entries to the filter.
 func (b *FilterBatch) Flush() {
-	if len(b.docIDs) == 0 {
-		return
+	_ = b.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush but returns ctx's error without modifying the
+// filter if ctx is cancelled before the entries are applied. The entries stay
+// buffered so the flush can be retried.
+func (b *FilterBatch) FlushContext(ctx context.Context) error {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+
+	docIDs, categories := b.drain()
+	if len(docIDs) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		b.restore(docIDs, categories)
+		return err
 	}
 
-	categoryList, groups := b.buildCategoryGroups()
+	categoryList, groups := buildCategoryGroups(docIDs, categories)
 	numCats := len(categoryList)
+	if err := ctx.Err(); err != nil {
+		b.restore(docIDs, categories)
+		return err
+	}
 
 	b.filter.mu.Lock()
 	defer b.filter.mu.Unlock()
 
-	fieldMap, ok := b.filter.fields[b.field]
-	if !ok {
-		fieldMap = make(map[string]*roaring.Bitmap)
-		b.filter.fields[b.field] = fieldMap
-	}
-
 	bitmaps := make([]*roaring.Bitmap, numCats)
 	for idx := range groups {
-		cat := categoryList[idx]
-		bm, ok := fieldMap[cat]
-		if !ok {
-			bm = roaring.New()
-			fieldMap[cat] = bm
-		}
-		bitmaps[idx] = bm
+		bitmaps[idx] = b.filter.categoryLocked(b.field, categoryList[idx])
 	}
 
 	if numCats >= 4 {
@@ -186,9 +292,9 @@ func (b *FilterBatch) Flush() {
 			bitmaps[idx].AddMany(ids)
 		}
 	}
-
-	b.docIDs = b.docIDs[:0]
-	b.categories = b.categories[:0]
+	b.filter.all.AddMany(docIDs)
+	b.filter.dirty.Store(true)
+	return nil
 }
 
 // Remove removes a document from all categories across all fields.
@@ -201,9 +307,73 @@ func (c *BitmapFilter) Remove(docID uint32) {
 			bm.Remove(docID)
 		}
 	}
+	c.all.Remove(docID)
+	c.dirty.Store(true)
+}
+
+// RemoveMany removes a batch of documents from all categories across all fields
+// with one AndNot per category bitmap.
+func (c *BitmapFilter) RemoveMany(docIDs []uint32) {
+	if len(docIDs) == 0 {
+		return
+	}
+	victims := roaring.BitmapOf(docIDs...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, fieldMap := range c.fields {
+		for _, bm := range fieldMap {
+			bm.AndNot(victims)
+		}
+	}
+	c.all.AndNot(victims)
 	c.dirty.Store(true)
 }
 
+// RemoveFromField removes a document from every category of one field.
+func (c *BitmapFilter) RemoveFromField(docID uint32, field string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeFromFieldLocked(docID, field)
+}
+
+func (c *BitmapFilter) removeFromFieldLocked(docID uint32, field string) {
+	for _, bm := range c.fields[field] {
+		bm.Remove(docID)
+	}
+	c.dirty.Store(true)
+}
+
+// ReplaceSet replaces a document's categories within a field.
+// The document is removed from all existing categories of the field and then
+// added to each of the given categories, under a single lock.
+func (c *BitmapFilter) ReplaceSet(docID uint32, field string, categories []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeFromFieldLocked(docID, field)
+	for _, cat := range categories {
+		c.setLocked(docID, field, cat)
+	}
+}
+
+// GetCategories returns the categories a document belongs to within a field, sorted.
+// Returns nil if the document has no categories in the field.
+func (c *BitmapFilter) GetCategories(docID uint32, field string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var cats []string
+	for cat, bm := range c.fields[field] {
+		if bm.Contains(docID) {
+			cats = append(cats, cat)
+		}
+	}
+	slices.Sort(cats)
+	return cats
+}
+
 // Get returns a bitmap of documents in the given category for a field.
 // Returns nil if field or category doesn't exist.
 func (c *BitmapFilter) Get(field, category string) *roaring.Bitmap {
@@ -236,6 +406,50 @@ func (c *BitmapFilter) GetAny(field string, categories []string) *roaring.Bitmap
 	return result
 }
 
+// AllDocs returns a copy of the bitmap of every document assigned to any category.
+func (c *BitmapFilter) AllDocs() *roaring.Bitmap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.all.Clone()
+}
+
+// DocCount returns the number of documents assigned to any category.
+func (c *BitmapFilter) DocCount() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.all.GetCardinality()
+}
+
+// GetNot returns documents that are not in the given category (NOT).
+// The complement is taken against universe, or against every document known
+// to the filter when universe is nil.
+func (c *BitmapFilter) GetNot(field, category string, universe *roaring.Bitmap) *roaring.Bitmap {
+	return c.GetNone(field, []string{category}, universe)
+}
+
+// GetNone returns documents in NONE of the given categories.
+// The complement is taken against universe, or against every document known
+// to the filter when universe is nil.
+func (c *BitmapFilter) GetNone(field string, categories []string, universe *roaring.Bitmap) *roaring.Bitmap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result *roaring.Bitmap
+	if universe != nil {
+		result = universe.Clone()
+	} else {
+		result = c.all.Clone()
+	}
+
+	fieldMap := c.fields[field]
+	for _, cat := range categories {
+		if bm, ok := fieldMap[cat]; ok {
+			result.AndNot(bm)
+		}
+	}
+	return result
+}
+
 // Categories returns all category values for a given field.
 func (c *BitmapFilter) Categories(field string) []string {
 	c.mu.RLock()
@@ -286,6 +500,129 @@ func (c *BitmapFilter) AllCounts() map[string]map[string]uint64 {
 	return result
 }
 
+// Facets returns per-category counts for several fields restricted to a result set.
+// Fields are counted concurrently under a single read lock. A nil result bitmap
+// counts all documents, matching AllCounts. Unknown fields are omitted.
+func (c *BitmapFilter) Facets(result *roaring.Bitmap, fields ...string) map[string]map[string]uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	facets := make(map[string]map[string]uint64, len(fields))
+	counts := make([]map[string]uint64, len(fields))
+
+	var wg sync.WaitGroup
+	for i, field := range fields {
+		fieldMap, ok := c.fields[field]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, fieldMap map[string]*roaring.Bitmap) {
+			defer wg.Done()
+			counts[i] = facetCounts(fieldMap, result)
+		}(i, fieldMap)
+	}
+	wg.Wait()
+
+	for i, field := range fields {
+		if counts[i] != nil {
+			facets[field] = counts[i]
+		}
+	}
+	return facets
+}
+
+// facetCounts counts each category of a field, intersected with result when non-nil.
+func facetCounts(fieldMap map[string]*roaring.Bitmap, result *roaring.Bitmap) map[string]uint64 {
+	counts := make(map[string]uint64, len(fieldMap))
+	for cat, bm := range fieldMap {
+		if result == nil {
+			counts[cat] = bm.GetCardinality()
+			continue
+		}
+		if n := bm.AndCardinality(result); n > 0 {
+			counts[cat] = n
+		}
+	}
+	return counts
+}
+
+// CategoryCount holds a category and its document count.
+type CategoryCount struct {
+	Category string
+	Count    uint64
+}
+
+// TopCategories returns the k categories of a field with the most documents,
+// highest count first (ties broken by category name). When result is non-nil,
+// counts are restricted to documents in result and empty categories are skipped.
+// A bounded heap keeps memory proportional to k rather than the number of categories.
+func (c *BitmapFilter) TopCategories(field string, k int, result *roaring.Bitmap) []CategoryCount {
+	if k <= 0 {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	h := &categoryHeap{items: make([]CategoryCount, 0, k)}
+	for cat, bm := range c.fields[field] {
+		var n uint64
+		if result == nil {
+			n = bm.GetCardinality()
+		} else {
+			n = bm.AndCardinality(result)
+		}
+		if n == 0 {
+			continue
+		}
+
+		item := CategoryCount{Category: cat, Count: n}
+		if h.Len() < k {
+			heap.Push(h, item)
+		} else if categoryCountBefore(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+
+	top := make([]CategoryCount, h.Len())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i] = heap.Pop(h).(CategoryCount)
+	}
+	return top
+}
+
+// categoryCountBefore reports whether a ranks ahead of b: higher count, then lower name.
+func categoryCountBefore(a, b CategoryCount) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Category < b.Category
+}
+
+// categoryHeap is a min-heap keeping the weakest of the current top-k at the root.
+type categoryHeap struct {
+	items []CategoryCount
+}
+
+func (h *categoryHeap) Len() int { return len(h.items) }
+
+func (h *categoryHeap) Less(i, j int) bool { return categoryCountBefore(h.items[j], h.items[i]) }
+
+func (h *categoryHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *categoryHeap) Push(x any) {
+	h.items = append(h.items, x.(CategoryCount))
+}
+
+func (h *categoryHeap) Pop() any {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items = h.items[:n-1]
+	return item
+}
+
 // MemoryUsage returns the total memory used by all bitmaps in bytes.
 func (c *BitmapFilter) MemoryUsage() uint64 {
 	c.mu.RLock()
@@ -300,7 +637,7 @@ func (c *BitmapFilter) MemoryUsage() uint64 {
 	return total
 }
 
-// bitmapFilterData is the serializable representation.
+// bitmapFilterData is the legacy msgpack representation, still accepted by ReadBitmapFilter.
 type bitmapFilterData struct {
 	Fields map[string]map[string][]byte `msgpack:"fields"`
 }
@@ -347,38 +684,6 @@ func (c *BitmapFilter) SaveToFile(path string) error {
 	return nil
 }
 
-// Encode writes the bitmap filter to a writer.
-// Takes a snapshot of the data first to avoid holding the lock during I/O.
-func (c *BitmapFilter) Encode(w io.Writer) error {
-	// Snapshot data while holding lock briefly
-	c.mu.RLock()
-	data := bitmapFilterData{
-		Fields: make(map[string]map[string][]byte, len(c.fields)),
-	}
-
-	for field, fieldMap := range c.fields {
-		data.Fields[field] = make(map[string][]byte, len(fieldMap))
-		for cat, bm := range fieldMap {
-			bmBytes, err := bm.ToBytes()
-			if err != nil {
-				c.mu.RUnlock()
-				return err
-			}
-			data.Fields[field][cat] = bmBytes
-		}
-	}
-	c.mu.RUnlock()
-
-	// Write without holding lock - safe for concurrent reads/writes
-	enc := msgpck.GetStructEncoder[bitmapFilterData]()
-	encoded, err := enc.Encode(&data)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(encoded)
-	return err
-}
-
 // LoadBitmapFilter loads a bitmap filter from a file.
 func LoadBitmapFilter(path string) (*BitmapFilter, error) {
 	file, err := os.Open(path)
@@ -389,37 +694,6 @@ func LoadBitmapFilter(path string) (*BitmapFilter, error) {
 	return ReadBitmapFilter(file)
 }
 
-// ReadBitmapFilter reads a bitmap filter from a reader.
-func ReadBitmapFilter(r io.Reader) (*BitmapFilter, error) {
-	data, err := io.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-
-	var decoded bitmapFilterData
-	dec := msgpck.GetStructDecoder[bitmapFilterData](false)
-	if err := dec.Decode(data, &decoded); err != nil {
-		return nil, err
-	}
-
-	c := &BitmapFilter{
-		fields: make(map[string]map[string]*roaring.Bitmap, len(decoded.Fields)),
-	}
-
-	for field, fieldMap := range decoded.Fields {
-		c.fields[field] = make(map[string]*roaring.Bitmap, len(fieldMap))
-		for cat, bmBytes := range fieldMap {
-			bm := roaring.New()
-			if err := bm.UnmarshalBinary(bmBytes); err != nil {
-				return nil, err
-			}
-			c.fields[field][cat] = bm
-		}
-	}
-
-	return c, nil
-}
-
 // SortColumn provides a typed columnar array for sorting documents by a value.
 // Uses heap-based partial sort for efficient top-K queries.
 //
@@ -434,13 +708,46 @@ func ReadBitmapFilter(r io.Reader) (*BitmapFilter, error) {
 //
 //	// Sort filtered docs from a bitmap
 //	results := ratings.SortBitmapDesc(filteredBitmap, 100)
+//
+// Values are stored in a dense slice indexed by docID. When docIDs are sparse
+// (e.g. a single Set(2_000_000_000, v)), the column switches to fixed-size pages
+// allocated on demand; see WithSparseValues.
 type SortColumn[T cmp.Ordered] struct {
 	mu       sync.RWMutex
 	values   []T
+	pages    map[uint32][]T // non-nil when using paged storage
 	maxDocID uint32
 	dirty    atomic.Bool
 }
 
+const (
+	sortColumnPageBits = 12
+	sortColumnPageSize = 1 << sortColumnPageBits
+	sortColumnPageMask = sortColumnPageSize - 1
+
+	// sparseJumpThreshold is how far past twice the dense length a docID must be
+	// before the column switches to paged storage instead of growing the slice.
+	sparseJumpThreshold = 1 << 20
+)
+
+// sortColumnConfig holds options for NewSortColumn.
+type sortColumnConfig struct {
+	sparse bool
+}
+
+// SortColumnOption configures a SortColumn.
+type SortColumnOption func(*sortColumnConfig)
+
+// WithSparseValues stores values in fixed-size pages allocated on demand instead
+// of a dense slice. Use it when docIDs are large or scattered.
+// Without this option the column switches to paged storage automatically when
+// a docID would grow the dense slice far beyond its current size.
+func WithSparseValues() SortColumnOption {
+	return func(cfg *sortColumnConfig) {
+		cfg.sparse = true
+	}
+}
+
 // SortedResult holds a document ID and its sort value.
 type SortedResult[T cmp.Ordered] struct {
 	DocID uint32
@@ -448,10 +755,26 @@ type SortedResult[T cmp.Ordered] struct {
 }
 
 // NewSortColumn creates a new typed sort column.
-func NewSortColumn[T cmp.Ordered]() *SortColumn[T] {
-	return &SortColumn[T]{
+func NewSortColumn[T cmp.Ordered](opts ...SortColumnOption) *SortColumn[T] {
+	var cfg sortColumnConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	col := &SortColumn[T]{
 		values: make([]T, 0),
 	}
+	if cfg.sparse {
+		col.pages = make(map[uint32][]T)
+	}
+	return col
+}
+
+// IsSparse reports whether the column uses paged storage.
+func (col *SortColumn[T]) IsSparse() bool {
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+	return col.pages != nil
 }
 
 // Set sets the value for a document.
@@ -462,6 +785,14 @@ func (col *SortColumn[T]) Set(docID uint32, value T) {
 }
 
 func (col *SortColumn[T]) setLocked(docID uint32, value T) {
+	if col.pages == nil && col.shouldPageLocked(docID) {
+		col.convertToPagesLocked()
+	}
+	if col.pages != nil {
+		col.setPagedLocked(docID, value)
+		return
+	}
+
 	// Grow array if needed
 	if docID >= uint32(len(col.values)) {
 		newSize := docID + 1
@@ -484,6 +815,75 @@ func (col *SortColumn[T]) setLocked(docID uint32, value T) {
 	col.dirty.Store(true)
 }
 
+// shouldPageLocked reports whether growing the dense slice to hold docID would
+// be a large jump rather than incremental growth.
+func (col *SortColumn[T]) shouldPageLocked(docID uint32) bool {
+	return uint64(docID) >= 2*uint64(len(col.values))+sparseJumpThreshold
+}
+
+// convertToPagesLocked moves dense values into pages, skipping all-zero pages.
+func (col *SortColumn[T]) convertToPagesLocked() {
+	col.pages = make(map[uint32][]T)
+	var zero T
+	for start := 0; start < len(col.values); start += sortColumnPageSize {
+		end := min(start+sortColumnPageSize, len(col.values))
+		chunk := col.values[start:end]
+		if !slices.ContainsFunc(chunk, func(v T) bool { return v != zero }) {
+			continue
+		}
+		page := make([]T, sortColumnPageSize)
+		copy(page, chunk)
+		col.pages[uint32(start>>sortColumnPageBits)] = page
+	}
+	col.values = nil
+}
+
+func (col *SortColumn[T]) setPagedLocked(docID uint32, value T) {
+	pageID := docID >> sortColumnPageBits
+	page, ok := col.pages[pageID]
+	if !ok {
+		page = make([]T, sortColumnPageSize)
+		col.pages[pageID] = page
+	}
+	page[docID&sortColumnPageMask] = value
+
+	if docID > col.maxDocID {
+		col.maxDocID = docID
+	}
+	col.dirty.Store(true)
+}
+
+// valueLocked returns the value for docID, or the zero value if unset.
+func (col *SortColumn[T]) valueLocked(docID uint32) T {
+	if col.pages != nil {
+		page, ok := col.pages[docID>>sortColumnPageBits]
+		if !ok {
+			var zero T
+			return zero
+		}
+		return page[docID&sortColumnPageMask]
+	}
+	if docID >= uint32(len(col.values)) {
+		var zero T
+		return zero
+	}
+	return col.values[docID]
+}
+
+// RemoveMany resets the values of a batch of documents to the zero value.
+func (col *SortColumn[T]) RemoveMany(docIDs []uint32) {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+
+	var zero T
+	for _, docID := range docIDs {
+		if col.valueLocked(docID) != zero {
+			col.setLocked(docID, zero)
+		}
+	}
+	col.dirty.Store(true)
+}
+
 // SortColumnBatch accumulates entries for efficient batch insertion.
 type SortColumnBatch[T cmp.Ordered] struct {
 	col    *SortColumn[T]
@@ -529,6 +929,18 @@ func (b *SortColumnBatch[T]) Flush() {
 	b.col.mu.Lock()
 	defer b.col.mu.Unlock()
 
+	if b.col.pages == nil && b.col.shouldPageLocked(maxID) {
+		b.col.convertToPagesLocked()
+	}
+	if b.col.pages != nil {
+		for i, id := range b.docIDs {
+			b.col.setPagedLocked(id, b.values[i])
+		}
+		b.docIDs = b.docIDs[:0]
+		b.values = b.values[:0]
+		return
+	}
+
 	// Pre-allocate if needed
 	if maxID >= uint32(len(b.col.values)) {
 		newValues := make([]T, maxID+1)
@@ -550,23 +962,23 @@ func (b *SortColumnBatch[T]) Flush() {
 }
 
 // Get returns the value for a document.
+// Documents without a value return the zero value; use FloatColumn or
+// TimeColumn when missing values must be distinguished from zero.
 func (col *SortColumn[T]) Get(docID uint32) T {
 	col.mu.RLock()
 	defer col.mu.RUnlock()
-
-	var zero T
-	if docID >= uint32(len(col.values)) {
-		return zero
-	}
-	return col.values[docID]
+	return col.valueLocked(docID)
 }
 
-// MemoryUsage returns the memory used by the values array in bytes.
+// MemoryUsage returns the memory used by the values array (or pages) in bytes.
 func (col *SortColumn[T]) MemoryUsage() uint64 {
 	col.mu.RLock()
 	defer col.mu.RUnlock()
 
 	var zero T
+	if col.pages != nil {
+		return uint64(len(col.pages)) * sortColumnPageSize * uint64(unsafe.Sizeof(zero))
+	}
 	return uint64(len(col.values)) * uint64(unsafe.Sizeof(zero))
 }
 
@@ -602,25 +1014,25 @@ func (col *SortColumn[T]) SortBitmapDesc(bm *roaring.Bitmap, limit int) []Sorted
 }
 
 func (col *SortColumn[T]) sortLocked(docIDs []uint32, asc bool, limit int) []SortedResult[T] {
+	return sortByValue(docIDs, asc, limit, col.valueLocked)
+}
+
+// sortByValue sorts docIDs by the values returned from value.
+// Uses heap-based partial sort when limit is small relative to input.
+func sortByValue[T cmp.Ordered](docIDs []uint32, asc bool, limit int, value func(uint32) T) []SortedResult[T] {
 	if len(docIDs) == 0 {
 		return nil
 	}
 
-	values := col.values
-
 	// Use heap for partial sort when limit is small relative to input
 	if limit > 0 && limit < len(docIDs)/4 {
-		return col.heapSort(docIDs, values, asc, limit)
+		return heapSort(docIDs, asc, limit, value)
 	}
 
 	// Full sort
 	results := make([]SortedResult[T], len(docIDs))
 	for i, docID := range docIDs {
-		var value T
-		if docID < uint32(len(values)) {
-			value = values[docID]
-		}
-		results[i] = SortedResult[T]{DocID: docID, Value: value}
+		results[i] = SortedResult[T]{DocID: docID, Value: value(docID)}
 	}
 
 	if asc {
@@ -657,18 +1069,14 @@ func heapToSortedResults[T cmp.Ordered](h *resultHeap[T]) []SortedResult[T] {
 	return results
 }
 
-func (col *SortColumn[T]) heapSort(docIDs []uint32, values []T, asc bool, limit int) []SortedResult[T] {
+func heapSort[T cmp.Ordered](docIDs []uint32, asc bool, limit int, value func(uint32) T) []SortedResult[T] {
 	h := &resultHeap[T]{
 		items: make([]SortedResult[T], 0, limit),
 		asc:   asc,
 	}
 
 	for _, docID := range docIDs {
-		var value T
-		if docID < uint32(len(values)) {
-			value = values[docID]
-		}
-		col.heapInsert(h, docID, value, asc, limit)
+		heapInsert(h, docID, value(docID), asc, limit)
 	}
 
 	if h.Len() < limit && h.Len() > 0 {
@@ -679,7 +1087,7 @@ func (col *SortColumn[T]) heapSort(docIDs []uint32, values []T, asc bool, limit
 }
 
 // heapInsert adds a value to the heap, maintaining the top-k invariant.
-func (col *SortColumn[T]) heapInsert(h *resultHeap[T], docID uint32, value T, asc bool, limit int) {
+func heapInsert[T cmp.Ordered](h *resultHeap[T], docID uint32, value T, asc bool, limit int) {
 	if h.Len() < limit {
 		h.items = append(h.items, SortedResult[T]{DocID: docID, Value: value})
 		if h.Len() == limit {
@@ -723,9 +1131,71 @@ func (h *resultHeap[T]) Pop() any {
 }
 
 // sortColumnData is the serializable representation.
+// Dense columns use Values; paged columns use PageIDs with PageValues holding
+// one full page per ID, in the same order.
 type sortColumnData[T cmp.Ordered] struct {
-	Values   []T    `msgpack:"values"`
-	MaxDocID uint32 `msgpack:"max_doc_id"`
+	Values     []T      `msgpack:"values"`
+	MaxDocID   uint32   `msgpack:"max_doc_id"`
+	PageIDs    []uint32 `msgpack:"page_ids"`
+	PageValues []T      `msgpack:"page_values"`
+}
+
+// snapshotLocked copies the column's values into its serializable form.
+func (col *SortColumn[T]) snapshotLocked() sortColumnData[T] {
+	data := sortColumnData[T]{MaxDocID: col.maxDocID}
+
+	if col.pages != nil {
+		data.PageIDs = make([]uint32, 0, len(col.pages))
+		for pageID := range col.pages {
+			data.PageIDs = append(data.PageIDs, pageID)
+		}
+		slices.Sort(data.PageIDs)
+		data.PageValues = make([]T, 0, len(col.pages)*sortColumnPageSize)
+		for _, pageID := range data.PageIDs {
+			data.PageValues = append(data.PageValues, col.pages[pageID]...)
+		}
+		return data
+	}
+
+	if len(col.values) > 0 {
+		data.Values = make([]T, col.maxDocID+1)
+		copy(data.Values, col.values[:col.maxDocID+1])
+	}
+	return data
+}
+
+// clone returns a copy of the column, or nil for a nil column.
+func (col *SortColumn[T]) clone() *SortColumn[T] {
+	if col == nil {
+		return nil
+	}
+	col.mu.RLock()
+	data := col.snapshotLocked()
+	col.mu.RUnlock()
+
+	c := &SortColumn[T]{}
+	_ = c.restoreLocked(&data)
+	return c
+}
+
+// restoreLocked replaces the column's values from their serializable form.
+func (col *SortColumn[T]) restoreLocked(data *sortColumnData[T]) error {
+	col.maxDocID = data.MaxDocID
+	if len(data.PageIDs) == 0 {
+		col.values = data.Values
+		col.pages = nil
+		return nil
+	}
+
+	if len(data.PageValues) != len(data.PageIDs)*sortColumnPageSize {
+		return ErrInvalidSize
+	}
+	col.values = nil
+	col.pages = make(map[uint32][]T, len(data.PageIDs))
+	for i, pageID := range data.PageIDs {
+		col.pages[pageID] = data.PageValues[i*sortColumnPageSize : (i+1)*sortColumnPageSize : (i+1)*sortColumnPageSize]
+	}
+	return nil
 }
 
 // SaveToFile saves the sort column to a file atomically.
@@ -775,19 +1245,10 @@ func (col *SortColumn[T]) SaveToFile(path string) error {
 func (col *SortColumn[T]) Encode(w io.Writer) error {
 	// Snapshot data while holding lock briefly
 	col.mu.RLock()
-	var valuesCopy []T
-	if len(col.values) > 0 {
-		valuesCopy = make([]T, col.maxDocID+1)
-		copy(valuesCopy, col.values[:col.maxDocID+1])
-	}
-	maxDocID := col.maxDocID
+	data := col.snapshotLocked()
 	col.mu.RUnlock()
 
 	// Write without holding lock - safe for concurrent reads/writes
-	data := sortColumnData[T]{
-		Values:   valuesCopy,
-		MaxDocID: maxDocID,
-	}
 
 	enc := msgpck.GetStructEncoder[sortColumnData[T]]()
 	encoded, err := enc.Encode(&data)
@@ -821,8 +1282,9 @@ func ReadSortColumn[T cmp.Ordered](r io.Reader) (*SortColumn[T], error) {
 		return nil, err
 	}
 
-	return &SortColumn[T]{
-		values:   data.Values,
-		maxDocID: data.MaxDocID,
-	}, nil
+	col := &SortColumn[T]{}
+	if err := col.restoreLocked(&data); err != nil {
+		return nil, err
+	}
+	return col, nil
 }
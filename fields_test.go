@@ -1,12 +1,19 @@
 package roaringsearch
 
 import (
+	"bytes"
 	"container/heap"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/freeeve/msgpck"
 )
 
 func TestBitmapFilterBasic(t *testing.T) {
@@ -125,6 +132,40 @@ func TestBitmapFilterGetAny(t *testing.T) {
 	}
 }
 
+func TestBitmapFilterGetNot(t *testing.T) {
+	filter := NewBitmapFilter()
+
+	filter.Set(1, "media_type", "books")
+	filter.Set(2, "media_type", "movies")
+	filter.Set(3, "media_type", "music")
+	filter.Set(4, "language", "english") // no media_type
+
+	notMovies := filter.GetNot("media_type", "movies", nil)
+	if got := notMovies.ToArray(); len(got) != 3 || notMovies.Contains(2) {
+		t.Errorf("GetNot(movies) = %v, want [1 3 4]", got)
+	}
+
+	none := filter.GetNone("media_type", []string{"books", "music"}, nil)
+	if got := none.ToArray(); len(got) != 2 || !none.Contains(2) || !none.Contains(4) {
+		t.Errorf("GetNone(books, music) = %v, want [2 4]", got)
+	}
+
+	// Explicit universe restricts the complement
+	universe := roaring.BitmapOf(1, 2, 10)
+	if got := filter.GetNot("media_type", "books", universe).ToArray(); len(got) != 2 || got[0] != 2 || got[1] != 10 {
+		t.Errorf("GetNot with universe = %v, want [2 10]", got)
+	}
+	if universe.GetCardinality() != 3 {
+		t.Error("GetNot should not modify the universe bitmap")
+	}
+
+	// Removed docs leave the maintained universe
+	filter.Remove(4)
+	if filter.GetNot("media_type", "movies", nil).Contains(4) {
+		t.Error("removed doc should not appear in complement")
+	}
+}
+
 func TestBitmapFilterCounts(t *testing.T) {
 	filter := NewBitmapFilter()
 
@@ -159,6 +200,27 @@ func TestBitmapFilterRemove(t *testing.T) {
 	}
 }
 
+func TestBitmapFilterRemoveMany(t *testing.T) {
+	filter := NewBitmapFilter()
+	for i := uint32(0); i < 10; i++ {
+		filter.Set(i, "parity", []string{"even", "odd"}[i%2])
+		filter.Set(i, "group", "all")
+	}
+
+	filter.RemoveMany([]uint32{0, 1, 2, 3})
+
+	if got := filter.Get("parity", "even").ToArray(); !reflect.DeepEqual(got, []uint32{4, 6, 8}) {
+		t.Errorf("even = %v, want [4 6 8]", got)
+	}
+	if got := filter.Get("group", "all").GetCardinality(); got != 6 {
+		t.Errorf("all count = %d, want 6", got)
+	}
+	if filter.DocCount() != 6 {
+		t.Errorf("DocCount = %d, want 6", filter.DocCount())
+	}
+	filter.RemoveMany(nil)
+}
+
 func TestSortColumnGenericTypes(t *testing.T) {
 	// Test with float64
 	floatCol := NewSortColumn[float64]()
@@ -277,6 +339,101 @@ func TestBitmapFilterPersistence(t *testing.T) {
 	}
 }
 
+func TestBitmapFilterStreamingEncode(t *testing.T) {
+	filter := NewBitmapFilter()
+	filter.Set(1, "media_type", "book")
+	filter.Set(2, "media_type", "movie")
+	filter.Set(2, "language", "spanish")
+	filter.Set(70000, "language", "english")
+
+	var buf bytes.Buffer
+	if err := filter.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte(filterMagicBytes)) {
+		t.Fatal("expected streaming format magic")
+	}
+
+	loaded, err := ReadBitmapFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBitmapFilter failed: %v", err)
+	}
+	if !loaded.Get("language", "english").Contains(70000) {
+		t.Error("english should contain 70000")
+	}
+	if loaded.DocCount() != 3 {
+		t.Errorf("DocCount = %d, want 3", loaded.DocCount())
+	}
+
+	// Truncated input should fail rather than return a partial filter
+	var full bytes.Buffer
+	if err := filter.Encode(&full); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if _, err := ReadBitmapFilter(bytes.NewReader(full.Bytes()[:full.Len()-3])); err == nil {
+		t.Error("expected error for truncated input")
+	}
+}
+
+func TestBitmapFilterSaveLoadField(t *testing.T) {
+	filter := NewBitmapFilter()
+	filter.Set(1, "media_type", "book")
+	filter.Set(2, "media_type", "movie")
+	filter.Set(3, "language", "english")
+
+	path := filepath.Join(t.TempDir(), "media_type.idx")
+	if err := filter.SaveField(path, "media_type"); err != nil {
+		t.Fatalf("SaveField failed: %v", err)
+	}
+	if err := filter.SaveField(path, "missing"); err != ErrFieldNotFound {
+		t.Errorf("SaveField(missing) error = %v, want ErrFieldNotFound", err)
+	}
+
+	loaded := NewBitmapFilter()
+	loaded.Set(9, "media_type", "stale")
+	if err := loaded.LoadField(path, "media_type"); err != nil {
+		t.Fatalf("LoadField failed: %v", err)
+	}
+	if loaded.Get("media_type", "stale") != nil {
+		t.Error("LoadField should replace existing categories")
+	}
+	if !loaded.Get("media_type", "movie").Contains(2) {
+		t.Error("movie should contain doc 2")
+	}
+	if loaded.Get("language", "english") != nil {
+		t.Error("language should not be loaded")
+	}
+	if err := loaded.LoadField(path, "language"); err != ErrFieldNotFound {
+		t.Errorf("LoadField(language) error = %v, want ErrFieldNotFound", err)
+	}
+}
+
+func TestBitmapFilterReadLegacyFormat(t *testing.T) {
+	bm := roaring.BitmapOf(3, 5)
+	bmBytes, err := bm.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bitmapFilterData{
+		Fields: map[string]map[string][]byte{"media_type": {"book": bmBytes}},
+	}
+	encoded, err := msgpck.GetStructEncoder[bitmapFilterData]().Encode(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadBitmapFilter(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("ReadBitmapFilter failed on legacy data: %v", err)
+	}
+	if got := loaded.Get("media_type", "book").GetCardinality(); got != 2 {
+		t.Errorf("book count = %d, want 2", got)
+	}
+	if loaded.DocCount() != 2 {
+		t.Errorf("DocCount = %d, want 2", loaded.DocCount())
+	}
+}
+
 func TestSortColumnPersistence(t *testing.T) {
 	col := NewSortColumn[uint16]()
 
@@ -557,6 +714,64 @@ func TestBitmapFilterBatch(t *testing.T) {
 	batch.Flush()
 }
 
+func TestFilterBatchFlushContext(t *testing.T) {
+	filter := NewBitmapFilter()
+	batch := filter.Batch("type")
+	batch.Add(1, "book")
+	batch.Add(2, "movie")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := batch.FlushContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FlushContext error = %v, want context.Canceled", err)
+	}
+	if filter.DocCount() != 0 {
+		t.Errorf("cancelled flush modified filter: %d docs", filter.DocCount())
+	}
+
+	if err := batch.FlushContext(context.Background()); err != nil {
+		t.Fatalf("FlushContext retry failed: %v", err)
+	}
+	if filter.DocCount() != 2 {
+		t.Errorf("DocCount after retry = %d, want 2", filter.DocCount())
+	}
+}
+
+func TestFilterBatchAutoFlushConcurrent(t *testing.T) {
+	filter := NewBitmapFilter()
+	batch := filter.Batch("shard", WithFilterAutoFlush(1000), WithFilterBatchShards(4))
+
+	const producers, perProducer = 8, 5000
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				docID := uint32(p*perProducer + i)
+				batch.Add(docID, fmt.Sprintf("s%d", docID%3))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := batch.Len(); n >= 1000 {
+		t.Errorf("Len = %d after auto-flush, want under the threshold", n)
+	}
+	if filter.DocCount() == 0 {
+		t.Error("auto-flush should have applied entries before Flush")
+	}
+	batch.Flush()
+	if got := filter.DocCount(); got != producers*perProducer {
+		t.Errorf("DocCount = %d, want %d", got, producers*perProducer)
+	}
+	for cat, want := range map[string]uint64{"s0": 13334, "s1": 13333, "s2": 13333} {
+		if got := filter.Get("shard", cat).GetCardinality(); got != want {
+			t.Errorf("%s has %d docs, want %d", cat, got, want)
+		}
+	}
+}
+
 func TestSortColumnBatch(t *testing.T) {
 	col := NewSortColumn[uint16]()
 
@@ -599,6 +814,76 @@ func TestSortColumnBatch(t *testing.T) {
 	batch.Flush()
 }
 
+func TestSortColumnSparse(t *testing.T) {
+	t.Run("Automatic", func(t *testing.T) {
+		col := NewSortColumn[uint16]()
+		col.Set(1, 10)
+		col.Set(2_000_000_000, 20)
+
+		if !col.IsSparse() {
+			t.Fatal("large docID jump should switch to paged storage")
+		}
+		if col.MemoryUsage() > 64*1024 {
+			t.Errorf("MemoryUsage = %d, want a few pages", col.MemoryUsage())
+		}
+		if col.Get(1) != 10 || col.Get(2_000_000_000) != 20 || col.Get(1_000_000) != 0 {
+			t.Error("values not preserved after switching to pages")
+		}
+
+		results := col.SortBitmapDesc(roaring.BitmapOf(1, 2_000_000_000, 5), 0)
+		if len(results) != 3 || results[0].DocID != 2_000_000_000 || results[1].DocID != 1 {
+			t.Errorf("sort results = %v", results)
+		}
+	})
+
+	t.Run("Option", func(t *testing.T) {
+		col := NewSortColumn[int64](WithSparseValues())
+		if !col.IsSparse() {
+			t.Fatal("WithSparseValues should start with paged storage")
+		}
+
+		batch := col.Batch()
+		batch.Add(3_000_000_000, -5)
+		batch.Add(7, 9)
+		batch.Flush()
+
+		if col.Get(3_000_000_000) != -5 || col.Get(7) != 9 {
+			t.Error("batch values not stored in pages")
+		}
+	})
+
+	t.Run("Persistence", func(t *testing.T) {
+		col := NewSortColumn[uint32](WithSparseValues())
+		col.Set(42, 1)
+		col.Set(4_000_000_000, 2)
+
+		path := filepath.Join(t.TempDir(), "sparse.col")
+		if err := col.SaveToFile(path); err != nil {
+			t.Fatalf(errSaveToFile, err)
+		}
+		loaded, err := LoadSortColumn[uint32](path)
+		if err != nil {
+			t.Fatalf("LoadSortColumn failed: %v", err)
+		}
+		if !loaded.IsSparse() {
+			t.Error("loaded column should be sparse")
+		}
+		if loaded.Get(42) != 1 || loaded.Get(4_000_000_000) != 2 {
+			t.Error("sparse values not preserved across save/load")
+		}
+	})
+
+	t.Run("DenseStaysDense", func(t *testing.T) {
+		col := NewSortColumn[uint16]()
+		for i := uint32(0); i < 10_000; i++ {
+			col.Set(i, uint16(i))
+		}
+		if col.IsSparse() {
+			t.Error("sequential docIDs should keep dense storage")
+		}
+	})
+}
+
 func TestBitmapFilterAllCounts(t *testing.T) {
 	filter := NewBitmapFilter()
 
@@ -629,6 +914,112 @@ func TestBitmapFilterAllCounts(t *testing.T) {
 	}
 }
 
+func TestBitmapFilterMultiValue(t *testing.T) {
+	filter := NewBitmapFilter()
+
+	filter.Set(1, "tags", "sale")
+	filter.Set(1, "tags", "new")
+	filter.Set(1, "media_type", "book")
+	filter.Set(2, "tags", "sale")
+
+	cats := filter.GetCategories(1, "tags")
+	if len(cats) != 2 || cats[0] != "new" || cats[1] != "sale" {
+		t.Errorf("GetCategories = %v, want [new sale]", cats)
+	}
+
+	filter.ReplaceSet(1, "tags", []string{"clearance"})
+	cats = filter.GetCategories(1, "tags")
+	if len(cats) != 1 || cats[0] != "clearance" {
+		t.Errorf("GetCategories after ReplaceSet = %v, want [clearance]", cats)
+	}
+	if filter.Get("tags", "sale").Contains(1) {
+		t.Error("ReplaceSet should remove doc from old categories")
+	}
+	if !filter.Get("tags", "sale").Contains(2) {
+		t.Error("ReplaceSet should not affect other docs")
+	}
+
+	filter.RemoveFromField(1, "tags")
+	if cats := filter.GetCategories(1, "tags"); cats != nil {
+		t.Errorf("GetCategories after RemoveFromField = %v, want nil", cats)
+	}
+	if !filter.Get("media_type", "book").Contains(1) {
+		t.Error("RemoveFromField should not affect other fields")
+	}
+}
+
+func TestBitmapFilterTopCategories(t *testing.T) {
+	filter := NewBitmapFilter()
+	for i := uint32(0); i < 10; i++ {
+		filter.Set(i, "lang", "english")
+	}
+	for i := uint32(10); i < 15; i++ {
+		filter.Set(i, "lang", "spanish")
+	}
+	for i := uint32(15); i < 20; i++ {
+		filter.Set(i, "lang", "french")
+	}
+	filter.Set(20, "lang", "german")
+
+	top := filter.TopCategories("lang", 3, nil)
+	want := []CategoryCount{{"english", 10}, {"french", 5}, {"spanish", 5}}
+	if len(top) != len(want) {
+		t.Fatalf("TopCategories = %v, want %v", top, want)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf("TopCategories[%d] = %v, want %v", i, top[i], want[i])
+		}
+	}
+
+	// Restricted to a result set
+	result := roaring.BitmapOf(0, 10, 11, 12, 20)
+	top = filter.TopCategories("lang", 10, result)
+	if len(top) != 3 || top[0] != (CategoryCount{"spanish", 3}) {
+		t.Errorf("TopCategories with result = %v", top)
+	}
+
+	if filter.TopCategories("lang", 0, nil) != nil {
+		t.Error("k=0 should return nil")
+	}
+	if got := filter.TopCategories("missing", 5, nil); len(got) != 0 {
+		t.Errorf("missing field = %v, want empty", got)
+	}
+}
+
+func TestBitmapFilterFacets(t *testing.T) {
+	filter := NewBitmapFilter()
+
+	filter.Set(1, "media_type", "book")
+	filter.Set(2, "media_type", "book")
+	filter.Set(3, "media_type", "movie")
+	filter.Set(1, "language", "english")
+	filter.Set(2, "language", "spanish")
+	filter.Set(3, "language", "english")
+
+	result := roaring.BitmapOf(1, 3)
+	facets := filter.Facets(result, "media_type", "language", "missing")
+
+	if len(facets) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(facets))
+	}
+	if facets["media_type"]["book"] != 1 || facets["media_type"]["movie"] != 1 {
+		t.Errorf("media_type facets = %v, want book:1 movie:1", facets["media_type"])
+	}
+	if facets["language"]["english"] != 2 {
+		t.Errorf("language.english = %d, want 2", facets["language"]["english"])
+	}
+	if _, ok := facets["language"]["spanish"]; ok {
+		t.Error("categories with no matches should be omitted")
+	}
+
+	// nil result counts everything
+	all := filter.Facets(nil, "media_type")
+	if all["media_type"]["book"] != 2 {
+		t.Errorf("unrestricted media_type.book = %d, want 2", all["media_type"]["book"])
+	}
+}
+
 // TestSaveToFileCreatesFileWhenNotDirty is a regression test for a bug where
 // SaveToFile would return success but not create the file when dirty=false.
 // See: https://github.com/freeeve/roaringsearch/issues/XXX
@@ -0,0 +1,126 @@
+package roaringsearch
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// MultiFieldBatch accumulates documents that set several fields at once, and
+// applies every field in one pass under a single lock, where a FilterBatch
+// per field would take the filter's lock once per field. It is safe for
+// concurrent use.
+//
+// Example:
+//
+//	batch := filter.MultiFieldBatch()
+//	batch.Add(1, map[string]string{"media_type": "book", "language": "english"})
+//	batch.Add(2, map[string]string{"media_type": "movie"})
+//	batch.Flush()
+type MultiFieldBatch struct {
+	filter *BitmapFilter
+	mu     sync.Mutex
+	fields map[string]*fieldRows
+	docIDs []uint32 // every document added, for the filter's set of all docs
+}
+
+// fieldRows holds the buffered entries of one field of a MultiFieldBatch.
+type fieldRows struct {
+	docIDs     []uint32
+	categories []string
+}
+
+// MultiFieldBatch creates a batch builder for documents with several fields.
+func (c *BitmapFilter) MultiFieldBatch() *MultiFieldBatch {
+	return &MultiFieldBatch{
+		filter: c,
+		fields: make(map[string]*fieldRows),
+	}
+}
+
+// Add adds a document with its category in each field to the batch.
+func (b *MultiFieldBatch) Add(docID uint32, fields map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for field, category := range fields {
+		rows, ok := b.fields[field]
+		if !ok {
+			rows = &fieldRows{}
+			b.fields[field] = rows
+		}
+		rows.docIDs = append(rows.docIDs, docID)
+		rows.categories = append(rows.categories, category)
+	}
+	b.docIDs = append(b.docIDs, docID)
+}
+
+// Len returns the number of buffered documents.
+func (b *MultiFieldBatch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.docIDs)
+}
+
+// Flush commits all accumulated documents to the filter.
+func (b *MultiFieldBatch) Flush() {
+	_ = b.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush but returns ctx's error without modifying the
+// filter if ctx is cancelled before the documents are applied. The documents
+// stay buffered so the flush can be retried.
+func (b *MultiFieldBatch) FlushContext(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.docIDs) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Group every field before taking the filter's lock
+	fields := slices.Sorted(maps.Keys(b.fields))
+	categoryLists := make([][]string, len(fields))
+	var groups [][]uint32
+	for i, field := range fields {
+		rows := b.fields[field]
+		var fieldGroups [][]uint32
+		categoryLists[i], fieldGroups = buildCategoryGroups(rows.docIDs, rows.categories)
+		groups = append(groups, fieldGroups...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.filter.mu.Lock()
+	defer b.filter.mu.Unlock()
+
+	bitmaps := make([]*roaring.Bitmap, 0, len(groups))
+	for i, field := range fields {
+		for _, category := range categoryLists[i] {
+			bitmaps = append(bitmaps, b.filter.categoryLocked(field, category))
+		}
+	}
+	if len(groups) >= 4 {
+		addToBitmapsParallel(bitmaps, groups)
+	} else {
+		for i, ids := range groups {
+			bitmaps[i].AddMany(ids)
+		}
+	}
+	b.filter.all.AddMany(b.docIDs)
+	b.filter.dirty.Store(true)
+
+	clear(b.fields)
+	b.docIDs = b.docIDs[:0]
+	return nil
+}
@@ -0,0 +1,47 @@
+package roaringsearch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMultiFieldBatch(t *testing.T) {
+	filter := NewBitmapFilter()
+	batch := filter.MultiFieldBatch()
+	batch.Add(1, map[string]string{"media_type": "book", "language": "english"})
+	batch.Add(2, map[string]string{"media_type": "movie", "language": "english"})
+	batch.Add(3, map[string]string{"media_type": "book"})
+	batch.Add(4, nil)
+	if batch.Len() != 3 {
+		t.Errorf("Len = %d, want 3", batch.Len())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := batch.FlushContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FlushContext error = %v, want context.Canceled", err)
+	}
+	if filter.DocCount() != 0 || batch.Len() != 3 {
+		t.Fatal("a cancelled flush should leave the filter unchanged and the batch full")
+	}
+
+	batch.Flush()
+	if got := filter.Get("media_type", "book").ToArray(); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("book = %v, want [1 3]", got)
+	}
+	if got := filter.Get("language", "english").ToArray(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("english = %v, want [1 2]", got)
+	}
+	if filter.DocCount() != 3 || batch.Len() != 0 {
+		t.Errorf("DocCount = %d, Len = %d after Flush, want 3 and 0", filter.DocCount(), batch.Len())
+	}
+
+	// The batch is reusable
+	batch.Add(5, map[string]string{"language": "spanish"})
+	batch.Flush()
+	if got := filter.Get("language", "spanish").ToArray(); !reflect.DeepEqual(got, []uint32{5}) {
+		t.Errorf("spanish = %v, want [5]", got)
+	}
+}
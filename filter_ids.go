@@ -0,0 +1,220 @@
+package roaringsearch
+
+import "github.com/RoaringBitmap/roaring/v2"
+
+// CategoryID is the interned ID of a field/category pair of a BitmapFilter.
+// IDs are assigned in order of first use and never reused by the filter, and
+// SaveToFile records them, so a filter loaded with LoadBitmapFilter keeps
+// the IDs of its categories. Hot filtering paths can look a category up
+// once and then use GetID and SetID, which index a slice instead of hashing
+// the field and category names on every call.
+type CategoryID uint32
+
+// categoryTable interns the field/category pairs of a BitmapFilter. The
+// zero value is ready to use.
+type categoryTable struct {
+	ids     map[string]map[string]CategoryID
+	names   []filterEntryRef  // field and category of each dense ID
+	bitmaps []*roaring.Bitmap // bitmap of each dense ID; nil while the category is absent
+	sparse  map[CategoryID]*sparseCategory
+	next    CategoryID // next free ID
+}
+
+// sparseCategory is a category whose ID, recorded in a file, lies too far
+// past the dense IDs to be stored by position. Only files from a filter that
+// dropped many categories, or corrupt ones, record such IDs; keeping them
+// apart stops a recorded ID from forcing a large allocation.
+type sparseCategory struct {
+	filterEntryRef
+	bm *roaring.Bitmap
+}
+
+// lookup returns the ID of field/category.
+func (t *categoryTable) lookup(field, category string) (CategoryID, bool) {
+	id, ok := t.ids[field][category]
+	return id, ok
+}
+
+// intern returns the ID of field/category, assigning the next free ID if
+// the pair is new.
+func (t *categoryTable) intern(field, category string) CategoryID {
+	if id, ok := t.lookup(field, category); ok {
+		return id
+	}
+	id := t.next
+	t.assign(id, field, category, len(t.names)+1)
+	return id
+}
+
+// assign gives field/category the ID id, as recorded in a saved filter. IDs
+// below denseLimit are stored by position, others in the sparse map.
+func (t *categoryTable) assign(id CategoryID, field, category string, denseLimit int) {
+	if t.ids == nil {
+		t.ids = make(map[string]map[string]CategoryID)
+	}
+	fieldIDs, ok := t.ids[field]
+	if !ok {
+		fieldIDs = make(map[string]CategoryID)
+		t.ids[field] = fieldIDs
+	}
+	fieldIDs[category] = id
+	t.next = max(t.next, id+1)
+
+	ref := filterEntryRef{field: field, category: category}
+	switch {
+	case int(id) < len(t.names):
+		t.names[id] = ref
+	case int(id) < denseLimit:
+		t.names = append(t.names, make([]filterEntryRef, int(id)+1-len(t.names))...)
+		t.bitmaps = append(t.bitmaps, make([]*roaring.Bitmap, int(id)+1-len(t.bitmaps))...)
+		t.names[id] = ref
+	default:
+		if t.sparse == nil {
+			t.sparse = make(map[CategoryID]*sparseCategory)
+		}
+		t.sparse[id] = &sparseCategory{filterEntryRef: ref}
+	}
+}
+
+// name returns the field and category of id.
+func (t *categoryTable) name(id CategoryID) (filterEntryRef, bool) {
+	var ref filterEntryRef
+	if int(id) < len(t.names) {
+		ref = t.names[id]
+	} else if s, ok := t.sparse[id]; ok {
+		ref = s.filterEntryRef
+	} else {
+		return filterEntryRef{}, false
+	}
+	if got, ok := t.lookup(ref.field, ref.category); !ok || got != id {
+		return filterEntryRef{}, false // a gap left by IDs recorded in a file
+	}
+	return ref, true
+}
+
+// bitmap returns the bitmap of id, or nil if id is unknown or its category
+// is absent from the filter.
+func (t *categoryTable) bitmap(id CategoryID) *roaring.Bitmap {
+	if int(id) < len(t.bitmaps) {
+		return t.bitmaps[id]
+	}
+	if s, ok := t.sparse[id]; ok {
+		return s.bm
+	}
+	return nil
+}
+
+// setBitmap makes bm the bitmap of id, which must be assigned.
+func (t *categoryTable) setBitmap(id CategoryID, bm *roaring.Bitmap) {
+	if int(id) < len(t.bitmaps) {
+		t.bitmaps[id] = bm
+	} else {
+		t.sparse[id].bm = bm
+	}
+}
+
+// drop records that field/category is no longer in the filter. Its ID stays
+// reserved.
+func (t *categoryTable) drop(field, category string) {
+	if id, ok := t.lookup(field, category); ok {
+		t.setBitmap(id, nil)
+	}
+}
+
+// categoryLocked returns the bitmap of field/category, creating the category
+// if needed.
+func (c *BitmapFilter) categoryLocked(field, category string) *roaring.Bitmap {
+	bm, ok := c.fields[field][category]
+	if !ok {
+		bm = roaring.New()
+		c.putCategoryLocked(field, category, bm)
+	}
+	return bm
+}
+
+// putCategoryLocked makes bm the bitmap of field/category, replacing any
+// existing one.
+func (c *BitmapFilter) putCategoryLocked(field, category string, bm *roaring.Bitmap) {
+	fieldMap, ok := c.fields[field]
+	if !ok {
+		fieldMap = make(map[string]*roaring.Bitmap)
+		c.fields[field] = fieldMap
+	}
+	fieldMap[category] = bm
+	c.cats.setBitmap(c.cats.intern(field, category), bm)
+}
+
+// CategoryID returns the ID of a category of field, and false if the pair
+// has never been used.
+func (c *BitmapFilter) CategoryID(field, category string) (CategoryID, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cats.lookup(field, category)
+}
+
+// InternCategory returns the ID of a category of field, assigning one if the
+// pair is new. The category itself is created by the first SetID or Set.
+//
+// Example:
+//
+//	books := filter.InternCategory("media_type", "book")
+//	for _, id := range bookIDs {
+//		filter.SetID(id, books)
+//	}
+//	bm := filter.GetID(books)
+func (c *BitmapFilter) InternCategory(field, category string) CategoryID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cats.intern(field, category)
+}
+
+// CategoryName returns the field and category of id, and false if the
+// filter never assigned id.
+func (c *BitmapFilter) CategoryName(id CategoryID) (field, category string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name, ok := c.cats.name(id)
+	return name.field, name.category, ok
+}
+
+// SetID is Set for an interned category. IDs the filter never assigned are
+// ignored.
+func (c *BitmapFilter) SetID(docID uint32, id CategoryID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bm := c.cats.bitmap(id)
+	if bm == nil {
+		name, ok := c.cats.name(id)
+		if !ok {
+			return
+		}
+		bm = c.categoryLocked(name.field, name.category)
+	}
+	bm.Add(docID)
+	c.all.Add(docID)
+	c.dirty.Store(true)
+}
+
+// GetID is Get for an interned category. It returns nil if id is unknown or
+// its category has no bitmap.
+func (c *BitmapFilter) GetID(id CategoryID) *roaring.Bitmap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cats.bitmap(id)
+}
+
+// GetAnyID is GetAny for interned categories, which may be of different
+// fields.
+func (c *BitmapFilter) GetAnyID(ids ...CategoryID) *roaring.Bitmap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := roaring.New()
+	for _, id := range ids {
+		if bm := c.cats.bitmap(id); bm != nil {
+			result.Or(bm)
+		}
+	}
+	return result
+}
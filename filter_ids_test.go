@@ -0,0 +1,166 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestBitmapFilterCategoryIDs(t *testing.T) {
+	filter := NewBitmapFilter()
+	filter.Set(1, "media_type", "book")
+	movie := filter.InternCategory("media_type", "movie")
+	english := filter.InternCategory("language", "english")
+
+	if id, ok := filter.CategoryID("media_type", "book"); !ok || id != 0 {
+		t.Errorf("CategoryID(book) = %d, %v, want 0, true", id, ok)
+	}
+	if _, ok := filter.CategoryID("media_type", "music"); ok {
+		t.Error("an unused category should have no ID")
+	}
+	if movie != 1 || english != 2 || filter.InternCategory("media_type", "movie") != movie {
+		t.Errorf("IDs = %d, %d, want 1 and 2, stable across calls", movie, english)
+	}
+	if filter.GetID(movie) != nil || filter.Get("media_type", "movie") != nil {
+		t.Error("interning alone should not create the category")
+	}
+
+	filter.SetID(2, movie)
+	filter.SetID(3, movie)
+	filter.SetID(2, english)
+	filter.SetID(4, 99) // never assigned
+	if got := filter.GetID(movie).ToArray(); !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("GetID(movie) = %v, want [2 3]", got)
+	}
+	if filter.GetID(movie) != filter.Get("media_type", "movie") {
+		t.Error("GetID and Get should return the same bitmap")
+	}
+	if got := filter.GetAnyID(0, english).ToArray(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("GetAnyID(book, english) = %v, want [1 2]", got)
+	}
+	if filter.DocCount() != 3 {
+		t.Errorf("DocCount = %d, want 3", filter.DocCount())
+	}
+	if field, cat, ok := filter.CategoryName(english); !ok || field != "language" || cat != "english" {
+		t.Errorf("CategoryName(%d) = %q, %q, %v", english, field, cat, ok)
+	}
+	if _, _, ok := filter.CategoryName(99); ok {
+		t.Error("CategoryName of an unassigned ID should fail")
+	}
+}
+
+func TestBitmapFilterCategoryIDsPersist(t *testing.T) {
+	filter := NewBitmapFilter()
+	filter.Set(1, "media_type", "book")
+	filter.Set(2, "media_type", "movie")
+	filter.Set(3, "language", "english")
+	filter.Remove(2)
+	filter.Optimize(WithShrinkMaps()) // drops movie, leaving a gap at ID 1
+
+	var buf bytes.Buffer
+	if err := filter.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	loaded, err := ReadBitmapFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBitmapFilter failed: %v", err)
+	}
+	if id, ok := loaded.CategoryID("language", "english"); !ok || id != 2 {
+		t.Errorf("loaded CategoryID(english) = %d, %v, want 2, true", id, ok)
+	}
+	if !loaded.GetID(2).Contains(3) {
+		t.Error("GetID(english) should contain 3 after loading")
+	}
+	if _, _, ok := loaded.CategoryName(1); ok {
+		t.Error("a dropped category's ID should not be in the file")
+	}
+	if id := loaded.InternCategory("media_type", "music"); id != 3 {
+		t.Errorf("new ID after loading = %d, want 3", id)
+	}
+}
+
+func TestReadBitmapFilterVersion1(t *testing.T) {
+	// Version 1 entries have no category ID
+	var buf bytes.Buffer
+	header := make([]byte, filterHeaderSize)
+	copy(header, filterMagicBytes)
+	binary.LittleEndian.PutUint16(header[4:6], 1)
+	binary.LittleEndian.PutUint32(header[8:12], 2)
+	buf.Write(header)
+	for _, e := range []struct {
+		field, category string
+		docs            []uint32
+	}{{"language", "english", []uint32{1}}, {"media_type", "book", []uint32{1, 2}}} {
+		bmBytes, err := roaring.BitmapOf(e.docs...).ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{e.field, e.category} {
+			buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+			buf.WriteString(s)
+		}
+		buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(bmBytes))))
+		buf.Write(bmBytes)
+	}
+
+	loaded, err := ReadBitmapFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBitmapFilter failed: %v", err)
+	}
+	if got := loaded.Get("media_type", "book").ToArray(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Get(book) = %v, want [1 2]", got)
+	}
+	if id, ok := loaded.CategoryID("media_type", "book"); !ok || loaded.GetID(id) == nil {
+		t.Error("categories of a version 1 file should be interned on load")
+	}
+}
+
+func TestReadBitmapFilterSparseCategoryID(t *testing.T) {
+	// A recorded ID far past the entry count must not size the ID table
+	var buf bytes.Buffer
+	header := make([]byte, filterHeaderSize)
+	copy(header, filterMagicBytes)
+	binary.LittleEndian.PutUint16(header[4:6], filterVersion)
+	binary.LittleEndian.PutUint32(header[8:12], 2)
+	buf.Write(header)
+	bmBytes, err := roaring.BitmapOf(1).ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const far = CategoryID(maxNgramCount - 1)
+	if err := writeFilterEntry(&buf, "language", "english", 0, bmBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFilterEntry(&buf, "media_type", "book", far, bmBytes); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadBitmapFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBitmapFilter failed: %v", err)
+	}
+	if n := len(loaded.cats.names); n > 4 {
+		t.Errorf("dense ID table has %d entries after loading 2 categories", n)
+	}
+	if id, ok := loaded.CategoryID("media_type", "book"); !ok || id != far {
+		t.Errorf("CategoryID(book) = %d, %v, want %d, true", id, ok, far)
+	}
+	if !loaded.GetID(far).Contains(1) {
+		t.Error("GetID of a sparse ID should contain 1")
+	}
+	if field, category, ok := loaded.CategoryName(far); !ok || field != "media_type" || category != "book" {
+		t.Errorf("CategoryName(%d) = %q, %q, %v", far, field, category, ok)
+	}
+	if id := loaded.InternCategory("media_type", "movie"); id != far+1 {
+		t.Errorf("new ID after loading = %d, want %d", id, far+1)
+	}
+
+	loaded.Remove(1)
+	loaded.Optimize(WithShrinkMaps())
+	if bm := loaded.GetID(far); bm != nil {
+		t.Errorf("GetID of a dropped sparse category = %v, want nil", bm)
+	}
+}
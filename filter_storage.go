@@ -0,0 +1,448 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/freeeve/msgpck"
+)
+
+const (
+	filterMagicBytes = "FTSF"
+	filterVersion    = 2 // version 1 had no category IDs
+
+	filterHeaderSize  = 12
+	maxFilterNameSize = 1 << 16 // max field or category name length in bytes
+)
+
+// filterEntryRef identifies one field/category bitmap to be written.
+type filterEntryRef struct {
+	field    string
+	category string
+}
+
+// Encode writes the bitmap filter to a writer.
+// Entries are streamed one bitmap at a time: the read lock is held only while
+// serializing each bitmap, so peak extra memory is a single bitmap rather than
+// a copy of the whole filter.
+//
+// Format: magic(4) + version(2) + reserved(2) + entry count(4), then per entry
+// field length(4) + field + category length(4) + category + category ID(4) +
+// bitmap size(4) + bitmap.
+func (c *BitmapFilter) Encode(w io.Writer) error {
+	c.mu.RLock()
+	refs := c.entryRefsLocked("")
+	c.mu.RUnlock()
+
+	return c.encodeEntries(w, refs)
+}
+
+// encodeEntries writes the header followed by the given entries.
+func (c *BitmapFilter) encodeEntries(w io.Writer, refs []filterEntryRef) error {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, filterHeaderSize)
+	copy(header[0:4], filterMagicBytes)
+	binary.LittleEndian.PutUint16(header[4:6], filterVersion)
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(refs)))
+	if _, err := bw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, ref := range refs {
+		id, bmBytes, err := c.entryBytes(ref)
+		if err != nil {
+			return fmt.Errorf("serialize bitmap: %w", err)
+		}
+		if err := writeFilterEntry(bw, ref.field, ref.category, id, bmBytes); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// entryRefsLocked lists field/category pairs in a stable order.
+// An empty onlyField lists every field.
+func (c *BitmapFilter) entryRefsLocked(onlyField string) []filterEntryRef {
+	var refs []filterEntryRef
+	for field, fieldMap := range c.fields {
+		if onlyField != "" && field != onlyField {
+			continue
+		}
+		for cat := range fieldMap {
+			refs = append(refs, filterEntryRef{field: field, category: cat})
+		}
+	}
+	slices.SortFunc(refs, func(a, b filterEntryRef) int {
+		return cmp.Or(cmp.Compare(a.field, b.field), cmp.Compare(a.category, b.category))
+	})
+	return refs
+}
+
+// entryBytes serializes a single bitmap under the read lock and returns it
+// with its category ID. Missing entries serialize as an empty bitmap.
+func (c *BitmapFilter) entryBytes(ref filterEntryRef) (CategoryID, []byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bm, ok := c.fields[ref.field][ref.category]
+	if !ok {
+		bm = roaring.New()
+	}
+	id, _ := c.cats.lookup(ref.field, ref.category)
+	bmBytes, err := bm.ToBytes()
+	return id, bmBytes, err
+}
+
+// writeFilterEntry writes one length-prefixed field/category/bitmap entry.
+func writeFilterEntry(w io.Writer, field, category string, id CategoryID, bmBytes []byte) error {
+	lenBuf := make([]byte, 4)
+
+	binary.LittleEndian.PutUint32(lenBuf, uint32(len(field)))
+	if _, err := w.Write(lenBuf); err != nil {
+		return fmt.Errorf("write field length: %w", err)
+	}
+	if _, err := io.WriteString(w, field); err != nil {
+		return fmt.Errorf("write field: %w", err)
+	}
+
+	binary.LittleEndian.PutUint32(lenBuf, uint32(len(category)))
+	if _, err := w.Write(lenBuf); err != nil {
+		return fmt.Errorf("write category length: %w", err)
+	}
+	if _, err := io.WriteString(w, category); err != nil {
+		return fmt.Errorf("write category: %w", err)
+	}
+
+	binary.LittleEndian.PutUint32(lenBuf, uint32(id))
+	if _, err := w.Write(lenBuf); err != nil {
+		return fmt.Errorf("write category id: %w", err)
+	}
+
+	binary.LittleEndian.PutUint32(lenBuf, uint32(len(bmBytes)))
+	if _, err := w.Write(lenBuf); err != nil {
+		return fmt.Errorf("write bitmap size: %w", err)
+	}
+	if _, err := w.Write(bmBytes); err != nil {
+		return fmt.Errorf("write bitmap: %w", err)
+	}
+	return nil
+}
+
+// readFilterString reads a length-prefixed string.
+func readFilterString(r io.Reader, lenBuf []byte) (string, error) {
+	if _, err := io.ReadFull(r, lenBuf); err != nil {
+		return "", err
+	}
+	n := binary.LittleEndian.Uint32(lenBuf)
+	if n > maxFilterNameSize {
+		return "", ErrInvalidSize
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// filterEntryHeader is what precedes an entry's bitmap in a filter file.
+type filterEntryHeader struct {
+	filterEntryRef
+	id     CategoryID // recorded ID; see hasID
+	hasID  bool       // false in version 1 files
+	bmSize uint32
+}
+
+// size returns the encoded size of the header.
+func (h filterEntryHeader) size() int64 {
+	n := int64(12 + len(h.field) + len(h.category))
+	if h.hasID {
+		n += 4
+	}
+	return n
+}
+
+// readFilterEntryHeader reads the names, category ID and bitmap size of an
+// entry of a file of the given version.
+func readFilterEntryHeader(r io.Reader, lenBuf []byte, version uint16) (filterEntryHeader, error) {
+	var h filterEntryHeader
+	var err error
+	h.field, err = readFilterString(r, lenBuf)
+	if err != nil {
+		return h, fmt.Errorf("read field: %w", err)
+	}
+	h.category, err = readFilterString(r, lenBuf)
+	if err != nil {
+		return h, fmt.Errorf("read category: %w", err)
+	}
+
+	if version >= 2 {
+		if _, err := io.ReadFull(r, lenBuf); err != nil {
+			return h, fmt.Errorf("read category id: %w", err)
+		}
+		id := binary.LittleEndian.Uint32(lenBuf)
+		if id >= maxNgramCount {
+			return h, ErrInvalidCount
+		}
+		h.id, h.hasID = CategoryID(id), true
+	}
+
+	if _, err := io.ReadFull(r, lenBuf); err != nil {
+		return h, fmt.Errorf("read bitmap size: %w", err)
+	}
+	h.bmSize = binary.LittleEndian.Uint32(lenBuf)
+	if h.bmSize > maxBitmapSize {
+		return h, ErrInvalidSize
+	}
+	return h, nil
+}
+
+// readFilterEntry reads one field/category/bitmap entry.
+func readFilterEntry(r io.Reader, lenBuf []byte, version uint16) (filterEntryHeader, *roaring.Bitmap, error) {
+	h, err := readFilterEntryHeader(r, lenBuf, version)
+	if err != nil {
+		return h, nil, err
+	}
+	bm := roaring.New()
+	if _, err := bm.ReadFrom(io.LimitReader(r, int64(h.bmSize))); err != nil {
+		return h, nil, fmt.Errorf("deserialize bitmap: %w", err)
+	}
+	return h, bm, nil
+}
+
+// readFilterHeader reads and checks the header of a filter file, returning
+// its version and entry count.
+func readFilterHeader(r io.Reader) (uint16, uint32, error) {
+	header := make([]byte, filterHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, fmt.Errorf("read header: %w", err)
+	}
+	if string(header[0:4]) != filterMagicBytes {
+		return 0, 0, ErrInvalidMagic
+	}
+	version := binary.LittleEndian.Uint16(header[4:6])
+	if version < 1 || version > filterVersion {
+		return 0, 0, ErrInvalidVersion
+	}
+	count := binary.LittleEndian.Uint32(header[8:12])
+	if count > maxNgramCount {
+		return 0, 0, ErrInvalidCount
+	}
+	return version, count, nil
+}
+
+// ReadBitmapFilter reads a bitmap filter from a reader.
+// Both the streaming format written by Encode and the older msgpack format are accepted.
+func ReadBitmapFilter(r io.Reader) (*BitmapFilter, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(filterMagicBytes))
+	if err != nil || string(magic) != filterMagicBytes {
+		return readLegacyBitmapFilter(br)
+	}
+
+	version, count, err := readFilterHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewBitmapFilter()
+	lenBuf := make([]byte, 4)
+	for i := uint32(0); i < count; i++ {
+		h, bm, err := readFilterEntry(br, lenBuf, version)
+		if err != nil {
+			return nil, err
+		}
+		if h.hasID {
+			if _, taken := c.cats.name(h.id); taken {
+				return nil, fmt.Errorf("category %s/%s: duplicate id %d: %w", h.field, h.category, h.id, ErrInvalidCount)
+			}
+			// IDs past twice the entry count are kept sparse
+			c.cats.assign(h.id, h.field, h.category, 2*int(count))
+		}
+		c.putCategoryLocked(h.field, h.category, bm)
+		c.all.Or(bm)
+	}
+
+	return c, nil
+}
+
+// SaveField saves a single field to a file atomically, in the same format as SaveToFile.
+// Fields saved separately can be loaded back individually with LoadField or
+// opened together with OpenCachedBitmapFilter.
+func (c *BitmapFilter) SaveField(path, field string) error {
+	c.mu.RLock()
+	refs := c.entryRefsLocked(field)
+	c.mu.RUnlock()
+
+	if len(refs) == 0 {
+		return ErrFieldNotFound
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := c.encodeEntries(file, refs); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// LoadField loads one field from a file written by SaveField or SaveToFile,
+// replacing any existing categories of that field. Other fields in the file
+// are skipped without being decoded.
+func (c *BitmapFilter) LoadField(path, field string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := scanFilterEntries(file)
+	if err != nil {
+		return err
+	}
+
+	fieldMap := make(map[string]*roaring.Bitmap)
+	for _, e := range entries {
+		if e.field != field {
+			continue
+		}
+		bm, err := readFilterBitmap(file, e.loc)
+		if err != nil {
+			return err
+		}
+		fieldMap[e.category] = bm
+	}
+	if len(fieldMap) == 0 {
+		return ErrFieldNotFound
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Categories keep the IDs they have in c; the file's IDs may clash
+	for cat := range c.fields[field] {
+		c.cats.drop(field, cat)
+	}
+	delete(c.fields, field)
+	for _, cat := range slices.Sorted(maps.Keys(fieldMap)) {
+		c.putCategoryLocked(field, cat, fieldMap[cat])
+		c.all.Or(fieldMap[cat])
+	}
+	c.dirty.Store(true)
+	return nil
+}
+
+// filterEntryLocation records where an entry's bitmap lives in a filter file.
+type filterEntryLocation struct {
+	field    string
+	category string
+	loc      ngramLocation
+}
+
+// scanFilterEntries reads entry names and bitmap locations from a streaming
+// filter file without decoding any bitmaps.
+func scanFilterEntries(r io.Reader) ([]filterEntryLocation, error) {
+	br := bufio.NewReader(r)
+
+	version, count, err := readFilterHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]filterEntryLocation, 0, count)
+	offset := int64(filterHeaderSize)
+	lenBuf := make([]byte, 4)
+	for i := uint32(0); i < count; i++ {
+		h, err := readFilterEntryHeader(br, lenBuf, version)
+		if err != nil {
+			return nil, err
+		}
+
+		offset += h.size()
+		entries = append(entries, filterEntryLocation{
+			field:    h.field,
+			category: h.category,
+			loc:      ngramLocation{offset: offset, size: h.bmSize},
+		})
+
+		if _, err := br.Discard(int(h.bmSize)); err != nil {
+			return nil, fmt.Errorf("skip bitmap: %w", err)
+		}
+		offset += int64(h.bmSize)
+	}
+
+	return entries, nil
+}
+
+// readFilterBitmap decodes the bitmap at loc.
+func readFilterBitmap(r io.ReaderAt, loc ngramLocation) (*roaring.Bitmap, error) {
+	bm := roaring.New()
+	if _, err := bm.ReadFrom(io.NewSectionReader(r, loc.offset, int64(loc.size))); err != nil {
+		return nil, fmt.Errorf("deserialize bitmap: %w", err)
+	}
+	return bm, nil
+}
+
+// readLegacyBitmapFilter reads the msgpack format used before streaming encode.
+func readLegacyBitmapFilter(r io.Reader) (*BitmapFilter, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded bitmapFilterData
+	dec := msgpck.GetStructDecoder[bitmapFilterData](false)
+	if err := dec.Decode(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	c := &BitmapFilter{
+		fields: make(map[string]map[string]*roaring.Bitmap, len(decoded.Fields)),
+		all:    roaring.New(),
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(decoded.Fields)) {
+		fieldMap := decoded.Fields[field]
+		for _, cat := range slices.Sorted(maps.Keys(fieldMap)) {
+			bm := roaring.New()
+			if err := bm.UnmarshalBinary(fieldMap[cat]); err != nil {
+				return nil, err
+			}
+			c.putCategoryLocked(field, cat, bm)
+			c.all.Or(bm)
+		}
+	}
+
+	return c, nil
+}
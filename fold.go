@@ -0,0 +1,308 @@
+package roaringsearch
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NormalizeNFKCFold lowercases and removes non-alphanumeric characters like
+// NormalizeLowercaseAlphanumeric, after folding compatibility forms to their
+// standard equivalents: full-width ASCII, half-width katakana, ligatures and
+// super/subscript digits are mapped as NFKC does, and combining accents and
+// kana voicing marks are composed with the preceding letter, so "ｶﾞｲﾄﾞ"
+// matches "ガイド" and a decomposed "e\u0301" matches "é".
+// ASCII input is normalized exactly like NormalizeLowercaseAlphanumeric, so
+// the ASCII fast path stays enabled.
+func NormalizeNFKCFold(s string) string {
+	return foldText(s, false)
+}
+
+// NormalizeStripAccents applies NormalizeNFKCFold and additionally folds
+// accented Latin letters to their base letters ("café" matches "cafe") and
+// letters such as æ, ø and ß to their usual ASCII spelling.
+// ASCII input is normalized exactly like NormalizeLowercaseAlphanumeric, so
+// the ASCII fast path stays enabled.
+func NormalizeStripAccents(s string) string {
+	return foldText(s, true)
+}
+
+// foldText implements NormalizeNFKCFold and NormalizeStripAccents.
+func foldText(s string, stripAccents bool) string {
+	out := make([]byte, 0, len(s))
+	last := -1 // start of the last written rune, for composition
+
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			r = compatRune(r)
+		}
+
+		if r < utf8.RuneSelf {
+			c := byte(r)
+			if c >= 'A' && c <= 'Z' {
+				c += 32
+			} else if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				continue
+			}
+			last = len(out)
+			out = append(out, c)
+			continue
+		}
+
+		if expansion, ok := compatExpansions[r]; ok {
+			last = len(out) + len(expansion) - 1
+			out = append(out, expansion...)
+			continue
+		}
+
+		if stripAccents {
+			if folded, ok := latinLetterFolds[r]; ok {
+				last = len(out) + len(folded) - 1
+				out = append(out, folded...)
+				continue
+			}
+			if base, _, _, ok := latinDecompose(r); ok {
+				last = len(out)
+				out = append(out, base)
+				continue
+			}
+		}
+
+		if unicode.Is(unicode.Mn, r) {
+			if last < 0 {
+				continue
+			}
+			prev, _ := utf8.DecodeRune(out[last:])
+			if composed, ok := composeRune(prev, r, !stripAccents); ok {
+				out = utf8.AppendRune(out[:last], composed)
+			}
+			continue
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			last = len(out)
+			out = utf8.AppendRune(out, unicode.ToLower(r))
+		}
+	}
+
+	return string(out)
+}
+
+// Combining kana voicing marks.
+const (
+	kanaVoiced     = '\u3099'
+	kanaSemiVoiced = '\u309A'
+)
+
+// halfwidthKana holds the standard forms of U+FF61-U+FF9F.
+var halfwidthKana = []rune("。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン" +
+	string(kanaVoiced) + string(kanaSemiVoiced))
+
+// compatRune maps single-rune compatibility forms to their NFKC equivalents.
+func compatRune(r rune) rune {
+	switch {
+	case r >= 0xFF01 && r <= 0xFF5E: // full-width ASCII
+		return r - 0xFF01 + '!'
+	case r >= 0xFF61 && r <= 0xFF9F:
+		return halfwidthKana[r-0xFF61]
+	case r >= 0x2080 && r <= 0x2089: // subscript digits
+		return r - 0x2080 + '0'
+	case r == 0x2070 || (r >= 0x2074 && r <= 0x2079): // superscript digits
+		return r - 0x2070 + '0'
+	}
+
+	switch r {
+	case 0x3000:
+		return ' '
+	case 'ª':
+		return 'a'
+	case 'º':
+		return 'o'
+	case '¹':
+		return '1'
+	case '²':
+		return '2'
+	case '³':
+		return '3'
+	case 'ⁱ':
+		return 'i'
+	case 'ſ':
+		return 's'
+	case 'µ':
+		return 'μ'
+	}
+	return r
+}
+
+// compatExpansions maps compatibility ligatures to their lowercase letters.
+var compatExpansions = map[rune]string{
+	'ﬀ': "ff", 'ﬁ': "fi", 'ﬂ': "fl", 'ﬃ': "ffi", 'ﬄ': "ffl", 'ﬅ': "st", 'ﬆ': "st",
+	'Ĳ': "ij", 'ĳ': "ij", 'Ŀ': "l", 'ŀ': "l",
+}
+
+// latinLetterFolds maps Latin letters without a canonical decomposition to
+// their usual ASCII spelling.
+var latinLetterFolds = map[rune]string{
+	'Æ': "ae", 'æ': "ae", 'Œ': "oe", 'œ': "oe", 'ß': "ss", 'ẞ': "ss",
+	'Ø': "o", 'ø': "o", 'Đ': "d", 'đ': "d", 'Ð': "d", 'ð': "d",
+	'Ł': "l", 'ł': "l", 'Ħ': "h", 'ħ': "h", 'Ŧ': "t", 'ŧ': "t",
+	'Þ': "th", 'þ': "th", 'ı': "i",
+}
+
+// latinFold describes the canonical decompositions of precomposed Latin
+// letters starting at lo. For each rune, base holds the lowercase ASCII base
+// letter and mark1 and mark2 the combining marks in order, encoded as their
+// offset from U+0300 plus '0'. '-' marks runes that do not decompose to an
+// ASCII letter, or a missing second mark.
+type latinFold struct {
+	lo                 rune
+	base, mark1, mark2 string
+}
+
+// latinFolds covers Latin-1 Supplement, Latin Extended-A and -B and Latin
+// Extended Additional.
+var latinFolds = [...]latinFold{
+	{
+		lo: 0x00C0,
+		base: "aaaaaa-ceeeeiiii-nooooo--uuuuy--aaaaaa-ceeeeiiii-nooooo--uuuuy-y" +
+			"aaaaaaccccccccdd--eeeeeeeeeegggggggghh--iiiiiiiii---jjkk-llllll-" +
+			"---nnnnnn---oooooo--rrrrrrsssssssstttt--uuuuuuuuuuuuwwyyyzzzzzz-" +
+			"--------------------------------oo-------------uu---------------" +
+			"-------------aaiioouuuuuuuuuu-aaaa----ggkkoooo--j---gg--nnaa----" +
+			"aaaaeeeeiiiioooorrrruuuusstt--hh------aaeeooooooooyy------------" +
+			"----------------",
+		mark1: "01238:-W01280128-301238--01281--01238:-W01280128-301238--01281-8" +
+			"4466XX112277<<<<--446677XX<<226677WW22--334466XX7---22WW-11WW<<-" +
+			"---11WW<<---4466;;--11WW<<1122WW<<WW<<--334466::;;XX222281177<<-" +
+			"--------------------------------KK-------------KK---------------" +
+			"-------------<<<<<<<<88888888-8877----<<<<XXXX--<---11--00::----" +
+			"??AA??AA??AA??AA??AA??AAVVVV--<<------77WW8833777744------------" +
+			"----------------",
+		mark2: "----------------------------------------------------------------" +
+			"----------------------------------------------------------------" +
+			"----------------------------------------------------------------" +
+			"----------------------------------------------------------------" +
+			"---------------------4411<<00-4444----------44------------11----" +
+			"------------------------------------------4444--44--------------" +
+			"----------------",
+	},
+	{
+		lo: 0x1E00,
+		base: "aabbbbbbccddddddddddeeeeeeeeeeffgghhhhhhhhhhiiiikkkkkkllllllllmm" +
+			"mmmmnnnnnnnnoooooooopppprrrrrrrrssssssssssttttttttuuuuuuuuuuvvvv" +
+			"wwwwwwwwwwxxxxyyzzzzzzhtwy------aaaaaaaaaaaaaaaaaaaaaaaaeeeeeeee" +
+			"eeeeeeeeiiiioooooooooooooooooooooooouuuuuuuuuuuuuuyyyyyyyy------",
+		mark1: "UU77SSaaWW77SSaaWW]]4444]]``WW774477SS88WW^^``8811SSaaSSSSaa]]11" +
+			"77SS77SSaa]]33334444117777SSSSaa77SS11<<SS77SSaa]]TT``]]334433SS" +
+			"00118877SS77887722SSaaa8::------SS9922222222SS66666666SSSS993322" +
+			"222222SS99SSSS9922222222SSKKKKKKKKKKSS99KKKKKKKKKK00SS9933------",
+		mark2: "--------11----------0011----66----------------11--------44------" +
+			"------------11880011--------44------777777--------------1188----" +
+			"------------------------------------11009933221100993366------11" +
+			"00993322--------110099332211009933SS----11009933SS--------------",
+	},
+}
+
+// latinDecompose returns the ASCII base letter and combining marks of a
+// precomposed Latin letter. mark2 is 0 for letters with a single mark.
+func latinDecompose(r rune) (base byte, mark1, mark2 rune, ok bool) {
+	for i := range latinFolds {
+		f := &latinFolds[i]
+		if r < f.lo || r >= f.lo+rune(len(f.base)) {
+			continue
+		}
+		i := r - f.lo
+		if f.base[i] == '-' {
+			return 0, 0, 0, false
+		}
+		mark1 = rune(f.mark1[i]-'0') + 0x300
+		if f.mark2[i] != '-' {
+			mark2 = rune(f.mark2[i]-'0') + 0x300
+		}
+		return f.base[i], mark1, mark2, true
+	}
+	return 0, 0, 0, false
+}
+
+// latinCompositions maps a lowercase letter and a combining mark to the
+// precomposed letter, built from latinFolds on first use.
+var latinCompositions = sync.OnceValue(func() map[[2]rune]rune {
+	compositions := make(map[[2]rune]rune)
+	var twoMarks []rune
+	for _, f := range latinFolds {
+		for i := range len(f.base) {
+			r := f.lo + rune(i)
+			base, mark1, mark2, ok := latinDecompose(r)
+			if !ok || unicode.ToLower(r) != r {
+				continue
+			}
+			if mark2 != 0 {
+				twoMarks = append(twoMarks, r)
+				continue
+			}
+			compositions[[2]rune{rune(base), mark1}] = r
+		}
+	}
+
+	// Letters with two marks compose from the single-mark letter. A mark
+	// below and a mark above may come in either order.
+	for _, r := range twoMarks {
+		base, mark1, mark2, _ := latinDecompose(r)
+		if mid, ok := compositions[[2]rune{rune(base), mark1}]; ok {
+			compositions[[2]rune{mid, mark2}] = r
+		}
+		if isMarkBelow(mark1) != isMarkBelow(mark2) {
+			if mid, ok := compositions[[2]rune{rune(base), mark2}]; ok {
+				compositions[[2]rune{mid, mark1}] = r
+			}
+		}
+	}
+	return compositions
+})
+
+// isMarkBelow reports whether a combining mark from latinFolds attaches below
+// or to the letter (horn, cedilla, ogonek and marks below) rather than above.
+func isMarkBelow(mark rune) bool {
+	return mark >= 0x31B
+}
+
+// composeRune combines prev with the combining mark. Latin accents are only
+// composed when latin is true; kana voicing marks always are.
+func composeRune(prev, mark rune, latin bool) (rune, bool) {
+	if mark == kanaVoiced || mark == kanaSemiVoiced {
+		return voiceKana(prev, mark)
+	}
+	if !latin {
+		return 0, false
+	}
+	composed, ok := latinCompositions()[[2]rune{prev, mark}]
+	return composed, ok
+}
+
+// voiceKana applies a voicing mark to a hiragana or katakana letter.
+func voiceKana(r, mark rune) (rune, bool) {
+	const hiraganaOffset = 0x60
+
+	k := r
+	if r >= 'ぁ' && r <= 'ゖ' {
+		k += hiraganaOffset
+	}
+
+	var voiced rune
+	switch {
+	case mark == kanaVoiced && k == 'ウ':
+		voiced = 'ヴ'
+	case mark == kanaVoiced && strings.ContainsRune("カキクケコサシスセソタチツテトハヒフヘホ", k):
+		voiced = k + 1
+	case mark == kanaSemiVoiced && strings.ContainsRune("ハヒフヘホ", k):
+		voiced = k + 2
+	default:
+		return 0, false
+	}
+
+	if k != r {
+		voiced -= hiraganaOffset
+	}
+	return voiced, true
+}
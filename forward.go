@@ -0,0 +1,170 @@
+package roaringsearch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// forwardIndex maps each document to the sorted n-gram keys it was indexed
+// under, so removing or updating a document only visits its own bitmaps.
+// All methods are no-ops on a nil forwardIndex.
+type forwardIndex map[uint32][]uint64
+
+// WithForwardIndex keeps a per-document list of n-gram keys.
+// Remove and Update then touch only the bitmaps of the affected document
+// instead of scanning every bitmap, at the cost of roughly 8 bytes per
+// n-gram occurrence of each document. When applied to an already populated
+// index (e.g. via LoadFromFileWithOptions), the forward index is built from
+// the existing bitmaps.
+func WithForwardIndex() Option {
+	return indexOption(func(idx *Index) {
+		if idx.forward != nil {
+			return
+		}
+		idx.forward = make(forwardIndex)
+		for key, bm := range idx.bitmaps {
+			idx.forward.recordBitmap(key, bm)
+		}
+	})
+}
+
+// HasForwardIndex reports whether the index keeps a forward index.
+func (idx *Index) HasForwardIndex() bool {
+	return idx.forward != nil
+}
+
+// record adds keys to a document's key set. keys need not be sorted and are
+// not retained.
+func (f forwardIndex) record(docID uint32, keys []uint64) {
+	if f == nil || len(keys) == 0 {
+		return
+	}
+
+	merged := append(f[docID], keys...)
+	slices.Sort(merged)
+	f[docID] = slices.Clip(slices.Compact(merged))
+}
+
+// recordBitmap adds key to the key set of every document in bm.
+func (f forwardIndex) recordBitmap(key uint64, bm *roaring.Bitmap) {
+	if f == nil {
+		return
+	}
+
+	it := bm.Iterator()
+	for it.HasNext() {
+		docID := it.Next()
+		keys := f[docID]
+		if i, found := slices.BinarySearch(keys, key); !found {
+			f[docID] = slices.Insert(keys, i, key)
+		}
+	}
+}
+
+// keysOf returns the union of the key sets of every document in docs.
+func (f forwardIndex) keysOf(docs *roaring.Bitmap) map[uint64]struct{} {
+	keys := make(map[uint64]struct{})
+	it := docs.Iterator()
+	for it.HasNext() {
+		for _, key := range f[it.Next()] {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// removeDocs drops the key sets of every document in docs.
+func (f forwardIndex) removeDocs(docs *roaring.Bitmap) {
+	if f == nil {
+		return
+	}
+
+	it := docs.Iterator()
+	for it.HasNext() {
+		delete(f, it.Next())
+	}
+}
+
+// writeTo writes the forward index as: doc count(4), then per document in
+// docID order: docID(4) + key count(4) + keys(8 each).
+func (f forwardIndex) writeTo(w io.Writer) (int64, error) {
+	var written int64
+
+	docIDs := make([]uint32, 0, len(f))
+	for docID := range f {
+		docIDs = append(docIDs, docID)
+	}
+	slices.Sort(docIDs)
+
+	countBuf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(countBuf, uint32(len(docIDs)))
+	n, err := w.Write(countBuf)
+	written += int64(n)
+	if err != nil {
+		return written, fmt.Errorf("write forward doc count: %w", err)
+	}
+
+	var buf []byte
+	for _, docID := range docIDs {
+		keys := f[docID]
+		buf = binary.LittleEndian.AppendUint32(buf[:0], docID)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(keys)))
+		for _, key := range keys {
+			buf = binary.LittleEndian.AppendUint64(buf, key)
+		}
+
+		n, err := w.Write(buf)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("write forward entry: %w", err)
+		}
+	}
+
+	return written, nil
+}
+
+// readFrom reads entries written by writeTo into f.
+func (f forwardIndex) readFrom(r io.Reader) (int64, error) {
+	var read int64
+
+	countBuf := make([]byte, 4)
+	n, err := io.ReadFull(r, countBuf)
+	read += int64(n)
+	if err != nil {
+		return read, fmt.Errorf("read forward doc count: %w", err)
+	}
+	docCount := binary.LittleEndian.Uint32(countBuf)
+
+	entryBuf := make([]byte, 8)
+	for i := uint32(0); i < docCount; i++ {
+		n, err := io.ReadFull(r, entryBuf)
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("read forward entry: %w", err)
+		}
+		docID := binary.LittleEndian.Uint32(entryBuf[0:4])
+		keyCount := binary.LittleEndian.Uint32(entryBuf[4:8])
+		if keyCount > maxNgramCount {
+			return read, ErrInvalidCount
+		}
+
+		keyBytes := make([]byte, int(keyCount)*8)
+		n, err = io.ReadFull(r, keyBytes)
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("read forward keys: %w", err)
+		}
+
+		keys := make([]uint64, keyCount)
+		for j := range keys {
+			keys[j] = binary.LittleEndian.Uint64(keyBytes[j*8:])
+		}
+		f[docID] = keys
+	}
+
+	return read, nil
+}
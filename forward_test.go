@@ -0,0 +1,142 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexUpdate(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithForwardIndex()}} {
+		idx := NewIndex(3, opts...)
+		idx.Add(1, testHelloWorld)
+		idx.Add(2, testHelloThere)
+
+		idx.Update(1, testGoodbyeWorld)
+
+		if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{2}) {
+			t.Errorf("forward=%v: Search(hello) = %v, want [2]", idx.HasForwardIndex(), got)
+		}
+		if got := idx.Search("goodbye"); !reflect.DeepEqual(got, []uint32{1}) {
+			t.Errorf("forward=%v: Search(goodbye) = %v, want [1]", idx.HasForwardIndex(), got)
+		}
+
+		expected := NewIndex(3)
+		expected.Add(1, testGoodbyeWorld)
+		expected.Add(2, testHelloThere)
+		if diff := CompareIndexes(idx, expected); !diff.Equal() {
+			t.Errorf("forward=%v: updated index differs from rebuilt: %+v", idx.HasForwardIndex(), diff)
+		}
+	}
+}
+
+func TestForwardIndexRemove(t *testing.T) {
+	idx := NewIndex(3, WithForwardIndex())
+
+	batch := idx.Batch()
+	for i := uint32(0); i < 500; i++ {
+		batch.Add(i, fmt.Sprintf("document %d ünïcode %d", i, i%7))
+	}
+	batch.Flush()
+	idx.Add(1000, "extra words")
+
+	expected := NewIndex(3)
+	for i := uint32(0); i < 500; i += 2 {
+		expected.Add(i, fmt.Sprintf("document %d ünïcode %d", i, i%7))
+	}
+
+	for i := uint32(1); i < 500; i += 2 {
+		idx.Remove(i)
+	}
+	idx.Remove(1000)
+
+	if diff := CompareIndexes(idx, expected); !diff.Equal() {
+		t.Errorf("index after forward removes differs: %+v", diff)
+	}
+	if len(idx.forward) != 250 {
+		t.Errorf("forward entries = %d, want 250", len(idx.forward))
+	}
+}
+
+func TestForwardIndexAfterLoad(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+
+	path := filepath.Join(t.TempDir(), "index.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, err := LoadFromFileWithOptions(path, WithForwardIndex())
+	if err != nil {
+		t.Fatalf("LoadFromFileWithOptions failed: %v", err)
+	}
+	if !loaded.HasForwardIndex() {
+		t.Fatal("expected forward index")
+	}
+
+	loaded.Remove(1)
+	if got := loaded.Search("world"); got != nil {
+		t.Errorf("Search(world) after remove = %v, want nil", got)
+	}
+	if got := loaded.Search("hello"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search(hello) = %v, want [2]", got)
+	}
+}
+
+func TestForwardIndexPersistence(t *testing.T) {
+	idx := NewIndex(3, WithForwardIndex())
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, "ünïcode wörld")
+
+	path := filepath.Join(t.TempDir(), "index.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if !loaded.HasForwardIndex() {
+		t.Fatal("forward index should be restored from file")
+	}
+	if !reflect.DeepEqual(loaded.forward, idx.forward) {
+		t.Errorf("forward index mismatch after load")
+	}
+
+	loaded.SubtractDocs([]uint32{1, 3})
+	if got := loaded.Search("world"); got != nil {
+		t.Errorf("Search(world) after subtract = %v, want nil", got)
+	}
+	if got := loaded.Search("hello"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search(hello) = %v, want [2]", got)
+	}
+
+	// Disk-backed index ignores the forward section
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	defer cached.Close()
+	if got := cached.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("cached Search(hello) = %v, want [1 2]", got)
+	}
+
+	// Indexes without a forward index keep the original format
+	plain := NewIndex(3)
+	plain.Add(1, testHelloWorld)
+	if err := plain.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	reloaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if reloaded.HasForwardIndex() {
+		t.Error("plain index should not gain a forward index")
+	}
+}
@@ -0,0 +1,430 @@
+package roaringsearch
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// FrozenIndex is an immutable snapshot of an Index, created with Freeze. It
+// has no locks: every search runs without synchronization, and per-n-gram
+// cardinalities are computed once up front instead of on every query. Use it
+// for serving workloads where the index is built offline and never modified.
+// A FrozenIndex is safe for concurrent use.
+type FrozenIndex struct {
+	gramSize        int
+	normalizer      Normalizer
+	ascii           *asciiTable
+	normalizerChain NormalizerChain
+	exact           map[string]uint64 // exact keys of hashed n-grams; nil unless WithExactKeys
+	cjkBigrams      bool
+	maxResults      int
+	missingNgrams   MissingNgramPolicy
+	synonyms        synonymSet
+	postings        map[uint64]frozenPosting
+	keys            []uint64 // n-gram keys in ascending order
+	docs            *roaring.Bitmap
+	stats           *queryStats // shared with the source Index
+}
+
+// frozenPosting is the bitmap of one n-gram and its precomputed cardinality.
+type frozenPosting struct {
+	bm   *roaring.Bitmap
+	card uint64
+}
+
+// Freeze returns an immutable snapshot of the index. Bitmaps are copied, so
+// the snapshot needs as much memory again as the index;
+// drop the Index afterwards if it will not be modified. The snapshot reports
+// to the index's query hook, slow query log and latency histogram, and keeps
+// the index's WithMaxResults, WithSynonyms and WithMissingNgramPolicy
+// settings, so a query returns the same results before and after Freeze.
+//
+// Freezing an index still loading with LoadFromFileAsync waits for the load
+// to finish. If the load fails, the snapshot holds the bitmaps loaded before
+// the error, as the index does; check ProgressiveLoad.Wait first.
+func (idx *Index) Freeze() *FrozenIndex {
+	idx.mu.RLock()
+	for idx.loading != nil {
+		load := idx.loading
+		idx.mu.RUnlock()
+		<-load.Done()
+		idx.mu.RLock()
+	}
+	defer idx.mu.RUnlock()
+
+	f := &FrozenIndex{
+		gramSize:        idx.gramSize,
+		normalizer:      idx.normalizer,
+		ascii:           idx.ascii,
+		normalizerChain: slices.Clone(idx.normalizerChain),
+		cjkBigrams:      idx.cjkBigrams,
+		maxResults:      idx.maxResults,
+		missingNgrams:   idx.missingNgrams,
+		synonyms:        maps.Clone(idx.synonyms),
+		postings:        make(map[uint64]frozenPosting, len(idx.bitmaps)),
+		keys:            make([]uint64, 0, len(idx.bitmaps)),
+		docs:            idx.docs.Clone(),
+		stats:           idx.stats,
+	}
+	for key, bm := range idx.bitmaps {
+		bm = bm.Clone()
+		f.postings[key] = frozenPosting{bm: bm, card: bm.GetCardinality()}
+		f.keys = append(f.keys, key)
+	}
+	slices.Sort(f.keys)
+	if idx.exact != nil {
+		idx.exact.mu.RLock()
+		f.exact = maps.Clone(idx.exact.keys)
+		idx.exact.mu.RUnlock()
+	}
+	return f
+}
+
+// GramSize returns the n-gram size of the snapshot.
+func (f *FrozenIndex) GramSize() int {
+	return f.gramSize
+}
+
+// NgramCount returns the number of unique n-grams in the snapshot.
+func (f *FrozenIndex) NgramCount() int {
+	return len(f.keys)
+}
+
+// DocCount returns the number of documents in the snapshot.
+func (f *FrozenIndex) DocCount() uint64 {
+	return f.docs.GetCardinality()
+}
+
+// AllDocs returns a copy of the bitmap of every document in the snapshot.
+func (f *FrozenIndex) AllDocs() *roaring.Bitmap {
+	return f.docs.Clone()
+}
+
+// NormalizerChain returns the normalizer chain of the source index, or nil.
+func (f *FrozenIndex) NormalizerChain() NormalizerChain {
+	return slices.Clone(f.normalizerChain)
+}
+
+// Keys returns the n-gram keys of the snapshot in ascending order.
+func (f *FrozenIndex) Keys() []uint64 {
+	return slices.Clone(f.keys)
+}
+
+// appendQueryKeys is Index.appendQueryKeys without the exact-key lock.
+func (f *FrozenIndex) appendQueryKeys(keys []uint64, query string) []uint64 {
+	if f.ascii != nil {
+		var buf [128]byte
+		var ok bool
+		if keys, _, ok = normalizeAndKeyASCIIPooled(query, f.gramSize, keys, buf[:0], f.ascii); ok {
+			return keys
+		}
+	}
+
+	runes := []rune(f.normalizer(query))
+	if f.cjkBigrams {
+		return appendCJKKeys(keys, runes, f.gramSize, f.queryKey)
+	}
+	for i := 0; i <= len(runes)-f.gramSize; i++ {
+		keys = appendKeyDedup(keys, f.queryKey(runes[i:i+f.gramSize]))
+	}
+	return keys
+}
+
+// queryKey returns the key an n-gram is stored under.
+func (f *FrozenIndex) queryKey(runes []rune) uint64 {
+	if f.exact != nil && isHashedNgram(runes) {
+		if key, ok := f.exact[string(runes)]; ok {
+			return key
+		}
+		return missingNgramKey
+	}
+	return runeNgramKey(runes)
+}
+
+// queryNgramCount returns the number of unique n-grams in the normalized query.
+func (f *FrozenIndex) queryNgramCount(query string) int {
+	var keyBuf [queryKeyBufSize]uint64
+	return len(f.appendQueryKeys(keyBuf[:0], query))
+}
+
+// recordQuery reports a completed search; callers check f.stats != nil first.
+func (f *FrozenIndex) recordQuery(method, query string, results int, start time.Time) {
+	f.stats.record(method, query, results, start, f.queryNgramCount)
+}
+
+// collect gathers the bitmaps of keys into s.bitmaps, smallest first by the
+// precomputed cardinalities. Returns false if any key is missing.
+func (f *FrozenIndex) collect(s *searchScratch, keys []uint64) bool {
+	s.bitmaps = s.bitmaps[:0]
+	s.cards = s.cards[:0]
+	for _, key := range keys {
+		p, ok := f.postings[key]
+		if !ok {
+			return false
+		}
+		i := len(s.cards)
+		for i > 0 && s.cards[i-1] > p.card {
+			i--
+		}
+		s.bitmaps = slices.Insert(s.bitmaps, i, p.bm)
+		s.cards = slices.Insert(s.cards, i, p.card)
+	}
+	return len(s.bitmaps) > 0
+}
+
+// collectPresent gathers the bitmaps of the keys that are in the snapshot
+// into s.bitmaps, unsorted.
+func (f *FrozenIndex) collectPresent(s *searchScratch, keys []uint64) {
+	s.bitmaps = s.bitmaps[:0]
+	for _, key := range keys {
+		if p, ok := f.postings[key]; ok {
+			s.bitmaps = append(s.bitmaps, p.bm)
+		}
+	}
+}
+
+// existing returns the bitmaps of the keys present in the snapshot.
+func (f *FrozenIndex) existing(keys []uint64) []*roaring.Bitmap {
+	bitmaps := make([]*roaring.Bitmap, 0, len(keys))
+	for _, key := range keys {
+		if p, ok := f.postings[key]; ok {
+			bitmaps = append(bitmaps, p.bm)
+		}
+	}
+	return bitmaps
+}
+
+// Search performs an AND search for documents containing all n-grams of the query.
+func (f *FrozenIndex) Search(query string) (matches []uint32) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("Search", query, len(matches), start) }(time.Now())
+	}
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if variants := f.synonyms.expand(query); variants != nil {
+		if bm := f.searchVariants(scratch, variants); !bm.IsEmpty() {
+			return limitedArray(bm, f.maxResults)
+		}
+		return nil
+	}
+	matches = f.searchAppend(scratch, query, nil)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
+}
+
+// SearchAppend appends the documents containing all n-grams of the query to
+// dst, like Index.SearchAppend.
+func (f *FrozenIndex) SearchAppend(query string, dst []uint32) (out []uint32) {
+	if f.stats != nil {
+		n := len(dst)
+		defer func(start time.Time) { f.recordQuery("SearchAppend", query, len(out)-n, start) }(time.Now())
+	}
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	return f.searchAppend(scratch, query, dst)
+}
+
+// searchAppend appends the AND matches of query to dst, up to the result
+// limit, applying the missing n-gram policy.
+func (f *FrozenIndex) searchAppend(s *searchScratch, query string, dst []uint32) []uint32 {
+	s.keys = f.appendQueryKeys(s.keys[:0], query)
+	if len(s.keys) == 0 {
+		return dst
+	}
+	if !f.collect(s, s.keys) {
+		if f.missingNgrams == MissingNgramFail {
+			return dst
+		}
+		f.collectPresent(s, s.keys)
+		bm := partialBitmap(f.missingNgrams, s.bitmaps, f.gramSize)
+		if bm == nil {
+			return dst
+		}
+		return append(dst, limitedArray(bm, f.maxResults)...)
+	}
+	dst, _ = s.appendCollectedMax(dst, f.maxResults)
+	return dst
+}
+
+// searchBitmap returns the AND matches of query as a bitmap the caller
+// owns, applying the missing n-gram policy.
+func (f *FrozenIndex) searchBitmap(s *searchScratch, query string) *roaring.Bitmap {
+	s.keys = f.appendQueryKeys(s.keys[:0], query)
+	if len(s.keys) == 0 {
+		return roaring.New()
+	}
+	if !f.collect(s, s.keys) {
+		if f.missingNgrams == MissingNgramFail {
+			return roaring.New()
+		}
+		f.collectPresent(s, s.keys)
+		bm := partialBitmap(f.missingNgrams, s.bitmaps, f.gramSize)
+		if bm == nil {
+			return roaring.New()
+		}
+		if len(s.bitmaps) == 1 {
+			return bm.Clone()
+		}
+		return bm
+	}
+	if len(s.bitmaps) == 1 {
+		return s.bitmaps[0].Clone()
+	}
+	return roaring.FastAnd(s.bitmaps...)
+}
+
+// searchVariants ORs the AND results of query variants.
+func (f *FrozenIndex) searchVariants(s *searchScratch, variants []string) *roaring.Bitmap {
+	result := roaring.New()
+	for _, v := range variants {
+		result.Or(f.searchBitmap(s, v))
+	}
+	return result
+}
+
+// SearchBitmap is like Search but returns the matches as a bitmap. A query
+// that is empty after normalization matches every document.
+func (f *FrozenIndex) SearchBitmap(query string) (matches *roaring.Bitmap) {
+	if f.stats != nil {
+		defer func(start time.Time) {
+			f.recordQuery("SearchBitmap", query, int(matches.GetCardinality()), start)
+		}(time.Now())
+	}
+	if strings.TrimSpace(f.normalizer(query)) == "" {
+		return f.AllDocs()
+	}
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if variants := f.synonyms.expand(query); variants != nil {
+		return f.searchVariants(scratch, variants)
+	}
+	return f.searchBitmap(scratch, query)
+}
+
+// SearchWithLimit returns up to limit matching document IDs.
+func (f *FrozenIndex) SearchWithLimit(query string, limit int) (matches []uint32) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("SearchWithLimit", query, len(matches), start) }(time.Now())
+	}
+	if limit <= 0 {
+		return nil
+	}
+	f.searchCallback(query, func(docID uint32) bool {
+		matches = append(matches, docID)
+		return len(matches) < limit
+	})
+	return matches
+}
+
+// SearchCallback calls cb for each matching document ID in ascending order
+// until cb returns false. Returns false if cb returned false, true otherwise.
+func (f *FrozenIndex) SearchCallback(query string, cb func(docID uint32) bool) bool {
+	if f.stats != nil {
+		visited := 0
+		inner := cb
+		cb = func(docID uint32) bool {
+			visited++
+			return inner(docID)
+		}
+		defer func(start time.Time) { f.recordQuery("SearchCallback", query, visited, start) }(time.Now())
+	}
+	return f.searchCallback(query, cb)
+}
+
+func (f *FrozenIndex) searchCallback(query string, cb func(docID uint32) bool) bool {
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	scratch.keys = f.appendQueryKeys(scratch.keys[:0], query)
+	if len(scratch.keys) == 0 || !f.collect(scratch, scratch.keys) {
+		return true
+	}
+
+	rest := scratch.bitmaps[1:]
+	it := scratch.bitmaps[0].Iterator()
+	for it.HasNext() {
+		docID := it.Next()
+		if existsInAllBitmaps(docID, rest) && !cb(docID) {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchCount returns the count of matching documents.
+func (f *FrozenIndex) SearchCount(query string) (matches uint64) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("SearchCount", query, int(matches), start) }(time.Now())
+	}
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	scratch.keys = f.appendQueryKeys(scratch.keys[:0], query)
+	if len(scratch.keys) == 0 || !f.collect(scratch, scratch.keys) {
+		return 0
+	}
+	return scratch.countCollected()
+}
+
+// SearchAny returns documents containing any n-gram of the query (OR search).
+func (f *FrozenIndex) SearchAny(query string) (matches []uint32) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("SearchAny", query, len(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	bitmaps := f.existing(f.appendQueryKeys(keyBuf[:0], query))
+	if len(bitmaps) == 0 {
+		return nil
+	}
+	return limitedArray(roaring.FastOr(bitmaps...), f.maxResults)
+}
+
+// SearchAnyCount returns the count of documents matching any n-gram (OR search).
+func (f *FrozenIndex) SearchAnyCount(query string) (matches uint64) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("SearchAnyCount", query, int(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	bitmaps := f.existing(f.appendQueryKeys(keyBuf[:0], query))
+	if len(bitmaps) == 0 {
+		return 0
+	}
+	return roaring.FastOr(bitmaps...).GetCardinality()
+}
+
+// SearchThreshold returns documents containing at least threshold n-grams of
+// the query, with the number matched per document.
+func (f *FrozenIndex) SearchThreshold(query string, threshold int) (result SearchResult) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("SearchThreshold", query, len(result.DocIDs), start) }(time.Now())
+	}
+	return f.searchThreshold(query, threshold, 0)
+}
+
+// SearchThresholdTopK returns at most k documents containing at least
+// threshold n-grams of the query, best scores first.
+func (f *FrozenIndex) SearchThresholdTopK(query string, threshold, k int) (result SearchResult) {
+	if f.stats != nil {
+		defer func(start time.Time) { f.recordQuery("SearchThresholdTopK", query, len(result.DocIDs), start) }(time.Now())
+	}
+	return f.searchThreshold(query, threshold, k)
+}
+
+func (f *FrozenIndex) searchThreshold(query string, threshold, k int) SearchResult {
+	var keyBuf [queryKeyBufSize]uint64
+	keys := f.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 || threshold <= 0 {
+		return SearchResult{}
+	}
+	bitmaps := f.existing(keys)
+	if len(bitmaps) == 0 {
+		return SearchResult{}
+	}
+	levels := matchLevels(bitmaps)
+	return thresholdResult(levels, min(threshold, len(bitmaps)), k)
+}
@@ -0,0 +1,208 @@
+package roaringsearch
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestFreezeMatchesIndex(t *testing.T) {
+	idx := NewIndex(3)
+	rng := rand.New(rand.NewSource(1))
+	for i := uint32(0); i < 2000; i++ {
+		idx.Add(i, generateDocument(rng, 5, 20))
+	}
+	f := idx.Freeze()
+
+	if f.NgramCount() != idx.NgramCount() || f.DocCount() != idx.DocCount() || f.GramSize() != 3 {
+		t.Fatalf("frozen %d n-grams %d docs, index %d n-grams %d docs",
+			f.NgramCount(), f.DocCount(), idx.NgramCount(), idx.DocCount())
+	}
+	keys := f.Keys()
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1] >= keys[i] {
+			t.Fatal("Keys not in ascending order")
+		}
+	}
+
+	for _, q := range []string{"server", "the", "john server", "xylophone", "zzzz", "", "ab"} {
+		if got, want := f.Search(q), idx.Search(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) = %d docs, want %d", q, len(got), len(want))
+		}
+		if got, want := f.SearchAppend(q, []uint32{7}), idx.SearchAppend(q, []uint32{7}); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchAppend(%q) differs", q)
+		}
+		if got, want := f.SearchBitmap(q), idx.SearchBitmap(q); !got.Equals(want) {
+			t.Errorf("SearchBitmap(%q) differs", q)
+		}
+		if got, want := f.SearchWithLimit(q, 10), idx.SearchWithLimit(q, 10); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchWithLimit(%q) = %v, want %v", q, got, want)
+		}
+		if got, want := f.SearchCount(q), idx.SearchCount(q); got != want {
+			t.Errorf("SearchCount(%q) = %d, want %d", q, got, want)
+		}
+		if got, want := f.SearchAny(q), idx.SearchAny(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchAny(%q) = %d docs, want %d", q, len(got), len(want))
+		}
+		if got, want := f.SearchAnyCount(q), idx.SearchAnyCount(q); got != want {
+			t.Errorf("SearchAnyCount(%q) = %d, want %d", q, got, want)
+		}
+		if got, want := f.SearchThresholdTopK(q, 2, 5), idx.SearchThresholdTopK(q, 2, 5); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchThresholdTopK(%q) = %v, want %v", q, got, want)
+		}
+	}
+
+	// The snapshot does not see later writes
+	idx.Add(5000, "xylophone quartet")
+	idx.Remove(0)
+	if f.DocCount() != 2000 || f.SearchCount("quartet") != 0 {
+		t.Error("frozen index changed after the source index was modified")
+	}
+}
+
+func TestFreezeExactKeys(t *testing.T) {
+	idx := NewIndex(3, WithExactKeys())
+	idx.Add(1, "日本語のテキスト")
+	idx.Add(2, "日本の首都")
+	f := idx.Freeze()
+
+	if got := f.Search("日本語"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(日本語) = %v, want [1]", got)
+	}
+	if got := f.SearchAny("の首都 日本語"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("SearchAny = %v, want [1 2]", got)
+	}
+	if got := f.Search("未知語"); got != nil {
+		t.Errorf("unknown n-gram matched %v", got)
+	}
+}
+
+func TestFreezeKeepsSearchSettings(t *testing.T) {
+	idx := NewIndex(3,
+		WithMaxResults(2),
+		WithSynonyms(map[string][]string{"tv": {"television"}}),
+		WithMissingNgramPolicy(MissingNgramIgnore))
+	idx.Add(1, "television stand")
+	idx.Add(2, "tv stand")
+	idx.Add(3, "oak stand")
+	idx.Add(4, "pine stand")
+	f := idx.Freeze()
+
+	for _, q := range []string{"stand", "tv stand", "stanx", "oak stanq"} {
+		if got, want := f.Search(q), idx.Search(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", q, got, want)
+		}
+		if got, want := f.SearchAppend(q, nil), idx.SearchAppend(q, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchAppend(%q) = %v, want %v", q, got, want)
+		}
+		if got, want := f.SearchBitmap(q), idx.SearchBitmap(q); !got.Equals(want) {
+			t.Errorf("SearchBitmap(%q) = %v, want %v", q, got.ToArray(), want.ToArray())
+		}
+		if got, want := f.SearchAny(q), idx.SearchAny(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchAny(%q) = %v, want %v", q, got, want)
+		}
+	}
+	if got := f.Search("stand"); len(got) != 2 {
+		t.Errorf("Search(stand) = %v, want the 2 result limit", got)
+	}
+}
+
+func TestFreezeWaitsForAsyncLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.sear")
+	idx := NewIndex(3)
+	rng := rand.New(rand.NewSource(1))
+	for i := uint32(0); i < 2000; i++ {
+		idx.Add(i, generateDocument(rng, 5, 20))
+	}
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, load, err := LoadFromFileAsync(path)
+	if err != nil {
+		t.Fatalf("LoadFromFileAsync failed: %v", err)
+	}
+	f := loaded.Freeze()
+	if err := load.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if f.NgramCount() != idx.NgramCount() || f.DocCount() != idx.DocCount() {
+		t.Errorf("frozen %d n-grams %d docs, want %d and %d",
+			f.NgramCount(), f.DocCount(), idx.NgramCount(), idx.DocCount())
+	}
+}
+
+func TestFreezeStats(t *testing.T) {
+	var methods []string
+	var results []int
+	idx := NewIndex(3, WithLatencyHistogram(0), WithQueryHook(func(s QueryStats) {
+		methods = append(methods, s.Method)
+		results = append(results, s.Results)
+	}))
+	idx.Add(1, testHelloWorld)
+	f := idx.Freeze()
+
+	f.Search("hello")
+	f.SearchWithLimit("hello", 1)
+	f.SearchCallback("world", func(uint32) bool { return true })
+	f.SearchAppend("hello", []uint32{9})
+	if want := []string{"Search", "SearchWithLimit", "SearchCallback", "SearchAppend"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("recorded %v, want %v", methods, want)
+	}
+	if results[3] != 1 {
+		t.Errorf("SearchAppend recorded %d results, want 1", results[3])
+	}
+	if idx.SearchLatency().Count != 4 {
+		t.Errorf("latency count = %d, want 4", idx.SearchLatency().Count)
+	}
+}
+
+func TestFreezeConcurrentSearch(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, testHelloWorld)
+	f := idx.Freeze()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				if got := f.Search("brown fox"); len(got) != 1 || got[0] != 1 {
+					t.Errorf("Search = %v", got)
+					return
+				}
+				f.SearchThreshold("hello fox", 1)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkFrozenSearch(b *testing.B) {
+	idx := NewIndex(3)
+	rng := rand.New(rand.NewSource(42))
+	for i := uint32(0); i < 100_000; i++ {
+		idx.Add(i, generateDocument(rng, 5, 20))
+	}
+	f := idx.Freeze()
+	queries := []string{"server", "client", "database"}
+
+	b.Run("Index", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				idx.SearchCount(queries[i%len(queries)])
+			}
+		})
+	})
+	b.Run("Frozen", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				f.SearchCount(queries[i%len(queries)])
+			}
+		})
+	})
+}
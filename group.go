@@ -0,0 +1,90 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// ResultGroup holds the top documents of one category of a grouped result.
+type ResultGroup[T cmp.Ordered] struct {
+	Category string
+	Count    uint64            // documents of the result in this category
+	Docs     []SortedResult[T] // at most perGroupLimit documents, best first
+}
+
+// GroupBy collapses a result set by the categories of a filter field, for
+// search pages with a section per category (e.g. the top 3 results per
+// media_type). Each category is intersected with result and its documents
+// are ranked by col, highest value first, keeping at most perGroupLimit with
+// a bounded heap. A nil col keeps the lowest docIDs instead, and a nil result
+// groups every document. perGroupLimit <= 0 keeps every document.
+//
+// Groups are ordered by Count, largest first, then by category name; empty
+// groups and unknown fields yield nothing. A document in several categories
+// appears in each of their groups.
+//
+// Example:
+//
+//	groups := GroupBy(filter, idx.SearchBitmap("star"), "media_type", 3, ratings)
+func GroupBy[T cmp.Ordered](filter *BitmapFilter, result *roaring.Bitmap, field string, perGroupLimit int, col *SortColumn[T]) []ResultGroup[T] {
+	filter.mu.RLock()
+	defer filter.mu.RUnlock()
+
+	if col != nil {
+		col.mu.RLock()
+		defer col.mu.RUnlock()
+	}
+
+	var groups []ResultGroup[T]
+	for cat, bm := range filter.fields[field] {
+		members := bm
+		if result != nil {
+			members = roaring.And(bm, result)
+		}
+		count := members.GetCardinality()
+		if count == 0 {
+			continue
+		}
+		groups = append(groups, ResultGroup[T]{
+			Category: cat,
+			Count:    count,
+			Docs:     topGroupDocs(members, perGroupLimit, col),
+		})
+	}
+
+	slices.SortFunc(groups, func(a, b ResultGroup[T]) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Category, b.Category)
+	})
+	return groups
+}
+
+// topGroupDocs returns the best limit documents of bm by col, or the first
+// limit docIDs when col is nil. The caller holds col's read lock.
+func topGroupDocs[T cmp.Ordered](bm *roaring.Bitmap, limit int, col *SortColumn[T]) []SortedResult[T] {
+	card := int(bm.GetCardinality())
+	if limit <= 0 || limit > card {
+		limit = card
+	}
+
+	if col == nil {
+		docs := make([]SortedResult[T], 0, limit)
+		it := bm.Iterator()
+		for it.HasNext() && len(docs) < limit {
+			docs = append(docs, SortedResult[T]{DocID: it.Next()})
+		}
+		return docs
+	}
+
+	h := &resultHeap[T]{items: make([]SortedResult[T], 0, limit)}
+	it := bm.Iterator()
+	for it.HasNext() {
+		docID := it.Next()
+		heapInsert(h, docID, col.valueLocked(docID), false, limit)
+	}
+	return heapToSortedResults(h)
+}
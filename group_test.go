@@ -0,0 +1,59 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestGroupBy(t *testing.T) {
+	filter := NewBitmapFilter()
+	ratings := NewSortColumn[uint16]()
+	for docID, mediaType := range map[uint32]string{
+		1: "book", 2: "book", 3: "book", 4: "book",
+		5: "movie", 6: "movie",
+		7: "music",
+	} {
+		filter.Set(docID, "media_type", mediaType)
+		ratings.Set(docID, uint16(docID*10))
+	}
+	filter.Set(8, "media_type", "podcast")
+
+	result := roaring.BitmapOf(1, 2, 3, 4, 5, 6, 7)
+	groups := GroupBy(filter, result, "media_type", 2, ratings)
+
+	var cats []string
+	for _, g := range groups {
+		cats = append(cats, g.Category)
+	}
+	if !reflect.DeepEqual(cats, []string{"book", "movie", "music"}) {
+		t.Fatalf("groups = %v, want [book movie music]", cats)
+	}
+	if groups[0].Count != 4 {
+		t.Errorf("book Count = %d, want 4", groups[0].Count)
+	}
+	want := []SortedResult[uint16]{{DocID: 4, Value: 40}, {DocID: 3, Value: 30}}
+	if !reflect.DeepEqual(groups[0].Docs, want) {
+		t.Errorf("book Docs = %v, want %v", groups[0].Docs, want)
+	}
+	if len(groups[2].Docs) != 1 || groups[2].Docs[0].DocID != 7 {
+		t.Errorf("music Docs = %v, want [7]", groups[2].Docs)
+	}
+
+	// Without a column the lowest docIDs are kept
+	byID := GroupBy[uint16](filter, result, "media_type", 2, nil)
+	if got := byID[0].Docs; len(got) != 2 || got[0].DocID != 1 || got[1].DocID != 2 {
+		t.Errorf("book Docs without column = %v, want [1 2]", got)
+	}
+
+	// A nil result groups every document; no limit keeps them all
+	all := GroupBy(filter, nil, "media_type", 0, ratings)
+	if len(all) != 4 || len(all[0].Docs) != 4 {
+		t.Errorf("GroupBy(nil result) = %d groups, first with %d docs, want 4 and 4", len(all), len(all[0].Docs))
+	}
+
+	if got := GroupBy(filter, result, "missing", 3, ratings); got != nil {
+		t.Errorf("GroupBy(unknown field) = %v, want nil", got)
+	}
+}
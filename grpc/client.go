@@ -0,0 +1,200 @@
+package rsgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	rs "github.com/freeeve/roaringsearch"
+	"github.com/freeeve/roaringsearch/grpc/searchpb"
+	"google.golang.org/grpc"
+)
+
+// Client calls a SearchService. Streamed results are collected into slices,
+// or handed over a chunk at a time with SearchEach.
+type Client struct {
+	rpc searchpb.SearchServiceClient
+}
+
+// NewClient returns a Client using cc, e.g. a *grpc.ClientConn.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{rpc: searchpb.NewSearchServiceClient(cc)}
+}
+
+// Search returns up to limit documents containing all n-grams of the query,
+// or every match if limit is 0.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]uint32, error) {
+	var ids []uint32
+	err := c.SearchEach(ctx, &searchpb.SearchRequest{Query: query, Limit: uint32(max(limit, 0))}, func(chunk []uint32) error {
+		ids = append(ids, chunk...)
+		return nil
+	})
+	return ids, err
+}
+
+// SearchEach runs a Search and calls fn with each chunk of docIDs as it
+// arrives, in ascending order, so very large answers need not be held in
+// memory. The chunk is only valid during the call. An error from fn cancels
+// the stream and is returned.
+func (c *Client) SearchEach(ctx context.Context, req *searchpb.SearchRequest, fn func(chunk []uint32) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.rpc.Search(ctx, req)
+	if err != nil {
+		return err
+	}
+	return recvEach(stream, func(chunk *searchpb.DocIDChunk) error {
+		return fn(chunk.DocIds)
+	})
+}
+
+// SearchAny returns up to limit documents containing any n-gram of the
+// query, or every match if limit is 0.
+func (c *Client) SearchAny(ctx context.Context, query string, limit int) ([]uint32, error) {
+	stream, err := c.rpc.SearchAny(ctx, &searchpb.SearchRequest{Query: query, Limit: uint32(max(limit, 0))})
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint32
+	err = recvEach(stream, func(chunk *searchpb.DocIDChunk) error {
+		ids = append(ids, chunk.DocIds...)
+		return nil
+	})
+	return ids, err
+}
+
+// SearchThreshold returns up to limit documents containing at least
+// minMatches n-grams of the query, best scores first, as
+// Index.SearchThresholdTopK does.
+func (c *Client) SearchThreshold(ctx context.Context, query string, minMatches, limit int) (rs.SearchResult, error) {
+	stream, err := c.rpc.SearchThreshold(ctx, &searchpb.ThresholdRequest{
+		Query:      query,
+		MinMatches: uint32(max(minMatches, 0)),
+		Limit:      uint32(max(limit, 0)),
+	})
+	if err != nil {
+		return rs.SearchResult{}, err
+	}
+	result := rs.SearchResult{Scores: make(map[uint32]int)}
+	err = recvEach(stream, func(chunk *searchpb.ScoredChunk) error {
+		for _, d := range chunk.Docs {
+			result.DocIDs = append(result.DocIDs, d.DocId)
+			result.Scores[d.DocId] = int(d.Score)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SearchCount returns the number of documents Search would return.
+func (c *Client) SearchCount(ctx context.Context, query string) (uint64, error) {
+	resp, err := c.rpc.SearchCount(ctx, &searchpb.SearchRequest{Query: query})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// Facets returns per-category counts of the filter fields over the query's
+// matches, or over every document for an empty query, as
+// BitmapFilter.Facets does. Unknown fields are omitted.
+func (c *Client) Facets(ctx context.Context, query string, fields ...string) (map[string]map[string]uint64, error) {
+	resp, err := c.rpc.Facets(ctx, &searchpb.FacetsRequest{Query: query, Fields: fields})
+	if err != nil {
+		return nil, err
+	}
+	facets := make(map[string]map[string]uint64, len(resp.Fields))
+	for field, counts := range resp.Fields {
+		facets[field] = counts.Counts
+	}
+	return facets, nil
+}
+
+// Stats returns statistics of the served index with the top heaviest n-grams.
+func (c *Client) Stats(ctx context.Context, top int) (*searchpb.StatsResponse, error) {
+	return c.rpc.Stats(ctx, &searchpb.StatsRequest{Top: uint32(max(top, 0))})
+}
+
+// CreateSnapshot saves the served index to path in the server's snapshot
+// directory.
+func (c *Client) CreateSnapshot(ctx context.Context, path string) (*searchpb.SnapshotResponse, error) {
+	return c.rpc.CreateSnapshot(ctx, &searchpb.SnapshotRequest{Path: path})
+}
+
+// RestoreSnapshot makes the server serve the index saved at path in its
+// snapshot directory.
+func (c *Client) RestoreSnapshot(ctx context.Context, path string) (*searchpb.SnapshotResponse, error) {
+	return c.rpc.RestoreSnapshot(ctx, &searchpb.SnapshotRequest{Path: path})
+}
+
+// Indexer streams mutations to the server, which applies them in order.
+// Call Close to finish the stream and get the counts applied.
+//
+// An Indexer is not safe for concurrent use.
+type Indexer struct {
+	stream grpc.ClientStreamingClient[searchpb.Mutation, searchpb.IndexResponse]
+}
+
+// Index opens a mutation stream. Canceling ctx aborts it; mutations the
+// server received before stay applied.
+//
+// Example:
+//
+//	w, err := client.Index(ctx)
+//	w.Add(1, "hello world")
+//	w.Remove(7, 8)
+//	counts, err := w.Close()
+func (c *Client) Index(ctx context.Context) (*Indexer, error) {
+	stream, err := c.rpc.Index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{stream: stream}, nil
+}
+
+// Add indexes a new document.
+func (w *Indexer) Add(docID uint32, text string) error {
+	return w.send(&searchpb.Mutation{Op: &searchpb.Mutation_Add{Add: &searchpb.AddDoc{DocId: docID, Text: text}}})
+}
+
+// Update replaces the text of a document.
+func (w *Indexer) Update(docID uint32, text string) error {
+	return w.send(&searchpb.Mutation{Op: &searchpb.Mutation_Update{Update: &searchpb.AddDoc{DocId: docID, Text: text}}})
+}
+
+// Remove removes documents.
+func (w *Indexer) Remove(docIDs ...uint32) error {
+	return w.send(&searchpb.Mutation{Op: &searchpb.Mutation_Remove{Remove: &searchpb.RemoveDocs{DocIds: docIDs}}})
+}
+
+// send sends m. When the server has failed the stream, Send returns io.EOF
+// and the server's error is reported by CloseAndRecv.
+func (w *Indexer) send(m *searchpb.Mutation) error {
+	err := w.stream.Send(m)
+	if err == io.EOF {
+		_, err = w.stream.CloseAndRecv()
+	}
+	return err
+}
+
+// Close finishes the stream and returns how many documents the server
+// added, updated and removed.
+func (w *Indexer) Close() (*searchpb.IndexResponse, error) {
+	return w.stream.CloseAndRecv()
+}
+
+// recvEach calls fn with every message of a server stream until it ends.
+func recvEach[T any](stream grpc.ServerStreamingClient[T], fn func(*T) error) error {
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+}
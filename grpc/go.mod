@@ -0,0 +1,22 @@
+module github.com/freeeve/roaringsearch/grpc
+
+go 1.25.0
+
+require (
+	github.com/RoaringBitmap/roaring/v2 v2.14.4
+	github.com/freeeve/roaringsearch v0.0.0-20261016082531-3350f56ab0dd
+	google.golang.org/grpc v1.82.1
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/bits-and-blooms/bitset v1.24.2 // indirect
+	github.com/freeeve/msgpck v0.3.2 // indirect
+	github.com/mschoch/smat v0.2.0 // indirect
+	golang.org/x/net v0.53.0 // indirect
+	golang.org/x/sys v0.43.0 // indirect
+	golang.org/x/text v0.36.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260414002931-afd174a4e478 // indirect
+)
+
+replace github.com/freeeve/roaringsearch => ../
@@ -0,0 +1,1214 @@
+// Service definition for serving a roaringsearch index over gRPC.
+//
+// Each RPC maps onto an Index method. Result sets are streamed in chunks so
+// very large answers never have to fit in a single message.
+//
+// The Go stubs and a server wrapping an Index live in the separate
+// github.com/freeeve/roaringsearch/grpc module, so the core module depends
+// only on roaring and msgpck; regenerate them with go generate in grpc/.
+// Generate stubs for other languages with protoc, e.g.
+//
+//   protoc -I proto --python_out=. --grpc_python_out=. roaringsearch/v1/search.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: roaringsearch/v1/search.proto
+
+package searchpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Query string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// Maximum number of documents; 0 returns all matches.
+	Limit uint32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Preferred number of docIDs per streamed chunk; 0 lets the server choose.
+	ChunkSize     uint32 `protobuf:"varint,3,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetChunkSize() uint32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+// DocIDChunk is one piece of a streamed result, in ascending docID order.
+type DocIDChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocIds        []uint32               `protobuf:"varint,1,rep,packed,name=doc_ids,json=docIds,proto3" json:"doc_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DocIDChunk) Reset() {
+	*x = DocIDChunk{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocIDChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocIDChunk) ProtoMessage() {}
+
+func (x *DocIDChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocIDChunk.ProtoReflect.Descriptor instead.
+func (*DocIDChunk) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DocIDChunk) GetDocIds() []uint32 {
+	if x != nil {
+		return x.DocIds
+	}
+	return nil
+}
+
+type ThresholdRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Query      string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	MinMatches uint32                 `protobuf:"varint,2,opt,name=min_matches,json=minMatches,proto3" json:"min_matches,omitempty"`
+	// Maximum number of documents; 0 returns all matches.
+	Limit         uint32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	ChunkSize     uint32 `protobuf:"varint,4,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ThresholdRequest) Reset() {
+	*x = ThresholdRequest{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ThresholdRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThresholdRequest) ProtoMessage() {}
+
+func (x *ThresholdRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThresholdRequest.ProtoReflect.Descriptor instead.
+func (*ThresholdRequest) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ThresholdRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *ThresholdRequest) GetMinMatches() uint32 {
+	if x != nil {
+		return x.MinMatches
+	}
+	return 0
+}
+
+func (x *ThresholdRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ThresholdRequest) GetChunkSize() uint32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+type ScoredDoc struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	DocId uint32                 `protobuf:"varint,1,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	// Number of query n-grams the document contains.
+	Score         uint32 `protobuf:"varint,2,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoredDoc) Reset() {
+	*x = ScoredDoc{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScoredDoc) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScoredDoc) ProtoMessage() {}
+
+func (x *ScoredDoc) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScoredDoc.ProtoReflect.Descriptor instead.
+func (*ScoredDoc) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ScoredDoc) GetDocId() uint32 {
+	if x != nil {
+		return x.DocId
+	}
+	return 0
+}
+
+func (x *ScoredDoc) GetScore() uint32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type ScoredChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Docs          []*ScoredDoc           `protobuf:"bytes,1,rep,name=docs,proto3" json:"docs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoredChunk) Reset() {
+	*x = ScoredChunk{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScoredChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScoredChunk) ProtoMessage() {}
+
+func (x *ScoredChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScoredChunk.ProtoReflect.Descriptor instead.
+func (*ScoredChunk) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ScoredChunk) GetDocs() []*ScoredDoc {
+	if x != nil {
+		return x.Docs
+	}
+	return nil
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         uint64                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CountResponse) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type FacetsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Fields        []string               `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FacetsRequest) Reset() {
+	*x = FacetsRequest{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FacetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacetsRequest) ProtoMessage() {}
+
+func (x *FacetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacetsRequest.ProtoReflect.Descriptor instead.
+func (*FacetsRequest) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FacetsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *FacetsRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type CategoryCounts struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Counts        map[string]uint64      `protobuf:"bytes,1,rep,name=counts,proto3" json:"counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CategoryCounts) Reset() {
+	*x = CategoryCounts{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CategoryCounts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CategoryCounts) ProtoMessage() {}
+
+func (x *CategoryCounts) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CategoryCounts.ProtoReflect.Descriptor instead.
+func (*CategoryCounts) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CategoryCounts) GetCounts() map[string]uint64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+type FacetsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Field name to per-category counts; unknown fields are omitted.
+	Fields        map[string]*CategoryCounts `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FacetsResponse) Reset() {
+	*x = FacetsResponse{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FacetsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacetsResponse) ProtoMessage() {}
+
+func (x *FacetsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacetsResponse.ProtoReflect.Descriptor instead.
+func (*FacetsResponse) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FacetsResponse) GetFields() map[string]*CategoryCounts {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type Mutation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Op:
+	//
+	//	*Mutation_Add
+	//	*Mutation_Update
+	//	*Mutation_Remove
+	Op            isMutation_Op `protobuf_oneof:"op"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Mutation) Reset() {
+	*x = Mutation{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Mutation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Mutation) ProtoMessage() {}
+
+func (x *Mutation) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Mutation.ProtoReflect.Descriptor instead.
+func (*Mutation) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Mutation) GetOp() isMutation_Op {
+	if x != nil {
+		return x.Op
+	}
+	return nil
+}
+
+func (x *Mutation) GetAdd() *AddDoc {
+	if x != nil {
+		if x, ok := x.Op.(*Mutation_Add); ok {
+			return x.Add
+		}
+	}
+	return nil
+}
+
+func (x *Mutation) GetUpdate() *AddDoc {
+	if x != nil {
+		if x, ok := x.Op.(*Mutation_Update); ok {
+			return x.Update
+		}
+	}
+	return nil
+}
+
+func (x *Mutation) GetRemove() *RemoveDocs {
+	if x != nil {
+		if x, ok := x.Op.(*Mutation_Remove); ok {
+			return x.Remove
+		}
+	}
+	return nil
+}
+
+type isMutation_Op interface {
+	isMutation_Op()
+}
+
+type Mutation_Add struct {
+	Add *AddDoc `protobuf:"bytes,1,opt,name=add,proto3,oneof"`
+}
+
+type Mutation_Update struct {
+	Update *AddDoc `protobuf:"bytes,2,opt,name=update,proto3,oneof"`
+}
+
+type Mutation_Remove struct {
+	Remove *RemoveDocs `protobuf:"bytes,3,opt,name=remove,proto3,oneof"`
+}
+
+func (*Mutation_Add) isMutation_Op() {}
+
+func (*Mutation_Update) isMutation_Op() {}
+
+func (*Mutation_Remove) isMutation_Op() {}
+
+type AddDoc struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocId         uint32                 `protobuf:"varint,1,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddDoc) Reset() {
+	*x = AddDoc{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddDoc) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddDoc) ProtoMessage() {}
+
+func (x *AddDoc) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddDoc.ProtoReflect.Descriptor instead.
+func (*AddDoc) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AddDoc) GetDocId() uint32 {
+	if x != nil {
+		return x.DocId
+	}
+	return 0
+}
+
+func (x *AddDoc) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type RemoveDocs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocIds        []uint32               `protobuf:"varint,1,rep,packed,name=doc_ids,json=docIds,proto3" json:"doc_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveDocs) Reset() {
+	*x = RemoveDocs{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveDocs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveDocs) ProtoMessage() {}
+
+func (x *RemoveDocs) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveDocs.ProtoReflect.Descriptor instead.
+func (*RemoveDocs) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RemoveDocs) GetDocIds() []uint32 {
+	if x != nil {
+		return x.DocIds
+	}
+	return nil
+}
+
+type IndexResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Added         uint64                 `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	Updated       uint64                 `protobuf:"varint,2,opt,name=updated,proto3" json:"updated,omitempty"`
+	Removed       uint64                 `protobuf:"varint,3,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexResponse) Reset() {
+	*x = IndexResponse{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexResponse) ProtoMessage() {}
+
+func (x *IndexResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexResponse.ProtoReflect.Descriptor instead.
+func (*IndexResponse) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *IndexResponse) GetAdded() uint64 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *IndexResponse) GetUpdated() uint64 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+func (x *IndexResponse) GetRemoved() uint64 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+type StatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of heaviest n-grams to report.
+	Top           uint32 `protobuf:"varint,1,opt,name=top,proto3" json:"top,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StatsRequest) GetTop() uint32 {
+	if x != nil {
+		return x.Top
+	}
+	return 0
+}
+
+type NgramCardinality struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           uint64                 `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Cardinality   uint64                 `protobuf:"varint,2,opt,name=cardinality,proto3" json:"cardinality,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NgramCardinality) Reset() {
+	*x = NgramCardinality{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NgramCardinality) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NgramCardinality) ProtoMessage() {}
+
+func (x *NgramCardinality) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NgramCardinality.ProtoReflect.Descriptor instead.
+func (*NgramCardinality) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *NgramCardinality) GetKey() uint64 {
+	if x != nil {
+		return x.Key
+	}
+	return 0
+}
+
+func (x *NgramCardinality) GetCardinality() uint64 {
+	if x != nil {
+		return x.Cardinality
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	GramSize        uint32                 `protobuf:"varint,1,opt,name=gram_size,json=gramSize,proto3" json:"gram_size,omitempty"`
+	Docs            uint64                 `protobuf:"varint,2,opt,name=docs,proto3" json:"docs,omitempty"`
+	Ngrams          uint64                 `protobuf:"varint,3,opt,name=ngrams,proto3" json:"ngrams,omitempty"`
+	Postings        uint64                 `protobuf:"varint,4,opt,name=postings,proto3" json:"postings,omitempty"`
+	MemoryBytes     uint64                 `protobuf:"varint,5,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`
+	SerializedBytes uint64                 `protobuf:"varint,6,opt,name=serialized_bytes,json=serializedBytes,proto3" json:"serialized_bytes,omitempty"`
+	Heaviest        []*NgramCardinality    `protobuf:"bytes,7,rep,name=heaviest,proto3" json:"heaviest,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *StatsResponse) GetGramSize() uint32 {
+	if x != nil {
+		return x.GramSize
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetDocs() uint64 {
+	if x != nil {
+		return x.Docs
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetNgrams() uint64 {
+	if x != nil {
+		return x.Ngrams
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetPostings() uint64 {
+	if x != nil {
+		return x.Postings
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetMemoryBytes() uint64 {
+	if x != nil {
+		return x.MemoryBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetSerializedBytes() uint64 {
+	if x != nil {
+		return x.SerializedBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetHeaviest() []*NgramCardinality {
+	if x != nil {
+		return x.Heaviest
+	}
+	return nil
+}
+
+type SnapshotRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Server-side path of the index file.
+	Path          string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotRequest) Reset() {
+	*x = SnapshotRequest{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotRequest) ProtoMessage() {}
+
+func (x *SnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotRequest.ProtoReflect.Descriptor instead.
+func (*SnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SnapshotRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type SnapshotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Docs          uint64                 `protobuf:"varint,1,opt,name=docs,proto3" json:"docs,omitempty"`
+	Ngrams        uint64                 `protobuf:"varint,2,opt,name=ngrams,proto3" json:"ngrams,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotResponse) Reset() {
+	*x = SnapshotResponse{}
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotResponse) ProtoMessage() {}
+
+func (x *SnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_roaringsearch_v1_search_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotResponse.ProtoReflect.Descriptor instead.
+func (*SnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_roaringsearch_v1_search_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SnapshotResponse) GetDocs() uint64 {
+	if x != nil {
+		return x.Docs
+	}
+	return 0
+}
+
+func (x *SnapshotResponse) GetNgrams() uint64 {
+	if x != nil {
+		return x.Ngrams
+	}
+	return 0
+}
+
+var File_roaringsearch_v1_search_proto protoreflect.FileDescriptor
+
+const file_roaringsearch_v1_search_proto_rawDesc = "" +
+	"\n" +
+	"\x1droaringsearch/v1/search.proto\x12\x10roaringsearch.v1\"Z\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x03 \x01(\rR\tchunkSize\")\n" +
+	"\n" +
+	"DocIDChunk\x12\x1b\n" +
+	"\adoc_ids\x18\x01 \x03(\rB\x02\x10\x01R\x06docIds\"~\n" +
+	"\x10ThresholdRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x1f\n" +
+	"\vmin_matches\x18\x02 \x01(\rR\n" +
+	"minMatches\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\rR\x05limit\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x04 \x01(\rR\tchunkSize\"8\n" +
+	"\tScoredDoc\x12\x15\n" +
+	"\x06doc_id\x18\x01 \x01(\rR\x05docId\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\rR\x05score\">\n" +
+	"\vScoredChunk\x12/\n" +
+	"\x04docs\x18\x01 \x03(\v2\x1b.roaringsearch.v1.ScoredDocR\x04docs\"%\n" +
+	"\rCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x04R\x05count\"=\n" +
+	"\rFacetsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x16\n" +
+	"\x06fields\x18\x02 \x03(\tR\x06fields\"\x91\x01\n" +
+	"\x0eCategoryCounts\x12D\n" +
+	"\x06counts\x18\x01 \x03(\v2,.roaringsearch.v1.CategoryCounts.CountsEntryR\x06counts\x1a9\n" +
+	"\vCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\xb3\x01\n" +
+	"\x0eFacetsResponse\x12D\n" +
+	"\x06fields\x18\x01 \x03(\v2,.roaringsearch.v1.FacetsResponse.FieldsEntryR\x06fields\x1a[\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x126\n" +
+	"\x05value\x18\x02 \x01(\v2 .roaringsearch.v1.CategoryCountsR\x05value:\x028\x01\"\xaa\x01\n" +
+	"\bMutation\x12,\n" +
+	"\x03add\x18\x01 \x01(\v2\x18.roaringsearch.v1.AddDocH\x00R\x03add\x122\n" +
+	"\x06update\x18\x02 \x01(\v2\x18.roaringsearch.v1.AddDocH\x00R\x06update\x126\n" +
+	"\x06remove\x18\x03 \x01(\v2\x1c.roaringsearch.v1.RemoveDocsH\x00R\x06removeB\x04\n" +
+	"\x02op\"3\n" +
+	"\x06AddDoc\x12\x15\n" +
+	"\x06doc_id\x18\x01 \x01(\rR\x05docId\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\")\n" +
+	"\n" +
+	"RemoveDocs\x12\x1b\n" +
+	"\adoc_ids\x18\x01 \x03(\rB\x02\x10\x01R\x06docIds\"Y\n" +
+	"\rIndexResponse\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\x04R\x05added\x12\x18\n" +
+	"\aupdated\x18\x02 \x01(\x04R\aupdated\x12\x18\n" +
+	"\aremoved\x18\x03 \x01(\x04R\aremoved\" \n" +
+	"\fStatsRequest\x12\x10\n" +
+	"\x03top\x18\x01 \x01(\rR\x03top\"F\n" +
+	"\x10NgramCardinality\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x04R\x03key\x12 \n" +
+	"\vcardinality\x18\x02 \x01(\x04R\vcardinality\"\x82\x02\n" +
+	"\rStatsResponse\x12\x1b\n" +
+	"\tgram_size\x18\x01 \x01(\rR\bgramSize\x12\x12\n" +
+	"\x04docs\x18\x02 \x01(\x04R\x04docs\x12\x16\n" +
+	"\x06ngrams\x18\x03 \x01(\x04R\x06ngrams\x12\x1a\n" +
+	"\bpostings\x18\x04 \x01(\x04R\bpostings\x12!\n" +
+	"\fmemory_bytes\x18\x05 \x01(\x04R\vmemoryBytes\x12)\n" +
+	"\x10serialized_bytes\x18\x06 \x01(\x04R\x0fserializedBytes\x12>\n" +
+	"\bheaviest\x18\a \x03(\v2\".roaringsearch.v1.NgramCardinalityR\bheaviest\"%\n" +
+	"\x0fSnapshotRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\">\n" +
+	"\x10SnapshotResponse\x12\x12\n" +
+	"\x04docs\x18\x01 \x01(\x04R\x04docs\x12\x16\n" +
+	"\x06ngrams\x18\x02 \x01(\x04R\x06ngrams2\xe3\x05\n" +
+	"\rSearchService\x12I\n" +
+	"\x06Search\x12\x1f.roaringsearch.v1.SearchRequest\x1a\x1c.roaringsearch.v1.DocIDChunk0\x01\x12L\n" +
+	"\tSearchAny\x12\x1f.roaringsearch.v1.SearchRequest\x1a\x1c.roaringsearch.v1.DocIDChunk0\x01\x12V\n" +
+	"\x0fSearchThreshold\x12\".roaringsearch.v1.ThresholdRequest\x1a\x1d.roaringsearch.v1.ScoredChunk0\x01\x12O\n" +
+	"\vSearchCount\x12\x1f.roaringsearch.v1.SearchRequest\x1a\x1f.roaringsearch.v1.CountResponse\x12K\n" +
+	"\x06Facets\x12\x1f.roaringsearch.v1.FacetsRequest\x1a .roaringsearch.v1.FacetsResponse\x12F\n" +
+	"\x05Index\x12\x1a.roaringsearch.v1.Mutation\x1a\x1f.roaringsearch.v1.IndexResponse(\x01\x12H\n" +
+	"\x05Stats\x12\x1e.roaringsearch.v1.StatsRequest\x1a\x1f.roaringsearch.v1.StatsResponse\x12W\n" +
+	"\x0eCreateSnapshot\x12!.roaringsearch.v1.SnapshotRequest\x1a\".roaringsearch.v1.SnapshotResponse\x12X\n" +
+	"\x0fRestoreSnapshot\x12!.roaringsearch.v1.SnapshotRequest\x1a\".roaringsearch.v1.SnapshotResponseB9Z7github.com/freeeve/roaringsearch/grpc/searchpb;searchpbb\x06proto3"
+
+var (
+	file_roaringsearch_v1_search_proto_rawDescOnce sync.Once
+	file_roaringsearch_v1_search_proto_rawDescData []byte
+)
+
+func file_roaringsearch_v1_search_proto_rawDescGZIP() []byte {
+	file_roaringsearch_v1_search_proto_rawDescOnce.Do(func() {
+		file_roaringsearch_v1_search_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_roaringsearch_v1_search_proto_rawDesc), len(file_roaringsearch_v1_search_proto_rawDesc)))
+	})
+	return file_roaringsearch_v1_search_proto_rawDescData
+}
+
+var file_roaringsearch_v1_search_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_roaringsearch_v1_search_proto_goTypes = []any{
+	(*SearchRequest)(nil),    // 0: roaringsearch.v1.SearchRequest
+	(*DocIDChunk)(nil),       // 1: roaringsearch.v1.DocIDChunk
+	(*ThresholdRequest)(nil), // 2: roaringsearch.v1.ThresholdRequest
+	(*ScoredDoc)(nil),        // 3: roaringsearch.v1.ScoredDoc
+	(*ScoredChunk)(nil),      // 4: roaringsearch.v1.ScoredChunk
+	(*CountResponse)(nil),    // 5: roaringsearch.v1.CountResponse
+	(*FacetsRequest)(nil),    // 6: roaringsearch.v1.FacetsRequest
+	(*CategoryCounts)(nil),   // 7: roaringsearch.v1.CategoryCounts
+	(*FacetsResponse)(nil),   // 8: roaringsearch.v1.FacetsResponse
+	(*Mutation)(nil),         // 9: roaringsearch.v1.Mutation
+	(*AddDoc)(nil),           // 10: roaringsearch.v1.AddDoc
+	(*RemoveDocs)(nil),       // 11: roaringsearch.v1.RemoveDocs
+	(*IndexResponse)(nil),    // 12: roaringsearch.v1.IndexResponse
+	(*StatsRequest)(nil),     // 13: roaringsearch.v1.StatsRequest
+	(*NgramCardinality)(nil), // 14: roaringsearch.v1.NgramCardinality
+	(*StatsResponse)(nil),    // 15: roaringsearch.v1.StatsResponse
+	(*SnapshotRequest)(nil),  // 16: roaringsearch.v1.SnapshotRequest
+	(*SnapshotResponse)(nil), // 17: roaringsearch.v1.SnapshotResponse
+	nil,                      // 18: roaringsearch.v1.CategoryCounts.CountsEntry
+	nil,                      // 19: roaringsearch.v1.FacetsResponse.FieldsEntry
+}
+var file_roaringsearch_v1_search_proto_depIdxs = []int32{
+	3,  // 0: roaringsearch.v1.ScoredChunk.docs:type_name -> roaringsearch.v1.ScoredDoc
+	18, // 1: roaringsearch.v1.CategoryCounts.counts:type_name -> roaringsearch.v1.CategoryCounts.CountsEntry
+	19, // 2: roaringsearch.v1.FacetsResponse.fields:type_name -> roaringsearch.v1.FacetsResponse.FieldsEntry
+	10, // 3: roaringsearch.v1.Mutation.add:type_name -> roaringsearch.v1.AddDoc
+	10, // 4: roaringsearch.v1.Mutation.update:type_name -> roaringsearch.v1.AddDoc
+	11, // 5: roaringsearch.v1.Mutation.remove:type_name -> roaringsearch.v1.RemoveDocs
+	14, // 6: roaringsearch.v1.StatsResponse.heaviest:type_name -> roaringsearch.v1.NgramCardinality
+	7,  // 7: roaringsearch.v1.FacetsResponse.FieldsEntry.value:type_name -> roaringsearch.v1.CategoryCounts
+	0,  // 8: roaringsearch.v1.SearchService.Search:input_type -> roaringsearch.v1.SearchRequest
+	0,  // 9: roaringsearch.v1.SearchService.SearchAny:input_type -> roaringsearch.v1.SearchRequest
+	2,  // 10: roaringsearch.v1.SearchService.SearchThreshold:input_type -> roaringsearch.v1.ThresholdRequest
+	0,  // 11: roaringsearch.v1.SearchService.SearchCount:input_type -> roaringsearch.v1.SearchRequest
+	6,  // 12: roaringsearch.v1.SearchService.Facets:input_type -> roaringsearch.v1.FacetsRequest
+	9,  // 13: roaringsearch.v1.SearchService.Index:input_type -> roaringsearch.v1.Mutation
+	13, // 14: roaringsearch.v1.SearchService.Stats:input_type -> roaringsearch.v1.StatsRequest
+	16, // 15: roaringsearch.v1.SearchService.CreateSnapshot:input_type -> roaringsearch.v1.SnapshotRequest
+	16, // 16: roaringsearch.v1.SearchService.RestoreSnapshot:input_type -> roaringsearch.v1.SnapshotRequest
+	1,  // 17: roaringsearch.v1.SearchService.Search:output_type -> roaringsearch.v1.DocIDChunk
+	1,  // 18: roaringsearch.v1.SearchService.SearchAny:output_type -> roaringsearch.v1.DocIDChunk
+	4,  // 19: roaringsearch.v1.SearchService.SearchThreshold:output_type -> roaringsearch.v1.ScoredChunk
+	5,  // 20: roaringsearch.v1.SearchService.SearchCount:output_type -> roaringsearch.v1.CountResponse
+	8,  // 21: roaringsearch.v1.SearchService.Facets:output_type -> roaringsearch.v1.FacetsResponse
+	12, // 22: roaringsearch.v1.SearchService.Index:output_type -> roaringsearch.v1.IndexResponse
+	15, // 23: roaringsearch.v1.SearchService.Stats:output_type -> roaringsearch.v1.StatsResponse
+	17, // 24: roaringsearch.v1.SearchService.CreateSnapshot:output_type -> roaringsearch.v1.SnapshotResponse
+	17, // 25: roaringsearch.v1.SearchService.RestoreSnapshot:output_type -> roaringsearch.v1.SnapshotResponse
+	17, // [17:26] is the sub-list for method output_type
+	8,  // [8:17] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_roaringsearch_v1_search_proto_init() }
+func file_roaringsearch_v1_search_proto_init() {
+	if File_roaringsearch_v1_search_proto != nil {
+		return
+	}
+	file_roaringsearch_v1_search_proto_msgTypes[9].OneofWrappers = []any{
+		(*Mutation_Add)(nil),
+		(*Mutation_Update)(nil),
+		(*Mutation_Remove)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_roaringsearch_v1_search_proto_rawDesc), len(file_roaringsearch_v1_search_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_roaringsearch_v1_search_proto_goTypes,
+		DependencyIndexes: file_roaringsearch_v1_search_proto_depIdxs,
+		MessageInfos:      file_roaringsearch_v1_search_proto_msgTypes,
+	}.Build()
+	File_roaringsearch_v1_search_proto = out.File
+	file_roaringsearch_v1_search_proto_goTypes = nil
+	file_roaringsearch_v1_search_proto_depIdxs = nil
+}
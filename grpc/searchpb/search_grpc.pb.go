@@ -0,0 +1,470 @@
+// Service definition for serving a roaringsearch index over gRPC.
+//
+// Each RPC maps onto an Index method. Result sets are streamed in chunks so
+// very large answers never have to fit in a single message.
+//
+// The Go stubs and a server wrapping an Index live in the separate
+// github.com/freeeve/roaringsearch/grpc module, so the core module depends
+// only on roaring and msgpck; regenerate them with go generate in grpc/.
+// Generate stubs for other languages with protoc, e.g.
+//
+//   protoc -I proto --python_out=. --grpc_python_out=. roaringsearch/v1/search.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: roaringsearch/v1/search.proto
+
+package searchpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SearchService_Search_FullMethodName          = "/roaringsearch.v1.SearchService/Search"
+	SearchService_SearchAny_FullMethodName       = "/roaringsearch.v1.SearchService/SearchAny"
+	SearchService_SearchThreshold_FullMethodName = "/roaringsearch.v1.SearchService/SearchThreshold"
+	SearchService_SearchCount_FullMethodName     = "/roaringsearch.v1.SearchService/SearchCount"
+	SearchService_Facets_FullMethodName          = "/roaringsearch.v1.SearchService/Facets"
+	SearchService_Index_FullMethodName           = "/roaringsearch.v1.SearchService/Index"
+	SearchService_Stats_FullMethodName           = "/roaringsearch.v1.SearchService/Stats"
+	SearchService_CreateSnapshot_FullMethodName  = "/roaringsearch.v1.SearchService/CreateSnapshot"
+	SearchService_RestoreSnapshot_FullMethodName = "/roaringsearch.v1.SearchService/RestoreSnapshot"
+)
+
+// SearchServiceClient is the client API for SearchService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SearchServiceClient interface {
+	// Search streams the documents containing all n-grams of the query
+	// (Index.Search / Index.SearchWithLimit).
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DocIDChunk], error)
+	// SearchAny streams the documents containing any n-gram of the query
+	// (Index.SearchAny).
+	SearchAny(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DocIDChunk], error)
+	// SearchThreshold streams documents with at least min_matches n-grams,
+	// best scores first (Index.SearchThresholdTopK).
+	SearchThreshold(ctx context.Context, in *ThresholdRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScoredChunk], error)
+	// SearchCount counts the documents Search would return (Index.SearchCount).
+	SearchCount(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	// Facets counts categories of filter fields over the query's matches
+	// (BitmapFilter.Facets); an empty query counts every document.
+	Facets(ctx context.Context, in *FacetsRequest, opts ...grpc.CallOption) (*FacetsResponse, error)
+	// Index applies a stream of mutations in batches (IndexBatch, Index.Update,
+	// Index.RemoveMany) and reports how many were applied.
+	Index(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Mutation, IndexResponse], error)
+	// Stats reports index statistics (Index.Stats).
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// CreateSnapshot writes the index to a server-side file (Index.SaveToFile).
+	CreateSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error)
+	// RestoreSnapshot replaces the served index with one loaded from a
+	// server-side file (LoadFromFile).
+	RestoreSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error)
+}
+
+type searchServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSearchServiceClient(cc grpc.ClientConnInterface) SearchServiceClient {
+	return &searchServiceClient{cc}
+}
+
+func (c *searchServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DocIDChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SearchService_ServiceDesc.Streams[0], SearchService_Search_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchRequest, DocIDChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_SearchClient = grpc.ServerStreamingClient[DocIDChunk]
+
+func (c *searchServiceClient) SearchAny(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DocIDChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SearchService_ServiceDesc.Streams[1], SearchService_SearchAny_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchRequest, DocIDChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_SearchAnyClient = grpc.ServerStreamingClient[DocIDChunk]
+
+func (c *searchServiceClient) SearchThreshold(ctx context.Context, in *ThresholdRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScoredChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SearchService_ServiceDesc.Streams[2], SearchService_SearchThreshold_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ThresholdRequest, ScoredChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_SearchThresholdClient = grpc.ServerStreamingClient[ScoredChunk]
+
+func (c *searchServiceClient) SearchCount(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, SearchService_SearchCount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) Facets(ctx context.Context, in *FacetsRequest, opts ...grpc.CallOption) (*FacetsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FacetsResponse)
+	err := c.cc.Invoke(ctx, SearchService_Facets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) Index(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Mutation, IndexResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SearchService_ServiceDesc.Streams[3], SearchService_Index_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Mutation, IndexResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_IndexClient = grpc.ClientStreamingClient[Mutation, IndexResponse]
+
+func (c *searchServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, SearchService_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) CreateSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SnapshotResponse)
+	err := c.cc.Invoke(ctx, SearchService_CreateSnapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchServiceClient) RestoreSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SnapshotResponse)
+	err := c.cc.Invoke(ctx, SearchService_RestoreSnapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SearchServiceServer is the server API for SearchService service.
+// All implementations must embed UnimplementedSearchServiceServer
+// for forward compatibility.
+type SearchServiceServer interface {
+	// Search streams the documents containing all n-grams of the query
+	// (Index.Search / Index.SearchWithLimit).
+	Search(*SearchRequest, grpc.ServerStreamingServer[DocIDChunk]) error
+	// SearchAny streams the documents containing any n-gram of the query
+	// (Index.SearchAny).
+	SearchAny(*SearchRequest, grpc.ServerStreamingServer[DocIDChunk]) error
+	// SearchThreshold streams documents with at least min_matches n-grams,
+	// best scores first (Index.SearchThresholdTopK).
+	SearchThreshold(*ThresholdRequest, grpc.ServerStreamingServer[ScoredChunk]) error
+	// SearchCount counts the documents Search would return (Index.SearchCount).
+	SearchCount(context.Context, *SearchRequest) (*CountResponse, error)
+	// Facets counts categories of filter fields over the query's matches
+	// (BitmapFilter.Facets); an empty query counts every document.
+	Facets(context.Context, *FacetsRequest) (*FacetsResponse, error)
+	// Index applies a stream of mutations in batches (IndexBatch, Index.Update,
+	// Index.RemoveMany) and reports how many were applied.
+	Index(grpc.ClientStreamingServer[Mutation, IndexResponse]) error
+	// Stats reports index statistics (Index.Stats).
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// CreateSnapshot writes the index to a server-side file (Index.SaveToFile).
+	CreateSnapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error)
+	// RestoreSnapshot replaces the served index with one loaded from a
+	// server-side file (LoadFromFile).
+	RestoreSnapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error)
+	mustEmbedUnimplementedSearchServiceServer()
+}
+
+// UnimplementedSearchServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSearchServiceServer struct{}
+
+func (UnimplementedSearchServiceServer) Search(*SearchRequest, grpc.ServerStreamingServer[DocIDChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedSearchServiceServer) SearchAny(*SearchRequest, grpc.ServerStreamingServer[DocIDChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method SearchAny not implemented")
+}
+func (UnimplementedSearchServiceServer) SearchThreshold(*ThresholdRequest, grpc.ServerStreamingServer[ScoredChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method SearchThreshold not implemented")
+}
+func (UnimplementedSearchServiceServer) SearchCount(context.Context, *SearchRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchCount not implemented")
+}
+func (UnimplementedSearchServiceServer) Facets(context.Context, *FacetsRequest) (*FacetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Facets not implemented")
+}
+func (UnimplementedSearchServiceServer) Index(grpc.ClientStreamingServer[Mutation, IndexResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Index not implemented")
+}
+func (UnimplementedSearchServiceServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedSearchServiceServer) CreateSnapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSnapshot not implemented")
+}
+func (UnimplementedSearchServiceServer) RestoreSnapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreSnapshot not implemented")
+}
+func (UnimplementedSearchServiceServer) mustEmbedUnimplementedSearchServiceServer() {}
+func (UnimplementedSearchServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeSearchServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SearchServiceServer will
+// result in compilation errors.
+type UnsafeSearchServiceServer interface {
+	mustEmbedUnimplementedSearchServiceServer()
+}
+
+func RegisterSearchServiceServer(s grpc.ServiceRegistrar, srv SearchServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSearchServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SearchService_ServiceDesc, srv)
+}
+
+func _SearchService_Search_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SearchServiceServer).Search(m, &grpc.GenericServerStream[SearchRequest, DocIDChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_SearchServer = grpc.ServerStreamingServer[DocIDChunk]
+
+func _SearchService_SearchAny_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SearchServiceServer).SearchAny(m, &grpc.GenericServerStream[SearchRequest, DocIDChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_SearchAnyServer = grpc.ServerStreamingServer[DocIDChunk]
+
+func _SearchService_SearchThreshold_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ThresholdRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SearchServiceServer).SearchThreshold(m, &grpc.GenericServerStream[ThresholdRequest, ScoredChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_SearchThresholdServer = grpc.ServerStreamingServer[ScoredChunk]
+
+func _SearchService_SearchCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).SearchCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SearchService_SearchCount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).SearchCount(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_Facets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FacetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).Facets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SearchService_Facets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).Facets(ctx, req.(*FacetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_Index_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SearchServiceServer).Index(&grpc.GenericServerStream[Mutation, IndexResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SearchService_IndexServer = grpc.ClientStreamingServer[Mutation, IndexResponse]
+
+func _SearchService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SearchService_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_CreateSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).CreateSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SearchService_CreateSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).CreateSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SearchService_RestoreSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServiceServer).RestoreSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SearchService_RestoreSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServiceServer).RestoreSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SearchService_ServiceDesc is the grpc.ServiceDesc for SearchService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SearchService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "roaringsearch.v1.SearchService",
+	HandlerType: (*SearchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchCount",
+			Handler:    _SearchService_SearchCount_Handler,
+		},
+		{
+			MethodName: "Facets",
+			Handler:    _SearchService_Facets_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _SearchService_Stats_Handler,
+		},
+		{
+			MethodName: "CreateSnapshot",
+			Handler:    _SearchService_CreateSnapshot_Handler,
+		},
+		{
+			MethodName: "RestoreSnapshot",
+			Handler:    _SearchService_RestoreSnapshot_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Search",
+			Handler:       _SearchService_Search_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchAny",
+			Handler:       _SearchService_SearchAny_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchThreshold",
+			Handler:       _SearchService_SearchThreshold_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Index",
+			Handler:       _SearchService_Index_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "roaringsearch/v1/search.proto",
+}
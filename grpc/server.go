@@ -0,0 +1,366 @@
+// Package rsgrpc serves a roaringsearch index over gRPC, implementing the
+// SearchService of proto/roaringsearch/v1/search.proto, and provides a
+// client for it. It is a separate module so the core package stays free of
+// gRPC and protobuf dependencies.
+//
+// Result sets are streamed in chunks of docIDs, so answers of any size are
+// delivered without exceeding gRPC's message size limit.
+//
+// Example:
+//
+//	srv := rsgrpc.NewServer(idx, rsgrpc.WithFilter(filter), rsgrpc.WithSnapshotDir("/var/lib/search"))
+//	err := srv.ListenAndServe(ctx, ":9090") // returns after ctx is done and RPCs drain
+//
+//	conn, _ := grpc.NewClient("search:9090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	client := rsgrpc.NewClient(conn)
+//	ids, err := client.Search(ctx, "hello", 0)
+package rsgrpc
+
+//go:generate protoc -I ../proto --go_out=. --go_opt=module=github.com/freeeve/roaringsearch/grpc --go-grpc_out=. --go-grpc_opt=module=github.com/freeeve/roaringsearch/grpc roaringsearch/v1/search.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	rs "github.com/freeeve/roaringsearch"
+	"github.com/freeeve/roaringsearch/grpc/searchpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultChunkSize is the number of docIDs per streamed message when the
+// request does not ask for a chunk size.
+const DefaultChunkSize = 8192
+
+// MaxChunkSize caps the chunk size a request may ask for, keeping every
+// message well under gRPC's default 4MB limit.
+const MaxChunkSize = 1 << 18
+
+// DefaultShutdownTimeout bounds how long ListenAndServe waits for in-flight
+// RPCs after its context is done.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Server implements searchpb.SearchServiceServer over an Index.
+type Server struct {
+	searchpb.UnimplementedSearchServiceServer
+
+	idx             atomic.Pointer[rs.Index]
+	filter          *rs.BitmapFilter
+	maxResults      int
+	snapshotDir     string
+	loadOpts        []rs.Option
+	shutdownTimeout time.Duration
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithFilter enables the Facets RPC.
+func WithFilter(filter *rs.BitmapFilter) Option {
+	return func(s *Server) {
+		s.filter = filter
+	}
+}
+
+// WithMaxResults caps the number of docIDs any search streams, whatever
+// limit the client asks for. n <= 0 means no cap.
+func WithMaxResults(n int) Option {
+	return func(s *Server) {
+		s.maxResults = max(n, 0)
+	}
+}
+
+// WithSnapshotDir enables CreateSnapshot and RestoreSnapshot for files in
+// dir. Snapshot paths are relative to dir and may not leave it, so clients
+// cannot read or write other files of the server.
+func WithSnapshotDir(dir string) Option {
+	return func(s *Server) {
+		s.snapshotDir = dir
+	}
+}
+
+// WithLoadOptions sets the options RestoreSnapshot loads an index with, as
+// for LoadFromFileWithOptions.
+func WithLoadOptions(opts ...rs.Option) Option {
+	return func(s *Server) {
+		s.loadOpts = opts
+	}
+}
+
+// WithShutdownTimeout sets how long ListenAndServe waits for in-flight RPCs
+// to finish after its context is done. d <= 0 keeps the default.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d > 0 {
+			s.shutdownTimeout = d
+		}
+	}
+}
+
+// NewServer returns a Server for the index.
+func NewServer(idx *rs.Index, opts ...Option) *Server {
+	s := &Server{shutdownTimeout: DefaultShutdownTimeout}
+	s.idx.Store(idx)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Served returns the index being served, which RestoreSnapshot may have
+// replaced.
+func (s *Server) Served() *rs.Index {
+	return s.idx.Load()
+}
+
+// Register registers the SearchService on reg, e.g. an existing grpc.Server.
+func (s *Server) Register(reg grpc.ServiceRegistrar) {
+	searchpb.RegisterSearchServiceServer(reg, s)
+}
+
+// ListenAndServe serves on addr until ctx is done, then stops gracefully:
+// it stops accepting connections and waits up to the shutdown timeout for
+// in-flight RPCs. It returns nil after a graceful stop.
+func (s *Server) ListenAndServe(ctx context.Context, addr string, opts ...grpc.ServerOption) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln, opts...)
+}
+
+// Serve is like ListenAndServe but accepts connections on ln.
+func (s *Server) Serve(ctx context.Context, ln net.Listener, opts ...grpc.ServerOption) error {
+	srv := grpc.NewServer(opts...)
+	s.Register(srv)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(s.shutdownTimeout):
+		srv.Stop()
+	}
+	return <-errc
+}
+
+// Search streams the documents containing all n-grams of the query.
+func (s *Server) Search(req *searchpb.SearchRequest, stream grpc.ServerStreamingServer[searchpb.DocIDChunk]) error {
+	idx := s.idx.Load()
+	var ids []uint32
+	if limit := s.limit(req.Limit); limit > 0 {
+		ids = idx.SearchWithLimit(req.Query, limit)
+	} else {
+		ids = idx.Search(req.Query)
+	}
+	return sendIDs(stream, ids, req.ChunkSize)
+}
+
+// SearchAny streams the documents containing any n-gram of the query.
+func (s *Server) SearchAny(req *searchpb.SearchRequest, stream grpc.ServerStreamingServer[searchpb.DocIDChunk]) error {
+	ids := s.idx.Load().SearchAny(req.Query)
+	if limit := s.limit(req.Limit); limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return sendIDs(stream, ids, req.ChunkSize)
+}
+
+// SearchThreshold streams the documents with at least min_matches n-grams
+// of the query, best scores first. min_matches 0 is taken as 1.
+func (s *Server) SearchThreshold(req *searchpb.ThresholdRequest, stream grpc.ServerStreamingServer[searchpb.ScoredChunk]) error {
+	result := s.idx.Load().SearchThresholdTopK(req.Query, int(max(req.MinMatches, 1)), s.limit(req.Limit))
+	ids, size := result.DocIDs, chunkSize(req.ChunkSize)
+	for len(ids) > 0 {
+		n := min(len(ids), size)
+		chunk := &searchpb.ScoredChunk{Docs: make([]*searchpb.ScoredDoc, n)}
+		for i, id := range ids[:n] {
+			chunk.Docs[i] = &searchpb.ScoredDoc{DocId: id, Score: uint32(result.Scores[id])}
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// SearchCount counts the documents Search would return.
+func (s *Server) SearchCount(_ context.Context, req *searchpb.SearchRequest) (*searchpb.CountResponse, error) {
+	return &searchpb.CountResponse{Count: s.idx.Load().SearchCount(req.Query)}, nil
+}
+
+// Facets counts the categories of the requested filter fields over the
+// query's matches, or over every document for an empty query. It fails
+// with FailedPrecondition unless the server has a filter.
+func (s *Server) Facets(_ context.Context, req *searchpb.FacetsRequest) (*searchpb.FacetsResponse, error) {
+	if s.filter == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no filter configured")
+	}
+	if len(req.Fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing fields")
+	}
+
+	var result *roaring.Bitmap
+	if req.Query != "" {
+		result = s.idx.Load().SearchBitmap(req.Query)
+	}
+	resp := &searchpb.FacetsResponse{Fields: make(map[string]*searchpb.CategoryCounts)}
+	for field, counts := range s.filter.Facets(result, req.Fields...) {
+		resp.Fields[field] = &searchpb.CategoryCounts{Counts: counts}
+	}
+	return resp, nil
+}
+
+// Index applies a stream of mutations in order. Consecutive adds are
+// indexed as one batch; an update or removal first flushes the adds before
+// it. Mutations received before a stream error stay applied.
+func (s *Server) Index(stream grpc.ClientStreamingServer[searchpb.Mutation, searchpb.IndexResponse]) error {
+	idx := s.idx.Load()
+	batch := idx.Batch()
+	var resp searchpb.IndexResponse
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			batch.Flush()
+			return err
+		}
+
+		switch op := m.Op.(type) {
+		case *searchpb.Mutation_Add:
+			batch.Add(op.Add.DocId, op.Add.Text)
+			resp.Added++
+		case *searchpb.Mutation_Update:
+			batch.Flush()
+			idx.Update(op.Update.DocId, op.Update.Text)
+			resp.Updated++
+		case *searchpb.Mutation_Remove:
+			batch.Flush()
+			idx.RemoveMany(op.Remove.DocIds)
+			resp.Removed += uint64(len(op.Remove.DocIds))
+		default:
+			batch.Flush()
+			return status.Error(codes.InvalidArgument, "mutation without an operation")
+		}
+	}
+	batch.Flush()
+	return stream.SendAndClose(&resp)
+}
+
+// Stats reports statistics of the index, with the top heaviest n-grams.
+func (s *Server) Stats(_ context.Context, req *searchpb.StatsRequest) (*searchpb.StatsResponse, error) {
+	stats := s.idx.Load().Stats(int(req.Top))
+	resp := &searchpb.StatsResponse{
+		GramSize:        uint32(stats.GramSize),
+		Docs:            stats.Docs,
+		Ngrams:          uint64(stats.Ngrams),
+		Postings:        stats.Postings,
+		MemoryBytes:     stats.MemoryBytes,
+		SerializedBytes: stats.SerializedBytes,
+		Heaviest:        make([]*searchpb.NgramCardinality, len(stats.Heaviest)),
+	}
+	for i, h := range stats.Heaviest {
+		resp.Heaviest[i] = &searchpb.NgramCardinality{Key: h.Key, Cardinality: h.Cardinality}
+	}
+	return resp, nil
+}
+
+// CreateSnapshot saves the index to a file of the snapshot directory.
+func (s *Server) CreateSnapshot(_ context.Context, req *searchpb.SnapshotRequest) (*searchpb.SnapshotResponse, error) {
+	path, err := s.snapshotPath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	idx := s.idx.Load()
+	if err := idx.SaveToFile(path); err != nil {
+		return nil, status.Errorf(codes.Internal, "save snapshot: %v", err)
+	}
+	return snapshotResponse(idx), nil
+}
+
+// RestoreSnapshot loads a file of the snapshot directory and serves it in
+// place of the current index. RPCs already running finish on the old index.
+func (s *Server) RestoreSnapshot(_ context.Context, req *searchpb.SnapshotRequest) (*searchpb.SnapshotResponse, error) {
+	path, err := s.snapshotPath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := rs.LoadFromFileWithOptions(path, s.loadOpts...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "restore snapshot: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "restore snapshot: %v", err)
+	}
+	s.idx.Store(idx)
+	return snapshotResponse(idx), nil
+}
+
+// snapshotPath resolves a client's snapshot path inside the snapshot
+// directory.
+func (s *Server) snapshotPath(name string) (string, error) {
+	if s.snapshotDir == "" {
+		return "", status.Error(codes.FailedPrecondition, "snapshots are not enabled")
+	}
+	if !filepath.IsLocal(name) {
+		return "", status.Errorf(codes.InvalidArgument, "snapshot path %q must be relative to the snapshot directory", name)
+	}
+	return filepath.Join(s.snapshotDir, name), nil
+}
+
+func snapshotResponse(idx *rs.Index) *searchpb.SnapshotResponse {
+	return &searchpb.SnapshotResponse{Docs: idx.DocCount(), Ngrams: uint64(idx.NgramCount())}
+}
+
+// limit returns the number of results to stream for a requested limit,
+// applying the server's cap. 0 means no limit.
+func (s *Server) limit(requested uint32) int {
+	limit := int(requested)
+	if s.maxResults > 0 && (limit == 0 || limit > s.maxResults) {
+		limit = s.maxResults
+	}
+	return limit
+}
+
+// chunkSize returns the number of results per message for a requested size.
+func chunkSize(requested uint32) int {
+	if requested == 0 {
+		return DefaultChunkSize
+	}
+	return int(min(requested, MaxChunkSize))
+}
+
+// sendIDs streams ids in chunks of the requested size.
+func sendIDs(stream grpc.ServerStreamingServer[searchpb.DocIDChunk], ids []uint32, requested uint32) error {
+	size := chunkSize(requested)
+	for len(ids) > 0 {
+		n := min(len(ids), size)
+		if err := stream.Send(&searchpb.DocIDChunk{DocIds: ids[:n]}); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package rsgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	rs "github.com/freeeve/roaringsearch"
+	"github.com/freeeve/roaringsearch/grpc/searchpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves idx over an in-memory connection and returns a
+// client for it.
+func newTestClient(t *testing.T, idx *rs.Index, opts ...Option) (*Client, *Server) {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	srv := NewServer(idx, opts...)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("Serve = %v", err)
+		}
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn), srv
+}
+
+func newTestIndex() *rs.Index {
+	idx := rs.NewIndex(3)
+	idx.Add(1, "the quick brown fox")
+	idx.Add(2, "the lazy brown dog")
+	idx.Add(3, "hello world")
+	return idx
+}
+
+func TestSearchRoundTrip(t *testing.T) {
+	filter := rs.NewBitmapFilter()
+	filter.Set(1, "kind", "animal")
+	filter.Set(2, "kind", "animal")
+	filter.Set(3, "kind", "greeting")
+	client, _ := newTestClient(t, newTestIndex(), WithFilter(filter))
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		limit int
+		want  []uint32
+	}{
+		{"brown", 0, []uint32{1, 2}},
+		{"brown", 1, []uint32{1}},
+		{"zebra", 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := client.Search(ctx, tt.query, tt.limit)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q, %d) = %v, %v, want %v", tt.query, tt.limit, got, err, tt.want)
+		}
+	}
+	if got, err := client.SearchAny(ctx, "fox world", 0); err != nil || !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("SearchAny = %v, %v, want [1 3]", got, err)
+	}
+	if n, err := client.SearchCount(ctx, "brown"); err != nil || n != 2 {
+		t.Errorf("SearchCount = %d, %v, want 2", n, err)
+	}
+
+	result, err := client.SearchThreshold(ctx, "brown fox", 3, 1)
+	if err != nil || !reflect.DeepEqual(result.DocIDs, []uint32{1}) || result.Scores[1] == 0 {
+		t.Errorf("SearchThreshold = %+v, %v, want doc 1 with a score", result, err)
+	}
+
+	facets, err := client.Facets(ctx, "brown", "kind")
+	if want := map[string]map[string]uint64{"kind": {"animal": 2}}; err != nil || !reflect.DeepEqual(facets, want) {
+		t.Errorf("Facets = %v, %v, want %v", facets, err, want)
+	}
+	if _, err := client.Facets(ctx, "brown"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Facets without fields = %v, want InvalidArgument", err)
+	}
+
+	stats, err := client.Stats(ctx, 2)
+	if err != nil || stats.Docs != 3 || stats.GramSize != 3 || len(stats.Heaviest) != 2 {
+		t.Errorf("Stats = %v, %v", stats, err)
+	}
+}
+
+func TestSearchStreamsChunks(t *testing.T) {
+	idx := rs.NewIndex(3)
+	for docID := uint32(0); docID < 50_000; docID++ {
+		idx.Add(docID, "common text")
+	}
+	client, _ := newTestClient(t, idx, WithMaxResults(40_000))
+
+	var chunks, total int
+	err := client.SearchEach(context.Background(), &searchpb.SearchRequest{Query: "common", ChunkSize: 1000}, func(ids []uint32) error {
+		chunks++
+		total += len(ids)
+		return nil
+	})
+	if err != nil || chunks != 40 || total != 40_000 {
+		t.Errorf("SearchEach = %d chunks, %d docIDs, %v; want 40 chunks of the 40000 cap", chunks, total, err)
+	}
+
+	stop := errors.New("stop")
+	err = client.SearchEach(context.Background(), &searchpb.SearchRequest{Query: "common"}, func([]uint32) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("SearchEach with a failing callback = %v, want its error", err)
+	}
+}
+
+func TestIndexStream(t *testing.T) {
+	idx := newTestIndex()
+	client, _ := newTestClient(t, idx)
+	ctx := context.Background()
+
+	w, err := client.Index(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, send := range []func() error{
+		func() error { return w.Add(4, "brown bear") },
+		func() error { return w.Add(5, "brown cow") },
+		func() error { return w.Update(5, "black cow") },
+		func() error { return w.Remove(1, 2) },
+	} {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts, err := w.Close()
+	if err != nil || counts.Added != 2 || counts.Updated != 1 || counts.Removed != 2 {
+		t.Fatalf("Close = %v, %v, want 2 added, 1 updated, 2 removed", counts, err)
+	}
+
+	// The update applies after the add before it
+	if got, err := client.Search(ctx, "brown", 0); err != nil || !reflect.DeepEqual(got, []uint32{4}) {
+		t.Errorf("Search(brown) = %v, %v, want [4]", got, err)
+	}
+	if got := idx.Search("black"); !reflect.DeepEqual(got, []uint32{5}) {
+		t.Errorf("index Search(black) = %v, want [5]", got)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	client, srv := newTestClient(t, newTestIndex(), WithSnapshotDir(dir))
+	ctx := context.Background()
+
+	resp, err := client.CreateSnapshot(ctx, "v1.sear")
+	if err != nil || resp.Docs != 3 {
+		t.Fatalf("CreateSnapshot = %v, %v, want 3 docs", resp, err)
+	}
+
+	w, err := client.Index(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Add(4, "brown bear")
+	if _, err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	before := srv.Served()
+
+	resp, err = client.RestoreSnapshot(ctx, "v1.sear")
+	if err != nil || resp.Docs != 3 {
+		t.Fatalf("RestoreSnapshot = %v, %v, want 3 docs", resp, err)
+	}
+	if srv.Served() == before {
+		t.Error("RestoreSnapshot should replace the served index")
+	}
+	if got, err := client.Search(ctx, "brown", 0); err != nil || !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search(brown) after restore = %v, %v, want [1 2]", got, err)
+	}
+
+	for _, tt := range []struct {
+		path string
+		code codes.Code
+	}{
+		{"../escape.sear", codes.InvalidArgument},
+		{"/etc/passwd", codes.InvalidArgument},
+		{"missing.sear", codes.NotFound},
+	} {
+		if _, err := client.RestoreSnapshot(ctx, tt.path); status.Code(err) != tt.code {
+			t.Errorf("RestoreSnapshot(%q) = %v, want %v", tt.path, err, tt.code)
+		}
+	}
+
+	plain, _ := newTestClient(t, newTestIndex())
+	if _, err := plain.CreateSnapshot(ctx, "v1.sear"); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("CreateSnapshot without a snapshot dir = %v, want FailedPrecondition", err)
+	}
+	if _, err := plain.Facets(ctx, "", "kind"); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Facets without a filter = %v, want FailedPrecondition", err)
+	}
+}
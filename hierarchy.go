@@ -0,0 +1,93 @@
+package roaringsearch
+
+import (
+	"slices"
+	"strings"
+)
+
+// CategoryPathSeparator separates levels in hierarchical category paths.
+const CategoryPathSeparator = "/"
+
+// categoryPathPrefixes returns every ancestor path of path, including path itself.
+// "a/b/c" yields ["a", "a/b", "a/b/c"]. Empty segments are ignored.
+func categoryPathPrefixes(path string) []string {
+	parts := strings.Split(path, CategoryPathSeparator)
+	prefixes := make([]string, 0, len(parts))
+
+	var b strings.Builder
+	b.Grow(len(path))
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(CategoryPathSeparator)
+		}
+		b.WriteString(part)
+		prefixes = append(prefixes, b.String())
+	}
+	return prefixes
+}
+
+// SetPath assigns a document to a hierarchical category path within a field,
+// e.g. "electronics/audio/headphones". The document is also added to every
+// ancestor ("electronics", "electronics/audio"), so Get on a parent returns
+// the union of all its descendants without any query-time work.
+//
+// Ancestors are stored as regular categories, so Counts, Facets, and GetAny
+// treat them like any other category.
+func (c *BitmapFilter) SetPath(docID uint32, field, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, prefix := range categoryPathPrefixes(path) {
+		c.setLocked(docID, field, prefix)
+	}
+}
+
+// ReplacePaths replaces a document's hierarchical categories within a field.
+func (c *BitmapFilter) ReplacePaths(docID uint32, field string, paths []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeFromFieldLocked(docID, field)
+	for _, path := range paths {
+		for _, prefix := range categoryPathPrefixes(path) {
+			c.setLocked(docID, field, prefix)
+		}
+	}
+}
+
+// Children returns the direct child categories of parent within a field, sorted.
+// An empty parent returns the top-level categories.
+func (c *BitmapFilter) Children(field, parent string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	prefix := ""
+	if parent != "" {
+		prefix = parent + CategoryPathSeparator
+	}
+
+	var children []string
+	for cat := range c.fields[field] {
+		if !strings.HasPrefix(cat, prefix) {
+			continue
+		}
+		rest := cat[len(prefix):]
+		if rest == "" || strings.Contains(rest, CategoryPathSeparator) {
+			continue
+		}
+		children = append(children, cat)
+	}
+	slices.Sort(children)
+	return children
+}
+
+// AddPath adds a document with a hierarchical category path to the batch.
+// The document is added to the path and all of its ancestors.
+func (b *FilterBatch) AddPath(docID uint32, path string) {
+	for _, prefix := range categoryPathPrefixes(path) {
+		b.Add(docID, prefix)
+	}
+}
@@ -0,0 +1,64 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryPathPrefixes(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"electronics", []string{"electronics"}},
+		{"electronics/audio/headphones", []string{"electronics", "electronics/audio", "electronics/audio/headphones"}},
+		{"/books//fiction/", []string{"books", "books/fiction"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := categoryPathPrefixes(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("categoryPathPrefixes(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBitmapFilterHierarchy(t *testing.T) {
+	filter := NewBitmapFilter()
+
+	filter.SetPath(1, "category", "electronics/audio/headphones")
+	filter.SetPath(2, "category", "electronics/audio/speakers")
+	filter.SetPath(3, "category", "electronics/tv")
+	filter.SetPath(4, "category", "books/fiction")
+
+	if got := filter.Get("category", "electronics").GetCardinality(); got != 3 {
+		t.Errorf("electronics count = %d, want 3", got)
+	}
+	if got := filter.Get("category", "electronics/audio").ToArray(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("electronics/audio = %v, want [1 2]", got)
+	}
+
+	children := filter.Children("category", "electronics")
+	if !reflect.DeepEqual(children, []string{"electronics/audio", "electronics/tv"}) {
+		t.Errorf("Children(electronics) = %v", children)
+	}
+	if top := filter.Children("category", ""); !reflect.DeepEqual(top, []string{"books", "electronics"}) {
+		t.Errorf("Children(\"\") = %v, want [books electronics]", top)
+	}
+
+	// Moving a doc updates every level incrementally
+	filter.ReplacePaths(1, "category", []string{"books/nonfiction"})
+	if filter.Get("category", "electronics").Contains(1) {
+		t.Error("doc 1 should no longer be under electronics")
+	}
+	if got := filter.Get("category", "books").GetCardinality(); got != 2 {
+		t.Errorf("books count = %d, want 2", got)
+	}
+
+	batch := filter.Batch("category")
+	batch.AddPath(5, "electronics/tv/oled")
+	batch.Flush()
+	if !filter.Get("category", "electronics/tv").Contains(5) {
+		t.Error("batch AddPath should populate ancestors")
+	}
+}
@@ -0,0 +1,416 @@
+// Package httpd serves a roaringsearch index over HTTP with JSON responses,
+// for running search as a sidecar service.
+//
+// Endpoints (all GET):
+//
+//	/search?q=...&limit=N              AND search; JSON array of docIDs
+//	/search?q=...&limit=N&after=ID     the next page of an AND search: matches after docID ID
+//	/searchany?q=...&limit=N           OR search; JSON array of docIDs
+//	/threshold?q=...&min=N&k=N         fuzzy search; [{"id":..,"score":..}] best first
+//	/facets?q=...&field=F[&field=G]    category counts per field over the matches
+//	/sort?q=...&column=C&order=desc&limit=N&filter=F:cat
+//	                                   matches sorted by a column; [{"id":..,"value":..}]
+//	/stats?top=N                       index statistics
+//
+// An empty q in /facets and /sort matches every document. Large docID arrays
+// are streamed rather than encoded in one piece; with after, /search reads
+// matches a chunk at a time without materializing them, so a client pages
+// through any number of results by passing the last docID it received. Errors are returned as
+// {"error": "..."} with a 4xx status.
+//
+// Example:
+//
+//	srv := httpd.New(idx, httpd.WithFilter(filter), httpd.WithSortColumn("rating", ratings))
+//	err := srv.ListenAndServe(ctx, ":8080") // returns after ctx is done and requests drain
+//
+// TransferServer and TransferClient copy index files to replicas, resuming
+// dropped downloads and verifying checksums.
+package httpd
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// DefaultShutdownTimeout bounds how long ListenAndServe waits for in-flight
+// requests after its context is done.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// streamFlushEvery is how many docIDs are written between flushes of a
+// streamed array.
+const streamFlushEvery = 4096
+
+// Server serves an Index over HTTP.
+type Server struct {
+	idx             *rs.Index
+	filter          *rs.BitmapFilter
+	columns         map[string]sortFunc
+	maxResults      int
+	shutdownTimeout time.Duration
+	mux             *http.ServeMux
+}
+
+// sortedDoc is a docID with its sort value, as returned by /sort.
+type sortedDoc struct {
+	ID    uint32 `json:"id"`
+	Value any    `json:"value"`
+}
+
+// sortFunc sorts the documents of bm by a column.
+type sortFunc func(bm *roaring.Bitmap, asc bool, limit int) []sortedDoc
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithFilter enables /facets and the filter parameter of /sort.
+func WithFilter(filter *rs.BitmapFilter) Option {
+	return func(s *Server) {
+		s.filter = filter
+	}
+}
+
+// WithSortColumn makes col available to /sort as column=name.
+func WithSortColumn[T cmp.Ordered](name string, col *rs.SortColumn[T]) Option {
+	return func(s *Server) {
+		s.columns[name] = func(bm *roaring.Bitmap, asc bool, limit int) []sortedDoc {
+			results := col.SortBitmap(bm, asc, limit)
+			docs := make([]sortedDoc, len(results))
+			for i, r := range results {
+				docs[i] = sortedDoc{ID: r.DocID, Value: r.Value}
+			}
+			return docs
+		}
+	}
+}
+
+// WithMaxResults caps the number of docIDs any response returns, whatever
+// limit the client asks for. n <= 0 means no cap.
+func WithMaxResults(n int) Option {
+	return func(s *Server) {
+		s.maxResults = max(n, 0)
+	}
+}
+
+// WithShutdownTimeout sets how long ListenAndServe waits for in-flight
+// requests to finish after its context is done. d <= 0 keeps the default.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d > 0 {
+			s.shutdownTimeout = d
+		}
+	}
+}
+
+// New returns a Server for the index.
+func New(idx *rs.Index, opts ...Option) *Server {
+	s := &Server{
+		idx:             idx,
+		columns:         make(map[string]sortFunc),
+		shutdownTimeout: DefaultShutdownTimeout,
+		mux:             http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET /search", s.handleSearch)
+	s.mux.HandleFunc("GET /searchany", s.handleSearchAny)
+	s.mux.HandleFunc("GET /threshold", s.handleThreshold)
+	s.mux.HandleFunc("GET /facets", s.handleFacets)
+	s.mux.HandleFunc("GET /sort", s.handleSort)
+	s.mux.HandleFunc("GET /stats", s.handleStats)
+	return s
+}
+
+// ServeHTTP implements http.Handler, so a Server can be mounted on another mux.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on addr until ctx is done, then shuts down
+// gracefully: it stops accepting connections and waits up to the shutdown
+// timeout for in-flight requests. It returns nil after a graceful shutdown.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like ListenAndServe but accepts connections on ln.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.limitParam(r, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q := r.URL.Query().Get("q")
+
+	if r.URL.Query().Has("after") {
+		after, err := strconv.ParseUint(r.URL.Query().Get("after"), 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("after must be a docID, got %q", r.URL.Query().Get("after")))
+			return
+		}
+		writeCursor(w, s.idx.SearchCursor(q), uint64(after)+1, limit)
+		return
+	}
+
+	var ids []uint32
+	if limit > 0 {
+		ids = s.idx.SearchWithLimit(q, limit)
+	} else {
+		ids = s.idx.Search(q)
+	}
+	writeIDs(w, ids)
+}
+
+// writeCursor streams up to limit matches of c from docID start on (all of
+// them when limit is 0), reading one chunk at a time.
+func writeCursor(w http.ResponseWriter, c *rs.Cursor, start uint64, limit int) {
+	if start > math.MaxUint32 {
+		writeIDs(w, nil)
+		return
+	}
+	c.Seek(uint32(start))
+	remaining := limit
+	writeIDStream(w, func(buf []uint32) int {
+		if limit > 0 {
+			buf = buf[:min(len(buf), remaining)]
+		}
+		n := c.Next(buf)
+		remaining -= n
+		return n
+	})
+}
+
+func (s *Server) handleSearchAny(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.limitParam(r, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ids := s.idx.SearchAny(r.URL.Query().Get("q"))
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	writeIDs(w, ids)
+}
+
+func (s *Server) handleThreshold(w http.ResponseWriter, r *http.Request) {
+	minMatches, err := intParam(r, "min", 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	k, err := s.limitParam(r, "k")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result := s.idx.SearchThresholdTopK(r.URL.Query().Get("q"), minMatches, k)
+	type scoredDoc struct {
+		ID    uint32 `json:"id"`
+		Score int    `json:"score"`
+	}
+	docs := make([]scoredDoc, len(result.DocIDs))
+	for i, id := range result.DocIDs {
+		docs[i] = scoredDoc{ID: id, Score: result.Scores[id]}
+	}
+	writeJSON(w, http.StatusOK, docs)
+}
+
+func (s *Server) handleFacets(w http.ResponseWriter, r *http.Request) {
+	if s.filter == nil {
+		writeError(w, http.StatusNotFound, errors.New("no filter configured"))
+		return
+	}
+	fields := r.URL.Query()["field"]
+	if len(fields) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("missing field parameter"))
+		return
+	}
+
+	var result *roaring.Bitmap
+	if q := r.URL.Query().Get("q"); q != "" {
+		result = s.idx.SearchBitmap(q)
+	}
+	writeJSON(w, http.StatusOK, s.filter.Facets(result, fields...))
+}
+
+func (s *Server) handleSort(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	sortBy, ok := s.columns[params.Get("column")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown column %q", params.Get("column")))
+		return
+	}
+	limit, err := s.limitParam(r, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var asc bool
+	switch params.Get("order") {
+	case "", "desc":
+	case "asc":
+		asc = true
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("order must be asc or desc, got %q", params.Get("order")))
+		return
+	}
+
+	result := s.idx.SearchBitmap(params.Get("q"))
+	for _, f := range params["filter"] {
+		field, category, ok := strings.Cut(f, ":")
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("filter must be field:category, got %q", f))
+			return
+		}
+		if s.filter == nil {
+			writeError(w, http.StatusNotFound, errors.New("no filter configured"))
+			return
+		}
+		result.And(s.filter.Get(field, category))
+	}
+
+	docs := sortBy(result, asc, limit)
+	if docs == nil {
+		docs = []sortedDoc{}
+	}
+	writeJSON(w, http.StatusOK, docs)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	top, err := intParam(r, "top", 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.idx.Stats(top))
+}
+
+// limitParam reads a result limit, applying the server's cap. 0 means no limit.
+func (s *Server) limitParam(r *http.Request, name string) (int, error) {
+	limit, err := intParam(r, name, 0)
+	if err != nil {
+		return 0, err
+	}
+	if s.maxResults > 0 && (limit <= 0 || limit > s.maxResults) {
+		limit = s.maxResults
+	}
+	return limit, nil
+}
+
+// intParam reads a non-negative integer query parameter, or def when absent.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, v)
+	}
+	return n, nil
+}
+
+// writeIDs streams ids as a JSON array, flushing periodically so large
+// results reach the client without being encoded in one piece.
+func writeIDs(w http.ResponseWriter, ids []uint32) {
+	writeIDStream(w, func(buf []uint32) int {
+		n := copy(buf, ids)
+		ids = ids[n:]
+		return n
+	})
+}
+
+// writeIDStream writes the docIDs produced by fill as a JSON array, one
+// chunk of streamFlushEvery at a time, until fill returns 0.
+func writeIDStream(w http.ResponseWriter, fill func(buf []uint32) int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriter(w)
+	var num [10]byte
+	buf := make([]uint32, streamFlushEvery)
+
+	bw.WriteByte('[')
+	for written := 0; ; {
+		n := fill(buf)
+		if n == 0 {
+			break
+		}
+		if written > 0 {
+			if bw.Flush() != nil {
+				return // client went away
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		for _, id := range buf[:n] {
+			if written > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(strconv.AppendUint(num[:0], uint64(id), 10))
+			written++
+		}
+	}
+	bw.WriteString("]\n")
+	bw.Flush()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,234 @@
+package httpd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+func newTestServer(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+
+	idx := rs.NewIndex(3)
+	idx.Add(1, "the quick brown fox")
+	idx.Add(2, "the lazy brown dog")
+	idx.Add(3, "hello world")
+
+	filter := rs.NewBitmapFilter()
+	filter.Set(1, "kind", "animal")
+	filter.Set(2, "kind", "animal")
+	filter.Set(3, "kind", "greeting")
+
+	ratings := rs.NewSortColumn[uint16]()
+	ratings.Set(1, 30)
+	ratings.Set(2, 90)
+	ratings.Set(3, 60)
+
+	opts = append([]Option{WithFilter(filter), WithSortColumn("rating", ratings)}, opts...)
+	ts := httptest.NewServer(New(idx, opts...))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// get fetches path and decodes the JSON body into v, returning the status.
+func get(t *testing.T, ts *httptest.Server, path string, v any) int {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s Content-Type = %q", path, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decoding body: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestSearchEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		path string
+		want []uint32
+	}{
+		{"/search?q=brown", []uint32{1, 2}},
+		{"/search?q=brown&limit=1", []uint32{1}},
+		{"/search?q=zebra", []uint32{}},
+		{"/search", []uint32{}},
+		{"/searchany?q=" + url.QueryEscape("fox world"), []uint32{1, 3}},
+		{"/searchany?q=" + url.QueryEscape("fox world") + "&limit=1", []uint32{1}},
+	}
+	for _, tt := range tests {
+		var got []uint32
+		if status := get(t, ts, tt.path, &got); status != http.StatusOK {
+			t.Errorf("GET %s status = %d", tt.path, status)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	var scored []struct{ ID, Score int }
+	get(t, ts, "/threshold?q="+url.QueryEscape("brown fox")+"&min=3&k=1", &scored)
+	if len(scored) != 1 || scored[0].ID != 1 {
+		t.Errorf("GET /threshold = %+v, want doc 1 first", scored)
+	}
+}
+
+func TestFacetsAndSort(t *testing.T) {
+	ts := newTestServer(t)
+
+	var facets map[string]map[string]uint64
+	get(t, ts, "/facets?q=brown&field=kind", &facets)
+	if want := map[string]map[string]uint64{"kind": {"animal": 2}}; !reflect.DeepEqual(facets, want) {
+		t.Errorf("GET /facets = %v, want %v", facets, want)
+	}
+
+	var sorted []struct {
+		ID    uint32
+		Value uint16
+	}
+	get(t, ts, "/sort?column=rating&limit=2", &sorted)
+	if len(sorted) != 2 || sorted[0].ID != 2 || sorted[1].ID != 3 {
+		t.Errorf("GET /sort (empty query) = %+v, want [2 3]", sorted)
+	}
+	get(t, ts, "/sort?column=rating&order=asc&filter=kind:animal", &sorted)
+	if len(sorted) != 2 || sorted[0].ID != 1 || sorted[0].Value != 30 {
+		t.Errorf("GET /sort asc with filter = %+v, want doc 1 (30) first", sorted)
+	}
+
+	var stats rs.IndexStats
+	get(t, ts, "/stats?top=2", &stats)
+	if stats.Docs != 3 || stats.GramSize != 3 || len(stats.Heaviest) != 2 {
+		t.Errorf("GET /stats = %+v", stats)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := newTestServer(t)
+
+	for path, want := range map[string]int{
+		"/search?limit=-1":                http.StatusBadRequest,
+		"/search?q=fox&after=x":           http.StatusBadRequest,
+		"/threshold?q=fox&min=x":          http.StatusBadRequest,
+		"/facets?q=fox":                   http.StatusBadRequest,
+		"/sort?column=missing":            http.StatusNotFound,
+		"/sort?column=rating&order=up":    http.StatusBadRequest,
+		"/sort?column=rating&filter=kind": http.StatusBadRequest,
+	} {
+		var body map[string]string
+		if status := get(t, ts, path, &body); status != want {
+			t.Errorf("GET %s status = %d, want %d", path, status, want)
+		}
+		if body["error"] == "" {
+			t.Errorf("GET %s body = %v, want an error message", path, body)
+		}
+	}
+
+	resp, err := http.Post(ts.URL+"/search?q=fox", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /search status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestMaxResultsAndStreaming(t *testing.T) {
+	idx := rs.NewIndex(3)
+	for i := uint32(0); i < 3*streamFlushEvery; i++ {
+		idx.Add(i, "hello world")
+	}
+	ts := httptest.NewServer(New(idx))
+	defer ts.Close()
+
+	var ids []uint32
+	get(t, ts, "/search?q=hello", &ids)
+	if len(ids) != 3*streamFlushEvery || ids[len(ids)-1] != 3*streamFlushEvery-1 {
+		t.Errorf("streamed %d ids, want %d", len(ids), 3*streamFlushEvery)
+	}
+
+	capped := httptest.NewServer(New(idx, WithMaxResults(5)))
+	defer capped.Close()
+	get(t, capped, "/search?q=hello&limit=100", &ids)
+	if len(ids) != 5 {
+		t.Errorf("capped search returned %d ids, want 5", len(ids))
+	}
+}
+
+func TestSearchAfter(t *testing.T) {
+	idx := rs.NewIndex(3)
+	for i := uint32(0); i < 2*streamFlushEvery+10; i++ {
+		idx.Add(i*2, "hello world")
+	}
+	ts := httptest.NewServer(New(idx))
+	defer ts.Close()
+
+	// The first page is a plain limited search; each next page starts after
+	// the last docID received
+	var all, page []uint32
+	for path := "/search?q=hello&limit=3000"; ; {
+		get(t, ts, path, &page)
+		if len(page) == 0 {
+			break
+		}
+		all = append(all, page...)
+		path = fmt.Sprintf("/search?q=hello&limit=3000&after=%d", page[len(page)-1])
+	}
+	if want := idx.Search("hello"); !reflect.DeepEqual(all, want) {
+		t.Errorf("paged %d ids, want %d", len(all), len(want))
+	}
+
+	get(t, ts, "/search?q=hello&after=4294967295", &page)
+	if len(page) != 0 {
+		t.Errorf("after the last docID returned %v", page)
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	idx := rs.NewIndex(3)
+	idx.Add(1, "hello world")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New(idx, WithShutdownTimeout(time.Second)).Serve(ctx, ln) }()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/search?q=hello", ln.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := strings.TrimSpace(string(body)); got != "[1]" {
+		t.Errorf("body = %q, want [1]", got)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v after shutdown, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the context was cancelled")
+	}
+}
@@ -0,0 +1,306 @@
+package httpd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// ErrChecksumMismatch is returned by TransferClient.Fetch when a downloaded
+// file does not match the checksum the server sent.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// DefaultTransferRetries is how many times Fetch resumes a dropped transfer
+// before giving up.
+const DefaultTransferRetries = 5
+
+// TransferServer serves index files to replicas over HTTP, with range
+// requests so a client can resume a dropped download. Each file is served at
+// /<name> with its SHA-256 as a strong ETag, so a resumed download whose
+// file was replaced in the meantime starts over rather than mixing
+// versions, and the client can verify what it received.
+//
+// Example:
+//
+//	ts := httpd.NewTransferServer()
+//	err := ts.ServeIndex("main.sear", idx, "/var/lib/search")
+//	http.Handle("/files/", http.StripPrefix("/files", ts))
+type TransferServer struct {
+	mu    sync.RWMutex
+	files map[string]transferFile
+}
+
+// transferFile is a file served by a TransferServer.
+type transferFile struct {
+	path     string
+	checksum string // hex SHA-256
+}
+
+// NewTransferServer returns a TransferServer with no files.
+func NewTransferServer() *TransferServer {
+	return &TransferServer{files: make(map[string]transferFile)}
+}
+
+// ServeFile serves the file at path as name, replacing any file served
+// under that name. The file's checksum is computed once, here, so the file
+// must not change while it is served; write a new file and call ServeFile
+// again instead. Transfers already under way finish with the old file.
+func (s *TransferServer) ServeFile(name, path string) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid transfer name %q", name)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("checksum %s: %w", path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = transferFile{path: path, checksum: hex.EncodeToString(h.Sum(nil))}
+	return nil
+}
+
+// ServeIndex saves a snapshot of idx to name in dir and serves it, so
+// ingestion into idx continues while replicas download a stable file.
+func (s *TransferServer) ServeIndex(name string, idx *rs.Index, dir string) error {
+	path := filepath.Join(dir, name)
+	if err := idx.Snapshot().SaveToFile(path); err != nil {
+		return err
+	}
+	return s.ServeFile(name, path)
+}
+
+// ServeHTTP serves GET and HEAD requests for /<name>, honoring Range and
+// If-Range.
+func (s *TransferServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	s.mu.RLock()
+	file, ok := s.files[name]
+	s.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no file %q", name))
+		return
+	}
+
+	f, err := os.Open(file.path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("ETag", strconv.Quote(file.checksum))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// TransferClient downloads files from a TransferServer, resuming after
+// connection drops and verifying each file's checksum.
+type TransferClient struct {
+	client     *http.Client
+	retries    int
+	retryDelay time.Duration
+}
+
+// TransferOption configures a TransferClient.
+type TransferOption func(*TransferClient)
+
+// WithHTTPClient sets the HTTP client used for requests. The default is
+// http.DefaultClient.
+func WithHTTPClient(c *http.Client) TransferOption {
+	return func(tc *TransferClient) {
+		tc.client = c
+	}
+}
+
+// WithTransferRetries sets how many times Fetch resumes a failed transfer.
+// The default is DefaultTransferRetries; n < 0 keeps it.
+func WithTransferRetries(n int) TransferOption {
+	return func(tc *TransferClient) {
+		if n >= 0 {
+			tc.retries = n
+		}
+	}
+}
+
+// WithRetryDelay sets the pause before each retry. The default is one second.
+func WithRetryDelay(d time.Duration) TransferOption {
+	return func(tc *TransferClient) {
+		tc.retryDelay = max(d, 0)
+	}
+}
+
+// NewTransferClient returns a TransferClient.
+func NewTransferClient(opts ...TransferOption) *TransferClient {
+	c := &TransferClient{
+		client:     http.DefaultClient,
+		retries:    DefaultTransferRetries,
+		retryDelay: time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// transferAttempt is the state of a download carried across attempts.
+type transferAttempt struct {
+	part     string // partial file, path + ".part"
+	checksum string // expected hex SHA-256, from the ETag; empty until known
+}
+
+// Fetch downloads url to path. Data is written to path + ".part" and renamed
+// to path only once its SHA-256 matches the server's, so path is never
+// left partially written. A dropped connection or server error is retried
+// from where it stopped, with If-Range so a file replaced on the server is
+// downloaded afresh. A partial file left by an earlier Fetch is resumed the
+// same way; its checksum is kept in path + ".part.etag".
+func (c *TransferClient) Fetch(ctx context.Context, url, path string) error {
+	t := &transferAttempt{part: path + ".part"}
+	if etag, err := os.ReadFile(t.part + ".etag"); err == nil {
+		t.checksum = string(etag)
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.fetchOnce(ctx, url, t)
+		if err == nil {
+			err = t.finish(path)
+			if err == nil {
+				return nil
+			}
+			retry = errors.Is(err, ErrChecksumMismatch) // start over once more
+		}
+		if !retry || attempt >= c.retries || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// fetchOnce makes one request, appending to the partial file when its
+// checksum is known. It reports whether a failure is worth retrying.
+func (c *TransferClient) fetchOnce(ctx context.Context, url string, t *transferAttempt) (retry bool, err error) {
+	var offset int64
+	if t.checksum != "" {
+		if info, err := os.Stat(t.part); err == nil {
+			offset = info.Size()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", strconv.Quote(t.checksum))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusOK:
+		flags |= os.O_TRUNC
+		etag, err := strconv.Unquote(resp.Header.Get("ETag"))
+		if err != nil || etag == "" {
+			return false, fmt.Errorf("fetch %s: missing ETag checksum", url)
+		}
+		t.checksum = etag
+		if err := os.WriteFile(t.part+".etag", []byte(etag), 0o644); err != nil {
+			return false, err
+		}
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return false, fmt.Errorf("fetch %s: unexpected Content-Range %q", url, resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is as long as the server's or longer; start over
+		t.reset()
+		return true, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	default:
+		return resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+
+	f, err := os.OpenFile(t.part, flags, 0o644)
+	if err != nil {
+		return false, err
+	}
+	_, copyErr := io.Copy(f, resp.Body)
+	if err := f.Close(); err != nil && copyErr == nil {
+		return false, err
+	}
+	if copyErr != nil {
+		return true, fmt.Errorf("fetch %s: %w", url, copyErr)
+	}
+	return false, nil
+}
+
+// finish verifies the partial file against the checksum and renames it to
+// path. A mismatched file is removed.
+func (t *transferAttempt) finish(path string) error {
+	f, err := os.Open(t.part)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != t.checksum {
+		t.reset()
+		return fmt.Errorf("%s: %w", path, ErrChecksumMismatch)
+	}
+
+	if err := os.Rename(t.part, path); err != nil {
+		return err
+	}
+	os.Remove(t.part + ".etag")
+	return nil
+}
+
+// reset discards the partial file so the next attempt starts from scratch.
+func (t *transferAttempt) reset() {
+	os.Remove(t.part)
+	os.Remove(t.part + ".etag")
+	t.checksum = ""
+}
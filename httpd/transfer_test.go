@@ -0,0 +1,134 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// droppingTransport fails the body of its first response after limit bytes,
+// like a dropped connection.
+type droppingTransport struct {
+	limit   int64
+	dropped bool
+}
+
+func (d *droppingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || d.dropped {
+		return resp, err
+	}
+	d.dropped = true
+	resp.Body = &droppingBody{r: io.LimitReader(resp.Body, d.limit), c: resp.Body}
+	return resp, nil
+}
+
+type droppingBody struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (b *droppingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (b *droppingBody) Close() error { return b.c.Close() }
+
+func TestTransfer(t *testing.T) {
+	dir := t.TempDir()
+	idx := rs.NewIndex(3)
+	for i := range 500 {
+		idx.Add(uint32(i), "the quick brown fox jumps over the lazy dog "+string(rune('a'+i%26)))
+	}
+
+	srv := NewTransferServer()
+	if err := srv.ServeIndex("main.sear", idx, dir); err != nil {
+		t.Fatalf("ServeIndex failed: %v", err)
+	}
+	var mu sync.Mutex
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		srv.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	want, err := os.ReadFile(filepath.Join(dir, "main.sear"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewTransferClient(
+		WithHTTPClient(&http.Client{Transport: &droppingTransport{limit: 100}}),
+		WithRetryDelay(0),
+	)
+	dst := filepath.Join(t.TempDir(), "replica.sear")
+	if err := client.Fetch(context.Background(), ts.URL+"/main.sear", dst); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	got, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("fetched file differs from the served one (err %v)", err)
+	}
+	if !reflect.DeepEqual(ranges, []string{"", "bytes=100-"}) {
+		t.Errorf("requests had ranges %q, want a resume from byte 100", ranges)
+	}
+	if _, err := os.Stat(dst + ".part.etag"); !os.IsNotExist(err) {
+		t.Error("the partial transfer state should be removed")
+	}
+	replica, err := rs.LoadFromFile(dst)
+	if err != nil || !reflect.DeepEqual(replica.Search("fox"), idx.Search("fox")) {
+		t.Errorf("replica does not match the index (err %v)", err)
+	}
+
+	if err := client.Fetch(context.Background(), ts.URL+"/missing", dst+"2"); err == nil {
+		t.Error("expected error for an unknown file")
+	}
+}
+
+func TestTransferChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data")
+	if err := os.WriteFile(path, []byte("original data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewTransferServer()
+	if err := srv.ServeFile("data", path); err != nil {
+		t.Fatalf("ServeFile failed: %v", err)
+	}
+	// Changing the file behind the server's back breaks the checksum
+	if err := os.WriteFile(path, []byte("tampered data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "data")
+	err := NewTransferClient(WithTransferRetries(1), WithRetryDelay(0)).Fetch(context.Background(), ts.URL+"/data", dst)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Fetch error = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("a mismatched file should not be renamed into place")
+	}
+
+	if err := srv.ServeFile("a/b", path); err == nil {
+		t.Error("expected error for a name with a slash")
+	}
+}
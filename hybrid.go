@@ -0,0 +1,293 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"unicode"
+)
+
+const hybridMagicBytes = "FTSY"
+
+// HybridIndex maintains one Index layer per gram size over the same documents
+// and picks a layer per query, so mixed-language corpora do not need separate
+// indexes. Queries containing CJK characters use the smallest gram size, since
+// CJK words are often one or two characters long; other queries use the
+// largest gram size that fits the query, which is the most selective.
+//
+// Example:
+//
+//	idx := NewHybridIndex([]int{2, 3})
+//	idx.Add(1, "東京 tower")
+//	idx.Search("東京")  // bigram layer
+//	idx.Search("tower") // trigram layer
+type HybridIndex struct {
+	layers []*Index // sorted by gram size
+}
+
+// NewHybridIndex creates a hybrid index with a layer per gram size.
+// Default gram sizes are 2 and 3. Options apply to every layer.
+func NewHybridIndex(gramSizes []int, opts ...Option) *HybridIndex {
+	if len(gramSizes) == 0 {
+		gramSizes = []int{2, 3}
+	}
+
+	h := &HybridIndex{}
+	for _, gramSize := range gramSizes {
+		idx := NewIndex(gramSize, opts...)
+		if h.Layer(idx.gramSize) == nil {
+			h.layers = append(h.layers, idx)
+		}
+	}
+	slices.SortFunc(h.layers, func(a, b *Index) int { return a.gramSize - b.gramSize })
+	return h
+}
+
+// GramSizes returns the layer gram sizes in ascending order.
+func (h *HybridIndex) GramSizes() []int {
+	sizes := make([]int, len(h.layers))
+	for i, layer := range h.layers {
+		sizes[i] = layer.gramSize
+	}
+	return sizes
+}
+
+// Layer returns the layer with the given gram size, or nil if there is none.
+func (h *HybridIndex) Layer(gramSize int) *Index {
+	for _, layer := range h.layers {
+		if layer.gramSize == gramSize {
+			return layer
+		}
+	}
+	return nil
+}
+
+// LayerFor returns the layer a query is searched in, or nil if the query is
+// shorter than every gram size.
+func (h *HybridIndex) LayerFor(query string) *Index {
+	// All layers share options, so any normalizer is representative
+	runes := []rune(h.layers[0].normalizer(query))
+
+	if slices.ContainsFunc(runes, isCJK) {
+		if len(runes) >= h.layers[0].gramSize {
+			return h.layers[0]
+		}
+		return nil
+	}
+
+	for i := len(h.layers) - 1; i >= 0; i-- {
+		if len(runes) >= h.layers[i].gramSize {
+			return h.layers[i]
+		}
+	}
+	return nil
+}
+
+// isCJK reports whether r is a Chinese, Japanese, or Korean character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Add indexes a document in every layer.
+func (h *HybridIndex) Add(docID uint32, text string) {
+	for _, layer := range h.layers {
+		layer.Add(docID, text)
+	}
+}
+
+// Update replaces a document's text in every layer.
+func (h *HybridIndex) Update(docID uint32, text string) {
+	for _, layer := range h.layers {
+		layer.Update(docID, text)
+	}
+}
+
+// Remove removes a document from every layer.
+func (h *HybridIndex) Remove(docID uint32) {
+	for _, layer := range h.layers {
+		layer.Remove(docID)
+	}
+}
+
+// RemoveMany removes a batch of documents from every layer.
+func (h *HybridIndex) RemoveMany(ids []uint32) {
+	for _, layer := range h.layers {
+		layer.RemoveMany(ids)
+	}
+}
+
+// Search performs an AND search in the layer chosen for the query.
+func (h *HybridIndex) Search(query string) []uint32 {
+	if layer := h.LayerFor(query); layer != nil {
+		return layer.Search(query)
+	}
+	return nil
+}
+
+// SearchWithLimit returns up to limit matches from the layer chosen for the query.
+func (h *HybridIndex) SearchWithLimit(query string, limit int) []uint32 {
+	if layer := h.LayerFor(query); layer != nil {
+		return layer.SearchWithLimit(query, limit)
+	}
+	return nil
+}
+
+// SearchCount counts AND matches in the layer chosen for the query.
+func (h *HybridIndex) SearchCount(query string) uint64 {
+	if layer := h.LayerFor(query); layer != nil {
+		return layer.SearchCount(query)
+	}
+	return 0
+}
+
+// SearchAny performs an OR search in the layer chosen for the query.
+func (h *HybridIndex) SearchAny(query string) []uint32 {
+	if layer := h.LayerFor(query); layer != nil {
+		return layer.SearchAny(query)
+	}
+	return nil
+}
+
+// DocCount returns the number of indexed documents.
+func (h *HybridIndex) DocCount() uint64 {
+	return h.layers[0].DocCount()
+}
+
+// MemoryUsage returns the memory used by all layers in bytes.
+func (h *HybridIndex) MemoryUsage() uint64 {
+	var total uint64
+	for _, layer := range h.layers {
+		total += layer.MemoryUsage()
+	}
+	return total
+}
+
+// HybridBatch accumulates documents for batch insertion into every layer.
+type HybridBatch struct {
+	batches []*IndexBatch
+}
+
+// Batch creates a batch builder that feeds every layer.
+func (h *HybridIndex) Batch(opts ...BatchOption) *HybridBatch {
+	b := &HybridBatch{batches: make([]*IndexBatch, len(h.layers))}
+	for i, layer := range h.layers {
+		b.batches[i] = layer.Batch(opts...)
+	}
+	return b
+}
+
+// Add adds a document to the batch.
+func (b *HybridBatch) Add(docID uint32, text string) {
+	for _, batch := range b.batches {
+		batch.Add(docID, text)
+	}
+}
+
+// Flush commits all accumulated documents to every layer.
+func (b *HybridBatch) Flush() {
+	for _, batch := range b.batches {
+		batch.Flush()
+	}
+}
+
+// WriteTo writes every layer to w.
+//
+// Format: magic(4) + layer count(4), then each layer in the Index format.
+func (h *HybridIndex) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 8)
+	copy(header[0:4], hybridMagicBytes)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(h.layers)))
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, fmt.Errorf("write header: %w", err)
+	}
+
+	for _, layer := range h.layers {
+		n, err := layer.WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// SaveToFile saves the hybrid index to a file atomically.
+func (h *HybridIndex) SaveToFile(path string) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	bw := bufio.NewWriter(f)
+	if _, err := h.WriteTo(bw); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("flush temp file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadHybridIndex reads a hybrid index written by WriteTo.
+// Options apply to every layer, as with NewHybridIndex.
+func ReadHybridIndex(r io.Reader, opts ...Option) (*HybridIndex, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if string(header[0:4]) != hybridMagicBytes {
+		return nil, ErrInvalidMagic
+	}
+	count := binary.LittleEndian.Uint32(header[4:8])
+	if count == 0 || count > maxGramSize {
+		return nil, ErrInvalidCount
+	}
+
+	h := &HybridIndex{layers: make([]*Index, count)}
+	for i := range h.layers {
+		layer := NewIndex(3, opts...)
+		if _, err := layer.ReadFrom(r); err != nil {
+			return nil, err
+		}
+		h.layers[i] = layer
+	}
+	return h, nil
+}
+
+// LoadHybridIndex loads a hybrid index from a file.
+func LoadHybridIndex(path string, opts ...Option) (*HybridIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	return ReadHybridIndex(bufio.NewReader(f), opts...)
+}
@@ -0,0 +1,87 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHybridIndexLayerSelection(t *testing.T) {
+	h := NewHybridIndex([]int{3, 2, 3})
+	if got := h.GramSizes(); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Fatalf("GramSizes = %v, want [2 3]", got)
+	}
+
+	tests := []struct {
+		query    string
+		gramSize int
+	}{
+		{"東京", 2},
+		{"東京タワー", 2},
+		{"tower", 3},
+		{"to", 2},
+		{"t", 0},
+	}
+	for _, tt := range tests {
+		layer := h.LayerFor(tt.query)
+		got := 0
+		if layer != nil {
+			got = layer.GramSize()
+		}
+		if got != tt.gramSize {
+			t.Errorf("LayerFor(%q) gram size = %d, want %d", tt.query, got, tt.gramSize)
+		}
+	}
+}
+
+func TestHybridIndexSearch(t *testing.T) {
+	h := NewHybridIndex(nil)
+	h.Add(1, "東京 tower")
+	h.Add(2, "京都 temple")
+
+	batch := h.Batch()
+	batch.Add(3, "東京 temple")
+	batch.Flush()
+
+	if got := h.Search("東京"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("Search(東京) = %v, want [1 3]", got)
+	}
+	if got := h.Search("temple"); !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("Search(temple) = %v, want [2 3]", got)
+	}
+	if got := h.SearchCount("tower"); got != 1 {
+		t.Errorf("SearchCount(tower) = %d, want 1", got)
+	}
+
+	h.Remove(3)
+	if got := h.Search("東京"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(東京) after Remove = %v, want [1]", got)
+	}
+	if h.DocCount() != 2 {
+		t.Errorf("DocCount = %d, want 2", h.DocCount())
+	}
+}
+
+func TestHybridIndexPersistence(t *testing.T) {
+	h := NewHybridIndex([]int{2, 3})
+	h.Add(1, "東京 tower")
+	h.Add(2, "京都 temple")
+
+	path := filepath.Join(t.TempDir(), "hybrid.sear")
+	if err := h.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	loaded, err := LoadHybridIndex(path)
+	if err != nil {
+		t.Fatalf("LoadHybridIndex failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded.GramSizes(), []int{2, 3}) {
+		t.Errorf("loaded GramSizes = %v", loaded.GramSizes())
+	}
+	for _, q := range []string{"東京", "temple", "tower"} {
+		if got, want := loaded.Search(q), h.Search(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("loaded Search(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
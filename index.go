@@ -1,9 +1,16 @@
 package roaringsearch
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"runtime"
-	"sort"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/RoaringBitmap/roaring/v2"
 )
@@ -22,7 +29,31 @@ type Index struct {
 	gramSize        int
 	normalizer      Normalizer
 	bitmaps         map[uint64]*roaring.Bitmap
-	useASCIFastPath bool // true when using default normalizer
+	docs            *roaring.Bitmap     // every docID passed to Add or a batch
+	ascii           *asciiTable         // ASCII fast path of the normalizer; nil if it has none
+	normalizerChain NormalizerChain     // set by WithNormalizerChain
+	stats           *queryStats         // nil unless a query hook, slow query log or logger is set
+	forward         forwardIndex        // docID -> sorted n-gram keys; nil unless WithForwardIndex
+	deterministic   bool                // fixed batch partitioning and sorted WriteTo; see WithDeterministicBuild
+	exact           *ngramDict          // collision-free keys for hashed n-grams; nil unless WithExactKeys
+	containerDir    bool                // WriteTo records container offsets; see WithContainerDirectory
+	maxResults      int                 // most docIDs a search returns, 0 for no cap; see WithMaxResults
+	missingNgrams   MissingNgramPolicy  // AND searches with an absent n-gram; see WithMissingNgramPolicy
+	synonyms        synonymSet          // query-time expansions; see WithSynonyms
+	indexSynonyms   synonymSet          // index-time expansions; see WithIndexSynonyms
+	cjkBigrams      bool                // bigrams for CJK runs; see WithCJKBigrams
+	metadata        *fileMetadata       // recorded in files; nil unless WithMetadata or loaded
+	optimizeOnSave  bool                // SaveToFile runs Optimize first; see WithOptimizeOnSave
+	saveWorkers     int                 // bitmap serializers of WriteTo, 0 for sequential; see WithSaveWorkers
+	lengths         *SortColumn[uint16] // unique n-grams per document; nil unless WithDocLengths
+	lengthSum       uint64              // sum of recorded lengths, for AvgDocLength
+	lengthCount     uint64              // documents with a recorded length
+	shared          map[uint64]struct{} // keys whose bitmaps a Snapshot may still share; see writableBitmapLocked
+	applying        sync.RWMutex        // read-held while a batch is applied, so Snapshot never sees part of one
+	loading         *ProgressiveLoad    // bitmaps still loading; nil unless LoadFromFileAsync is in progress
+	hotKeysPath     string              // load order for LoadFromFileAsync; see WithHotKeys
+	results         *resultCache        // whole query results; nil unless WithResultCache
+	generation      uint64              // bumped by every change to the bitmaps, invalidating results
 }
 
 // NewIndex creates a new Index with the specified gram size.
@@ -37,14 +68,15 @@ func NewIndex(gramSize int, opts ...Option) *Index {
 	}
 
 	idx := &Index{
-		gramSize:        gramSize,
-		normalizer:      NormalizeLowercaseAlphanumeric,
-		bitmaps:         make(map[uint64]*roaring.Bitmap),
-		useASCIFastPath: true, // default normalizer supports fast path
+		gramSize:   gramSize,
+		normalizer: NormalizeLowercaseAlphanumeric,
+		bitmaps:    make(map[uint64]*roaring.Bitmap),
+		docs:       roaring.New(),
+		ascii:      lowerAlnumASCII, // default normalizer supports fast path
 	}
 
 	for _, opt := range opts {
-		opt(idx)
+		opt.applyIndex(idx)
 	}
 
 	return idx
@@ -62,9 +94,25 @@ func (idx *Index) NgramCount() int {
 	return len(idx.bitmaps)
 }
 
+// AllDocs returns a copy of the bitmap of every indexed docID, including
+// documents too short to produce any n-grams.
+// After loading from disk it holds every docID that appears in some n-gram.
+func (idx *Index) AllDocs() *roaring.Bitmap {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.docs.Clone()
+}
+
+// DocCount returns the number of indexed documents.
+func (idx *Index) DocCount() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.docs.GetCardinality()
+}
+
 // getOrCreateBitmap returns the bitmap for the key, creating it if needed.
 func (idx *Index) getOrCreateBitmap(key uint64) *roaring.Bitmap {
-	bm, exists := idx.bitmaps[key]
+	bm, exists := idx.writableBitmapLocked(key)
 	if !exists {
 		bm = roaring.New()
 		idx.bitmaps[key] = bm
@@ -72,27 +120,63 @@ func (idx *Index) getOrCreateBitmap(key uint64) *roaring.Bitmap {
 	return bm
 }
 
-// addRuneBasedNgrams indexes a document using rune-based n-gram processing.
-func (idx *Index) addRuneBasedNgrams(docID uint32, text string) {
-	normalized := idx.normalizer(text)
-	runes := []rune(normalized)
+// indexScratch holds the reusable buffers of a single Add.
+type indexScratch struct {
+	keys  []uint64
+	buf   []byte
+	runes []rune
+}
+
+var indexScratchPool = sync.Pool{
+	New: func() any {
+		return &indexScratch{keys: make([]uint64, 0, 64)}
+	},
+}
 
-	if len(runes) < idx.gramSize {
+// maxPooledRunes caps the rune buffer returned to indexScratchPool, so one
+// huge document does not pin its buffers for the life of the pool.
+const maxPooledRunes = 1 << 16
+
+// putIndexScratch returns s to the pool unless its buffers grew too large.
+func putIndexScratch(s *indexScratch) {
+	if cap(s.runes) > maxPooledRunes || cap(s.buf) > maxPooledRunes {
 		return
 	}
+	indexScratchPool.Put(s)
+}
 
-	seen := make([]uint64, 0, len(runes)-idx.gramSize+1)
+// appendDocumentKeys appends the unique n-gram keys of text to s.keys and
+// returns them. Uses fast ASCII path when possible, falls back to rune-based
+// for Unicode.
+func (idx *Index) appendDocumentKeys(s *indexScratch, text string) []uint64 {
+	text = idx.indexSynonyms.expandDocument(text)
+	if idx.ascii != nil {
+		var ok bool
+		s.keys, s.buf, ok = normalizeAndKeyASCIIPooled(text, idx.gramSize, s.keys[:0], s.buf, idx.ascii)
+		if ok {
+			return s.keys
+		}
+	}
 
+	s.runes = appendRunes(s.runes[:0], idx.normalizer(text))
+	runes := s.runes
+	s.keys = s.keys[:0]
+	if idx.cjkBigrams {
+		s.keys = appendCJKKeys(s.keys, runes, idx.gramSize, idx.indexKey)
+		return s.keys
+	}
 	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-
-		if containsKey(seen, key) {
-			continue
-		}
-		seen = append(seen, key)
+		s.keys = appendKeyDedup(s.keys, idx.indexKey(runes[i:i+idx.gramSize]))
+	}
+	return s.keys
+}
 
-		idx.getOrCreateBitmap(key).Add(docID)
+// appendRunes appends the runes of s to dst.
+func appendRunes(dst []rune, s string) []rune {
+	for _, r := range s {
+		dst = append(dst, r)
 	}
+	return dst
 }
 
 // Add indexes a document with the given ID and text.
@@ -100,29 +184,37 @@ func (idx *Index) addRuneBasedNgrams(docID uint32, text string) {
 func (idx *Index) Add(docID uint32, text string) {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
+	idx.addLocked(docID, text)
+}
 
-	if idx.useASCIFastPath {
-		keys := make([]uint64, 0, 64)
-		keys, ok := normalizeAndKeyASCII(text, idx.gramSize, keys)
-		if ok {
-			for _, key := range keys {
-				idx.getOrCreateBitmap(key).Add(docID)
-			}
-			return
-		}
-	}
+func (idx *Index) addLocked(docID uint32, text string) {
+	idx.docs.Add(docID)
+
+	scratch := indexScratchPool.Get().(*indexScratch)
+	defer putIndexScratch(scratch)
 
-	idx.addRuneBasedNgrams(docID, text)
+	keys := idx.appendDocumentKeys(scratch, text)
+	for _, key := range keys {
+		idx.getOrCreateBitmap(key).Add(docID)
+	}
+	idx.forward.record(docID, keys)
+	idx.setLengthLocked(docID, docLength(len(keys)))
 }
 
 // addBatch indexes multiple documents efficiently using parallel processing.
-func (idx *Index) addBatch(docs []document) {
-	idx.addBatchN(docs, 0)
+func (idx *Index) addBatch(ctx context.Context, docs []document) error {
+	return idx.addBatchN(ctx, docs, 0)
 }
 
+// cancelCheckInterval is how many documents a batch worker indexes between
+// context checks.
+const cancelCheckInterval = 256
+
 // localIndex holds per-worker bitmap data during batch indexing.
 type localIndex struct {
 	bitmaps map[uint64]*roaring.Bitmap
+	forward forwardIndex // non-nil when the index keeps a forward index
+	lengths []uint16     // length of each document of the chunk; nil unless WithDocLengths
 }
 
 // addKeyToBitmap adds a document ID to the bitmap for the given key.
@@ -135,53 +227,24 @@ func (local *localIndex) addKeyToBitmap(key uint64, docID uint32) {
 	bm.Add(docID)
 }
 
-// processDocASCII processes a document using the fast ASCII path.
-func (idx *Index) processDocASCII(doc document, local *localIndex, keys []uint64, buf []byte) ([]uint64, []byte, bool) {
-	var ok bool
-	keys, buf, ok = normalizeAndKeyASCIIPooled(doc.text, idx.gramSize, keys, buf)
-	if !ok {
-		return keys, buf, false
-	}
+// processDoc indexes a document into the worker's local index.
+func (idx *Index) processDoc(doc document, local *localIndex, scratch *indexScratch) {
+	keys := idx.appendDocumentKeys(scratch, doc.text)
 	for _, key := range keys {
 		local.addKeyToBitmap(key, doc.id)
 	}
-	return keys, buf, true
-}
-
-// processDocUnicode processes a document using rune-based Unicode handling.
-func (idx *Index) processDocUnicode(doc document, local *localIndex, seen []uint64) []uint64 {
-	normalized := idx.normalizer(doc.text)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
-		return seen
-	}
-
-	seen = seen[:0]
-	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if !containsKey(seen, key) {
-			seen = append(seen, key)
-			local.addKeyToBitmap(key, doc.id)
-		}
+	local.forward.record(doc.id, keys)
+	if local.lengths != nil {
+		local.lengths = append(local.lengths, docLength(len(keys)))
 	}
-	return seen
-}
-
-// containsKey checks if key exists in the slice.
-func containsKey(keys []uint64, key uint64) bool {
-	for _, k := range keys {
-		if k == key {
-			return true
-		}
-	}
-	return false
 }
 
 // addBatchN indexes multiple documents with a specified number of workers.
-func (idx *Index) addBatchN(docs []document, workers int) {
+// If ctx is cancelled or a worker fails before the final merge starts, the
+// index is left unchanged and the error is returned.
+func (idx *Index) addBatchN(ctx context.Context, docs []document, workers int) error {
 	if len(docs) == 0 {
-		return
+		return nil
 	}
 	workers = idx.clampWorkers(workers, len(docs))
 
@@ -189,18 +252,48 @@ func (idx *Index) addBatchN(docs []document, workers int) {
 
 	var wg sync.WaitGroup
 	chunkSize := (len(docs) + workers - 1) / workers
+	errs := make([]error, workers)
 
 	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go idx.processChunk(docs, w, chunkSize, &localIndexes[w], &wg)
+		go idx.processChunk(ctx, docs, w, chunkSize, &localIndexes[w], &errs[w], &wg)
 	}
 
 	wg.Wait()
-	idx.mergeLocalIndexes(localIndexes)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	idx.applying.RLock()
+	defer idx.applying.RUnlock()
+	if err := idx.mergeLocalIndexes(ctx, localIndexes); err != nil {
+		return err
+	}
+
+	ids := make([]uint32, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.id
+	}
+	idx.mu.Lock()
+	idx.docs.AddMany(ids)
+	for w := range localIndexes {
+		for i, n := range localIndexes[w].lengths {
+			idx.setLengthLocked(docs[w*chunkSize+i].id, n)
+		}
+	}
+	idx.mu.Unlock()
+	return nil
 }
 
+// deterministicWorkers is the fixed batch partition count used by
+// WithDeterministicBuild, independent of the machine's CPU count.
+const deterministicWorkers = 8
+
 // clampWorkers adjusts worker count based on document count.
 func (idx *Index) clampWorkers(workers, docCount int) int {
+	if idx.deterministic {
+		workers = deterministicWorkers
+	}
 	if workers <= 0 {
 		workers = runtime.NumCPU()
 	}
@@ -224,13 +317,26 @@ func (idx *Index) initLocalIndexes(workers, docCount int) []localIndex {
 	localIndexes := make([]localIndex, workers)
 	for i := range localIndexes {
 		localIndexes[i].bitmaps = make(map[uint64]*roaring.Bitmap, estimatedNgrams)
+		if idx.forward != nil {
+			localIndexes[i].forward = make(forwardIndex, docsPerWorker)
+		}
+		if idx.lengths != nil {
+			localIndexes[i].lengths = make([]uint16, 0, docsPerWorker)
+		}
 	}
 	return localIndexes
 }
 
-// processChunk processes a chunk of documents for a worker.
-func (idx *Index) processChunk(docs []document, workerID, chunkSize int, local *localIndex, wg *sync.WaitGroup) {
+// processChunk processes a chunk of documents for a worker. Cancellation is
+// checked every cancelCheckInterval documents; panics (e.g. from a custom
+// normalizer) are reported through err rather than crashing the process.
+func (idx *Index) processChunk(ctx context.Context, docs []document, workerID, chunkSize int, local *localIndex, err *error, wg *sync.WaitGroup) {
 	defer wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			*err = fmt.Errorf("index worker %d: %v", workerID, r)
+		}
+	}()
 
 	start := workerID * chunkSize
 	end := start + chunkSize
@@ -241,31 +347,36 @@ func (idx *Index) processChunk(docs []document, workerID, chunkSize int, local *
 		return
 	}
 
-	keys := make([]uint64, 0, 64)
-	buf := make([]byte, 0, 256)
-	seen := make([]uint64, 0, 64)
+	scratch := &indexScratch{
+		keys: make([]uint64, 0, 64),
+		buf:  make([]byte, 0, 256),
+	}
 
-	for _, doc := range docs[start:end] {
-		if idx.useASCIFastPath {
-			var ok bool
-			keys, buf, ok = idx.processDocASCII(doc, local, keys, buf)
-			if ok {
-				continue
+	for i, doc := range docs[start:end] {
+		if i%cancelCheckInterval == 0 {
+			if *err = ctx.Err(); *err != nil {
+				return
 			}
 		}
-		seen = idx.processDocUnicode(doc, local, seen)
+		idx.processDoc(doc, local, scratch)
 	}
 }
 
 // mergeLocalIndexes merges all local indexes into the main index.
 // Uses parallel pairwise reduction for better performance with many workers.
-func (idx *Index) mergeLocalIndexes(localIndexes []localIndex) {
+// Cancellation is honored between reduction rounds; once the final merge into
+// the index starts it runs to completion so the index is never left half-merged.
+func (idx *Index) mergeLocalIndexes(ctx context.Context, localIndexes []localIndex) error {
 	if len(localIndexes) == 0 {
-		return
+		return nil
 	}
 
 	// Parallel pairwise reduction: 16 -> 8 -> 4 -> 2 -> 1
 	for len(localIndexes) > 1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		half := (len(localIndexes) + 1) / 2
 		var wg sync.WaitGroup
 
@@ -279,6 +390,17 @@ func (idx *Index) mergeLocalIndexes(localIndexes []localIndex) {
 		wg.Wait()
 		localIndexes = localIndexes[:half]
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if forward := localIndexes[0].forward; forward != nil {
+		idx.mu.Lock()
+		for docID, keys := range forward {
+			idx.forward.record(docID, keys)
+		}
+		idx.mu.Unlock()
+	}
 
 	// Final merge into main index - incremental to allow reads between batches
 	local := localIndexes[0].bitmaps
@@ -297,7 +419,7 @@ func (idx *Index) mergeLocalIndexes(localIndexes []localIndex) {
 		idx.mu.Lock()
 		for _, key := range keys[i:end] {
 			localBm := local[key]
-			if bm, ok := idx.bitmaps[key]; ok {
+			if bm, ok := idx.writableBitmapLocked(key); ok {
 				bm.Or(localBm)
 			} else {
 				idx.bitmaps[key] = localBm
@@ -306,6 +428,7 @@ func (idx *Index) mergeLocalIndexes(localIndexes []localIndex) {
 		}
 		idx.mu.Unlock()
 	}
+	return nil
 }
 
 // mergeTwoLocals merges src into dst.
@@ -317,62 +440,332 @@ func mergeTwoLocals(dst, src *localIndex) {
 			dst.bitmaps[key] = srcBm
 		}
 	}
+	for docID, keys := range src.forward {
+		dst.forward.record(docID, keys)
+	}
 }
 
 // document represents a document to be indexed (internal use).
 type document struct {
 	id   uint32
 	text string
+	seq  uint64 // order of the Add within an IndexBatch
 }
 
 // IndexBatch accumulates documents for efficient batch insertion.
+// It is safe for concurrent use: Add spreads documents over internally
+// sharded buffers so multiple producer goroutines do not contend on one lock.
 type IndexBatch struct {
-	idx  *Index
+	idx    *Index
+	shards []batchShard
+	next   atomic.Uint64 // Adds so far; orders documents and selects the shard
+
+	docs  atomic.Int64 // buffered documents across all shards
+	bytes atomic.Int64 // buffered text bytes across all shards
+
+	maxDocs  int
+	maxBytes int
+
+	flushing chan struct{} // holds a token while a flush is being applied
+	inflight sync.WaitGroup
+
+	errMu    sync.Mutex
+	asyncErr error // first error from FlushAsync since the last Wait
+}
+
+// batchShard is one producer buffer of an IndexBatch.
+type batchShard struct {
+	mu   sync.Mutex
 	docs []document
+	_    [32]byte // pad to a cache line so shards do not false-share
+}
+
+// BatchOption configures an IndexBatch.
+type BatchOption func(*IndexBatch)
+
+// WithAutoFlush flushes the batch asynchronously whenever it holds n documents.
+func WithAutoFlush(n int) BatchOption {
+	return func(b *IndexBatch) {
+		b.maxDocs = n
+	}
+}
+
+// WithAutoFlushBytes flushes the batch asynchronously whenever its buffered
+// text reaches n bytes.
+func WithAutoFlushBytes(n int) BatchOption {
+	return func(b *IndexBatch) {
+		b.maxBytes = n
+	}
+}
+
+// WithBatchShards sets the number of internal buffers used by concurrent
+// producers. Default is runtime.GOMAXPROCS(0).
+func WithBatchShards(n int) BatchOption {
+	return func(b *IndexBatch) {
+		if n > 0 {
+			b.shards = make([]batchShard, n)
+		}
+	}
 }
 
 // Batch creates a new batch builder for this index.
 // Use BatchSize for better performance when you know the approximate count.
-func (idx *Index) Batch() *IndexBatch {
-	return idx.BatchSize(1024)
+func (idx *Index) Batch(opts ...BatchOption) *IndexBatch {
+	return idx.BatchSize(1024, opts...)
 }
 
 // BatchSize creates a batch builder with pre-allocated capacity.
-func (idx *Index) BatchSize(size int) *IndexBatch {
-	return &IndexBatch{
-		idx:  idx,
-		docs: make([]document, 0, size),
+func (idx *Index) BatchSize(size int, opts ...BatchOption) *IndexBatch {
+	b := &IndexBatch{
+		idx:      idx,
+		flushing: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.shards == nil {
+		b.shards = make([]batchShard, runtime.GOMAXPROCS(0))
 	}
+
+	perShard := (size + len(b.shards) - 1) / len(b.shards)
+	for i := range b.shards {
+		b.shards[i].docs = make([]document, 0, perShard)
+	}
+	return b
 }
 
-// Add adds a document to the batch.
+// Add adds a document to the batch. With WithAutoFlush or WithAutoFlushBytes
+// set, reaching a threshold starts a FlushAsync, which blocks while a previous
+// flush is still being applied so producers cannot outrun indexing.
 func (b *IndexBatch) Add(docID uint32, text string) {
-	b.docs = append(b.docs, document{id: docID, text: text})
+	seq := b.next.Add(1)
+	shard := &b.shards[seq%uint64(len(b.shards))]
+	shard.mu.Lock()
+	shard.docs = append(shard.docs, document{id: docID, text: text, seq: seq})
+	shard.mu.Unlock()
+
+	docs := b.docs.Add(1)
+	bytes := b.bytes.Add(int64(len(text)))
+	if (b.maxDocs > 0 && docs >= int64(b.maxDocs)) || (b.maxBytes > 0 && bytes >= int64(b.maxBytes)) {
+		b.FlushAsync()
+	}
+}
+
+// Len returns the number of buffered documents.
+func (b *IndexBatch) Len() int {
+	return int(b.docs.Load())
+}
+
+// drain takes every buffered document out of the shards, in the order they
+// were added, so a docID added twice ends up as if added twice by Add.
+func (b *IndexBatch) drain() []document {
+	docs := make([]document, 0, b.Len())
+	for i := range b.shards {
+		shard := &b.shards[i]
+		shard.mu.Lock()
+		docs = append(docs, shard.docs...)
+		shard.docs = shard.docs[:0]
+		shard.mu.Unlock()
+	}
+	slices.SortFunc(docs, func(a, b document) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	b.docs.Add(-int64(len(docs)))
+	var bytes int64
+	for _, doc := range docs {
+		bytes += int64(len(doc.text))
+	}
+	b.bytes.Add(-bytes)
+	return docs
+}
+
+// restore puts documents back into the batch after a failed flush.
+func (b *IndexBatch) restore(docs []document) {
+	shard := &b.shards[0]
+	shard.mu.Lock()
+	shard.docs = append(shard.docs, docs...)
+	shard.mu.Unlock()
+
+	var bytes int64
+	for _, doc := range docs {
+		bytes += int64(len(doc.text))
+	}
+	b.docs.Add(int64(len(docs)))
+	b.bytes.Add(bytes)
 }
 
 // Flush commits all accumulated documents to the index using parallel processing.
+// It waits for any asynchronous flush to finish first, so when Flush returns
+// every document added before the call is searchable.
+// Use FlushContext to cancel long flushes or observe errors.
 func (b *IndexBatch) Flush() {
-	if len(b.docs) == 0 {
+	_ = b.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush but can be cancelled through ctx and returns
+// any error from the parallel workers. If it fails before the final merge
+// into the index starts, the index is unchanged and the documents stay
+// buffered in the batch so the flush can be retried.
+func (b *IndexBatch) FlushContext(ctx context.Context) error {
+	docs := b.drain()
+
+	select {
+	case b.flushing <- struct{}{}:
+	case <-ctx.Done():
+		b.restore(docs)
+		return ctx.Err()
+	}
+	defer func() { <-b.flushing }()
+
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := b.idx.addBatch(ctx, docs); err != nil {
+		b.restore(docs)
+		return err
+	}
+	return nil
+}
+
+// FlushAsync commits the accumulated documents in a background goroutine so
+// normalization and merging overlap with continued Adds. Only one flush is
+// applied at a time; FlushAsync blocks while a previous one is still running.
+// Use Wait or Flush to wait for completion.
+func (b *IndexBatch) FlushAsync() {
+	docs := b.drain()
+	if len(docs) == 0 {
 		return
 	}
 
-	b.idx.addBatch(b.docs)
+	b.flushing <- struct{}{}
+	b.inflight.Add(1)
+	go func() {
+		defer b.inflight.Done()
+		defer func() { <-b.flushing }()
+		if err := b.idx.addBatch(context.Background(), docs); err != nil {
+			b.errMu.Lock()
+			if b.asyncErr == nil {
+				b.asyncErr = err
+			}
+			b.errMu.Unlock()
+		}
+	}()
+}
 
-	// Clear for reuse
-	b.docs = b.docs[:0]
+// Wait blocks until every FlushAsync started so far has been applied and
+// returns the first error any of them reported since the previous Wait.
+func (b *IndexBatch) Wait() error {
+	b.inflight.Wait()
+
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	err := b.asyncErr
+	b.asyncErr = nil
+	return err
 }
 
 // Remove removes a document from the index.
 func (idx *Index) Remove(docID uint32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(docID)
+}
+
+// removeLocked removes a document from its bitmaps. With a forward index only
+// the document's own n-grams are visited; otherwise every bitmap is scanned.
+func (idx *Index) removeLocked(docID uint32) {
+	if idx.lengths != nil {
+		idx.removeLengthsLocked(roaring.BitmapOf(docID))
+	}
+	if idx.forward != nil {
+		for _, key := range idx.forward[docID] {
+			if bm, ok := idx.writableBitmapLocked(key); ok {
+				bm.Remove(docID)
+				if bm.IsEmpty() {
+					delete(idx.bitmaps, key)
+				}
+			}
+		}
+		delete(idx.forward, docID)
+	} else {
+		for key, bm := range idx.bitmaps {
+			if !bm.Contains(docID) {
+				continue
+			}
+			bm, _ = idx.writableBitmapLocked(key)
+			bm.Remove(docID)
+			if bm.IsEmpty() {
+				delete(idx.bitmaps, key)
+			}
+		}
+	}
+	idx.docs.Remove(docID)
+}
+
+// Update replaces a document's text: its old n-gram memberships are removed
+// and the new ones added under a single lock, so searches never observe the
+// document half-updated. Use WithForwardIndex to make updates proportional to
+// the document's size rather than the number of n-grams in the index.
+func (idx *Index) Update(docID uint32, text string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(docID)
+	idx.addLocked(docID, text)
+}
+
+// AndNot removes every document in docs from the index.
+// Unlike calling Remove per document, each n-gram bitmap is visited once;
+// with a forward index only the removed documents' n-grams are visited.
+func (idx *Index) AndNot(docs *roaring.Bitmap) {
+	if docs == nil || docs.IsEmpty() {
+		return
+	}
+
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	for key, bm := range idx.bitmaps {
-		bm.Remove(docID)
-		if bm.IsEmpty() {
-			delete(idx.bitmaps, key)
+	if idx.forward != nil {
+		// Only the removed documents' own n-grams can change
+		for key := range idx.forward.keysOf(docs) {
+			if bm, ok := idx.writableBitmapLocked(key); ok {
+				bm.AndNot(docs)
+				if bm.IsEmpty() {
+					delete(idx.bitmaps, key)
+				}
+			}
+		}
+		idx.forward.removeDocs(docs)
+	} else {
+		for key, bm := range idx.bitmaps {
+			if !bm.Intersects(docs) {
+				continue
+			}
+			bm, _ = idx.writableBitmapLocked(key)
+			bm.AndNot(docs)
+			if bm.IsEmpty() {
+				delete(idx.bitmaps, key)
+			}
 		}
 	}
+	idx.docs.AndNot(docs)
+	idx.removeLengthsLocked(docs)
+}
+
+// RemoveMany removes a batch of documents with one AndNot per stored bitmap,
+// instead of one full scan per document as with Remove.
+func (idx *Index) RemoveMany(ids []uint32) {
+	if len(ids) == 0 {
+		return
+	}
+	idx.AndNot(roaring.BitmapOf(ids...))
+}
+
+// SubtractDocs removes a set of documents from the index in one pass.
+// It is equivalent to RemoveMany.
+func (idx *Index) SubtractDocs(ids []uint32) {
+	idx.RemoveMany(ids)
 }
 
 // Clear removes all documents from the index.
@@ -380,77 +773,194 @@ func (idx *Index) Clear() {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 	idx.bitmaps = make(map[uint64]*roaring.Bitmap)
+	idx.shared = nil
+	idx.generation++
+	idx.docs = roaring.New()
+	if idx.forward != nil {
+		idx.forward = make(forwardIndex)
+	}
+	if idx.exact != nil {
+		idx.exact = newNgramDict()
+	}
+	if idx.lengths != nil {
+		idx.lengths = NewSortColumn[uint16]()
+		idx.lengthSum, idx.lengthCount = 0, 0
+	}
 }
 
 // Search performs an AND search for documents containing all n-grams of the query.
 // Uses rune-based n-gram generation for consistent Unicode support.
-func (idx *Index) Search(query string) []uint32 {
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
+func (idx *Index) Search(query string) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("Search", query, len(matches), start) }(time.Now())
+	}
+	if variants := idx.synonyms.expand(query); variants != nil {
+		if bm := idx.searchVariants(variants); !bm.IsEmpty() {
+			return limitedArray(bm, idx.maxResults)
+		}
+		return nil
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return nil
 	}
 
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
+	return idx.searchLocked(keys)
+}
 
-	bitmaps := make([]*roaring.Bitmap, 0, len(runes)-idx.gramSize+1)
-	seen := make(map[uint64]struct{})
+// MatchAll returns a bitmap of every indexed document: the result of a query
+// with no text. Intersect it with filters and sort it like any search result.
+func (idx *Index) MatchAll() *roaring.Bitmap {
+	return idx.AllDocs()
+}
 
-	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if _, ok := seen[key]; ok {
-			continue
-		}
-		seen[key] = struct{}{}
-		bm, ok := idx.bitmaps[key]
-		if !ok {
-			return nil
-		}
-		bitmaps = append(bitmaps, bm)
+// SearchBitmap is like Search but returns the matches as a bitmap ready to
+// intersect with BitmapFilter categories or pass to SortColumn. A query that
+// is empty after normalization matches every document (see MatchAll), so an
+// empty search box needs no separate code path.
+func (idx *Index) SearchBitmap(query string) (matches *roaring.Bitmap) {
+	if idx.stats != nil {
+		defer func(start time.Time) {
+			idx.recordQuery("SearchBitmap", query, int(matches.GetCardinality()), start)
+		}(time.Now())
+	}
+	if strings.TrimSpace(idx.normalizer(query)) == "" {
+		return idx.MatchAll()
+	}
+	if variants := idx.synonyms.expand(query); variants != nil {
+		return idx.searchVariants(variants)
 	}
 
-	if len(bitmaps) == 0 {
-		return nil
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
+		return roaring.New()
 	}
 
-	if len(bitmaps) == 1 {
-		return bitmaps[0].ToArray()
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.searchBitmapLocked(keys)
+}
+
+// searchBitmapLocked runs an AND search for unique query keys, returning a
+// bitmap the caller owns.
+func (idx *Index) searchBitmapLocked(keys []uint64) *roaring.Bitmap {
+	if idx.results != nil {
+		return idx.cachedResultLocked(keys).Clone()
 	}
+	return idx.intersectKeysLocked(keys)
+}
 
-	// Sort by cardinality for better performance
-	sort.Slice(bitmaps, func(i, j int) bool {
-		return bitmaps[i].GetCardinality() < bitmaps[j].GetCardinality()
-	})
+// intersectKeysLocked is searchBitmapLocked without the result cache.
+func (idx *Index) intersectKeysLocked(keys []uint64) *roaring.Bitmap {
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectLocked(idx, keys) {
+		if idx.missingNgrams == MissingNgramFail {
+			return roaring.New()
+		}
+		scratch.collectPresentLocked(idx, keys)
+		bm := partialBitmap(idx.missingNgrams, scratch.bitmaps, idx.gramSize)
+		if bm == nil {
+			return roaring.New()
+		}
+		if len(scratch.bitmaps) == 1 {
+			return bm.Clone()
+		}
+		return bm
+	}
+	if len(scratch.bitmaps) == 1 {
+		return scratch.bitmaps[0].Clone()
+	}
+	return roaring.FastAnd(scratch.bitmaps...)
+}
 
-	result := roaring.FastAnd(bitmaps...)
-	if result == nil || result.IsEmpty() {
+// SearchAllTerms returns the documents containing every whitespace-separated
+// term of the query, in any order and position. Each term must match all of
+// its own n-grams, but n-grams spanning two terms are not required, so
+// "fox brown" finds "the brown fox". Terms shorter than the gram size are
+// ignored; a query with no usable term matches nothing.
+func (idx *Index) SearchAllTerms(query string) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchAllTerms", query, len(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendTermKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return nil
 	}
 
-	return result.ToArray()
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.searchLocked(keys)
+}
+
+// appendTermKeys appends the unique n-gram keys of each whitespace-separated
+// term of the query to keys. Terms are split before normalization, since
+// normalizers such as the default drop whitespace. Requiring every key of
+// every term is the AND of the per-term AND groups.
+func (idx *Index) appendTermKeys(keys []uint64, query string) []uint64 {
+	for _, term := range strings.Fields(query) {
+		keys = idx.appendQueryKeys(keys, term)
+	}
+	return keys
 }
 
-// collectQueryBitmaps collects bitmaps for query n-grams.
-// Returns nil if any n-gram is not found in the index.
-func (idx *Index) collectQueryBitmaps(runes []rune) []*roaring.Bitmap {
-	bitmaps := make([]*roaring.Bitmap, 0, len(runes)-idx.gramSize+1)
-	seen := make(map[uint64]struct{})
+// queryKeyBufSize sizes the stack buffer for query keys; longer queries
+// spill to the heap.
+const queryKeyBufSize = 32
+
+// appendQueryKeys appends the unique n-gram keys of a query to keys, in query
+// order. Nothing is appended when the normalized query is shorter than the
+// gram size. ASCII queries take the same allocation-free path as Add when the
+// normalizer allows it.
+func (idx *Index) appendQueryKeys(keys []uint64, query string) []uint64 {
+	if idx.ascii != nil {
+		var buf [128]byte
+		var ok bool
+		if keys, _, ok = normalizeAndKeyASCIIPooled(query, idx.gramSize, keys, buf[:0], idx.ascii); ok {
+			return keys
+		}
+	}
 
+	runes := []rune(idx.normalizer(query))
+	if idx.cjkBigrams {
+		return appendCJKKeys(keys, runes, idx.gramSize, idx.queryKey)
+	}
 	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if _, ok := seen[key]; ok {
-			continue
-		}
-		seen[key] = struct{}{}
-		bm, ok := idx.bitmaps[key]
-		if !ok {
-			return nil
+		keys = appendKeyDedup(keys, idx.queryKey(runes[i:i+idx.gramSize]))
+	}
+	return keys
+}
+
+// sortByCardinality orders bitmaps smallest first, so intersections start
+// from the most selective n-gram.
+func sortByCardinality(bitmaps []*roaring.Bitmap) {
+	slices.SortFunc(bitmaps, func(a, b *roaring.Bitmap) int {
+		return cmp.Compare(a.GetCardinality(), b.GetCardinality())
+	})
+}
+
+// searchLocked runs an AND search for unique query keys.
+func (idx *Index) searchLocked(keys []uint64) []uint32 {
+	if idx.results != nil {
+		if bm := idx.cachedResultLocked(keys); !bm.IsEmpty() {
+			return limitedArray(bm, idx.maxResults)
 		}
-		bitmaps = append(bitmaps, bm)
+		return nil
 	}
-	return bitmaps
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+
+	matches := scratch.appendMatchesLocked(idx, keys, nil)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
 }
 
 // existsInAllBitmaps returns true if docID exists in all bitmaps.
@@ -463,44 +973,70 @@ func existsInAllBitmaps(docID uint32, bitmaps []*roaring.Bitmap) bool {
 	return true
 }
 
+// Batch sizes of appendProbedMatches: enough candidates to amortize an
+// intersection, but not so many that a small limit probes far past its end.
+const (
+	minProbeBatch = 64
+	maxProbeBatch = 4096
+)
+
+// appendProbedMatches appends to dst the lowest docIDs, up to limit in all,
+// of smallest that are in every bitmap of rest. Rather than one Contains per
+// candidate and bitmap, candidates are taken from smallest in sorted runs,
+// materialized as a small bitmap and intersected with each bitmap of rest in
+// bulk, so only the containers a run overlaps are visited.
+func appendProbedMatches(dst []uint32, smallest *roaring.Bitmap, rest []*roaring.Bitmap, limit int) []uint32 {
+	it := smallest.ManyIterator()
+	buf := make([]uint32, min(max(limit, minProbeBatch), maxProbeBatch))
+	batch := roaring.New()
+	for len(dst) < limit {
+		// Probe about as many candidates as matches are still wanted
+		n := it.NextMany(buf[:min(max(limit-len(dst), minProbeBatch), len(buf))])
+		if n == 0 {
+			break
+		}
+		batch.Clear()
+		batch.AddMany(buf[:n])
+		for _, bm := range rest {
+			batch.And(bm)
+			if batch.IsEmpty() {
+				break
+			}
+		}
+		want := min(limit-len(dst), int(batch.GetCardinality()))
+		dst = slices.Grow(dst, want)
+		dst = dst[:len(dst)+batch.ManyIterator().NextMany(dst[len(dst):len(dst)+want])]
+	}
+	return dst
+}
+
 // SearchWithLimit returns up to limit matching document IDs.
 // This can be faster than Search when you only need a subset of results.
-func (idx *Index) SearchWithLimit(query string, limit int) []uint32 {
+func (idx *Index) SearchWithLimit(query string, limit int) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchWithLimit", query, len(matches), start) }(time.Now())
+	}
 	if limit <= 0 {
 		return nil
 	}
 
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return nil
 	}
 
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
-	bitmaps := idx.collectQueryBitmaps(runes)
-	if len(bitmaps) == 0 {
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectMatchingLocked(idx, keys) {
 		return nil
 	}
+	bitmaps := scratch.bitmaps
 
-	sort.Slice(bitmaps, func(i, j int) bool {
-		return bitmaps[i].GetCardinality() < bitmaps[j].GetCardinality()
-	})
-
-	results := make([]uint32, 0, limit)
-	smallest := bitmaps[0]
-	rest := bitmaps[1:]
-
-	it := smallest.Iterator()
-	for it.HasNext() && len(results) < limit {
-		docID := it.Next()
-		if existsInAllBitmaps(docID, rest) {
-			results = append(results, docID)
-		}
-	}
-
+	results := appendProbedMatches(make([]uint32, 0, min(limit, 1024)), bitmaps[0], bitmaps[1:], limit)
 	if len(results) == 0 {
 		return nil
 	}
@@ -516,24 +1052,30 @@ func (idx *Index) SearchWithLimit(query string, limit int) []uint32 {
 // only need a subset of results without allocating a slice.
 // For iterating ALL results, use SearchIterateResults which uses FastAnd.
 func (idx *Index) SearchCallback(query string, cb func(docID uint32) bool) bool {
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
+	if idx.stats != nil {
+		visited := 0
+		inner := cb
+		cb = func(docID uint32) bool {
+			visited++
+			return inner(docID)
+		}
+		defer func(start time.Time) { idx.recordQuery("SearchCallback", query, visited, start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return true
 	}
 
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
-	bitmaps := idx.collectQueryBitmaps(runes)
-	if len(bitmaps) == 0 {
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectMatchingLocked(idx, keys) {
 		return true
 	}
-
-	sort.Slice(bitmaps, func(i, j int) bool {
-		return bitmaps[i].GetCardinality() < bitmaps[j].GetCardinality()
-	})
+	bitmaps := scratch.bitmaps
 
 	smallest := bitmaps[0]
 	rest := bitmaps[1:]
@@ -552,58 +1094,32 @@ func (idx *Index) SearchCallback(query string, cb func(docID uint32) bool) bool
 }
 
 // SearchCount returns the count of matching documents without allocating a result slice.
-func (idx *Index) SearchCount(query string) uint64 {
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
+func (idx *Index) SearchCount(query string) (matches uint64) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchCount", query, int(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return 0
 	}
 
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
-	bitmaps := make([]*roaring.Bitmap, 0, len(runes)-idx.gramSize+1)
-	seen := make(map[uint64]struct{})
-
-	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if _, ok := seen[key]; ok {
-			continue
-		}
-		seen[key] = struct{}{}
-		bm, ok := idx.bitmaps[key]
-		if !ok {
-			return 0
-		}
-		bitmaps = append(bitmaps, bm)
-	}
-
-	if len(bitmaps) == 0 {
-		return 0
-	}
-
-	if len(bitmaps) == 1 {
-		return bitmaps[0].GetCardinality()
-	}
-
-	sort.Slice(bitmaps, func(i, j int) bool {
-		return bitmaps[i].GetCardinality() < bitmaps[j].GetCardinality()
-	})
-
-	result := roaring.FastAnd(bitmaps...)
-	if result == nil {
-		return 0
-	}
-	return result.GetCardinality()
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	return scratch.countLocked(idx, keys)
 }
 
 // SearchAny returns documents containing any n-gram of the query (OR search).
-func (idx *Index) SearchAny(query string) []uint32 {
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
+func (idx *Index) SearchAny(query string) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchAny", query, len(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return nil
 	}
 
@@ -611,15 +1127,8 @@ func (idx *Index) SearchAny(query string) []uint32 {
 	defer idx.mu.RUnlock()
 
 	result := roaring.New()
-	seen := make(map[uint64]struct{})
-
-	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if _, ok := seen[key]; ok {
-			continue
-		}
-		seen[key] = struct{}{}
-		if bm, ok := idx.bitmaps[key]; ok {
+	for _, key := range keys {
+		if bm, ok := idx.bitmapLocked(key); ok {
 			result.Or(bm)
 		}
 	}
@@ -628,112 +1137,199 @@ func (idx *Index) SearchAny(query string) []uint32 {
 		return nil
 	}
 
-	return result.ToArray()
+	return limitedArray(result, idx.maxResults)
 }
 
 // SearchAnyCount returns the count of documents matching any n-gram (OR search).
-func (idx *Index) SearchAnyCount(query string) uint64 {
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
-
-	if len(runes) < idx.gramSize {
+func (idx *Index) SearchAnyCount(query string) (matches uint64) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchAnyCount", query, int(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
 		return 0
 	}
 
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
-	result := roaring.New()
-	seen := make(map[uint64]struct{})
-
-	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if _, ok := seen[key]; ok {
-			continue
-		}
-		seen[key] = struct{}{}
-		if bm, ok := idx.bitmaps[key]; ok {
-			result.Or(bm)
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	for _, key := range keys {
+		if bm, ok := idx.bitmapLocked(key); ok {
+			scratch.result.Or(bm)
 		}
 	}
 
-	return result.GetCardinality()
+	return scratch.result.GetCardinality()
 }
 
-// collectExistingQueryBitmaps collects bitmaps for query n-grams that exist in the index.
-// Unlike collectQueryBitmaps, this doesn't return nil on missing n-grams.
-func (idx *Index) collectExistingQueryBitmaps(runes []rune) []*roaring.Bitmap {
-	bitmaps := make([]*roaring.Bitmap, 0, len(runes)-idx.gramSize+1)
-	seen := make(map[uint64]struct{})
-
-	for i := 0; i <= len(runes)-idx.gramSize; i++ {
-		key := runeNgramKey(runes[i : i+idx.gramSize])
-		if _, ok := seen[key]; ok {
-			continue
-		}
-		seen[key] = struct{}{}
-		if bm, ok := idx.bitmaps[key]; ok {
+// collectExistingQueryBitmaps collects bitmaps for query keys that exist in the index.
+func (idx *Index) collectExistingQueryBitmaps(keys []uint64) []*roaring.Bitmap {
+	bitmaps := make([]*roaring.Bitmap, 0, len(keys))
+	for _, key := range keys {
+		if bm, ok := idx.bitmapLocked(key); ok {
 			bitmaps = append(bitmaps, bm)
 		}
 	}
 	return bitmaps
 }
 
-// countBitmapMatches counts how many bitmaps each document appears in.
-func countBitmapMatches(bitmaps []*roaring.Bitmap) map[uint32]int {
-	counts := make(map[uint32]int)
+// matchLevels layers bitmaps by match count: levels[c] holds the documents
+// that appear in at least c+1 of the input bitmaps. Each input is folded in
+// with one AND and one OR per level, so memory stays bounded by the bitmaps
+// themselves rather than by a per-document counter map.
+func matchLevels(bitmaps []*roaring.Bitmap) []*roaring.Bitmap {
+	levels := make([]*roaring.Bitmap, 0, len(bitmaps))
 	for _, bm := range bitmaps {
-		it := bm.Iterator()
+		levels = append(levels, roaring.New())
+		for c := len(levels) - 1; c > 0; c-- {
+			if levels[c-1].IsEmpty() {
+				continue
+			}
+			levels[c].Or(roaring.And(levels[c-1], bm))
+		}
+		levels[0].Or(bm)
+	}
+
+	// Trim empty top levels
+	for len(levels) > 0 && levels[len(levels)-1].IsEmpty() {
+		levels = levels[:len(levels)-1]
+	}
+	return levels
+}
+
+// walkThreshold calls fn for each document matching at least threshold bitmaps,
+// ordered by score descending, then docID ascending, until fn returns false.
+func walkThreshold(levels []*roaring.Bitmap, threshold int, fn func(docID uint32, score int) bool) {
+	for c := len(levels) - 1; c >= threshold-1; c-- {
+		exact := levels[c]
+		if c+1 < len(levels) {
+			exact = roaring.AndNot(levels[c], levels[c+1])
+		}
+		it := exact.Iterator()
 		for it.HasNext() {
-			counts[it.Next()]++
+			if !fn(it.Next(), c+1) {
+				return
+			}
 		}
 	}
-	return counts
 }
 
-// SearchThreshold returns documents containing at least threshold n-grams of the query.
-// Results include scores indicating how many n-grams matched for each document.
-func (idx *Index) SearchThreshold(query string, threshold int) SearchResult {
-	normalized := idx.normalizer(query)
-	runes := []rune(normalized)
+// thresholdResult builds a SearchResult from matchLevels output for at most
+// limit documents matching at least threshold bitmaps (limit <= 0 means all).
+// Documents come out ordered by score descending, then docID ascending, without a sort.
+func thresholdResult(levels []*roaring.Bitmap, threshold, limit int) SearchResult {
+	if threshold > len(levels) {
+		return SearchResult{}
+	}
 
-	if len(runes) < idx.gramSize || threshold <= 0 {
+	total := levels[threshold-1].GetCardinality()
+	if total == 0 {
 		return SearchResult{}
 	}
+	if limit > 0 && uint64(limit) < total {
+		total = uint64(limit)
+	}
 
-	idx.mu.RLock()
-	defer idx.mu.RUnlock()
+	docIDs := make([]uint32, 0, total)
+	scores := make(map[uint32]int, total)
+
+	walkThreshold(levels, threshold, func(docID uint32, score int) bool {
+		docIDs = append(docIDs, docID)
+		scores[docID] = score
+		return uint64(len(docIDs)) < total
+	})
+
+	return SearchResult{
+		DocIDs: docIDs,
+		Scores: scores,
+	}
+}
 
-	bitmaps := idx.collectExistingQueryBitmaps(runes)
+// thresholdLevelsLocked returns the match levels for a query and the threshold
+// clamped to the number of query n-grams present in the index.
+// Returns nil levels when nothing can match.
+func (idx *Index) thresholdLevelsLocked(keys []uint64, threshold int) ([]*roaring.Bitmap, int) {
+	bitmaps := idx.collectExistingQueryBitmaps(keys)
 	if len(bitmaps) == 0 {
-		return SearchResult{}
+		return nil, 0
 	}
 
 	if threshold > len(bitmaps) {
 		threshold = len(bitmaps)
 	}
 
-	counts := countBitmapMatches(bitmaps)
+	levels := matchLevels(bitmaps)
+	if threshold > len(levels) {
+		return nil, 0
+	}
+	return levels, threshold
+}
 
-	var docIDs []uint32
-	scores := make(map[uint32]int)
+// SearchThreshold returns documents containing at least threshold n-grams of the query.
+// Results include scores indicating how many n-grams matched for each document.
+func (idx *Index) SearchThreshold(query string, threshold int) (result SearchResult) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchThreshold", query, len(result.DocIDs), start) }(time.Now())
+	}
+	return idx.searchThreshold(query, threshold, 0)
+}
 
-	for docID, count := range counts {
-		if count >= threshold {
-			docIDs = append(docIDs, docID)
-			scores[docID] = count
-		}
+// SearchThresholdTopK returns at most k documents containing at least threshold
+// n-grams of the query, best scores first (ties by ascending docID).
+// Only the returned documents are materialized; k <= 0 returns all matches.
+func (idx *Index) SearchThresholdTopK(query string, threshold, k int) (result SearchResult) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchThresholdTopK", query, len(result.DocIDs), start) }(time.Now())
+	}
+	return idx.searchThreshold(query, threshold, k)
+}
+
+func (idx *Index) searchThreshold(query string, threshold, k int) SearchResult {
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 || threshold <= 0 {
+		return SearchResult{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	levels, threshold := idx.thresholdLevelsLocked(keys, threshold)
+	if levels == nil {
+		return SearchResult{}
 	}
+	return thresholdResult(levels, threshold, k)
+}
 
-	sort.Slice(docIDs, func(i, j int) bool {
-		if scores[docIDs[i]] != scores[docIDs[j]] {
-			return scores[docIDs[i]] > scores[docIDs[j]]
+// SearchThresholdCallback streams documents containing at least threshold
+// n-grams of the query to fn, best scores first (ties by ascending docID),
+// until fn returns false. The index is read-locked while fn runs, so fn must
+// not modify the index.
+func (idx *Index) SearchThresholdCallback(query string, threshold int, fn func(docID uint32, score int) bool) {
+	if idx.stats != nil {
+		visited := 0
+		inner := fn
+		fn = func(docID uint32, score int) bool {
+			visited++
+			return inner(docID, score)
 		}
-		return docIDs[i] < docIDs[j]
-	})
+		defer func(start time.Time) { idx.recordQuery("SearchThresholdCallback", query, visited, start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 || threshold <= 0 {
+		return
+	}
 
-	return SearchResult{
-		DocIDs: docIDs,
-		Scores: scores,
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	levels, threshold := idx.thresholdLevelsLocked(keys, threshold)
+	if levels == nil {
+		return
 	}
+	walkThreshold(levels, threshold, fn)
 }
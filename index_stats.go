@@ -0,0 +1,208 @@
+package roaringsearch
+
+import (
+	"container/heap"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+// NgramCardinality holds an n-gram key and the number of documents containing it.
+type NgramCardinality struct {
+	Key         uint64
+	Cardinality uint64
+}
+
+// IndexStats summarizes the shape of an index for capacity planning.
+type IndexStats struct {
+	GramSize        int
+	Docs            uint64  // documents in the index
+	Ngrams          int     // distinct n-grams
+	Postings        uint64  // sum of all n-gram bitmap cardinalities
+	MinCardinality  uint64  // smallest n-gram bitmap
+	MaxCardinality  uint64  // largest n-gram bitmap
+	AvgCardinality  float64 // mean n-gram bitmap cardinality
+	MemoryBytes     uint64  // see MemoryUsage
+	SerializedBytes uint64  // size WriteTo would produce
+	Heaviest        []NgramCardinality
+	Latency         LatencyStats // see SearchLatency; zero unless WithLatencyHistogram
+}
+
+// MemoryUsage returns the memory used by the index's bitmaps in bytes,
+// including the forward index when enabled. Map overhead is not counted.
+func (idx *Index) MemoryUsage() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.memoryUsageLocked()
+}
+
+func (idx *Index) memoryUsageLocked() uint64 {
+	total := idx.docs.GetSizeInBytes()
+	for _, bm := range idx.bitmaps {
+		total += bm.GetSizeInBytes()
+	}
+	for _, keys := range idx.forward {
+		total += uint64(unsafe.Sizeof(keys)) + uint64(cap(keys))*8
+	}
+	return total
+}
+
+// Stats returns size statistics for the index, with the topN n-grams that
+// have the most documents in Heaviest, largest first.
+// It walks every bitmap, so it is meant for diagnostics rather than hot paths.
+func (idx *Index) Stats(topN int) IndexStats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	s := IndexStats{
+		GramSize:    idx.gramSize,
+		Docs:        idx.docs.GetCardinality(),
+		Ngrams:      len(idx.bitmaps),
+		MemoryBytes: idx.memoryUsageLocked(),
+		Heaviest:    idx.heaviestLocked(topN),
+		Latency:     idx.SearchLatency(),
+	}
+
+	// Header (8) + n-gram count (4), then key (8) + size (4) + bitmap per entry
+	s.SerializedBytes = 12
+	for _, bm := range idx.bitmaps {
+		n := bm.GetCardinality()
+		s.Postings += n
+		if s.MinCardinality == 0 || n < s.MinCardinality {
+			s.MinCardinality = n
+		}
+		s.MaxCardinality = max(s.MaxCardinality, n)
+		s.SerializedBytes += 12 + bm.GetSerializedSizeInBytes()
+	}
+	if s.Ngrams > 0 {
+		s.AvgCardinality = float64(s.Postings) / float64(s.Ngrams)
+	}
+
+	if idx.forward != nil {
+		// Doc count (4), then docID (4) + key count (4) + keys (8 each) per doc
+		s.SerializedBytes += 4
+		for _, keys := range idx.forward {
+			s.SerializedBytes += 8 + uint64(len(keys))*8
+		}
+	}
+
+	return s
+}
+
+// heaviestLocked returns the n n-grams with the largest bitmaps, largest first
+// (ties broken by lower key), using a bounded heap.
+func (idx *Index) heaviestLocked(n int) []NgramCardinality {
+	if n <= 0 {
+		return nil
+	}
+
+	h := &ngramHeap{items: make([]NgramCardinality, 0, min(n, len(idx.bitmaps)))}
+	for key, bm := range idx.bitmaps {
+		item := NgramCardinality{Key: key, Cardinality: bm.GetCardinality()}
+		if h.Len() < n {
+			heap.Push(h, item)
+		} else if ngramCardinalityBefore(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+
+	top := make([]NgramCardinality, h.Len())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i] = heap.Pop(h).(NgramCardinality)
+	}
+	return top
+}
+
+// HeavyNgram is an n-gram key with its document count and, when the key is
+// packed rather than hashed, the n-gram text.
+type HeavyNgram struct {
+	NgramCardinality
+	Ngram   string // decoded n-gram; empty for hashed Unicode keys without WithExactKeys
+	Decoded bool
+}
+
+// HeaviestNgrams returns the n n-grams contained in the most documents,
+// largest first. These are the candidates for stop-ngrams or a larger gram size.
+func (idx *Index) HeaviestNgrams(n int) []HeavyNgram {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	top := idx.heaviestLocked(n)
+	heavy := make([]HeavyNgram, len(top))
+	for i, nc := range top {
+		heavy[i].NgramCardinality = nc
+		heavy[i].Ngram, heavy[i].Decoded = idx.ngramText(nc.Key)
+	}
+	return heavy
+}
+
+// HistogramBucket counts the n-grams whose cardinality is in [Min, Max].
+type HistogramBucket struct {
+	Min    uint64
+	Max    uint64
+	Ngrams int
+}
+
+// NgramHistogram returns the distribution of n-gram bitmap cardinalities in
+// power-of-two buckets: bucket i holds cardinalities in [2^i, 2^(i+1)-1], and
+// the last bucket also holds everything larger. Trailing empty buckets are
+// trimmed, so fewer than buckets entries may be returned.
+func (idx *Index) NgramHistogram(buckets int) []HistogramBucket {
+	if buckets <= 0 {
+		return nil
+	}
+	buckets = min(buckets, 64)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	hist := make([]HistogramBucket, buckets)
+	for i := range hist {
+		hist[i].Min = 1 << i
+		hist[i].Max = 1<<(i+1) - 1
+	}
+	hist[buckets-1].Max = math.MaxUint64
+
+	used := 0
+	for _, bm := range idx.bitmaps {
+		n := bm.GetCardinality()
+		if n == 0 {
+			continue
+		}
+		b := min(bits.Len64(n)-1, buckets-1)
+		hist[b].Ngrams++
+		used = max(used, b+1)
+	}
+	return hist[:used]
+}
+
+// ngramCardinalityBefore reports whether a ranks ahead of b: higher cardinality, then lower key.
+func ngramCardinalityBefore(a, b NgramCardinality) bool {
+	if a.Cardinality != b.Cardinality {
+		return a.Cardinality > b.Cardinality
+	}
+	return a.Key < b.Key
+}
+
+// ngramHeap is a min-heap keeping the lightest of the current top-n at the root.
+type ngramHeap struct {
+	items []NgramCardinality
+}
+
+func (h *ngramHeap) Len() int { return len(h.items) }
+
+func (h *ngramHeap) Less(i, j int) bool { return ngramCardinalityBefore(h.items[j], h.items[i]) }
+
+func (h *ngramHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *ngramHeap) Push(x any) {
+	h.items = append(h.items, x.(NgramCardinality))
+}
+
+func (h *ngramHeap) Pop() any {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items = h.items[:n-1]
+	return item
+}
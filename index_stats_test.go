@@ -0,0 +1,108 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndexStats(t *testing.T) {
+	for _, forward := range []bool{false, true} {
+		var opts []Option
+		if forward {
+			opts = append(opts, WithForwardIndex())
+		}
+		idx := NewIndex(3, opts...)
+		idx.Add(1, "aaaa")
+		idx.Add(2, "aaab")
+		idx.Add(3, "aaac")
+
+		s := idx.Stats(2)
+		if s.Docs != 3 || s.Ngrams != 3 || s.Postings != 5 {
+			t.Errorf("forward=%v: Docs=%d Ngrams=%d Postings=%d, want 3, 3, 5", forward, s.Docs, s.Ngrams, s.Postings)
+		}
+		if s.MinCardinality != 1 || s.MaxCardinality != 3 || s.AvgCardinality != 5.0/3 {
+			t.Errorf("forward=%v: min=%d max=%d avg=%v", forward, s.MinCardinality, s.MaxCardinality, s.AvgCardinality)
+		}
+		if len(s.Heaviest) != 2 {
+			t.Fatalf("forward=%v: Heaviest = %v, want 2 entries", forward, s.Heaviest)
+		}
+		if s.Heaviest[0].Key != runeNgramKey([]rune("aaa")) || s.Heaviest[0].Cardinality != 3 {
+			t.Errorf("forward=%v: heaviest = %+v, want aaa with 3 docs", forward, s.Heaviest[0])
+		}
+		if s.MemoryBytes == 0 || s.MemoryBytes != idx.MemoryUsage() {
+			t.Errorf("forward=%v: MemoryBytes = %d, MemoryUsage = %d", forward, s.MemoryBytes, idx.MemoryUsage())
+		}
+
+		var buf bytes.Buffer
+		if _, err := idx.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo failed: %v", err)
+		}
+		if s.SerializedBytes != uint64(buf.Len()) {
+			t.Errorf("forward=%v: SerializedBytes = %d, WriteTo wrote %d", forward, s.SerializedBytes, buf.Len())
+		}
+	}
+}
+
+func TestIndexStatsEmpty(t *testing.T) {
+	s := NewIndex(3).Stats(5)
+	if s.Ngrams != 0 || s.AvgCardinality != 0 || len(s.Heaviest) != 0 {
+		t.Errorf("unexpected stats for empty index: %+v", s)
+	}
+	if s.SerializedBytes != 12 {
+		t.Errorf("SerializedBytes = %d, want 12", s.SerializedBytes)
+	}
+}
+
+func TestHeaviestNgrams(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, "aaaa")
+	idx.Add(2, "aaab")
+	idx.Add(3, "ééé")
+
+	heavy := idx.HeaviestNgrams(3)
+	if len(heavy) != 3 {
+		t.Fatalf("HeaviestNgrams = %v, want 3 entries", heavy)
+	}
+	if heavy[0].Ngram != "aaa" || !heavy[0].Decoded || heavy[0].Cardinality != 2 {
+		t.Errorf("heaviest = %+v, want aaa with 2 docs", heavy[0])
+	}
+	var decoded, hashed int
+	for _, h := range heavy[1:] {
+		if h.Decoded {
+			decoded++
+		} else if h.Ngram == "" {
+			hashed++
+		}
+	}
+	if decoded != 1 || hashed != 1 {
+		t.Errorf("expected one packed and one hashed key, got %+v", heavy[1:])
+	}
+}
+
+func TestNgramHistogram(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 10; i++ {
+		idx.Add(i, "common")
+	}
+	idx.Add(20, "rare")
+	idx.Add(21, "rarer")
+
+	hist := idx.NgramHistogram(3)
+	if len(hist) != 3 {
+		t.Fatalf("NgramHistogram = %+v, want 3 buckets", hist)
+	}
+	// "rar" and "are" appear twice, "rer" once, common's 4 n-grams ten times
+	want := []int{1, 2, 4}
+	for i, b := range hist {
+		if b.Ngrams != want[i] {
+			t.Errorf("bucket %d [%d, %d] = %d, want %d", i, b.Min, b.Max, b.Ngrams, want[i])
+		}
+	}
+	if hist[1].Min != 2 || hist[1].Max != 3 {
+		t.Errorf("bucket 1 bounds = [%d, %d], want [2, 3]", hist[1].Min, hist[1].Max)
+	}
+
+	if got := idx.NgramHistogram(8); len(got) != 4 || got[3].Ngrams != 4 {
+		t.Errorf("NgramHistogram(8) = %+v, want 4 buckets ending with common's n-grams", got)
+	}
+}
@@ -1,8 +1,14 @@
 package roaringsearch
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -90,6 +96,88 @@ func TestSearchThreshold(t *testing.T) {
 	}
 }
 
+func TestSearchThresholdOrdering(t *testing.T) {
+	idx := NewIndex(2)
+	words := []string{"ab", "abc", "abcd", "abcde", "xbcd", "bcde", "zz"}
+	for i, w := range words {
+		idx.Add(uint32(i), w)
+	}
+
+	// Query grams: ab, bc, cd, de
+	result := idx.SearchThreshold("abcde", 2)
+
+	// Brute-force expected scores
+	want := make(map[uint32]int)
+	for i, w := range words {
+		n := 0
+		for _, g := range []string{"ab", "bc", "cd", "de"} {
+			if strings.Contains(w, g) {
+				n++
+			}
+		}
+		if n >= 2 {
+			want[uint32(i)] = n
+		}
+	}
+
+	if len(result.DocIDs) != len(want) {
+		t.Fatalf("DocIDs = %v, want %d docs", result.DocIDs, len(want))
+	}
+	for docID, score := range want {
+		if result.Scores[docID] != score {
+			t.Errorf("score[%d] = %d, want %d", docID, result.Scores[docID], score)
+		}
+	}
+	for i := 1; i < len(result.DocIDs); i++ {
+		prev, cur := result.DocIDs[i-1], result.DocIDs[i]
+		if result.Scores[prev] < result.Scores[cur] ||
+			(result.Scores[prev] == result.Scores[cur] && prev > cur) {
+			t.Errorf("DocIDs not ordered by score desc, docID asc: %v", result.DocIDs)
+		}
+	}
+}
+
+func TestSearchThresholdTopK(t *testing.T) {
+	idx := NewIndex(2)
+	idx.Add(1, "abcde")
+	idx.Add(2, "abx")
+	idx.Add(3, "abcd")
+	idx.Add(4, "bcde")
+	idx.Add(5, "ab")
+
+	// Query grams: ab, bc, cd, de; scores 1:4, 3:3, 4:3, 2:1, 5:1
+	result := idx.SearchThresholdTopK("abcde", 1, 3)
+	if !reflect.DeepEqual(result.DocIDs, []uint32{1, 3, 4}) {
+		t.Errorf("TopK DocIDs = %v, want [1 3 4]", result.DocIDs)
+	}
+	if len(result.Scores) != 3 || result.Scores[3] != 3 {
+		t.Errorf("TopK Scores = %v", result.Scores)
+	}
+
+	all := idx.SearchThresholdTopK("abcde", 1, 0)
+	if len(all.DocIDs) != 5 {
+		t.Errorf("k=0 should return all matches, got %v", all.DocIDs)
+	}
+
+	var streamed []uint32
+	idx.SearchThresholdCallback("abcde", 1, func(docID uint32, score int) bool {
+		streamed = append(streamed, docID)
+		return len(streamed) < 4
+	})
+	if !reflect.DeepEqual(streamed, []uint32{1, 3, 4, 2}) {
+		t.Errorf("callback order = %v, want [1 3 4 2]", streamed)
+	}
+
+	called := false
+	idx.SearchThresholdCallback("zz", 1, func(uint32, int) bool {
+		called = true
+		return true
+	})
+	if called {
+		t.Error("callback should not run when nothing matches")
+	}
+}
+
 func TestRemove(t *testing.T) {
 	idx := NewIndex(3)
 
@@ -126,6 +214,83 @@ func TestClear(t *testing.T) {
 	}
 }
 
+func TestSearchBitmapMatchAll(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, "hi")
+	idx.Add(3, testGoodbyeWorld)
+
+	filter := NewBitmapFilter()
+	filter.Set(1, "lang", "en")
+	filter.Set(2, "lang", "en")
+	filter.Set(3, "lang", "fr")
+
+	if got := idx.MatchAll().ToArray(); !reflect.DeepEqual(got, []uint32{1, 2, 3}) {
+		t.Errorf("MatchAll = %v, want [1 2 3]", got)
+	}
+
+	for _, query := range []string{"", "   ", "!?"} {
+		got := idx.SearchBitmap(query)
+		got.And(filter.Get("lang", "en"))
+		if !reflect.DeepEqual(got.ToArray(), []uint32{1, 2}) {
+			t.Errorf("SearchBitmap(%q) & lang=en = %v, want [1 2]", query, got.ToArray())
+		}
+	}
+
+	if got := idx.SearchBitmap("world").ToArray(); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("SearchBitmap(world) = %v, want [1 3]", got)
+	}
+	if got := idx.SearchBitmap("hello world").ToArray(); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("SearchBitmap(hello world) = %v, want [1]", got)
+	}
+	if got := idx.SearchBitmap("xyz"); !got.IsEmpty() {
+		t.Errorf("SearchBitmap(xyz) = %v, want empty", got.ToArray())
+	}
+	if got := idx.SearchBitmap("ab"); !got.IsEmpty() {
+		t.Errorf("SearchBitmap of a query shorter than the gram size = %v, want empty", got.ToArray())
+	}
+
+	// The result is a copy
+	idx.SearchBitmap("world").Add(42)
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("Search(world) after mutating SearchBitmap result = %v, want [1 3]", got)
+	}
+}
+
+func TestAllDocs(t *testing.T) {
+	idx := NewIndex(3)
+
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, "hi") // too short for any trigram, still a document
+
+	batch := idx.Batch()
+	batch.Add(3, testGoodbyeWorld)
+	batch.Flush()
+
+	if got := idx.AllDocs().ToArray(); !reflect.DeepEqual(got, []uint32{1, 2, 3}) {
+		t.Errorf("AllDocs = %v, want [1 2 3]", got)
+	}
+
+	idx.Remove(1)
+	if idx.DocCount() != 2 {
+		t.Errorf("DocCount after remove = %d, want 2", idx.DocCount())
+	}
+
+	idx.Clear()
+	if idx.DocCount() != 0 {
+		t.Errorf("DocCount after clear = %d, want 0", idx.DocCount())
+	}
+
+	filter := NewBitmapFilter()
+	filter.Set(5, "media_type", "book")
+	fb := filter.Batch("language")
+	fb.Add(6, "english")
+	fb.Flush()
+	if got := filter.AllDocs().ToArray(); !reflect.DeepEqual(got, []uint32{5, 6}) {
+		t.Errorf("filter AllDocs = %v, want [5 6]", got)
+	}
+}
+
 func TestCustomNormalizer(t *testing.T) {
 	// Custom normalizer that removes vowels
 	removeVowels := func(s string) string {
@@ -269,6 +434,27 @@ func TestSearchWithLimit(t *testing.T) {
 	}
 }
 
+func TestSearchWithLimitBatches(t *testing.T) {
+	idx := NewIndex(3)
+	// Matches are sparse and span several containers, so candidates are
+	// probed over many batches
+	for i := uint32(0); i < 200_000; i += 7 {
+		text := testHelloThere
+		if i%3 == 0 {
+			text = testHelloWorld
+		}
+		idx.Add(i, text)
+	}
+
+	all := idx.Search("hello world")
+	for _, limit := range []int{1, 63, 64, 65, 1000, 5000, len(all), len(all) + 1} {
+		want := all[:min(limit, len(all))]
+		if got := idx.SearchWithLimit("hello world", limit); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchWithLimit(hello world, %d) returned %d docs, want the first %d", limit, len(got), len(want))
+		}
+	}
+}
+
 func TestSearchCallback(t *testing.T) {
 	idx := NewIndex(3)
 
@@ -548,6 +734,57 @@ func BenchmarkSearch(b *testing.B) {
 	}
 }
 
+// BenchmarkScratchAllocs tracks per-call allocations of the hot Add and
+// Search paths, which draw their scratch buffers from pools.
+func BenchmarkScratchAllocs(b *testing.B) {
+	idx := NewIndex(3)
+	for i := 0; i < 10000; i++ {
+		idx.Add(uint32(i), testQuickBrownFox)
+	}
+
+	b.Run("AddASCII", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.Add(uint32(i%10000), testQuickBrownFox)
+		}
+	})
+
+	b.Run("AddUnicode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.Add(uint32(i%10000), "the quick brown 狐狸 jumps")
+		}
+	})
+
+	b.Run("Search", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.Search("brown fox")
+		}
+	})
+
+	b.Run("SearchCount", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.SearchCount("brown fox")
+		}
+	})
+
+	b.Run("SearchAnyCount", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.SearchAnyCount("brown fox")
+		}
+	})
+
+	b.Run("SearchThreshold", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.SearchThreshold("brown fox", 2)
+		}
+	})
+}
+
 func BenchmarkSearchThreshold(b *testing.B) {
 	idx := NewIndex(3)
 
@@ -599,6 +836,103 @@ func TestIndexBatch(t *testing.T) {
 	batch.Flush()
 }
 
+func TestIndexBatchConcurrentAdd(t *testing.T) {
+	idx := NewIndex(3)
+	batch := idx.Batch(WithAutoFlush(500), WithBatchShards(4))
+
+	const producers, perProducer = 8, 1000
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				batch.Add(uint32(p*perProducer+i), fmt.Sprintf("%s %d", testHelloWorld, i))
+			}
+		}(p)
+	}
+	wg.Wait()
+	batch.Flush()
+
+	if idx.DocCount() != producers*perProducer {
+		t.Errorf("DocCount = %d, want %d", idx.DocCount(), producers*perProducer)
+	}
+	if got := idx.SearchCount("hello"); got != producers*perProducer {
+		t.Errorf("SearchCount = %d, want %d", got, producers*perProducer)
+	}
+	if batch.Len() != 0 {
+		t.Errorf("Len after Flush = %d, want 0", batch.Len())
+	}
+}
+
+func TestIndexBatchDuplicateDocID(t *testing.T) {
+	// The second copy of doc 7 lands in an earlier shard than the first
+	texts := []string{testQuickBrownFox, "filler one", "filler two", testHelloWorld}
+	for i := range 200 {
+		texts = append(texts, fmt.Sprintf("document %d", i))
+	}
+	ids := func(i int) uint32 {
+		if i == 0 || i == 3 {
+			return 7
+		}
+		return uint32(100 + i)
+	}
+
+	idx := NewIndex(3, WithDocLengths(), WithForwardIndex())
+	batch := idx.Batch(WithBatchShards(4))
+	plain := NewIndex(3, WithDocLengths(), WithForwardIndex())
+	for i, text := range texts {
+		batch.Add(ids(i), text)
+		plain.Add(ids(i), text)
+	}
+	batch.Flush()
+
+	// Like two Adds: n-grams of both copies, and the last copy's length
+	if got, want := idx.DocLength(7), plain.DocLength(7); got != want {
+		t.Errorf("DocLength(7) = %d, want %d", got, want)
+	}
+	if got, want := idx.forward[7], plain.forward[7]; !reflect.DeepEqual(got, want) {
+		t.Errorf("forward keys of doc 7 = %v, want %v", got, want)
+	}
+	for _, query := range []string{"quick", "hello"} {
+		if got := idx.Search(query); !reflect.DeepEqual(got, []uint32{7}) {
+			t.Errorf("Search(%q) = %v, want [7]", query, got)
+		}
+	}
+}
+
+func TestIndexBatchAutoFlushBytes(t *testing.T) {
+	idx := NewIndex(3)
+	batch := idx.Batch(WithAutoFlushBytes(20))
+
+	batch.Add(1, testHelloWorld) // 11 bytes
+	if batch.Len() != 1 {
+		t.Fatalf("Len = %d, want 1 before threshold", batch.Len())
+	}
+	batch.Add(2, testHelloThere) // 22 bytes total: flushes
+	batch.Wait()
+
+	if batch.Len() != 0 || idx.DocCount() != 2 {
+		t.Errorf("after byte threshold: Len = %d, DocCount = %d", batch.Len(), idx.DocCount())
+	}
+}
+
+func TestIndexBatchFlushAsync(t *testing.T) {
+	idx := NewIndex(3)
+	batch := idx.Batch()
+
+	batch.Add(1, testHelloWorld)
+	batch.FlushAsync()
+	batch.Add(2, testGoodbyeWorld)
+	batch.FlushAsync()
+	batch.FlushAsync() // nothing buffered
+	batch.Wait()
+
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search after FlushAsync = %v, want [1 2]", got)
+	}
+}
+
 func BenchmarkIndexBatch(b *testing.B) {
 	const numDocs = 100_000
 	texts := []string{
@@ -629,3 +963,163 @@ func BenchmarkIndexBatch(b *testing.B) {
 		}
 	})
 }
+
+func TestIndexBatchFlushContext(t *testing.T) {
+	idx := NewIndex(3)
+	batch := idx.Batch()
+	for i := uint32(0); i < 1000; i++ {
+		batch.Add(i, fmt.Sprintf("%s %d", testHelloWorld, i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := batch.FlushContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FlushContext error = %v, want context.Canceled", err)
+	}
+	if idx.DocCount() != 0 || idx.NgramCount() != 0 {
+		t.Errorf("cancelled flush modified index: %d docs, %d n-grams", idx.DocCount(), idx.NgramCount())
+	}
+	if batch.Len() != 1000 {
+		t.Errorf("Len after cancelled flush = %d, want 1000", batch.Len())
+	}
+
+	if err := batch.FlushContext(context.Background()); err != nil {
+		t.Fatalf("FlushContext retry failed: %v", err)
+	}
+	if idx.DocCount() != 1000 {
+		t.Errorf("DocCount after retry = %d, want 1000", idx.DocCount())
+	}
+}
+
+func TestIndexBatchWorkerPanic(t *testing.T) {
+	idx := NewIndex(3, WithNormalizer(func(s string) string {
+		if s == "boom" {
+			panic("bad input")
+		}
+		return s
+	}))
+
+	batch := idx.Batch()
+	batch.Add(1, "fine")
+	batch.Add(2, "boom")
+	err := batch.FlushContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("FlushContext error = %v, want worker panic", err)
+	}
+	if idx.DocCount() != 0 {
+		t.Errorf("failed flush modified index: %d docs", idx.DocCount())
+	}
+
+	batch.Add(3, "boom")
+	batch.FlushAsync()
+	if err := batch.Wait(); err == nil {
+		t.Error("Wait should report the async flush error")
+	}
+	if err := batch.Wait(); err != nil {
+		t.Errorf("second Wait = %v, want nil", err)
+	}
+}
+
+func TestQueryKeysASCIIFastPath(t *testing.T) {
+	// Same normalization as the default, but opaque to the fast path check
+	slow := func(s string) string { return NormalizeLowercaseAlphanumeric(s) }
+
+	for _, gramSize := range []int{1, 2, 3, 5} {
+		fastIdx := NewIndex(gramSize)
+		slowIdx := NewIndex(gramSize, WithNormalizer(slow))
+		for _, query := range []string{"", "ab", "Hello, World!", "banana banana", "AAAA aaaa", "café"} {
+			got, want := fastIdx.appendQueryKeys(nil, query), slowIdx.appendQueryKeys(nil, query)
+			if !slices.Equal(got, want) {
+				t.Errorf("gram %d: appendQueryKeys(%q) = %v, rune path %v", gramSize, query, got, want)
+			}
+		}
+	}
+
+	fastIdx := NewIndex(3)
+	slowIdx := NewIndex(3, WithNormalizer(slow))
+	for _, idx := range []*Index{fastIdx, slowIdx} {
+		idx.Add(1, testHelloWorld)
+		idx.Add(2, testHelloThere)
+	}
+	if got := fastIdx.SearchAny("HELLO hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("SearchAny(HELLO hello) = %v, want [1 2]", got)
+	}
+	if got := fastIdx.SearchCount("Hello World"); got != 1 {
+		t.Errorf("SearchCount(Hello World) = %d, want 1", got)
+	}
+
+	fastAllocs := testing.AllocsPerRun(100, func() { fastIdx.SearchCount("hello world") })
+	slowAllocs := testing.AllocsPerRun(100, func() { slowIdx.SearchCount("hello world") })
+	if fastAllocs >= slowAllocs {
+		t.Errorf("ASCII query allocs = %v, want fewer than rune path %v", fastAllocs, slowAllocs)
+	}
+}
+
+func TestPooledScratchReuse(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, "héllo wörld")
+	idx.Add(3, testHelloThere)
+	idx.Add(4, "日本語 hello")
+
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 3, 4}) {
+		t.Errorf("Search(hello) = %v, want [1 3 4]", got)
+	}
+	if got := idx.Search("wörld"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search(wörld) = %v, want [2]", got)
+	}
+	if got := idx.Search("日本語"); !reflect.DeepEqual(got, []uint32{4}) {
+		t.Errorf("Search(日本語) = %v, want [4]", got)
+	}
+
+	// Results must not alias pooled scratch space
+	first := idx.Search("hello")
+	first[0] = 99
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 3, 4}) {
+		t.Errorf("Search(hello) after mutating a result = %v, want [1 3 4]", got)
+	}
+
+	if got := idx.SearchCount("hello"); got != 3 {
+		t.Errorf("SearchCount(hello) = %d, want 3", got)
+	}
+	if got := idx.SearchAnyCount("world there"); got != 3 {
+		t.Errorf("SearchAnyCount(world there) = %d, want 3", got)
+	}
+	if got := idx.Search("missing"); got != nil {
+		t.Errorf("Search(missing) = %v, want nil", got)
+	}
+}
+
+func TestSearchAllTerms(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, "a fox that is brown")
+	idx.Add(3, "brownie foxglove")
+	idx.Add(4, "the brown dog")
+	idx.Add(5, "日本語の テキスト")
+
+	tests := []struct {
+		query string
+		want  []uint32
+	}{
+		{"brown fox", []uint32{1, 2, 3}},
+		{"fox brown", []uint32{1, 2, 3}},
+		{"  Brown,   FOX! ", []uint32{1, 2, 3}},
+		{"brown fox dog", []uint32{1}},
+		{"brown is a", []uint32{1, 2, 3, 4}}, // "is" and "a" are too short to filter
+		{"テキスト 日本語", []uint32{5}},
+		{"brown cat", nil},
+		{"is a", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := idx.SearchAllTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchAllTerms(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// The cross-word n-grams of Search still require adjacency
+	if got := idx.Search("fox brown"); got != nil {
+		t.Errorf("Search(fox brown) = %v, want nil", got)
+	}
+}
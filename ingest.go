@@ -0,0 +1,235 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Document is a document to be indexed by the streaming ingestion helpers.
+type Document struct {
+	ID   uint32
+	Text string
+}
+
+// IngestProgress reports how far a streaming ingestion has progressed.
+type IngestProgress struct {
+	Docs    uint64        // documents indexed so far
+	Batches int           // batches flushed so far
+	Elapsed time.Duration // time since ingestion started
+}
+
+// ingestConfig holds options for the Ingest methods.
+type ingestConfig struct {
+	batchSize int
+	progress  func(IngestProgress)
+
+	idField, textField   string // NDJSON field names
+	idColumn, textColumn int    // CSV column indexes
+	csvHeader            bool
+}
+
+// IngestOption configures streaming ingestion.
+type IngestOption func(*ingestConfig)
+
+// WithIngestBatchSize sets how many documents are buffered before each flush.
+// Memory use is bounded by one batch of documents. Default is 10000.
+func WithIngestBatchSize(n int) IngestOption {
+	return func(cfg *ingestConfig) {
+		if n > 0 {
+			cfg.batchSize = n
+		}
+	}
+}
+
+// WithIngestProgress sets a callback invoked after every flushed batch.
+func WithIngestProgress(fn func(IngestProgress)) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.progress = fn
+	}
+}
+
+// WithNDJSONFields sets the JSON field names holding the document ID and text.
+// Default is "id" and "text".
+func WithNDJSONFields(idField, textField string) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.idField = idField
+		cfg.textField = textField
+	}
+}
+
+// WithCSVColumns sets the zero-based CSV columns holding the document ID and
+// text. Default is 0 and 1.
+func WithCSVColumns(idColumn, textColumn int) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.idColumn = idColumn
+		cfg.textColumn = textColumn
+	}
+}
+
+// WithCSVHeader skips the first CSV record.
+func WithCSVHeader() IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.csvHeader = true
+	}
+}
+
+// ingester accumulates documents into a reusable batch and flushes it when full.
+type ingester struct {
+	cfg      ingestConfig
+	batch    *IndexBatch
+	start    time.Time
+	progress IngestProgress
+}
+
+func (idx *Index) newIngester(opts []IngestOption) *ingester {
+	cfg := ingestConfig{
+		batchSize:  10000,
+		idField:    "id",
+		textField:  "text",
+		textColumn: 1,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &ingester{
+		cfg:   cfg,
+		batch: idx.BatchSize(cfg.batchSize, WithBatchShards(1)),
+		start: time.Now(),
+	}
+}
+
+func (in *ingester) add(docID uint32, text string) {
+	in.batch.Add(docID, text)
+	if in.batch.Len() >= in.cfg.batchSize {
+		in.flush()
+	}
+}
+
+func (in *ingester) flush() {
+	n := in.batch.Len()
+	if n == 0 {
+		return
+	}
+	in.batch.Flush()
+
+	in.progress.Docs += uint64(n)
+	in.progress.Batches++
+	in.progress.Elapsed = time.Since(in.start)
+	if in.cfg.progress != nil {
+		in.cfg.progress(in.progress)
+	}
+}
+
+// finish flushes the remaining documents and returns the final progress.
+func (in *ingester) finish() IngestProgress {
+	in.flush()
+	in.progress.Elapsed = time.Since(in.start)
+	return in.progress
+}
+
+// IngestChannel indexes documents received from docs in batches until the
+// channel is closed.
+func (idx *Index) IngestChannel(docs <-chan Document, opts ...IngestOption) IngestProgress {
+	in := idx.newIngester(opts)
+	for doc := range docs {
+		in.add(doc.ID, doc.Text)
+	}
+	return in.finish()
+}
+
+// IngestNDJSON indexes newline-delimited JSON objects from r in batches.
+// Each object must have a numeric ID field and a string text field; see
+// WithNDJSONFields. Blank lines are skipped. On a malformed line, the
+// documents before it are indexed and an error naming the line is returned.
+func (idx *Index) IngestNDJSON(r io.Reader, opts ...IngestOption) (IngestProgress, error) {
+	in := idx.newIngester(opts)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBitmapSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		docID, text, err := in.parseNDJSON(data)
+		if err != nil {
+			return in.finish(), fmt.Errorf("line %d: %w", line, err)
+		}
+		in.add(docID, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return in.finish(), fmt.Errorf("read ndjson: %w", err)
+	}
+
+	return in.finish(), nil
+}
+
+func (in *ingester) parseNDJSON(data []byte) (uint32, string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, "", err
+	}
+
+	rawID, ok := fields[in.cfg.idField]
+	if !ok {
+		return 0, "", fmt.Errorf("missing field %q", in.cfg.idField)
+	}
+	var docID uint32
+	if err := json.Unmarshal(rawID, &docID); err != nil {
+		return 0, "", fmt.Errorf("field %q: %w", in.cfg.idField, err)
+	}
+
+	var text string
+	if rawText, ok := fields[in.cfg.textField]; ok {
+		if err := json.Unmarshal(rawText, &text); err != nil {
+			return 0, "", fmt.Errorf("field %q: %w", in.cfg.textField, err)
+		}
+	}
+	return docID, text, nil
+}
+
+// IngestCSV indexes CSV records from r in batches, reading the document ID
+// and text from the columns set with WithCSVColumns. On a malformed record,
+// the documents before it are indexed and an error naming the record is returned.
+func (idx *Index) IngestCSV(r io.Reader, opts ...IngestOption) (IngestProgress, error) {
+	in := idx.newIngester(opts)
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	for record := 1; ; record++ {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return in.finish(), fmt.Errorf("read csv: %w", err)
+		}
+		if record == 1 && in.cfg.csvHeader {
+			continue
+		}
+
+		if in.cfg.idColumn >= len(fields) || in.cfg.textColumn >= len(fields) {
+			return in.finish(), fmt.Errorf("record %d: expected at least %d columns, got %d",
+				record, max(in.cfg.idColumn, in.cfg.textColumn)+1, len(fields))
+		}
+		docID, err := strconv.ParseUint(fields[in.cfg.idColumn], 10, 32)
+		if err != nil {
+			return in.finish(), fmt.Errorf("record %d: %w", record, err)
+		}
+		in.add(uint32(docID), fields[in.cfg.textColumn])
+	}
+
+	return in.finish(), nil
+}
@@ -0,0 +1,402 @@
+package ingest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// EventOp is the kind of change an Event applies.
+type EventOp uint8
+
+const (
+	EventAdd    EventOp = iota // index a document not yet in the index
+	EventUpdate                // replace a document's text, fields and values
+	EventDelete                // remove a document
+)
+
+// Event is one change from a message stream.
+type Event struct {
+	Offset uint64 // position in the stream; must increase from event to event
+	Op     EventOp
+	DocID  uint32
+	Text   string
+	Fields map[string]string // filter field -> category
+	Values map[string]any    // sort column name -> value
+}
+
+// Source delivers the events of a change stream in offset order. Next blocks
+// until an event is available and returns io.EOF when the stream ends, or
+// ctx's error when ctx is done.
+//
+// Kafka, NATS JetStream and similar clients are adapted by mapping their
+// messages to Events, using the partition or stream sequence as the offset.
+type Source interface {
+	Next(ctx context.Context) (Event, error)
+}
+
+// ChannelSource is a Source reading from a channel; closing the channel ends
+// the stream.
+type ChannelSource <-chan Event
+
+// Next implements Source.
+func (s ChannelSource) Next(ctx context.Context) (Event, error) {
+	select {
+	case ev, ok := <-s:
+		if !ok {
+			return Event{}, io.EOF
+		}
+		return ev, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Consumer applies a change stream to a live index, and optionally to a
+// BitmapFilter and sort columns, in batches.
+//
+// Within a batch the last event for a document wins. With WithCheckpoint the
+// index is saved periodically together with the offset of the next
+// unapplied event, so after a restart the stream is resumed from Offset and
+// any replayed events already covered by the checkpoint are skipped.
+//
+// Example:
+//
+//	idx, err := rs.LoadFromFile(path) // or rs.NewIndex(3) on first start
+//	c, err := ingest.NewConsumer(idx, src, ingest.WithCheckpoint(path))
+//	kafkaReader.SetOffset(int64(c.Offset()))
+//	err = c.Run(ctx) // returns after ctx is done and the last batch is checkpointed
+type Consumer struct {
+	idx     *rs.Index
+	src     Source
+	filter  *rs.BitmapFilter
+	columns []eventColumn
+
+	batchSize          int
+	flushInterval      time.Duration
+	indexPath          string
+	checkpointInterval time.Duration
+	onCheckpoint       func(offset uint64) error
+	progress           func(rs.IngestProgress)
+
+	pending      []Event
+	received     uint64 // offset after the last buffered event
+	applied      uint64 // offset after the last applied event
+	checkpointed uint64 // offset stored in the last checkpoint
+	lastSaved    time.Time
+	start        time.Time
+	stats        rs.IngestProgress
+}
+
+// eventColumn applies the values of one sort column.
+type eventColumn struct {
+	name  string
+	apply func(removed []uint32, docs []Event)
+}
+
+// DefaultFlushInterval is how often a Consumer applies a partial batch.
+const DefaultFlushInterval = time.Second
+
+// DefaultCheckpointInterval is how often a Consumer saves a checkpoint.
+const DefaultCheckpointInterval = time.Minute
+
+// ConsumerOption configures a Consumer.
+type ConsumerOption func(*Consumer)
+
+// WithConsumerFilter applies each event's Fields to filter.
+func WithConsumerFilter(filter *rs.BitmapFilter) ConsumerOption {
+	return func(c *Consumer) {
+		c.filter = filter
+	}
+}
+
+// WithConsumerColumn applies each event's Values[name] to col. Values of
+// another type than T are ignored.
+func WithConsumerColumn[T cmp.Ordered](name string, col *rs.SortColumn[T]) ConsumerOption {
+	return func(c *Consumer) {
+		c.columns = append(c.columns, eventColumn{
+			name: name,
+			apply: func(removed []uint32, docs []Event) {
+				if len(removed) > 0 {
+					col.RemoveMany(removed)
+				}
+				batch := col.BatchSize(len(docs))
+				for _, ev := range docs {
+					if v, ok := ev.Values[name].(T); ok {
+						batch.Add(ev.DocID, v)
+					}
+				}
+				batch.Flush()
+			},
+		})
+	}
+}
+
+// WithConsumerBatchSize sets how many events are buffered before they are
+// applied. Default is DefaultBatchSize.
+func WithConsumerBatchSize(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithFlushInterval sets how long events may wait in a partial batch.
+// Default is DefaultFlushInterval.
+func WithFlushInterval(d time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if d > 0 {
+			c.flushInterval = d
+		}
+	}
+}
+
+// WithCheckpoint saves the index to indexPath at each checkpoint and stores
+// the stream offset next to it in indexPath + ".offset". An existing offset
+// file is read by NewConsumer.
+func WithCheckpoint(indexPath string) ConsumerOption {
+	return func(c *Consumer) {
+		c.indexPath = indexPath
+	}
+}
+
+// WithCheckpointInterval sets the minimum time between checkpoints.
+// Default is DefaultCheckpointInterval.
+func WithCheckpointInterval(d time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if d > 0 {
+			c.checkpointInterval = d
+		}
+	}
+}
+
+// WithCheckpointHook sets a function called at each checkpoint after the index
+// is saved and before the offset is stored, for saving filters and columns
+// alongside it. An error aborts the checkpoint.
+func WithCheckpointHook(fn func(offset uint64) error) ConsumerOption {
+	return func(c *Consumer) {
+		c.onCheckpoint = fn
+	}
+}
+
+// WithConsumerProgress sets a callback invoked after every applied batch.
+func WithConsumerProgress(fn func(rs.IngestProgress)) ConsumerOption {
+	return func(c *Consumer) {
+		c.progress = fn
+	}
+}
+
+// NewConsumer returns a Consumer applying events from src to idx. With
+// WithCheckpoint it resumes from the stored offset, if any.
+func NewConsumer(idx *rs.Index, src Source, opts ...ConsumerOption) (*Consumer, error) {
+	c := &Consumer{
+		idx:                idx,
+		src:                src,
+		batchSize:          DefaultBatchSize,
+		flushInterval:      DefaultFlushInterval,
+		checkpointInterval: DefaultCheckpointInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.indexPath != "" {
+		data, err := os.ReadFile(c.offsetPath())
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("read checkpoint: %w", err)
+		default:
+			offset, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse checkpoint %s: %w", c.offsetPath(), err)
+			}
+			c.received, c.applied, c.checkpointed = offset, offset, offset
+		}
+	}
+	return c, nil
+}
+
+// Offset returns the offset of the next event to apply: where to resume the
+// source from.
+func (c *Consumer) Offset() uint64 {
+	return c.applied
+}
+
+// Run consumes events until the source ends or ctx is done, then applies the
+// buffered events and saves a final checkpoint. It returns nil when ctx is
+// done or the source returns io.EOF, and otherwise the source's error.
+func (c *Consumer) Run(ctx context.Context) error {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan Event)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			ev, err := c.src.Next(readCtx)
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case events <- ev:
+			case <-readCtx.Done():
+				errc <- readCtx.Err()
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+	c.start = time.Now()
+	c.lastSaved = c.start
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.Offset < c.received {
+				continue // replayed from before the checkpoint
+			}
+			c.pending = append(c.pending, ev)
+			c.received = ev.Offset + 1
+			if len(c.pending) >= c.batchSize {
+				c.flush()
+				if err := c.maybeCheckpoint(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			c.flush()
+			if err := c.maybeCheckpoint(); err != nil {
+				return err
+			}
+		case err := <-errc:
+			c.flush()
+			cerr := c.checkpoint()
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return cerr
+			}
+			return errors.Join(fmt.Errorf("read source: %w", err), cerr)
+		}
+	}
+}
+
+// flush applies the buffered events.
+func (c *Consumer) flush() {
+	if len(c.pending) == 0 {
+		return
+	}
+
+	// Only the last event of each document is applied, but a document
+	// updated or deleted anywhere in the batch must be removed first.
+	last := make(map[uint32]int, len(c.pending))
+	replace := make(map[uint32]bool)
+	for i, ev := range c.pending {
+		last[ev.DocID] = i
+		if ev.Op != EventAdd {
+			replace[ev.DocID] = true
+		}
+	}
+	removed := make([]uint32, 0, len(replace))
+	for id := range replace {
+		removed = append(removed, id)
+	}
+	docs := make([]Event, 0, len(last))
+	for i, ev := range c.pending {
+		if last[ev.DocID] == i && ev.Op != EventDelete {
+			docs = append(docs, ev)
+		}
+	}
+
+	if len(removed) > 0 {
+		c.idx.RemoveMany(removed)
+		if c.filter != nil {
+			c.filter.RemoveMany(removed)
+		}
+	}
+
+	batch := c.idx.BatchSize(len(docs), rs.WithBatchShards(1))
+	fields := make(map[string]*rs.FilterBatch)
+	for _, ev := range docs {
+		batch.Add(ev.DocID, ev.Text)
+		if c.filter == nil {
+			continue
+		}
+		for field, category := range ev.Fields {
+			fb, ok := fields[field]
+			if !ok {
+				fb = c.filter.BatchSize(field, len(docs))
+				fields[field] = fb
+			}
+			fb.Add(ev.DocID, category)
+		}
+	}
+	batch.Flush()
+	for _, fb := range fields {
+		fb.Flush()
+	}
+	for _, col := range c.columns {
+		col.apply(removed, docs)
+	}
+
+	c.applied = c.received
+	c.stats.Docs += uint64(len(c.pending))
+	c.stats.Batches++
+	c.stats.Elapsed = time.Since(c.start)
+	if c.progress != nil {
+		c.progress(c.stats)
+	}
+	clear(c.pending)
+	c.pending = c.pending[:0]
+}
+
+// maybeCheckpoint saves a checkpoint once the checkpoint interval has passed.
+func (c *Consumer) maybeCheckpoint() error {
+	if time.Since(c.lastSaved) < c.checkpointInterval {
+		return nil
+	}
+	return c.checkpoint()
+}
+
+// checkpoint saves the index, runs the hook and then stores the offset, so
+// the stored offset never runs ahead of the saved index.
+func (c *Consumer) checkpoint() error {
+	c.lastSaved = time.Now()
+	if c.indexPath == "" || c.applied == c.checkpointed {
+		return nil
+	}
+	if err := c.idx.SaveToFile(c.indexPath); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if c.onCheckpoint != nil {
+		if err := c.onCheckpoint(c.applied); err != nil {
+			return fmt.Errorf("checkpoint hook: %w", err)
+		}
+	}
+
+	tmpPath := c.offsetPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.FormatUint(c.applied, 10)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.offsetPath()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename checkpoint: %w", err)
+	}
+	c.checkpointed = c.applied
+	return nil
+}
+
+func (c *Consumer) offsetPath() string {
+	return c.indexPath + ".offset"
+}
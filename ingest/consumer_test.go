@@ -0,0 +1,142 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+func runEvents(t *testing.T, c *Consumer, events []Event) {
+	t.Helper()
+	ch := make(chan Event, len(events))
+	for _, ev := range events {
+		ch <- ev
+	}
+	close(ch)
+	c.src = ChannelSource(ch)
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestConsumerApplies(t *testing.T) {
+	idx := rs.NewIndex(3)
+	filter := rs.NewBitmapFilter()
+	prices := rs.NewSortColumn[int]()
+	c, err := NewConsumer(idx, nil,
+		WithConsumerFilter(filter),
+		WithConsumerColumn("price", prices),
+		WithConsumerBatchSize(3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runEvents(t, c, []Event{
+		{Offset: 0, Op: EventAdd, DocID: 1, Text: "hello world", Fields: map[string]string{"kind": "greeting"}, Values: map[string]any{"price": 10}},
+		{Offset: 1, Op: EventAdd, DocID: 2, Text: "goodbye world", Fields: map[string]string{"kind": "farewell"}, Values: map[string]any{"price": 20}},
+		{Offset: 2, Op: EventAdd, DocID: 3, Text: "hello there"},
+		{Offset: 3, Op: EventUpdate, DocID: 1, Text: "brave new world", Fields: map[string]string{"kind": "book"}, Values: map[string]any{"price": 15}},
+		{Offset: 4, Op: EventDelete, DocID: 2},
+		{Offset: 5, Op: EventAdd, DocID: 4, Text: "hello again", Values: map[string]any{"price": "free"}},
+		{Offset: 6, Op: EventUpdate, DocID: 4, Text: "hello once more"},
+		{Offset: 7, Op: EventDelete, DocID: 3},
+	})
+
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(world) = %v, want [1]", got)
+	}
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{4}) {
+		t.Errorf("Search(hello) = %v, want [4]", got)
+	}
+	if got := filter.GetCategories(1, "kind"); !reflect.DeepEqual(got, []string{"book"}) {
+		t.Errorf("kind of doc 1 = %v, want [book]", got)
+	}
+	if got := filter.Get("kind", "farewell").GetCardinality(); got != 0 {
+		t.Errorf("deleted doc 2 still has a category")
+	}
+	if got, want := []int{prices.Get(1), prices.Get(2), prices.Get(4)}, []int{15, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("prices = %v, want %v", got, want)
+	}
+	if c.Offset() != 8 {
+		t.Errorf("Offset() = %d, want 8", c.Offset())
+	}
+}
+
+func TestConsumerCheckpointReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live.idx")
+	events := []Event{
+		{Offset: 10, Op: EventAdd, DocID: 1, Text: "hello world"},
+		{Offset: 11, Op: EventAdd, DocID: 2, Text: "goodbye world"},
+	}
+
+	var hooked uint64
+	c, err := NewConsumer(rs.NewIndex(3), nil,
+		WithCheckpoint(path),
+		WithCheckpointHook(func(offset uint64) error { hooked = offset; return nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runEvents(t, c, events)
+	if data, err := os.ReadFile(path + ".offset"); err != nil || string(data) != "12\n" || hooked != 12 {
+		t.Fatalf("offset file = %q (%v), hook saw %d, want 12", data, err, hooked)
+	}
+
+	// Restart: the stream replays from before the checkpoint
+	idx, err := rs.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = NewConsumer(idx, nil, WithCheckpoint(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Offset() != 12 {
+		t.Fatalf("resumed Offset() = %d, want 12", c.Offset())
+	}
+	runEvents(t, c, append(events,
+		Event{Offset: 10, Op: EventDelete, DocID: 1}, // stale duplicate, skipped
+		Event{Offset: 12, Op: EventDelete, DocID: 2},
+	))
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("after replay Search(world) = %v, want [1]", got)
+	}
+	if c.Offset() != 13 {
+		t.Errorf("Offset() = %d, want 13", c.Offset())
+	}
+}
+
+type failingSource struct{ err error }
+
+func (s failingSource) Next(context.Context) (Event, error) { return Event{}, s.err }
+
+func TestConsumerStops(t *testing.T) {
+	errBroker := errors.New("broker down")
+	c, _ := NewConsumer(rs.NewIndex(3), failingSource{errBroker})
+	if err := c.Run(context.Background()); !errors.Is(err, errBroker) {
+		t.Errorf("Run = %v, want the source error", err)
+	}
+
+	ch := make(chan Event, 1)
+	ch <- Event{Offset: 0, Op: EventAdd, DocID: 7, Text: "hello world"}
+	idx := rs.NewIndex(3)
+	c, _ = NewConsumer(idx, ChannelSource(ch), WithFlushInterval(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run after cancel = %v, want nil", err)
+	}
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{7}) {
+		t.Errorf("Search(hello) = %v, want [7]", got)
+	}
+}
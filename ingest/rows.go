@@ -0,0 +1,265 @@
+// Package ingest loads database tables into roaringsearch structures, covering
+// the common "index my table" path: rows are streamed from a query into Index,
+// BitmapFilter and SortColumn batches, so memory use is bounded by one batch
+// whatever the table size.
+//
+// It works with any database/sql driver (SQLite, Postgres, MySQL, ...):
+//
+//	db, _ := sql.Open("sqlite", "books.db")
+//	idx := rs.NewIndex(3)
+//	filter := rs.NewBitmapFilter()
+//	ratings := rs.NewSortColumn[float64]()
+//
+//	progress, err := ingest.IndexFromRows(ctx, db, idx,
+//		"SELECT id, title, genre, language, rating FROM books", "id", "title",
+//		ingest.WithFilter(filter, "genre", "language"),
+//		ingest.WithSortColumn("rating", ratings),
+//		ingest.WithProgress(func(p rs.IngestProgress) { log.Printf("%d rows", p.Docs) }),
+//	)
+//
+// Consumer keeps an index current afterwards by applying a change stream
+// (Kafka, NATS, ...) with offset checkpoints.
+package ingest
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// DefaultBatchSize is the number of rows buffered before each flush.
+const DefaultBatchSize = 10000
+
+// config holds options for IndexFromRows and IndexRows.
+type config struct {
+	batchSize int
+	progress  func(rs.IngestProgress)
+	args      []any
+	filter    *rs.BitmapFilter
+	fields    []string
+	columns   []columnSink
+}
+
+// columnSink scans one result column into a batch.
+type columnSink struct {
+	column string
+	// bind returns the scan destination, the function adding the scanned
+	// value of a row to the batch, and the function flushing the batch.
+	bind func(batchSize int) (dest any, add func(docID uint32), flush func(ctx context.Context) error)
+}
+
+// Option configures IndexFromRows and IndexRows.
+type Option func(*config)
+
+// WithBatchSize sets how many rows are buffered before each flush.
+// Default is DefaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.batchSize = n
+		}
+	}
+}
+
+// WithProgress sets a callback invoked after every flushed batch.
+func WithProgress(fn func(rs.IngestProgress)) Option {
+	return func(cfg *config) {
+		cfg.progress = fn
+	}
+}
+
+// WithArgs sets the arguments for placeholders in the query of IndexFromRows.
+func WithArgs(args ...any) Option {
+	return func(cfg *config) {
+		cfg.args = args
+	}
+}
+
+// WithFilter adds each row to filter under the given columns, using the
+// column name as the field name and the value as the category. NULL values
+// are skipped.
+func WithFilter(filter *rs.BitmapFilter, columns ...string) Option {
+	return func(cfg *config) {
+		cfg.filter = filter
+		cfg.fields = append(cfg.fields, columns...)
+	}
+}
+
+// WithSortColumn sets each row's value of column in col. The column must be
+// scannable into T; NULL values are skipped.
+func WithSortColumn[T cmp.Ordered](column string, col *rs.SortColumn[T]) Option {
+	return func(cfg *config) {
+		cfg.columns = append(cfg.columns, columnSink{
+			column: column,
+			bind: func(batchSize int) (any, func(uint32), func(context.Context) error) {
+				var value sql.Null[T]
+				batch := col.BatchSize(batchSize)
+				add := func(docID uint32) {
+					if value.Valid {
+						batch.Add(docID, value.V)
+					}
+				}
+				flush := func(context.Context) error {
+					batch.Flush()
+					return nil
+				}
+				return &value, add, flush
+			},
+		})
+	}
+}
+
+// IndexFromRows runs query on db and indexes the text of textCol under the
+// docID in idCol for every row; see IndexRows.
+func IndexFromRows(ctx context.Context, db *sql.DB, idx *rs.Index, query, idCol, textCol string, opts ...Option) (rs.IngestProgress, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	rows, err := db.QueryContext(ctx, query, cfg.args...)
+	if err != nil {
+		return rs.IngestProgress{}, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+	return IndexRows(ctx, rows, idx, idCol, textCol, opts...)
+}
+
+// IndexRows indexes every remaining row of rows: the text of textCol is added
+// to idx under the docID in idCol, and the columns named with WithFilter and
+// WithSortColumn are added to their filter and sort columns. Rows are applied
+// in batches; the progress callback runs after each one.
+//
+// idCol must hold integers in the uint32 range. A NULL text indexes an empty
+// document. On an error the rows before it are indexed and the returned
+// progress counts them. The caller still owns rows and should close it.
+func IndexRows(ctx context.Context, rows *sql.Rows, idx *rs.Index, idCol, textCol string, opts ...Option) (rs.IngestProgress, error) {
+	cfg := config{batchSize: DefaultBatchSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	names, err := rows.Columns()
+	if err != nil {
+		return rs.IngestProgress{}, fmt.Errorf("columns: %w", err)
+	}
+	var discard any
+	dests := make([]any, len(names))
+	for i := range dests {
+		dests[i] = &discard
+	}
+	bindColumn := func(column string, dest any) error {
+		for i, name := range names {
+			if name != column {
+				continue
+			}
+			if dests[i] != &discard {
+				return fmt.Errorf("column %q is mapped more than once", column)
+			}
+			dests[i] = dest
+			return nil
+		}
+		return fmt.Errorf("column %q not in result", column)
+	}
+
+	var id sql.NullInt64
+	var text sql.NullString
+	if err := bindColumn(idCol, &id); err != nil {
+		return rs.IngestProgress{}, err
+	}
+	if err := bindColumn(textCol, &text); err != nil {
+		return rs.IngestProgress{}, err
+	}
+
+	sinks := cfg.columns
+	for _, field := range cfg.fields {
+		sinks = append(sinks, filterSink(cfg.filter, field))
+	}
+	adds := make([]func(uint32), len(sinks))
+	flushes := make([]func(context.Context) error, len(sinks))
+	for i, sink := range sinks {
+		var dest any
+		dest, adds[i], flushes[i] = sink.bind(cfg.batchSize)
+		if err := bindColumn(sink.column, dest); err != nil {
+			return rs.IngestProgress{}, err
+		}
+	}
+
+	batch := idx.BatchSize(cfg.batchSize, rs.WithBatchShards(1))
+	start := time.Now()
+	var progress rs.IngestProgress
+	flush := func() error {
+		n := batch.Len()
+		if n == 0 {
+			return nil
+		}
+		if err := batch.FlushContext(ctx); err != nil {
+			return err
+		}
+		for _, fn := range flushes {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+		progress.Docs += uint64(n)
+		progress.Batches++
+		progress.Elapsed = time.Since(start)
+		if cfg.progress != nil {
+			cfg.progress(progress)
+		}
+		return nil
+	}
+	// finish flushes the buffered rows and returns the final progress with
+	// err, or with the flush error if err is nil.
+	finish := func(err error) (rs.IngestProgress, error) {
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+		progress.Elapsed = time.Since(start)
+		return progress, err
+	}
+
+	for row := 1; rows.Next(); row++ {
+		if err := rows.Scan(dests...); err != nil {
+			return finish(fmt.Errorf("row %d: %w", row, err))
+		}
+		if !id.Valid || id.Int64 < 0 || id.Int64 > math.MaxUint32 {
+			return finish(fmt.Errorf("row %d: %s is not a uint32 docID", row, idCol))
+		}
+		docID := uint32(id.Int64)
+		batch.Add(docID, text.String)
+		for _, add := range adds {
+			add(docID)
+		}
+		if batch.Len() >= cfg.batchSize {
+			if err := flush(); err != nil {
+				return progress, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return finish(fmt.Errorf("read rows: %w", err))
+	}
+	return finish(nil)
+}
+
+// filterSink adds a column's values to filter as categories of field.
+func filterSink(filter *rs.BitmapFilter, field string) columnSink {
+	return columnSink{
+		column: field,
+		bind: func(batchSize int) (any, func(uint32), func(context.Context) error) {
+			var category sql.NullString
+			batch := filter.BatchSize(field, batchSize)
+			add := func(docID uint32) {
+				if category.Valid {
+					batch.Add(docID, category.String)
+				}
+			}
+			return &category, add, batch.FlushContext
+		},
+	}
+}
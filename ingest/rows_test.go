@@ -0,0 +1,153 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	rs "github.com/freeeve/roaringsearch"
+)
+
+// tableDriver serves every query from one in-memory table, so the tests need
+// no real database.
+type tableDriver struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *tableDriver) Open(string) (driver.Conn, error) { return tableConn{d}, nil }
+
+type tableConn struct{ d *tableDriver }
+
+func (c tableConn) Prepare(string) (driver.Stmt, error) { return tableStmt(c), nil }
+func (c tableConn) Close() error                        { return nil }
+func (c tableConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type tableStmt struct{ d *tableDriver }
+
+func (s tableStmt) Close() error  { return nil }
+func (s tableStmt) NumInput() int { return -1 }
+func (s tableStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s tableStmt) Query([]driver.Value) (driver.Rows, error) { return &tableRows{d: s.d}, nil }
+
+type tableRows struct {
+	d   *tableDriver
+	pos int
+}
+
+func (r *tableRows) Columns() []string { return r.d.columns }
+func (r *tableRows) Close() error      { return nil }
+func (r *tableRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.d.rows) {
+		return io.EOF
+	}
+	copy(dest, r.d.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+func openTable(t *testing.T, columns []string, rows [][]driver.Value) *sql.DB {
+	t.Helper()
+	name := "table-" + t.Name()
+	sql.Register(name, &tableDriver{columns: columns, rows: rows})
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestIndexFromRows(t *testing.T) {
+	db := openTable(t,
+		[]string{"id", "title", "genre", "lang", "rating", "notes"},
+		[][]driver.Value{
+			{int64(1), "the quick brown fox", "fable", "en", 4.5, "x"},
+			{int64(2), "der schnelle braune fuchs", "fable", "de", 3.0, nil},
+			{int64(3), "hello world", nil, "en", nil, "y"},
+			{int64(4), nil, "howto", "en", 2.0, nil},
+			{int64(5), "brown bread recipes", "howto", "en", 5.0, nil},
+		})
+
+	idx := rs.NewIndex(3)
+	filter := rs.NewBitmapFilter()
+	ratings := rs.NewSortColumn[float64]()
+	var reports []rs.IngestProgress
+
+	progress, err := IndexFromRows(context.Background(), db, idx,
+		"SELECT id, title, genre, lang, rating, notes FROM books", "id", "title",
+		WithFilter(filter, "genre", "lang"),
+		WithSortColumn("rating", ratings),
+		WithBatchSize(2),
+		WithProgress(func(p rs.IngestProgress) { reports = append(reports, p) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Docs != 5 || progress.Batches != 3 || len(reports) != 3 {
+		t.Errorf("progress = %+v after %d reports, want 5 docs in 3 batches", progress, len(reports))
+	}
+
+	if got := idx.Search("brown"); !reflect.DeepEqual(got, []uint32{1, 5}) {
+		t.Errorf("Search(brown) = %v, want [1 5]", got)
+	}
+	if got := filter.Get("genre", "fable").ToArray(); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("genre=fable = %v, want [1 2]", got)
+	}
+	if got := filter.Get("lang", "en").GetCardinality(); got != 4 {
+		t.Errorf("lang=en has %d docs, want 4", got)
+	}
+	if got := filter.GetCategories(3, "genre"); len(got) != 0 {
+		t.Errorf("NULL genre of doc 3 stored as %v", got)
+	}
+	if got := ratings.Get(5); got != 5.0 {
+		t.Errorf("rating of doc 5 = %v, want 5", got)
+	}
+	top := ratings.SortDesc([]uint32{1, 2, 3, 4, 5}, 2)
+	if len(top) != 2 || top[0].DocID != 5 || top[1].DocID != 1 {
+		t.Errorf("top ratings = %+v, want docs 5 and 1", top)
+	}
+}
+
+func TestIndexFromRowsErrors(t *testing.T) {
+	db := openTable(t,
+		[]string{"id", "body"},
+		[][]driver.Value{
+			{int64(1), "hello world"},
+			{int64(2), "goodbye world"},
+			{int64(-3), "negative"},
+			{int64(4), "never reached"},
+		})
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		idCol, textCol string
+		opts           []Option
+		want           string
+	}{
+		{"missing", "body", nil, `column "missing" not in result`},
+		{"id", "id", nil, `column "id" is mapped more than once`},
+		{"id", "body", []Option{WithFilter(rs.NewBitmapFilter(), "body")}, "mapped more than once"},
+	} {
+		_, err := IndexFromRows(ctx, db, rs.NewIndex(3), "SELECT", tt.idCol, tt.textCol, tt.opts...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("IndexFromRows(%s, %s) error = %v, want %q", tt.idCol, tt.textCol, err, tt.want)
+		}
+	}
+
+	idx := rs.NewIndex(3)
+	progress, err := IndexFromRows(ctx, db, idx, "SELECT", "id", "body")
+	if err == nil || !strings.Contains(err.Error(), "row 3") {
+		t.Fatalf("error = %v, want a row 3 docID error", err)
+	}
+	if progress.Docs != 2 || !reflect.DeepEqual(idx.Search("world"), []uint32{1, 2}) {
+		t.Errorf("rows before the error not indexed: progress %+v", progress)
+	}
+}
@@ -0,0 +1,92 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIngestChannel(t *testing.T) {
+	idx := NewIndex(3)
+	docs := make(chan Document)
+	go func() {
+		defer close(docs)
+		for i := uint32(1); i <= 25; i++ {
+			docs <- Document{ID: i, Text: fmt.Sprintf("%s %d", testHelloWorld, i)}
+		}
+	}()
+
+	var reports []IngestProgress
+	p := idx.IngestChannel(docs, WithIngestBatchSize(10), WithIngestProgress(func(p IngestProgress) {
+		reports = append(reports, p)
+	}))
+
+	if p.Docs != 25 || p.Batches != 3 {
+		t.Errorf("progress = %+v, want 25 docs in 3 batches", p)
+	}
+	if len(reports) != 3 || reports[0].Docs != 10 || reports[2].Docs != 25 {
+		t.Errorf("progress reports = %+v", reports)
+	}
+	if idx.DocCount() != 25 {
+		t.Errorf("DocCount = %d, want 25", idx.DocCount())
+	}
+}
+
+func TestIngestNDJSON(t *testing.T) {
+	input := `{"id": 1, "text": "hello world"}
+
+{"id": 2, "text": "goodbye world", "extra": true}
+`
+	idx := NewIndex(3)
+	p, err := idx.IngestNDJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("IngestNDJSON failed: %v", err)
+	}
+	if p.Docs != 2 {
+		t.Errorf("Docs = %d, want 2", p.Docs)
+	}
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search = %v, want [1 2]", got)
+	}
+
+	custom := NewIndex(3)
+	_, err = custom.IngestNDJSON(strings.NewReader(`{"doc": 7, "body": "custom fields"}`), WithNDJSONFields("doc", "body"))
+	if err != nil {
+		t.Fatalf("IngestNDJSON with custom fields failed: %v", err)
+	}
+	if got := custom.Search("custom"); !reflect.DeepEqual(got, []uint32{7}) {
+		t.Errorf("Search = %v, want [7]", got)
+	}
+
+	// Documents before a malformed line are still indexed
+	partial := NewIndex(3)
+	p, err = partial.IngestNDJSON(strings.NewReader("{\"id\": 1, \"text\": \"ok\"}\n{\"text\": \"no id\"}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected line 2 error, got %v", err)
+	}
+	if p.Docs != 1 {
+		t.Errorf("Docs before error = %d, want 1", p.Docs)
+	}
+}
+
+func TestIngestCSV(t *testing.T) {
+	input := "title,id\n\"hello, world\",1\ngoodbye world,2\n"
+
+	idx := NewIndex(3)
+	p, err := idx.IngestCSV(strings.NewReader(input), WithCSVHeader(), WithCSVColumns(1, 0), WithIngestBatchSize(1))
+	if err != nil {
+		t.Fatalf("IngestCSV failed: %v", err)
+	}
+	if p.Docs != 2 || p.Batches != 2 {
+		t.Errorf("progress = %+v, want 2 docs in 2 batches", p)
+	}
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search = %v, want [1 2]", got)
+	}
+
+	_, err = NewIndex(3).IngestCSV(strings.NewReader("x,hello\n"))
+	if err == nil || !strings.Contains(err.Error(), "record 1") {
+		t.Errorf("expected record 1 error, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package roaringsearch
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Latency histogram layout, in the style of HdrHistogram: durations in
+// nanoseconds fall into power-of-two ranges, each split into latencySub
+// linear sub-buckets, so every bucket is within about 6% of its values.
+// Durations from 2^41ns (about 36 minutes) up share the last bucket.
+const (
+	latencySubBits  = 4
+	latencySub      = 1 << latencySubBits
+	latencyMaxShift = 36
+	latencyBuckets  = (latencyMaxShift + 2) * latencySub
+	latencyMaxValue = 1<<(latencyMaxShift+latencySubBits+1) - 1
+
+	// latencySlots is the number of sub-windows a rolling window is split
+	// into; the oldest is dropped as each new one starts.
+	latencySlots = 6
+
+	// DefaultLatencyWindow is the rolling window of WithLatencyHistogram.
+	DefaultLatencyWindow = time.Minute
+)
+
+// LatencyStats summarizes the search latencies recorded in the rolling window
+// of WithLatencyHistogram. Percentiles are upper bounds of histogram buckets,
+// accurate to about 6%.
+type LatencyStats struct {
+	Window time.Duration // span covered, up to the configured window
+	Count  uint64        // searches recorded in the window
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// WithLatencyHistogram records the duration of every search in a rolling
+// histogram covering the last window (DefaultLatencyWindow when window <= 0),
+// reported by SearchLatency and Stats. Recording is lock-free and
+// allocation-free, so it can stay enabled in production to alert on
+// regressions without wrapping every call site.
+func WithLatencyHistogram(window time.Duration) Option {
+	return indexOption(func(idx *Index) {
+		if window <= 0 {
+			window = DefaultLatencyWindow
+		}
+		idx.queryStatsTracker().latency = newLatencyHistogram(window)
+	})
+}
+
+// latencySlot is the histogram of one sub-window, identified by its epoch:
+// the start time divided by the sub-window length.
+type latencySlot struct {
+	epoch   atomic.Int64
+	max     atomic.Int64
+	buckets [latencyBuckets]atomic.Uint64
+}
+
+// latencyHistogram is a rolling latency histogram of latencySlots sub-windows.
+type latencyHistogram struct {
+	tick  time.Duration // sub-window length
+	reset sync.Mutex    // serializes clearing a slot for a new epoch
+	slots [latencySlots]latencySlot
+}
+
+func newLatencyHistogram(window time.Duration) *latencyHistogram {
+	h := &latencyHistogram{tick: max(window/latencySlots, 1)}
+	for i := range h.slots {
+		h.slots[i].epoch.Store(-latencySlots) // never current
+	}
+	return h
+}
+
+// latencyBucket returns the bucket of a duration in nanoseconds.
+func latencyBucket(v uint64) int {
+	if v < 2*latencySub {
+		return int(v)
+	}
+	v = min(v, latencyMaxValue)
+	shift := bits.Len64(v) - latencySubBits - 1
+	return shift*latencySub + int(v>>shift)
+}
+
+// latencyBucketMax returns the largest duration in bucket i.
+func latencyBucketMax(i int) uint64 {
+	if i < 2*latencySub {
+		return uint64(i)
+	}
+	shift := i/latencySub - 1
+	mantissa := uint64(i%latencySub + latencySub)
+	return (mantissa+1)<<shift - 1
+}
+
+// record adds a search that started at start and took d.
+func (h *latencyHistogram) record(start time.Time, d time.Duration) {
+	epoch := start.UnixNano() / int64(h.tick)
+	slot := &h.slots[epoch%latencySlots]
+	if slot.epoch.Load() != epoch {
+		h.reset.Lock()
+		if slot.epoch.Load() != epoch {
+			for i := range slot.buckets {
+				slot.buckets[i].Store(0)
+			}
+			slot.max.Store(0)
+			slot.epoch.Store(epoch)
+		}
+		h.reset.Unlock()
+	}
+
+	v := max(int64(d), 0)
+	slot.buckets[latencyBucket(uint64(v))].Add(1)
+	for {
+		old := slot.max.Load()
+		if v <= old || slot.max.CompareAndSwap(old, v) {
+			break
+		}
+	}
+}
+
+// snapshot summarizes the sub-windows still inside the window at now.
+func (h *latencyHistogram) snapshot(now time.Time) LatencyStats {
+	current := now.UnixNano() / int64(h.tick)
+	var counts [latencyBuckets]uint64
+	var s LatencyStats
+	oldest := current
+	for i := range h.slots {
+		slot := &h.slots[i]
+		epoch := slot.epoch.Load()
+		if epoch > current || current-epoch >= latencySlots {
+			continue
+		}
+		oldest = min(oldest, epoch)
+		for b := range slot.buckets {
+			n := slot.buckets[b].Load()
+			counts[b] += n
+			s.Count += n
+		}
+		s.Max = max(s.Max, time.Duration(slot.max.Load()))
+	}
+	if s.Count == 0 {
+		return LatencyStats{}
+	}
+	s.Window = now.Sub(time.Unix(0, oldest*int64(h.tick)))
+
+	percentile := func(p float64) time.Duration {
+		rank := uint64(p*float64(s.Count) + 0.5)
+		var seen uint64
+		for b, n := range counts {
+			seen += n
+			if seen >= max(rank, 1) {
+				return min(time.Duration(latencyBucketMax(b)), s.Max)
+			}
+		}
+		return s.Max
+	}
+	s.P50 = percentile(0.50)
+	s.P95 = percentile(0.95)
+	s.P99 = percentile(0.99)
+	return s
+}
+
+// SearchLatency returns percentiles of the search latencies recorded in the
+// rolling window. Returns zero stats unless the index was created with
+// WithLatencyHistogram.
+func (idx *Index) SearchLatency() LatencyStats {
+	if idx.stats == nil || idx.stats.latency == nil {
+		return LatencyStats{}
+	}
+	return idx.stats.latency.snapshot(time.Now())
+}
@@ -0,0 +1,85 @@
+package roaringsearch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	prev := -1
+	for _, v := range []uint64{0, 1, 31, 32, 33, 34, 1000, 1 << 20, 1<<40 + 12345, latencyMaxValue, 1 << 62} {
+		b := latencyBucket(v)
+		if b < prev || b >= latencyBuckets {
+			t.Fatalf("bucket(%d) = %d out of order or range", v, b)
+		}
+		prev = b
+		if hi := latencyBucketMax(b); v <= latencyMaxValue && (v > hi || float64(hi-v) > 0.07*float64(v)+1) {
+			t.Errorf("bucket(%d) max %d too far from value", v, hi)
+		}
+	}
+}
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	h := newLatencyHistogram(time.Minute)
+	now := time.Now()
+	for i := 1; i <= 1000; i++ {
+		h.record(now, time.Duration(i)*time.Microsecond)
+	}
+
+	s := h.snapshot(now)
+	if s.Count != 1000 || s.Max != time.Millisecond {
+		t.Fatalf("count %d max %v", s.Count, s.Max)
+	}
+	for _, c := range []struct {
+		got, want time.Duration
+	}{{s.P50, 500 * time.Microsecond}, {s.P95, 950 * time.Microsecond}, {s.P99, 990 * time.Microsecond}} {
+		if c.got < c.want || float64(c.got) > 1.07*float64(c.want) {
+			t.Errorf("percentile %v, want about %v", c.got, c.want)
+		}
+	}
+
+	// Sub-windows older than the window are dropped
+	if s := h.snapshot(now.Add(50 * time.Second)); s.Count != 1000 {
+		t.Errorf("sub-window five ticks old dropped: count %d", s.Count)
+	}
+	if s := h.snapshot(now.Add(2 * time.Minute)); s.Count != 0 {
+		t.Errorf("expired window still has %d searches", s.Count)
+	}
+	later := now.Add(2 * time.Minute)
+	h.record(later, 5*time.Millisecond)
+	if s := h.snapshot(later); s.Count != 1 || s.P50 > 5*time.Millisecond || s.Max != 5*time.Millisecond {
+		t.Errorf("reused slot = %+v", s)
+	}
+}
+
+func TestSearchLatency(t *testing.T) {
+	idx := NewIndex(3)
+	if s := idx.SearchLatency(); s.Count != 0 {
+		t.Errorf("untracked index reports %d searches", s.Count)
+	}
+
+	var hooked int
+	idx = NewIndex(3, WithLatencyHistogram(0), WithQueryHook(func(QueryStats) { hooked++ }))
+	idx.Add(1, testHelloWorld)
+	idx.Search("hello")
+	idx.SearchAny("world")
+	idx.SearchCount("nothing")
+
+	s := idx.Stats(0).Latency
+	if s.Count != 3 || s.P50 == 0 || s.P99 > s.Max || s.Window <= 0 || s.Window > DefaultLatencyWindow {
+		t.Errorf("Stats().Latency = %+v", s)
+	}
+	if hooked != 3 {
+		t.Errorf("hook called %d times, want 3", hooked)
+	}
+}
+
+func BenchmarkSearchLatencyHistogram(b *testing.B) {
+	idx := NewIndex(3, WithLatencyHistogram(0))
+	idx.Add(1, testHelloWorld)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Search("hello")
+	}
+}
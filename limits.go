@@ -0,0 +1,123 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// WithMaxResults caps the docIDs that Search, SearchAppend, SearchAllTerms,
+// SearchAny, SearchBatch and SearchAndFilter return per query at the n
+// lowest matching docIDs, so a query matching tens of millions of documents
+// cannot allocate an unbounded slice; on a CachedIndex it caps Search,
+// SearchAny and SearchAndFilter. Use SearchLimited to learn that a result was
+// cut short. n <= 0 leaves results uncapped.
+func WithMaxResults(n int) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			idx.maxResults = capResults(idx.maxResults, n)
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			idx.maxResults = capResults(idx.maxResults, n)
+		}),
+	}
+}
+
+// WithMaxQueryMemory caps the memory a query's result slice may take, at 4
+// bytes per docID, as WithMaxResults does by count. When both are set, the
+// smaller cap applies. bytes < 4 leaves results uncapped.
+func WithMaxQueryMemory(bytes int64) SharedOption {
+	return WithMaxResults(int(min(bytes/4, maxInt)))
+}
+
+// WithCachedMaxResults is WithMaxResults for a CachedIndex.
+//
+// Deprecated: WithMaxResults applies to a CachedIndex too.
+func WithCachedMaxResults(n int) CachedIndexOption {
+	return WithMaxResults(n)
+}
+
+// WithCachedMaxQueryMemory is WithMaxQueryMemory for a CachedIndex.
+//
+// Deprecated: WithMaxQueryMemory applies to a CachedIndex too.
+func WithCachedMaxQueryMemory(bytes int64) CachedIndexOption {
+	return WithMaxQueryMemory(bytes)
+}
+
+const maxInt = int64(^uint(0) >> 1)
+
+// capResults returns the smaller of two result caps, where 0 is no cap and
+// invalid caps are ignored.
+func capResults(current, n int) int {
+	if n <= 0 || (current > 0 && current < n) {
+		return current
+	}
+	return n
+}
+
+// TruncatedError is returned by SearchLimited with the first matches of a
+// query whose result exceeded WithMaxResults or WithMaxQueryMemory. It
+// matches ErrTooManyResults with errors.Is.
+type TruncatedError struct {
+	Query    string
+	Total    uint64 // documents that matched
+	Returned int    // documents returned, the lowest docIDs
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("%v: query %q matched %d documents, returned %d", ErrTooManyResults, e.Query, e.Total, e.Returned)
+}
+
+// Is reports whether target is ErrTooManyResults.
+func (e *TruncatedError) Is(target error) bool {
+	return target == ErrTooManyResults
+}
+
+// SearchLimited is like Search but reports a result cut short by the
+// index's result cap: the lowest matching docIDs come with a
+// *TruncatedError recording how many documents matched in total. Callers
+// can show the partial page with a "refine your query" hint, or fall back
+// to SearchCursor to stream everything.
+func (idx *Index) SearchLimited(query string) (matches []uint32, err error) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchLimited", query, len(matches), start) }(time.Now())
+	}
+	matches, err = idx.searchE(query)
+	if isNoMatch(err) {
+		return matches, nil
+	}
+	return matches, err
+}
+
+// SearchLimited is like Index.SearchLimited for a CachedIndex. A bitmap
+// that fails to load returns its error, as from SearchE.
+func (idx *CachedIndex) SearchLimited(query string) ([]uint32, error) {
+	matches, err := idx.SearchE(query)
+	if isNoMatch(err) {
+		return matches, nil
+	}
+	return matches, err
+}
+
+// truncated returns a *TruncatedError if fewer documents were returned than
+// matched.
+func truncated(query string, total uint64, returned int) error {
+	if total <= uint64(returned) {
+		return nil
+	}
+	return &TruncatedError{Query: query, Total: total, Returned: returned}
+}
+
+// limitedArray returns the docIDs of bm, only the limit lowest when
+// limit > 0.
+func limitedArray(bm *roaring.Bitmap, limit int) []uint32 {
+	card := bm.GetCardinality()
+	if limit <= 0 || card <= uint64(limit) {
+		return bm.ToArray()
+	}
+	ids := make([]uint32, limit)
+	it := bm.ManyIterator()
+	it.NextMany(ids)
+	return ids
+}
@@ -0,0 +1,98 @@
+package roaringsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestWithMaxResults(t *testing.T) {
+	full := NewIndex(3)
+	capped := NewIndex(3, WithMaxResults(100), WithMaxQueryMemory(1<<20))
+	for i := uint32(0); i < 1000; i++ {
+		text := testHelloWorld
+		if i%100 == 0 {
+			text = testHelloThere
+		}
+		full.Add(i, text)
+		capped.Add(i, text)
+	}
+
+	all := full.Search("hello")
+	if got := capped.Search("hello"); !reflect.DeepEqual(got, all[:100]) {
+		t.Errorf("capped Search returned %d docs, want the first 100", len(got))
+	}
+	if got := capped.SearchAny("world"); len(got) != 100 || got[99] != 101 {
+		t.Errorf("capped SearchAny returned %d docs ending at %d", len(got), got[len(got)-1])
+	}
+	if got := capped.SearchAppend("hello", []uint32{7}); len(got) != 101 || got[0] != 7 {
+		t.Errorf("capped SearchAppend returned %d docs", len(got))
+	}
+	if got := capped.Search("hello there"); len(got) != 10 {
+		t.Errorf("Search under the cap returned %d docs, want 10", len(got))
+	}
+	odd := roaring.New()
+	for i := uint32(1); i < 1000; i += 2 {
+		odd.Add(i)
+	}
+	if got := capped.SearchAndFilter("hello", odd); len(got) != 100 || got[99] != 199 {
+		t.Errorf("capped SearchAndFilter returned %d docs", len(got))
+	}
+
+	matches, err := capped.SearchLimited("hello")
+	var trunc *TruncatedError
+	if !errors.As(err, &trunc) || !errors.Is(err, ErrTooManyResults) {
+		t.Fatalf("SearchLimited error = %v, want a *TruncatedError", err)
+	}
+	if trunc.Total != 1000 || trunc.Returned != 100 || len(matches) != 100 {
+		t.Errorf("truncated %d of %d, got %d docs", trunc.Returned, trunc.Total, len(matches))
+	}
+	if matches, err := capped.SearchLimited("hello there"); err != nil || len(matches) != 10 {
+		t.Errorf("SearchLimited under the cap = %d docs, %v", len(matches), err)
+	}
+	if _, err := full.SearchLimited("hello"); err != nil {
+		t.Errorf("uncapped SearchLimited error = %v", err)
+	}
+
+	// The smaller cap wins, in either order
+	byMemory := NewIndex(3, WithMaxQueryMemory(40), WithMaxResults(50))
+	for i := uint32(0); i < 20; i++ {
+		byMemory.Add(i, testHelloWorld)
+	}
+	if got := byMemory.Search("hello"); len(got) != 10 {
+		t.Errorf("memory cap returned %d docs, want 10", len(got))
+	}
+
+	path := filepath.Join(t.TempDir(), "capped.sear")
+	if err := full.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path, WithCachedMaxResults(100))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("hello"); !reflect.DeepEqual(got, all[:100]) {
+		t.Errorf("cached capped Search returned %d docs", len(got))
+	}
+	if got := cached.SearchAny("hello"); len(got) != 100 {
+		t.Errorf("cached capped SearchAny returned %d docs", len(got))
+	}
+	if got := cached.SearchAndFilter("hello", odd); len(got) != 100 || got[99] != 199 {
+		t.Errorf("cached capped SearchAndFilter returned %d docs", len(got))
+	}
+	pushed, err := OpenCachedIndex(path, WithCachedMaxResults(100), WithFilterPushdown(0))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer pushed.Close()
+	if got := pushed.SearchAndFilter("hello", odd); len(got) != 100 || got[99] != 199 {
+		t.Errorf("pushdown capped SearchAndFilter returned %d docs", len(got))
+	}
+	if _, err := cached.SearchLimited("hello world"); !errors.Is(err, ErrTooManyResults) {
+		t.Errorf("cached SearchLimited error = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package roaringsearch
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultSlowQueryThreshold is the duration from which WithLogger logs a
+// search as slow.
+const DefaultSlowQueryThreshold = 100 * time.Millisecond
+
+// WithLogger logs index events to l: searches slower than
+// DefaultSlowQueryThreshold (see WithSlowQueryThreshold) at Warn, completed
+// saves and loads at Info, and failed saves at Error. A CachedIndex also
+// logs bitmaps that fail to load from disk at Error, the opened index at
+// Info and cache evictions at Debug; its Search reports a load failure as
+// no match, so the log is where it shows up, and SearchE also returns it.
+// A nil l is ignored.
+//
+// Example:
+//
+//	idx := rs.NewIndex(3, rs.WithLogger(slog.Default()))
+func WithLogger(l *slog.Logger) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			if l == nil {
+				return
+			}
+			q := idx.queryStatsTracker()
+			q.logger = l
+			if q.slowThreshold == 0 {
+				q.slowThreshold = DefaultSlowQueryThreshold
+			}
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			if l == nil {
+				return
+			}
+			idx.logger = l
+			if idx.slowQuery == 0 {
+				idx.slowQuery = DefaultSlowQueryThreshold
+			}
+		}),
+	}
+}
+
+// WithSlowQueryThreshold sets the duration from which WithLogger logs a
+// search. d <= 0 is ignored.
+func WithSlowQueryThreshold(d time.Duration) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			if d > 0 {
+				idx.queryStatsTracker().slowThreshold = d
+			}
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			if d > 0 {
+				idx.slowQuery = d
+			}
+		}),
+	}
+}
+
+// logger returns the index's logger, or nil without WithLogger.
+func (idx *Index) logger() *slog.Logger {
+	if idx.stats == nil {
+		return nil
+	}
+	return idx.stats.logger
+}
+
+// logSlow logs a search that took at least threshold.
+func logSlow(l *slog.Logger, threshold time.Duration, method, query string, results int, d time.Duration) {
+	if d < threshold {
+		return
+	}
+	l.Warn("roaringsearch: slow query",
+		"method", method, "query", query, "results", results, "duration", d)
+}
+
+// WithCachedLogger is WithLogger for a CachedIndex.
+//
+// Deprecated: WithLogger applies to a CachedIndex too.
+func WithCachedLogger(l *slog.Logger) CachedIndexOption {
+	return WithLogger(l)
+}
+
+// WithCachedSlowQueryThreshold is WithSlowQueryThreshold for a CachedIndex.
+//
+// Deprecated: WithSlowQueryThreshold applies to a CachedIndex too.
+func WithCachedSlowQueryThreshold(d time.Duration) CachedIndexOption {
+	return WithSlowQueryThreshold(d)
+}
+
+// logQuery logs a completed search if it was slow. Callers check
+// idx.logger != nil first.
+func (idx *CachedIndex) logQuery(method, query string, results int, start time.Time) {
+	logSlow(idx.logger, idx.slowQuery, method, query, results, time.Since(start))
+}
+
+// logLoadError logs a bitmap that failed to load from disk.
+func (idx *CachedIndex) logLoadError(key uint64, loc ngramLocation, err error) {
+	if idx.logger == nil {
+		return
+	}
+	idx.logger.Error("roaringsearch: load bitmap",
+		"key", key, "offset", loc.offset, "size", loc.size, "error", err)
+}
+
+// logEvictions logs each bitmap the cache evicts at Debug.
+func (idx *CachedIndex) logEvictions() {
+	l := idx.logger
+	idx.lru.onEvict = func(key uint64, size uint64) {
+		l.Debug("roaringsearch: evict bitmap", "key", key, "bytes", size)
+	}
+}
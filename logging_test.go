@@ -0,0 +1,98 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), &buf
+}
+
+func TestWithLogger(t *testing.T) {
+	l, buf := newTestLogger()
+	idx := NewIndex(3, WithLogger(l), WithSlowQueryThreshold(time.Nanosecond))
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+
+	idx.Search("hello")
+	if got := buf.String(); !strings.Contains(got, "slow query") || !strings.Contains(got, "method=Search") {
+		t.Errorf("slow query not logged: %s", got)
+	}
+
+	buf.Reset()
+	path := filepath.Join(t.TempDir(), "logged.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	if got := buf.String(); !strings.Contains(got, "level=INFO") || !strings.Contains(got, "saved index") {
+		t.Errorf("save not logged: %s", got)
+	}
+
+	buf.Reset()
+	if err := idx.SaveToFile(filepath.Join(t.TempDir(), "missing", "x.sear")); err == nil {
+		t.Fatal("SaveToFile into a missing directory succeeded")
+	}
+	if got := buf.String(); !strings.Contains(got, "level=ERROR") {
+		t.Errorf("failed save not logged: %s", got)
+	}
+
+	buf.Reset()
+	if _, err := LoadFromFileWithOptions(path, WithLogger(l)); err != nil {
+		t.Fatalf("LoadFromFileWithOptions: %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "loaded index") {
+		t.Errorf("load not logged: %s", got)
+	}
+
+	// The default threshold keeps fast searches quiet
+	quiet := NewIndex(3, WithLogger(l))
+	quiet.Add(1, testHelloWorld)
+	buf.Reset()
+	quiet.Search("hello")
+	if buf.Len() != 0 {
+		t.Errorf("fast search logged: %s", buf.String())
+	}
+}
+
+func TestWithCachedLogger(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	var data bytes.Buffer
+	if _, err := idx.WriteTo(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	l, buf := newTestLogger()
+	origin := &failingReader{r: bytes.NewReader(data.Bytes())}
+	cached, err := OpenCachedIndexReader(origin, WithCachedLogger(l), WithCacheSize(1),
+		WithCachedSlowQueryThreshold(time.Nanosecond))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := buf.String(); !strings.Contains(got, "opened cached index") {
+		t.Errorf("open not logged: %s", got)
+	}
+
+	buf.Reset()
+	cached.Search("hello")
+	if got := buf.String(); !strings.Contains(got, "evict bitmap") || !strings.Contains(got, "slow query") {
+		t.Errorf("eviction or slow query not logged: %s", got)
+	}
+
+	buf.Reset()
+	origin.fail.Store(true)
+	if got := cached.Search("world"); got != nil {
+		t.Errorf("Search on a failing reader = %v", got)
+	}
+	if got := buf.String(); !strings.Contains(got, "load bitmap") || !strings.Contains(got, errDisk.Error()) {
+		t.Errorf("load failure not logged: %s", got)
+	}
+}
@@ -0,0 +1,308 @@
+package roaringsearch
+
+import "github.com/RoaringBitmap/roaring/v2"
+
+// lruCache is a least-recently-used cache bounded either by entry count, by
+// total value memory as reported by sizeOf, or by the budget of a shared
+// ResourceManager. It is not safe for concurrent use; callers hold their own
+// lock.
+type lruCache[K comparable, V any] struct {
+	entries    map[K]*lruEntry[K, V]
+	head       *lruEntry[K, V] // most recently used
+	tail       *lruEntry[K, V] // least recently used
+	sizeOf     func(V) uint64
+	maxEntries int    // max number of entries (0 = unlimited when using memory budget)
+	maxMemory  int64  // max memory in bytes (0 = use maxEntries instead)
+	memory     uint64 // current memory usage in bytes
+
+	shared *ResourceManager // replaces the limits above when set
+	lock   tryLocker        // the owner's lock, taken by the manager to evict
+
+	onEvict func(key K, size uint64) // called for each evicted entry, if set
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	size  uint64 // memory size of value
+	tick  uint64 // last use on the shared manager's clock
+	prev  *lruEntry[K, V]
+	next  *lruEntry[K, V]
+}
+
+func newLRUCache[K comparable, V any](maxEntries int, sizeOf func(V) uint64) lruCache[K, V] {
+	return lruCache[K, V]{
+		entries:    make(map[K]*lruEntry[K, V]),
+		sizeOf:     sizeOf,
+		maxEntries: maxEntries,
+	}
+}
+
+// bitmapLRU caches bitmaps by key.
+type bitmapLRU[K comparable] = lruCache[K, *roaring.Bitmap]
+
+func newBitmapLRU[K comparable](maxEntries int) bitmapLRU[K] {
+	return newLRUCache[K](maxEntries, (*roaring.Bitmap).GetSizeInBytes)
+}
+
+// setMaxEntries limits the cache by entry count.
+func (c *lruCache[K, V]) setMaxEntries(n int) {
+	if n > 0 {
+		c.maxEntries = n
+	}
+}
+
+// setMemoryBudget limits the cache by memory; the count limit is disabled.
+func (c *lruCache[K, V]) setMemoryBudget(bytes int64) {
+	if bytes > 0 {
+		c.maxMemory = bytes
+		c.maxEntries = 0
+	}
+}
+
+// get returns a cached value and marks it most recently used.
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	entry, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.moveToFront(entry)
+	c.touch(entry)
+	return entry.value, true
+}
+
+// touch stamps an entry with the shared manager's clock, so the manager can
+// find the least recently used entry across caches.
+func (c *lruCache[K, V]) touch(entry *lruEntry[K, V]) {
+	if c.shared != nil {
+		entry.tick = c.shared.clock.Add(1)
+	}
+}
+
+// account adds delta bytes to the shared manager's usage.
+func (c *lruCache[K, V]) account(delta int64) {
+	if c.shared != nil {
+		c.shared.used.Add(delta)
+	}
+}
+
+// add inserts a value, evicting least recently used entries as needed.
+func (c *lruCache[K, V]) add(key K, value V) {
+	size := c.sizeOf(value)
+
+	// Evict based on memory budget or count limit; a shared manager evicts
+	// after the entry is added
+	if c.shared != nil {
+		if size > uint64(c.shared.budget) {
+			return
+		}
+	} else if c.maxMemory > 0 {
+		// Skip caching if single value exceeds entire budget
+		if size > uint64(c.maxMemory) {
+			return
+		}
+		for c.memory+size > uint64(c.maxMemory) && c.tail != nil {
+			c.evict()
+		}
+	} else {
+		for len(c.entries) >= c.maxEntries && c.tail != nil {
+			c.evict()
+		}
+	}
+
+	entry := &lruEntry[K, V]{
+		key:   key,
+		value: value,
+		size:  size,
+	}
+
+	c.entries[key] = entry
+	c.memory += size
+	c.addToFront(entry)
+	c.touch(entry)
+
+	if c.shared != nil {
+		c.account(int64(size))
+		c.shared.reclaim(c)
+	}
+}
+
+func (c *lruCache[K, V]) addToFront(entry *lruEntry[K, V]) {
+	entry.prev = nil
+	entry.next = c.head
+
+	if c.head != nil {
+		c.head.prev = entry
+	}
+	c.head = entry
+
+	if c.tail == nil {
+		c.tail = entry
+	}
+}
+
+func (c *lruCache[K, V]) moveToFront(entry *lruEntry[K, V]) {
+	if entry == c.head {
+		return
+	}
+
+	// Remove from current position
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	}
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	}
+	if entry == c.tail {
+		c.tail = entry.prev
+	}
+
+	// Add to front
+	c.addToFront(entry)
+}
+
+func (c *lruCache[K, V]) evict() {
+	if c.tail == nil {
+		return
+	}
+
+	entry := c.tail
+	delete(c.entries, entry.key)
+	c.memory -= entry.size
+	c.account(-int64(entry.size))
+	if c.onEvict != nil {
+		c.onEvict(entry.key, entry.size)
+	}
+
+	if entry.prev != nil {
+		entry.prev.next = nil
+	}
+	c.tail = entry.prev
+
+	if c.head == entry {
+		c.head = nil
+	}
+}
+
+// replace swaps the value of a cached entry in place, keeping its recency,
+// and evicts as needed if the new value is larger. It reports false if key
+// is not cached.
+func (c *lruCache[K, V]) replace(key K, value V) bool {
+	entry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	size := c.sizeOf(value)
+	c.memory = c.memory - entry.size + size
+	c.account(int64(size) - int64(entry.size))
+	entry.value, entry.size = value, size
+
+	if c.shared != nil {
+		c.shared.reclaim(c)
+	} else if c.maxMemory > 0 {
+		for c.memory > uint64(c.maxMemory) && c.tail != nil {
+			c.evict()
+		}
+	}
+	return true
+}
+
+// remove deletes an entry and returns its value.
+func (c *lruCache[K, V]) remove(key K) (V, bool) {
+	entry, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	} else {
+		c.head = entry.next
+	}
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		c.tail = entry.prev
+	}
+
+	delete(c.entries, key)
+	c.memory -= entry.size
+	c.account(-int64(entry.size))
+	return entry.value, true
+}
+
+// clear removes every entry.
+func (c *lruCache[K, V]) clear() {
+	c.account(-int64(c.memory))
+	c.entries = make(map[K]*lruEntry[K, V])
+	c.head = nil
+	c.tail = nil
+	c.memory = 0
+}
+
+func (c *lruCache[K, V]) len() int {
+	return len(c.entries)
+}
+
+// victim returns the key that adding a value of the given size would evict
+// first, or false if the value fits without eviction.
+func (c *lruCache[K, V]) victim(size uint64) (K, bool) {
+	var zero K
+	if c.tail == nil {
+		return zero, false
+	}
+	if c.shared != nil {
+		return c.tail.key, c.shared.used.Load()+int64(size) > c.shared.budget
+	}
+	if c.maxMemory > 0 {
+		return c.tail.key, c.memory+size > uint64(c.maxMemory)
+	}
+	return c.tail.key, len(c.entries) >= c.maxEntries
+}
+
+// memoryLimit returns the memory the cache may fill, or 0 if it is bounded
+// by entry count.
+func (c *lruCache[K, V]) memoryLimit() int64 {
+	if c.shared != nil {
+		return c.shared.budget
+	}
+	return c.maxMemory
+}
+
+// share makes the cache draw on m's budget instead of its own limits. lock
+// is the lock its owner holds while using it.
+func (c *lruCache[K, V]) share(m *ResourceManager, lock tryLocker) {
+	c.shared, c.lock = m, lock
+	c.maxEntries, c.maxMemory = 0, 0
+	m.register(c)
+}
+
+// leave empties the cache and removes it from its shared manager.
+func (c *lruCache[K, V]) leave() {
+	if c.lock == nil {
+		return
+	}
+	c.lock.Lock()
+	m := c.shared
+	c.clear()
+	c.shared = nil
+	c.lock.Unlock()
+	if m != nil {
+		m.unregister(c)
+	}
+}
+
+// tryLock, unlock, oldest and evictOldest make the cache a resourceMember.
+func (c *lruCache[K, V]) tryLock() bool { return c.lock.TryLock() }
+func (c *lruCache[K, V]) unlock()       { c.lock.Unlock() }
+
+func (c *lruCache[K, V]) oldest() (uint64, bool) {
+	if c.tail == nil {
+		return 0, false
+	}
+	return c.tail.tick, true
+}
+
+func (c *lruCache[K, V]) evictOldest() { c.evict() }
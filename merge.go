@@ -0,0 +1,193 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// Merge unions every n-gram bitmap of other into idx.
+// Both indexes must use the same gram size and should use the same normalizer;
+// normalizers that are recorded in index files (see WithNormalizerChain) must match.
+// Documents present in both are combined, so merging disjoint shards built on
+// separate machines yields the same index as building it in one place.
+func (idx *Index) Merge(other *Index) error {
+	if idx == other {
+		return nil
+	}
+
+	// Lock in a fixed order so concurrent a.Merge(b) and b.Merge(a) can't deadlock
+	if uintptr(unsafe.Pointer(idx)) < uintptr(unsafe.Pointer(other)) {
+		idx.mu.Lock()
+		other.mu.RLock()
+	} else {
+		other.mu.RLock()
+		idx.mu.Lock()
+	}
+	defer idx.mu.Unlock()
+	defer other.mu.RUnlock()
+
+	if idx.gramSize != other.gramSize {
+		return ErrGramSizeMismatch
+	}
+	if err := idx.checkMergeNormalizerLocked(other.recordedNormalizerLocked()); err != nil {
+		return err
+	}
+	remap, err := idx.exactRemapLocked(other.exact)
+	if err != nil {
+		return err
+	}
+
+	for key, bm := range other.bitmaps {
+		idx.mergeBitmapLocked(remap.key(key), bm, true)
+	}
+	idx.docs.Or(other.docs)
+	return nil
+}
+
+// checkMergeNormalizerLocked returns ErrNormalizerMismatch if the merged
+// index recorded a different normalizer than idx.
+func (idx *Index) checkMergeNormalizerLocked(other string) error {
+	if own := idx.recordedNormalizerLocked(); own != other {
+		return fmt.Errorf("%w: merging %q into %q", ErrNormalizerMismatch, other, own)
+	}
+	return nil
+}
+
+// keyRemap translates exact keys of a merged index into idx's key space.
+type keyRemap map[uint64]uint64
+
+func (m keyRemap) key(key uint64) uint64 {
+	if mapped, ok := m[key]; ok {
+		return mapped
+	}
+	return key
+}
+
+// exactRemapLocked assigns idx keys for every n-gram in the other index's
+// exact dictionary. Both indexes must agree on whether exact keys are used,
+// since hashed keys cannot be translated into exact ones.
+func (idx *Index) exactRemapLocked(other *ngramDict) (keyRemap, error) {
+	if (idx.exact == nil) != (other == nil) {
+		return nil, ErrKeyModeMismatch
+	}
+	if other == nil {
+		return nil, nil
+	}
+
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	remap := make(keyRemap, len(other.ngrams))
+	for key, ngram := range other.ngrams {
+		remap[key] = idx.exact.assign([]rune(ngram))
+	}
+	return remap, nil
+}
+
+// mergeBitmapLocked ORs bm into the bitmap for key. When shared is false, bm
+// is owned by the caller and may be stored directly instead of copied.
+func (idx *Index) mergeBitmapLocked(key uint64, bm *roaring.Bitmap, shared bool) {
+	idx.forward.recordBitmap(key, bm)
+	if existing, ok := idx.writableBitmapLocked(key); ok {
+		existing.Or(bm)
+		return
+	}
+	if shared {
+		bm = bm.Clone()
+	}
+	idx.bitmaps[key] = bm
+}
+
+// MergeFiles loads and unions several index files into a new Index.
+// Files are streamed one n-gram at a time, so peak memory is the merged
+// index plus a single bitmap. All files must share the same gram size and
+// recorded normalizer chain, or ErrNormalizerMismatch is returned. Like
+// LoadFromFile, the result uses the chain recorded in the files, or the
+// default normalizer if none was recorded.
+func MergeFiles(paths ...string) (*Index, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("merge files: no paths given")
+	}
+
+	idx, err := LoadFromFile(paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", paths[0], err)
+	}
+
+	for _, path := range paths[1:] {
+		if err := idx.mergeFile(path); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return idx, nil
+}
+
+// mergeFile streams the entries of an index file into idx.
+func (idx *Index) mergeFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	return idx.mergeFrom(bufio.NewReader(f))
+}
+
+// mergeFrom unions an encoded index from r into idx.
+// A trailing forward index section is not read; idx's own forward index, if
+// any, is updated from the merged bitmaps.
+func (idx *Index) mergeFrom(r io.Reader) error {
+	gramSize, fileVersion, _, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	ext, _, err := readExtensions(r, fileVersion)
+	if err != nil {
+		return err
+	}
+
+	countBuf := make([]byte, 4)
+	if _, err := io.ReadFull(r, countBuf); err != nil {
+		return fmt.Errorf("read ngram count: %w", err)
+	}
+	ngramCount := binary.LittleEndian.Uint32(countBuf)
+	if ngramCount > maxNgramCount {
+		return ErrInvalidCount
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if gramSize != idx.gramSize {
+		return ErrGramSizeMismatch
+	}
+	if err := idx.checkMergeNormalizerLocked(ext.normalizer); err != nil {
+		return err
+	}
+	remap, err := idx.exactRemapLocked(ext.exact)
+	if err != nil {
+		return err
+	}
+
+	keyBuf := make([]byte, 8)
+	sizeBuf := make([]byte, 4)
+
+	for i := uint32(0); i < ngramCount; i++ {
+		key, bm, _, err := readNgramEntry(r, keyBuf, sizeBuf)
+		if err != nil {
+			return err
+		}
+		idx.mergeBitmapLocked(remap.key(key), bm, false)
+		idx.docs.Or(bm)
+	}
+
+	return nil
+}
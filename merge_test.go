@@ -0,0 +1,91 @@
+package roaringsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexMerge(t *testing.T) {
+	a := NewIndex(3)
+	a.Add(1, testHelloWorld)
+	a.Add(2, testHelloThere)
+
+	b := NewIndex(3)
+	b.Add(3, testGoodbyeWorld)
+	b.Add(4, "hello again")
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if got := a.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2, 4}) {
+		t.Errorf("Search(hello) = %v, want [1 2 4]", got)
+	}
+	if got := a.Search("world"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("Search(world) = %v, want [1 3]", got)
+	}
+	if a.DocCount() != 4 {
+		t.Errorf("DocCount = %d, want 4", a.DocCount())
+	}
+
+	// Merged bitmaps must not alias the source index
+	b.Remove(4)
+	if got := a.Search("again"); !reflect.DeepEqual(got, []uint32{4}) {
+		t.Errorf("Search(again) after source remove = %v, want [4]", got)
+	}
+
+	if err := a.Merge(NewIndex(2)); !errors.Is(err, ErrGramSizeMismatch) {
+		t.Errorf("Merge with different gram size error = %v, want ErrGramSizeMismatch", err)
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	shards := []map[uint32]string{
+		{1: testHelloWorld},
+		{2: testHelloThere},
+		{3: testGoodbyeWorld, 1: "extra words"},
+	}
+	var paths []string
+	for i, docs := range shards {
+		idx := NewIndex(3)
+		for id, text := range docs {
+			idx.Add(id, text)
+		}
+		path := filepath.Join(dir, string(rune('a'+i))+".sear")
+		if err := idx.SaveToFile(path); err != nil {
+			t.Fatalf(errSaveToFile, err)
+		}
+		paths = append(paths, path)
+	}
+
+	merged, err := MergeFiles(paths...)
+	if err != nil {
+		t.Fatalf("MergeFiles failed: %v", err)
+	}
+	if got := merged.Search("world"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("Search(world) = %v, want [1 3]", got)
+	}
+	if got := merged.Search("extra"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(extra) = %v, want [1]", got)
+	}
+	if merged.DocCount() != 3 {
+		t.Errorf("DocCount = %d, want 3", merged.DocCount())
+	}
+
+	other := NewIndex(4)
+	other.Add(9, "mismatch")
+	badPath := filepath.Join(dir, "bad.sear")
+	if err := other.SaveToFile(badPath); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	if _, err := MergeFiles(paths[0], badPath); !errors.Is(err, ErrGramSizeMismatch) {
+		t.Errorf("MergeFiles mismatch error = %v, want ErrGramSizeMismatch", err)
+	}
+	if _, err := MergeFiles(); err == nil {
+		t.Error("MergeFiles with no paths should fail")
+	}
+}
@@ -0,0 +1,138 @@
+package roaringsearch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxSourceChecksumLen bounds the recorded source checksum.
+const maxSourceChecksumLen = 1024
+
+// IndexMetadata describes an index file, so a fleet of servers can check
+// that each one serves the intended artifact.
+type IndexMetadata struct {
+	FormatVersion uint16 // file format version
+	GramSize      int
+	Normalizer    string // recorded normalizer chain, "" for the default or an unrecorded normalizer
+	NgramCount    int
+
+	// The fields below come from the metadata section that WithMetadata
+	// adds to the file, and are zero when Recorded is false.
+	Recorded       bool
+	CreatedAt      time.Time // when the file was written; zero under WithDeterministicBuild
+	DocCount       uint64
+	TotalPostings  uint64 // sum of the cardinalities of all n-gram bitmaps
+	SourceChecksum string // as passed to WithMetadata
+}
+
+// fileMetadata is the part of IndexMetadata an Index carries between files.
+type fileMetadata struct {
+	createdAt      time.Time
+	sourceChecksum string
+}
+
+// WithMetadata records an IndexMetadata section in files written by the
+// index: the time of writing, document count, total postings and
+// sourceChecksum, which identifies the data the index was built from (for
+// example a hash of the corpus) and may be empty. The section is read by
+// OpenCachedIndex without loading any bitmap. It makes WriteTo write file
+// version 4.
+func WithMetadata(sourceChecksum string) Option {
+	if len(sourceChecksum) > maxSourceChecksumLen {
+		sourceChecksum = sourceChecksum[:maxSourceChecksumLen]
+	}
+	return indexOption(func(idx *Index) {
+		idx.metadata = &fileMetadata{sourceChecksum: sourceChecksum}
+	})
+}
+
+// Metadata describes the index as WriteTo would write it now. CreatedAt is
+// that of the file the index was loaded from, if it recorded one.
+func (idx *Index) Metadata() IndexMetadata {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	normalizer := idx.recordedNormalizerLocked()
+	meta := IndexMetadata{
+		FormatVersion: idx.fileVersionLocked(normalizer),
+		GramSize:      idx.gramSize,
+		Normalizer:    normalizer,
+		NgramCount:    len(idx.bitmaps),
+	}
+	if idx.metadata != nil {
+		meta.Recorded = true
+		meta.CreatedAt = idx.metadata.createdAt
+		meta.DocCount = idx.docs.GetCardinality()
+		meta.TotalPostings = idx.totalPostingsLocked()
+		meta.SourceChecksum = idx.metadata.sourceChecksum
+	}
+	return meta
+}
+
+func (idx *Index) totalPostingsLocked() uint64 {
+	var total uint64
+	for _, bm := range idx.bitmaps {
+		total += bm.GetCardinality()
+	}
+	return total
+}
+
+// writeMetadata writes the metadata section: creation time in Unix
+// nanoseconds (0 if not recorded), document count, total postings and the
+// length-prefixed source checksum.
+func (idx *Index) writeMetadata(w io.Writer) (int64, error) {
+	var created int64
+	if !idx.deterministic {
+		created = time.Now().UnixNano()
+	}
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(created))
+	buf = binary.LittleEndian.AppendUint64(buf, idx.docs.GetCardinality())
+	buf = binary.LittleEndian.AppendUint64(buf, idx.totalPostingsLocked())
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(idx.metadata.sourceChecksum)))
+	buf = append(buf, idx.metadata.sourceChecksum...)
+
+	n, err := w.Write(buf)
+	if err != nil {
+		return int64(n), fmt.Errorf("write metadata: %w", err)
+	}
+	return int64(n), nil
+}
+
+// readMetadata reads the section written by writeMetadata into meta.
+func readMetadata(r io.Reader, meta *IndexMetadata) (int64, error) {
+	var buf [26]byte
+	n, err := io.ReadFull(r, buf[:])
+	read := int64(n)
+	if err != nil {
+		return read, fmt.Errorf("read metadata: %w", err)
+	}
+	if created := int64(binary.LittleEndian.Uint64(buf[0:8])); created != 0 {
+		meta.CreatedAt = time.Unix(0, created)
+	}
+	meta.DocCount = binary.LittleEndian.Uint64(buf[8:16])
+	meta.TotalPostings = binary.LittleEndian.Uint64(buf[16:24])
+
+	checksumLen := binary.LittleEndian.Uint16(buf[24:26])
+	if checksumLen > maxSourceChecksumLen {
+		return read, ErrInvalidSize
+	}
+	checksum := make([]byte, checksumLen)
+	n, err = io.ReadFull(r, checksum)
+	read += int64(n)
+	if err != nil {
+		return read, fmt.Errorf("read source checksum: %w", err)
+	}
+	meta.SourceChecksum = string(checksum)
+	meta.Recorded = true
+	return read, nil
+}
+
+// Metadata describes the index file. It is read when the file is opened or
+// refreshed, and loads no bitmap.
+func (idx *CachedIndex) Metadata() IndexMetadata {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.metadata
+}
@@ -0,0 +1,92 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithMetadata(t *testing.T) {
+	idx := NewIndex(3, WithMetadata("sha256:abc"), WithNormalizerChain(NormalizerChain{NormalizerNFKCFold}))
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+
+	meta := idx.Metadata()
+	if !meta.Recorded || meta.FormatVersion != versionExtended || meta.DocCount != 2 ||
+		meta.SourceChecksum != "sha256:abc" || meta.Normalizer != NormalizerNFKCFold {
+		t.Errorf("Metadata = %+v", meta)
+	}
+	if want := idx.Stats(0).Postings; meta.TotalPostings != want {
+		t.Errorf("TotalPostings = %d, want %d", meta.TotalPostings, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "meta.sear")
+	before := time.Now()
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	got := cached.Metadata()
+	if got.CreatedAt.Before(before.Add(-time.Second)) || got.CreatedAt.After(time.Now()) {
+		t.Errorf("CreatedAt = %v, want about %v", got.CreatedAt, before)
+	}
+	meta.CreatedAt = got.CreatedAt
+	if got != meta {
+		t.Errorf("cached Metadata = %+v, want %+v", got, meta)
+	}
+
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if lm := loaded.Metadata(); !lm.CreatedAt.Equal(got.CreatedAt) || lm.SourceChecksum != "sha256:abc" {
+		t.Errorf("loaded Metadata = %+v", lm)
+	}
+	if got := loaded.Search("hello"); len(got) != 2 {
+		t.Errorf("Search after load = %v", got)
+	}
+}
+
+func TestMetadataNotRecorded(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	path := filepath.Join(t.TempDir(), "plain.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+
+	want := IndexMetadata{FormatVersion: version, GramSize: 3, NgramCount: idx.NgramCount()}
+	if got := cached.Metadata(); got != want {
+		t.Errorf("Metadata = %+v, want %+v", got, want)
+	}
+	if got := idx.Metadata(); got != want {
+		t.Errorf("Index Metadata = %+v, want %+v", got, want)
+	}
+}
+
+func TestMetadataDeterministic(t *testing.T) {
+	idx := NewIndex(3, WithMetadata(""), WithDeterministicBuild())
+	idx.Add(1, testHelloWorld)
+	path := filepath.Join(t.TempDir(), "det.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if meta := cached.Metadata(); !meta.Recorded || !meta.CreatedAt.IsZero() {
+		t.Errorf("Metadata = %+v, want recorded without CreatedAt", meta)
+	}
+}
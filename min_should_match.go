@@ -0,0 +1,177 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// MinShouldMatch says how many of a query's clauses (n-grams or terms) a
+// document must contain. The zero value requires all of them.
+type MinShouldMatch struct {
+	value   int  // count or percentage; negative counts from the total
+	percent bool // value is a percentage of the total
+	set     bool // false for the zero value, which requires everything
+}
+
+// ParseMinShouldMatch parses a minimum-should-match spec:
+//
+//	"3"    at least 3 clauses
+//	"-1"   all but 1 clause
+//	"75%"  at least 75% of the clauses, rounded down
+//	"-25%" all but 25% of the clauses, rounded down
+//
+// Whatever the spec, at least one clause and at most all of them are required.
+func ParseMinShouldMatch(spec string) (MinShouldMatch, error) {
+	s := strings.TrimSpace(spec)
+	percent := strings.HasSuffix(s, "%")
+	if percent {
+		s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	}
+
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return MinShouldMatch{}, fmt.Errorf("%w: %q", ErrMinShouldMatch, spec)
+	}
+	if percent && (value < -100 || value > 100) {
+		return MinShouldMatch{}, fmt.Errorf("%w: %q is outside -100%%..100%%", ErrMinShouldMatch, spec)
+	}
+	return MinShouldMatch{value: value, percent: percent, set: true}, nil
+}
+
+// Required returns how many of total clauses must match, between 1 and total.
+func (m MinShouldMatch) Required(total int) int {
+	if total <= 0 {
+		return 0
+	}
+	if !m.set {
+		return total
+	}
+
+	n := m.value
+	if m.percent {
+		n = total * m.value / 100
+	}
+	if n < 0 || (n == 0 && m.value < 0) {
+		n += total
+	}
+	return max(1, min(n, total))
+}
+
+// String returns the spec in the form accepted by ParseMinShouldMatch.
+func (m MinShouldMatch) String() string {
+	if !m.set {
+		return "100%"
+	}
+	if m.percent {
+		return strconv.Itoa(m.value) + "%"
+	}
+	return strconv.Itoa(m.value)
+}
+
+// matchConfig holds options for SearchMatch.
+type matchConfig struct {
+	msm   MinShouldMatch
+	terms bool
+	limit int
+}
+
+// MatchOption configures a SearchMatch query.
+type MatchOption func(*matchConfig)
+
+// WithMinShouldMatch sets how many of the query's n-grams (or terms, with
+// WithTermMatching) a document must contain; see ParseMinShouldMatch for the
+// syntax. An invalid spec is ignored, leaving every clause required.
+func WithMinShouldMatch(spec string) MatchOption {
+	return func(cfg *matchConfig) {
+		if msm, err := ParseMinShouldMatch(spec); err == nil {
+			cfg.msm = msm
+		}
+	}
+}
+
+// WithTermMatching counts whitespace-separated terms instead of n-grams.
+// A term matches a document that contains all of its n-grams, as in
+// SearchAllTerms, so "75%" of a four-word query tolerates one missing word.
+func WithTermMatching() MatchOption {
+	return func(cfg *matchConfig) {
+		cfg.terms = true
+	}
+}
+
+// WithMatchLimit returns at most n documents, best scores first.
+// n <= 0 returns all matches.
+func WithMatchLimit(n int) MatchOption {
+	return func(cfg *matchConfig) {
+		cfg.limit = n
+	}
+}
+
+// SearchMatch returns documents containing at least the minimum-should-match
+// number of the query's n-grams, or of its terms with WithTermMatching, best
+// scores first. Scores count the matched n-grams or terms.
+//
+// Without options every clause is required, like Search; WithMinShouldMatch("1")
+// gives the recall of SearchAny. Unlike SearchThreshold, the requirement is
+// computed from all clauses of the query, so clauses absent from the index
+// still count against a document.
+func (idx *Index) SearchMatch(query string, opts ...MatchOption) (result SearchResult) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchMatch", query, len(result.DocIDs), start) }(time.Now())
+	}
+
+	var cfg matchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.terms {
+		return idx.searchMatchTerms(query, cfg)
+	}
+
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	required := cfg.msm.Required(len(keys))
+	if required == 0 {
+		return SearchResult{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	levels, threshold := idx.thresholdLevelsLocked(keys, required)
+	if levels == nil || threshold < required {
+		return SearchResult{}
+	}
+	return thresholdResult(levels, threshold, cfg.limit)
+}
+
+// searchMatchTerms is SearchMatch with terms as clauses.
+func (idx *Index) searchMatchTerms(query string, cfg matchConfig) SearchResult {
+	terms := strings.Fields(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	bitmaps := make([]*roaring.Bitmap, 0, len(terms))
+	total := 0
+	for _, term := range terms {
+		var keyBuf [queryKeyBufSize]uint64
+		if len(idx.appendQueryKeys(keyBuf[:0], term)) == 0 {
+			continue // too short to match, as in SearchAllTerms
+		}
+		total++
+		if bm := idx.termBitmapLocked(term); !bm.IsEmpty() {
+			bitmaps = append(bitmaps, bm)
+		}
+	}
+
+	required := cfg.msm.Required(total)
+	if required == 0 || required > len(bitmaps) {
+		return SearchResult{}
+	}
+	return thresholdResult(matchLevels(bitmaps), required, cfg.limit)
+}
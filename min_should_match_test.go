@@ -0,0 +1,113 @@
+package roaringsearch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseMinShouldMatch(t *testing.T) {
+	tests := []struct {
+		spec  string
+		total int
+		want  int
+	}{
+		{"3", 5, 3},
+		{"7", 5, 5},
+		{"0", 5, 1},
+		{"-1", 5, 4},
+		{"-10", 5, 1},
+		{"75%", 5, 3},
+		{"75%", 4, 3},
+		{"100%", 4, 4},
+		{"1%", 4, 1},
+		{"-25%", 5, 4},
+		{"-25%", 3, 3},
+		{" 50 % ", 10, 5},
+		{"3", 0, 0},
+	}
+	for _, tt := range tests {
+		m, err := ParseMinShouldMatch(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseMinShouldMatch(%q) error: %v", tt.spec, err)
+		}
+		if got := m.Required(tt.total); got != tt.want {
+			t.Errorf("ParseMinShouldMatch(%q).Required(%d) = %d, want %d", tt.spec, tt.total, got, tt.want)
+		}
+	}
+
+	var all MinShouldMatch
+	if got := all.Required(6); got != 6 {
+		t.Errorf("zero MinShouldMatch.Required(6) = %d, want 6", got)
+	}
+	if got := all.String(); got != "100%" {
+		t.Errorf("zero MinShouldMatch.String() = %q, want 100%%", got)
+	}
+	if m, _ := ParseMinShouldMatch("-25%"); m.String() != "-25%" {
+		t.Errorf("String() = %q, want -25%%", m.String())
+	}
+
+	for _, spec := range []string{"", "abc", "75%%", "150%", "1.5"} {
+		if _, err := ParseMinShouldMatch(spec); !errors.Is(err, ErrMinShouldMatch) {
+			t.Errorf("ParseMinShouldMatch(%q) error = %v, want ErrMinShouldMatch", spec, err)
+		}
+	}
+}
+
+func TestSearchMatch(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, "quick brown fox")
+	idx.Add(2, "quick brown dog")
+	idx.Add(3, "slow brown fox")
+	idx.Add(4, "red panda")
+
+	// Term level: "quick brown fox" has 3 terms
+	tests := []struct {
+		spec string
+		want []uint32
+	}{
+		{"100%", []uint32{1}},
+		{"-1", []uint32{1, 2, 3}},
+		{"66%", []uint32{1, 2, 3}},
+		{"1", []uint32{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		got := idx.SearchMatch("quick brown fox", WithTermMatching(), WithMinShouldMatch(tt.spec))
+		if !reflect.DeepEqual(got.DocIDs, tt.want) {
+			t.Errorf("SearchMatch terms %s = %v, want %v", tt.spec, got.DocIDs, tt.want)
+		}
+	}
+
+	got := idx.SearchMatch("quick brown fox", WithTermMatching(), WithMinShouldMatch("-1"))
+	if got.Scores[1] != 3 || got.Scores[2] != 2 {
+		t.Errorf("term scores = %v, want doc 1: 3, doc 2: 2", got.Scores)
+	}
+
+	// A term missing from the index still counts toward the total
+	if got := idx.SearchMatch("quick brown zebra", WithTermMatching()); len(got.DocIDs) != 0 {
+		t.Errorf("SearchMatch with an unindexed term = %v, want none", got.DocIDs)
+	}
+	if got := idx.SearchMatch("quick brown zebra", WithTermMatching(), WithMinShouldMatch("-1")); !reflect.DeepEqual(got.DocIDs, []uint32{1, 2}) {
+		t.Errorf("SearchMatch -1 with an unindexed term = %v, want [1 2]", got.DocIDs)
+	}
+
+	// N-gram level defaults to all n-grams, like Search
+	if got := idx.SearchMatch("brown fox"); !reflect.DeepEqual(got.DocIDs, idx.Search("brown fox")) {
+		t.Errorf("SearchMatch(brown fox) = %v, want Search result %v", got.DocIDs, idx.Search("brown fox"))
+	}
+	// A misspelled word still matches through its remaining n-grams
+	if got := idx.SearchMatch("quick brawn", WithMinShouldMatch("60%")); !reflect.DeepEqual(got.DocIDs, []uint32{1, 2}) {
+		t.Errorf("SearchMatch(quick brawn, 60%%) = %v, want [1 2]", got.DocIDs)
+	}
+	if got := idx.SearchMatch("brown", WithMinShouldMatch("1"), WithMatchLimit(2)); len(got.DocIDs) != 2 {
+		t.Errorf("SearchMatch with limit 2 returned %d docs", len(got.DocIDs))
+	}
+
+	// Invalid specs are ignored
+	if got := idx.SearchMatch("quick brown fox", WithTermMatching(), WithMinShouldMatch("lots")); !reflect.DeepEqual(got.DocIDs, []uint32{1}) {
+		t.Errorf("SearchMatch with invalid spec = %v, want [1]", got.DocIDs)
+	}
+	if got := idx.SearchMatch("ab"); len(got.DocIDs) != 0 {
+		t.Errorf("SearchMatch of a short query = %v, want none", got.DocIDs)
+	}
+}
@@ -0,0 +1,141 @@
+package roaringsearch
+
+import "github.com/RoaringBitmap/roaring/v2"
+
+// MissingNgramPolicy decides what an AND search does when a query n-gram
+// occurs in no document, as happens with a typo or OCR noise.
+type MissingNgramPolicy int
+
+const (
+	// MissingNgramFail matches nothing: every n-gram of the query is
+	// required. This is the default.
+	MissingNgramFail MissingNgramPolicy = iota
+
+	// MissingNgramIgnore drops the missing n-grams and returns the documents
+	// containing all the others.
+	MissingNgramIgnore
+
+	// MissingNgramThreshold drops the missing n-grams and returns the
+	// documents containing all but gramSize-1 of the others, so a typo whose
+	// other n-grams do exist elsewhere in the index is tolerated too.
+	MissingNgramThreshold
+)
+
+// WithMissingNgramPolicy sets what Search, SearchAppend, SearchAllTerms,
+// SearchBatch, SearchBitmap, SearchWithLimit, SearchCallback and
+// SearchAndFilter do when a query n-gram is not in the index. SearchE still
+// returns the ErrNgramNotFound error, along with the best-effort matches of a
+// lenient policy. On a CachedIndex it applies to Search, SearchBatch,
+// SearchLimited, SearchE and SearchAndFilter. Unknown policies are ignored.
+//
+// Example:
+//
+//	idx := rs.NewIndex(3, rs.WithMissingNgramPolicy(rs.MissingNgramThreshold))
+//	idx.Search("quikc brown") // still finds "quick brown fox"
+func WithMissingNgramPolicy(p MissingNgramPolicy) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			if p.valid() {
+				idx.missingNgrams = p
+			}
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			if p.valid() {
+				idx.missingNgrams = p
+			}
+		}),
+	}
+}
+
+// WithCachedMissingNgramPolicy is WithMissingNgramPolicy for a CachedIndex.
+//
+// Deprecated: WithMissingNgramPolicy applies to a CachedIndex too.
+func WithCachedMissingNgramPolicy(p MissingNgramPolicy) CachedIndexOption {
+	return WithMissingNgramPolicy(p)
+}
+
+func (p MissingNgramPolicy) valid() bool {
+	return p >= MissingNgramFail && p <= MissingNgramThreshold
+}
+
+// collectPresentLocked gathers the bitmaps of the keys that are in the
+// index into s.bitmaps, unsorted.
+func (s *searchScratch) collectPresentLocked(idx *Index, keys []uint64) {
+	s.bitmaps = s.bitmaps[:0]
+	for _, key := range keys {
+		if bm, ok := idx.bitmapLocked(key); ok {
+			s.bitmaps = append(s.bitmaps, bm)
+		}
+	}
+}
+
+// collectMatchingLocked is collectLocked under the index's policy: when a
+// key is missing and the policy is lenient, s.bitmaps is left holding
+// bitmaps whose intersection is the policy's matches. Returns false if
+// nothing can match.
+func (s *searchScratch) collectMatchingLocked(idx *Index, keys []uint64) bool {
+	if s.collectLocked(idx, keys) {
+		return true
+	}
+	if idx.missingNgrams == MissingNgramFail {
+		return false
+	}
+	s.collectPresentLocked(idx, keys)
+	s.reducePartial(idx.missingNgrams, idx.gramSize)
+	return len(s.bitmaps) > 0
+}
+
+// reducePartial replaces the present bitmaps of a query in s.bitmaps, under
+// a lenient policy, with bitmaps whose intersection is the policy's
+// matches, smallest first.
+func (s *searchScratch) reducePartial(policy MissingNgramPolicy, gramSize int) {
+	if policy == MissingNgramThreshold && len(s.bitmaps) > 1 {
+		bm := partialBitmap(policy, s.bitmaps, gramSize)
+		s.bitmaps = s.bitmaps[:0]
+		if bm != nil {
+			s.bitmaps = append(s.bitmaps, bm)
+		}
+	}
+	sortByCardinality(s.bitmaps)
+}
+
+// appendPartialLocked appends to dst, up to the index's result limit, the
+// matches of keys under the index's lenient policy, for a query with a key
+// missing from the index.
+func (s *searchScratch) appendPartialLocked(idx *Index, keys []uint64, dst []uint32) []uint32 {
+	s.collectPresentLocked(idx, keys)
+	if len(s.bitmaps) == 0 {
+		return dst
+	}
+	if idx.missingNgrams == MissingNgramThreshold {
+		bm := partialBitmap(idx.missingNgrams, s.bitmaps, idx.gramSize)
+		if bm == nil {
+			return dst
+		}
+		return append(dst, limitedArray(bm, idx.maxResults)...)
+	}
+	sortByCardinality(s.bitmaps)
+	dst, _ = s.appendCollectedMax(dst, idx.maxResults)
+	return dst
+}
+
+// partialBitmap returns the documents matching the present bitmaps of a
+// query under a lenient policy, or nil if there are none. A single bitmap is
+// returned as is; do not modify the result.
+func partialBitmap(policy MissingNgramPolicy, bitmaps []*roaring.Bitmap, gramSize int) *roaring.Bitmap {
+	switch {
+	case len(bitmaps) == 0:
+		return nil
+	case len(bitmaps) == 1:
+		return bitmaps[0]
+	case policy == MissingNgramThreshold:
+		levels := matchLevels(bitmaps)
+		threshold := max(len(bitmaps)-(gramSize-1), 1)
+		if threshold > len(levels) {
+			return nil
+		}
+		return levels[threshold-1]
+	}
+	sortByCardinality(bitmaps)
+	return roaring.FastAnd(bitmaps...)
+}
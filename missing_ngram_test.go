@@ -0,0 +1,116 @@
+package roaringsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func missingNgramIndex(opts ...Option) *Index {
+	idx := NewIndex(3, opts...)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, testHelloWorld)
+	idx.Add(3, "quick red fox")
+	idx.Add(4, "raw data")
+	return idx
+}
+
+func TestWithMissingNgramPolicy(t *testing.T) {
+	tests := []struct {
+		policy MissingNgramPolicy
+		query  string
+		want   []uint32
+	}{
+		{MissingNgramFail, "quikc brown", nil},
+		{MissingNgramIgnore, "quikc brown", []uint32{1}},
+		{MissingNgramThreshold, "quikc brown", []uint32{1}},
+		// "raw" exists, so only the threshold policy tolerates it
+		{MissingNgramIgnore, "quick brawn", nil},
+		{MissingNgramThreshold, "quick brawn", []uint32{1}},
+		{MissingNgramIgnore, "xyzzy", nil},
+		{MissingNgramPolicy(42), "quikc brown", nil},
+	}
+	for _, tt := range tests {
+		idx := missingNgramIndex(WithMissingNgramPolicy(tt.policy))
+		if got := idx.Search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("policy %d: Search(%q) = %v, want %v", tt.policy, tt.query, got, tt.want)
+		}
+		if got := idx.SearchBitmap(tt.query).ToArray(); len(got) != len(tt.want) {
+			t.Errorf("policy %d: SearchBitmap(%q) = %v, want %v", tt.policy, tt.query, got, tt.want)
+		}
+		if got := idx.SearchWithLimit(tt.query, 10); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("policy %d: SearchWithLimit(%q) = %v, want %v", tt.policy, tt.query, got, tt.want)
+		}
+		var visited []uint32
+		idx.SearchCallback(tt.query, func(docID uint32) bool {
+			visited = append(visited, docID)
+			return true
+		})
+		if !reflect.DeepEqual(visited, tt.want) {
+			t.Errorf("policy %d: SearchCallback(%q) visited %v, want %v", tt.policy, tt.query, visited, tt.want)
+		}
+		if got := idx.SearchAndFilter(tt.query, roaring.BitmapOf(1, 2, 3, 4)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("policy %d: SearchAndFilter(%q) = %v, want %v", tt.policy, tt.query, got, tt.want)
+		}
+	}
+
+	idx := missingNgramIndex(WithMissingNgramPolicy(MissingNgramIgnore))
+	matches, err := idx.SearchE("quikc brown")
+	if !reflect.DeepEqual(matches, []uint32{1}) || !errors.Is(err, ErrNgramNotFound) {
+		t.Errorf("SearchE = %v, %v, want [1] with ErrNgramNotFound", matches, err)
+	}
+	if matches, err := idx.SearchLimited("quikc brown"); !reflect.DeepEqual(matches, []uint32{1}) || err != nil {
+		t.Errorf("SearchLimited = %v, %v, want [1], nil", matches, err)
+	}
+	if got := idx.Snapshot().Search("quikc brown"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("snapshot lost the policy: %v", got)
+	}
+}
+
+func TestCachedMissingNgramPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.sear")
+	if err := missingNgramIndex().SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path, WithCachedMissingNgramPolicy(MissingNgramThreshold))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+
+	if got := cached.Search("quick brawn"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search = %v, want [1]", got)
+	}
+	matches, err := cached.SearchE("quick brawn")
+	if !reflect.DeepEqual(matches, []uint32{1}) || !errors.Is(err, ErrNgramNotFound) {
+		t.Errorf("SearchE = %v, %v, want [1] with ErrNgramNotFound", matches, err)
+	}
+	if got := cached.Search("xyzzy"); got != nil {
+		t.Errorf("Search(xyzzy) = %v, want nil", got)
+	}
+
+	// SearchAndFilter applies the policy, with or without pushdown
+	pushed, err := OpenCachedIndex(path, WithMissingNgramPolicy(MissingNgramThreshold), WithFilterPushdown(0))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer pushed.Close()
+	strict, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer strict.Close()
+	filter := roaring.BitmapOf(1, 3)
+	for _, tt := range []struct {
+		name string
+		idx  *CachedIndex
+		want []uint32
+	}{{"threshold", cached, []uint32{1}}, {"pushdown", pushed, []uint32{1}}, {"fail", strict, nil}} {
+		if got := tt.idx.SearchAndFilter("quick brawn", filter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SearchAndFilter = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package roaringsearch
 
 import (
+	"reflect"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Normalizer transforms text before n-gram generation.
@@ -26,19 +28,88 @@ func NormalizeLowercaseAlphanumeric(s string) string {
 	return b.String()
 }
 
-// normalizeASCIIToBuf normalizes ASCII text to a byte buffer.
+// NormalizeIdentity returns the text unchanged, preserving case,
+// punctuation and whitespace, for code search and other uses where "Foo("
+// and "foo(" must be distinct. ASCII text takes the fast path.
+func NormalizeIdentity(s string) string {
+	return s
+}
+
+// asciiTable maps each ASCII byte to its normalized byte, or to asciiDrop
+// to remove it.
+type asciiTable [128]byte
+
+const asciiDrop = 0xFF
+
+// lowerAlnumASCII normalizes ASCII like NormalizeLowercaseAlphanumeric.
+var lowerAlnumASCII = func() *asciiTable {
+	var t asciiTable
+	for c := range t {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			t[c] = byte(c + 32)
+		case (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'):
+			t[c] = byte(c)
+		default:
+			t[c] = asciiDrop
+		}
+	}
+	return &t
+}()
+
+// identityASCII keeps every ASCII byte, like NormalizeIdentity.
+var identityASCII = func() *asciiTable {
+	var t asciiTable
+	for c := range t {
+		t[c] = byte(c)
+	}
+	return &t
+}()
+
+// asciiNormalizers normalize ASCII input exactly like normalizeASCIIToBuf
+// with their table.
+var asciiNormalizers = []struct {
+	normalizer Normalizer
+	table      *asciiTable
+}{
+	{NormalizeLowercaseAlphanumeric, lowerAlnumASCII},
+	{NormalizeNFKCFold, lowerAlnumASCII},
+	{NormalizeStripAccents, lowerAlnumASCII},
+	{NormalizeIdentity, identityASCII},
+}
+
+// asciiTableFor returns the ASCII fast path table of a built-in normalizer,
+// or nil if n has none.
+func asciiTableFor(n Normalizer) *asciiTable {
+	if n == nil {
+		return nil
+	}
+	p := reflect.ValueOf(n).Pointer()
+	for _, known := range asciiNormalizers {
+		if reflect.ValueOf(known.normalizer).Pointer() == p {
+			return known.table
+		}
+	}
+	return nil
+}
+
+// supportsASCIIFastPath reports whether n is a built-in normalizer whose
+// output for ASCII input matches the ASCII fast path.
+func supportsASCIIFastPath(n Normalizer) bool {
+	return asciiTableFor(n) != nil
+}
+
+// normalizeASCIIToBuf normalizes ASCII text to a byte buffer through table.
 // Returns the buffer and true if successful, or the buffer and false if non-ASCII found.
-func normalizeASCIIToBuf(s string, buf []byte) ([]byte, bool) {
+func normalizeASCIIToBuf(s string, buf []byte, table *asciiTable) ([]byte, bool) {
 	buf = buf[:0]
 	for i := 0; i < len(s); i++ {
 		c := s[i]
 		if c > 127 {
 			return buf, false
 		}
-		if c >= 'A' && c <= 'Z' {
-			buf = append(buf, c+32)
-		} else if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
-			buf = append(buf, c)
+		if b := table[c]; b != asciiDrop {
+			buf = append(buf, b)
 		}
 	}
 	return buf, true
@@ -70,42 +141,17 @@ func appendKeyDedup(keys []uint64, key uint64) []uint64 {
 	return append(keys, key)
 }
 
-// normalizeAndKeyASCII normalizes ASCII text and generates n-gram keys directly.
-// Returns keys slice and true if successful, nil and false if text contains non-ASCII.
+// normalizeAndKeyASCIIPooled normalizes ASCII text into buf and appends its n-gram keys
+// to keys, skipping keys already present.
 // Key encoding must match runeNgramKey: 32-bit per char for n<=2, 8-bit for n>2.
-func normalizeAndKeyASCII(s string, gramSize int, keys []uint64) ([]uint64, bool) {
-	buf := make([]byte, 0, len(s))
-	buf, ok := normalizeASCIIToBuf(s, buf)
-	if !ok {
-		return nil, false
-	}
-
-	if len(buf) < gramSize {
-		return keys[:0], true
-	}
-
-	keys = keys[:0]
-	for i := 0; i <= len(buf)-gramSize; i++ {
-		key := packBytesToKey(buf, i, gramSize)
-		keys = appendKeyDedup(keys, key)
-	}
-
-	return keys, true
-}
-
-// normalizeAndKeyASCIIPooled is like normalizeAndKeyASCII but uses a provided buffer.
 // Returns (keys, buf, ok) where buf is the potentially grown buffer for pool return.
-func normalizeAndKeyASCIIPooled(s string, gramSize int, keys []uint64, buf []byte) ([]uint64, []byte, bool) {
-	buf, ok := normalizeASCIIToBuf(s, buf)
+// On non-ASCII input keys is returned unchanged.
+func normalizeAndKeyASCIIPooled(s string, gramSize int, keys []uint64, buf []byte, table *asciiTable) ([]uint64, []byte, bool) {
+	buf, ok := normalizeASCIIToBuf(s, buf, table)
 	if !ok {
-		return nil, buf, false
+		return keys, buf, false
 	}
 
-	if len(buf) < gramSize {
-		return keys[:0], buf, true
-	}
-
-	keys = keys[:0]
 	for i := 0; i <= len(buf)-gramSize; i++ {
 		key := packBytesToKey(buf, i, gramSize)
 		keys = appendKeyDedup(keys, key)
@@ -147,6 +193,51 @@ func hashRunes(runes []rune) uint64 {
 	return h
 }
 
+// KeyToNgram decodes an n-gram key, as returned by HeaviestNgrams or accepted
+// by PreloadKeys, back to its text. Keys of 1-2 runes and ASCII n-grams up to
+// 8 bytes are packed and decode exactly; hashed keys (Unicode n-grams longer
+// than 2 runes) cannot be decoded and return false.
+func KeyToNgram(key uint64, gramSize int) (string, bool) {
+	switch {
+	case gramSize < 1 || gramSize > 8:
+		return "", false
+	case gramSize <= 2:
+		if gramSize == 1 && key>>32 != 0 {
+			return "", false
+		}
+		runes := make([]rune, gramSize)
+		for i := gramSize - 1; i >= 0; i-- {
+			r := rune(uint32(key))
+			if r == 0 || !utf8.ValidRune(r) {
+				return "", false
+			}
+			runes[i] = r
+			key >>= 32
+		}
+		return string(runes), true
+	}
+
+	if key>>(8*gramSize) != 0 {
+		return "", false
+	}
+	buf := make([]byte, gramSize)
+	for i := gramSize - 1; i >= 0; i-- {
+		b := byte(key)
+		if b == 0 || b > 127 {
+			return "", false
+		}
+		buf[i] = b
+		key >>= 8
+	}
+	return string(buf), true
+}
+
+// NgramKey returns the key under which an index stores the n-gram text,
+// which must already be normalized. It is the inverse of KeyToNgram.
+func NgramKey(ngram string) uint64 {
+	return runeNgramKey([]rune(ngram))
+}
+
 // runeNgramKey returns the appropriate key for a rune n-gram.
 // For n <= 2: collision-free packing
 // For n 3-8 with ASCII-only: collision-free packing
@@ -0,0 +1,253 @@
+package roaringsearch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"sync"
+)
+
+// missingNgramKey is the key looked up for exact-mode n-grams that were never
+// indexed. It is never assigned, and packed keys never have the top bit set,
+// so lookups with it always miss.
+const missingNgramKey = math.MaxUint64
+
+// maxNgramBytes bounds the encoded length of a dictionary n-gram (8 runes of
+// at most 4 bytes each).
+const maxNgramBytes = 32
+
+// WithExactKeys stores the text of every n-gram that would otherwise be keyed
+// by hash (Unicode n-grams longer than 2 runes) and assigns it a key that is
+// verified unique, so two different n-grams can never share a bitmap. Packed
+// keys (ASCII n-grams and 1-2 rune n-grams) are exact already and are not
+// stored. It must be set before documents are added; files written by an
+// exact index enable it automatically when loaded, and loading a file
+// written without it with this option returns ErrKeyModeMismatch, since its
+// hashed keys cannot be translated.
+func WithExactKeys() Option {
+	return indexOption(func(idx *Index) {
+		if idx.exact == nil {
+			idx.exact = newNgramDict()
+		}
+	})
+}
+
+// HasExactKeys reports whether the index uses collision-free n-gram keys.
+func (idx *Index) HasExactKeys() bool {
+	return idx.exact != nil
+}
+
+// ngramDict assigns collision-free keys to hashed n-grams. It has its own
+// lock because batch workers assign keys without holding the index lock.
+type ngramDict struct {
+	mu     sync.RWMutex
+	keys   map[string]uint64
+	ngrams map[uint64]string
+}
+
+func newNgramDict() *ngramDict {
+	return &ngramDict{
+		keys:   make(map[string]uint64),
+		ngrams: make(map[uint64]string),
+	}
+}
+
+// clone returns a copy of the dictionary; nil stays nil.
+func (d *ngramDict) clone() *ngramDict {
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return &ngramDict{keys: maps.Clone(d.keys), ngrams: maps.Clone(d.ngrams)}
+}
+
+// merge adds the entries of other, as read from a newer version of the same
+// index; a nil dictionary or other is left alone.
+func (d *ngramDict) merge(other *ngramDict) {
+	if d == nil || other == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for ngram, key := range other.keys {
+		d.keys[ngram] = key
+		d.ngrams[key] = ngram
+	}
+}
+
+// isHashedNgram reports whether runeNgramKey hashes runes rather than packing them.
+func isHashedNgram(runes []rune) bool {
+	if len(runes) <= 2 {
+		return false
+	}
+	if len(runes) > 8 {
+		return true
+	}
+	for _, r := range runes {
+		if r > 127 {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the key assigned to runes, or missingNgramKey.
+func (d *ngramDict) lookup(runes []rune) uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if key, ok := d.keys[string(runes)]; ok {
+		return key
+	}
+	return missingNgramKey
+}
+
+// assign returns the key for runes, assigning one if needed. The hash is used
+// when it is free; on a collision the next free key is probed, skipping keys
+// that a packed n-gram of the same size could produce.
+func (d *ngramDict) assign(runes []rune) uint64 {
+	ngram := string(runes)
+
+	d.mu.RLock()
+	key, ok := d.keys[ngram]
+	d.mu.RUnlock()
+	if ok {
+		return key
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if key, ok := d.keys[ngram]; ok {
+		return key
+	}
+
+	key = hashRunes(runes)
+	for !d.freeLocked(key, len(runes)) {
+		key++
+	}
+	d.keys[ngram] = key
+	d.ngrams[key] = ngram
+	return key
+}
+
+func (d *ngramDict) freeLocked(key uint64, gramSize int) bool {
+	if key == missingNgramKey {
+		return false
+	}
+	if _, taken := d.ngrams[key]; taken {
+		return false
+	}
+	_, packed := KeyToNgram(key, gramSize)
+	return !packed
+}
+
+// ngram returns the n-gram text assigned to key.
+func (d *ngramDict) ngram(key uint64) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ngram, ok := d.ngrams[key]
+	return ngram, ok
+}
+
+// writeTo encodes the dictionary as count(4), then key(8) + length(1) + bytes per entry.
+func (d *ngramDict) writeTo(w io.Writer) (int64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(len(d.ngrams)))
+	n, err := w.Write(buf)
+	written := int64(n)
+	if err != nil {
+		return written, fmt.Errorf("write dictionary count: %w", err)
+	}
+
+	for key, ngram := range d.ngrams {
+		buf = binary.LittleEndian.AppendUint64(buf[:0], key)
+		buf = append(buf, byte(len(ngram)))
+		buf = append(buf, ngram...)
+		n, err := w.Write(buf)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("write dictionary entry: %w", err)
+		}
+	}
+	return written, nil
+}
+
+// readFrom decodes entries written by writeTo into d.
+func (d *ngramDict) readFrom(r io.Reader) (int64, error) {
+	var read int64
+	buf := make([]byte, 9+maxNgramBytes)
+
+	n, err := io.ReadFull(r, buf[:4])
+	read += int64(n)
+	if err != nil {
+		return read, fmt.Errorf("read dictionary count: %w", err)
+	}
+	count := binary.LittleEndian.Uint32(buf[:4])
+	if count > maxNgramCount {
+		return read, ErrInvalidCount
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := uint32(0); i < count; i++ {
+		n, err := io.ReadFull(r, buf[:9])
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("read dictionary entry: %w", err)
+		}
+		key := binary.LittleEndian.Uint64(buf[:8])
+		size := int(buf[8])
+		if size > maxNgramBytes {
+			return read, ErrInvalidSize
+		}
+
+		n, err = io.ReadFull(r, buf[9:9+size])
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("read dictionary entry: %w", err)
+		}
+		ngram := string(buf[9 : 9+size])
+		d.keys[ngram] = key
+		d.ngrams[key] = ngram
+	}
+	return read, nil
+}
+
+// queryKey returns the key to look up for a query n-gram.
+func (idx *Index) queryKey(runes []rune) uint64 {
+	return idx.exact.queryKey(runes)
+}
+
+// queryKey returns the key an n-gram is stored under. A nil dictionary falls
+// back to runeNgramKey, so callers need not check for exact mode.
+func (d *ngramDict) queryKey(runes []rune) uint64 {
+	if d != nil && isHashedNgram(runes) {
+		return d.lookup(runes)
+	}
+	return runeNgramKey(runes)
+}
+
+// indexKey returns the key to index an n-gram under, assigning exact keys as needed.
+func (idx *Index) indexKey(runes []rune) uint64 {
+	if idx.exact != nil && isHashedNgram(runes) {
+		return idx.exact.assign(runes)
+	}
+	return runeNgramKey(runes)
+}
+
+// ngramText decodes key to its n-gram text using the exact dictionary when
+// present, falling back to KeyToNgram for packed keys.
+func (idx *Index) ngramText(key uint64) (string, bool) {
+	if idx.exact != nil {
+		if ngram, ok := idx.exact.ngram(key); ok {
+			return ngram, true
+		}
+	}
+	return KeyToNgram(key, idx.gramSize)
+}
@@ -0,0 +1,155 @@
+package roaringsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestExactKeysCollision(t *testing.T) {
+	idx := NewIndex(3, WithExactKeys())
+	if !idx.HasExactKeys() {
+		t.Fatal("HasExactKeys = false, want true")
+	}
+
+	// Occupy the hash of "日本語" with another n-gram to force a collision
+	hashed := runeNgramKey([]rune("日本語"))
+	idx.exact.keys["中文字"] = hashed
+	idx.exact.ngrams[hashed] = "中文字"
+	idx.bitmaps[hashed] = roaring.BitmapOf(99)
+
+	idx.Add(1, "日本語です")
+
+	key := idx.queryKey([]rune("日本語"))
+	if key == hashed {
+		t.Fatalf("colliding n-gram was assigned the occupied key %d", key)
+	}
+	if got, ok := idx.ngramText(key); !ok || got != "日本語" {
+		t.Errorf("ngramText(%d) = %q, %v, want 日本語", key, got, ok)
+	}
+	if got := idx.Search("日本語"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(日本語) = %v, want [1]", got)
+	}
+	if got := idx.Search("語です"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(語です) = %v, want [1]", got)
+	}
+	if got := idx.Search("未知語"); len(got) != 0 {
+		t.Errorf("Search of unindexed n-gram = %v, want none", got)
+	}
+}
+
+func TestExactKeysPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, withForward := range []bool{false, true} {
+		opts := []Option{WithExactKeys()}
+		if withForward {
+			opts = append(opts, WithForwardIndex())
+		}
+		idx := NewIndex(3, opts...)
+		idx.Add(1, "привет мир")
+		idx.Add(2, "日本語です")
+		idx.Add(3, testHelloWorld)
+
+		path := filepath.Join(tmpDir, "exact.sear")
+		if err := idx.SaveToFile(path); err != nil {
+			t.Fatalf(errSaveToFile, err)
+		}
+
+		loaded, err := LoadFromFile(path)
+		if err != nil {
+			t.Fatalf("LoadFromFile failed: %v", err)
+		}
+		if !loaded.HasExactKeys() {
+			t.Error("loaded index lost exact keys")
+		}
+		if loaded.HasForwardIndex() != withForward {
+			t.Errorf("HasForwardIndex = %v, want %v", loaded.HasForwardIndex(), withForward)
+		}
+		for query, want := range map[string][]uint32{
+			"привет": {1},
+			"日本語":    {2},
+			"hello":  {3},
+		} {
+			if got := loaded.Search(query); !reflect.DeepEqual(got, want) {
+				t.Errorf("forward=%v: Search(%s) = %v, want %v", withForward, query, got, want)
+			}
+		}
+
+		cached, err := OpenCachedIndex(path)
+		if err != nil {
+			t.Fatalf("OpenCachedIndex failed: %v", err)
+		}
+		defer cached.Close()
+		if got := cached.Search("日本語"); !reflect.DeepEqual(got, []uint32{2}) {
+			t.Errorf("forward=%v: cached Search(日本語) = %v, want [2]", withForward, got)
+		}
+		if !cached.HasNgram("привет"[:6]) {
+			t.Errorf("forward=%v: cached HasNgram(при) = false, want true", withForward)
+		}
+	}
+}
+
+func TestExactKeysLoadHashedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashed.sear")
+	idx := NewIndex(3)
+	idx.Add(1, "日本語です")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	if _, err := LoadFromFileWithOptions(path, WithExactKeys()); !errors.Is(err, ErrKeyModeMismatch) {
+		t.Errorf("LoadFromFileWithOptions error = %v, want ErrKeyModeMismatch", err)
+	}
+	if _, _, err := LoadFromFileAsync(path, WithExactKeys()); !errors.Is(err, ErrKeyModeMismatch) {
+		t.Errorf("LoadFromFileAsync error = %v, want ErrKeyModeMismatch", err)
+	}
+	loaded, err := LoadFromFileWithOptions(path)
+	if err != nil {
+		t.Fatalf("LoadFromFileWithOptions failed: %v", err)
+	}
+	if got := loaded.Search("日本語"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(日本語) = %v, want [1]", got)
+	}
+}
+
+func TestExactKeysMerge(t *testing.T) {
+	a := NewIndex(3, WithExactKeys())
+	a.Add(1, "日本語")
+
+	b := NewIndex(3, WithExactKeys())
+	b.Add(2, "привет")
+	b.Add(3, "日本語です")
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if got := a.Search("日本語"); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("Search(日本語) = %v, want [1 3]", got)
+	}
+	if got := a.Search("привет"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search(привет) = %v, want [2]", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "b.sear")
+	if err := b.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	c := NewIndex(3, WithExactKeys())
+	if err := c.mergeFile(path); err != nil {
+		t.Fatalf("mergeFile failed: %v", err)
+	}
+	if got := c.Search("語です"); !reflect.DeepEqual(got, []uint32{3}) {
+		t.Errorf("merged file Search(語です) = %v, want [3]", got)
+	}
+
+	if err := NewIndex(3).Merge(b); !errors.Is(err, ErrKeyModeMismatch) {
+		t.Errorf("Merge into hashed index error = %v, want ErrKeyModeMismatch", err)
+	}
+	if err := NewIndex(3).mergeFile(path); !errors.Is(err, ErrKeyModeMismatch) {
+		t.Errorf("mergeFile into hashed index error = %v, want ErrKeyModeMismatch", err)
+	}
+}
@@ -16,6 +16,8 @@ func FuzzNormalize(f *testing.F) {
 
 	f.Fuzz(func(t *testing.T, input string) {
 		_ = NormalizeLowercase(input)
+		_ = NormalizeNFKCFold(input)
+		_ = NormalizeStripAccents(input)
 		// No panic = success
 	})
 }
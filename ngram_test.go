@@ -1,6 +1,10 @@
 package roaringsearch
 
-import "testing"
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
 
 func TestNormalizers(t *testing.T) {
 	tests := []struct {
@@ -14,6 +18,21 @@ func TestNormalizers(t *testing.T) {
 		{"alphanumeric with numbers", NormalizeLowercaseAlphanumeric, "Test123!", "test123"},
 		{"unicode lowercase", NormalizeLowercase, "ÜBER", "über"},
 		{"japanese preserved", NormalizeLowercaseAlphanumeric, "日本語テスト", "日本語テスト"},
+		{"nfkc full-width", NormalizeNFKCFold, "Ｈｅｌｌｏ　Ｗｏｒｌｄ！", "helloworld"},
+		{"nfkc half-width kana", NormalizeNFKCFold, "ｶﾞｲﾄﾞﾌﾞｯｸ", "ガイドブック"},
+		{"nfkc semi-voiced kana", NormalizeNFKCFold, "ﾊﾟﾝ", "パン"},
+		{"nfkc combining accent", NormalizeNFKCFold, "Cafe\u0301", "café"},
+		{"nfkc two combining accents", NormalizeNFKCFold, "Vie\u0302\u0323t", "việt"},
+		{"nfkc keeps accents", NormalizeNFKCFold, "Café", "café"},
+		{"nfkc ligature", NormalizeNFKCFold, "ﬁnancial", "financial"},
+		{"nfkc superscript", NormalizeNFKCFold, "x²", "x2"},
+		{"strip accents", NormalizeStripAccents, "Café Crème", "cafecreme"},
+		{"strip combining accent", NormalizeStripAccents, "Cafe\u0301", "cafe"},
+		{"strip vietnamese", NormalizeStripAccents, "Tiếng Việt", "tiengviet"},
+		{"strip letters", NormalizeStripAccents, "Straße Ørsted Łódź", "strasseorstedlodz"},
+		{"strip full-width", NormalizeStripAccents, "ｃａｆé", "cafe"},
+		{"strip keeps kana voicing", NormalizeStripAccents, "ｶﾞｲﾄﾞ", "ガイド"},
+		{"strip cyrillic preserved", NormalizeStripAccents, "Привет", "привет"},
 	}
 
 	for _, tt := range tests {
@@ -26,6 +45,75 @@ func TestNormalizers(t *testing.T) {
 	}
 }
 
+func TestFoldNormalizersASCII(t *testing.T) {
+	inputs := []string{"", testHelloWorld, "The Quick Brown Fox! 123", "a-b_c.d", "  MiXeD  "}
+	for _, n := range []Normalizer{NormalizeNFKCFold, NormalizeStripAccents} {
+		if !supportsASCIIFastPath(n) {
+			t.Error("fold normalizer should support the ASCII fast path")
+		}
+		for _, input := range inputs {
+			if got, want := n(input), NormalizeLowercaseAlphanumeric(input); got != want {
+				t.Errorf("fold(%q) = %q, want %q", input, got, want)
+			}
+		}
+	}
+	if supportsASCIIFastPath(NormalizeLowercase) {
+		t.Error("NormalizeLowercase should not support the ASCII fast path")
+	}
+
+	idx := NewIndex(3, WithNormalizer(NormalizeStripAccents))
+	if idx.ascii == nil {
+		t.Error("WithNormalizer(NormalizeStripAccents) disabled the ASCII fast path")
+	}
+	idx.Add(1, "café au lait")
+	idx.Add(2, "cafe racer")
+	if got := idx.Search("CAFÉ"); len(got) != 2 {
+		t.Errorf("Search(CAFÉ) = %v, want [1 2]", got)
+	}
+}
+
+func TestWithCaseSensitive(t *testing.T) {
+	idx := NewIndex(3, WithCaseSensitive())
+	if idx.ascii != identityASCII {
+		t.Fatal("WithCaseSensitive disabled the ASCII fast path")
+	}
+	idx.Add(1, "func Foo(x int)")
+	idx.Add(2, "func foo(x int)")
+	idx.Add(3, "Ünïcode: Foo(")
+
+	tests := []struct {
+		query string
+		want  []uint32
+	}{
+		{"Foo(", []uint32{1, 3}},
+		{"foo(", []uint32{2}},
+		{"(x int)", []uint32{1, 2}},
+		{"FOO(", nil},
+		{"Ünï", []uint32{3}},
+	}
+	for _, tt := range tests {
+		if got := idx.Search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "code.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("Foo("); !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("cached Search(Foo() = %v, want [1 3]", got)
+	}
+	if got := cached.NormalizerChain().String(); got != NormalizerIdentity {
+		t.Errorf("cached normalizer = %q, want %q", got, NormalizerIdentity)
+	}
+}
+
 func BenchmarkNormalizers(b *testing.B) {
 	text := "The Quick Brown Fox Jumps Over The Lazy Dog! 123"
 
@@ -40,4 +128,34 @@ func BenchmarkNormalizers(b *testing.B) {
 			NormalizeLowercaseAlphanumeric(text)
 		}
 	})
+
+	accented := "Crème Brûlée à la Française, ｶﾞｲﾄﾞﾌﾞｯｸ"
+	b.Run("NFKCFold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NormalizeNFKCFold(accented)
+		}
+	})
+
+	b.Run("StripAccents", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NormalizeStripAccents(accented)
+		}
+	})
+}
+
+func TestKeyToNgram(t *testing.T) {
+	for _, s := range []string{"a", "é", "ab", "日本", "abc", "hello", "abcdefgh"} {
+		runes := []rune(s)
+		got, ok := KeyToNgram(NgramKey(s), len(runes))
+		if !ok || got != s {
+			t.Errorf("KeyToNgram(%q) = %q, %v", s, got, ok)
+		}
+	}
+
+	if _, ok := KeyToNgram(runeNgramKey([]rune("日本語")), 3); ok {
+		t.Error("hashed key should not decode")
+	}
+	if _, ok := KeyToNgram(1<<40, 3); ok {
+		t.Error("key wider than gram size should not decode")
+	}
 }
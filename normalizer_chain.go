@@ -0,0 +1,294 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ComposeNormalizers returns a Normalizer that applies normalizers in order.
+// With no normalizers the text is returned unchanged.
+func ComposeNormalizers(normalizers ...Normalizer) Normalizer {
+	switch len(normalizers) {
+	case 0:
+		return func(s string) string { return s }
+	case 1:
+		return normalizers[0]
+	}
+
+	chain := append([]Normalizer(nil), normalizers...)
+	return func(s string) string {
+		for _, n := range chain {
+			s = n(s)
+		}
+		return s
+	}
+}
+
+// NormalizeStripPunctuation removes punctuation and symbols, keeping letters,
+// digits and whitespace. Case is preserved.
+func NormalizeStripPunctuation(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// NormalizeCollapseWhitespace replaces each run of whitespace with a single
+// space and trims leading and trailing whitespace.
+func NormalizeCollapseWhitespace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// NormalizeFoldDigits maps decimal digits of every script (Arabic-Indic,
+// Devanagari, full-width, ...) to ASCII 0-9, so "٢٠٢٤" matches "2024".
+func NormalizeFoldDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf || !unicode.IsDigit(r) {
+			return r
+		}
+		return '0' + digitValue(r)
+	}, s)
+}
+
+// digitValue returns the value of a decimal digit. Every range of unicode.Nd
+// is made of consecutive 0-9 blocks starting at a zero.
+func digitValue(r rune) rune {
+	for _, rng := range unicode.Nd.R16 {
+		if r >= rune(rng.Lo) && r <= rune(rng.Hi) {
+			return (r - rune(rng.Lo)) % 10
+		}
+	}
+	for _, rng := range unicode.Nd.R32 {
+		if r >= rune(rng.Lo) && r <= rune(rng.Hi) {
+			return (r - rune(rng.Lo)) % 10
+		}
+	}
+	return 0
+}
+
+// Names of the built-in normalizers, for use in a NormalizerChain.
+const (
+	NormalizerLowercase             = "lowercase"
+	NormalizerLowercaseAlphanumeric = "lowercase-alphanumeric"
+	NormalizerNFKCFold              = "nfkc-fold"
+	NormalizerStripAccents          = "strip-accents"
+	NormalizerStripPunctuation      = "strip-punctuation"
+	NormalizerCollapseWhitespace    = "collapse-whitespace"
+	NormalizerFoldDigits            = "fold-digits"
+	NormalizerIdentity              = "identity"
+	NormalizerStemEnglish           = "stem-english"
+)
+
+var builtinNormalizers = map[string]Normalizer{
+	NormalizerLowercase:             NormalizeLowercase,
+	NormalizerLowercaseAlphanumeric: NormalizeLowercaseAlphanumeric,
+	NormalizerNFKCFold:              NormalizeNFKCFold,
+	NormalizerStripAccents:          NormalizeStripAccents,
+	NormalizerStripPunctuation:      NormalizeStripPunctuation,
+	NormalizerCollapseWhitespace:    NormalizeCollapseWhitespace,
+	NormalizerFoldDigits:            NormalizeFoldDigits,
+	NormalizerIdentity:              NormalizeIdentity,
+	NormalizerStemEnglish:           NormalizeStemEnglish,
+}
+
+var (
+	registryMu            sync.RWMutex
+	registeredNormalizers = map[string]Normalizer{}
+)
+
+// RegisterNormalizer makes a custom normalizer available to NormalizerChain
+// under name. Indexes built with a chain naming it record the chain in their
+// files, so they can be loaded and verified like built-in chains as long as
+// the same name is registered in the loading process.
+func RegisterNormalizer(name string, n Normalizer) error {
+	if name == "" || strings.ContainsRune(name, ',') || n == nil {
+		return fmt.Errorf("register normalizer %q: invalid name or normalizer", name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := builtinNormalizers[name]; ok {
+		return fmt.Errorf("register normalizer %q: already registered", name)
+	}
+	if _, ok := registeredNormalizers[name]; ok {
+		return fmt.Errorf("register normalizer %q: already registered", name)
+	}
+	registeredNormalizers[name] = n
+	return nil
+}
+
+// lookupNormalizer returns the built-in or registered normalizer for name.
+func lookupNormalizer(name string) (Normalizer, bool) {
+	if n, ok := builtinNormalizers[name]; ok {
+		return n, true
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	n, ok := registeredNormalizers[name]
+	return n, ok
+}
+
+// NormalizerNames returns the names of the built-in and registered
+// normalizers, sorted.
+func NormalizerNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(builtinNormalizers)+len(registeredNormalizers))
+	for name := range builtinNormalizers {
+		names = append(names, name)
+	}
+	for name := range registeredNormalizers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NormalizerChain declaratively describes a normalizer as the names of
+// built-in normalizers applied in order. Unlike a Normalizer func it can be
+// stored, compared and read from configuration.
+type NormalizerChain []string
+
+// ParseNormalizerChain parses a comma-separated list of normalizer names,
+// e.g. "nfkc-fold,fold-digits,lowercase-alphanumeric".
+func ParseNormalizerChain(s string) (NormalizerChain, error) {
+	var chain NormalizerChain
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			chain = append(chain, name)
+		}
+	}
+	if err := chain.Validate(); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// String returns the chain in the form accepted by ParseNormalizerChain.
+func (c NormalizerChain) String() string {
+	return strings.Join(c, ",")
+}
+
+// Validate checks that every name in the chain is a built-in or registered
+// normalizer.
+func (c NormalizerChain) Validate() error {
+	for _, name := range c {
+		if _, ok := lookupNormalizer(name); !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownNormalizer, name)
+		}
+	}
+	return nil
+}
+
+// Normalizer returns the composed Normalizer for the chain.
+func (c NormalizerChain) Normalizer() (Normalizer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	normalizers := make([]Normalizer, len(c))
+	for i, name := range c {
+		normalizers[i], _ = lookupNormalizer(name)
+	}
+	return ComposeNormalizers(normalizers...), nil
+}
+
+// WithNormalizerChain sets the normalizer from a declarative chain and
+// records the chain on the index. Like other options it does not fail:
+// unknown names are skipped, so check configuration with
+// ParseNormalizerChain or Validate first. A CachedIndex given a chain that
+// differs from the one its file records fails with ErrNormalizerMismatch.
+func WithNormalizerChain(chain NormalizerChain) SharedOption {
+	known := make(NormalizerChain, 0, len(chain))
+	for _, name := range chain {
+		if _, ok := lookupNormalizer(name); ok {
+			known = append(known, name)
+		}
+	}
+	n, _ := known.Normalizer()
+
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			WithNormalizer(n).applyIndex(idx)
+			idx.normalizerChain = known
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			idx.normalizer = n
+			idx.chain = known
+		}),
+	}
+}
+
+// NormalizerChain returns the chain set with WithNormalizerChain or read
+// from an index file, or nil when the index uses the default or a custom
+// Normalizer func.
+func (idx *Index) NormalizerChain() NormalizerChain {
+	return idx.normalizerChain
+}
+
+// normalizerID names a normalizer: the chain if one is set, otherwise the
+// name of a built-in normalizer func. Custom funcs have no name.
+func normalizerID(n Normalizer, chain NormalizerChain) string {
+	if chain != nil {
+		return chain.String()
+	}
+	if n == nil {
+		return ""
+	}
+	p := reflect.ValueOf(n).Pointer()
+	for name, builtin := range builtinNormalizers {
+		if reflect.ValueOf(builtin).Pointer() == p {
+			return name
+		}
+	}
+	return ""
+}
+
+// recordedNormalizerLocked returns the normalizer name written to index
+// files. The default normalizer and unnamed custom funcs are not recorded.
+func (idx *Index) recordedNormalizerLocked() string {
+	id := normalizerID(idx.normalizer, idx.normalizerChain)
+	if id == NormalizerLowercaseAlphanumeric {
+		return ""
+	}
+	return id
+}
+
+// checkNormalizer returns ErrNormalizerMismatch if a file that recorded a
+// normalizer is used with a different one. Files without a record are
+// trusted, since older files could not record one.
+func checkNormalizer(recorded string, n Normalizer, chain NormalizerChain) error {
+	if recorded == "" {
+		return nil
+	}
+	id := normalizerID(n, chain)
+	if id == recorded {
+		return nil
+	}
+	if id == "" {
+		id = "custom"
+	}
+	return fmt.Errorf("%w: built with %q, opened with %q", ErrNormalizerMismatch, recorded, id)
+}
@@ -0,0 +1,206 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestComposeNormalizers(t *testing.T) {
+	n := ComposeNormalizers(NormalizeStripPunctuation, NormalizeCollapseWhitespace, NormalizeLowercase)
+	if got := n("  Hello,   World!  It's\t\tme. "); got != "hello world its me" {
+		t.Errorf("composed = %q, want %q", got, "hello world its me")
+	}
+
+	if got := ComposeNormalizers()("As Is"); got != "As Is" {
+		t.Errorf("empty composition = %q, want input unchanged", got)
+	}
+	if !supportsASCIIFastPath(ComposeNormalizers(NormalizeStripAccents)) {
+		t.Error("single normalizer composition should keep the ASCII fast path")
+	}
+}
+
+func TestStandardNormalizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer Normalizer
+		input      string
+		expected   string
+	}{
+		{"strip punctuation", NormalizeStripPunctuation, "C++ & Go: rock!", "C  Go rock"},
+		{"strip punctuation unicode", NormalizeStripPunctuation, "«日本語»、テスト。", "日本語テスト"},
+		{"collapse whitespace", NormalizeCollapseWhitespace, "\t a \n\n b  c ", "a b c"},
+		{"collapse empty", NormalizeCollapseWhitespace, "   ", ""},
+		{"fold arabic-indic digits", NormalizeFoldDigits, "عام ٢٠٢٤", "عام 2024"},
+		{"fold devanagari digits", NormalizeFoldDigits, "१२३", "123"},
+		{"fold full-width digits", NormalizeFoldDigits, "ｖ９", "ｖ9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer(tt.input); got != tt.expected {
+				t.Errorf("normalizer(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizerChain(t *testing.T) {
+	chain, err := ParseNormalizerChain(" nfkc-fold, fold-digits ,lowercase-alphanumeric")
+	if err != nil {
+		t.Fatalf("ParseNormalizerChain failed: %v", err)
+	}
+	want := NormalizerChain{NormalizerNFKCFold, NormalizerFoldDigits, NormalizerLowercaseAlphanumeric}
+	if !reflect.DeepEqual(chain, want) {
+		t.Errorf("chain = %v, want %v", chain, want)
+	}
+	if chain.String() != "nfkc-fold,fold-digits,lowercase-alphanumeric" {
+		t.Errorf("String() = %q", chain.String())
+	}
+
+	if _, err := ParseNormalizerChain("lowercase,soundex"); !errors.Is(err, ErrUnknownNormalizer) {
+		t.Errorf("unknown name error = %v, want ErrUnknownNormalizer", err)
+	}
+	for _, name := range NormalizerNames() {
+		if err := (NormalizerChain{name}).Validate(); err != nil {
+			t.Errorf("built-in %q failed validation: %v", name, err)
+		}
+	}
+
+	idx := NewIndex(3, WithNormalizerChain(chain))
+	if !reflect.DeepEqual(idx.NormalizerChain(), want) {
+		t.Errorf("NormalizerChain() = %v, want %v", idx.NormalizerChain(), want)
+	}
+	idx.Add(1, "Order №１２３ shipped")
+	if got := idx.Search("order 123"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(order 123) = %v, want [1]", got)
+	}
+
+	// Unknown names are skipped rather than failing the option
+	idx = NewIndex(3, WithNormalizerChain(NormalizerChain{"bogus", NormalizerLowercase}))
+	if !reflect.DeepEqual(idx.NormalizerChain(), NormalizerChain{NormalizerLowercase}) {
+		t.Errorf("NormalizerChain() = %v, want [lowercase]", idx.NormalizerChain())
+	}
+
+	// A later func normalizer replaces the chain
+	idx = NewIndex(3, WithNormalizerChain(chain), WithNormalizer(NormalizeLowercase))
+	if idx.NormalizerChain() != nil {
+		t.Errorf("NormalizerChain() = %v after WithNormalizer, want nil", idx.NormalizerChain())
+	}
+}
+
+func TestNormalizerPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+	chain := NormalizerChain{NormalizerStripAccents, NormalizerFoldDigits}
+
+	idx := NewIndex(3, WithNormalizerChain(chain))
+	idx.Add(1, "Café ٢٠٢٤")
+	path := filepath.Join(tmpDir, "chain.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.NormalizerChain(), chain) {
+		t.Errorf("loaded NormalizerChain() = %v, want %v", loaded.NormalizerChain(), chain)
+	}
+	if got := loaded.Search("cafe 2024"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("loaded Search(cafe 2024) = %v, want [1]", got)
+	}
+
+	if _, err := LoadFromFileWithOptions(path, WithNormalizerChain(chain)); err != nil {
+		t.Errorf("LoadFromFileWithOptions with matching chain failed: %v", err)
+	}
+	if _, err := LoadFromFileWithOptions(path, WithNormalizer(NormalizeLowercase)); !errors.Is(err, ErrNormalizerMismatch) {
+		t.Errorf("LoadFromFileWithOptions mismatch error = %v, want ErrNormalizerMismatch", err)
+	}
+
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	defer cached.Close()
+	if got := cached.Search("cafe 2024"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("cached Search(cafe 2024) = %v, want [1]", got)
+	}
+	if _, err := OpenCachedIndex(path, WithCachedNormalizer(NormalizeLowercase)); !errors.Is(err, ErrNormalizerMismatch) {
+		t.Errorf("OpenCachedIndex mismatch error = %v, want ErrNormalizerMismatch", err)
+	}
+
+	if err := NewIndex(3).Merge(idx); !errors.Is(err, ErrNormalizerMismatch) {
+		t.Errorf("Merge mismatch error = %v, want ErrNormalizerMismatch", err)
+	}
+	merged, err := MergeFiles(path, path)
+	if err != nil {
+		t.Fatalf("MergeFiles with matching normalizers failed: %v", err)
+	}
+	if !reflect.DeepEqual(merged.NormalizerChain(), chain) {
+		t.Errorf("merged NormalizerChain() = %v, want %v", merged.NormalizerChain(), chain)
+	}
+	if got := merged.Search("cafe 2024"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("merged Search(cafe 2024) = %v, want [1]", got)
+	}
+	plainPath := filepath.Join(tmpDir, "plain.sear")
+	if err := NewIndex(3).SaveToFile(plainPath); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	if _, err := MergeFiles(path, plainPath); !errors.Is(err, ErrNormalizerMismatch) {
+		t.Errorf("MergeFiles mismatch error = %v, want ErrNormalizerMismatch", err)
+	}
+
+	// The default normalizer is not recorded, keeping version 2 files
+	plain := NewIndex(3)
+	plain.Add(1, testHelloWorld)
+	var buf bytes.Buffer
+	if _, err := plain.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if v := binary.LittleEndian.Uint16(buf.Bytes()[4:6]); v != version {
+		t.Errorf("default normalizer file version = %d, want %d", v, version)
+	}
+}
+
+func TestRegisterNormalizer(t *testing.T) {
+	reverse := func(s string) string {
+		runes := []rune(NormalizeLowercaseAlphanumeric(s))
+		slices.Reverse(runes)
+		return string(runes)
+	}
+	if err := RegisterNormalizer("test-reverse", reverse); err != nil {
+		t.Fatalf("RegisterNormalizer failed: %v", err)
+	}
+	if err := RegisterNormalizer("test-reverse", reverse); err == nil {
+		t.Error("duplicate RegisterNormalizer should fail")
+	}
+	if err := RegisterNormalizer(NormalizerLowercase, reverse); err == nil {
+		t.Error("registering a built-in name should fail")
+	}
+	if err := RegisterNormalizer("a,b", reverse); err == nil {
+		t.Error("name with comma should fail")
+	}
+	if !slices.Contains(NormalizerNames(), "test-reverse") {
+		t.Error("NormalizerNames() missing registered normalizer")
+	}
+
+	idx := NewIndex(3, WithNormalizerChain(NormalizerChain{"test-reverse"}))
+	idx.Add(1, "abcdef")
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	loaded := NewIndex(3)
+	if _, err := loaded.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom failed: %v", err)
+	}
+	if got := loaded.Search("def"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(def) = %v, want [1]", got)
+	}
+}
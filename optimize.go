@@ -0,0 +1,146 @@
+package roaringsearch
+
+import (
+	"maps"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// OptimizeResult reports the effect of an Optimize pass.
+type OptimizeResult struct {
+	Bitmaps     int    // number of bitmaps visited
+	Dropped     int    // empty bitmaps removed when shrinking
+	BytesBefore uint64 // bitmap memory before optimizing
+	BytesAfter  uint64 // bitmap memory after optimizing
+}
+
+// BytesSaved returns the reduction in bitmap memory, which is negative in the
+// rare case that run containers grew a bitmap.
+func (r OptimizeResult) BytesSaved() int64 {
+	return int64(r.BytesBefore) - int64(r.BytesAfter)
+}
+
+// optimizeConfig holds options for Optimize.
+type optimizeConfig struct {
+	shrink bool
+}
+
+// OptimizeOption configures an Optimize pass.
+type OptimizeOption func(*optimizeConfig)
+
+// WithShrinkMaps makes Optimize drop empty bitmaps and reallocate its maps at
+// their current size. Go maps never release buckets after deletes, so this
+// reclaims memory after large removals.
+func WithShrinkMaps() OptimizeOption {
+	return func(cfg *optimizeConfig) {
+		cfg.shrink = true
+	}
+}
+
+// WithOptimizeOnSave makes SaveToFile run Optimize before writing, so files
+// store run-length encoded bitmaps where they are smaller. The pass holds
+// the write lock, like Optimize.
+func WithOptimizeOnSave() Option {
+	return indexOption(func(idx *Index) {
+		idx.optimizeOnSave = true
+	})
+}
+
+// optimizeBitmap run-length optimizes bm and adds its sizes to r.
+func (r *OptimizeResult) optimizeBitmap(bm *roaring.Bitmap) {
+	r.Bitmaps++
+	r.BytesBefore += bm.GetSizeInBytes()
+	bm.RunOptimize()
+	r.BytesAfter += bm.GetSizeInBytes()
+}
+
+// Optimize converts every bitmap to its most compact container representation
+// with RunOptimize. Indexes built incrementally with Add never get run-length
+// encoded otherwise, so dense n-grams can shrink considerably.
+// It holds the write lock for the whole pass.
+func (idx *Index) Optimize(opts ...OptimizeOption) OptimizeResult {
+	var cfg optimizeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var r OptimizeResult
+	for key, bm := range idx.bitmaps {
+		if cfg.shrink && bm.IsEmpty() {
+			delete(idx.bitmaps, key)
+			idx.generation++
+			r.Dropped++
+			continue
+		}
+		bm, _ = idx.writableBitmapLocked(key)
+		r.optimizeBitmap(bm)
+	}
+	r.optimizeBitmap(idx.docs)
+
+	if cfg.shrink {
+		idx.bitmaps = maps.Clone(idx.bitmaps)
+		if idx.forward != nil {
+			idx.forward = maps.Clone(idx.forward)
+		}
+	}
+	return r
+}
+
+// Optimize converts every category bitmap to its most compact container
+// representation with RunOptimize. With WithShrinkMaps, categories left empty
+// by removals are dropped, along with fields that have no categories left.
+func (c *BitmapFilter) Optimize(opts ...OptimizeOption) OptimizeResult {
+	var cfg optimizeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var r OptimizeResult
+	for field, fieldMap := range c.fields {
+		for category, bm := range fieldMap {
+			if cfg.shrink && bm.IsEmpty() {
+				delete(fieldMap, category)
+				c.cats.drop(field, category)
+				r.Dropped++
+				continue
+			}
+			r.optimizeBitmap(bm)
+		}
+		if cfg.shrink {
+			if len(fieldMap) == 0 {
+				delete(c.fields, field)
+			} else {
+				c.fields[field] = maps.Clone(fieldMap)
+			}
+		}
+	}
+	r.optimizeBitmap(c.all)
+
+	if cfg.shrink {
+		c.fields = maps.Clone(c.fields)
+	}
+	if r.Dropped > 0 {
+		c.dirty.Store(true)
+	}
+	return r
+}
+
+// Optimize converts every slice bitmap to its most compact container
+// representation with RunOptimize.
+func (b *BitSlicedIndex) Optimize() OptimizeResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var r OptimizeResult
+	for _, bm := range b.slices {
+		r.optimizeBitmap(bm)
+	}
+	r.optimizeBitmap(b.exists)
+	return r
+}
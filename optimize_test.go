@@ -0,0 +1,67 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestIndexOptimize(t *testing.T) {
+	idx := NewIndex(3)
+	batch := idx.BatchSize(10000)
+	for i := uint32(0); i < 10000; i++ {
+		batch.Add(i, fmt.Sprintf("%s %d", testHelloWorld, i%7))
+	}
+	batch.Flush()
+
+	before := idx.Search(testHelloWorld)
+	r := idx.Optimize()
+	if r.Bitmaps != idx.NgramCount()+1 {
+		t.Errorf("Bitmaps = %d, want %d", r.Bitmaps, idx.NgramCount()+1)
+	}
+	if r.BytesSaved() <= 0 {
+		t.Errorf("expected dense bitmaps to shrink, saved %d bytes", r.BytesSaved())
+	}
+	if got := idx.Search(testHelloWorld); !reflect.DeepEqual(got, before) {
+		t.Error("Search results changed after Optimize")
+	}
+
+	// Shrinking keeps results intact
+	idx.RemoveMany(before[:5000])
+	r = idx.Optimize(WithShrinkMaps())
+	if got := idx.SearchCount(testHelloWorld); got != 5000 {
+		t.Errorf("SearchCount after shrink = %d, want 5000", got)
+	}
+	if r.Dropped != 0 {
+		t.Errorf("Index should not hold empty bitmaps, dropped %d", r.Dropped)
+	}
+}
+
+func TestBitmapFilterOptimize(t *testing.T) {
+	filter := NewBitmapFilter()
+	for i := uint32(0); i < 5000; i++ {
+		filter.Set(i, "type", "book")
+	}
+	filter.Set(9000, "type", "movie")
+	filter.Set(9000, "lang", "en")
+
+	r := filter.Optimize()
+	if r.BytesSaved() <= 0 {
+		t.Errorf("expected dense bitmap to shrink, saved %d bytes", r.BytesSaved())
+	}
+	if filter.Get("type", "book").GetCardinality() != 5000 {
+		t.Errorf("book count = %d, want 5000", filter.Get("type", "book").GetCardinality())
+	}
+
+	filter.Remove(9000)
+	r = filter.Optimize(WithShrinkMaps())
+	if r.Dropped != 2 {
+		t.Errorf("Dropped = %d, want 2", r.Dropped)
+	}
+	if got := filter.Categories("lang"); got != nil {
+		t.Errorf("Categories(lang) after shrink = %v, want nil", got)
+	}
+	if got := filter.Categories("type"); !reflect.DeepEqual(got, []string{"book"}) {
+		t.Errorf("Categories after shrink = %v, want [book]", got)
+	}
+}
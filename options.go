@@ -1,14 +1,87 @@
 package roaringsearch
 
-// Option configures an Index.
-type Option func(*Index)
+// Option configures an Index. Options that apply to a CachedIndex as well
+// are SharedOptions; passing an Index-only option such as WithForwardIndex
+// to OpenCachedIndex, or a CachedIndexOption to NewIndex, does not compile.
+type Option interface {
+	applyIndex(*Index)
+}
+
+// indexOption adapts a func to an Option.
+type indexOption func(*Index)
+
+func (f indexOption) applyIndex(idx *Index) { f(idx) }
+
+// SharedOption configures an Index and a CachedIndex alike, so one set of
+// options can describe both the index that writes a file and the one that
+// serves it:
+//
+//	opts := []rs.SharedOption{rs.WithNormalizerChain(chain), rs.WithMaxResults(1000)}
+//	idx := rs.NewIndex(3, rs.IndexOptions(opts)...)
+//	cached, err := rs.OpenCachedIndex(path, rs.CachedOptions(opts)...)
+type SharedOption struct {
+	index  Option
+	cached CachedIndexOption
+}
+
+func (o SharedOption) applyIndex(idx *Index)        { o.index.applyIndex(idx) }
+func (o SharedOption) applyCached(idx *CachedIndex) { o.cached.applyCached(idx) }
+
+// IndexOptions converts shared options for NewIndex.
+func IndexOptions(opts []SharedOption) []Option {
+	out := make([]Option, len(opts))
+	for i, opt := range opts {
+		out[i] = opt
+	}
+	return out
+}
+
+// CachedOptions converts shared options for OpenCachedIndex.
+func CachedOptions(opts []SharedOption) []CachedIndexOption {
+	out := make([]CachedIndexOption, len(opts))
+	for i, opt := range opts {
+		out[i] = opt
+	}
+	return out
+}
 
 // WithNormalizer sets the text normalizer for n-gram generation.
 // Default is NormalizeLowercaseAlphanumeric.
-// Note: Custom normalizers disable the ASCII fast path optimization.
-func WithNormalizer(n Normalizer) Option {
-	return func(idx *Index) {
-		idx.normalizer = n
-		idx.useASCIFastPath = false // custom normalizer requires full processing
+// Note: Custom normalizers disable the ASCII fast path optimization. The
+// built-in NormalizeStripAccents and NormalizeNFKCFold keep it, since they
+// normalize ASCII text exactly like the default, and so does
+// NormalizeIdentity. For a CachedIndex opened on a file that records the
+// normalizer it was built with, a different n makes OpenCachedIndex return
+// ErrNormalizerMismatch.
+func WithNormalizer(n Normalizer) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			idx.normalizer = n
+			idx.ascii = asciiTableFor(n)
+			idx.normalizerChain = nil
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			idx.normalizer = n
+			idx.chain = nil
+		}),
 	}
 }
+
+// WithCaseSensitive indexes text as is, with NormalizeIdentity: case,
+// punctuation and whitespace are kept, so "Foo(" and "foo(" are distinct
+// n-grams. The file records the normalizer, so a CachedIndex opened on it
+// adopts it.
+func WithCaseSensitive() SharedOption {
+	return WithNormalizer(NormalizeIdentity)
+}
+
+// WithDeterministicBuild makes identical input produce identical index files.
+// Batch indexing splits documents into a fixed number of chunks regardless of
+// CPU count, so bitmaps are merged in the same order on every machine, and
+// WriteTo writes n-grams in ascending key order instead of map order.
+// Useful for reproducible build pipelines and content-addressed caching.
+func WithDeterministicBuild() Option {
+	return indexOption(func(idx *Index) {
+		idx.deterministic = true
+	})
+}
@@ -0,0 +1,49 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Shared options satisfy both option types; Index-only options such as
+// WithForwardIndex do not satisfy CachedIndexOption.
+var (
+	_ Option            = WithLogger(nil)
+	_ CachedIndexOption = WithLogger(nil)
+	_ CachedIndexOption = WithNormalizerChain(nil)
+)
+
+func TestSharedOptions(t *testing.T) {
+	opts := []SharedOption{
+		WithNormalizerChain(NormalizerChain{NormalizerStripAccents}),
+		WithMaxResults(1),
+		WithMissingNgramPolicy(MissingNgramIgnore),
+	}
+
+	idx := NewIndex(3, IndexOptions(opts)...)
+	idx.Add(1, "Café crème")
+	idx.Add(2, "cafe latte")
+	if got := idx.Search("cafe"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search = %v, want [1]", got)
+	}
+	if got := idx.Search("cafzz"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search with a missing n-gram = %v, want [1]", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "shared.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path, CachedOptions(opts)...)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("CAFÉ"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("cached Search = %v, want [1]", got)
+	}
+	if got := cached.NormalizerChain(); !reflect.DeepEqual(got, NormalizerChain{NormalizerStripAccents}) {
+		t.Errorf("cached NormalizerChain = %v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// saveChunkPerWorker is the number of bitmaps each worker serializes per
+// chunk of a parallel save.
+const saveChunkPerWorker = 256
+
+// WithSaveWorkers makes WriteTo and SaveToFile serialize bitmaps on n worker
+// goroutines while a single writer emits them in order, so saving a large
+// index is bound by the disk rather than by one core. Workers serialize the
+// next chunk of bitmaps while the previous one is written, holding at most
+// two chunks of serialized bitmaps in memory. The file is identical to a
+// sequential save. n <= 0 uses runtime.NumCPU().
+func WithSaveWorkers(n int) Option {
+	return indexOption(func(idx *Index) {
+		if n <= 0 {
+			n = runtime.NumCPU()
+		}
+		idx.saveWorkers = n
+	})
+}
+
+// serializedChunk is a run of bitmaps serialized by a parallel save.
+type serializedChunk struct {
+	start int
+	data  [][]byte
+	err   error
+}
+
+// writeBitmapsParallelLocked serializes the n-gram bitmaps on
+// idx.saveWorkers goroutines and passes them to emit in WriteTo order.
+func (idx *Index) writeBitmapsParallelLocked(emit func(key uint64, bmBytes []byte) error) error {
+	keys := make([]uint64, 0, len(idx.bitmaps))
+	bitmaps := make([]*roaring.Bitmap, 0, len(idx.bitmaps))
+	for key, bm := range idx.orderedBitmapsLocked() {
+		keys = append(keys, key)
+		bitmaps = append(bitmaps, bm)
+	}
+
+	chunks := make(chan serializedChunk, 1)
+	stop := make(chan struct{})
+	go serializeChunks(bitmaps, idx.saveWorkers, chunks, stop)
+	defer func() {
+		// Wait for the serializer, so no worker reads a bitmap after the
+		// caller releases the read lock
+		close(stop)
+		for range chunks {
+		}
+	}()
+
+	for chunk := range chunks {
+		if chunk.err != nil {
+			return fmt.Errorf("serialize bitmap: %w", chunk.err)
+		}
+		for i, bmBytes := range chunk.data {
+			if err := emit(keys[chunk.start+i], bmBytes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serializeChunks serializes bitmaps chunk by chunk on workers goroutines
+// and sends each chunk to out, stopping early when stop is closed. It
+// closes out when done.
+func serializeChunks(bitmaps []*roaring.Bitmap, workers int, out chan<- serializedChunk, stop <-chan struct{}) {
+	defer close(out)
+	size := saveChunkPerWorker * workers
+	for start := 0; start < len(bitmaps); start += size {
+		chunk := serializedChunk{start: start, data: make([][]byte, min(size, len(bitmaps)-start))}
+		errs := make([]error, len(chunk.data))
+		runBatch(len(chunk.data), workers, func(i int) {
+			chunk.data[i], errs[i] = bitmaps[start+i].ToBytes()
+		})
+		for _, err := range errs {
+			if err != nil {
+				chunk.err = err
+				break
+			}
+		}
+
+		select {
+		case out <- chunk:
+		case <-stop:
+			return
+		}
+		if chunk.err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// failingWriter accepts n bytes and then fails.
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return 0, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWithSaveWorkers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]string, 2000)
+	for i := range docs {
+		docs[i] = generateDocument(rng, 5, 20)
+	}
+	build := func(opts ...Option) *Index {
+		idx := NewIndex(3, append(opts, WithDeterministicBuild(), WithContainerDirectory())...)
+		for i, doc := range docs {
+			idx.Add(uint32(i), doc)
+		}
+		return idx
+	}
+
+	var want bytes.Buffer
+	if _, err := build().WriteTo(&want); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	for _, workers := range []int{0, 1, 3} {
+		var got bytes.Buffer
+		n, err := build(WithSaveWorkers(workers)).WriteTo(&got)
+		if err != nil {
+			t.Fatalf("%d workers: WriteTo failed: %v", workers, err)
+		}
+		if n != int64(got.Len()) || !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%d workers: parallel save differs from sequential save", workers)
+		}
+	}
+
+	idx := build(WithSaveWorkers(4))
+	if _, err := idx.WriteTo(&failingWriter{n: want.Len() / 2}); err == nil {
+		t.Error("expected the write error")
+	}
+	// The index stays writable after an aborted save
+	idx.Add(9999, testHelloWorld)
+}
@@ -0,0 +1,99 @@
+package roaringsearch
+
+import "cmp"
+
+// Posting is a search result with the payload of its document.
+type Posting[T cmp.Ordered] struct {
+	DocID   uint32
+	Payload T
+}
+
+// PayloadIndex is an Index whose documents each carry a small value, such as
+// a uint8 section ID or flag set, returned alongside search results so
+// callers can tell hits apart without a second lookup or a second index.
+// Payloads are kept in a SortColumn, an array parallel to the docIDs, and
+// documents without one read as the zero value.
+//
+// Example:
+//
+//	idx := rs.NewPayloadIndex[uint8](3)
+//	idx.Add(1, "Quick start", sectionTitle)
+//	idx.Add(2, "Start the server with ...", sectionBody)
+//	for _, p := range idx.Search("start") {
+//	    fmt.Println(p.DocID, p.Payload)
+//	}
+type PayloadIndex[T cmp.Ordered] struct {
+	idx      *Index
+	payloads *SortColumn[T]
+}
+
+// NewPayloadIndex creates a PayloadIndex; the options configure the Index.
+func NewPayloadIndex[T cmp.Ordered](gramSize int, opts ...Option) *PayloadIndex[T] {
+	return &PayloadIndex[T]{
+		idx:      NewIndex(gramSize, opts...),
+		payloads: NewSortColumn[T](),
+	}
+}
+
+// Index returns the underlying index, for filters, statistics and saving.
+// Modify documents through the PayloadIndex so payloads stay in step.
+func (p *PayloadIndex[T]) Index() *Index {
+	return p.idx
+}
+
+// Payloads returns the payload column, for sorting and saving with the
+// SortColumn API.
+func (p *PayloadIndex[T]) Payloads() *SortColumn[T] {
+	return p.payloads
+}
+
+// Add indexes a document with its payload, replacing the payload of an
+// earlier Add. Like Index.Add it does not remove the document's old
+// n-grams; use Update to replace its text.
+func (p *PayloadIndex[T]) Add(docID uint32, text string, payload T) {
+	p.idx.Add(docID, text)
+	p.payloads.Set(docID, payload)
+}
+
+// Update replaces a document's text and payload.
+func (p *PayloadIndex[T]) Update(docID uint32, text string, payload T) {
+	p.idx.Update(docID, text)
+	p.payloads.Set(docID, payload)
+}
+
+// Remove removes a document and its payload.
+func (p *PayloadIndex[T]) Remove(docID uint32) {
+	p.idx.Remove(docID)
+	p.payloads.RemoveMany([]uint32{docID})
+}
+
+// Payload returns the payload of a document, or the zero value.
+func (p *PayloadIndex[T]) Payload(docID uint32) T {
+	return p.payloads.Get(docID)
+}
+
+// Search runs Index.Search and returns each match with its payload, in
+// docID order.
+func (p *PayloadIndex[T]) Search(query string) []Posting[T] {
+	return p.withPayloads(p.idx.Search(query))
+}
+
+// SearchAny runs Index.SearchAny and returns each match with its payload,
+// in docID order.
+func (p *PayloadIndex[T]) SearchAny(query string) []Posting[T] {
+	return p.withPayloads(p.idx.SearchAny(query))
+}
+
+// withPayloads pairs docIDs with their payloads under one column lock.
+func (p *PayloadIndex[T]) withPayloads(ids []uint32) []Posting[T] {
+	if len(ids) == 0 {
+		return nil
+	}
+	postings := make([]Posting[T], len(ids))
+	p.payloads.mu.RLock()
+	defer p.payloads.mu.RUnlock()
+	for i, id := range ids {
+		postings[i] = Posting[T]{DocID: id, Payload: p.payloads.valueLocked(id)}
+	}
+	return postings
+}
@@ -0,0 +1,47 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPayloadIndex(t *testing.T) {
+	const (
+		title uint8 = 1
+		body  uint8 = 2
+	)
+	idx := NewPayloadIndex[uint8](3)
+	idx.Add(1, "Quick start", title)
+	idx.Add(2, "Start the server with make run", body)
+	idx.Add(3, testHelloWorld, body)
+
+	want := []Posting[uint8]{{1, title}, {2, body}}
+	if got := idx.Search("start"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Search = %v, want %v", got, want)
+	}
+	if got := idx.SearchAny("quick hello"); !reflect.DeepEqual(got, []Posting[uint8]{{1, title}, {3, body}}) {
+		t.Errorf("SearchAny = %v", got)
+	}
+	if got := idx.Search("zzz"); got != nil {
+		t.Errorf("no match = %v, want nil", got)
+	}
+
+	idx.Update(2, "server setup", title)
+	if got := idx.Search("start"); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("after Update = %v, want %v", got, want[:1])
+	}
+	if got := idx.Payload(2); got != title {
+		t.Errorf("Payload(2) = %d, want %d", got, title)
+	}
+
+	idx.Remove(1)
+	if got := idx.Search("start"); got != nil {
+		t.Errorf("after Remove = %v, want nil", got)
+	}
+	if got := idx.Payload(1); got != 0 {
+		t.Errorf("removed payload = %d, want 0", got)
+	}
+	if idx.Index().DocCount() != 2 || idx.Payloads().Get(3) != body {
+		t.Error("Index and Payloads should expose the underlying state")
+	}
+}
@@ -0,0 +1,157 @@
+package roaringsearch
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// Percolator matches documents against saved queries, the inverse of
+// Search: instead of finding the documents containing a query, it finds the
+// queries a new document satisfies, for alerting on incoming text. A query
+// matches a document when the document contains all of its n-grams, as in
+// Search, so matches are candidates to verify in the same way.
+//
+// Each query is filed under one anchor n-gram, the one shared with the
+// fewest other queries when it is added, so a document only checks the
+// queries anchored on its own n-grams.
+//
+// Example:
+//
+//	p := rs.NewPercolator(3)
+//	p.Add(1, "outage")
+//	p.Add(2, "price drop")
+//	p.Match("Major outage reported in us-east") // [1]
+type Percolator struct {
+	keys *Index // generates n-gram keys; holds no documents
+
+	mu      sync.RWMutex
+	queries map[uint32][]uint64        // query ID -> sorted n-gram keys
+	anchors map[uint64]*roaring.Bitmap // anchor key -> query IDs
+	anchor  map[uint32]uint64          // query ID -> its anchor key
+}
+
+// NewPercolator creates a Percolator with the given gram size. Options such
+// as WithNormalizer configure n-gram generation as for NewIndex, and must
+// match the index the queries are written for.
+func NewPercolator(gramSize int, opts ...Option) *Percolator {
+	return &Percolator{
+		keys:    NewIndex(gramSize, opts...),
+		queries: make(map[uint32][]uint64),
+		anchors: make(map[uint64]*roaring.Bitmap),
+		anchor:  make(map[uint32]uint64),
+	}
+}
+
+// Len returns the number of saved queries.
+func (p *Percolator) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.queries)
+}
+
+// Add saves a query under queryID, replacing any query saved under it
+// before. It returns ErrQueryTooShort, and saves nothing, if the query has
+// no n-grams.
+func (p *Percolator) Add(queryID uint32, query string) error {
+	// Document keys, so exact-key dictionaries assign the query's n-grams
+	scratch := indexScratchPool.Get().(*indexScratch)
+	keys := slices.Clone(p.keys.appendDocumentKeys(scratch, query))
+	putIndexScratch(scratch)
+	if len(keys) == 0 {
+		return ErrQueryTooShort
+	}
+	slices.Sort(keys)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(queryID)
+
+	anchor := keys[0]
+	least := uint64(1<<64 - 1)
+	for _, key := range keys {
+		n := uint64(0)
+		if bm, ok := p.anchors[key]; ok {
+			n = bm.GetCardinality()
+		}
+		if n < least {
+			anchor, least = key, n
+		}
+	}
+	bm, ok := p.anchors[anchor]
+	if !ok {
+		bm = roaring.New()
+		p.anchors[anchor] = bm
+	}
+	bm.Add(queryID)
+	p.queries[queryID] = keys
+	p.anchor[queryID] = anchor
+	return nil
+}
+
+// Remove deletes a saved query.
+func (p *Percolator) Remove(queryID uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(queryID)
+}
+
+func (p *Percolator) removeLocked(queryID uint32) {
+	anchor, ok := p.anchor[queryID]
+	if !ok {
+		return
+	}
+	if bm := p.anchors[anchor]; bm != nil {
+		bm.Remove(queryID)
+		if bm.IsEmpty() {
+			delete(p.anchors, anchor)
+		}
+	}
+	delete(p.anchor, queryID)
+	delete(p.queries, queryID)
+}
+
+// Match returns the IDs of the saved queries whose n-grams all occur in
+// text, in ascending order.
+func (p *Percolator) Match(text string) []uint32 {
+	// Query keys only look n-grams up, so documents never grow the
+	// dictionary; n-grams no query has are all the same missing key
+	var keyBuf [queryKeyBufSize]uint64
+	docKeys := p.keys.appendQueryKeys(keyBuf[:0], text)
+	slices.Sort(docKeys)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var matches []uint32
+	for _, key := range docKeys {
+		bm, ok := p.anchors[key]
+		if !ok {
+			continue
+		}
+		bm.Iterate(func(queryID uint32) bool {
+			if containsSorted(docKeys, p.queries[queryID]) {
+				matches = append(matches, queryID)
+			}
+			return true
+		})
+	}
+	// Each query has one anchor, so there are no duplicates to remove
+	slices.Sort(matches)
+	return matches
+}
+
+// containsSorted reports whether sorted set holds every key of sorted keys.
+func containsSorted(set, keys []uint64) bool {
+	i := 0
+	for _, key := range keys {
+		for i < len(set) && set[i] < key {
+			i++
+		}
+		if i == len(set) || set[i] != key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package roaringsearch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPercolator(t *testing.T) {
+	p := NewPercolator(3)
+	for id, query := range map[uint32]string{
+		1: "outage",
+		2: "price drop",
+		3: "brown fox",
+		4: "quick",
+		5: "lazy cat",
+	} {
+		if err := p.Add(id, query); err != nil {
+			t.Fatalf("Add(%d) error: %v", id, err)
+		}
+	}
+	if err := p.Add(6, "ab"); !errors.Is(err, ErrQueryTooShort) {
+		t.Errorf("Add(short) = %v, want ErrQueryTooShort", err)
+	}
+	if got := p.Len(); got != 5 {
+		t.Errorf("Len = %d, want 5", got)
+	}
+
+	tests := []struct {
+		text string
+		want []uint32
+	}{
+		{testQuickBrownFox, []uint32{3, 4}},
+		{"Major OUTAGE reported; price drops", []uint32{1, 2}},
+		{testHelloWorld, nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := p.Match(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	p.Remove(3)
+	if err := p.Add(4, "lazy dog"); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Match(testQuickBrownFox); !reflect.DeepEqual(got, []uint32{4}) {
+		t.Errorf("Match after Remove and replace = %v, want [4]", got)
+	}
+}
+
+func TestPercolatorExactKeys(t *testing.T) {
+	p := NewPercolator(3, WithExactKeys())
+	if err := p.Add(1, "brown fox"); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Match(testQuickBrownFox); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Match = %v, want [1]", got)
+	}
+	if got := p.Match(testHelloWorld); got != nil {
+		t.Errorf("Match(unrelated) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,275 @@
+package roaringsearch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+	"strconv"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// PilosaShardWidth is the number of columns per shard in Pilosa and
+// FeatureBase; a docID is column docID%PilosaShardWidth of shard
+// docID/PilosaShardWidth.
+const PilosaShardWidth = 1 << 20
+
+// Pilosa's roaring file format: a cookie (magic number and version), the
+// container count, a 12-byte descriptor per container (key, type,
+// cardinality-1), a 4-byte offset per container, then the containers. Keys
+// are the high 48 bits of 64-bit positions row*PilosaShardWidth + column.
+const (
+	pilosaMagic      = 12348
+	pilosaHeaderSize = 8
+
+	pilosaArray  = 1
+	pilosaBitmap = 2
+	pilosaRun    = 3
+
+	pilosaArrayMax   = 4096
+	pilosaBitmapSize = 8192
+)
+
+// pilosaContainer is one 2^16-position container of a Pilosa bitmap.
+type pilosaContainer struct {
+	key    uint64
+	values []uint16
+}
+
+// PilosaShards returns the shards holding documents of field, in order.
+func (c *BitmapFilter) PilosaShards(field string) []uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	docs := roaring.New()
+	for _, bm := range c.fields[field] {
+		docs.Or(bm)
+	}
+	var shards []uint64
+	it := docs.Iterator()
+	for it.HasNext() {
+		shard := uint64(it.Next()) / PilosaShardWidth
+		shards = append(shards, shard)
+		next := (shard + 1) * PilosaShardWidth
+		if next >= 1<<32 {
+			break
+		}
+		it.AdvanceIfNeeded(uint32(next))
+	}
+	return shards
+}
+
+// ExportPilosa writes one shard of field as a Pilosa/FeatureBase roaring
+// fragment, for loading with FeatureBase's import-roaring endpoint. Categories
+// become rows in sorted order; the returned slice maps row IDs to categories
+// for key translation. Use PilosaShards to list the shards to export.
+func (c *BitmapFilter) ExportPilosa(w io.Writer, field string, shard uint64) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	fieldMap := c.fields[field]
+	rows := make([]string, 0, len(fieldMap))
+	for cat := range fieldMap {
+		rows = append(rows, cat)
+	}
+	slices.Sort(rows)
+
+	base := shard * PilosaShardWidth
+	var containers []pilosaContainer
+	for row, cat := range rows {
+		if base >= 1<<32 {
+			break // no uint32 docIDs in this shard
+		}
+		shardDocs := fieldMap[cat].Clone()
+		shardDocs.RemoveRange(0, base)
+		shardDocs.RemoveRange(base+PilosaShardWidth, 1<<32)
+
+		it := shardDocs.Iterator()
+		for it.HasNext() {
+			pos := uint64(row)*PilosaShardWidth + uint64(it.Next()) - base
+			key := pos >> 16
+			if n := len(containers); n == 0 || containers[n-1].key != key {
+				containers = append(containers, pilosaContainer{key: key})
+			}
+			last := &containers[len(containers)-1]
+			last.values = append(last.values, uint16(pos))
+		}
+	}
+	return rows, writePilosaRoaring(w, containers)
+}
+
+// writePilosaRoaring encodes containers, sorted by key, in Pilosa's format.
+func writePilosaRoaring(w io.Writer, containers []pilosaContainer) error {
+	n := len(containers)
+	header := make([]byte, pilosaHeaderSize+n*16)
+	binary.LittleEndian.PutUint32(header[0:], pilosaMagic)
+	binary.LittleEndian.PutUint32(header[4:], uint32(n))
+
+	offset := uint32(len(header))
+	for i, ct := range containers {
+		desc := header[pilosaHeaderSize+i*12:]
+		typ, size := uint16(pilosaArray), uint32(2*len(ct.values))
+		if len(ct.values) > pilosaArrayMax {
+			typ, size = pilosaBitmap, pilosaBitmapSize
+		}
+		binary.LittleEndian.PutUint64(desc[0:], ct.key)
+		binary.LittleEndian.PutUint16(desc[8:], typ)
+		binary.LittleEndian.PutUint16(desc[10:], uint16(len(ct.values)-1))
+		binary.LittleEndian.PutUint32(header[pilosaHeaderSize+n*12+i*4:], offset)
+		offset += size
+	}
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("write pilosa header: %w", err)
+	}
+
+	var buf []byte
+	for _, ct := range containers {
+		buf = buf[:0]
+		if len(ct.values) > pilosaArrayMax {
+			var words [pilosaBitmapSize / 8]uint64
+			for _, v := range ct.values {
+				words[v>>6] |= 1 << (v & 63)
+			}
+			for _, word := range words {
+				buf = binary.LittleEndian.AppendUint64(buf, word)
+			}
+		} else {
+			for _, v := range ct.values {
+				buf = binary.LittleEndian.AppendUint16(buf, v)
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return fmt.Errorf("write pilosa container: %w", err)
+		}
+	}
+	return nil
+}
+
+// ImportPilosa adds one shard of a Pilosa/FeatureBase roaring fragment to
+// field: each set position row*PilosaShardWidth + column becomes document
+// shard*PilosaShardWidth + column in category rows[row]. Rows beyond the
+// rows slice, or all rows when it is nil, use the decimal row ID as the
+// category. Fragments with a pending operation log must be snapshotted first.
+func (c *BitmapFilter) ImportPilosa(r io.Reader, field string, shard uint64, rows []string) error {
+	if shard >= 1<<32/PilosaShardWidth {
+		return fmt.Errorf("%w: shard %d beyond the uint32 docID range", ErrInvalidSize, shard)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read pilosa fragment: %w", err)
+	}
+	containers, err := readPilosaRoaring(data)
+	if err != nil {
+		return err
+	}
+
+	byRow := make(map[uint64][]uint32)
+	base := uint32(shard * PilosaShardWidth)
+	for _, ct := range containers {
+		for _, v := range ct.values {
+			pos := ct.key<<16 | uint64(v)
+			row := pos / PilosaShardWidth
+			byRow[row] = append(byRow[row], base+uint32(pos%PilosaShardWidth))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for row, ids := range byRow {
+		cat := strconv.FormatUint(row, 10)
+		if row < uint64(len(rows)) {
+			cat = rows[row]
+		}
+		c.categoryLocked(field, cat).AddMany(ids)
+		c.all.AddMany(ids)
+	}
+	c.dirty.Store(true)
+	return nil
+}
+
+// readPilosaRoaring decodes a bitmap in Pilosa's roaring format.
+func readPilosaRoaring(data []byte) ([]pilosaContainer, error) {
+	if len(data) < pilosaHeaderSize {
+		return nil, fmt.Errorf("read pilosa header: %w", io.ErrUnexpectedEOF)
+	}
+	if binary.LittleEndian.Uint16(data[0:]) != pilosaMagic {
+		return nil, ErrInvalidMagic
+	}
+	if data[2] != 0 {
+		return nil, ErrInvalidVersion
+	}
+	n := int(binary.LittleEndian.Uint32(data[4:]))
+	if n > (len(data)-pilosaHeaderSize)/16 {
+		return nil, ErrInvalidCount
+	}
+
+	containers := make([]pilosaContainer, n)
+	end := pilosaHeaderSize + n*16
+	for i := range containers {
+		desc := data[pilosaHeaderSize+i*12:]
+		key := binary.LittleEndian.Uint64(desc[0:])
+		typ := binary.LittleEndian.Uint16(desc[8:])
+		card := int(binary.LittleEndian.Uint16(desc[10:])) + 1
+		offset := int(binary.LittleEndian.Uint32(data[pilosaHeaderSize+n*12+i*4:]))
+		if offset < pilosaHeaderSize+n*16 || offset > len(data) {
+			return nil, fmt.Errorf("%w: container %d offset %d", ErrInvalidSize, i, offset)
+		}
+		body := data[offset:]
+
+		var values []uint16
+		var size int
+		switch typ {
+		case pilosaArray:
+			size = 2 * card
+			if size > len(body) {
+				return nil, fmt.Errorf("%w: array container %d", ErrInvalidSize, i)
+			}
+			values = make([]uint16, card)
+			for j := range values {
+				values[j] = binary.LittleEndian.Uint16(body[2*j:])
+			}
+		case pilosaBitmap:
+			size = pilosaBitmapSize
+			if size > len(body) {
+				return nil, fmt.Errorf("%w: bitmap container %d", ErrInvalidSize, i)
+			}
+			values = make([]uint16, 0, card)
+			for j := 0; j < pilosaBitmapSize/8; j++ {
+				for word := binary.LittleEndian.Uint64(body[8*j:]); word != 0; word &= word - 1 {
+					values = append(values, uint16(j*64+bits.TrailingZeros64(word)))
+				}
+			}
+		case pilosaRun:
+			if len(body) < 2 {
+				return nil, fmt.Errorf("%w: run container %d", ErrInvalidSize, i)
+			}
+			runs := int(binary.LittleEndian.Uint16(body))
+			size = 2 + 4*runs
+			if size > len(body) {
+				return nil, fmt.Errorf("%w: run container %d", ErrInvalidSize, i)
+			}
+			values = make([]uint16, 0, card)
+			for j := 0; j < runs; j++ {
+				start := int(binary.LittleEndian.Uint16(body[2+4*j:]))
+				last := int(binary.LittleEndian.Uint16(body[4+4*j:]))
+				if last < start || len(values)+last-start+1 > card {
+					return nil, fmt.Errorf("%w: run container %d", ErrInvalidSize, i)
+				}
+				for v := start; v <= last; v++ {
+					values = append(values, uint16(v))
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w: container %d has unknown type %d", ErrInvalidSize, i, typ)
+		}
+		containers[i] = pilosaContainer{key: key, values: values}
+		end = max(end, offset+size)
+	}
+
+	if end < len(data) {
+		return nil, fmt.Errorf("%w: %d bytes after the containers (operation log?)", ErrInvalidSize, len(data)-end)
+	}
+	return containers, nil
+}
@@ -0,0 +1,108 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPilosaRoundTrip(t *testing.T) {
+	filter := NewBitmapFilter()
+	for i := uint32(0); i < 10000; i++ {
+		filter.Set(i, "parity", []string{"even", "odd"}[i%2])
+	}
+	filter.Set(3*PilosaShardWidth+5, "parity", "odd")
+	filter.Set(1<<32-1, "parity", "odd")
+	filter.Set(7, "other", "x")
+
+	shards := filter.PilosaShards("parity")
+	if want := []uint64{0, 3, 4095}; !reflect.DeepEqual(shards, want) {
+		t.Fatalf("PilosaShards = %v, want %v", shards, want)
+	}
+
+	imported := NewBitmapFilter()
+	for _, shard := range shards {
+		var buf bytes.Buffer
+		rows, err := filter.ExportPilosa(&buf, "parity", shard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rows, []string{"even", "odd"}) {
+			t.Errorf("rows = %v", rows)
+		}
+		if err := imported.ImportPilosa(&buf, "parity", shard, rows); err != nil {
+			t.Fatalf("ImportPilosa shard %d: %v", shard, err)
+		}
+	}
+	for _, cat := range []string{"even", "odd"} {
+		if !imported.Get("parity", cat).Equals(filter.Get("parity", cat)) {
+			t.Errorf("category %s differs after round trip", cat)
+		}
+	}
+	if imported.DocCount() != 10002 {
+		t.Errorf("DocCount = %d, want 10002", imported.DocCount())
+	}
+
+	// Rows without names keep their numeric IDs
+	var buf bytes.Buffer
+	filter.ExportPilosa(&buf, "parity", 0)
+	unnamed := NewBitmapFilter()
+	if err := unnamed.ImportPilosa(&buf, "parity", 0, []string{"even"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := unnamed.Get("parity", "1").GetCardinality(); got != 5000 {
+		t.Errorf("row 1 imported as category \"1\" with %d docs, want 5000", got)
+	}
+}
+
+// pilosaRunFragment encodes row 2 holding columns 10-19 and 100 as one run
+// container, as Pilosa writes dense ranges.
+func pilosaRunFragment() []byte {
+	data := binary.LittleEndian.AppendUint32(nil, pilosaMagic)
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	data = binary.LittleEndian.AppendUint64(data, 2*PilosaShardWidth>>16)
+	data = binary.LittleEndian.AppendUint16(data, pilosaRun)
+	data = binary.LittleEndian.AppendUint16(data, 11-1)
+	data = binary.LittleEndian.AppendUint32(data, pilosaHeaderSize+16)
+	data = binary.LittleEndian.AppendUint16(data, 2)
+	for _, v := range []uint16{10, 19, 100, 100} {
+		data = binary.LittleEndian.AppendUint16(data, v)
+	}
+	return data
+}
+
+func TestImportPilosaRunContainer(t *testing.T) {
+	filter := NewBitmapFilter()
+	if err := filter.ImportPilosa(bytes.NewReader(pilosaRunFragment()), "f", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := filter.Get("f", "2").ToArray()
+	if len(got) != 11 || got[0] != PilosaShardWidth+10 || got[10] != PilosaShardWidth+100 {
+		t.Errorf("imported docs = %v", got)
+	}
+}
+
+func TestImportPilosaErrors(t *testing.T) {
+	valid := pilosaRunFragment()
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"magic", append([]byte{1, 2}, valid[2:]...), ErrInvalidMagic},
+		{"count", valid[:20], ErrInvalidCount},
+		{"truncated", valid[:len(valid)-2], ErrInvalidSize},
+		{"op log", append(bytes.Clone(valid), 1, 2, 3), ErrInvalidSize},
+	}
+	for _, tt := range tests {
+		err := NewBitmapFilter().ImportPilosa(bytes.NewReader(tt.data), "f", 0, nil)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := NewBitmapFilter().ImportPilosa(bytes.NewReader(valid), "f", 4096, nil); err == nil {
+		t.Error("shard beyond the docID range imported")
+	}
+}
@@ -0,0 +1,101 @@
+package roaringsearch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// Pin loads the bitmaps for n-gram strings and keeps them resident regardless
+// of LRU pressure until they are unpinned. Strings are used as-is, so they
+// should already be normalized; strings that are not exactly GramSize runes
+// long or are missing from the index are ignored.
+func (idx *CachedIndex) Pin(ngrams []string) error {
+	return idx.PinKeys(idx.ngramKeys(ngrams))
+}
+
+// PinKeys is like Pin but takes n-gram keys, as computed by NgramKey.
+// Pinned bitmaps do not count toward WithCacheSize or WithMemoryBudget;
+// see PinnedMemoryUsage.
+func (idx *CachedIndex) PinKeys(keys []uint64) error {
+	var errs []error
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := idx.pinned[key]; ok {
+			continue
+		}
+		loc, ok := idx.ngramIndex[key]
+		if !ok {
+			continue
+		}
+
+		bm, ok := idx.lru.remove(key)
+		if !ok {
+			var err error
+			if bm, err = idx.loadBitmap(loc); err != nil {
+				errs = append(errs, fmt.Errorf("failed to load key: %d: %w", key, err))
+				continue
+			}
+		}
+
+		if idx.pinned == nil {
+			idx.pinned = make(map[uint64]*roaring.Bitmap)
+		}
+		idx.pinned[key] = bm
+		idx.pinnedMemory += bm.GetSizeInBytes()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Unpin releases pinned n-grams back to the regular LRU cache.
+func (idx *CachedIndex) Unpin(ngrams []string) {
+	idx.UnpinKeys(idx.ngramKeys(ngrams))
+}
+
+// UnpinKeys releases pinned keys back to the regular LRU cache.
+func (idx *CachedIndex) UnpinKeys(keys []uint64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, key := range keys {
+		bm, ok := idx.pinned[key]
+		if !ok {
+			continue
+		}
+		delete(idx.pinned, key)
+		idx.pinnedMemory -= bm.GetSizeInBytes()
+		idx.lru.add(key, bm)
+	}
+}
+
+// PinnedCount returns the number of pinned bitmaps.
+func (idx *CachedIndex) PinnedCount() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.pinned)
+}
+
+// PinnedMemoryUsage returns the memory used by pinned bitmaps in bytes.
+// It is separate from MemoryUsage, which covers only the LRU cache.
+func (idx *CachedIndex) PinnedMemoryUsage() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.pinnedMemory
+}
+
+// ngramKeys converts n-gram strings of the index's gram size to keys.
+func (idx *CachedIndex) ngramKeys(ngrams []string) []uint64 {
+	keys := make([]uint64, 0, len(ngrams))
+	for _, ngram := range ngrams {
+		runes := []rune(ngram)
+		if len(runes) == idx.gramSize {
+			keys = append(keys, idx.exact.queryKey(runes))
+		}
+	}
+	return keys
+}
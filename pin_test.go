@@ -0,0 +1,48 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCachedIndexPin(t *testing.T) {
+	path := newAdmissionTestFile(t)
+	cached, err := OpenCachedIndex(path, WithCacheSize(2))
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	defer cached.Close()
+
+	// Pinning an n-gram already in the LRU moves it out of the LRU
+	cached.SearchAny("wor")
+	if err := cached.Pin([]string{"wor", "ord", "zzz", "toolong"}); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if cached.PinnedCount() != 2 || cached.CacheSize() != 0 {
+		t.Errorf("PinnedCount = %d, CacheSize = %d, want 2 and 0", cached.PinnedCount(), cached.CacheSize())
+	}
+	if cached.PinnedMemoryUsage() == 0 || cached.MemoryUsage() != 0 {
+		t.Errorf("pinned memory = %d, LRU memory = %d", cached.PinnedMemoryUsage(), cached.MemoryUsage())
+	}
+
+	// LRU pressure does not evict pinned bitmaps
+	for i := 0; i < 50; i++ {
+		cached.SearchAny(fmt.Sprintf("d%02d", i))
+	}
+	if cached.PinnedCount() != 2 {
+		t.Errorf("PinnedCount after scan = %d, want 2", cached.PinnedCount())
+	}
+	if got := cached.Search("word"); len(got) != 50 {
+		t.Errorf("Search with pinned n-grams = %d docs, want 50", len(got))
+	}
+
+	cached.ClearCache()
+	if cached.PinnedCount() != 2 {
+		t.Error("ClearCache should not drop pinned bitmaps")
+	}
+
+	cached.Unpin([]string{"wor"})
+	if cached.PinnedCount() != 1 || cached.CacheSize() != 1 {
+		t.Errorf("after Unpin: PinnedCount = %d, CacheSize = %d, want 1 and 1", cached.PinnedCount(), cached.CacheSize())
+	}
+}
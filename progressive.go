@@ -0,0 +1,305 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// progressiveBatch is the number of bitmaps a background load moves into
+// the index per write lock.
+const progressiveBatch = 1024
+
+// ProgressiveLoad tracks the background load of an index opened with
+// LoadFromFileAsync.
+type ProgressiveLoad struct {
+	idx            *Index
+	path           string
+	file           *os.File
+	order          []uint64 // keys in load order
+	rebuildForward bool     // record loaded bitmaps in a forward index the file lacks
+	start          time.Time
+
+	mu      sync.Mutex
+	pending map[uint64]ngramLocation   // keys not yet moved into idx.bitmaps
+	fetched map[uint64]*roaring.Bitmap // pending keys loaded by a search
+
+	loaded atomic.Int64
+	done   chan struct{}
+	err    error // set before done is closed
+}
+
+// LoadFromFileAsync opens an index file and returns the index at once, while
+// its bitmaps load in the background, so a multi-GB index can serve searches
+// long before LoadFromFile would return. A search that needs a bitmap not
+// yet loaded reads it from the file first, so results are always complete.
+//
+// Only the n-gram locations and the sections after the n-grams are read up
+// front. Bitmaps then load most popular first, in the order of the hot keys
+// file given with WithHotKeys (see CachedIndex.SaveHotKeys), and the rest in
+// file order. Options are applied as by LoadFromFileWithOptions.
+//
+// Until Wait returns, MatchAll, DocCount, statistics, iteration and saving
+// see only the bitmaps loaded so far, and the index must not be modified:
+// a bitmap loaded after an Add or Remove would not reflect it.
+//
+// Example:
+//
+//	idx, load, err := rs.LoadFromFileAsync("index.sear", rs.WithHotKeys("hot.keys"))
+//	if err != nil {
+//	    return err
+//	}
+//	go func() {
+//	    if err := load.Wait(); err != nil {
+//	        log.Printf("index load: %v", err)
+//	    }
+//	}()
+//	idx.Search("hello") // served immediately
+func LoadFromFileAsync(path string, opts ...Option) (*Index, *ProgressiveLoad, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open file: %w", err)
+	}
+
+	idx := NewIndex(3) // gram size will be overwritten by the file
+	load, err := idx.openProgressive(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	load.path = path
+
+	fileForward := idx.forward != nil
+	recorded := idx.recordedNormalizerLocked()
+	exact := idx.exact != nil
+	for _, opt := range opts {
+		opt.applyIndex(idx)
+	}
+	if err := checkNormalizer(recorded, idx.normalizer, idx.normalizerChain); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if idx.exact != nil && !exact {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, ErrKeyModeMismatch)
+	}
+	load.rebuildForward = idx.forward != nil && !fileForward
+
+	load.order, err = progressiveOrder(load.pending, idx.hotKeysPath)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	idx.loading = load
+	go load.run()
+	return idx, load, nil
+}
+
+// openProgressive reads everything but the bitmaps of an index file into
+// idx and returns a load of the bitmaps, not yet started.
+func (idx *Index) openProgressive(file *os.File) (*ProgressiveLoad, error) {
+	f := io.NewSectionReader(file, 0, math.MaxInt64)
+	ext, ngramCount, read, err := idx.readPreambleLocked(f)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := readNgramLocations(f, ngramCount, read)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.bitmaps = make(map[uint64]*roaring.Bitmap, len(pending))
+	idx.docs = roaring.New()
+	if ext.flags&flagForward != 0 {
+		idx.forward = make(forwardIndex)
+	}
+	if _, err := idx.readTrailerLocked(f, ext); err != nil {
+		return nil, err
+	}
+
+	return &ProgressiveLoad{
+		idx:     idx,
+		file:    file,
+		start:   time.Now(),
+		pending: pending,
+		fetched: make(map[uint64]*roaring.Bitmap),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// progressiveOrder returns the keys of pending with those in the hot keys
+// file first, most frequent first, and the rest in file order. A missing
+// hot keys file is ignored.
+func progressiveOrder(pending map[uint64]ngramLocation, hotKeysPath string) ([]uint64, error) {
+	var hot []HotKey
+	if hotKeysPath != "" {
+		f, err := os.Open(hotKeysPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("open hot keys: %w", err)
+		}
+		if err == nil {
+			hot, err = readHotKeys(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	order := make([]uint64, 0, len(pending))
+	seen := make(map[uint64]struct{}, len(hot))
+	for _, hk := range hot {
+		if _, ok := pending[hk.Key]; !ok {
+			continue
+		}
+		if _, dup := seen[hk.Key]; !dup {
+			seen[hk.Key] = struct{}{}
+			order = append(order, hk.Key)
+		}
+	}
+	rest := slices.SortedFunc(maps.Keys(pending), func(a, b uint64) int {
+		return cmp.Compare(pending[a].offset, pending[b].offset)
+	})
+	for _, key := range rest {
+		if _, ok := seen[key]; !ok {
+			order = append(order, key)
+		}
+	}
+	return order, nil
+}
+
+// run loads every pending bitmap into the index, then closes the file.
+func (p *ProgressiveLoad) run() {
+	err := p.loadAll()
+
+	p.idx.mu.Lock()
+	p.idx.loading = nil
+	p.idx.mu.Unlock()
+	if cerr := p.file.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("close file: %w", cerr)
+	}
+
+	if l := p.idx.logger(); l != nil {
+		if err != nil {
+			l.Error("roaringsearch: load index", "path", p.path, "error", err)
+		} else {
+			l.Info("roaringsearch: loaded index",
+				"path", p.path, "ngrams", p.idx.NgramCount(), "duration", time.Since(p.start))
+		}
+	}
+	p.err = err
+	close(p.done)
+}
+
+// loadAll reads the bitmaps in load order and moves them into the index in
+// batches, stopping at the first error.
+func (p *ProgressiveLoad) loadAll() error {
+	keys := make([]uint64, 0, progressiveBatch)
+	bitmaps := make([]*roaring.Bitmap, 0, progressiveBatch)
+	for i, key := range p.order {
+		p.mu.Lock()
+		bm, fetched := p.fetched[key]
+		loc := p.pending[key]
+		p.mu.Unlock()
+
+		if !fetched {
+			var err error
+			if bm, err = loadBitmapAt(p.file, loc); err != nil {
+				return fmt.Errorf("load ngram key %d: %w", key, err)
+			}
+		}
+		keys = append(keys, key)
+		bitmaps = append(bitmaps, bm)
+
+		if len(keys) == progressiveBatch || i == len(p.order)-1 {
+			p.move(keys, bitmaps)
+			keys, bitmaps = keys[:0], bitmaps[:0]
+		}
+	}
+	return nil
+}
+
+// move adds loaded bitmaps to the index and drops them from pending.
+func (p *ProgressiveLoad) move(keys []uint64, bitmaps []*roaring.Bitmap) {
+	idx := p.idx
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, key := range keys {
+		bm := bitmaps[i]
+		if p.fetched[key] != nil {
+			bm = p.fetched[key] // a search may hold it already
+		}
+		idx.bitmaps[key] = bm
+		idx.docs.Or(bm)
+		if p.rebuildForward {
+			idx.forward.recordBitmap(key, bm)
+		}
+		delete(p.pending, key)
+		delete(p.fetched, key)
+	}
+	p.loaded.Add(int64(len(keys)))
+}
+
+// bitmapLocked returns the bitmap of key, reading it from the file first
+// when a LoadFromFileAsync load has not reached it yet. A bitmap that fails
+// to load is reported as missing; the background load reports the error.
+func (idx *Index) bitmapLocked(key uint64) (*roaring.Bitmap, bool) {
+	bm, ok := idx.bitmaps[key]
+	if ok || idx.loading == nil {
+		return bm, ok
+	}
+	return idx.loading.fetch(key)
+}
+
+// fetch returns the bitmap of a pending key, reading it on first use.
+func (p *ProgressiveLoad) fetch(key uint64) (*roaring.Bitmap, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if bm, ok := p.fetched[key]; ok {
+		return bm, true
+	}
+	loc, ok := p.pending[key]
+	if !ok {
+		return nil, false
+	}
+	bm, err := loadBitmapAt(p.file, loc)
+	if err != nil {
+		return nil, false
+	}
+	p.fetched[key] = bm
+	return bm, true
+}
+
+// Progress returns how many bitmaps have been loaded into the index and
+// how many the file has.
+func (p *ProgressiveLoad) Progress() (loaded, total int) {
+	return int(p.loaded.Load()), len(p.order)
+}
+
+// Done returns a channel that is closed when the load ends.
+func (p *ProgressiveLoad) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait blocks until every bitmap is loaded and returns the first error.
+// After an error the index holds only the bitmaps loaded before it.
+func (p *ProgressiveLoad) Wait() error {
+	<-p.done
+	return p.err
+}
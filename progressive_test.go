@@ -0,0 +1,81 @@
+package roaringsearch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFromFileAsync(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.sear")
+
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testGoodbyeWorld)
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	loaded, load, err := LoadFromFileAsync(path, WithForwardIndex())
+	if err != nil {
+		t.Fatalf("LoadFromFileAsync failed: %v", err)
+	}
+	// Searches racing the background load see complete results
+	for _, query := range []string{"hello", "world", "there"} {
+		if got, want := loaded.Search(query), idx.Search(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) during load = %v, want %v", query, got, want)
+		}
+	}
+	if err := load.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	if n, total := load.Progress(); n != idx.NgramCount() || total != idx.NgramCount() {
+		t.Errorf("Progress = %d/%d, want %d/%d", n, total, idx.NgramCount(), idx.NgramCount())
+	}
+	if loaded.DocCount() != 3 || loaded.NgramCount() != idx.NgramCount() {
+		t.Errorf("loaded %d docs and %d n-grams, want 3 and %d", loaded.DocCount(), loaded.NgramCount(), idx.NgramCount())
+	}
+	if !loaded.HasForwardIndex() {
+		t.Error("WithForwardIndex should build a forward index from the loaded bitmaps")
+	}
+	loaded.Remove(1)
+	if got := loaded.Search("hello"); !reflect.DeepEqual(got, []uint32{2}) {
+		t.Errorf("Search after Remove = %v, want [2]", got)
+	}
+
+	if _, _, err := LoadFromFileAsync(filepath.Join(dir, "missing.sear")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestProgressiveOrder(t *testing.T) {
+	pending := map[uint64]ngramLocation{
+		10: {offset: 300}, 20: {offset: 100}, 30: {offset: 200}, 40: {offset: 400},
+	}
+	if got, err := progressiveOrder(pending, ""); err != nil || !reflect.DeepEqual(got, []uint64{20, 30, 10, 40}) {
+		t.Errorf("file order = %v, %v; want [20 30 10 40]", got, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "hot.keys")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hot := []HotKey{{Key: 40, Frequency: 9}, {Key: 99, Frequency: 5}, {Key: 10, Frequency: 2}}
+	if err := writeHotKeys(f, hot); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got, err := progressiveOrder(pending, path)
+	if err != nil || !reflect.DeepEqual(got, []uint64{40, 10, 20, 30}) {
+		t.Errorf("hot keys order = %v, %v; want [40 10 20 30]", got, err)
+	}
+	if _, err := progressiveOrder(pending, filepath.Join(t.TempDir(), "none")); err != nil {
+		t.Errorf("missing hot keys file: %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// DefaultPushdownLimit is the filter size used by WithFilterPushdown(0).
+const DefaultPushdownLimit = 4096
+
+// pushdownMinSize is the on-disk size below which an n-gram bitmap is read
+// whole through the cache: up to about one dense container, a single read
+// of the bitmap costs no more than reading its header and a container.
+const pushdownMinSize = 8 << 10
+
+// WithFilterPushdown makes SearchAndFilter check small filters directly
+// against the n-gram bitmaps on disk. When the smallest filter holds at most
+// maxDocs documents, bitmaps that are not already in memory are not loaded:
+// only the roaring containers that hold filter documents are read, and
+// nothing is added to the cache. Use it under WithMemoryBudget when selective
+// filters such as a tenant or category meet common n-grams whose bitmaps
+// span megabytes. maxDocs <= 0 uses DefaultPushdownLimit.
+func WithFilterPushdown(maxDocs int) CachedIndexOption {
+	return cachedIndexOption(func(idx *CachedIndex) {
+		if maxDocs <= 0 {
+			maxDocs = DefaultPushdownLimit
+		}
+		idx.pushdownLimit = maxDocs
+	})
+}
+
+// searchPushdown runs SearchAndFilter by narrowing the filter documents with
+// each n-gram bitmap, reading large uncached bitmaps container by container.
+// It returns false if no filter is small enough or a bitmap cannot be read
+// from disk, leaving the search to the cached path.
+func (idx *CachedIndex) searchPushdown(keys []uint64, filters []*roaring.Bitmap) ([]uint32, bool) {
+	var smallest *roaring.Bitmap
+	for _, f := range filters {
+		if f != nil && (smallest == nil || f.GetCardinality() < smallest.GetCardinality()) {
+			smallest = f
+		}
+	}
+	if smallest == nil || smallest.GetCardinality() > uint64(idx.pushdownLimit) {
+		return nil, false
+	}
+	cand := smallest.Clone()
+	for _, f := range filters {
+		if f != nil && f != smallest {
+			cand.And(f)
+		}
+	}
+
+	// Locations and the reader come from one version of the file, even if a
+	// Refresh runs meanwhile
+	v := idx.view()
+	defer v.release()
+
+	// Bitmaps in memory or cheap to load narrow the candidates first
+	var onDisk []keyLocation
+	for _, key := range keys {
+		if cand.IsEmpty() {
+			return nil, true
+		}
+		loc, ok := v.location(key)
+		if !ok {
+			// Lenient policies are left to the cached path
+			return nil, idx.missingNgrams == MissingNgramFail
+		}
+		bm, ok := idx.residentBitmap(key)
+		if !ok && loc.size < pushdownMinSize {
+			bm, ok = idx.getBitmap(key)
+		}
+		if !ok {
+			onDisk = append(onDisk, keyLocation{key: key, loc: loc})
+			continue
+		}
+		cand.And(bm)
+	}
+
+	slices.SortFunc(onDisk, func(a, b keyLocation) int {
+		return cmp.Compare(a.loc.size, b.loc.size)
+	})
+	for _, kl := range onDisk {
+		if cand.IsEmpty() {
+			return nil, true
+		}
+		d, err := idx.diskBitmap(v, kl.key, kl.loc)
+		if err != nil {
+			return nil, false
+		}
+		if cand, err = d.and(cand); err != nil {
+			return nil, false
+		}
+	}
+
+	if cand.IsEmpty() {
+		return nil, true
+	}
+	return limitedArray(cand, idx.maxResults), true
+}
+
+// residentBitmap returns the bitmap of key if it is pinned or cached,
+// without loading it.
+func (idx *CachedIndex) residentBitmap(key uint64) (*roaring.Bitmap, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if bm, ok := idx.pinned[key]; ok {
+		return bm, true
+	}
+	return idx.lru.get(key)
+}
+
+// Serialization constants of the roaring portable format.
+const (
+	serialCookieNoRun = 12346 // only array and bitmap containers
+	serialCookieRun   = 12347 // may also hold run containers
+	noOffsetThreshold = 4     // run bitmaps with fewer containers omit offsets
+	arrayMaxSize      = 4096  // larger containers are stored as bitmaps
+	bitmapContainer   = 8192  // bytes of a bitmap container
+)
+
+// diskHeaderPrefix is how much of a bitmap is read to parse its header;
+// larger headers take a second read.
+const diskHeaderPrefix = 4096
+
+// diskBitmap is the container directory of a roaring bitmap serialized in
+// the portable format, so that single containers can be read on their own.
+type diskBitmap struct {
+	r       BlockReader
+	loc     ngramLocation
+	keys    []uint16 // high 16 bits of each container, ascending
+	cards   []uint16 // cardinality - 1 of each container
+	offsets []uint32 // container offsets from loc.offset, nil if not stored
+	runs    []byte   // bitset of run containers, nil if there are none
+}
+
+// readDiskBitmap reads the header of the bitmap at loc.
+func readDiskBitmap(r BlockReader, loc ngramLocation) (*diskBitmap, error) {
+	head := make([]byte, min(loc.size, diskHeaderPrefix))
+	if err := readFullAt(r, head, loc.offset); err != nil {
+		return nil, err
+	}
+	headerLen, err := bitmapHeaderLen(head)
+	if err != nil {
+		return nil, err
+	}
+	if headerLen > int(loc.size) {
+		return nil, ErrInvalidSize
+	}
+	if headerLen > len(head) {
+		head = make([]byte, headerLen)
+		if err := readFullAt(r, head, loc.offset); err != nil {
+			return nil, err
+		}
+	}
+
+	d := parseBitmapHeader(head[:headerLen])
+	d.r, d.loc = r, loc
+	return d, nil
+}
+
+// bitmapHeaderLen returns the header length of a serialized bitmap from at
+// least its first 8 bytes.
+func bitmapHeaderLen(head []byte) (int, error) {
+	if len(head) < 8 {
+		return 0, ErrInvalidSize
+	}
+	cookie := binary.LittleEndian.Uint32(head)
+	switch {
+	case cookie == serialCookieNoRun:
+		n := int(binary.LittleEndian.Uint32(head[4:]))
+		if n > 1<<16 {
+			return 0, ErrInvalidCount
+		}
+		return 8 + 8*n, nil
+	case cookie&0xFFFF == serialCookieRun:
+		n := int(cookie>>16) + 1
+		headerLen := 4 + (n+7)/8 + 4*n
+		if n >= noOffsetThreshold {
+			headerLen += 4 * n
+		}
+		return headerLen, nil
+	}
+	return 0, fmt.Errorf("invalid bitmap cookie %d", cookie)
+}
+
+// parseBitmapHeader parses a complete header, as sized by bitmapHeaderLen.
+func parseBitmapHeader(head []byte) *diskBitmap {
+	d := &diskBitmap{}
+	var n, pos int
+	cookie := binary.LittleEndian.Uint32(head)
+	if cookie == serialCookieNoRun {
+		n, pos = int(binary.LittleEndian.Uint32(head[4:])), 8
+	} else {
+		n, pos = int(cookie>>16)+1, 4
+		pos += (n + 7) / 8
+		d.runs = head[4:pos]
+	}
+
+	d.keys = make([]uint16, n)
+	d.cards = make([]uint16, n)
+	for i := range n {
+		d.keys[i] = binary.LittleEndian.Uint16(head[pos+4*i:])
+		d.cards[i] = binary.LittleEndian.Uint16(head[pos+4*i+2:])
+	}
+	if pos += 4 * n; pos < len(head) {
+		d.offsets = make([]uint32, n)
+		for i := range n {
+			d.offsets[i] = binary.LittleEndian.Uint32(head[pos+4*i:])
+		}
+	}
+	return d
+}
+
+// and returns the documents of cand that are in the bitmap, reading only
+// the containers that hold candidates.
+func (d *diskBitmap) and(cand *roaring.Bitmap) (*roaring.Bitmap, error) {
+	if d.offsets == nil {
+		// Offsets are only omitted for bitmaps of a few containers
+		bm, err := loadBitmapAt(d.r, d.loc)
+		if err != nil {
+			return nil, err
+		}
+		return roaring.And(cand, bm), nil
+	}
+
+	var matches []uint32
+	it := cand.Iterator()
+	for it.HasNext() {
+		high := it.PeekNext() >> 16
+		i, found := slices.BinarySearch(d.keys, uint16(high))
+		if !found {
+			if high == 0xFFFF {
+				break
+			}
+			it.AdvanceIfNeeded((high + 1) << 16)
+			continue
+		}
+		c, err := d.container(i)
+		if err != nil {
+			return nil, err
+		}
+		for it.HasNext() && it.PeekNext()>>16 == high {
+			docID := it.Next()
+			if c.contains(uint16(docID)) {
+				matches = append(matches, docID)
+			}
+		}
+	}
+	return roaring.BitmapOf(matches...), nil
+}
+
+// isRun reports whether container i is a run container.
+func (d *diskBitmap) isRun(i int) bool {
+	return d.runs != nil && d.runs[i/8]&(1<<(i%8)) != 0
+}
+
+// container reads container i.
+func (d *diskBitmap) container(i int) (diskContainer, error) {
+	start, end := d.offsets[i], d.loc.size
+	if i+1 < len(d.offsets) {
+		end = d.offsets[i+1]
+	}
+	if start > end || end > d.loc.size {
+		return diskContainer{}, ErrInvalidSize
+	}
+	c := diskContainer{data: make([]byte, end-start)}
+	if err := readFullAt(d.r, c.data, d.loc.offset+int64(start)); err != nil {
+		return diskContainer{}, err
+	}
+
+	card := int(d.cards[i]) + 1
+	switch {
+	case d.isRun(i):
+		c.kind = containerRun
+		if len(c.data) < 2 || len(c.data) != 2+4*int(binary.LittleEndian.Uint16(c.data)) {
+			return diskContainer{}, ErrInvalidSize
+		}
+	case card > arrayMaxSize:
+		c.kind = containerBitmap
+		if len(c.data) != bitmapContainer {
+			return diskContainer{}, ErrInvalidSize
+		}
+	default:
+		c.kind = containerArray
+		if len(c.data) != 2*card {
+			return diskContainer{}, ErrInvalidSize
+		}
+	}
+	return c, nil
+}
+
+const (
+	containerArray = iota
+	containerBitmap
+	containerRun
+)
+
+// diskContainer is one serialized roaring container.
+type diskContainer struct {
+	kind int
+	data []byte
+}
+
+// contains reports whether the container holds the low 16 bits of a docID.
+func (c diskContainer) contains(low uint16) bool {
+	switch c.kind {
+	case containerBitmap:
+		return c.data[low/8]&(1<<(low%8)) != 0
+	case containerRun:
+		// Runs are (start, length-1) pairs after the run count
+		runs := c.data[2:]
+		j := sort.Search(len(runs)/4, func(j int) bool {
+			return binary.LittleEndian.Uint16(runs[4*j:]) > low
+		}) - 1
+		if j < 0 {
+			return false
+		}
+		start := binary.LittleEndian.Uint16(runs[4*j:])
+		return low-start <= binary.LittleEndian.Uint16(runs[4*j+2:])
+	default:
+		n := len(c.data) / 2
+		j := sort.Search(n, func(j int) bool {
+			return binary.LittleEndian.Uint16(c.data[2*j:]) >= low
+		})
+		return j < n && binary.LittleEndian.Uint16(c.data[2*j:]) == low
+	}
+}
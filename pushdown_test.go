@@ -0,0 +1,163 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestDiskBitmapAnd(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	cand := roaring.New()
+	for range 3000 {
+		cand.Add(rng.Uint32() % (8 << 16))
+	}
+	cand.AddRange(5<<16-10, 5<<16+10)
+	cand.Add(0xFFFFFFFF)
+
+	sparse := roaring.New()
+	for range 500 {
+		sparse.Add(rng.Uint32() % (8 << 16))
+	}
+	dense := roaring.New()
+	for range 100000 {
+		dense.Add(rng.Uint32() % (8 << 16))
+	}
+	runs := roaring.New()
+	runs.AddRange(100, 70000)
+	runs.AddRange(5<<16-5, 5<<16+5)
+	runs.Add(0xFFFFFFFF)
+	mixed := dense.Clone()
+	mixed.Or(runs)
+	mixed.Or(sparse)
+	fewRuns := roaring.New()
+	fewRuns.AddRange(0, 1000)
+	fewRuns.AddRange(1<<16, 1<<16+3)
+
+	for name, bm := range map[string]*roaring.Bitmap{
+		"sparse": sparse, "dense": dense, "runs": runs, "mixed": mixed, "few runs": fewRuns, "empty": roaring.New(),
+	} {
+		bm.RunOptimize()
+		var buf bytes.Buffer
+		bm.WriteTo(&buf)
+		// Place the bitmap behind a prefix, as in an index file
+		data := append(make([]byte, 13), buf.Bytes()...)
+		d, err := readDiskBitmap(bytes.NewReader(data), ngramLocation{offset: 13, size: uint32(buf.Len())})
+		if err != nil {
+			t.Fatalf("%s: readDiskBitmap: %v", name, err)
+		}
+		got, err := d.and(cand)
+		if err != nil {
+			t.Fatalf("%s: and: %v", name, err)
+		}
+		if want := roaring.And(cand, bm); !got.Equals(want) {
+			t.Errorf("%s: and returned %d docs, want %d", name, got.GetCardinality(), want.GetCardinality())
+		}
+	}
+
+	if _, err := readDiskBitmap(bytes.NewReader(make([]byte, 16)), ngramLocation{size: 16}); err == nil {
+		t.Error("readDiskBitmap accepted a bad cookie")
+	}
+}
+
+func TestSearchAndFilterPushdown(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 200000; i++ {
+		text := "other"
+		if i%2 == 0 {
+			text = "common words"
+		}
+		if i%3 == 0 {
+			text += fmt.Sprintf(" tag%d", i%10)
+		}
+		idx.Add(i, text)
+	}
+	path := filepath.Join(t.TempDir(), "pushdown.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	plain, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer plain.Close()
+	pushed, err := OpenCachedIndex(path, WithFilterPushdown(0))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer pushed.Close()
+
+	tenant := roaring.New()
+	for i := uint32(0); i < 200000; i += 97 {
+		tenant.Add(i)
+	}
+	for _, q := range []string{"common", "common words", "tag3", "tag9 common", "absent"} {
+		want := plain.SearchAndFilter(q, tenant)
+		if got := pushed.SearchAndFilter(q, tenant); !reflect.DeepEqual(got, want) {
+			t.Errorf("pushdown SearchAndFilter(%q) = %d docs, want %d", q, len(got), len(want))
+		}
+	}
+	if pushed.MemoryUsage() >= plain.MemoryUsage()/4 {
+		t.Errorf("pushdown cached %d bytes of bitmaps, without it %d", pushed.MemoryUsage(), plain.MemoryUsage())
+	}
+
+	// Resident bitmaps are used as they are, large filters load bitmaps
+	pushed.Search("common")
+	resident := pushed.CacheSize()
+	if got, want := pushed.SearchAndFilter("common", tenant), plain.SearchAndFilter("common", tenant); !reflect.DeepEqual(got, want) {
+		t.Errorf("pushdown with cached bitmaps = %d docs, want %d", len(got), len(want))
+	}
+	all := roaring.New()
+	all.AddRange(0, 200000)
+	if got := pushed.SearchAndFilter("tag3", all); len(got) != 6667 {
+		t.Errorf("large filter matched %d docs, want 6667", len(got))
+	}
+	if pushed.CacheSize() <= resident {
+		t.Error("a filter over the pushdown limit did not load bitmaps")
+	}
+}
+
+// flakyReader fails the next fails reads, then serves r.
+type flakyReader struct {
+	r     *bytes.Reader
+	fails atomic.Int32
+}
+
+func (f *flakyReader) ReadAt(p []byte, off int64) (int, error) {
+	if f.fails.Add(-1) >= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestSearchAndFilterPushdownReadError(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 200000; i += 2 {
+		idx.Add(i, "common words")
+	}
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	origin := &flakyReader{r: bytes.NewReader(buf.Bytes())}
+	cached, err := OpenCachedIndexReader(origin, WithFilterPushdown(0))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+
+	tenant := roaring.BitmapOf(2, 3, 150000)
+	origin.fails.Store(1)
+	// A failed container read falls back to loading the bitmaps whole
+	if got := cached.SearchAndFilter("common", tenant); !reflect.DeepEqual(got, []uint32{2, 150000}) {
+		t.Errorf("SearchAndFilter after a read error = %v, want [2 150000]", got)
+	}
+}
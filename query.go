@@ -0,0 +1,399 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// QueryOp is the kind of a Query node.
+type QueryOp uint8
+
+const (
+	QueryTerm QueryOp = iota // documents containing all n-grams of Text
+	QueryAnd                 // documents matching every clause
+	QueryOr                  // documents matching any clause
+	QueryNot                 // documents not matching Clauses[0]
+	QueryAll                 // every document
+)
+
+// Query is a node of a boolean query tree. Build one with ParseQuery or by
+// hand, and run it with Index.SearchQuery.
+type Query struct {
+	Op      QueryOp
+	Text    string   // QueryTerm only
+	Phrase  bool     // QueryTerm written in quotes
+	Clauses []*Query // operands of QueryAnd, QueryOr and QueryNot
+}
+
+// ParseQuery parses a boolean query such as `"foo bar" AND baz OR -qux`.
+//
+// Words and quoted phrases are terms. Adjacent terms are ANDed; AND binds
+// tighter than OR, and parentheses group. A leading '-' or NOT negates the
+// next term or group. Operators are recognized only in upper case, so "and"
+// and "or" are ordinary words. An empty query matches every document.
+// Syntax errors wrap ErrQuerySyntax.
+func ParseQuery(query string) (*Query, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return &Query{Op: QueryAll}, nil
+	}
+
+	p := queryParser{tokens: tokens}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %s", p.tokens[p.pos])
+	}
+	return q, nil
+}
+
+// String renders the query in the syntax accepted by ParseQuery.
+func (q *Query) String() string {
+	var b strings.Builder
+	q.writeTo(&b)
+	return b.String()
+}
+
+func (q *Query) writeTo(b *strings.Builder) {
+	switch q.Op {
+	case QueryTerm:
+		if q.Phrase || q.Text == "" || strings.HasPrefix(q.Text, "-") ||
+			strings.ContainsAny(q.Text, " \t\n\r()") || isQueryOperator(q.Text) {
+			b.WriteByte('"')
+			b.WriteString(q.Text)
+			b.WriteByte('"')
+		} else {
+			b.WriteString(q.Text)
+		}
+	case QueryAnd, QueryOr:
+		sep := " AND "
+		if q.Op == QueryOr {
+			sep = " OR "
+		}
+		for i, c := range q.Clauses {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			// Only an OR inside an AND needs grouping
+			group := q.Op == QueryAnd && c.Op == QueryOr
+			if group {
+				b.WriteByte('(')
+			}
+			c.writeTo(b)
+			if group {
+				b.WriteByte(')')
+			}
+		}
+	case QueryNot:
+		b.WriteByte('-')
+		c := q.Clauses[0]
+		group := c.Op == QueryAnd || c.Op == QueryOr
+		if group {
+			b.WriteByte('(')
+		}
+		c.writeTo(b)
+		if group {
+			b.WriteByte(')')
+		}
+	}
+}
+
+// SearchQuery returns the documents matching the query tree.
+func (idx *Index) SearchQuery(q *Query) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchQuery", q.String(), len(matches), start) }(time.Now())
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	result := q.evalLocked(idx)
+	if result.IsEmpty() {
+		return nil
+	}
+	return result.ToArray()
+}
+
+// SearchQueryBitmap is like SearchQuery but returns the matches as a bitmap.
+func (idx *Index) SearchQueryBitmap(q *Query) (matches *roaring.Bitmap) {
+	if idx.stats != nil {
+		defer func(start time.Time) {
+			idx.recordQuery("SearchQueryBitmap", q.String(), int(matches.GetCardinality()), start)
+		}(time.Now())
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return q.evalLocked(idx)
+}
+
+// evalLocked returns a new bitmap of the documents in idx matching q.
+func (q *Query) evalLocked(idx *Index) *roaring.Bitmap {
+	switch q.Op {
+	case QueryTerm:
+		return idx.termBitmapLocked(q.Text)
+	case QueryAll:
+		return idx.docs.Clone()
+	case QueryNot:
+		return roaring.AndNot(idx.docs, q.Clauses[0].evalLocked(idx))
+	case QueryOr:
+		result := roaring.New()
+		for _, c := range q.Clauses {
+			result.Or(c.evalLocked(idx))
+		}
+		return result
+	case QueryAnd:
+		return q.evalAndLocked(idx)
+	}
+	return roaring.New()
+}
+
+// evalAndLocked intersects the positive clauses and subtracts the negated
+// ones, so "foo -bar" never materializes the complement of bar.
+func (q *Query) evalAndLocked(idx *Index) *roaring.Bitmap {
+	var result *roaring.Bitmap
+	for _, c := range q.Clauses {
+		if c.Op == QueryNot {
+			continue
+		}
+		bm := c.evalLocked(idx)
+		if result == nil {
+			result = bm
+		} else {
+			result.And(bm)
+		}
+		if result.IsEmpty() {
+			return result
+		}
+	}
+	if result == nil {
+		result = idx.docs.Clone()
+	}
+	for _, c := range q.Clauses {
+		if c.Op == QueryNot {
+			result.AndNot(c.Clauses[0].evalLocked(idx))
+		}
+	}
+	return result
+}
+
+// termBitmapLocked returns the documents containing all n-grams of text.
+// Text too short to form an n-gram matches nothing.
+func (idx *Index) termBitmapLocked(text string) *roaring.Bitmap {
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], text)
+	if len(keys) == 0 {
+		return roaring.New()
+	}
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectLocked(idx, keys) {
+		return roaring.New()
+	}
+	if len(scratch.bitmaps) == 1 {
+		return scratch.bitmaps[0].Clone()
+	}
+	return roaring.FastAnd(scratch.bitmaps...)
+}
+
+// queryTokenKind is the kind of a lexed query token.
+type queryTokenKind uint8
+
+const (
+	tokenWord queryTokenKind = iota
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int // byte offset in the query
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokenPhrase:
+		return fmt.Sprintf("phrase %q at %d", t.text, t.pos)
+	case tokenWord:
+		return fmt.Sprintf("word %q at %d", t.text, t.pos)
+	}
+	return fmt.Sprintf("%q at %d", t.text, t.pos)
+}
+
+func isQueryOperator(s string) bool {
+	return s == "AND" || s == "OR" || s == "NOT"
+}
+
+// lexQuery splits a query into tokens.
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose, text: ")", pos: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quote at %d", ErrQuerySyntax, i)
+			}
+			tokens = append(tokens, queryToken{kind: tokenPhrase, text: query[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c == '-':
+			tokens = append(tokens, queryToken{kind: tokenNot, text: "-", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(" \t\n\r()\"", rune(query[i])) {
+				i++
+			}
+			word := query[start:i]
+			kind := tokenWord
+			switch word {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: word, pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser is a recursive descent parser over lexed tokens:
+//
+//	or    = and { "OR" and }
+//	and   = unary { ["AND"] unary }
+//	unary = ("-" | "NOT") unary | "(" or ")" | word | phrase
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrQuerySyntax, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr() (*Query, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	clauses := []*Query{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			break
+		}
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, next)
+	}
+	return combineQuery(QueryOr, clauses), nil
+}
+
+func (p *queryParser) parseAnd() (*Query, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	clauses := []*Query{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenClose {
+			break
+		}
+		if tok.kind == tokenAnd {
+			p.pos++
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, next)
+	}
+	return combineQuery(QueryAnd, clauses), nil
+}
+
+func (p *queryParser) parseUnary() (*Query, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("unexpected end of query")
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokenNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.Op == QueryNot {
+			return operand.Clauses[0], nil
+		}
+		return &Query{Op: QueryNot, Clauses: []*Query{operand}}, nil
+	case tokenOpen:
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenClose {
+			return nil, p.errorf("missing ) for ( at %d", tok.pos)
+		}
+		p.pos++
+		return q, nil
+	case tokenWord:
+		return &Query{Op: QueryTerm, Text: tok.text}, nil
+	case tokenPhrase:
+		return &Query{Op: QueryTerm, Text: tok.text, Phrase: true}, nil
+	}
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+// combineQuery joins clauses under op, flattening nested nodes of the same op.
+func combineQuery(op QueryOp, clauses []*Query) *Query {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	flat := make([]*Query, 0, len(clauses))
+	for _, c := range clauses {
+		if c.Op == op {
+			flat = append(flat, c.Clauses...)
+		} else {
+			flat = append(flat, c)
+		}
+	}
+	return &Query{Op: op, Clauses: flat}
+}
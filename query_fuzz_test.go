@@ -0,0 +1,34 @@
+package roaringsearch
+
+import (
+	"testing"
+)
+
+// FuzzParseQuery tests that parsed queries render back to an equivalent query
+func FuzzParseQuery(f *testing.F) {
+	f.Add(`"foo bar" AND baz OR -qux`)
+	f.Add(`(a OR b) NOT c`)
+	f.Add(`--"x" AND (y`)
+	f.Add(`e-mail "AND"`)
+	f.Add("")
+
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, testHelloWorld)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		q, err := ParseQuery(input)
+		if err != nil {
+			return
+		}
+		rendered := q.String()
+		again, err := ParseQuery(rendered)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) rendered %q, which fails to parse: %v", input, rendered, err)
+		}
+		if again.String() != rendered {
+			t.Fatalf("ParseQuery(%q) renders %q, then %q", input, rendered, again.String())
+		}
+		_ = idx.SearchQuery(q)
+	})
+}
@@ -0,0 +1,97 @@
+package roaringsearch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`"foo bar" AND baz OR -qux`, `"foo bar" AND baz OR -qux`},
+		{`foo bar`, `foo AND bar`},
+		{`foo AND bar AND baz`, `foo AND bar AND baz`},
+		{`foo OR bar baz`, `foo OR bar AND baz`},
+		{`(foo OR bar) baz`, `(foo OR bar) AND baz`},
+		{`NOT (foo bar)`, `-(foo AND bar)`},
+		{`--foo`, `foo`},
+		{`e-mail and or`, `e-mail AND and AND or`},
+		{`"AND"`, `"AND"`},
+		{``, ``},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error: %v", tt.query, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("ParseQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	q, _ := ParseQuery(`"foo bar" baz`)
+	want := &Query{Op: QueryAnd, Clauses: []*Query{
+		{Op: QueryTerm, Text: "foo bar", Phrase: true},
+		{Op: QueryTerm, Text: "baz"},
+	}}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseQuery tree = %+v, want %+v", q, want)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		`"foo`,
+		`foo AND`,
+		`OR foo`,
+		`(foo bar`,
+		`foo)`,
+		`-`,
+		`()`,
+	} {
+		if _, err := ParseQuery(query); !errors.Is(err, ErrQuerySyntax) {
+			t.Errorf("ParseQuery(%q) error = %v, want ErrQuerySyntax", query, err)
+		}
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, "the lazy brown dog")
+	idx.Add(3, testHelloWorld)
+	idx.Add(4, "a quick hello")
+
+	tests := []struct {
+		query string
+		want  []uint32
+	}{
+		{`brown`, []uint32{1, 2}},
+		{`"brown fox"`, []uint32{1}},
+		{`"brown dog" OR hello`, []uint32{2, 3, 4}},
+		{`quick -hello`, []uint32{1}},
+		{`-brown`, []uint32{3, 4}},
+		{`brown AND (fox OR lazy)`, []uint32{1, 2}},
+		{`"brown fox" AND lazy OR -quick`, []uint32{1, 2, 3}},
+		{`quick -(hello OR fox)`, nil},
+		{`missing`, nil},
+		{`ab`, nil},
+		{``, []uint32{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) error: %v", tt.query, err)
+		}
+		if got := idx.SearchQuery(q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchQuery(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+		if got := idx.SearchQueryBitmap(q).GetCardinality(); got != uint64(len(tt.want)) {
+			t.Errorf("SearchQueryBitmap(%s) cardinality = %d, want %d", tt.query, got, len(tt.want))
+		}
+	}
+}
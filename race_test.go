@@ -0,0 +1,7 @@
+//go:build race
+
+package roaringsearch
+
+// The race detector makes sync.Pool drop items at random, so pooled scratch
+// is reallocated and allocation counts are not meaningful.
+func init() { raceEnabled = true }
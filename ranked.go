@@ -0,0 +1,118 @@
+package roaringsearch
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// RankedResult is a document ranked by RankedSearch.
+type RankedResult[T PagedValue] struct {
+	DocID     uint32
+	Score     float64 // blended score in [0, 1]
+	TextScore float64 // fraction of the query's n-grams in the document
+	Value     T       // the document's column value
+}
+
+// RankedSearch returns the top k documents by a blend of text relevance and a
+// numeric column such as popularity or recency:
+//
+//	score = alpha*textScore + (1-alpha)*valueScore
+//
+// textScore is the fraction of the query's n-grams the document contains, and
+// valueScore is the column value scaled to [0, 1] between the smallest and
+// largest value among the candidates (0 when they are all equal). Candidates
+// are the documents containing at least one n-gram of the query, as in
+// SearchAny. alpha is clamped to [0, 1]; k <= 0 returns every candidate.
+// Results are ordered by score, best first. Documents without a column value
+// rank as if their value were zero.
+//
+// Example:
+//
+//	top := RankedSearch(idx, "brown fox", popularity, 0.7, 10)
+func RankedSearch[T PagedValue](idx *Index, query string, col *SortColumn[T], alpha float64, k int) (results []RankedResult[T]) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("RankedSearch", query, len(results), start) }(time.Now())
+	}
+	alpha = max(0, min(alpha, 1))
+
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	levels, _ := idx.thresholdLevelsLocked(keys, 1)
+	if levels == nil {
+		return nil
+	}
+	candidates := levels[0]
+	if k <= 0 || uint64(k) > candidates.GetCardinality() {
+		k = int(candidates.GetCardinality())
+	}
+
+	col.mu.RLock()
+	defer col.mu.RUnlock()
+
+	lo, hi := valueRangeLocked(col, candidates)
+	valueScore := func(v T) float64 {
+		if hi == lo {
+			return 0
+		}
+		return (float64(v) - lo) / (hi - lo)
+	}
+
+	total := float64(len(keys))
+	h := &resultHeap[float64]{items: make([]SortedResult[float64], 0, k)}
+	walkThreshold(levels, 1, func(docID uint32, matched int) bool {
+		score := alpha*float64(matched)/total + (1-alpha)*valueScore(col.valueLocked(docID))
+		heapInsert(h, docID, score, false, k)
+		return true
+	})
+	if h.Len() < k && h.Len() > 0 {
+		heap.Init(h)
+	}
+
+	top := heapToSortedResults(h)
+	results = make([]RankedResult[T], len(top))
+	for i, r := range top {
+		results[i] = RankedResult[T]{
+			DocID:     r.DocID,
+			Score:     r.Value,
+			TextScore: float64(matchedLevel(levels, r.DocID)) / total,
+			Value:     col.valueLocked(r.DocID),
+		}
+	}
+	return results
+}
+
+// valueRangeLocked returns the smallest and largest column values of the
+// documents in bm.
+func valueRangeLocked[T PagedValue](col *SortColumn[T], bm *roaring.Bitmap) (lo, hi float64) {
+	first := true
+	it := bm.Iterator()
+	for it.HasNext() {
+		v := float64(col.valueLocked(it.Next()))
+		if first {
+			lo, hi, first = v, v, false
+			continue
+		}
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return lo, hi
+}
+
+// matchedLevel returns how many of the input bitmaps of matchLevels output
+// contain docID.
+func matchedLevel(levels []*roaring.Bitmap, docID uint32) int {
+	for c := len(levels) - 1; c >= 0; c-- {
+		if levels[c].Contains(docID) {
+			return c + 1
+		}
+	}
+	return 0
+}
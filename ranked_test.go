@@ -0,0 +1,81 @@
+package roaringsearch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRankedSearch(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, "brown fox")
+	idx.Add(3, "a brown bear")
+	idx.Add(4, testHelloWorld)
+
+	popularity := NewSortColumn[uint32]()
+	popularity.Set(1, 10)
+	popularity.Set(2, 50)
+	popularity.Set(3, 1000)
+	popularity.Set(4, 5000)
+
+	ids := func(results []RankedResult[uint32]) []uint32 {
+		out := make([]uint32, len(results))
+		for i, r := range results {
+			out[i] = r.DocID
+		}
+		return out
+	}
+
+	// Pure text relevance: both full matches beat the partial one
+	results := RankedSearch(idx, "brown fox", popularity, 1, 0)
+	if len(results) != 3 || results[2].DocID != 3 {
+		t.Fatalf("alpha=1 results = %v, want doc 3 last of 3", ids(results))
+	}
+	if results[0].TextScore != 1 || results[1].TextScore != 1 {
+		t.Errorf("full matches TextScore = %v, %v, want 1", results[0].TextScore, results[1].TextScore)
+	}
+
+	// Pure popularity among the candidates; doc 4 is not a candidate
+	results = RankedSearch(idx, "brown fox", popularity, 0, 0)
+	if got := ids(results); len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("alpha=0 results = %v, want [3 2 1]", got)
+	}
+
+	// Blended: doc 2 is a full match and more popular than doc 1
+	results = RankedSearch(idx, "brown fox", popularity, 0.7, 2)
+	if got := ids(results); len(got) != 2 || got[0] != 2 {
+		t.Fatalf("alpha=0.7 top 2 = %v, want doc 2 first", got)
+	}
+	r := results[0]
+	wantScore := 0.7*1 + 0.3*(50.0-10)/(1000-10)
+	if math.Abs(r.Score-wantScore) > 1e-9 || r.Value != 50 {
+		t.Errorf("doc 2 = %+v, want score %v and value 50", r, wantScore)
+	}
+
+	// Out-of-range alpha is clamped
+	if got := ids(RankedSearch(idx, "brown fox", popularity, 5, 1)); len(got) != 1 || got[0] == 3 {
+		t.Errorf("alpha=5 top 1 = %v, want a full match", got)
+	}
+
+	if got := RankedSearch(idx, "zebra", popularity, 0.5, 10); got != nil {
+		t.Errorf("RankedSearch(zebra) = %v, want nil", got)
+	}
+	if got := RankedSearch(idx, "ab", popularity, 0.5, 10); got != nil {
+		t.Errorf("RankedSearch of a short query = %v, want nil", got)
+	}
+}
+
+func TestRankedSearchEqualValues(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, "brown")
+	idx.Add(2, "brown fox")
+
+	col := NewSortColumn[float64]()
+	results := RankedSearch(idx, "brown fox", col, 0.5, 0)
+	if len(results) != 2 || results[0].DocID != 2 {
+		t.Fatalf("results = %+v, want doc 2 first", results)
+	}
+	if results[0].Score != 0.5 {
+		t.Errorf("Score with equal values = %v, want 0.5", results[0].Score)
+	}
+}
@@ -0,0 +1,189 @@
+package roaringsearch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// readerRef is a reader owned by a CachedIndex. Reads that run without the
+// index lock hold a reference, so Refresh can close the reader it replaces
+// once they finish.
+type readerRef struct {
+	BlockReader
+	users sync.WaitGroup
+}
+
+// close waits for the reads holding a reference, then closes the reader if
+// it implements io.Closer.
+func (r *readerRef) close() error {
+	r.users.Wait()
+	if c, ok := r.BlockReader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// sameReader reports whether a and b are the same reader.
+func sameReader(a, b BlockReader) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t != nil && t.Comparable() && a == b
+}
+
+// fileView is the n-gram locations, container directory and reader of one
+// version of the file of a CachedIndex, read under one lock so a concurrent
+// Refresh cannot pair a location in one version with the reader of another.
+// It holds a reference on the reader until released.
+type fileView struct {
+	reader     *readerRef
+	ngramIndex map[uint64]ngramLocation
+	containers containerDirectory
+}
+
+// view returns the current fileView. Refresh replaces the maps of a view
+// rather than modifying them, so they can be read without the lock.
+func (idx *CachedIndex) view() fileView {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.reader.users.Add(1)
+	return fileView{reader: idx.reader, ngramIndex: idx.ngramIndex, containers: idx.containers}
+}
+
+// release drops the view's reference on its reader.
+func (v fileView) release() {
+	v.reader.users.Done()
+}
+
+// location returns where the bitmap of key is in the view's file.
+func (v fileView) location(key uint64) (ngramLocation, bool) {
+	loc, ok := v.ngramIndex[key]
+	return loc, ok
+}
+
+// keyLocation is an n-gram key with its location in the file and its
+// generation when the location was read.
+type keyLocation struct {
+	key uint64
+	loc ngramLocation
+	gen uint64
+}
+
+// location returns where the bitmap of key is in the file.
+func (idx *CachedIndex) location(key uint64) (ngramLocation, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	loc, ok := idx.ngramIndex[key]
+	return loc, ok
+}
+
+// Refresh switches the index to a new version of its file, such as one
+// rewritten after applying a delta, and invalidates only the cache entries of
+// the n-grams that changed, so the rest of the warm cache survives the
+// reload. changed lists the keys whose bitmaps differ between the versions,
+// including keys added or removed; for two Index versions, IndexDiff.Keys
+// returns them. Changed bitmaps that are cached or pinned are re-read from
+// the new file and replaced in place, keeping their recency; any other
+// changed entry is dropped.
+//
+// Each changed key's generation is bumped, so a preload that read the old
+// bitmap concurrently does not cache it, and cached query results are
+// dropped. The new file must have the same gram size, key mode and
+// normalizer.
+//
+// On success the index takes over r, as OpenCachedIndexReader does: Close
+// closes it. The reader it replaces, including the file opened by
+// OpenCachedIndex, is closed by Refresh once the searches reading it finish,
+// and an error closing it is returned. On failure r is left to the caller.
+//
+// Example:
+//
+//	diff := rs.CompareIndexes(oldIdx, newIdx)
+//	newIdx.SaveToFile("index.v2.sear")
+//	f, _ := os.Open("index.v2.sear")
+//	err := cached.Refresh(f, diff.Keys()) // f is closed with cached
+func (idx *CachedIndex) Refresh(r BlockReader, changed []uint64) error {
+	next := &CachedIndex{reader: &readerRef{BlockReader: r}, normalizer: idx.normalizer, chain: idx.chain}
+	if err := next.loadIndex(); err != nil {
+		return err
+	}
+	switch {
+	case next.gramSize != idx.gramSize:
+		return fmt.Errorf("refresh: %w: %d != %d", ErrGramSizeMismatch, next.gramSize, idx.gramSize)
+	case (next.exact == nil) != (idx.exact == nil) || next.cjkBigrams != idx.cjkBigrams:
+		return fmt.Errorf("refresh: %w", ErrKeyModeMismatch)
+	case normalizerID(next.normalizer, next.chain) != normalizerID(idx.normalizer, idx.chain):
+		return fmt.Errorf("refresh: %w", ErrNormalizerMismatch)
+	}
+
+	// Re-read the changed bitmaps in use before taking the write lock, so
+	// searches keep running meanwhile
+	idx.mu.RLock()
+	var reread []uint64
+	for _, key := range changed {
+		_, cached := idx.lru.entries[key]
+		_, pinned := idx.pinned[key]
+		if cached || pinned {
+			reread = append(reread, key)
+		}
+	}
+	idx.mu.RUnlock()
+
+	fresh := make(map[uint64]*roaring.Bitmap, len(reread))
+	var errs []error
+	for _, key := range reread {
+		loc, ok := next.ngramIndex[key]
+		if !ok {
+			continue
+		}
+		bm, err := loadBitmapAt(r, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load key: %d: %w", key, err))
+			continue
+		}
+		fresh[key] = bm
+	}
+
+	idx.mu.Lock()
+	prev := idx.reader
+	idx.exact.merge(next.exact)
+	idx.reader = next.reader
+	idx.ngramIndex = next.ngramIndex
+	idx.containers = next.containers
+	idx.metadata = next.metadata
+	if len(changed) > 0 {
+		idx.generation++
+	}
+
+	if idx.gens == nil && len(changed) > 0 {
+		idx.gens = make(map[uint64]uint64, len(changed))
+	}
+	for _, key := range changed {
+		idx.gens[key]++
+		bm, ok := fresh[key]
+		if old, pinned := idx.pinned[key]; pinned {
+			idx.pinnedMemory -= old.GetSizeInBytes()
+			if ok {
+				idx.pinned[key] = bm
+				idx.pinnedMemory += bm.GetSizeInBytes()
+			} else {
+				delete(idx.pinned, key)
+			}
+			continue
+		}
+		if !ok || !idx.lru.replace(key, bm) {
+			idx.lru.remove(key)
+		}
+	}
+	idx.mu.Unlock()
+
+	if !sameReader(prev.BlockReader, r) {
+		if err := prev.close(); err != nil {
+			errs = append(errs, fmt.Errorf("refresh: close replaced reader: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCachedIndexRefresh(t *testing.T) {
+	dir := t.TempDir()
+	v1 := NewIndex(3)
+	v1.Add(1, testHelloWorld)
+	v1.Add(2, testHelloThere)
+	v1.Add(3, testGoodbyeWorld)
+	path1 := filepath.Join(dir, "v1.sear")
+	if err := v1.SaveToFile(path1); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	cached, err := OpenCachedIndex(path1, WithCacheSize(100))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	cached.Search("hello")
+	cached.Search("world")
+	if err := cached.Pin([]string{"the"}); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	worKey, helKey := NgramKey("wor"), NgramKey("hel")
+	worBitmap := cached.lru.entries[worKey].value
+	cachedBefore := cached.CacheSize()
+
+	v2 := v1.Snapshot()
+	v2.Add(4, "hello again")
+	v2.Add(5, "over there")
+	path2 := filepath.Join(dir, "v2.sear")
+	if err := v2.SaveToFile(path2); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	f, err := os.Open(path2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The index takes over f and closes it with the index
+	changed := CompareIndexes(v1, v2).Keys()
+	if err := cached.Refresh(f, changed); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// Unchanged entries survive, changed ones are replaced in place
+	if got := cached.lru.entries[worKey]; got == nil || got.value != worBitmap {
+		t.Error("unchanged cache entry should survive Refresh")
+	}
+	if got := cached.lru.entries[helKey]; got == nil || got.value.GetCardinality() != 3 {
+		t.Error("changed cache entry should be refreshed in place")
+	}
+	if cached.CacheSize() != cachedBefore {
+		t.Errorf("cache size = %d after Refresh, want %d", cached.CacheSize(), cachedBefore)
+	}
+	if got := cached.pinned[NgramKey("the")]; got == nil || got.GetCardinality() != 2 {
+		t.Error("changed pinned bitmap should be refreshed")
+	}
+	if cached.gens[helKey] != 1 || cached.gens[worKey] != 0 {
+		t.Errorf("generations = %v, want only changed keys bumped", cached.gens)
+	}
+
+	if got := cached.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2, 4}) {
+		t.Errorf("Search(hello) = %v, want [1 2 4]", got)
+	}
+	if got := cached.Search("again"); !reflect.DeepEqual(got, []uint32{4}) {
+		t.Errorf("Search(again) = %v, want [4]", got)
+	}
+	if cached.NgramCount() != v2.NgramCount() {
+		t.Errorf("NgramCount = %d, want %d", cached.NgramCount(), v2.NgramCount())
+	}
+
+	other := NewIndex(2)
+	other.Add(1, testHelloWorld)
+	path3 := filepath.Join(dir, "bigram.sear")
+	if err := other.SaveToFile(path3); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	f3, err := os.Open(path3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f3.Close()
+	if err := cached.Refresh(f3, nil); !errors.Is(err, ErrGramSizeMismatch) {
+		t.Errorf("Refresh with another gram size = %v, want ErrGramSizeMismatch", err)
+	}
+}
+
+func TestRefreshClosesReplacedReader(t *testing.T) {
+	v1 := NewIndex(3)
+	v1.Add(1, testHelloWorld)
+	v2 := v1.Snapshot()
+	v2.Add(2, testHelloThere)
+	var buf1, buf2 bytes.Buffer
+	if _, err := v1.WriteTo(&buf1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v2.WriteTo(&buf2); err != nil {
+		t.Fatal(err)
+	}
+
+	origin1 := &countingReader{r: bytes.NewReader(buf1.Bytes())}
+	cached, err := OpenCachedIndexReader(origin1)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+
+	// A search reading the old file delays closing it
+	v := cached.view()
+	origin2 := &countingReader{r: bytes.NewReader(buf2.Bytes())}
+	done := make(chan error, 1)
+	go func() { done <- cached.Refresh(origin2, CompareIndexes(v1, v2).Keys()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Refresh returned %v while the old reader was in use", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	v.release()
+	if err := <-done; err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if !origin1.closed {
+		t.Error("replaced reader should be closed")
+	}
+	if got := cached.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search(hello) = %v, want [1 2]", got)
+	}
+
+	// Refreshing from the current reader keeps it open
+	if err := cached.Refresh(origin2, nil); err != nil || origin2.closed {
+		t.Errorf("Refresh from the same reader = %v, closed = %v", err, origin2.closed)
+	}
+	if err := cached.Close(); err != nil || !origin2.closed {
+		t.Errorf("Close = %v, current reader closed = %v", err, origin2.closed)
+	}
+}
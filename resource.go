@@ -0,0 +1,151 @@
+package roaringsearch
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ResourceManager enforces one memory budget across several disk-backed
+// structures: CachedIndexes, CachedBitmapFilters and CachedSortColumns
+// opened with its option. Per-structure budgets do not compose when one
+// service holds many of them; with a shared manager, a load that goes over
+// the budget evicts the least recently used entries across every member,
+// so memory flows to whichever index, filter or column is busy.
+//
+// A member that is in use by another goroutine is skipped during eviction,
+// so the budget can be exceeded briefly until a later load evicts again.
+// Pinned CachedIndex bitmaps are not counted, as with WithMemoryBudget.
+//
+// Example:
+//
+//	rm := rs.NewResourceManager(512 << 20)
+//	products, _ := rs.OpenCachedIndex("products.sear", rs.WithResourceManager(rm))
+//	reviews, _ := rs.OpenCachedIndex("reviews.sear", rs.WithResourceManager(rm))
+//	prices, _ := rs.OpenCachedSortColumn[float32]("prices.col", rs.WithPageResourceManager(rm))
+type ResourceManager struct {
+	budget int64
+	used   atomic.Int64
+	clock  atomic.Uint64 // orders entry uses across members
+
+	mu      sync.Mutex // guards members and serializes eviction
+	members []resourceMember
+}
+
+// resourceMember is a cache sharing a ResourceManager's budget. oldest and
+// evictOldest are called with the member's lock held.
+type resourceMember interface {
+	tryLock() bool
+	unlock()
+	oldest() (tick uint64, ok bool)
+	evictOldest()
+}
+
+// tryLocker is a lock the manager can take without blocking. *sync.Mutex
+// and *sync.RWMutex implement it.
+type tryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// NewResourceManager returns a manager for a budget of the given bytes.
+// A budget <= 0 is treated as 1 byte, so nothing is cached.
+func NewResourceManager(budget int64) *ResourceManager {
+	return &ResourceManager{budget: max(budget, 1)}
+}
+
+// Budget returns the memory budget in bytes.
+func (m *ResourceManager) Budget() int64 {
+	return m.budget
+}
+
+// MemoryUsage returns the memory held by all members' caches in bytes.
+func (m *ResourceManager) MemoryUsage() uint64 {
+	return uint64(max(m.used.Load(), 0))
+}
+
+// Members returns the number of structures sharing the budget.
+func (m *ResourceManager) Members() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.members)
+}
+
+func (m *ResourceManager) register(member resourceMember) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members = append(m.members, member)
+}
+
+func (m *ResourceManager) unregister(member resourceMember) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members = slices.DeleteFunc(m.members, func(r resourceMember) bool { return r == member })
+}
+
+// reclaim evicts least recently used entries across members until usage is
+// within budget. self is the member that just grew, whose lock the caller
+// already holds.
+func (m *ResourceManager) reclaim(self resourceMember) {
+	if m.used.Load() <= m.budget {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.used.Load() > m.budget {
+		var victim resourceMember
+		var oldest uint64
+		for _, member := range m.members {
+			if member != self && !member.tryLock() {
+				continue
+			}
+			tick, ok := member.oldest()
+			if member != self {
+				member.unlock()
+			}
+			if ok && (victim == nil || tick < oldest) {
+				victim, oldest = member, tick
+			}
+		}
+		if victim == nil {
+			return
+		}
+		if victim != self {
+			if !victim.tryLock() {
+				return
+			}
+			victim.evictOldest()
+			victim.unlock()
+			continue
+		}
+		victim.evictOldest()
+	}
+}
+
+// WithResourceManager makes the bitmap cache draw on m's shared budget
+// instead of WithCacheSize or WithMemoryBudget. Close releases the cached
+// bitmaps and leaves the manager.
+func WithResourceManager(m *ResourceManager) CachedIndexOption {
+	return cachedIndexOption(func(idx *CachedIndex) {
+		idx.manager = m
+	})
+}
+
+// WithFilterResourceManager makes the category bitmap cache draw on m's
+// shared budget instead of WithFilterCacheSize or WithFilterMemoryBudget.
+// Close releases the cached bitmaps and leaves the manager.
+func WithFilterResourceManager(m *ResourceManager) CachedFilterOption {
+	return func(c *CachedBitmapFilter) {
+		c.manager = m
+	}
+}
+
+// WithPageResourceManager makes the page cache draw on m's shared budget
+// instead of WithPageCacheSize or WithPageMemoryBudget. Close releases the
+// cached pages and leaves the manager.
+func WithPageResourceManager(m *ResourceManager) CachedColumnOption {
+	return func(cfg *cachedColumnConfig) {
+		cfg.manager = m
+	}
+}
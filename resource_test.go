@@ -0,0 +1,151 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// sharedFixtures saves two indexes, a filter and a sort column to dir.
+func sharedFixtures(t *testing.T, dir string) (indexA, indexB, filter, column string) {
+	t.Helper()
+	indexA, indexB = filepath.Join(dir, "a.sear"), filepath.Join(dir, "b.sear")
+	filter, column = filepath.Join(dir, "f.filter"), filepath.Join(dir, "c.col")
+
+	for _, path := range []string{indexA, indexB} {
+		idx := NewIndex(3)
+		for i := uint32(0); i < 2000; i++ {
+			idx.Add(i, fmt.Sprintf("document %d about %s", i, path))
+		}
+		if err := idx.SaveToFile(path); err != nil {
+			t.Fatalf(errSaveToFile, err)
+		}
+	}
+
+	f := NewBitmapFilter()
+	col := NewSortColumn[uint32]()
+	for i := uint32(0); i < 200000; i++ {
+		f.Set(i, "bucket", fmt.Sprint(i%50))
+		col.Set(i, i)
+	}
+	if err := f.SaveToFile(filter); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+	if err := SavePagedSortColumn(col, column); err != nil {
+		t.Fatalf("SavePagedSortColumn: %v", err)
+	}
+	return indexA, indexB, filter, column
+}
+
+func TestResourceManagerSharedBudget(t *testing.T) {
+	pathA, pathB, filterPath, columnPath := sharedFixtures(t, t.TempDir())
+	const budget = 64 << 10
+	rm := NewResourceManager(budget)
+
+	a, err := OpenCachedIndex(pathA, WithResourceManager(rm))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	b, err := OpenCachedIndex(pathB, WithResourceManager(rm), WithCacheSize(1))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	filter, err := OpenCachedBitmapFilter(filterPath, WithFilterResourceManager(rm))
+	if err != nil {
+		t.Fatalf("OpenCachedBitmapFilter: %v", err)
+	}
+	column, err := OpenCachedSortColumn[uint32](columnPath, WithPageResourceManager(rm))
+	if err != nil {
+		t.Fatalf("OpenCachedSortColumn: %v", err)
+	}
+	if rm.Members() != 4 {
+		t.Fatalf("Members() = %d, want 4", rm.Members())
+	}
+
+	sum := func() uint64 {
+		return a.MemoryUsage() + b.MemoryUsage() + filter.MemoryUsage() + column.MemoryUsage()
+	}
+	for i := range 300 {
+		q := fmt.Sprintf("document %d about", 1000+i)
+		if len(a.Search(q)) == 0 || len(b.Search(q)) == 0 {
+			t.Fatalf("Search(%q) lost results", q)
+		}
+		filter.Get("bucket", fmt.Sprint(i%50))
+		column.Get(uint32(i * 4096))
+		if rm.MemoryUsage() > budget {
+			t.Fatalf("usage %d over budget %d", rm.MemoryUsage(), budget)
+		}
+		if rm.MemoryUsage() != sum() {
+			t.Fatalf("manager usage %d, members hold %d", rm.MemoryUsage(), sum())
+		}
+	}
+	if b.CacheSize() <= 1 {
+		t.Error("WithCacheSize still limits a shared cache")
+	}
+
+	// An idle member gives its memory to a busy one
+	for i := range 2000 {
+		b.Search(fmt.Sprintf("document %d about", i))
+	}
+	if a.MemoryUsage() != 0 || filter.MemoryUsage() != 0 || column.MemoryUsage() != 0 {
+		t.Errorf("idle members kept %d, %d and %d bytes", a.MemoryUsage(), filter.MemoryUsage(), column.MemoryUsage())
+	}
+
+	b.Close()
+	if rm.Members() != 3 || rm.MemoryUsage() != 0 {
+		t.Errorf("after Close: %d members using %d bytes", rm.Members(), rm.MemoryUsage())
+	}
+	a.Close()
+	filter.Close()
+	column.Close()
+	if rm.Members() != 0 || rm.MemoryUsage() != 0 {
+		t.Errorf("after closing all: %d members using %d bytes", rm.Members(), rm.MemoryUsage())
+	}
+}
+
+func TestResourceManagerConcurrent(t *testing.T) {
+	pathA, pathB, filterPath, _ := sharedFixtures(t, t.TempDir())
+	rm := NewResourceManager(32 << 10)
+	a, err := OpenCachedIndex(pathA, WithResourceManager(rm))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer a.Close()
+	b, err := OpenCachedIndex(pathB, WithResourceManager(rm), WithTinyLFU())
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer b.Close()
+	filter, err := OpenCachedBitmapFilter(filterPath, WithFilterResourceManager(rm))
+	if err != nil {
+		t.Fatalf("OpenCachedBitmapFilter: %v", err)
+	}
+	defer filter.Close()
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				q := fmt.Sprintf("document %d about", (w*500+i)%2000)
+				if len(a.Search(q)) == 0 || len(b.Search(q)) == 0 {
+					t.Errorf("Search(%q) found nothing", q)
+					return
+				}
+				filter.Get("bucket", fmt.Sprint(i%50))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := a.MemoryUsage() + b.MemoryUsage() + filter.MemoryUsage(); rm.MemoryUsage() != got {
+		t.Errorf("manager usage %d, members hold %d", rm.MemoryUsage(), got)
+	}
+	// Eviction skips busy members, but the next load settles the budget
+	a.Search("document")
+	if rm.MemoryUsage() > uint64(rm.Budget()) {
+		t.Errorf("usage %d over budget %d", rm.MemoryUsage(), rm.Budget())
+	}
+}
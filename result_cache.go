@@ -0,0 +1,171 @@
+package roaringsearch
+
+import (
+	"encoding/binary"
+	"slices"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// resultCache is an LRU of whole AND query results, keyed by the query's
+// n-gram keys so queries that normalize alike share an entry. Results are
+// tagged with the generation of the index they were computed on, and the
+// first lookup after the index changes drops them all.
+type resultCache struct {
+	mu  sync.Mutex
+	lru bitmapLRU[string]
+	gen uint64 // generation of the cached results
+}
+
+// WithResultCache caches the results of the last n distinct AND queries, so
+// a workload that repeats a handful of queries skips the intersections.
+// Queries are keyed by their n-gram keys, so "Hello!" and "hello" share an
+// entry. Any change to the index (Add, Remove, a batch flush, ReadFrom,
+// Clear; Refresh on a CachedIndex) invalidates every cached result.
+//
+// On an Index it serves Search, SearchAllTerms, SearchBatch and
+// SearchBitmap; on a CachedIndex it serves Search, and the cached results
+// are not counted against WithCacheSize or WithMemoryBudget. n <= 0 is
+// ignored.
+//
+// Example:
+//
+//	idx := rs.NewIndex(3, rs.WithResultCache(1000))
+func WithResultCache(n int) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			if n > 0 {
+				idx.results = idx.results.orNew()
+				idx.results.lru.setMaxEntries(n)
+			}
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			if n > 0 {
+				idx.results = idx.results.orNew()
+				idx.results.lru.setMaxEntries(n)
+			}
+		}),
+	}
+}
+
+// WithResultCacheBytes is like WithResultCache but bounds the cache by the
+// memory of the cached result bitmaps instead of by entry count. bytes <= 0
+// is ignored.
+func WithResultCacheBytes(bytes int64) SharedOption {
+	return SharedOption{
+		index: indexOption(func(idx *Index) {
+			if bytes > 0 {
+				idx.results = idx.results.orNew()
+				idx.results.lru.setMemoryBudget(bytes)
+			}
+		}),
+		cached: cachedIndexOption(func(idx *CachedIndex) {
+			if bytes > 0 {
+				idx.results = idx.results.orNew()
+				idx.results.lru.setMemoryBudget(bytes)
+			}
+		}),
+	}
+}
+
+// orNew returns c, or a new empty cache if c is nil.
+func (c *resultCache) orNew() *resultCache {
+	if c != nil {
+		return c
+	}
+	return &resultCache{lru: newBitmapLRU[string](0)}
+}
+
+// empty returns a new cache with the limits of c, or nil if c is nil.
+func (c *resultCache) empty() *resultCache {
+	if c == nil {
+		return nil
+	}
+	lru := newBitmapLRU[string](c.lru.maxEntries)
+	lru.maxMemory = c.lru.maxMemory
+	return &resultCache{lru: lru}
+}
+
+// get returns the cached result of key computed at generation gen.
+func (c *resultCache) get(key string, gen uint64) (*roaring.Bitmap, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.advanceLocked(gen) {
+		return nil, false
+	}
+	return c.lru.get(key)
+}
+
+// add caches the result of key computed at generation gen. A result older
+// than those cached is dropped.
+func (c *resultCache) add(key string, gen uint64, bm *roaring.Bitmap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.advanceLocked(gen) {
+		return
+	}
+	if !c.lru.replace(key, bm) {
+		c.lru.add(key, bm)
+	}
+}
+
+// advanceLocked drops every cached result if gen is newer than them, and
+// reports whether results of gen can be served.
+func (c *resultCache) advanceLocked(gen uint64) bool {
+	if gen > c.gen {
+		c.lru.clear()
+		c.gen = gen
+	}
+	return gen == c.gen
+}
+
+// len returns the number of cached results.
+func (c *resultCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.len()
+}
+
+// resultKey encodes a set of n-gram keys independently of their order.
+func resultKey(keys []uint64) string {
+	sorted := slices.Clone(keys)
+	slices.Sort(sorted)
+	buf := make([]byte, 0, 8*len(sorted))
+	for _, key := range sorted {
+		buf = binary.LittleEndian.AppendUint64(buf, key)
+	}
+	return string(buf)
+}
+
+// cachedResultLocked returns the AND matches of keys from the result cache,
+// computing and caching them on a miss. The bitmap is shared with the cache;
+// do not modify it.
+func (idx *Index) cachedResultLocked(keys []uint64) *roaring.Bitmap {
+	key := resultKey(keys)
+	if bm, ok := idx.results.get(key, idx.generation); ok {
+		return bm
+	}
+	bm := idx.intersectKeysLocked(keys)
+	idx.results.add(key, idx.generation, bm)
+	return bm
+}
+
+// cachedSearchBitmap is searchKeys through the result cache. Refresh may run
+// while the bitmaps are read, so the result is tagged with the generation
+// from before, and dropped by add if a Refresh has since been observed.
+func (idx *CachedIndex) cachedSearchBitmap(keys []uint64) *roaring.Bitmap {
+	idx.mu.RLock()
+	gen := idx.generation
+	idx.mu.RUnlock()
+
+	key := resultKey(keys)
+	if bm, ok := idx.results.get(key, gen); ok {
+		return bm
+	}
+	bm := idx.searchKeys(keys)
+	if bm != nil {
+		idx.results.add(key, gen, bm)
+	}
+	return bm
+}
@@ -0,0 +1,109 @@
+package roaringsearch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResultCache(t *testing.T) {
+	idx := NewIndex(3, WithResultCache(2))
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testGoodbyeWorld)
+
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Fatalf("Search(hello) = %v, want [1 2]", got)
+	}
+	// Queries that normalize alike share an entry
+	if got := idx.Search("HELLO!"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search(HELLO!) = %v, want [1 2]", got)
+	}
+	if n := idx.results.len(); n != 1 {
+		t.Errorf("cached %d results, want 1", n)
+	}
+
+	// The cached bitmap must not be handed out for modification
+	bm := idx.SearchBitmap("hello")
+	bm.Add(99)
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search after modifying a SearchBitmap result = %v", got)
+	}
+
+	idx.Search("world")
+	idx.Search("there")
+	if n := idx.results.len(); n != 2 {
+		t.Errorf("cached %d results, want the limit of 2", n)
+	}
+
+	// Any mutation invalidates the cached results
+	idx.Add(4, "hello again")
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2, 4}) {
+		t.Errorf("Search(hello) after Add = %v, want [1 2 4]", got)
+	}
+	idx.Remove(1)
+	if got := idx.SearchBatch([]string{"hello", "world"}, 1); !reflect.DeepEqual(got, [][]uint32{{2, 4}, {3}}) {
+		t.Errorf("SearchBatch after Remove = %v", got)
+	}
+	idx.Clear()
+	if got := idx.Search("hello"); got != nil {
+		t.Errorf("Search(hello) after Clear = %v, want nil", got)
+	}
+
+	snap := idx.Snapshot()
+	if snap.results == nil || snap.results == idx.results {
+		t.Error("a snapshot should get its own result cache")
+	}
+}
+
+func TestResultCacheBytes(t *testing.T) {
+	idx := NewIndex(3, WithResultCacheBytes(1))
+	idx.Add(1, testHelloWorld)
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(hello) = %v, want [1]", got)
+	}
+	if n := idx.results.len(); n != 0 {
+		t.Errorf("cached %d results larger than the budget", n)
+	}
+}
+
+func TestCachedIndexResultCache(t *testing.T) {
+	dir := t.TempDir()
+	v1 := NewIndex(3)
+	v1.Add(1, testHelloWorld)
+	v1.Add(2, testGoodbyeWorld)
+	path := filepath.Join(dir, "v1.sear")
+	if err := v1.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+
+	cached, err := OpenCachedIndex(path, WithResultCache(10))
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+	if got := cached.Search("world"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Fatalf("Search(world) = %v, want [1 2]", got)
+	}
+	if n := cached.results.len(); n != 1 {
+		t.Errorf("cached %d results, want 1", n)
+	}
+
+	v2 := v1.Snapshot()
+	v2.Add(3, "brave new world")
+	path2 := filepath.Join(dir, "v2.sear")
+	if err := v2.SaveToFile(path2); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	f, err := os.Open(path2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cached.Refresh(f, CompareIndexes(v1, v2).Keys()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if got := cached.Search("world"); !reflect.DeepEqual(got, []uint32{1, 2, 3}) {
+		t.Errorf("Search(world) after Refresh = %v, want [1 2 3]", got)
+	}
+}
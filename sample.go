@@ -0,0 +1,87 @@
+package roaringsearch
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// SearchSample returns a uniform random sample of n documents containing all
+// n-grams of the query, in ascending docID order. The same seed over the same
+// index gives the same sample. When fewer than n documents match, all of them
+// are returned.
+//
+// The matches are intersected into a bitmap and sampled by rank with Select,
+// so a small sample of a huge result set never materializes the full result.
+func (idx *Index) SearchSample(query string, n int, seed uint64) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchSample", query, len(matches), start) }(time.Now())
+	}
+	if n <= 0 {
+		return nil
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectLocked(idx, keys) {
+		return nil
+	}
+	result := scratch.bitmaps[0]
+	if len(scratch.bitmaps) > 1 {
+		scratch.intersectLocked()
+		result = scratch.result
+	}
+	return sampleBitmap(result, n, seed)
+}
+
+// sampleBitmap returns n uniformly chosen members of bm in ascending order,
+// or all of them when bm has at most n.
+func sampleBitmap(bm *roaring.Bitmap, n int, seed uint64) []uint32 {
+	card := bm.GetCardinality()
+	if card == 0 {
+		return nil
+	}
+	if uint64(n) >= card {
+		return bm.ToArray()
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	ranks := sampleRanks(rng, card, n)
+	slices.Sort(ranks)
+
+	sample := make([]uint32, len(ranks))
+	for i, rank := range ranks {
+		docID, err := bm.Select(uint32(rank))
+		if err != nil {
+			return sample[:i]
+		}
+		sample[i] = docID
+	}
+	return sample
+}
+
+// sampleRanks picks n distinct ranks from [0, card) with Floyd's algorithm,
+// which draws exactly n random numbers whatever the ratio of n to card.
+func sampleRanks(rng *rand.Rand, card uint64, n int) []uint64 {
+	chosen := make(map[uint64]struct{}, n)
+	ranks := make([]uint64, 0, n)
+	for j := card - uint64(n); j < card; j++ {
+		r := rng.Uint64N(j + 1)
+		if _, dup := chosen[r]; dup {
+			r = j
+		}
+		chosen[r] = struct{}{}
+		ranks = append(ranks, r)
+	}
+	return ranks
+}
@@ -0,0 +1,75 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSearchSample(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 1000; i++ {
+		if i%3 == 0 {
+			idx.Add(i, testQuickBrownFox)
+		} else {
+			idx.Add(i, testHelloWorld)
+		}
+	}
+	matches := idx.Search("brown fox")
+
+	sample := idx.SearchSample("brown fox", 50, 1)
+	if len(sample) != 50 {
+		t.Fatalf("len(sample) = %d, want 50", len(sample))
+	}
+	if !slices.IsSorted(sample) || len(slices.Compact(slices.Clone(sample))) != 50 {
+		t.Errorf("sample is not sorted and distinct: %v", sample)
+	}
+	for _, docID := range sample {
+		if _, found := slices.BinarySearch(matches, docID); !found {
+			t.Errorf("sampled doc %d does not match", docID)
+		}
+	}
+
+	if again := idx.SearchSample("brown fox", 50, 1); !reflect.DeepEqual(again, sample) {
+		t.Error("same seed gave a different sample")
+	}
+	if other := idx.SearchSample("brown fox", 50, 2); reflect.DeepEqual(other, sample) {
+		t.Error("different seeds gave the same sample")
+	}
+
+	// Single n-gram queries sample the index bitmap directly
+	if got := idx.SearchSample("fox", 10, 3); len(got) != 10 {
+		t.Errorf("len(SearchSample(fox)) = %d, want 10", len(got))
+	}
+
+	if got := idx.SearchSample("brown fox", 5000, 1); !reflect.DeepEqual(got, matches) {
+		t.Errorf("oversized sample returned %d docs, want all %d", len(got), len(matches))
+	}
+	if got := idx.SearchSample("zebra", 5, 1); got != nil {
+		t.Errorf("SearchSample(zebra) = %v, want nil", got)
+	}
+	if got := idx.SearchSample("brown fox", 0, 1); got != nil {
+		t.Errorf("SearchSample(n=0) = %v, want nil", got)
+	}
+}
+
+func TestSearchSampleUniform(t *testing.T) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 10; i++ {
+		idx.Add(i*1000, testHelloWorld)
+	}
+
+	counts := make(map[uint32]int)
+	const trials = 5000
+	for seed := uint64(0); seed < trials; seed++ {
+		for _, docID := range idx.SearchSample("hello", 2, seed) {
+			counts[docID]++
+		}
+	}
+	// Each doc is expected in 2/10 of the samples
+	for i := uint32(0); i < 10; i++ {
+		if got := counts[i*1000]; got < 850 || got > 1150 {
+			t.Errorf("doc %d sampled %d times, want about 1000", i*1000, got)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package roaringsearch
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runBatch calls fn for every index in [0, n) using up to workers goroutines.
+// Workers <= 0 uses runtime.NumCPU().
+func runBatch(n, workers int, fn func(i int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, n)
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// SearchBatch runs an AND search for each query in parallel and returns the
+// results in query order. The read lock is acquired once for the whole batch,
+// so writers wait until every query has finished; split very large batches if
+// the index is updated concurrently. Workers <= 0 uses runtime.NumCPU().
+func (idx *Index) SearchBatch(queries []string, workers int) [][]uint32 {
+	results := make([][]uint32, len(queries))
+	if len(queries) == 0 {
+		return results
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	runBatch(len(queries), workers, func(i int) {
+		var start time.Time
+		if idx.stats != nil {
+			start = time.Now()
+		}
+
+		var keyBuf [queryKeyBufSize]uint64
+		if keys := idx.appendQueryKeys(keyBuf[:0], queries[i]); len(keys) > 0 {
+			results[i] = idx.searchLocked(keys)
+		}
+
+		if idx.stats != nil {
+			idx.recordQuery("SearchBatch", queries[i], len(results[i]), start)
+		}
+	})
+	return results
+}
+
+// SearchBatch runs an AND search for each query in parallel and returns the
+// results in query order. Workers <= 0 uses runtime.NumCPU().
+func (idx *CachedIndex) SearchBatch(queries []string, workers int) [][]uint32 {
+	results := make([][]uint32, len(queries))
+	if len(queries) == 0 {
+		return results
+	}
+
+	runBatch(len(queries), workers, func(i int) {
+		results[i] = idx.Search(queries[i])
+	})
+	return results
+}
@@ -0,0 +1,67 @@
+package roaringsearch
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchBatch(t *testing.T) {
+	idx := NewIndex(3, WithSlowQueryLog(100))
+	for i := uint32(0); i < 200; i++ {
+		idx.Add(i, fmt.Sprintf("%s item%03d", testHelloWorld, i))
+	}
+
+	queries := []string{"hello", "item007", "missing", "hi", "item01"}
+	for _, workers := range []int{0, 1, 3} {
+		got := idx.SearchBatch(queries, workers)
+		if len(got) != len(queries) {
+			t.Fatalf("workers=%d: got %d results, want %d", workers, len(got), len(queries))
+		}
+		for i, q := range queries {
+			if want := idx.Search(q); !reflect.DeepEqual(got[i], want) {
+				t.Errorf("workers=%d: SearchBatch[%q] = %v, want %v", workers, q, got[i], want)
+			}
+		}
+	}
+
+	if len(idx.SearchBatch(nil, 4)) != 0 {
+		t.Error("empty batch should return no results")
+	}
+
+	var batched int
+	for _, s := range idx.SlowQueries() {
+		if s.Method == "SearchBatch" {
+			batched++
+		}
+	}
+	if batched != 3*len(queries) {
+		t.Errorf("recorded %d SearchBatch queries, want %d", batched, 3*len(queries))
+	}
+}
+
+func TestCachedIndexSearchBatch(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testGoodbyeWorld)
+
+	path := filepath.Join(t.TempDir(), "batch.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path, WithCacheSize(2))
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	defer cached.Close()
+
+	queries := []string{"hello", "world", "goodbye", "nothing"}
+	got := cached.SearchBatch(queries, 4)
+	for i, q := range queries {
+		if want := idx.Search(q); !reflect.DeepEqual(got[i], want) {
+			t.Errorf("SearchBatch[%q] = %v, want %v", q, got[i], want)
+		}
+	}
+}
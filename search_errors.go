@@ -0,0 +1,130 @@
+package roaringsearch
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// SearchE is like Search but tells apart the outcomes that Search reports
+// as nil: a query shorter than the gram size after normalization returns
+// ErrQueryTooShort, and a query with an n-gram that no document contains
+// returns an error wrapping ErrNgramNotFound that names the n-gram, along
+// with the best-effort matches of a lenient WithMissingNgramPolicy. A query
+// whose n-grams all exist but never occur together returns nil, nil. Results
+// cut short by WithMaxResults come with a *TruncatedError, as from
+// SearchLimited.
+func (idx *Index) SearchE(query string) (matches []uint32, err error) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchE", query, len(matches), start) }(time.Now())
+	}
+	return idx.searchE(query)
+}
+
+// searchE runs SearchE without recording query statistics.
+func (idx *Index) searchE(query string) ([]uint32, error) {
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
+		return nil, ErrQueryTooShort
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectLocked(idx, keys) {
+		err := missingNgram(idx.normalizer(query), idx.gramSize, idx.queryKey, func(key uint64) bool {
+			_, ok := idx.bitmapLocked(key)
+			return ok
+		})
+		if idx.missingNgrams == MissingNgramFail {
+			return nil, err
+		}
+		matches := scratch.appendPartialLocked(idx, keys, nil)
+		if len(matches) == 0 {
+			return nil, err
+		}
+		return matches, err
+	}
+	matches, total := scratch.appendCollectedMax(nil, idx.maxResults)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	return matches, truncated(query, total, len(matches))
+}
+
+// SearchE is like Index.SearchE for a CachedIndex. A bitmap that fails to
+// load from disk, which Search reports as no match, returns the wrapped I/O
+// or decoding error.
+func (idx *CachedIndex) SearchE(query string) (matches []uint32, err error) {
+	if idx.logger != nil {
+		defer func(start time.Time) { idx.logQuery("SearchE", query, len(matches), start) }(time.Now())
+	}
+	keys := idx.generateKeys(query)
+	if len(keys) == 0 {
+		return nil, ErrQueryTooShort
+	}
+
+	var missing error
+	bitmaps := make([]*roaring.Bitmap, 0, len(keys))
+	for _, key := range keys {
+		bm, ok, err := idx.loadKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			bitmaps = append(bitmaps, bm)
+			continue
+		}
+		if missing == nil {
+			missing = missingNgram(idx.normalizer(query), idx.gramSize, idx.exact.queryKey, func(key uint64) bool {
+				_, ok := idx.location(key)
+				return ok
+			})
+		}
+		if idx.missingNgrams == MissingNgramFail {
+			return nil, missing
+		}
+	}
+
+	var result *roaring.Bitmap
+	switch {
+	case missing != nil:
+		result = partialBitmap(idx.missingNgrams, bitmaps, idx.gramSize)
+	case len(bitmaps) == 1:
+		result = bitmaps[0]
+	default:
+		sortByCardinality(bitmaps)
+		result = roaring.FastAnd(bitmaps...)
+	}
+	if result == nil || result.IsEmpty() {
+		return nil, missing
+	}
+	matches = limitedArray(result, idx.maxResults)
+	if missing != nil {
+		return matches, missing
+	}
+	return matches, truncated(query, result.GetCardinality(), len(matches))
+}
+
+// missingNgram returns an ErrNgramNotFound error naming the first n-gram of
+// the normalized query whose key has reports as absent.
+func missingNgram(normalized string, gramSize int, key func([]rune) uint64, has func(uint64) bool) error {
+	runes := []rune(normalized)
+	for i := 0; i+gramSize <= len(runes); i++ {
+		if gram := runes[i : i+gramSize]; !has(key(gram)) {
+			return fmt.Errorf("%w: %q", ErrNgramNotFound, string(gram))
+		}
+	}
+	return ErrNgramNotFound
+}
+
+// isNoMatch reports whether err only says that a query cannot match, as
+// opposed to a truncated result or a failure.
+func isNoMatch(err error) bool {
+	return errors.Is(err, ErrQueryTooShort) || errors.Is(err, ErrNgramNotFound)
+}
@@ -0,0 +1,92 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// failingReader serves an index until fail is set, then returns errDisk.
+type failingReader struct {
+	r    *bytes.Reader
+	fail atomic.Bool
+}
+
+var errDisk = errors.New("disk on fire")
+
+func (f *failingReader) ReadAt(p []byte, off int64) (int, error) {
+	if f.fail.Load() {
+		return 0, errDisk
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestSearchE(t *testing.T) {
+	idx := NewIndex(3, WithMaxResults(2))
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testHelloWorld)
+	idx.Add(4, "abcd")
+	idx.Add(5, "bcde")
+
+	if _, err := idx.SearchE("hi"); !errors.Is(err, ErrQueryTooShort) {
+		t.Errorf("short query error = %v, want ErrQueryTooShort", err)
+	}
+	_, err := idx.SearchE("helxyz")
+	if !errors.Is(err, ErrNgramNotFound) || !strings.Contains(err.Error(), `"elx"`) {
+		t.Errorf("missing n-gram error = %v, want ErrNgramNotFound naming \"elx\"", err)
+	}
+	if matches, err := idx.SearchE("abcde"); matches != nil || err != nil {
+		t.Errorf("no common docs = %v, %v, want nil, nil", matches, err)
+	}
+	if matches, err := idx.SearchE("hello world"); !reflect.DeepEqual(matches, []uint32{1, 3}) || err != nil {
+		t.Errorf("SearchE(hello world) = %v, %v", matches, err)
+	}
+	if matches, err := idx.SearchE("hello"); len(matches) != 2 || !errors.Is(err, ErrTooManyResults) {
+		t.Errorf("truncated SearchE = %v, %v", matches, err)
+	}
+
+	// SearchLimited still reports no match as nil, nil
+	if matches, err := idx.SearchLimited("helxyz"); matches != nil || err != nil {
+		t.Errorf("SearchLimited(helxyz) = %v, %v", matches, err)
+	}
+}
+
+func TestCachedSearchE(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	origin := &failingReader{r: bytes.NewReader(buf.Bytes())}
+	cached, err := OpenCachedIndexReader(origin)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+
+	if _, err := cached.SearchE("hi"); !errors.Is(err, ErrQueryTooShort) {
+		t.Errorf("short query error = %v, want ErrQueryTooShort", err)
+	}
+	if _, err := cached.SearchE("helxyz"); !errors.Is(err, ErrNgramNotFound) {
+		t.Errorf("missing n-gram error = %v, want ErrNgramNotFound", err)
+	}
+	if matches, err := cached.SearchE("there"); !reflect.DeepEqual(matches, []uint32{2}) || err != nil {
+		t.Errorf("SearchE(there) = %v, %v", matches, err)
+	}
+
+	// A load failure is an error from SearchE but no match from Search
+	origin.fail.Store(true)
+	_, err = cached.SearchE("world")
+	if !errors.Is(err, errDisk) || isNoMatch(err) {
+		t.Errorf("load failure error = %v, want it to wrap the read error", err)
+	}
+	if got := cached.Search("world"); got != nil {
+		t.Errorf("Search on a failing reader = %v, want nil", got)
+	}
+}
@@ -0,0 +1,96 @@
+package roaringsearch
+
+import (
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// SearchAndFilter returns the documents containing all n-grams of the query
+// that are also in every filter bitmap, such as BitmapFilter categories or a
+// FilterQuery result. The filters join the query's bitmaps in a single
+// intersection that starts from the smallest input, so a selective filter
+// narrows the work instead of being applied to a materialized result. Nil
+// filters are ignored; with none, it is equivalent to Search.
+func (idx *Index) SearchAndFilter(query string, filters ...*roaring.Bitmap) (matches []uint32) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchAndFilter", query, len(matches), start) }(time.Now())
+	}
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	if !scratch.collectMatchingLocked(idx, keys) {
+		return nil
+	}
+	return scratch.appendFiltered(filters, idx.maxResults)
+}
+
+// SearchAndFilter is like Index.SearchAndFilter, loading the query's bitmaps
+// through the cache. With WithFilterPushdown, small filters are checked
+// against large uncached bitmaps on disk instead.
+func (idx *CachedIndex) SearchAndFilter(query string, filters ...*roaring.Bitmap) (matches []uint32) {
+	if idx.logger != nil {
+		defer func(start time.Time) { idx.logQuery("SearchAndFilter", query, len(matches), start) }(time.Now())
+	}
+	keys := idx.generateKeys(query)
+	if len(keys) == 0 {
+		return nil
+	}
+	if idx.pushdownLimit > 0 {
+		if matches, ok := idx.searchPushdown(keys, filters); ok {
+			return matches
+		}
+	}
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	missing := false
+	for _, key := range keys {
+		bm, ok := idx.getBitmap(key)
+		if !ok {
+			if idx.missingNgrams == MissingNgramFail {
+				return nil
+			}
+			missing = true
+			continue
+		}
+		scratch.bitmaps = append(scratch.bitmaps, bm)
+	}
+	if missing {
+		scratch.reducePartial(idx.missingNgrams, idx.gramSize)
+	}
+	if len(scratch.bitmaps) == 0 {
+		return nil
+	}
+	return scratch.appendFiltered(filters, idx.maxResults)
+}
+
+// appendFiltered adds the non-nil filters to s.bitmaps and returns the
+// documents in all of them, only the limit lowest when limit > 0, or nil if
+// there are none.
+func (s *searchScratch) appendFiltered(filters []*roaring.Bitmap, limit int) []uint32 {
+	for _, f := range filters {
+		if f == nil {
+			continue
+		}
+		if f.IsEmpty() {
+			return nil
+		}
+		s.bitmaps = append(s.bitmaps, f)
+	}
+	sortByCardinality(s.bitmaps)
+
+	matches, _ := s.appendCollectedMax(nil, limit)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
+}
@@ -0,0 +1,59 @@
+package roaringsearch
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+func TestSearchAndFilter(t *testing.T) {
+	idx := NewIndex(3)
+	filter := NewBitmapFilter()
+	rng := rand.New(rand.NewSource(3))
+	for i := uint32(0); i < 3000; i++ {
+		idx.Add(i, generateDocument(rng, 5, 20))
+		if i%7 == 0 {
+			filter.Set(i, "color", "red")
+		}
+	}
+	red := filter.Get("color", "red")
+	few := roaring.BitmapOf(7, 14, 21, 2999)
+
+	path := filepath.Join(t.TempDir(), "filter.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path)
+	if err != nil {
+		t.Fatalf(errOpenCachedIndex, err)
+	}
+	defer cached.Close()
+
+	for _, q := range []string{"server", "the", "john server", "zzzz"} {
+		for _, filters := range [][]*roaring.Bitmap{{red}, {red, few}, {few}, {nil}, nil} {
+			want := roaring.BitmapOf(idx.Search(q)...)
+			for _, f := range filters {
+				if f != nil {
+					want.And(f)
+				}
+			}
+			wantIDs := want.ToArray()
+			if len(wantIDs) == 0 {
+				wantIDs = nil
+			}
+			if got := idx.SearchAndFilter(q, filters...); !reflect.DeepEqual(got, wantIDs) {
+				t.Errorf("SearchAndFilter(%q) with %d filters = %d docs, want %d", q, len(filters), len(got), len(wantIDs))
+			}
+			if got := cached.SearchAndFilter(q, filters...); !reflect.DeepEqual(got, wantIDs) {
+				t.Errorf("cached SearchAndFilter(%q) with %d filters = %d docs, want %d", q, len(filters), len(got), len(wantIDs))
+			}
+		}
+	}
+
+	if got := idx.SearchAndFilter("the", red, roaring.New()); got != nil {
+		t.Errorf("empty filter matched %v", got)
+	}
+}
@@ -0,0 +1,240 @@
+package roaringsearch
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// SearchAppend appends the documents containing all n-grams of the query to
+// dst and returns the extended slice. Scratch space comes from a pool, so
+// when dst has enough capacity, selective ASCII queries allocate nothing and
+// broad ones allocate only per roaring container of the result.
+func (idx *Index) SearchAppend(query string, dst []uint32) (out []uint32) {
+	if idx.stats != nil {
+		n := len(dst)
+		defer func(start time.Time) { idx.recordQuery("SearchAppend", query, len(out)-n, start) }(time.Now())
+	}
+
+	scratch := getSearchScratch()
+	defer putSearchScratch(scratch)
+	return scratch.searchAppend(idx, query, dst)
+}
+
+// walkLimit is the largest rarest-n-gram cardinality for which matches are
+// found by probing each candidate instead of intersecting bitmaps. Probing
+// never allocates but costs a lookup per candidate and bitmap.
+const walkLimit = 64
+
+var searchScratchPool = sync.Pool{
+	New: func() any { return newSearchScratch() },
+}
+
+// getSearchScratch takes scratch space from the pool.
+func getSearchScratch() *searchScratch {
+	return searchScratchPool.Get().(*searchScratch)
+}
+
+// putSearchScratch resets s and returns it to the pool.
+func putSearchScratch(s *searchScratch) {
+	s.reset()
+	searchScratchPool.Put(s)
+}
+
+// searchScratch holds the reusable buffers of a search.
+type searchScratch struct {
+	keys    []uint64
+	bitmaps []*roaring.Bitmap
+	cards   []uint64 // cardinalities of bitmaps, when known in advance
+	result  *roaring.Bitmap
+	it      roaring.ManyIntIterator
+}
+
+func newSearchScratch() *searchScratch {
+	return &searchScratch{
+		keys:    make([]uint64, 0, queryKeyBufSize),
+		bitmaps: make([]*roaring.Bitmap, 0, queryKeyBufSize),
+		result:  roaring.New(),
+	}
+}
+
+// reset empties the scratch space so it does not keep index bitmaps alive.
+func (s *searchScratch) reset() {
+	clear(s.bitmaps)
+	s.bitmaps = s.bitmaps[:0]
+	s.result.Clear()
+}
+
+// searchAppend appends the AND matches of query in idx to dst.
+func (s *searchScratch) searchAppend(idx *Index, query string, dst []uint32) []uint32 {
+	s.keys = idx.appendQueryKeys(s.keys[:0], query)
+	if len(s.keys) == 0 {
+		return dst
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return s.appendMatchesLocked(idx, s.keys, dst)
+}
+
+// searchCount returns the number of AND matches of query in idx.
+func (s *searchScratch) searchCount(idx *Index, query string) uint64 {
+	s.keys = idx.appendQueryKeys(s.keys[:0], query)
+	if len(s.keys) == 0 {
+		return 0
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return s.countLocked(idx, s.keys)
+}
+
+// collectLocked gathers the bitmaps of keys into s.bitmaps, smallest first.
+// Returns false if any key is missing from the index, so nothing can match.
+func (s *searchScratch) collectLocked(idx *Index, keys []uint64) bool {
+	s.bitmaps = s.bitmaps[:0]
+	for _, key := range keys {
+		bm, ok := idx.bitmapLocked(key)
+		if !ok {
+			return false
+		}
+		s.bitmaps = append(s.bitmaps, bm)
+	}
+	sortByCardinality(s.bitmaps)
+	return len(s.bitmaps) > 0
+}
+
+// appendMatchesLocked appends the documents in every bitmap of keys to dst,
+// up to the index's result limit. A missing key matches nothing unless the
+// index has a lenient MissingNgramPolicy.
+func (s *searchScratch) appendMatchesLocked(idx *Index, keys []uint64, dst []uint32) []uint32 {
+	if !s.collectLocked(idx, keys) {
+		if idx.missingNgrams != MissingNgramFail {
+			return s.appendPartialLocked(idx, keys, dst)
+		}
+		return dst
+	}
+	dst, _ = s.appendCollectedMax(dst, idx.maxResults)
+	return dst
+}
+
+// appendCollected appends the documents in every bitmap of s.bitmaps, which
+// is sorted smallest first, to dst.
+func (s *searchScratch) appendCollected(dst []uint32) []uint32 {
+	dst, _ = s.appendCollectedMax(dst, 0)
+	return dst
+}
+
+// appendCollectedMax is like appendCollected but, when limit > 0, appends
+// only the limit lowest docIDs. It returns how many documents matched.
+func (s *searchScratch) appendCollectedMax(dst []uint32, limit int) ([]uint32, uint64) {
+	n := len(dst)
+	smallest, rest := s.bitmaps[0], s.bitmaps[1:]
+	if smallest.GetCardinality() <= walkLimit {
+		smallest.Iterate(func(docID uint32) bool {
+			if existsInAllBitmaps(docID, rest) {
+				dst = append(dst, docID)
+			}
+			return true
+		})
+		matched := len(dst) - n
+		if limit > 0 && matched > limit {
+			dst = dst[:n+limit]
+		}
+		return dst, uint64(matched)
+	}
+
+	matches := smallest
+	if len(rest) > 0 {
+		s.intersectLocked()
+		matches = s.result
+	}
+	card := matches.GetCardinality()
+	take := int(card)
+	if limit > 0 && take > limit {
+		take = limit
+	}
+	dst = slices.Grow(dst, take)[:n+take]
+	s.it.Initialize(matches)
+	s.it.NextMany(dst[n:])
+	return dst, card
+}
+
+// countLocked returns the number of documents in every bitmap of keys.
+func (s *searchScratch) countLocked(idx *Index, keys []uint64) uint64 {
+	if !s.collectLocked(idx, keys) {
+		return 0
+	}
+	return s.countCollected()
+}
+
+// countCollected returns the number of documents in every bitmap of s.bitmaps.
+func (s *searchScratch) countCollected() uint64 {
+	switch len(s.bitmaps) {
+	case 1:
+		return s.bitmaps[0].GetCardinality()
+	case 2:
+		return s.bitmaps[0].AndCardinality(s.bitmaps[1])
+	}
+	s.intersectLocked()
+	return s.result.GetCardinality()
+}
+
+// intersectLocked stores the intersection of s.bitmaps in s.result.
+func (s *searchScratch) intersectLocked() {
+	s.result.Or(s.bitmaps[0])
+	for _, bm := range s.bitmaps[1:] {
+		s.result.And(bm)
+	}
+}
+
+// Searcher runs AND queries against an Index with its own scratch space and
+// result buffer, so that steady-state queries allocate as little as
+// SearchAppend without returning buffers to a shared pool.
+// A Searcher is not safe for concurrent use; create one per goroutine.
+type Searcher struct {
+	idx     *Index
+	scratch *searchScratch
+	results []uint32
+}
+
+// NewSearcher returns a Searcher for the index.
+func (idx *Index) NewSearcher() *Searcher {
+	return &Searcher{idx: idx, scratch: newSearchScratch()}
+}
+
+// Search returns the documents containing all n-grams of the query.
+// The returned slice is reused by the next call; copy it to keep it.
+func (s *Searcher) Search(query string) []uint32 {
+	s.results = s.SearchAppend(query, s.results[:0])
+	if len(s.results) == 0 {
+		return nil
+	}
+	return s.results
+}
+
+// SearchAppend is like Index.SearchAppend but uses the Searcher's scratch space.
+func (s *Searcher) SearchAppend(query string, dst []uint32) (out []uint32) {
+	idx := s.idx
+	if idx.stats != nil {
+		n := len(dst)
+		defer func(start time.Time) { idx.recordQuery("SearchAppend", query, len(out)-n, start) }(time.Now())
+	}
+
+	defer s.scratch.reset()
+	return s.scratch.searchAppend(idx, query, dst)
+}
+
+// SearchCount returns the number of documents containing all n-grams of the
+// query.
+func (s *Searcher) SearchCount(query string) (matches uint64) {
+	idx := s.idx
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchCount", query, int(matches), start) }(time.Now())
+	}
+
+	defer s.scratch.reset()
+	return s.scratch.searchCount(idx, query)
+}
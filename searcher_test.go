@@ -0,0 +1,128 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchAppend(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testGoodbyeWorld)
+
+	dst := []uint32{99}
+	if got := idx.SearchAppend("hello", dst); !reflect.DeepEqual(got, []uint32{99, 1, 2}) {
+		t.Errorf("SearchAppend(hello) = %v, want [99 1 2]", got)
+	}
+	for _, query := range []string{"world", "hello world", "missing", "hi", "", "日本"} {
+		got := idx.SearchAppend(query, nil)
+		if want := idx.Search(query); len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Errorf("SearchAppend(%q) = %v, Search = %v", query, got, want)
+		}
+	}
+
+	// Above walkLimit matches come from intersecting bitmaps
+	for i := uint32(10); i < 10+2*walkLimit; i++ {
+		idx.Add(i, testHelloWorld)
+	}
+	if got, want := idx.SearchAppend("hello world", nil), idx.Search("hello world"); !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchAppend over walkLimit = %v, Search = %v", got, want)
+	}
+
+	idx.Remove(1)
+	buf := make([]uint32, 0, 16)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = idx.SearchAppend("hello there", buf[:0])
+	})
+	if allocs != 0 && !raceEnabled {
+		t.Errorf("SearchAppend allocs = %v, want 0", allocs)
+	}
+}
+
+func TestSearcher(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testQuickBrownFox)
+	idx.Add(2, "the lazy brown dog")
+	idx.Add(3, "東京タワーと東京駅")
+
+	s := idx.NewSearcher()
+	if got := s.Search("brown"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("Search(brown) = %v, want [1 2]", got)
+	}
+	if got := s.Search("東京タワー"); !reflect.DeepEqual(got, []uint32{3}) {
+		t.Errorf("Search(東京タワー) = %v, want [3]", got)
+	}
+	if got := s.Search("purple"); got != nil {
+		t.Errorf("Search(purple) = %v, want nil", got)
+	}
+	if got := s.SearchCount("the"); got != 2 {
+		t.Errorf("SearchCount(the) = %d, want 2", got)
+	}
+
+	// A query longer than the stack buffers allocates only until the
+	// Searcher's scratch space has grown
+	long := testQuickBrownFox + " jumps over the lazy dog"
+	s.Search(long)
+	allocs := testing.AllocsPerRun(100, func() { s.Search(long) })
+	if allocs != 0 {
+		t.Errorf("Searcher.Search allocs = %v, want 0", allocs)
+	}
+}
+
+func TestSearchAppendQueryStats(t *testing.T) {
+	var got []QueryStats
+	idx := NewIndex(3, WithQueryHook(func(s QueryStats) {
+		got = append(got, s)
+	}))
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+
+	idx.SearchAppend("hello", []uint32{99})
+	s := idx.NewSearcher()
+	s.SearchAppend("hello", nil)
+	s.SearchCount("world")
+
+	want := []struct {
+		method  string
+		results int
+	}{{"SearchAppend", 2}, {"SearchAppend", 2}, {"SearchCount", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("hook called %d times, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Method != w.method || got[i].Results != w.results {
+			t.Errorf("stats[%d] = %+v, want %s with %d results", i, got[i], w.method, w.results)
+		}
+	}
+}
+
+func BenchmarkSearchAppend(b *testing.B) {
+	idx := NewIndex(3)
+	for i := uint32(0); i < 10000; i++ {
+		idx.Add(i, testQuickBrownFox)
+	}
+
+	b.Run("Search", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.Search("brown fox")
+		}
+	})
+
+	b.Run("SearchAppend", func(b *testing.B) {
+		b.ReportAllocs()
+		var dst []uint32
+		for i := 0; i < b.N; i++ {
+			dst = idx.SearchAppend("brown fox", dst[:0])
+		}
+	})
+
+	b.Run("Searcher", func(b *testing.B) {
+		b.ReportAllocs()
+		s := idx.NewSearcher()
+		for i := 0; i < b.N; i++ {
+			s.Search("brown fox")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package roaringsearch
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// SimilarResult is a document scored by SearchSimilar.
+type SimilarResult struct {
+	DocID      uint32
+	Similarity float64 // in (0, 1]
+}
+
+// similarConfig holds options for SearchSimilar.
+type similarConfig struct {
+	jaccard bool
+	limit   int
+}
+
+// SimilarityOption configures a SearchSimilar query.
+type SimilarityOption func(*similarConfig)
+
+// WithJaccard scores documents by the Jaccard similarity of their n-grams
+// and the query's, matched / (query + document - matched), so a document
+// much longer than the query scores low even when it contains all of it.
+// It needs the document lengths of WithDocLengths; documents without one
+// are scored as if they had exactly the n-grams they matched.
+func WithJaccard() SimilarityOption {
+	return func(cfg *similarConfig) {
+		cfg.jaccard = true
+	}
+}
+
+// WithSimilarLimit returns at most n documents, most similar first.
+// n <= 0 returns all matches.
+func WithSimilarLimit(n int) SimilarityOption {
+	return func(cfg *similarConfig) {
+		cfg.limit = n
+	}
+}
+
+// SearchSimilar returns the documents whose similarity to the query is at
+// least minSimilarity, most similar first, ties by ascending docID. By
+// default similarity is the fraction of the query's n-grams a document
+// contains, so 0.8 tolerates a fifth of them missing whatever the query
+// length, which is easier to tune than SearchThreshold's absolute count.
+// N-grams absent from the index count against every document, as in
+// SearchMatch. minSimilarity is clamped to [0, 1]; 0 matches any document
+// sharing an n-gram with the query.
+//
+// Example:
+//
+//	idx.SearchSimilar("quick brwn fox", 0.7, rs.WithSimilarLimit(10))
+func (idx *Index) SearchSimilar(query string, minSimilarity float64, opts ...SimilarityOption) (results []SimilarResult) {
+	if idx.stats != nil {
+		defer func(start time.Time) { idx.recordQuery("SearchSimilar", query, len(results), start) }(time.Now())
+	}
+
+	var cfg similarConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	minSimilarity = max(0, min(minSimilarity, 1))
+
+	var keyBuf [queryKeyBufSize]uint64
+	keys := idx.appendQueryKeys(keyBuf[:0], query)
+	total := len(keys)
+	if total == 0 {
+		return nil
+	}
+	// Both measures are at most matched/total, so this many n-grams are
+	// needed either way
+	required := max(1, int(math.Ceil(minSimilarity*float64(total)-1e-9)))
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	levels, threshold := idx.thresholdLevelsLocked(keys, required)
+	if levels == nil || threshold < required {
+		return nil
+	}
+
+	if !cfg.jaccard {
+		walkThreshold(levels, threshold, func(docID uint32, matched int) bool {
+			results = append(results, SimilarResult{DocID: docID, Similarity: float64(matched) / float64(total)})
+			return cfg.limit <= 0 || len(results) < cfg.limit
+		})
+		return results
+	}
+
+	walkThreshold(levels, threshold, func(docID uint32, matched int) bool {
+		length := matched
+		if idx.lengths != nil {
+			length = max(length, int(idx.lengths.Get(docID)))
+		}
+		sim := float64(matched) / float64(total+length-matched)
+		if sim >= minSimilarity {
+			results = append(results, SimilarResult{DocID: docID, Similarity: sim})
+		}
+		return true
+	})
+	slices.SortFunc(results, func(a, b SimilarResult) int {
+		return cmp.Or(cmp.Compare(b.Similarity, a.Similarity), cmp.Compare(a.DocID, b.DocID))
+	})
+	if cfg.limit > 0 && len(results) > cfg.limit {
+		results = results[:cfg.limit]
+	}
+	return results
+}
@@ -0,0 +1,37 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchSimilar(t *testing.T) {
+	idx := NewIndex(3, WithDocLengths())
+	idx.Add(1, "quick brown fox")
+	idx.Add(2, testLongFox)
+	idx.Add(3, testHelloWorld)
+
+	// 8 of the 10 query n-grams are in documents 1 and 2
+	const query = "quick brwn fox"
+	want := []SimilarResult{{1, 0.8}, {2, 0.8}}
+	if got := idx.SearchSimilar(query, 0.8); !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchSimilar(0.8) = %v, want %v", got, want)
+	}
+	if got := idx.SearchSimilar(query, 0.81); got != nil {
+		t.Errorf("SearchSimilar(0.81) = %v, want nil", got)
+	}
+	if got := idx.SearchSimilar(query, 0.5, WithSimilarLimit(1)); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("limited SearchSimilar = %v, want %v", got, want[:1])
+	}
+
+	got := idx.SearchSimilar(query, 0.5, WithJaccard())
+	if len(got) != 1 || got[0].DocID != 1 || got[0].Similarity != 8.0/13 {
+		t.Errorf("Jaccard SearchSimilar = %v, want doc 1 at 8/13", got)
+	}
+	if got := idx.SearchSimilar(query, 0, WithJaccard()); len(got) != 2 || got[0].DocID != 1 {
+		t.Errorf("Jaccard order = %v, want the short document first", got)
+	}
+	if got := idx.SearchSimilar("xy", 0.5); got != nil {
+		t.Errorf("short query = %v, want nil", got)
+	}
+}
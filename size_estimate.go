@@ -0,0 +1,106 @@
+package roaringsearch
+
+import "math"
+
+const (
+	containerSpan      = 1 << 16 // docIDs per roaring container
+	bitmapContainerLen = 8192    // bytes of a roaring bitmap container
+	containerOverhead  = 16      // key, type and slice header per container, roughly
+)
+
+// IndexSizeEstimate is the projected size of an index built from a full
+// corpus, extrapolated from a sample of it.
+type IndexSizeEstimate struct {
+	GramSize       int
+	SampleDocs     int
+	TotalDocs      int
+	UniqueNgrams   int     // projected distinct n-grams
+	Postings       uint64  // projected sum of n-gram bitmap cardinalities
+	MemoryBytes    uint64  // projected MemoryUsage
+	FileBytes      uint64  // projected SaveToFile size
+	GrowthExponent float64 // Heaps' law exponent fitted to the sample, in [0, 1]
+}
+
+// EstimateIndexSize indexes a sample of a corpus and projects the unique
+// n-gram count, memory and file size of an index of totalDocs documents
+// like it, for capacity planning without building the full index. The
+// sample should be drawn at random from the corpus; options such as
+// WithNormalizer apply to the trial index as they would to the real one.
+//
+// Distinct n-grams grow sublinearly with the corpus, following Heaps' law:
+// the exponent is fitted from the counts after half and all of the sample.
+// Postings grow linearly. Sizes are projected per n-gram with a model of
+// roaring containers, which turn from 2-byte arrays into fixed 8 KiB bitmaps
+// as they fill, and scaled by the ratio of the sample's measured size to the
+// model's. N-grams first seen beyond the sample are counted as holding one
+// document each. The larger and more representative the sample, the closer
+// the estimate; totalDocs below the sample size is treated as the sample size.
+//
+// Example:
+//
+//	est := rs.EstimateIndexSize(sample, 100_000_000, 3)
+//	fmt.Printf("%d n-grams, %d MiB\n", est.UniqueNgrams, est.MemoryBytes>>20)
+func EstimateIndexSize(sampleDocs []string, totalDocs int, gramSize int, opts ...Option) IndexSizeEstimate {
+	idx := NewIndex(gramSize, opts...)
+	n := len(sampleDocs)
+	totalDocs = max(totalDocs, n)
+	est := IndexSizeEstimate{GramSize: idx.gramSize, SampleDocs: n, TotalDocs: totalDocs, GrowthExponent: 1}
+	if n == 0 {
+		return est
+	}
+
+	half := n / 2
+	addSample(idx, sampleDocs[:half], 0)
+	halfNgrams := idx.NgramCount()
+	addSample(idx, sampleDocs[half:], half)
+	stats := idx.Stats(0)
+	if stats.Ngrams == 0 {
+		return est
+	}
+	if half > 0 && halfNgrams > 0 {
+		beta := math.Log(float64(stats.Ngrams)/float64(halfNgrams)) / math.Log(float64(n)/float64(half))
+		est.GrowthExponent = min(max(beta, 0), 1)
+	}
+
+	scale := float64(totalDocs) / float64(n)
+	ngrams := math.Round(float64(stats.Ngrams) * math.Pow(scale, est.GrowthExponent))
+	newNgrams := max(ngrams-float64(stats.Ngrams), 0)
+
+	var sampleModel, fullModel float64
+	for _, bm := range idx.bitmaps {
+		card := float64(bm.GetCardinality())
+		sampleModel += modelBitmapBytes(card, float64(n))
+		fullModel += modelBitmapBytes(card*scale, float64(totalDocs))
+	}
+	fullModel += newNgrams * modelBitmapBytes(1, float64(totalDocs))
+	ratio := fullModel / sampleModel
+
+	est.UniqueNgrams = int(ngrams)
+	est.Postings = uint64(math.Round(float64(stats.Postings)*scale + newNgrams))
+	est.MemoryBytes = uint64(math.Round(float64(stats.MemoryBytes) * ratio))
+	est.FileBytes = uint64(math.Round(float64(stats.SerializedBytes) * ratio))
+	return est
+}
+
+// addSample indexes docs with consecutive docIDs from first.
+func addSample(idx *Index, docs []string, first int) {
+	if len(docs) == 0 {
+		return
+	}
+	batch := idx.BatchSize(len(docs))
+	for i, text := range docs {
+		batch.Add(uint32(first+i), text)
+	}
+	batch.Flush()
+}
+
+// modelBitmapBytes models the size of a roaring bitmap of card docIDs spread
+// evenly over [0, universe): one container per 65536 docIDs spanned, each an
+// array of 2-byte entries or an 8 KiB bitmap, whichever is smaller.
+func modelBitmapBytes(card, universe float64) float64 {
+	if card <= 0 {
+		return 0
+	}
+	containers := min(card, math.Ceil(universe/containerSpan))
+	return containers * (containerOverhead + min(2*card/containers, bitmapContainerLen))
+}
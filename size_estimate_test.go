@@ -0,0 +1,47 @@
+package roaringsearch
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEstimateIndexSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]string, 20000)
+	for i := range docs {
+		docs[i] = generateDocument(rng, 5, 20)
+	}
+	sample := docs[:2000]
+
+	// At the sample size the estimate is the sample index itself
+	est := EstimateIndexSize(sample, 0, 3)
+	idx := NewIndex(3)
+	addSample(idx, sample[:1000], 0)
+	addSample(idx, sample[1000:], 1000)
+	want := idx.Stats(0)
+	if est.TotalDocs != len(sample) || est.UniqueNgrams != want.Ngrams || est.Postings != want.Postings ||
+		est.MemoryBytes != want.MemoryBytes || est.FileBytes != want.SerializedBytes {
+		t.Errorf("sample-sized estimate = %+v, want %+v", est, want)
+	}
+	if est.GrowthExponent < 0 || est.GrowthExponent > 1 {
+		t.Errorf("growth exponent %v out of range", est.GrowthExponent)
+	}
+
+	full := NewIndex(3)
+	addSample(full, docs, 0)
+	actual := full.Stats(0)
+	est = EstimateIndexSize(sample, len(docs), 3)
+	within := func(name string, got, want float64) {
+		if got < want/2 || got > want*2 {
+			t.Errorf("%s = %v, want within 2x of %v", name, got, want)
+		}
+	}
+	within("UniqueNgrams", float64(est.UniqueNgrams), float64(actual.Ngrams))
+	within("Postings", float64(est.Postings), float64(actual.Postings))
+	within("MemoryBytes", float64(est.MemoryBytes), float64(actual.MemoryBytes))
+	within("FileBytes", float64(est.FileBytes), float64(actual.SerializedBytes))
+
+	if got := EstimateIndexSize(nil, 1000, 3); got.UniqueNgrams != 0 || got.MemoryBytes != 0 {
+		t.Errorf("empty sample = %+v, want zero sizes", got)
+	}
+}
@@ -0,0 +1,81 @@
+package roaringsearch
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// Snapshot returns a consistent point-in-time view of the index as a new
+// Index, while Add, Remove and batch flushes on idx continue. Bitmaps are
+// shared copy-on-write: whichever index next modifies a shared n-gram clones
+// its bitmap first, so taking a snapshot costs one map copy rather than a
+// copy of every bitmap, and memory grows only with the n-grams written
+// afterwards. A snapshot never observes part of a batch: it waits for batch
+// flushes being applied and blocks new ones until it is taken.
+//
+// Use a snapshot for long-running exports, WriteTo or analytics that must
+// not block ingestion. The snapshot has no forward index, so Remove and
+// Update on it scan every bitmap; load a saved snapshot with
+// WithForwardIndex to rebuild one.
+func (idx *Index) Snapshot() *Index {
+	idx.applying.Lock()
+	defer idx.applying.Unlock()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	shared := make(map[uint64]struct{}, len(idx.bitmaps))
+	for key := range idx.bitmaps {
+		shared[key] = struct{}{}
+	}
+	snap := &Index{
+		gramSize:        idx.gramSize,
+		normalizer:      idx.normalizer,
+		bitmaps:         maps.Clone(idx.bitmaps),
+		docs:            idx.docs.Clone(),
+		ascii:           idx.ascii,
+		normalizerChain: slices.Clone(idx.normalizerChain),
+		stats:           idx.stats,
+		deterministic:   idx.deterministic,
+		containerDir:    idx.containerDir,
+		maxResults:      idx.maxResults,
+		missingNgrams:   idx.missingNgrams,
+		synonyms:        idx.synonyms,
+		indexSynonyms:   idx.indexSynonyms,
+		cjkBigrams:      idx.cjkBigrams,
+		metadata:        idx.metadata,
+		optimizeOnSave:  idx.optimizeOnSave,
+		saveWorkers:     idx.saveWorkers,
+		lengths:         idx.lengths.clone(),
+		lengthSum:       idx.lengthSum,
+		lengthCount:     idx.lengthCount,
+		exact:           idx.exact.clone(),
+		shared:          maps.Clone(shared),
+		results:         idx.results.empty(),
+	}
+	if len(shared) > 0 {
+		idx.shared = shared
+	}
+	return snap
+}
+
+// writableBitmapLocked returns the bitmap of key ready to be modified in
+// place, cloning it first if it is still shared with a snapshot. Since the
+// caller is about to change the index, it bumps the generation.
+func (idx *Index) writableBitmapLocked(key uint64) (*roaring.Bitmap, bool) {
+	idx.generation++
+	bm, ok := idx.bitmaps[key]
+	if !ok || idx.shared == nil {
+		return bm, ok
+	}
+	if _, shared := idx.shared[key]; shared {
+		bm = bm.Clone()
+		idx.bitmaps[key] = bm
+		delete(idx.shared, key)
+		if len(idx.shared) == 0 {
+			idx.shared = nil
+		}
+	}
+	return bm, true
+}
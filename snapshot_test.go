@@ -0,0 +1,129 @@
+package roaringsearch
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotIsolation(t *testing.T) {
+	idx := NewIndex(3, WithForwardIndex())
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testQuickBrownFox)
+
+	snap := idx.Snapshot()
+	helloKey := NgramKey("hel")
+	foxKey := NgramKey("fox")
+
+	idx.Add(4, "hello again")
+	idx.Remove(3)
+	idx.RemoveMany([]uint32{2})
+	idx.Optimize()
+
+	if got := snap.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("snapshot Search(hello) = %v, want [1 2]", got)
+	}
+	if got := snap.Search("fox"); !reflect.DeepEqual(got, []uint32{3}) {
+		t.Errorf("snapshot Search(fox) = %v, want [3]", got)
+	}
+	if snap.DocCount() != 3 || idx.DocCount() != 2 {
+		t.Errorf("doc counts: snapshot %d, index %d", snap.DocCount(), idx.DocCount())
+	}
+	if got := idx.Search("hello"); !reflect.DeepEqual(got, []uint32{1, 4}) {
+		t.Errorf("index Search(hello) = %v, want [1 4]", got)
+	}
+	if snap.bitmaps[helloKey] == idx.bitmaps[helloKey] {
+		t.Error("modified bitmap still shared")
+	}
+	if idx.bitmaps[foxKey] != nil || snap.bitmaps[foxKey] == nil {
+		t.Error("emptied bitmap should be dropped from the index only")
+	}
+
+	// Writes to the snapshot do not reach the index either
+	snap.Remove(1)
+	if got := idx.Search("world"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("index Search(world) after snapshot Remove = %v, want [1]", got)
+	}
+	if snap.HasForwardIndex() {
+		t.Error("snapshot has a forward index")
+	}
+}
+
+func TestSnapshotSharesUnmodifiedBitmaps(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testQuickBrownFox)
+
+	snap := idx.Snapshot()
+	idx.Add(3, "hello")
+
+	worldKey, helKey := NgramKey("wor"), NgramKey("hel")
+	if snap.bitmaps[worldKey] != idx.bitmaps[worldKey] {
+		t.Error("untouched bitmap was copied")
+	}
+	if snap.bitmaps[helKey] == idx.bitmaps[helKey] {
+		t.Error("written bitmap was not copied")
+	}
+
+	// A second write to the same n-gram does not copy again
+	hel := idx.bitmaps[helKey]
+	idx.Add(4, "hello")
+	if idx.bitmaps[helKey] != hel {
+		t.Error("owned bitmap copied twice")
+	}
+
+	var want bytes.Buffer
+	indexOf(t, testHelloWorld, testQuickBrownFox).WriteTo(&want)
+	var got bytes.Buffer
+	snap.WriteTo(&got)
+	if got.Len() != want.Len() {
+		t.Errorf("snapshot serializes to %d bytes, want %d", got.Len(), want.Len())
+	}
+}
+
+// indexOf returns a trigram index of texts with docIDs 1, 2, ...
+func indexOf(t *testing.T, texts ...string) *Index {
+	t.Helper()
+	idx := NewIndex(3)
+	for i, text := range texts {
+		idx.Add(uint32(i+1), text)
+	}
+	return idx
+}
+
+func TestSnapshotDuringBatches(t *testing.T) {
+	const batches, batchSize = 20, 500
+	idx := NewIndex(3)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for b := range batches {
+			batch := idx.BatchSize(batchSize)
+			for i := range batchSize {
+				batch.Add(uint32(b*batchSize+i), fmt.Sprintf("batch%03d document", b))
+			}
+			batch.Flush()
+		}
+	}()
+
+	for range 50 {
+		snap := idx.Snapshot()
+		docs := snap.DocCount()
+		if docs%batchSize != 0 {
+			t.Fatalf("snapshot holds %d docs, not whole batches", docs)
+		}
+		if got := snap.SearchCount("document"); got != docs {
+			t.Fatalf("snapshot has %d docs but %d match a shared word", docs, got)
+		}
+	}
+	wg.Wait()
+
+	if got := idx.Snapshot().SearchCount("document"); got != batches*batchSize {
+		t.Errorf("final snapshot matches %d docs, want %d", got, batches*batchSize)
+	}
+}
@@ -0,0 +1,139 @@
+package roaringsearch
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// QueryStats describes a single completed search.
+type QueryStats struct {
+	Method   string        // search method, e.g. "Search" or "SearchThreshold"
+	Query    string        // query text as passed by the caller
+	Ngrams   int           // number of unique n-grams in the normalized query
+	Results  int           // number of documents returned, counted, or visited
+	Duration time.Duration // time spent in the search
+}
+
+// QueryHook is called after each search when set with WithQueryHook.
+// It runs synchronously on the searching goroutine, so it should be fast.
+type QueryHook func(QueryStats)
+
+// WithQueryHook sets a hook invoked after every search on the index.
+func WithQueryHook(hook QueryHook) Option {
+	return indexOption(func(idx *Index) {
+		idx.queryStatsTracker().hook = hook
+	})
+}
+
+// WithSlowQueryLog keeps the n slowest searches, retrievable with SlowQueries.
+func WithSlowQueryLog(n int) Option {
+	return indexOption(func(idx *Index) {
+		if n > 0 {
+			idx.queryStatsTracker().capacity = n
+		}
+	})
+}
+
+// queryStats holds the optional query hook, slow query log, latency
+// histogram and logger of an Index.
+type queryStats struct {
+	hook     QueryHook
+	capacity int
+	latency  *latencyHistogram
+
+	logger        *slog.Logger
+	slowThreshold time.Duration
+
+	mu      sync.Mutex
+	slowest []QueryStats // sorted by duration, slowest first
+}
+
+// queryStatsTracker returns the index's tracker, creating it if needed.
+func (idx *Index) queryStatsTracker() *queryStats {
+	if idx.stats == nil {
+		idx.stats = &queryStats{}
+	}
+	return idx.stats
+}
+
+// recordQuery reports a completed search to the latency histogram, logger,
+// hook and slow query log.
+// Callers check idx.stats != nil first so untracked indexes pay nothing.
+func (idx *Index) recordQuery(method, query string, results int, start time.Time) {
+	idx.stats.record(method, query, results, start, idx.queryNgramCount)
+}
+
+// record reports a completed search; ngrams counts the query's n-grams and is
+// only called when a hook or slow query log needs it.
+func (q *queryStats) record(method, query string, results int, start time.Time, ngrams func(string) int) {
+	d := time.Since(start)
+	if q.latency != nil {
+		q.latency.record(start, d)
+	}
+	if q.logger != nil {
+		logSlow(q.logger, q.slowThreshold, method, query, results, d)
+	}
+	if q.hook == nil && q.capacity == 0 {
+		return
+	}
+
+	s := QueryStats{
+		Method:   method,
+		Query:    query,
+		Ngrams:   ngrams(query),
+		Results:  results,
+		Duration: d,
+	}
+
+	if q.hook != nil {
+		q.hook(s)
+	}
+	if q.capacity > 0 {
+		q.addSlow(s)
+	}
+}
+
+// queryNgramCount returns the number of unique n-grams in the normalized query.
+func (idx *Index) queryNgramCount(query string) int {
+	var keyBuf [queryKeyBufSize]uint64
+	return len(idx.appendQueryKeys(keyBuf[:0], query))
+}
+
+// addSlow inserts s if it is among the slowest queries seen so far.
+func (q *queryStats) addSlow(s QueryStats) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.slowest) == q.capacity && s.Duration <= q.slowest[len(q.slowest)-1].Duration {
+		return
+	}
+
+	i, _ := slices.BinarySearchFunc(q.slowest, s.Duration, func(e QueryStats, d time.Duration) int {
+		// Descending order: slower entries sort first
+		switch {
+		case e.Duration > d:
+			return -1
+		case e.Duration < d:
+			return 1
+		}
+		return 0
+	})
+	q.slowest = slices.Insert(q.slowest, i, s)
+	if len(q.slowest) > q.capacity {
+		q.slowest = q.slowest[:q.capacity]
+	}
+}
+
+// SlowQueries returns the slowest searches recorded since the index was created,
+// slowest first. Returns nil unless the index was created with WithSlowQueryLog.
+func (idx *Index) SlowQueries() []QueryStats {
+	if idx.stats == nil || idx.stats.capacity == 0 {
+		return nil
+	}
+
+	idx.stats.mu.Lock()
+	defer idx.stats.mu.Unlock()
+	return slices.Clone(idx.stats.slowest)
+}
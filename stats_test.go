@@ -0,0 +1,73 @@
+package roaringsearch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryHook(t *testing.T) {
+	var got []QueryStats
+	idx := NewIndex(3, WithQueryHook(func(s QueryStats) {
+		got = append(got, s)
+	}))
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+
+	idx.Search("hello")
+	idx.SearchCount("world")
+	idx.SearchThreshold("hello", 2)
+	idx.SearchCallback("hello", func(uint32) bool { return false })
+
+	if len(got) != 4 {
+		t.Fatalf("hook called %d times, want 4", len(got))
+	}
+
+	first := got[0]
+	if first.Method != "Search" || first.Query != "hello" || first.Ngrams != 3 || first.Results != 2 {
+		t.Errorf("Search stats = %+v", first)
+	}
+	if got[1].Method != "SearchCount" || got[1].Results != 1 {
+		t.Errorf("SearchCount stats = %+v", got[1])
+	}
+	if got[2].Method != "SearchThreshold" || got[2].Results != 2 {
+		t.Errorf("SearchThreshold stats = %+v", got[2])
+	}
+	if got[3].Method != "SearchCallback" || got[3].Results != 1 {
+		t.Errorf("SearchCallback stats = %+v", got[3])
+	}
+}
+
+func TestSlowQueries(t *testing.T) {
+	idx := NewIndex(3)
+	if idx.SlowQueries() != nil {
+		t.Error("SlowQueries should be nil without WithSlowQueryLog")
+	}
+
+	idx = NewIndex(3, WithSlowQueryLog(2))
+	idx.Add(1, testHelloWorld)
+	for _, q := range []string{"hello", "world", "there", "hello world"} {
+		idx.Search(q)
+	}
+
+	slow := idx.SlowQueries()
+	if len(slow) != 2 {
+		t.Fatalf("SlowQueries len = %d, want 2", len(slow))
+	}
+	if slow[0].Duration < slow[1].Duration {
+		t.Errorf("SlowQueries not ordered slowest first: %v, %v", slow[0].Duration, slow[1].Duration)
+	}
+}
+
+func TestQueryStatsAddSlow(t *testing.T) {
+	q := &queryStats{capacity: 3}
+	for _, d := range []time.Duration{5, 1, 9, 3, 7, 2} {
+		q.addSlow(QueryStats{Duration: d})
+	}
+
+	want := []time.Duration{9, 7, 5}
+	for i, s := range q.slowest {
+		if s.Duration != want[i] {
+			t.Errorf("slowest[%d] = %v, want %v", i, s.Duration, want[i])
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package roaringsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WithEnglishStemming indexes and queries text through the chain
+// stem-english, lowercase-alphanumeric, so "running", "runs" and "run" all
+// index as "run". The chain is recorded in the index file like any other.
+// Stemming runs before the default normalizer because that one drops the
+// spaces between words.
+func WithEnglishStemming() SharedOption {
+	return WithNormalizerChain(NormalizerChain{NormalizerStemEnglish, NormalizerLowercaseAlphanumeric})
+}
+
+// NormalizeStemEnglish lowercases each ASCII word and reduces it to its
+// stem with the Snowball English (Porter2) stemmer. Words are runs of
+// letters and digits; words with anything but ASCII letters, and all other
+// text, are left as they are. Compose it before normalizers that remove
+// whitespace.
+func NormalizeStemEnglish(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			b.WriteString(stemWord(s[start:end]))
+			start = -1
+		}
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteRune(r)
+	}
+	flush(len(s))
+	return b.String()
+}
+
+// stemWord stems a word made only of ASCII letters and returns any other
+// word unchanged.
+func stemWord(word string) string {
+	for i := range len(word) {
+		c := word[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return word
+		}
+	}
+	return stemEnglish(strings.ToLower(word))
+}
+
+// stemExceptions are the words Porter2 special-cases before stemming.
+var stemExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie",
+	"tying": "tie", "idly": "idl", "gently": "gentl", "ugly": "ugli",
+	"early": "earli", "only": "onli", "singly": "singl",
+	"sky": "sky", "news": "news", "howe": "howe", "atlas": "atlas",
+	"cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+// stemInvariants are left as they are after step 1a.
+var stemInvariants = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true,
+	"earring": true, "proceed": true, "exceed": true, "succeed": true,
+}
+
+// stemRule replaces a suffix. Rule lists are ordered longest suffix first,
+// since Porter2 only ever considers the longest suffix that matches.
+type stemRule struct {
+	suffix, repl string
+}
+
+var stemStep2 = []stemRule{
+	{"ization", "ize"}, {"ational", "ate"}, {"fulness", "ful"}, {"ousness", "ous"},
+	{"iveness", "ive"}, {"tional", "tion"}, {"biliti", "ble"}, {"lessli", "less"},
+	{"entli", "ent"}, {"ation", "ate"}, {"alism", "al"}, {"aliti", "al"},
+	{"ousli", "ous"}, {"iviti", "ive"}, {"fulli", "ful"}, {"enci", "ence"},
+	{"anci", "ance"}, {"abli", "able"}, {"izer", "ize"}, {"ator", "ate"},
+	{"alli", "al"}, {"bli", "ble"}, {"ogi", "og"}, {"li", ""},
+}
+
+var stemStep3 = []stemRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"alize", "al"}, {"icate", "ic"},
+	{"iciti", "ic"}, {"ative", ""}, {"ical", "ic"}, {"ness", ""}, {"ful", ""},
+}
+
+var stemStep4 = []string{
+	"ement", "ance", "ence", "able", "ible", "ment", "ant", "ent", "ism",
+	"ate", "iti", "ous", "ive", "ize", "ion", "al", "er", "ic",
+}
+
+// stemEnglish applies the Porter2 algorithm to a lowercase ASCII word.
+// Apostrophes never reach it, so step 0 is omitted.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	if stem, ok := stemExceptions[word]; ok {
+		return stem
+	}
+
+	b := []byte(word)
+	// A y that acts as a consonant is marked Y, which isStemVowel rejects
+	if b[0] == 'y' {
+		b[0] = 'Y'
+	}
+	for i := 1; i < len(b); i++ {
+		if b[i] == 'y' && isStemVowel(b[i-1]) {
+			b[i] = 'Y'
+		}
+	}
+	r1, r2 := stemRegions(b)
+
+	b = stemStep1a(b)
+	if stemInvariants[string(b)] {
+		return string(b)
+	}
+	b = stemStep1b(b, r1)
+	b = stemStep1c(b)
+	b = stemStep2or3(b, stemStep2, r1, r2)
+	b = stemStep2or3(b, stemStep3, r1, r2)
+	b = stemStep4or5(b, r1, r2)
+
+	for i, c := range b {
+		if c == 'Y' {
+			b[i] = 'y'
+		}
+	}
+	return string(b)
+}
+
+func isStemVowel(c byte) bool {
+	switch c {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+// stemRegions returns the starts of R1, the part after the first
+// non-vowel that follows a vowel, and R2, the same region within R1.
+func stemRegions(b []byte) (r1, r2 int) {
+	r1 = -1
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(string(b), prefix) {
+			r1 = len(prefix)
+		}
+	}
+	if r1 < 0 {
+		r1 = stemRegionAfter(b, 0)
+	}
+	return r1, stemRegionAfter(b, r1)
+}
+
+func stemRegionAfter(b []byte, start int) int {
+	for i := start + 1; i < len(b); i++ {
+		if !isStemVowel(b[i]) && isStemVowel(b[i-1]) {
+			return i + 1
+		}
+	}
+	return len(b)
+}
+
+func hasStemSuffix(b []byte, suffix string) bool {
+	return len(b) >= len(suffix) && string(b[len(b)-len(suffix):]) == suffix
+}
+
+func containsStemVowel(b []byte) bool {
+	for _, c := range b {
+		if isStemVowel(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsShortSyllable reports whether b ends in a non-vowel, vowel, non-vowel
+// other than w, x or Y, or is a vowel followed by a non-vowel.
+func endsShortSyllable(b []byte) bool {
+	n := len(b)
+	switch {
+	case n == 2:
+		return isStemVowel(b[0]) && !isStemVowel(b[1])
+	case n > 2:
+		c := b[n-1]
+		return !isStemVowel(b[n-3]) && isStemVowel(b[n-2]) && !isStemVowel(c) &&
+			c != 'w' && c != 'x' && c != 'Y'
+	}
+	return false
+}
+
+func stemStep1a(b []byte) []byte {
+	n := len(b)
+	switch {
+	case hasStemSuffix(b, "sses"):
+		return b[:n-2]
+	case hasStemSuffix(b, "ied"), hasStemSuffix(b, "ies"):
+		if n > 4 {
+			return b[:n-2]
+		}
+		return b[:n-1]
+	case hasStemSuffix(b, "us"), hasStemSuffix(b, "ss"):
+		return b
+	case hasStemSuffix(b, "s"):
+		if containsStemVowel(b[:n-2]) {
+			return b[:n-1]
+		}
+	}
+	return b
+}
+
+func stemStep1b(b []byte, r1 int) []byte {
+	n := len(b)
+	for _, suffix := range []string{"eedly", "ingly", "edly", "eed", "ing", "ed"} {
+		if !hasStemSuffix(b, suffix) {
+			continue
+		}
+		start := n - len(suffix)
+		if suffix == "eedly" || suffix == "eed" {
+			if start >= r1 {
+				return append(b[:start], "ee"...)
+			}
+			return b
+		}
+		if !containsStemVowel(b[:start]) {
+			return b
+		}
+		b = b[:start]
+		switch {
+		case hasStemSuffix(b, "at"), hasStemSuffix(b, "bl"), hasStemSuffix(b, "iz"):
+			return append(b, 'e')
+		case len(b) >= 2 && b[len(b)-1] == b[len(b)-2] && strings.IndexByte("bdfgmnprt", b[len(b)-1]) >= 0:
+			return b[:len(b)-1]
+		case r1 >= len(b) && endsShortSyllable(b):
+			return append(b, 'e')
+		}
+		return b
+	}
+	return b
+}
+
+func stemStep1c(b []byte) []byte {
+	n := len(b)
+	if n > 2 && (b[n-1] == 'y' || b[n-1] == 'Y') && !isStemVowel(b[n-2]) {
+		b[n-1] = 'i'
+	}
+	return b
+}
+
+// stemStep2or3 applies the longest matching rule if its suffix is in R1,
+// with the extra conditions of some step 2 and 3 rules.
+func stemStep2or3(b []byte, rules []stemRule, r1, r2 int) []byte {
+	for _, rule := range rules {
+		if !hasStemSuffix(b, rule.suffix) {
+			continue
+		}
+		start := len(b) - len(rule.suffix)
+		ok := start >= r1
+		switch rule.suffix {
+		case "ogi":
+			ok = ok && start > 0 && b[start-1] == 'l'
+		case "li":
+			ok = ok && start > 0 && strings.IndexByte("cdeghkmnrt", b[start-1]) >= 0
+		case "ative":
+			ok = start >= r2
+		}
+		if ok {
+			return append(b[:start], rule.repl...)
+		}
+		return b
+	}
+	return b
+}
+
+// stemStep4or5 deletes the longest step 4 suffix in R2, then a final e or
+// double l.
+func stemStep4or5(b []byte, r1, r2 int) []byte {
+	for _, suffix := range stemStep4 {
+		if !hasStemSuffix(b, suffix) {
+			continue
+		}
+		start := len(b) - len(suffix)
+		if start >= r2 && (suffix != "ion" || start > 0 && (b[start-1] == 's' || b[start-1] == 't')) {
+			b = b[:start]
+		}
+		break
+	}
+
+	n := len(b)
+	switch {
+	case hasStemSuffix(b, "e"):
+		if n-1 >= r2 || n-1 >= r1 && !endsShortSyllable(b[:n-1]) {
+			return b[:n-1]
+		}
+	case hasStemSuffix(b, "l"):
+		if n-1 >= r2 && n > 1 && b[n-2] == 'l' {
+			return b[:n-1]
+		}
+	}
+	return b
+}
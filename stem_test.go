@@ -0,0 +1,61 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStemEnglish(t *testing.T) {
+	// Expected stems are those of the Snowball English stemmer
+	tests := map[string]string{
+		"running": "run", "runs": "run", "ran": "ran",
+		"caresses": "caress", "ponies": "poni", "ties": "tie", "cats": "cat",
+		"hopping": "hop", "hoped": "hope", "skies": "sky", "dying": "die",
+		"relational": "relat", "conditional": "condit", "connection": "connect",
+		"connected": "connect", "agreed": "agre", "happiness": "happi",
+		"generously": "generous", "communication": "communic", "studies": "studi",
+		"study": "studi", "crying": "cri", "abilities": "abil", "argument": "argument",
+		"controlling": "control", "yellow": "yellow", "succeed": "succeed", "kiwis": "kiwi",
+	}
+	for word, want := range tests {
+		if got := stemEnglish(word); got != want {
+			t.Errorf("stemEnglish(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestNormalizeStemEnglish(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Running dogs, jumping!", "run dog, jump!"},
+		{"café runs", "café run"},
+		{"abc123 fishing", "abc123 fish"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeStemEnglish(tt.in); got != tt.want {
+			t.Errorf("NormalizeStemEnglish(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWithEnglishStemming(t *testing.T) {
+	idx := NewIndex(3, WithEnglishStemming())
+	idx.Add(1, "She was running late")
+	idx.Add(2, "connected components")
+	idx.Add(3, testHelloWorld)
+
+	for query, want := range map[string][]uint32{
+		"runs":       {1},
+		"RUN":        {1},
+		"connection": {2},
+		"hello":      {3},
+	} {
+		if got := idx.Search(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+	want := NormalizerChain{NormalizerStemEnglish, NormalizerLowercaseAlphanumeric}
+	if got := idx.NormalizerChain(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizerChain = %v, want %v", got, want)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/RoaringBitmap/roaring/v2"
 )
@@ -14,14 +18,55 @@ import (
 const (
 	magicBytes = "FTSR"
 	version    = 2 // Version 2 uses uint64 keys
+
+	// versionForward is version 2 followed by the forward index. It is only
+	// written for indexes created with WithForwardIndex.
+	versionForward = 3
+
+	// versionExtended adds a flags word after the header, followed by the
+	// exact key dictionary when flagExactKeys is set, the normalizer name
+	// when flagNormalizer is set and the metadata section when flagMetadata
+	// is set. The container directory follows the n-grams
+	// when flagContainers is set, then the forward index when flagForward is
+	// set, then the document lengths when flagDocLengths is set.
+	// flagCJKBigrams adds no section. It is only written for indexes created
+	// with WithExactKeys, WithContainerDirectory, WithDocLengths,
+	// WithCJKBigrams, WithMetadata or a non-default named normalizer.
+	versionExtended = 4
 )
 
+const (
+	flagForward    = 1 << 0
+	flagExactKeys  = 1 << 1
+	flagNormalizer = 1 << 2
+	flagContainers = 1 << 3
+	flagDocLengths = 1 << 4
+	flagCJKBigrams = 1 << 5
+	flagMetadata   = 1 << 6
+)
+
+// maxNormalizerNameLen bounds the recorded normalizer name.
+const maxNormalizerNameLen = 4096
+
 var (
-	ErrInvalidMagic    = errors.New("invalid magic bytes")
-	ErrInvalidVersion  = errors.New("unsupported version")
-	ErrInvalidGramSize = errors.New("invalid gram size")
-	ErrInvalidCount    = errors.New("invalid count exceeds limit")
-	ErrInvalidSize     = errors.New("invalid size exceeds limit")
+	ErrInvalidMagic       = errors.New("invalid magic bytes")
+	ErrInvalidVersion     = errors.New("unsupported version")
+	ErrInvalidGramSize    = errors.New("invalid gram size")
+	ErrInvalidCount       = errors.New("invalid count exceeds limit")
+	ErrInvalidSize        = errors.New("invalid size exceeds limit")
+	ErrFieldNotFound      = errors.New("field not found")
+	ErrTypeMismatch       = errors.New("value type mismatch")
+	ErrGramSizeMismatch   = errors.New("gram size mismatch")
+	ErrKeyModeMismatch    = errors.New("exact key mode mismatch")
+	ErrUnknownNormalizer  = errors.New("unknown normalizer")
+	ErrNormalizerMismatch = errors.New("normalizer mismatch")
+	ErrQuerySyntax        = errors.New("query syntax error")
+	ErrMinShouldMatch     = errors.New("invalid minimum should match")
+	ErrUnsupportedQuery   = errors.New("unsupported query type")
+	ErrTooManyResults     = errors.New("too many results")
+	ErrQueryTooShort      = errors.New("query shorter than gram size")
+	ErrNgramNotFound      = errors.New("ngram not found")
+	ErrInvalidConfig      = errors.New("invalid config")
 )
 
 const (
@@ -30,6 +75,19 @@ const (
 	maxBitmapSize = 100 << 20 // 100MB per bitmap max
 )
 
+// fileVersionLocked returns the file version WriteTo writes, given the
+// recorded normalizer name.
+func (idx *Index) fileVersionLocked(normalizer string) uint16 {
+	switch {
+	case idx.exact != nil || normalizer != "" || idx.containerDir || idx.lengths != nil ||
+		idx.cjkBigrams || idx.metadata != nil:
+		return versionExtended
+	case idx.forward != nil:
+		return versionForward
+	}
+	return version
+}
+
 // WriteTo writes the index to the provided writer.
 func (idx *Index) WriteTo(w io.Writer) (int64, error) {
 	idx.mu.RLock()
@@ -40,15 +98,68 @@ func (idx *Index) WriteTo(w io.Writer) (int64, error) {
 	// Write header: magic (4) + version (2) + gram size (2) = 8 bytes
 	header := make([]byte, 8)
 	copy(header[0:4], magicBytes)
-	binary.LittleEndian.PutUint16(header[4:6], version)
+	normalizer := idx.recordedNormalizerLocked()
+	fileVersion := idx.fileVersionLocked(normalizer)
+	binary.LittleEndian.PutUint16(header[4:6], fileVersion)
 	binary.LittleEndian.PutUint16(header[6:8], uint16(idx.gramSize))
 
+	if fileVersion == versionExtended {
+		var flags uint32
+		if idx.forward != nil {
+			flags |= flagForward
+		}
+		if idx.exact != nil {
+			flags |= flagExactKeys
+		}
+		if normalizer != "" {
+			flags |= flagNormalizer
+		}
+		if idx.containerDir {
+			flags |= flagContainers
+		}
+		if idx.lengths != nil {
+			flags |= flagDocLengths
+		}
+		if idx.cjkBigrams {
+			flags |= flagCJKBigrams
+		}
+		if idx.metadata != nil {
+			flags |= flagMetadata
+		}
+		header = binary.LittleEndian.AppendUint32(header, flags)
+	}
+
 	n, err := w.Write(header)
 	written += int64(n)
 	if err != nil {
 		return written, fmt.Errorf("write header: %w", err)
 	}
 
+	if idx.exact != nil {
+		n, err := idx.exact.writeTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if normalizer != "" {
+		buf := binary.LittleEndian.AppendUint16(nil, uint16(len(normalizer)))
+		n, err := w.Write(append(buf, normalizer...))
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("write normalizer: %w", err)
+		}
+	}
+
+	if idx.metadata != nil {
+		n, err := idx.writeMetadata(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
 	// Write n-gram count
 	countBuf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(countBuf, uint32(len(idx.bitmaps)))
@@ -61,20 +172,19 @@ func (idx *Index) WriteTo(w io.Writer) (int64, error) {
 	// Write each n-gram key and its bitmap
 	keyBuf := make([]byte, 8)
 	sizeBuf := make([]byte, 4)
+	var dir containerDirectory
+	var dirKeys []uint64
+	if idx.containerDir {
+		dir = make(containerDirectory)
+	}
 
-	for key, bm := range idx.bitmaps {
+	emit := func(key uint64, bmBytes []byte) error {
 		// N-gram key (8 bytes)
 		binary.LittleEndian.PutUint64(keyBuf, key)
-		n, err = w.Write(keyBuf)
+		n, err := w.Write(keyBuf)
 		written += int64(n)
 		if err != nil {
-			return written, fmt.Errorf("write ngram key: %w", err)
-		}
-
-		// Serialize bitmap to buffer first to get size
-		bmBytes, err := bm.ToBytes()
-		if err != nil {
-			return written, fmt.Errorf("serialize bitmap: %w", err)
+			return fmt.Errorf("write ngram key: %w", err)
 		}
 
 		// Bitmap size (4 bytes)
@@ -82,44 +192,177 @@ func (idx *Index) WriteTo(w io.Writer) (int64, error) {
 		n, err = w.Write(sizeBuf)
 		written += int64(n)
 		if err != nil {
-			return written, fmt.Errorf("write bitmap size: %w", err)
+			return fmt.Errorf("write bitmap size: %w", err)
 		}
 
 		// Bitmap data
 		n, err = w.Write(bmBytes)
 		written += int64(n)
 		if err != nil {
-			return written, fmt.Errorf("write bitmap: %w", err)
+			return fmt.Errorf("write bitmap: %w", err)
+		}
+
+		if dir != nil {
+			if err := dir.add(key, bmBytes); err != nil {
+				return fmt.Errorf("container directory: %w", err)
+			}
+			if _, ok := dir[key]; ok {
+				dirKeys = append(dirKeys, key)
+			}
+		}
+		return nil
+	}
+
+	if idx.saveWorkers > 0 {
+		if err := idx.writeBitmapsParallelLocked(emit); err != nil {
+			return written, err
+		}
+	} else {
+		for key, bm := range idx.orderedBitmapsLocked() {
+			// Serialize bitmap to buffer first to get size
+			bmBytes, err := bm.ToBytes()
+			if err != nil {
+				return written, fmt.Errorf("serialize bitmap: %w", err)
+			}
+			if err := emit(key, bmBytes); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	if dir != nil {
+		n, err := dir.writeTo(w, dirKeys)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if idx.forward != nil {
+		n, err := idx.forward.writeTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if idx.lengths != nil {
+		n, err := idx.writeDocLengths(w)
+		written += n
+		if err != nil {
+			return written, err
 		}
 	}
 
 	return written, nil
 }
 
-// readHeader reads and validates the file header, returning gram size.
-func readHeader(r io.Reader) (gramSize int, read int64, err error) {
+// orderedBitmapsLocked iterates the n-gram bitmaps, in ascending key order
+// when the index was created with WithDeterministicBuild.
+func (idx *Index) orderedBitmapsLocked() iter.Seq2[uint64, *roaring.Bitmap] {
+	if !idx.deterministic {
+		return maps.All(idx.bitmaps)
+	}
+	return func(yield func(uint64, *roaring.Bitmap) bool) {
+		for _, key := range slices.Sorted(maps.Keys(idx.bitmaps)) {
+			if !yield(key, idx.bitmaps[key]) {
+				return
+			}
+		}
+	}
+}
+
+// readHeader reads and validates the file header, returning gram size and version.
+func readHeader(r io.Reader) (gramSize int, fileVersion uint16, read int64, err error) {
 	header := make([]byte, 8)
 	n, err := io.ReadFull(r, header)
 	read = int64(n)
 	if err != nil {
-		return 0, read, fmt.Errorf("read header: %w", err)
+		return 0, 0, read, fmt.Errorf("read header: %w", err)
 	}
 
 	if string(header[0:4]) != magicBytes {
-		return 0, read, ErrInvalidMagic
+		return 0, 0, read, ErrInvalidMagic
 	}
 
-	fileVersion := binary.LittleEndian.Uint16(header[4:6])
-	if fileVersion != version {
-		return 0, read, ErrInvalidVersion
+	fileVersion = binary.LittleEndian.Uint16(header[4:6])
+	if fileVersion < version || fileVersion > versionExtended {
+		return 0, 0, read, ErrInvalidVersion
 	}
 
 	gramSize = int(binary.LittleEndian.Uint16(header[6:8]))
 	if gramSize < 1 || gramSize > maxGramSize {
-		return 0, read, ErrInvalidGramSize
+		return 0, 0, read, ErrInvalidGramSize
 	}
 
-	return gramSize, read, nil
+	return gramSize, fileVersion, read, nil
+}
+
+// fileExtensions holds the sections that follow the header in
+// versionExtended files.
+type fileExtensions struct {
+	flags      uint32
+	exact      *ngramDict // nil unless flagExactKeys
+	normalizer string     // empty unless flagNormalizer
+	metadata   IndexMetadata
+}
+
+// readExtensions reads the flags, exact key dictionary, normalizer name and
+// metadata that follow the header in versionExtended files. For older versions the
+// flags are derived from the version and nothing is read.
+func readExtensions(r io.Reader, fileVersion uint16) (ext fileExtensions, read int64, err error) {
+	switch fileVersion {
+	case version:
+		return ext, 0, nil
+	case versionForward:
+		ext.flags = flagForward
+		return ext, 0, nil
+	}
+
+	buf := make([]byte, 4)
+	n, err := io.ReadFull(r, buf)
+	read = int64(n)
+	if err != nil {
+		return ext, read, fmt.Errorf("read flags: %w", err)
+	}
+	ext.flags = binary.LittleEndian.Uint32(buf)
+
+	if ext.flags&flagExactKeys != 0 {
+		ext.exact = newNgramDict()
+		n, err := ext.exact.readFrom(r)
+		read += n
+		if err != nil {
+			return ext, read, err
+		}
+	}
+
+	if ext.flags&flagNormalizer != 0 {
+		n, err := io.ReadFull(r, buf[:2])
+		read += int64(n)
+		if err != nil {
+			return ext, read, fmt.Errorf("read normalizer length: %w", err)
+		}
+		nameLen := binary.LittleEndian.Uint16(buf[:2])
+		if nameLen > maxNormalizerNameLen {
+			return ext, read, ErrInvalidSize
+		}
+		name := make([]byte, nameLen)
+		n, err = io.ReadFull(r, name)
+		read += int64(n)
+		if err != nil {
+			return ext, read, fmt.Errorf("read normalizer: %w", err)
+		}
+		ext.normalizer = string(name)
+	}
+
+	if ext.flags&flagMetadata != 0 {
+		n, err := readMetadata(r, &ext.metadata)
+		read += n
+		if err != nil {
+			return ext, read, err
+		}
+	}
+	return ext, read, nil
 }
 
 // readNgramEntry reads a single n-gram key and bitmap from the reader.
@@ -158,43 +401,121 @@ func readNgramEntry(r io.Reader, keyBuf, sizeBuf []byte) (key uint64, bm *roarin
 }
 
 // ReadFrom reads the index from the provided reader.
-// Note: This replaces the current index contents. The normalizer is preserved.
+// Note: This replaces the current index contents. The normalizer is preserved
+// unless the data records the normalizer chain it was built with, which then
+// replaces it. If the data includes a forward index, the index keeps one from
+// then on. The exact key mode always follows the data.
 func (idx *Index) ReadFrom(r io.Reader) (int64, error) {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	var totalRead int64
+	ext, ngramCount, totalRead, err := idx.readPreambleLocked(r)
+	if err != nil {
+		return totalRead, err
+	}
 
-	gramSize, read, err := readHeader(r)
+	idx.bitmaps = make(map[uint64]*roaring.Bitmap, ngramCount)
+	idx.shared = nil
+	idx.generation++
+	idx.docs = roaring.New()
+	hasForward := ext.flags&flagForward != 0
+	if idx.forward != nil || hasForward {
+		idx.forward = make(forwardIndex)
+	}
+	rebuildForward := idx.forward != nil && !hasForward
+
+	keyBuf := make([]byte, 8)
+	sizeBuf := make([]byte, 4)
+
+	for i := uint32(0); i < ngramCount; i++ {
+		key, bm, read, err := readNgramEntry(r, keyBuf, sizeBuf)
+		totalRead += read
+		if err != nil {
+			return totalRead, err
+		}
+		idx.bitmaps[key] = bm
+		idx.docs.Or(bm)
+		if rebuildForward {
+			idx.forward.recordBitmap(key, bm)
+		}
+	}
+
+	read, err := idx.readTrailerLocked(r, ext)
+	totalRead += read
+	return totalRead, err
+}
+
+// readPreambleLocked reads the header and extensions into idx and returns
+// them with the n-gram count that follows.
+func (idx *Index) readPreambleLocked(r io.Reader) (ext fileExtensions, ngramCount uint32, totalRead int64, err error) {
+	gramSize, fileVersion, read, err := readHeader(r)
 	totalRead += read
 	if err != nil {
-		return totalRead, err
+		return ext, 0, totalRead, err
 	}
 	idx.gramSize = gramSize
 
+	ext, read, err = readExtensions(r, fileVersion)
+	totalRead += read
+	if err != nil {
+		return ext, 0, totalRead, err
+	}
+	if ext.normalizer != "" {
+		chain, err := ParseNormalizerChain(ext.normalizer)
+		if err != nil {
+			return ext, 0, totalRead, err
+		}
+		WithNormalizerChain(chain).applyIndex(idx)
+	}
+	idx.exact = ext.exact
+	idx.cjkBigrams = ext.flags&flagCJKBigrams != 0
+	if ext.metadata.Recorded {
+		idx.metadata = &fileMetadata{createdAt: ext.metadata.CreatedAt, sourceChecksum: ext.metadata.SourceChecksum}
+	}
+
 	countBuf := make([]byte, 4)
 	n, err := io.ReadFull(r, countBuf)
 	totalRead += int64(n)
 	if err != nil {
-		return totalRead, fmt.Errorf("read ngram count: %w", err)
+		return ext, 0, totalRead, fmt.Errorf("read ngram count: %w", err)
 	}
-	ngramCount := binary.LittleEndian.Uint32(countBuf)
+	ngramCount = binary.LittleEndian.Uint32(countBuf)
 	if ngramCount > maxNgramCount {
-		return totalRead, ErrInvalidCount
+		return ext, 0, totalRead, ErrInvalidCount
 	}
+	return ext, ngramCount, totalRead, nil
+}
 
-	idx.bitmaps = make(map[uint64]*roaring.Bitmap, ngramCount)
+// readTrailerLocked reads the sections that follow the n-grams. idx.forward
+// must be non-nil when the file has a forward index.
+func (idx *Index) readTrailerLocked(r io.Reader, ext fileExtensions) (int64, error) {
+	var totalRead int64
+	if ext.flags&flagContainers != 0 {
+		idx.containerDir = true
+		read, err := skipContainerDirectory(r)
+		totalRead += read
+		if err != nil {
+			return totalRead, err
+		}
+	}
 
-	keyBuf := make([]byte, 8)
-	sizeBuf := make([]byte, 4)
+	if ext.flags&flagForward != 0 {
+		read, err := idx.forward.readFrom(r)
+		totalRead += read
+		if err != nil {
+			return totalRead, err
+		}
+	}
 
-	for i := uint32(0); i < ngramCount; i++ {
-		key, bm, read, err := readNgramEntry(r, keyBuf, sizeBuf)
+	if ext.flags&flagDocLengths != 0 {
+		read, err := idx.readDocLengths(r)
 		totalRead += read
 		if err != nil {
 			return totalRead, err
 		}
-		idx.bitmaps[key] = bm
+	} else if idx.lengths != nil {
+		idx.lengths = NewSortColumn[uint16]()
+		idx.lengthSum, idx.lengthCount = 0, 0
 	}
 
 	return totalRead, nil
@@ -203,40 +524,63 @@ func (idx *Index) ReadFrom(r io.Reader) (int64, error) {
 // SaveToFile saves the index to a file atomically.
 // Writes to a temp file first, then renames to prevent corruption on crash.
 func (idx *Index) SaveToFile(path string) error {
+	l := idx.logger()
+	if l == nil {
+		_, err := idx.saveToFile(path)
+		return err
+	}
+
+	start := time.Now()
+	n, err := idx.saveToFile(path)
+	if err != nil {
+		l.Error("roaringsearch: save index", "path", path, "error", err)
+		return err
+	}
+	l.Info("roaringsearch: saved index",
+		"path", path, "bytes", n, "ngrams", idx.NgramCount(), "duration", time.Since(start))
+	return nil
+}
+
+// saveToFile runs SaveToFile and returns the bytes written.
+func (idx *Index) saveToFile(path string) (int64, error) {
+	if idx.optimizeOnSave {
+		idx.Optimize()
+	}
 	tmpPath := path + ".tmp"
 	f, err := os.Create(tmpPath)
 	if err != nil {
-		return fmt.Errorf("create temp file: %w", err)
+		return 0, fmt.Errorf("create temp file: %w", err)
 	}
 
-	_, err = idx.WriteTo(f)
+	n, err := idx.WriteTo(f)
 	if err != nil {
 		f.Close()
 		os.Remove(tmpPath)
-		return err
+		return 0, err
 	}
 
 	if err := f.Sync(); err != nil {
 		f.Close()
 		os.Remove(tmpPath)
-		return fmt.Errorf("sync temp file: %w", err)
+		return 0, fmt.Errorf("sync temp file: %w", err)
 	}
 
 	if err := f.Close(); err != nil {
 		os.Remove(tmpPath)
-		return fmt.Errorf("close temp file: %w", err)
+		return 0, fmt.Errorf("close temp file: %w", err)
 	}
 
 	if err := os.Rename(tmpPath, path); err != nil {
 		os.Remove(tmpPath)
-		return fmt.Errorf("rename temp file: %w", err)
+		return 0, fmt.Errorf("rename temp file: %w", err)
 	}
 
-	return nil
+	return n, nil
 }
 
 // LoadFromFile loads an index from a file.
-// Returns a new Index with the default normalizer.
+// Returns a new Index with the normalizer chain recorded in the file, or the
+// default normalizer if none was recorded.
 func LoadFromFile(path string) (*Index, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -254,15 +598,33 @@ func LoadFromFile(path string) (*Index, error) {
 }
 
 // LoadFromFileWithOptions loads an index from a file with custom options.
+// If the file records the normalizer it was built with and the options set a
+// different one, ErrNormalizerMismatch is returned; WithExactKeys on a file
+// written without exact keys returns ErrKeyModeMismatch. With WithLogger, the
+// loaded index is logged at Info.
 func LoadFromFileWithOptions(path string, opts ...Option) (*Index, error) {
+	start := time.Now()
 	idx, err := LoadFromFile(path)
 	if err != nil {
 		return nil, err
 	}
+	recorded := idx.recordedNormalizerLocked()
+	exact := idx.exact != nil
 
 	for _, opt := range opts {
-		opt(idx)
+		opt.applyIndex(idx)
 	}
 
+	if err := checkNormalizer(recorded, idx.normalizer, idx.normalizerChain); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if idx.exact != nil && !exact {
+		return nil, fmt.Errorf("%s: %w", path, ErrKeyModeMismatch)
+	}
+
+	if l := idx.logger(); l != nil {
+		l.Info("roaringsearch: loaded index",
+			"path", path, "ngrams", idx.NgramCount(), "duration", time.Since(start))
+	}
 	return idx, nil
 }
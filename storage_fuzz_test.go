@@ -16,3 +16,18 @@ func FuzzIndexReadFrom(f *testing.F) {
 		// No panic = success
 	})
 }
+
+// FuzzImportPilosa tests decoding of Pilosa roaring fragments
+func FuzzImportPilosa(f *testing.F) {
+	f.Add(pilosaRunFragment())
+	var buf bytes.Buffer
+	filter := NewBitmapFilter()
+	filter.Set(1, "f", "a")
+	filter.ExportPilosa(&buf, "f", 0)
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = NewBitmapFilter().ImportPilosa(bytes.NewReader(data), "f", 0, nil)
+		// No panic = success
+	})
+}
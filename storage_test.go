@@ -1,6 +1,8 @@
 package roaringsearch
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -54,6 +56,11 @@ func TestSerialization(t *testing.T) {
 	if !reflect.DeepEqual(results1, results2) {
 		t.Errorf("search results mismatch: got %v, want %v", results2, results1)
 	}
+
+	// All-docs bitmap is rebuilt from the n-gram bitmaps
+	if idx2.DocCount() != 3 {
+		t.Errorf("DocCount after load = %d, want 3", idx2.DocCount())
+	}
 }
 
 func TestLoadFromFileWithOptions(t *testing.T) {
@@ -120,3 +127,32 @@ func TestOpenCachedIndexError(t *testing.T) {
 		t.Error("OpenCachedIndex should fail for invalid file format")
 	}
 }
+
+func TestDeterministicBuild(t *testing.T) {
+	build := func(opts ...Option) []byte {
+		idx := NewIndex(3, opts...)
+		batch := idx.BatchSize(2000)
+		for i := uint32(0); i < 2000; i++ {
+			batch.Add(i, fmt.Sprintf("document %d about topic %d", i, i%37))
+		}
+		batch.Flush()
+
+		var buf bytes.Buffer
+		if _, err := idx.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, opts := range [][]Option{
+		{WithDeterministicBuild()},
+		{WithDeterministicBuild(), WithForwardIndex()},
+	} {
+		first := build(opts...)
+		for range 3 {
+			if !bytes.Equal(first, build(opts...)) {
+				t.Fatal("deterministic builds produced different bytes")
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package roaringsearch
+
+import (
+	"strings"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// maxSynonymVariants caps the query variants one query expands to. Words
+// past the cap are searched as written.
+const maxSynonymVariants = 32
+
+// synonymSet maps a lowercased word to its alternatives.
+type synonymSet map[string][]string
+
+// newSynonymSet copies syn with lowercased words, merging words that only
+// differ in case.
+func newSynonymSet(syn map[string][]string) synonymSet {
+	if len(syn) == 0 {
+		return nil
+	}
+	set := make(synonymSet, len(syn))
+	for word, alts := range syn {
+		word = strings.ToLower(word)
+		set[word] = append(set[word], alts...)
+	}
+	return set
+}
+
+// WithSynonyms expands whitespace-separated query words before n-gram
+// generation, so with {"tv": {"television"}} a Search for "tv stand" also
+// finds "television stand". Each combination of alternatives is searched
+// like a query of its own and the results are ORed. Words match case
+// insensitively. Expansion is one way: list both directions for symmetric
+// synonyms. A query expands to at most 32 variants; later words are kept
+// as written once the cap is reached. As with any query, a variant shorter
+// than the gram size matches nothing.
+//
+// Search and SearchBitmap expand queries. Synonyms are not saved with the
+// index; pass the option again after loading.
+func WithSynonyms(syn map[string][]string) Option {
+	return indexOption(func(idx *Index) {
+		idx.synonyms = newSynonymSet(syn)
+	})
+}
+
+// WithIndexSynonyms expands documents instead of queries: a document
+// containing a word is also indexed under its alternatives, so every search
+// method, including those WithSynonyms does not cover, finds it by them. It
+// costs index size rather than query time, and changing the synonyms means
+// reindexing.
+func WithIndexSynonyms(syn map[string][]string) Option {
+	return indexOption(func(idx *Index) {
+		idx.indexSynonyms = newSynonymSet(syn)
+	})
+}
+
+// expand returns the variants of a query, the query itself first, or nil
+// if no word of it has synonyms.
+func (s synonymSet) expand(query string) []string {
+	if s == nil {
+		return nil
+	}
+	words := strings.Fields(query)
+	variants := []string{""}
+	expanded := false
+	for _, word := range words {
+		alts := s[strings.ToLower(word)]
+		if len(alts) == 0 || len(variants)*(len(alts)+1) > maxSynonymVariants {
+			alts = nil
+		}
+		next := make([]string, 0, len(variants)*(len(alts)+1))
+		for _, v := range variants {
+			next = append(next, v+" "+word)
+			for _, alt := range alts {
+				next = append(next, v+" "+alt)
+			}
+		}
+		variants = next
+		expanded = expanded || len(alts) > 0
+	}
+	if !expanded {
+		return nil
+	}
+	for i, v := range variants {
+		variants[i] = v[1:]
+	}
+	return variants
+}
+
+// expandDocument appends the alternatives of the words of text to it.
+func (s synonymSet) expandDocument(text string) string {
+	if s == nil {
+		return text
+	}
+	var b strings.Builder
+	for _, word := range strings.Fields(text) {
+		for _, alt := range s[strings.ToLower(word)] {
+			if b.Len() == 0 {
+				b.WriteString(text)
+			}
+			b.WriteByte(' ')
+			b.WriteString(alt)
+		}
+	}
+	if b.Len() == 0 {
+		return text
+	}
+	return b.String()
+}
+
+// searchVariants ORs the AND results of query variants.
+func (idx *Index) searchVariants(variants []string) *roaring.Bitmap {
+	var keyBuf [queryKeyBufSize]uint64
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	result := roaring.New()
+	for _, v := range variants {
+		keys := idx.appendQueryKeys(keyBuf[:0], v)
+		if len(keys) == 0 {
+			continue
+		}
+		result.Or(idx.searchBitmapLocked(keys))
+	}
+	return result
+}
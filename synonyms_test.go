@@ -0,0 +1,73 @@
+package roaringsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithSynonyms(t *testing.T) {
+	idx := NewIndex(3, WithSynonyms(map[string][]string{
+		"TV":    {"television"},
+		"couch": {"sofa", "settee"},
+	}))
+	idx.Add(1, "television stand")
+	idx.Add(2, "tv stand")
+	idx.Add(3, "leather sofa")
+	idx.Add(4, "television on a settee")
+	idx.Add(5, testHelloWorld)
+
+	tests := []struct {
+		query string
+		want  []uint32
+	}{
+		{"tv stand", []uint32{1, 2}},
+		{"TV Stand", []uint32{1, 2}},
+		{"tv", []uint32{1, 4}}, // "tv" itself is shorter than a trigram
+		{"leather couch", []uint32{3}},
+		{"couch", []uint32{3, 4}},
+		{"television", []uint32{1, 4}}, // one way
+		{"hello", []uint32{5}},
+		{"tv xyzzy", nil},
+	}
+	for _, tt := range tests {
+		if got := idx.Search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		if got := idx.SearchBitmap(tt.query).ToArray(); len(got) != len(tt.want) {
+			t.Errorf("SearchBitmap(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got := idx.Snapshot().Search("tv"); !reflect.DeepEqual(got, []uint32{1, 4}) {
+		t.Errorf("snapshot lost the synonyms: %v", got)
+	}
+}
+
+func TestSynonymVariantCap(t *testing.T) {
+	syn := synonymSet{"a": {"b", "c", "d"}}
+	variants := syn.expand("a a a a a")
+	if len(variants) > maxSynonymVariants {
+		t.Errorf("expand gave %d variants, want at most %d", len(variants), maxSynonymVariants)
+	}
+	if variants[0] != "a a a a a" {
+		t.Errorf("first variant = %q, want the query", variants[0])
+	}
+	if got := syn.expand("x y"); got != nil {
+		t.Errorf("expand without synonyms = %v, want nil", got)
+	}
+}
+
+func TestWithIndexSynonyms(t *testing.T) {
+	idx := NewIndex(3, WithIndexSynonyms(map[string][]string{"tv": {"television"}}))
+	idx.Add(1, "tv stand")
+	idx.Add(2, "radio")
+
+	if got := idx.Search("television"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(television) = %v, want [1]", got)
+	}
+	if got := idx.SearchAny("television"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("SearchAny(television) = %v, want [1]", got)
+	}
+	if got := idx.Search("tv stand"); !reflect.DeepEqual(got, []uint32{1}) {
+		t.Errorf("Search(tv stand) = %v, want [1]", got)
+	}
+}
@@ -0,0 +1,129 @@
+package roaringsearch
+
+import (
+	"math"
+	"slices"
+)
+
+// defaultDigestCompression bounds a tdigest to about a hundred centroids,
+// which keeps percentiles within a fraction of a percent of rank, and much
+// closer at the tails.
+const defaultDigestCompression = 100
+
+// centroid is a cluster of values of a tdigest.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// tdigest is a merging t-digest (Dunning & Ertl): an approximate sketch of
+// a distribution from which percentiles can be read, in memory bounded by
+// the compression rather than by the number of values. Values are buffered
+// and merged into centroids sorted by mean, with centroids near the tails
+// kept small so extreme percentiles stay accurate.
+type tdigest struct {
+	compression float64
+	centroids   []centroid // merged, sorted by mean
+	buffer      []centroid // added since the last merge
+	count       float64
+	min, max    float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		buffer:      make([]centroid, 0, int(5*compression)),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// add records one value.
+func (d *tdigest) add(x float64) {
+	d.buffer = append(d.buffer, centroid{mean: x, weight: 1})
+	d.count++
+	d.min, d.max = min(d.min, x), max(d.max, x)
+	if len(d.buffer) == cap(d.buffer) {
+		d.merge()
+	}
+}
+
+// scale is the k1 scale function, mapping a quantile to a centroid index
+// scale on which every centroid may span at most 1.
+func (d *tdigest) scale(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// inverseScale is the inverse of scale.
+func (d *tdigest) inverseScale(k float64) float64 {
+	return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2
+}
+
+// merge folds the buffered values into the centroids.
+func (d *tdigest) merge() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := append(d.buffer, d.centroids...)
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	merged := make([]centroid, 0, len(d.centroids)+1)
+	cur := all[0]
+	var before float64 // weight of the centroids already emitted
+	limit := d.inverseScale(d.scale(0) + 1)
+	for _, next := range all[1:] {
+		if (before+cur.weight+next.weight)/d.count <= limit {
+			cur.weight += next.weight
+			cur.mean += (next.mean - cur.mean) * next.weight / cur.weight
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.weight
+		limit = d.inverseScale(d.scale(before/d.count) + 1)
+		cur = next
+	}
+	d.centroids = append(merged, cur)
+	d.buffer = d.buffer[:0]
+}
+
+// quantile returns the approximate value at quantile q in [0, 1], or NaN if
+// no value was added. Values are interpolated between centroid centers,
+// and between the extreme centroids and the exact min and max.
+func (d *tdigest) quantile(q float64) float64 {
+	d.merge()
+	switch {
+	case d.count == 0:
+		return math.NaN()
+	case q <= 0:
+		return d.min
+	case q >= 1:
+		return d.max
+	case len(d.centroids) == 1:
+		return d.centroids[0].mean
+	}
+
+	target := q * d.count
+	first := d.centroids[0]
+	if target < first.weight/2 {
+		return d.min + (first.mean-d.min)*target/(first.weight/2)
+	}
+	center := first.weight / 2 // cumulative weight at the current center
+	for i := 1; i < len(d.centroids); i++ {
+		prev, cur := d.centroids[i-1], d.centroids[i]
+		next := center + (prev.weight+cur.weight)/2
+		if target < next {
+			return prev.mean + (cur.mean-prev.mean)*(target-center)/(next-center)
+		}
+		center = next
+	}
+	last := d.centroids[len(d.centroids)-1]
+	return last.mean + (d.max-last.mean)*(target-center)/(last.weight/2)
+}
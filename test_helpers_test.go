@@ -23,6 +23,9 @@ const (
 	errEmptyQueryResult = "empty query should return nil, got %v"
 )
 
+// raceEnabled reports whether tests run under the race detector.
+var raceEnabled bool
+
 // Word pools for generating realistic documents
 var (
 	commonWords = []string{
@@ -47,6 +50,8 @@ var (
 	}
 )
 
+// generateDocument matches bench.GenerateDocument, which in-package tests
+// cannot import without a cycle.
 func generateDocument(rng *rand.Rand, minWords, maxWords int) string {
 	numWords := minWords + rng.Intn(maxWords-minWords+1)
 	words := make([]string, numWords)
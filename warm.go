@@ -0,0 +1,159 @@
+package roaringsearch
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// PreloadNgrams loads the bitmaps for n-gram strings into cache.
+// Strings are used as-is, so they should already be normalized; strings that
+// are not exactly GramSize runes long are ignored.
+func (idx *CachedIndex) PreloadNgrams(ngrams []string) error {
+	return idx.preloadConcurrent(idx.ngramKeys(ngrams))
+}
+
+// PreloadQuery loads every bitmap a search for query would need into cache.
+func (idx *CachedIndex) PreloadQuery(query string) error {
+	return idx.preloadConcurrent(idx.generateKeys(query))
+}
+
+// WarmFromQueries reads a query log with one query per line and preloads the
+// bitmaps its queries need, most frequently used n-grams first. When the log
+// needs more bitmaps than the cache holds, only the most frequent are loaded,
+// so a startup warmup does not evict its own popular entries.
+func (idx *CachedIndex) WarmFromQueries(r io.Reader) error {
+	counts := make(map[uint64]int)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		query := strings.TrimSpace(scanner.Text())
+		if query == "" {
+			continue
+		}
+		for _, key := range idx.generateKeys(query) {
+			if _, ok := idx.location(key); ok {
+				counts[key]++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read query log: %w", err)
+	}
+
+	keys := make([]uint64, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b uint64) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	keys = idx.fitCache(keys)
+
+	// Load the most frequent last so they are the most recently used
+	slices.Reverse(keys)
+	return idx.preloadConcurrent(keys)
+}
+
+// fitCache truncates keys to the prefix that fits in the cache, estimating
+// each bitmap's memory from its serialized size.
+func (idx *CachedIndex) fitCache(keys []uint64) []uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if limit := idx.lru.memoryLimit(); limit > 0 {
+		var total int64
+		for i, key := range keys {
+			total += int64(idx.ngramIndex[key].size)
+			if total > limit {
+				return keys[:i]
+			}
+		}
+		return keys
+	}
+	if idx.lru.maxEntries > 0 && len(keys) > idx.lru.maxEntries {
+		return keys[:idx.lru.maxEntries]
+	}
+	return keys
+}
+
+// preloadConcurrent reads the uncached bitmaps for keys from disk in parallel,
+// then adds them to the cache in key order, so the last keys end up most
+// recently used. Keys missing from the index are skipped, and so are
+// bitmaps whose n-gram a concurrent Refresh changed while they were read.
+func (idx *CachedIndex) preloadConcurrent(keys []uint64) error {
+	idx.mu.RLock()
+	pending := make([]keyLocation, 0, len(keys))
+	seen := make(map[uint64]struct{}, len(keys))
+	for _, key := range keys {
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		_, cached := idx.lru.entries[key]
+		if _, ok := idx.pinned[key]; ok {
+			cached = true
+		}
+		if loc, exists := idx.ngramIndex[key]; exists && !cached {
+			pending = append(pending, keyLocation{key: key, loc: loc, gen: idx.gens[key]})
+		}
+	}
+	reader := idx.reader
+	reader.users.Add(1)
+	idx.mu.RUnlock()
+	defer reader.users.Done()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	bitmaps := make([]*roaring.Bitmap, len(pending))
+	errs := make([]error, len(pending))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(pending)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(pending) {
+					return
+				}
+				bm, err := loadBitmapAt(reader, pending[i].loc)
+				if err != nil {
+					errs[i] = fmt.Errorf("failed to load key: %d: %w", pending[i].key, err)
+					continue
+				}
+				bitmaps[i] = bm
+			}
+		}()
+	}
+	wg.Wait()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for i, kl := range pending {
+		if bitmaps[i] == nil || idx.gens[kl.key] != kl.gen {
+			continue
+		}
+		_, cached := idx.lru.entries[kl.key]
+		_, pinned := idx.pinned[kl.key]
+		if !cached && !pinned {
+			idx.lru.add(kl.key, bitmaps[i])
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,84 @@
+package roaringsearch
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newWarmTestIndex(t *testing.T, opts ...CachedIndexOption) *CachedIndex {
+	t.Helper()
+
+	idx := NewIndex(3)
+	idx.Add(1, testHelloWorld)
+	idx.Add(2, testHelloThere)
+	idx.Add(3, testGoodbyeWorld)
+
+	path := filepath.Join(t.TempDir(), "warm.sear")
+	if err := idx.SaveToFile(path); err != nil {
+		t.Fatalf(errSaveToFile, err)
+	}
+	cached, err := OpenCachedIndex(path, opts...)
+	if err != nil {
+		t.Fatalf("OpenCachedIndex failed: %v", err)
+	}
+	t.Cleanup(func() { cached.Close() })
+	return cached
+}
+
+func TestPreloadNgramsAndQuery(t *testing.T) {
+	cached := newWarmTestIndex(t)
+
+	if err := cached.PreloadNgrams([]string{"hel", "wor", "zzz", "toolong"}); err != nil {
+		t.Fatalf("PreloadNgrams failed: %v", err)
+	}
+	if cached.CacheSize() != 2 {
+		t.Errorf("CacheSize after PreloadNgrams = %d, want 2", cached.CacheSize())
+	}
+
+	cached.ClearCache()
+	if err := cached.PreloadQuery("hello"); err != nil {
+		t.Fatalf("PreloadQuery failed: %v", err)
+	}
+	if cached.CacheSize() != 3 {
+		t.Errorf("CacheSize after PreloadQuery = %d, want 3", cached.CacheSize())
+	}
+	if got := cached.Search("hello"); len(got) != 2 {
+		t.Errorf("Search after preload = %v, want 2 docs", got)
+	}
+}
+
+func TestWarmFromQueries(t *testing.T) {
+	log := "hello\n\nhello world\ngoodbye\nunknownquery\n"
+
+	cached := newWarmTestIndex(t)
+	if err := cached.WarmFromQueries(strings.NewReader(log)); err != nil {
+		t.Fatalf("WarmFromQueries failed: %v", err)
+	}
+	needed := make(map[uint64]struct{})
+	for _, query := range strings.Split(log, "\n") {
+		for _, key := range cached.generateKeys(query) {
+			if _, ok := cached.ngramIndex[key]; ok {
+				needed[key] = struct{}{}
+			}
+		}
+	}
+	want := len(needed)
+	if cached.CacheSize() != want {
+		t.Errorf("CacheSize after warmup = %d, want %d", cached.CacheSize(), want)
+	}
+
+	// With a small cache only the most frequent n-grams are loaded
+	small := newWarmTestIndex(t, WithCacheSize(3))
+	if err := small.WarmFromQueries(strings.NewReader(log)); err != nil {
+		t.Fatalf("WarmFromQueries failed: %v", err)
+	}
+	if small.CacheSize() != 3 {
+		t.Fatalf("CacheSize = %d, want 3", small.CacheSize())
+	}
+	for _, ngram := range []string{"hel", "ell", "llo"} {
+		if _, ok := small.lru.entries[NgramKey(ngram)]; !ok {
+			t.Errorf("expected frequent n-gram %q to be cached", ngram)
+		}
+	}
+}